@@ -0,0 +1,200 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// operatorNot is the operator of a node negating its only child, accepted
+// when decoding and parsing trees, which have no such node: it is replaced by
+// the negation of its child, see `negate`.
+const operatorNot = Operator("!")
+
+// operatorAliases are the other spellings of operators accepted when decoding
+// and parsing trees, besides the names of the operators in any case.
+var operatorAliases = map[string]Operator{
+	"&&": OperatorAnd,
+	"||": OperatorOr,
+	"!":  operatorNot,
+}
+
+// normalizeOperator returns the operator spelled `s`, accepting the aliases
+// `&&`, `||` and `!`, and the names of the built-in operators and `NOT` in
+// any case.  Registered operators are matched exactly, and unknown operators are
+// returned as they are, for `Validate` to reject.
+func normalizeOperator(s string) Operator {
+	if op, ok := operatorAliases[s]; ok {
+		return op
+	}
+	for _, op := range []Operator{OperatorAnd, OperatorOr, OperatorIf, OperatorSwitch, OperatorCase, OperatorRef, OperatorLeaf, OperatorAdvanced} {
+		if strings.EqualFold(s, string(op)) {
+			return op
+		}
+	}
+	if strings.EqualFold(s, "not") && !isCustom(Operator(s)) {
+		return operatorNot
+	}
+	return Operator(s)
+}
+
+// normalizeNode replaces the decoded node `n`, whose children have been
+// normalized, by its canonical form: a `!` node by the negation of its only
+// child.
+func normalizeNode(n *Node) error {
+	if n.Op != operatorNot {
+		return nil
+	}
+	if len(n.Nodes) != 1 || n.Leaf != "" {
+		return fmt.Errorf("%w: %s needs exactly one child", ErrInvalidOperator, n.Op)
+	}
+	c, err := negate(n.Nodes[0])
+	if err != nil {
+		return err
+	}
+	*n = *c
+	return nil
+}
+
+// negate returns the negation of the tree rooted at `n`, pushing it down to
+// the leaves: `and` and `or` nodes are swapped and their children negated, as
+// by De Morgan's laws, `if` nodes and switches negate their branches, and
+// leaves become calls of `not`.  Advanced leaves and registered operators cannot be
+// negated.
+func negate(n *Node) (*Node, error) {
+	switch n.Op {
+	case OperatorLeaf:
+		if v, negated, constant, ok := leafLiteral(leafVariable(n)); !ok {
+			return constantNode(!constant), nil
+		} else if negated {
+			return &Node{Op: OperatorLeaf, Leaf: v}, nil
+		}
+		// The leaf is parenthesized, as `NewLeafNode` does, for `not` to
+		// take the whole of it as its only argument.
+		leaf := strings.TrimSpace(n.Leaf)
+		if !strings.HasPrefix(leaf, "(") || closingParen(leaf) != len(leaf)-1 {
+			leaf = "(" + leaf + ")"
+		}
+		return NewLeafNode("not " + leaf), nil
+	case OperatorAnd, OperatorOr:
+		op := Operator(OperatorAnd)
+		if n.Op == OperatorAnd {
+			op = OperatorOr
+		}
+		c := &Node{Op: op, Nodes: make([]*Node, len(n.Nodes))}
+		for i, child := range n.Nodes {
+			var err error
+			if c.Nodes[i], err = negate(child); err != nil {
+				return nil, err
+			}
+		}
+		return c, nil
+	case OperatorIf:
+		if len(n.Nodes) != 3 {
+			return nil, fmt.Errorf("%w: if needs exactly three children", ErrInvalidOperator)
+		}
+		then, err := negate(n.Nodes[1])
+		if err != nil {
+			return nil, err
+		}
+		els, err := negate(n.Nodes[2])
+		if err != nil {
+			return nil, err
+		}
+		return NewIfNode(n.Nodes[0], then, els), nil
+	case OperatorSwitch:
+		// A switch whose field matches no case is false, so its negation
+		// has a true default.
+		c := &Node{Op: n.Op, Leaf: n.Leaf, Nodes: make([]*Node, len(n.Nodes))}
+		def := false
+		for i, cs := range n.Nodes {
+			if cs.Op != OperatorCase || len(cs.Nodes) != 1 {
+				return nil, fmt.Errorf("%w: case needs exactly one child", ErrInvalidOperator)
+			}
+			child, err := negate(cs.Nodes[0])
+			if err != nil {
+				return nil, err
+			}
+			c.Nodes[i] = &Node{Op: OperatorCase, Leaf: cs.Leaf, Nodes: []*Node{child}}
+			def = def || cs.Leaf == ""
+		}
+		if !def {
+			c.Nodes = append(c.Nodes, NewCaseNode(constantNode(true)))
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("%w: %s nodes cannot be negated", ErrInvalidOperator, n.Op)
+}
+
+// MarshalJSON encodes a node as `json.Marshal` otherwise would, but for the
+// nodes of a `PayloadOperator`, whose payload is encoded as their "Payload"
+// rather than as a string in their "Leaf".
+func (n *Node) MarshalJSON() ([]byte, error) {
+	type plain Node
+	if _, ok := payloadOperator(n.Op); !ok || !json.Valid([]byte(n.Leaf)) {
+		return json.Marshal((*plain)(n))
+	}
+	return json.Marshal(struct {
+		Op      Operator        `json:"Op"`
+		Nodes   []*Node         `json:"Nodes,omitempty"`
+		Payload json.RawMessage `json:"Payload"`
+	}{n.Op, n.Nodes, json.RawMessage(n.Leaf)})
+}
+
+// UnmarshalJSON decodes a node as `json.Unmarshal` otherwise would, accepting
+// the aliases of operators, see `normalizeOperator`, the shorthand
+// `{"Ref": id}` for references, see `NewRefNode`, and the "Payload" of the
+// nodes of a `PayloadOperator`, and replacing `!` nodes by the negation of
+// their child.  Children encoded as null, which would decode as nil nodes,
+// are rejected.
+func (n *Node) UnmarshalJSON(data []byte) error {
+	type plain Node
+	v := struct {
+		*plain
+		Ref     string          `json:"Ref"`
+		Payload json.RawMessage `json:"Payload"`
+	}{plain: (*plain)(n)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Payload != nil {
+		if err := setPayload(n, v.Payload); err != nil {
+			return err
+		}
+	}
+	for i, c := range n.Nodes {
+		if c == nil {
+			return fmt.Errorf("invalid tree: child %d is null", i)
+		}
+	}
+	if v.Ref != "" {
+		if err := expandRef(n, v.Ref); err != nil {
+			return err
+		}
+	}
+	return normalizeNode(n)
+}
+
+// setPayload sets the leaf of the decoded node `n` to the JSON `payload` of
+// a `PayloadOperator`, compacted.  The JSON null is no payload.
+func setPayload(n *Node, payload []byte) error {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, payload); err != nil {
+		return err
+	}
+	if _, ok := payloadOperator(n.Op); !ok {
+		return fmt.Errorf("%w: %s nodes have no payload", ErrInvalidOperator, n.Op)
+	}
+	if n.Leaf != "" {
+		return fmt.Errorf("%w: %s node with both a payload and a leaf", ErrInvalidOperator, n.Op)
+	}
+	if buf.String() != "null" {
+		n.Leaf = buf.String()
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestOperatorAliases(t *testing.T) {
+	for _, tc := range []struct {
+		src      string
+		expected *Node
+	}{
+		{`{"Op": "&&", "Nodes": [{"Op": "leaf", "Leaf": "(.A)"}, {"Op": "LEAF", "Leaf": "(.B)"}]}`,
+			NewNode(OperatorAnd, NewLeafNode(".A"), NewLeafNode(".B"))},
+		{`{"Op": "OR", "Nodes": [{"Op": "leaf", "Leaf": "(.A)"}, {"Op": "And", "Nodes": [{"Op": "leaf", "Leaf": "(.B)"}]}]}`,
+			NewNode(OperatorOr, NewLeafNode(".A"), NewNode(OperatorAnd, NewLeafNode(".B")))},
+		{`{"Op": "||", "Nodes": [{"Op": "!", "Nodes": [{"Op": "leaf", "Leaf": "(eq .A 1)"}]}]}`,
+			NewNode(OperatorOr, NewLeafNode("not (eq .A 1)"))},
+		// Negations are pushed down to the leaves.
+		{`{"Op": "NOT", "Nodes": [{"Op": "and", "Nodes": [{"Op": "leaf", "Leaf": "(not .A)"}, {"Op": "leaf", "Leaf": "(true)"}, {"Op": "leaf", "Leaf": "(.B)"}]}]}`,
+			NewNode(OperatorOr, NewLeafNode(".A"), NewLeafNode("false"), NewLeafNode("not (.B)"))},
+	} {
+		var n Node
+		if err := json.Unmarshal([]byte(tc.src), &n); err != nil || !reflect.DeepEqual(&n, tc.expected) {
+			t.Errorf("json.Unmarshal(%s) expected=%v actual=%v err=%v\n", tc.src, tc.expected, &n, err)
+		}
+		if safe, err := SafeUnmarshal([]byte(tc.src)); err != nil || !reflect.DeepEqual(safe, tc.expected) {
+			t.Errorf("SafeUnmarshal(%s) expected=%v actual=%v err=%v\n", tc.src, tc.expected, safe, err)
+		}
+	}
+
+	for _, src := range []string{
+		`{"Op": "!", "Nodes": []}`,
+		`{"Op": "!", "Nodes": [{"Op": "leaf", "Leaf": "(.A)"}, {"Op": "leaf", "Leaf": "(.B)"}]}`,
+		`{"Op": "!", "Nodes": [{"Op": "advanced", "Leaf": "{{ .A }}"}]}`,
+	} {
+		var n Node
+		if err := json.Unmarshal([]byte(src), &n); !errors.Is(err, ErrInvalidOperator) {
+			t.Errorf("json.Unmarshal(%s) expected=%v actual=%v\n", src, ErrInvalidOperator, err)
+		}
+		if _, err := SafeUnmarshal([]byte(src)); !errors.Is(err, ErrInvalidOperator) {
+			t.Errorf("SafeUnmarshal(%s) expected=%v actual=%v\n", src, ErrInvalidOperator, err)
+		}
+	}
+
+	// Null children would decode as nil nodes.
+	var n Node
+	if err := json.Unmarshal([]byte(`{"Op": "and", "Nodes": [{"Op": "leaf", "Leaf": "(.A)"}, null]}`), &n); err == nil {
+		t.Errorf("json.Unmarshal() expected an error for a null child\n")
+	}
+}
+
+func TestOperatorAliasesEvaluate(t *testing.T) {
+	// Negated leaves which are not parenthesized still evaluate.
+	for _, tc := range []struct {
+		src      string
+		data     map[string]interface{}
+		expected bool
+	}{
+		{`{"Op": "!", "Nodes": [{"Op": "leaf", "Leaf": "eq .A 1"}]}`, map[string]interface{}{"A": 1}, false},
+		{`{"Op": "!", "Nodes": [{"Op": "leaf", "Leaf": "eq .A 1"}]}`, map[string]interface{}{"A": 2}, true},
+		{`{"Op": "!", "Nodes": [{"Op": "leaf", "Leaf": " (eq .A 1) "}]}`, map[string]interface{}{"A": 2}, true},
+		{`{"Op": "!", "Nodes": [{"Op": "and", "Nodes": [{"Op": "leaf", "Leaf": "eq .A 1"}, {"Op": "leaf", "Leaf": "gt .B 2"}]}]}`, map[string]interface{}{"A": 1, "B": 3}, false},
+		{`{"Op": "!", "Nodes": [{"Op": "and", "Nodes": [{"Op": "leaf", "Leaf": "eq .A 1"}, {"Op": "leaf", "Leaf": "gt .B 2"}]}]}`, map[string]interface{}{"A": 1, "B": 2}, true},
+	} {
+		var n Node
+		if err := json.Unmarshal([]byte(tc.src), &n); err != nil {
+			t.Fatalf("json.Unmarshal(%s) error: %s\n", tc.src, err.Error())
+		}
+		ct, err := Compile(&n)
+		if err != nil {
+			t.Fatalf("Compile(%s) error: %s\n", &n, err.Error())
+		}
+		if actual, err := ct.Evaluate(tc.data); err != nil || actual != tc.expected {
+			t.Errorf("Evaluate(%s, %v) expected=%v actual=%v err=%v\n", &n, tc.data, tc.expected, actual, err)
+		}
+	}
+}
+
+func TestOperatorAliasesSexpr(t *testing.T) {
+	for _, tc := range []struct {
+		src      string
+		expected *Node
+	}{
+		{`(&& (eq .A 1) (|| .B .C))`, NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewNode(OperatorOr, NewLeafNode(".B"), NewLeafNode(".C")))},
+		{`(AND (! (OR .B (eq .C 2))))`, NewNode(OperatorAnd, NewNode(OperatorAnd, NewLeafNode("not (.B)"), NewLeafNode("not (eq .C 2)")))},
+		// Lists headed by `not` are leaves.
+		{`(not .B)`, NewLeafNode("not .B")},
+	} {
+		n, err := ParseSexpr(tc.src)
+		if err != nil || !reflect.DeepEqual(n, tc.expected) {
+			t.Errorf("ParseSexpr(%s) expected=%v actual=%v err=%v\n", tc.src, tc.expected, n, err)
+		}
+	}
+	if _, err := ParseSexpr(`(! .A .B)`); err == nil {
+		t.Errorf("ParseSexpr() expected an error for a negation of two nodes\n")
+	}
+}
@@ -0,0 +1,249 @@
+// Package arrowtree evaluates logictree trees column-wise over Apache Arrow
+// record batches.
+//
+// Columns are looked up by the fields a tree references, so `.Price` reads
+// the column "Price" and `.Order.Total` reads the field "Total" of the struct
+// column "Order" (or a top level column named "Order.Total").  Integer,
+// floating point, string, boolean and dictionary encoded columns of those
+// types are handed to `(*logictree.CompiledTree).EvaluateBatch` as typed
+// slices, so that comparisons against literals run over the whole column.
+// Timestamp and date columns are converted to `time.Time` values, and
+// `uint64` columns to their values, both of which are evaluated row by row.
+// Nulls, including those of enclosing struct columns, are left out of the
+// data, so that `logictree.WithMissing` applies to them.  Leaves which read
+// the whole row, such as `len .`, are given every column.
+package arrowtree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Evaluate executes `ct` against every row of `rec` and returns the selection
+// bitmap of the matching rows.
+func Evaluate(ct *logictree.CompiledTree, rec arrow.RecordBatch) (*logictree.Bitmap, error) {
+	return ct.EvaluateBatch(NewBatch(rec))
+}
+
+// NewBatch adapts `rec` to a `logictree.Batch`.  Columns are converted as
+// they are first requested.
+func NewBatch(rec arrow.RecordBatch) logictree.Batch {
+	return &batch{rec: rec}
+}
+
+type batch struct {
+	rec arrow.RecordBatch
+}
+
+func (b *batch) Len() int {
+	return int(b.rec.NumRows())
+}
+
+// Column returns the column named `name`, preferring a top level column of
+// that name and otherwise resolving each dot separated part through struct
+// columns.
+func (b *batch) Column(name string) (*logictree.Column, error) {
+	if is := b.rec.Schema().FieldIndices(name); len(is) > 0 {
+		return column(name, b.rec.Column(is[0]), nil)
+	}
+
+	parts := strings.Split(name, ".")
+	is := b.rec.Schema().FieldIndices(parts[0])
+	if len(is) == 0 {
+		return nil, nil
+	}
+	arr := b.rec.Column(is[0])
+	var null []bool
+	for _, p := range parts[1:] {
+		s, ok := arr.(*array.Struct)
+		if !ok {
+			return nil, nil
+		}
+		i, ok := s.DataType().(*arrow.StructType).FieldIdx(p)
+		if !ok {
+			return nil, nil
+		}
+		null = nulls(s, null)
+		arr = s.Field(i)
+	}
+	return column(name, arr, null)
+}
+
+// ColumnNames returns the names of the columns of the record, with the
+// fields of struct columns named as "Store.City" in place of the struct.
+func (b *batch) ColumnNames() []string {
+	names := []string{}
+	var add func(prefix string, f arrow.Field)
+	add = func(prefix string, f arrow.Field) {
+		st, ok := f.Type.(*arrow.StructType)
+		if !ok {
+			names = append(names, prefix+f.Name)
+			return
+		}
+		for _, sf := range st.Fields() {
+			add(prefix+f.Name+".", sf)
+		}
+	}
+	for _, f := range b.rec.Schema().Fields() {
+		add("", f)
+	}
+	return names
+}
+
+// nulls returns `null` with the null rows of `arr` also set.
+func nulls(arr arrow.Array, null []bool) []bool {
+	if arr.NullN() == 0 {
+		return null
+	}
+	if null == nil {
+		null = make([]bool, arr.Len())
+	}
+	for i := range null {
+		null[i] = null[i] || arr.IsNull(i)
+	}
+	return null
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// column converts `arr` into a column, with the rows set in `null` (which may
+// be nil) also null.
+func column(name string, arr arrow.Array, null []bool) (*logictree.Column, error) {
+	c, err := values(name, arr)
+	if err != nil {
+		return nil, err
+	}
+	c.Null = nulls(arr, null)
+	return c, nil
+}
+
+// values converts the values of `arr`, ignoring nulls.
+func values(name string, arr arrow.Array) (*logictree.Column, error) {
+	c := &logictree.Column{}
+	n := arr.Len()
+
+	switch a := arr.(type) {
+	case *array.Int64:
+		c.Int64 = a.Int64Values()
+	case *array.Int32:
+		c.Int64 = widen(a.Int32Values())
+	case *array.Int16:
+		c.Int64 = widen(a.Int16Values())
+	case *array.Int8:
+		c.Int64 = widen(a.Int8Values())
+	case *array.Uint32:
+		c.Int64 = widen(a.Uint32Values())
+	case *array.Uint16:
+		c.Int64 = widen(a.Uint16Values())
+	case *array.Uint8:
+		c.Int64 = widen(a.Uint8Values())
+	case *array.Uint64:
+		c.Values = make([]interface{}, n)
+		for i, v := range a.Uint64Values() {
+			c.Values[i] = v
+		}
+	case *array.Float64:
+		c.Float64 = a.Float64Values()
+	case *array.Float32:
+		c.Float64 = make([]float64, n)
+		for i, v := range a.Float32Values() {
+			c.Float64[i] = float64(v)
+		}
+	case *array.String, *array.LargeString, *array.StringView:
+		s := a.(interface{ Value(int) string })
+		c.String = make([]string, n)
+		for i := range c.String {
+			c.String[i] = s.Value(i)
+		}
+	case *array.Boolean:
+		c.Bool = make([]bool, n)
+		for i := range c.Bool {
+			c.Bool[i] = a.Value(i)
+		}
+	case *array.Timestamp:
+		toTime, err := a.DataType().(*arrow.TimestampType).GetToTimeFunc()
+		if err != nil {
+			return nil, fmt.Errorf("arrowtree: column %q: %w", name, err)
+		}
+		c.Values = make([]interface{}, n)
+		for i, v := range a.TimestampValues() {
+			c.Values[i] = toTime(v)
+		}
+	case *array.Date32:
+		c.Values = make([]interface{}, n)
+		for i, v := range a.Date32Values() {
+			c.Values[i] = v.ToTime()
+		}
+	case *array.Date64:
+		c.Values = make([]interface{}, n)
+		for i, v := range a.Date64Values() {
+			c.Values[i] = v.ToTime()
+		}
+	case *array.Dictionary:
+		dict, err := values(name, a.Dictionary())
+		if err != nil {
+			return nil, err
+		}
+		c = gather(dict, a)
+	default:
+		return nil, fmt.Errorf("arrowtree: column %q: unsupported type %s", name, arr.DataType())
+	}
+	return c, nil
+}
+
+// widen converts integer values to int64.
+func widen[T int8 | int16 | int32 | uint8 | uint16 | uint32](vs []T) []int64 {
+	is := make([]int64, len(vs))
+	for i, v := range vs {
+		is[i] = int64(v)
+	}
+	return is
+}
+
+// gather returns the values of the dictionary column `dict` indexed by each
+// row of `a`.  Null rows are given the zero value.
+func gather(dict *logictree.Column, a *array.Dictionary) *logictree.Column {
+	c := &logictree.Column{}
+	n := a.Len()
+	switch {
+	case dict.Int64 != nil:
+		c.Int64 = make([]int64, n)
+	case dict.Float64 != nil:
+		c.Float64 = make([]float64, n)
+	case dict.String != nil:
+		c.String = make([]string, n)
+	case dict.Bool != nil:
+		c.Bool = make([]bool, n)
+	default:
+		c.Values = make([]interface{}, n)
+	}
+
+	for i := 0; i < n; i++ {
+		if a.IsNull(i) {
+			continue
+		}
+		j := a.GetValueIndex(i)
+		switch {
+		case c.Int64 != nil:
+			c.Int64[i] = dict.Int64[j]
+		case c.Float64 != nil:
+			c.Float64[i] = dict.Float64[j]
+		case c.String != nil:
+			c.String[i] = dict.String[j]
+		case c.Bool != nil:
+			c.Bool[i] = dict.Bool[j]
+		default:
+			c.Values[i] = dict.Values[j]
+		}
+	}
+	return c
+}
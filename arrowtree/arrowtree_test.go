@@ -0,0 +1,134 @@
+package arrowtree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func testRecord(t *testing.T) arrow.RecordBatch {
+	mem := memory.NewGoAllocator()
+	store := arrow.StructOf(arrow.Field{Name: "City", Type: arrow.BinaryTypes.String})
+	dict := &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int8, ValueType: arrow.BinaryTypes.String}
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "Price", Type: arrow.PrimitiveTypes.Int32, Nullable: true},
+		{Name: "Weight", Type: arrow.PrimitiveTypes.Float64},
+		{Name: "Name", Type: arrow.BinaryTypes.String},
+		{Name: "Fresh", Type: arrow.FixedWidthTypes.Boolean},
+		{Name: "Store", Type: store, Nullable: true},
+		{Name: "Aisle", Type: dict},
+		{Name: "Stocked", Type: &arrow.TimestampType{Unit: arrow.Second, TimeZone: "UTC"}},
+	}, nil)
+
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+
+	b.Field(0).(*array.Int32Builder).AppendValues([]int32{1, 5, 10, 0}, []bool{true, true, true, false})
+	b.Field(1).(*array.Float64Builder).AppendValues([]float64{0.5, 2, 3.5, 1}, nil)
+	b.Field(2).(*array.StringBuilder).AppendValues([]string{"milk", "onions", "toothpaste", "bread"}, nil)
+	b.Field(3).(*array.BooleanBuilder).AppendValues([]bool{true, false, true, true}, nil)
+
+	sb := b.Field(4).(*array.StructBuilder)
+	city := sb.FieldBuilder(0).(*array.StringBuilder)
+	for _, c := range []string{"SF", "NY", "", "SF"} {
+		if c == "" {
+			sb.AppendNull() // also appends a null to every field
+			continue
+		}
+		sb.Append(true)
+		city.Append(c)
+	}
+
+	db := b.Field(5).(*array.BinaryDictionaryBuilder)
+	for _, a := range []string{"dairy", "produce", "health", "dairy"} {
+		if err := db.AppendString(a); err != nil {
+			t.Fatalf("AppendString() error: %s\n", err.Error())
+		}
+	}
+
+	b.Field(6).(*array.TimestampBuilder).AppendValues([]arrow.Timestamp{1700000000, 1700086400, 1700172800, 1700259200}, nil)
+
+	return b.NewRecordBatch()
+}
+
+func TestEvaluate(t *testing.T) {
+	rec := testRecord(t)
+	defer rec.Release()
+
+	for _, tc := range []struct {
+		leaf     string
+		expected []int
+	}{
+		{"ge .Price 5", []int{1, 2}},
+		{"gt .Weight 1", []int{1, 2}},
+		{`hasPrefix .Name "o"`, []int{1}},
+		{"eq .Fresh true", []int{0, 2, 3}},
+		{`eq .Store.City "SF"`, []int{0, 3}},
+		{`in .Aisle ["dairy", "health"]`, []int{0, 2, 3}},
+		{`after .Stocked "2023-11-15T00:00:00Z"`, []int{1, 2, 3}},
+		{"eq .Nothing 1", []int{}},
+	} {
+		ct, err := logictree.Compile(logictree.NewLeafNode(tc.leaf),
+			logictree.WithFuncs(logictree.StdFuncs()),
+			logictree.WithMissing(logictree.MissingIsFalse))
+		if err != nil {
+			t.Fatalf("Compile(%q) error: %s\n", tc.leaf, err.Error())
+		}
+		bm, err := Evaluate(ct, rec)
+		if err != nil {
+			t.Errorf("Evaluate(%q) error: %s\n", tc.leaf, err.Error())
+			continue
+		}
+		if actual := bm.Indices(); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("Evaluate(%q) expected=%v actual=%v\n", tc.leaf, tc.expected, actual)
+		}
+	}
+}
+
+func TestUnsupportedColumn(t *testing.T) {
+	mem := memory.NewGoAllocator()
+	schema := arrow.NewSchema([]arrow.Field{
+		{Name: "Tags", Type: arrow.ListOf(arrow.BinaryTypes.String)},
+	}, nil)
+	b := array.NewRecordBuilder(mem, schema)
+	defer b.Release()
+	b.Field(0).(*array.ListBuilder).AppendNull()
+	rec := b.NewRecordBatch()
+	defer rec.Release()
+
+	ct, err := logictree.Compile(logictree.NewLeafNode(`in "a" .Tags`), logictree.WithFuncs(logictree.StdFuncs()))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if _, err := Evaluate(ct, rec); err == nil {
+		t.Errorf("Evaluate() expected an error for a list column\n")
+	}
+}
+
+func TestEvaluateWholeRow(t *testing.T) {
+	rec := testRecord(t)
+	defer rec.Release()
+
+	ct, err := logictree.Compile(logictree.NewLeafNode(`eq (len .) 7`),
+		logictree.WithFuncs(logictree.StdFuncs()))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	// Rows 2 and 3 have no store and no price.
+	bm, err := Evaluate(ct, rec)
+	if err != nil {
+		t.Fatalf("Evaluate() error: %s\n", err.Error())
+	}
+	if actual, expected := bm.Indices(), []int{0, 1}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Evaluate() expected=%v actual=%v\n", expected, actual)
+	}
+}
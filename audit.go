@@ -0,0 +1,203 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// DecisionRecord is the audit record of a single decision made by a tree,
+// see `Auditor`: when it was made, by which tree, on which data, with which
+// result and why.  Records are chained by their digests, so that a record
+// which is altered, removed or reordered is found by `VerifyRecords`.
+type DecisionRecord struct {
+	Time        time.Time `json:"Time"`
+	Fingerprint string    `json:"Fingerprint"` // of the tree, see `Fingerprint`
+	Version     string    `json:"Version,omitempty"`
+
+	// Input is the hex encoded SHA-256 of the JSON of the data, and Data
+	// that JSON if the auditor keeps snapshots.
+	Input string          `json:"Input"`
+	Data  json.RawMessage `json:"Data,omitempty"`
+
+	Result bool          `json:"Result"`
+	Error  string        `json:"Error,omitempty"` // if the evaluation failed
+	Leaves []LeafOutcome `json:"Leaves"`
+
+	// Previous is the digest of the record made before this one by the same
+	// auditor, empty for its first, and Digest the hex encoded SHA-256 of the
+	// JSON of this record without its digest.
+	Previous string `json:"Previous"`
+	Digest   string `json:"Digest"`
+}
+
+// LeafOutcome is the outcome of a leaf of a `DecisionRecord`.  `Error` is set,
+// and `Result` false, for a leaf which failed after `Evaluate` would have
+// stopped, as for `ExplainedNode`.
+type LeafOutcome struct {
+	Path   string `json:"Path"`
+	Leaf   string `json:"Leaf"`
+	Output string `json:"Output,omitempty"`
+	Error  string `json:"Error,omitempty"`
+	Result bool   `json:"Result"`
+}
+
+// AuditOptions configures an `Auditor`.
+type AuditOptions struct {
+	// Version is the version of the tree recorded, such as that of the
+	// document it was loaded from.
+	Version string
+
+	// Snapshot records the data of every decision, rather than its digest
+	// alone.
+	Snapshot bool
+
+	// Previous is the digest of the last record made before the auditor,
+	// for chains which span restarts.
+	Previous string
+
+	// Now returns the time of a decision, `time.Now` if nil.
+	Now func() time.Time
+
+	// Sink, if set, is written every record as it is made, in the order of
+	// the chain.  Backends which may be slow are wrapped by
+	// `NewBoundedAuditSink`, so that they cannot stall evaluation.
+	Sink AuditSink
+}
+
+// AuditSink is written the records of an `Auditor`, such as a log, a queue or
+// a database, see `AuditOptions.Sink`.
+type AuditSink interface {
+	WriteRecord(r *DecisionRecord) error
+}
+
+// Auditor evaluates a tree, recording every decision it makes as a
+// `DecisionRecord`, for compliance with rules requiring the reason for each
+// automated decision to be kept.  It is safe for concurrent use; the records
+// are chained in the order the decisions are made.
+type Auditor struct {
+	ct   *CompiledTree
+	opts AuditOptions
+
+	fingerprint string
+
+	mu   sync.Mutex
+	last string // the digest of the last record
+}
+
+// NewAuditor returns an auditor of the tree configured by `o`.
+func (ct *CompiledTree) NewAuditor(o AuditOptions) *Auditor {
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	fingerprint := ct.fingerprint
+	if fingerprint == "" {
+		fingerprint = ct.root.Fingerprint()
+	}
+	return &Auditor{ct: ct, opts: o, fingerprint: fingerprint, last: o.Previous}
+}
+
+// Evaluate evaluates the tree against `data` as `ExplainContext` does,
+// returning the record of the decision.  The outcomes of every leaf are
+// recorded, including those `Evaluate` would not have evaluated.  An
+// evaluation which fails is recorded too, with its error, which is also
+// returned.  Failing to write the record to the sink of the auditor fails
+// with that error, joined with that of the evaluation, if any.
+func (a *Auditor) Evaluate(ctx context.Context, data interface{}) (*DecisionRecord, error) {
+	r := &DecisionRecord{
+		Fingerprint: a.fingerprint,
+		Version:     a.opts.Version,
+		Leaves:      []LeafOutcome{},
+	}
+	bs, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot record data: %w", err)
+	}
+	r.Input = hexDigest(bs)
+	if a.opts.Snapshot {
+		r.Data = bs
+	}
+
+	x, xerr := a.ct.ExplainContext(ctx, data)
+	if xerr != nil {
+		r.Error = xerr.Error()
+	} else {
+		r.Result = x.Result
+		r.Leaves = leafOutcomes(x.Root, r.Leaves)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	r.Time = a.opts.Now().UTC().Round(0)
+	r.Previous = a.last
+	if r.Digest, err = r.digest(); err != nil {
+		return nil, err
+	}
+	a.last = r.Digest
+	if a.opts.Sink != nil {
+		if err := a.opts.Sink.WriteRecord(r); err != nil {
+			return r, errors.Join(xerr, fmt.Errorf("cannot write record: %w", err))
+		}
+	}
+	return r, xerr
+}
+
+// leafOutcomes appends the outcomes of the leaves of `en` to `out`, in order.
+func leafOutcomes(en *ExplainedNode, out []LeafOutcome) []LeafOutcome {
+	if (&Node{Op: en.Op}).isLeaf() {
+		return append(out, LeafOutcome{Path: en.Path, Leaf: en.Leaf, Output: en.Output, Error: en.Error, Result: en.Result})
+	}
+	for _, c := range en.Nodes {
+		out = leafOutcomes(c, out)
+	}
+	return out
+}
+
+// digest returns the hex encoded SHA-256 of the JSON of the record without
+// its digest.
+func (r *DecisionRecord) digest() (string, error) {
+	c := *r
+	c.Digest = ""
+	bs, err := json.Marshal(&c)
+	if err != nil {
+		return "", err
+	}
+	return hexDigest(bs), nil
+}
+
+// hexDigest returns the hex encoded SHA-256 of `bs`.
+func hexDigest(bs []byte) string {
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyRecords checks that `records`, in the order they were made by an
+// auditor, are untampered: that the digest of every record is that of its
+// content, and follows the digest of the record before it.  The first
+// record may follow any digest, so that a chain can be verified in parts.
+// It returns an error wrapping `ErrTampered` for the first record which is
+// not.
+func VerifyRecords(records []*DecisionRecord) error {
+	for i, r := range records {
+		d, err := r.digest()
+		if err != nil {
+			return err
+		}
+		if d != r.Digest {
+			return fmt.Errorf("%w: record %d does not match its digest", ErrTampered, i)
+		}
+		if i > 0 && r.Previous != records[i-1].Digest {
+			return fmt.Errorf("%w: record %d does not follow record %d", ErrTampered, i, i-1)
+		}
+	}
+	return nil
+}
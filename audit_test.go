@@ -0,0 +1,103 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestAuditor(t *testing.T) {
+	tree := NewNode(OperatorOr, NewLeafNode("eq .A 1"), NewLeafNode("eq .B 1"))
+	ct, err := Compile(tree, WithMissing(MissingIsError))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	a := ct.NewAuditor(AuditOptions{
+		Version:  "v3",
+		Snapshot: true,
+		Now:      func() time.Time { return now },
+	})
+
+	var records []*DecisionRecord
+	for _, data := range []interface{}{
+		map[string]interface{}{"A": 1, "B": 2},
+		map[string]interface{}{"A": 2, "B": 2},
+	} {
+		r, err := a.Evaluate(context.Background(), data)
+		if err != nil {
+			t.Fatalf("Evaluate() error: %s\n", err.Error())
+		}
+		records = append(records, r)
+	}
+
+	r := records[0]
+	leaves := []LeafOutcome{
+		{Path: "/0", Leaf: "(eq .A 1)", Output: "true", Result: true},
+		{Path: "/1", Leaf: "(eq .B 1)", Output: "false"},
+	}
+	if !r.Time.Equal(now) || r.Fingerprint != tree.Fingerprint() || r.Version != "v3" || !r.Result || r.Previous != "" || string(r.Data) != `{"A":1,"B":2}` {
+		t.Errorf("Evaluate() expected a true record, got %+v\n", r)
+	}
+	if !reflect.DeepEqual(r.Leaves, leaves) {
+		t.Errorf("Evaluate() expected=%+v actual=%+v\n", leaves, r.Leaves)
+	}
+	if records[1].Result || records[1].Previous != r.Digest || records[1].Input == r.Input {
+		t.Errorf("Evaluate() expected a false record following %s, got %+v\n", r.Digest, records[1])
+	}
+
+	// Failed evaluations are recorded too.
+	failed, err := a.Evaluate(context.Background(), map[string]interface{}{"A": 2})
+	if !errors.Is(err, ErrMissingField) || failed == nil || failed.Error == "" || failed.Previous != records[1].Digest {
+		t.Errorf("Evaluate() expected a failed record, got %+v %v\n", failed, err)
+	}
+	records = append(records, failed)
+
+	// Records verify after a round trip through JSON.
+	bs, err := json.Marshal(records)
+	if err != nil {
+		t.Fatalf("Marshal() error: %s\n", err.Error())
+	}
+	var decoded []*DecisionRecord
+	if err := json.Unmarshal(bs, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error: %s\n", err.Error())
+	}
+	if err := VerifyRecords(decoded); err != nil {
+		t.Errorf("VerifyRecords() expected=nil actual=%v\n", err)
+	}
+	if err := VerifyRecords(decoded[1:]); err != nil {
+		t.Errorf("VerifyRecords() expected=nil actual=%v\n", err)
+	}
+
+	// Altered, removed and reordered records are found.
+	decoded[1].Result = true
+	if err := VerifyRecords(decoded); !errors.Is(err, ErrTampered) {
+		t.Errorf("VerifyRecords() expected=%v actual=%v\n", ErrTampered, err)
+	}
+	for _, rs := range [][]*DecisionRecord{
+		{records[0], records[2]},
+		{records[1], records[0]},
+	} {
+		if err := VerifyRecords(rs); !errors.Is(err, ErrTampered) {
+			t.Errorf("VerifyRecords() expected=%v actual=%v\n", ErrTampered, err)
+		}
+	}
+
+	// Auditors without snapshots record the digest of the data alone, and
+	// may continue the chain of another.
+	b := ct.NewAuditor(AuditOptions{Previous: failed.Digest})
+	next, err := b.Evaluate(context.Background(), map[string]interface{}{"A": 1, "B": 2})
+	if err != nil {
+		t.Fatalf("Evaluate() error: %s\n", err.Error())
+	}
+	if next.Data != nil || next.Input != r.Input || VerifyRecords(append(records, next)) != nil {
+		t.Errorf("Evaluate() expected a record following %s, got %+v\n", failed.Digest, next)
+	}
+}
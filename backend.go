@@ -0,0 +1,85 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strconv"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// EvalBackend compiles and evaluates the expressions held by ordinary leaves,
+// so that leaves may be written in a language other than text/template (such
+// as cel-go, expr-lang or govaluate) without changing how trees are built,
+// combined or serialized.  Trees are compiled with the text/template backend
+// unless `WithBackend` is given.
+//
+// A backend must be safe for concurrent use: a compiled tree may be evaluated
+// from many goroutines at once, with the same compiled leaf.
+type EvalBackend interface {
+	// CompileLeaf compiles the expression of a leaf, `Node.Leaf`, returning a
+	// program which is later passed to `Evaluate`.  Leaves built with
+	// `NewLeafNode` hold their expression in parentheses.
+	CompileLeaf(leaf string) (interface{}, error)
+
+	// Evaluate executes a program returned by `CompileLeaf` against `data`.
+	Evaluate(prog interface{}, data interface{}) (bool, error)
+}
+
+// FieldBackend is implemented by backends which can report the fields of the
+// data a compiled leaf references, such as ["Dairy", "Milk"] for
+// `Dairy.Milk`.  Those fields are what `WithMissing`, `Fields`,
+// `WithIncremental` and `EvaluateBatch` consult; the leaves of a backend
+// which does not implement it reference no fields.
+type FieldBackend interface {
+	EvalBackend
+
+	// Fields returns the fields referenced by a program returned by
+	// `CompileLeaf`.
+	Fields(prog interface{}) [][]string
+}
+
+// WithBackend compiles and evaluates the ordinary leaves of the tree with
+// `b` rather than as templates.  Leaves are then not validated as template
+// expressions, `WithFuncs` does not apply to them, and comparisons are never
+// evaluated column-wise by `EvaluateBatch`.  Advanced leaves are always
+// templates.  A nil backend restores the default.
+func WithBackend(b EvalBackend) Option {
+	return func(o *compileOptions) {
+		o.backend = b
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// compileBackendLeaf compiles the leaf of `cn` with the backend of the tree.
+func (c *compiler) compileBackendLeaf(cn *compiledNode) error {
+	prog, err := c.opts.backend.CompileLeaf(cn.node.Leaf)
+	if err != nil {
+		return err
+	}
+	cn.backend = c.opts.backend
+	cn.prog = prog
+	if fb, ok := c.opts.backend.(FieldBackend); ok {
+		cn.fields = fb.Fields(prog)
+	}
+	return nil
+}
+
+// execLeaf executes the leaf against `data`, returning its output.  Leaves
+// compiled by a backend output their result as "true" or "false".
+func (cn *compiledNode) execLeaf(data interface{}) (string, error) {
+	if cn.backend == nil {
+		if cn.cmp != nil {
+			if v, ok := cn.compareRow(data); ok {
+				return strconv.FormatBool(v), nil
+			}
+		}
+		return executeLeaf(cn.tmpl, data)
+	}
+	v, err := cn.backend.Evaluate(cn.prog, data)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatBool(v), nil
+}
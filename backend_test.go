@@ -0,0 +1,131 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// infixBackend evaluates leaves of the form `Field > 5`, comparing an integer
+// field of a `map[string]interface{}` against an integer literal.
+type infixBackend struct{}
+
+type infixProgram struct {
+	field string
+	op    string
+	value int
+}
+
+func (infixBackend) CompileLeaf(leaf string) (interface{}, error) {
+	parts := strings.Fields(strings.TrimSuffix(strings.TrimPrefix(leaf, "("), ")"))
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("expected `field op value`, got %q", leaf)
+	}
+	v, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	switch parts[1] {
+	case "<", "==", ">":
+	default:
+		return nil, fmt.Errorf("unknown operator %q", parts[1])
+	}
+	return &infixProgram{parts[0], parts[1], v}, nil
+}
+
+func (infixBackend) Evaluate(prog interface{}, data interface{}) (bool, error) {
+	p := prog.(*infixProgram)
+	v, ok := data.(map[string]interface{})[p.field].(int)
+	if !ok {
+		return false, errors.New("not an integer: " + p.field)
+	}
+	switch p.op {
+	case "<":
+		return v < p.value, nil
+	case "==":
+		return v == p.value, nil
+	}
+	return v > p.value, nil
+}
+
+// infixFieldBackend also reports the field referenced by each leaf.
+type infixFieldBackend struct {
+	infixBackend
+}
+
+func (infixFieldBackend) Fields(prog interface{}) [][]string {
+	return [][]string{{prog.(*infixProgram).field}}
+}
+
+func TestBackend(t *testing.T) {
+	n := NewNode(OperatorOr,
+		NewLeafNode("Milk > 4"),
+		NewNode(OperatorAnd,
+			NewLeafNode("Eggs == 12"),
+			NewAdvancedLeafNode("{{ lt .Milk 2 }}")))
+
+	ct, err := Compile(n, WithBackend(infixBackend{}))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	for _, tc := range []struct {
+		data     map[string]interface{}
+		expected bool
+	}{
+		{map[string]interface{}{"Milk": 5, "Eggs": 0}, true},
+		{map[string]interface{}{"Milk": 1, "Eggs": 12}, true},
+		{map[string]interface{}{"Milk": 3, "Eggs": 12}, false},
+	} {
+		if v, err := ct.Evaluate(tc.data); err != nil || v != tc.expected {
+			t.Errorf("Evaluate(%v) expected=%v actual=%v (%v)\n", tc.data, tc.expected, v, err)
+		}
+	}
+	if _, err := ct.Evaluate(map[string]interface{}{"Milk": "5"}); err == nil {
+		t.Errorf("Evaluate() expected an error from the backend\n")
+	}
+	if fields := ct.Fields(); !reflect.DeepEqual(fields, [][]string{{"Milk"}}) {
+		t.Errorf("Fields() expected=%v actual=%v\n", [][]string{{"Milk"}}, fields)
+	}
+
+	if err := n.Validate(); err == nil {
+		t.Errorf("Validate() expected the infix leaves to be invalid templates\n")
+	}
+	if _, err := Compile(NewNode(OperatorAnd, NewLeafNode("Milk > 4"), NewLeafNode("Milk >")), WithBackend(infixBackend{})); err == nil || !strings.HasPrefix(err.Error(), "/1: ") {
+		t.Errorf("Compile() expected an error at /1, got: %v\n", err)
+	}
+	if _, err := Compile(NewNode(OperatorAnd), WithBackend(infixBackend{})); !errors.Is(err, ErrEmptyNode) {
+		t.Errorf("Compile() expected=%v actual=%v\n", ErrEmptyNode, err)
+	}
+}
+
+func TestFieldBackend(t *testing.T) {
+	n := NewNode(OperatorAnd, NewLeafNode("Milk > 4"), NewLeafNode("Eggs == 12"))
+	ct, err := Compile(n, WithBackend(infixFieldBackend{}), WithMissing(MissingIsFalse))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if fields := ct.Fields(); !reflect.DeepEqual(fields, [][]string{{"Milk"}, {"Eggs"}}) {
+		t.Errorf("Fields() expected=%v actual=%v\n", [][]string{{"Milk"}, {"Eggs"}}, fields)
+	}
+	if v, err := ct.Evaluate(map[string]interface{}{"Milk": 5}); err != nil || v {
+		t.Errorf("Evaluate() expected=false actual=%v (%v)\n", v, err)
+	}
+
+	b, err := ct.EvaluateBatch(Columns{
+		"Milk": {Values: []interface{}{5, 1, 9}},
+		"Eggs": {Values: []interface{}{12, 12, 6}},
+	})
+	if err != nil {
+		t.Fatalf("EvaluateBatch() error: %s\n", err.Error())
+	}
+	if actual := b.Indices(); !reflect.DeepEqual(actual, []int{0}) {
+		t.Errorf("EvaluateBatch() expected=%v actual=%v\n", []int{0}, actual)
+	}
+}
@@ -0,0 +1,572 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Batch is a set of rows stored column by column, such as an Apache Arrow
+// record batch.  Columns are named by the field they hold, with nested fields
+// joined by dots, so the column for `.Order.Total` is named "Order.Total".
+type Batch interface {
+	// Len returns the number of rows in the batch.
+	Len() int
+
+	// Column returns the named column, or nil if the batch has no such
+	// column.
+	Column(name string) (*Column, error)
+}
+
+// ColumnNamer is implemented by batches which can list their columns.  Leaves
+// which read the data other than through its fields, such as
+// `eq (index . "Name") "milk"` or `len .`, are evaluated against rows holding
+// every column, so they can only be evaluated over such batches.
+type ColumnNamer interface {
+	// ColumnNames returns the names of every column of the batch.
+	ColumnNames() []string
+}
+
+// Column holds the value of a single field for every row of a `Batch`.
+// Exactly one of the value slices is set.  Comparisons of a field against
+// literals are evaluated over `Int64`, `Float64`, `String` and `Bool` columns
+// directly, `Values` holds values of any other type and is evaluated row by
+// row.
+type Column struct {
+	Int64   []int64
+	Float64 []float64
+	String  []string
+	Bool    []bool
+	Values  []interface{}
+
+	// Null marks the rows which have no value, it may be nil if every row has
+	// one.  Null values are left out of the data, so `WithMissing` applies.
+	Null []bool
+}
+
+// Len returns the number of rows in the column.
+func (c *Column) Len() int {
+	switch {
+	case c.Int64 != nil:
+		return len(c.Int64)
+	case c.Float64 != nil:
+		return len(c.Float64)
+	case c.String != nil:
+		return len(c.String)
+	case c.Bool != nil:
+		return len(c.Bool)
+	}
+	return len(c.Values)
+}
+
+func (c *Column) null(i int) bool {
+	return c.Null != nil && c.Null[i]
+}
+
+func (c *Column) value(i int) interface{} {
+	switch {
+	case c.Int64 != nil:
+		return c.Int64[i]
+	case c.Float64 != nil:
+		return c.Float64[i]
+	case c.String != nil:
+		return c.String[i]
+	case c.Bool != nil:
+		return c.Bool[i]
+	}
+	return c.Values[i]
+}
+
+// Columns is a `Batch` of in memory columns keyed by name.
+type Columns map[string]*Column
+
+// Len returns the number of rows in the first column, zero if there are none.
+func (cs Columns) Len() int {
+	for _, c := range cs {
+		return c.Len()
+	}
+	return 0
+}
+
+// Column returns the named column.
+func (cs Columns) Column(name string) (*Column, error) {
+	return cs[name], nil
+}
+
+// ColumnNames returns the names of the columns, sorted.
+func (cs Columns) ColumnNames() []string {
+	names := make([]string, 0, len(cs))
+	for name := range cs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Bitmap is a fixed length set of bits, one per row of a `Batch`.
+type Bitmap struct {
+	words []uint64
+	n     int
+}
+
+// NewBitmap returns a bitmap of `n` bits, all unset.
+func NewBitmap(n int) *Bitmap {
+	return &Bitmap{words: make([]uint64, (n+63)/64), n: n}
+}
+
+// fullBitmap returns a bitmap of `n` bits, all set.
+func fullBitmap(n int) *Bitmap {
+	b := NewBitmap(n)
+	for i := range b.words {
+		b.words[i] = math.MaxUint64
+	}
+	b.trim()
+	return b
+}
+
+// trim clears the bits past the end of the bitmap.
+func (b *Bitmap) trim() {
+	if r := b.n % 64; r != 0 {
+		b.words[len(b.words)-1] &= 1<<r - 1
+	}
+}
+
+// Len returns the number of bits in the bitmap.
+func (b *Bitmap) Len() int {
+	return b.n
+}
+
+// Get reports whether bit `i` is set.
+func (b *Bitmap) Get(i int) bool {
+	return b.words[i/64]&(1<<(i%64)) != 0
+}
+
+// Set sets bit `i`.
+func (b *Bitmap) Set(i int) {
+	b.words[i/64] |= 1 << (i % 64)
+}
+
+// Count returns the number of bits set.
+func (b *Bitmap) Count() int {
+	n := 0
+	for _, w := range b.words {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// Indices returns the indices of the bits set, in ascending order.
+func (b *Bitmap) Indices() []int {
+	is := make([]int, 0, b.Count())
+	b.each(func(i int) {
+		is = append(is, i)
+	})
+	return is
+}
+
+// each calls `fn` with the index of every bit set, in ascending order.
+func (b *Bitmap) each(fn func(i int)) {
+	for wi, w := range b.words {
+		for w != 0 {
+			fn(wi*64 + bits.TrailingZeros64(w))
+			w &= w - 1
+		}
+	}
+}
+
+func (b *Bitmap) empty() bool {
+	for _, w := range b.words {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *Bitmap) clone() *Bitmap {
+	return &Bitmap{words: append([]uint64(nil), b.words...), n: b.n}
+}
+
+func (b *Bitmap) and(o *Bitmap) {
+	for i := range b.words {
+		b.words[i] &= o.words[i]
+	}
+}
+
+func (b *Bitmap) or(o *Bitmap) {
+	for i := range b.words {
+		b.words[i] |= o.words[i]
+	}
+}
+
+func (b *Bitmap) andNot(o *Bitmap) {
+	for i := range b.words {
+		b.words[i] &^= o.words[i]
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// EvaluateBatch evaluates the compiled tree against every row of `b` and
+// returns a bitmap with the bits of the matching rows set.
+//
+// Leaves which compare a single field against literals using the standard
+// comparisons (`eq`, `ne`, `lt`, `le`, `gt`, `ge`, `between`, and `oneOf` or
+// `in` with a list literal, if provided by `StdFuncs`) are evaluated over the
+// whole column at once.  Every other leaf, and rows whose value is null or
+// NaN, are evaluated row by row against a map holding the fields the leaf
+// references, giving the same result as `Evaluate` would for that row.
+// Leaves which read the data other than through its fields are evaluated
+// against a map holding every column, and fail unless the batch is a
+// `ColumnNamer`.  The
+// results of children are combined a bitmap at a time and children of an
+// `and` or `or` are only evaluated for the rows which are still undecided.
+//
+// Errors are wrapped with the index of the row which failed.
+func (ct *CompiledTree) EvaluateBatch(b Batch) (*Bitmap, error) {
+	n := b.Len()
+	bs := &batchState{
+		batch:   b,
+		n:       n,
+		columns: map[string]*Column{},
+		st:      &evalState{ctx: context.Background(), stop: context.Background(), missing: ct.opts.missing},
+	}
+	return ct.eval.evaluateBatch(bs, fullBitmap(n))
+}
+
+// batchState is shared by every node visited during a single batch
+// evaluation.
+type batchState struct {
+	batch   Batch
+	n       int
+	columns map[string]*Column // columns fetched so far, nil if absent
+	st      *evalState         // used to evaluate leaves row by row
+}
+
+// column returns the named column, or nil if the batch has none.
+func (bs *batchState) column(name string) (*Column, error) {
+	if c, ok := bs.columns[name]; ok {
+		return c, nil
+	}
+	c, err := bs.batch.Column(name)
+	if err != nil {
+		return nil, err
+	}
+	if c != nil && c.Len() != bs.n {
+		return nil, fmt.Errorf("column %q has %d rows, expected %d", name, c.Len(), bs.n)
+	}
+	if c != nil && c.Null != nil && len(c.Null) != bs.n {
+		return nil, fmt.Errorf("column %q has %d null flags, expected %d", name, len(c.Null), bs.n)
+	}
+	bs.columns[name] = c
+	return c, nil
+}
+
+// row returns the data for row `i` holding `fields`.  Each field is read from
+// the column named by the longest prefix of the field present in the batch.
+func (bs *batchState) row(i int, fields [][]string) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	for _, f := range fields {
+		for k := len(f); k > 0; k-- {
+			c, err := bs.column(strings.Join(f[:k], "."))
+			if err != nil {
+				return nil, err
+			}
+			if c == nil {
+				continue
+			}
+			if !c.null(i) {
+				setField(data, f[:k], c.value(i))
+			}
+			break
+		}
+	}
+	return data, nil
+}
+
+// fullRow returns the data for row `i` holding every column of the batch.
+// Columns are set shortest name first, so that a column "Store" holding a
+// map is extended rather than replaced by a column "Store.City".
+func (bs *batchState) fullRow(i int, names []string) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	for _, name := range names {
+		c, err := bs.column(name)
+		if err != nil {
+			return nil, err
+		}
+		if c != nil && !c.null(i) {
+			setField(data, strings.Split(name, "."), c.value(i))
+		}
+	}
+	return data, nil
+}
+
+// setField sets the field at `path` in `data`, creating nested maps as
+// needed.  Fields nested inside a value which is not a map are left alone.
+func setField(data map[string]interface{}, path []string, v interface{}) {
+	for _, k := range path[:len(path)-1] {
+		m, ok := data[k].(map[string]interface{})
+		if !ok {
+			if _, exists := data[k]; exists {
+				return
+			}
+			m = map[string]interface{}{}
+			data[k] = m
+		}
+		data = m
+	}
+	data[path[len(path)-1]] = v
+}
+
+// evaluateBatch returns the rows of `active` for which the node is true.
+// Rows outside of `active` are never set.
+func (cn *compiledNode) evaluateBatch(bs *batchState, active *Bitmap) (*Bitmap, error) {
+	if cn.node.isLeaf() {
+		return cn.evaluateBatchLeaf(bs, active)
+	}
+
+	if cn.custom != nil {
+		rs := make([]*Bitmap, len(cn.children))
+		for i, c := range cn.children {
+			r, err := c.evaluateBatch(bs, active)
+			if err != nil {
+				return nil, err
+			}
+			rs[i] = r
+		}
+		acc, results := NewBitmap(bs.n), make([]bool, len(rs))
+		active.each(func(row int) {
+			for i, r := range rs {
+				results[i] = r.Get(row)
+			}
+			if cn.custom.Evaluate(results) {
+				acc.Set(row)
+			}
+		})
+		return acc, nil
+	}
+
+	if cn.sw != nil {
+		return cn.evaluateBatchSwitch(bs, active)
+	}
+
+	if cn.node.Op == OperatorIf {
+		cond, err := cn.children[0].evaluateBatch(bs, active)
+		if err != nil {
+			return nil, err
+		}
+		// Each branch is evaluated over the rows which take it.
+		els := active.clone()
+		els.andNot(cond)
+		acc := NewBitmap(bs.n)
+		for i, rows := range []*Bitmap{cond, els} {
+			if rows.empty() {
+				continue
+			}
+			r, err := cn.children[i+1].evaluateBatch(bs, rows)
+			if err != nil {
+				return nil, err
+			}
+			acc.or(r)
+		}
+		return acc, nil
+	}
+
+	if cn.node.Op == OperatorAnd {
+		acc := active.clone()
+		for _, c := range cn.children {
+			if acc.empty() {
+				break
+			}
+			r, err := c.evaluateBatch(bs, acc)
+			if err != nil {
+				return nil, err
+			}
+			acc.and(r)
+		}
+		return acc, nil
+	}
+
+	acc, rest := NewBitmap(bs.n), active.clone()
+	for _, c := range cn.children {
+		if rest.empty() {
+			break
+		}
+		r, err := c.evaluateBatch(bs, rest)
+		if err != nil {
+			return nil, err
+		}
+		acc.or(r)
+		rest.andNot(r)
+	}
+	return acc, nil
+}
+
+func (cn *compiledNode) evaluateBatchLeaf(bs *batchState, active *Bitmap) (*Bitmap, error) {
+	res, rows := (*Bitmap)(nil), active
+	if cn.cmp != nil {
+		c, err := bs.column(strings.Join(cn.cmp.field, "."))
+		if err != nil {
+			return nil, err
+		}
+		if c != nil {
+			if r, deferred, ok := compareColumn(c, cn.cmp); ok {
+				r.and(active)
+				deferred.and(active)
+				res, rows = r, deferred
+			}
+		}
+	}
+	if res == nil {
+		res = NewBitmap(bs.n)
+	}
+
+	var names []string
+	if cn.opaque {
+		cls, ok := bs.batch.(ColumnNamer)
+		if !ok {
+			return nil, fmt.Errorf("%s: leaf reads the whole row, which needs a batch listing its columns", cn.path)
+		}
+		names = append([]string(nil), cls.ColumnNames()...)
+		sort.SliceStable(names, func(i, j int) bool { return len(names[i]) < len(names[j]) })
+	}
+
+	var err error
+	rows.each(func(i int) {
+		if err != nil {
+			return
+		}
+		var data map[string]interface{}
+		if cn.opaque {
+			data, err = bs.fullRow(i, names)
+		} else {
+			data, err = bs.row(i, cn.fields)
+		}
+		if err != nil {
+			return
+		}
+		var v bool
+		if v, err = cn.evaluateLeaf(bs.st, data); err != nil {
+			err = fmt.Errorf("row %d: %w", i, err)
+			return
+		}
+		if v {
+			res.Set(i)
+		}
+	})
+	return res, err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// compareColumn evaluates the comparison `cmp` over every row of `c`.  Rows
+// that must be evaluated row by row, because they are null or NaN, are set in
+// the second bitmap rather than the first.  It returns false if the column
+// and literals have types the kernels do not handle.
+func compareColumn(c *Column, cmp *comparison) (*Bitmap, *Bitmap, bool) {
+	n := c.Len()
+	res, deferred := NewBitmap(n), NewBitmap(n)
+
+	switch {
+	case c.Int64 != nil:
+		if is, ok := literals[int64](cmp.values); ok {
+			compareKernel(c.Int64, cmp.op, is, res.words)
+			break
+		}
+		fs, ok := floatLiterals(cmp.values)
+		if !ok {
+			return nil, nil, false
+		}
+		vs := make([]float64, n)
+		for i, v := range c.Int64 {
+			vs[i] = float64(v)
+		}
+		compareKernel(vs, cmp.op, fs, res.words)
+	case c.Float64 != nil:
+		fs, ok := floatLiterals(cmp.values)
+		if !ok {
+			return nil, nil, false
+		}
+		compareKernel(c.Float64, cmp.op, fs, res.words)
+		if cmp.op != "eq" && cmp.op != "ne" {
+			// NaN cannot be ordered, which the scalar comparisons report as
+			// an error.
+			for i, v := range c.Float64 {
+				if math.IsNaN(v) {
+					deferred.Set(i)
+				}
+			}
+		}
+	case c.String != nil:
+		ss, ok := literals[string](cmp.values)
+		if !ok {
+			return nil, nil, false
+		}
+		compareKernel(c.String, cmp.op, ss, res.words)
+	case c.Bool != nil:
+		bs, ok := literals[bool](cmp.values)
+		if !ok || cmp.op != "eq" && cmp.op != "ne" {
+			return nil, nil, false
+		}
+		for i, v := range c.Bool {
+			eq := false
+			for _, b := range bs {
+				eq = eq || v == b
+			}
+			if eq == (cmp.op == "eq") {
+				res.words[i/64] |= 1 << (i % 64)
+			}
+		}
+	default:
+		return nil, nil, false
+	}
+
+	res.trim()
+	for i, null := range c.Null {
+		if null {
+			deferred.Set(i)
+		}
+	}
+	res.andNot(deferred)
+	return res, deferred, true
+}
+
+// literals returns the comparison literals as a slice of `T`, or false if any
+// of them is of another type.
+func literals[T any](vs []interface{}) ([]T, bool) {
+	ts := make([]T, len(vs))
+	for i, v := range vs {
+		t, ok := v.(T)
+		if !ok {
+			return nil, false
+		}
+		ts[i] = t
+	}
+	return ts, true
+}
+
+// floatLiterals returns the numeric comparison literals as float64s, which
+// is how the scalar comparisons compare floats against integers.
+func floatLiterals(vs []interface{}) ([]float64, bool) {
+	fs := make([]float64, len(vs))
+	for i, v := range vs {
+		switch v := v.(type) {
+		case int64:
+			fs[i] = float64(v)
+		case float64:
+			fs[i] = v
+		default:
+			return nil, false
+		}
+	}
+	return fs, true
+}
@@ -0,0 +1,236 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func batchColumns() Columns {
+	return Columns{
+		"Price":      {Int64: []int64{1, 5, 10, 20, 0, 7}, Null: []bool{false, false, false, false, true, false}},
+		"Weight":     {Float64: []float64{0.5, 2, math.NaN(), 3.5, 1, 9}},
+		"Name":       {String: []string{"milk", "onions", "toothpaste", "milk", "bread", "eggs"}},
+		"Fresh":      {Bool: []bool{true, false, true, false, true, true}},
+		"Tags":       {Values: []interface{}{[]interface{}{"dairy"}, nil, []interface{}{"dental"}, []interface{}{"dairy"}, nil, nil}},
+		"Store.City": {String: []string{"SF", "NY", "SF", "LA", "NY", "SF"}},
+	}
+}
+
+// batchRow returns row `i` of `cs` as `Evaluate` would be given it.
+func batchRow(cs Columns, i int) map[string]interface{} {
+	data := map[string]interface{}{}
+	for name, c := range cs {
+		if !c.null(i) {
+			setField(data, strings.Split(name, "."), c.value(i))
+		}
+	}
+	return data
+}
+
+func TestEvaluateBatch(t *testing.T) {
+	cs := batchColumns()
+	for _, tc := range []struct {
+		leaf     string
+		expected []int
+	}{
+		{"ge .Price 5", []int{1, 2, 3, 5}},
+		{"lt 5 .Price", []int{2, 3, 5}},
+		{"eq .Price 5 20", []int{1, 3}},
+		{"ne .Price 5", []int{0, 2, 3, 5}},
+		{"between .Price 5 10", []int{1, 2, 5}},
+		{"gt .Price 4.5", []int{1, 2, 3, 5}},
+		{"eq .Weight 2", []int{1}},
+		{"ne .Weight 2", []int{0, 2, 3, 4, 5}},
+		{`eq .Name "milk"`, []int{0, 3}},
+		{`in .Name ["milk", "eggs"]`, []int{0, 3, 5}},
+		{`oneOf .Name "bread" "eggs"`, []int{4, 5}},
+		{`gt .Name "n"`, []int{1, 2}},
+		{`eq .Name 1`, []int{}},
+		{"eq .Fresh true", []int{0, 2, 4, 5}},
+		{"ne .Fresh true", []int{1, 3}},
+		{`contains .Name "o"`, []int{1, 2}},
+		{`in "dairy" .Tags`, []int{0, 3}},
+		{`eq .Store.City "SF"`, []int{0, 2, 5}},
+		{`eq .Missing 1`, []int{}},
+		{`and (eq .Fresh true) (gt .Price 5)`, []int{2, 5}},
+	} {
+		ct, err := Compile(NewLeafNode(tc.leaf), WithFuncs(StdFuncs()), WithMissing(MissingIsFalse))
+		if err != nil {
+			t.Fatalf("Compile(%q) error: %s\n", tc.leaf, err.Error())
+		}
+		b, err := ct.EvaluateBatch(cs)
+		if err != nil {
+			t.Errorf("EvaluateBatch(%q) error: %s\n", tc.leaf, err.Error())
+			continue
+		}
+		if actual := b.Indices(); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("EvaluateBatch(%q) expected=%v actual=%v\n", tc.leaf, tc.expected, actual)
+		}
+	}
+}
+
+func TestEvaluateBatchMatchesEvaluate(t *testing.T) {
+	cs := batchColumns()
+	n := NewNode(OperatorOr,
+		NewNode(OperatorAnd,
+			NewLeafNode("ge .Price 5"),
+			NewLeafNode(`eq .Store.City "SF"`)),
+		NewNode(OperatorAnd,
+			NewLeafNode("eq .Fresh false"),
+			NewLeafNode(`hasPrefix .Name "o"`)),
+		NewLeafNode(`eq .Name "bread"`))
+
+	ct, err := Compile(n, WithFuncs(StdFuncs()), WithMissing(MissingIsFalse))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	b, err := ct.EvaluateBatch(cs)
+	if err != nil {
+		t.Fatalf("EvaluateBatch() error: %s\n", err.Error())
+	}
+	if b.Len() != cs.Len() {
+		t.Errorf("EvaluateBatch() expected=%d rows actual=%d\n", cs.Len(), b.Len())
+	}
+	for i := 0; i < cs.Len(); i++ {
+		v, err := ct.Evaluate(batchRow(cs, i))
+		if err != nil {
+			t.Fatalf("Evaluate() error: %s\n", err.Error())
+		}
+		if b.Get(i) != v {
+			t.Errorf("EvaluateBatch() row %d expected=%v actual=%v\n", i, v, b.Get(i))
+		}
+	}
+}
+
+func TestEvaluateBatchErrors(t *testing.T) {
+	cs := batchColumns()
+	for _, tc := range []struct {
+		leaf     string
+		opts     []Option
+		expected string
+	}{
+		{"gt .Weight 1", nil, "row 2: "},
+		{"gt .Price 1", []Option{WithMissing(MissingIsError)}, "row 4: /: missing field"},
+		{`lt .Fresh 1`, nil, "row 0: "},
+	} {
+		ct, err := Compile(NewLeafNode(tc.leaf), tc.opts...)
+		if err != nil {
+			t.Fatalf("Compile(%q) error: %s\n", tc.leaf, err.Error())
+		}
+		_, err = ct.EvaluateBatch(cs)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.expected) {
+			t.Errorf("EvaluateBatch(%q) expected error=%q actual=%v\n", tc.leaf, tc.expected, err)
+		}
+	}
+
+	ct, err := Compile(NewLeafNode("gt .Price 1"), WithMissing(MissingIsError))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if _, err := ct.EvaluateBatch(cs); !errors.Is(err, ErrMissingField) {
+		t.Errorf("EvaluateBatch() expected=%v actual=%v\n", ErrMissingField, err)
+	}
+
+	bad := Columns{"Price": {Int64: []int64{1, 2}, Null: []bool{true}}}
+	if _, err := ct.EvaluateBatch(bad); err == nil {
+		t.Errorf("EvaluateBatch() expected an error for mismatched null flags\n")
+	}
+}
+
+func TestBitmap(t *testing.T) {
+	b := NewBitmap(130)
+	for _, i := range []int{0, 63, 64, 129} {
+		b.Set(i)
+	}
+	if b.Len() != 130 || b.Count() != 4 {
+		t.Errorf("Bitmap expected len=130 count=4 actual len=%d count=%d\n", b.Len(), b.Count())
+	}
+	if !b.Get(64) || b.Get(65) {
+		t.Errorf("Bitmap.Get() expected bit 64 only actual 64=%v 65=%v\n", b.Get(64), b.Get(65))
+	}
+	if actual := b.Indices(); !reflect.DeepEqual(actual, []int{0, 63, 64, 129}) {
+		t.Errorf("Bitmap.Indices() expected=%v actual=%v\n", []int{0, 63, 64, 129}, actual)
+	}
+	if f := fullBitmap(130); f.Count() != 130 {
+		t.Errorf("fullBitmap() expected=130 actual=%d\n", f.Count())
+	}
+}
+
+func TestLeafComparison(t *testing.T) {
+	for _, tc := range []struct {
+		leaf     string
+		expected *comparison
+	}{
+		{"ge .Price 10", &comparison{op: "ge", fn: "ge", field: []string{"Price"}, values: []interface{}{int64(10)}}},
+		{"(lt 10 $.Price)", &comparison{op: "gt", fn: "lt", field: []string{"Price"}, values: []interface{}{int64(10)}}},
+		{"eq .A.B 1.0 'x'", &comparison{op: "eq", fn: "eq", field: []string{"A", "B"}, values: []interface{}{1.0, int64('x')}}},
+		{`in .Name ["a", "b"]`, &comparison{op: "eq", fn: "in", field: []string{"Name"}, values: []interface{}{"a", "b"}}},
+		{"between .X 0x10 1e3", &comparison{op: "between", fn: "between", field: []string{"X"}, values: []interface{}{int64(16), 1000.0}}},
+		{"ne .Fresh true", &comparison{op: "ne", fn: "ne", field: []string{"Fresh"}, values: []interface{}{true}}},
+		{"ge .Price .Cost", nil},
+		{"ge .Price 1 2", nil},
+		{`in "a" .Tags`, nil},
+		{"eq .Price 1 | not", nil},
+		{`contains .Name "o"`, nil},
+	} {
+		tr, err := parseLeaf(tc.leaf)
+		if err != nil {
+			t.Fatalf("parseLeaf(%q) error: %s\n", tc.leaf, err.Error())
+		}
+		actual, _ := leafComparison(tr)
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("leafComparison(%q) expected=%#v actual=%#v\n", tc.leaf, tc.expected, actual)
+		}
+	}
+}
+
+func TestEvaluateBatchOpaque(t *testing.T) {
+	cs := batchColumns()
+	for _, leaf := range []string{
+		`eq (index . "Name") "milk"`,
+		"eq (len .) 6",
+		`eq (index . "Store" "City") "SF"`,
+	} {
+		ct, err := Compile(NewLeafNode(leaf), WithFuncs(StdFuncs()))
+		if err != nil {
+			t.Fatalf("Compile(%q) error: %s\n", leaf, err.Error())
+		}
+		b, err := ct.EvaluateBatch(cs)
+		if err != nil {
+			t.Fatalf("EvaluateBatch(%q) error: %s\n", leaf, err.Error())
+		}
+		for i := 0; i < cs.Len(); i++ {
+			v, err := ct.Evaluate(batchRow(cs, i))
+			if err != nil {
+				t.Fatalf("Evaluate(%q) error: %s\n", leaf, err.Error())
+			}
+			if b.Get(i) != v {
+				t.Errorf("EvaluateBatch(%q) row %d expected=%v actual=%v\n", leaf, i, v, b.Get(i))
+			}
+		}
+	}
+
+	// Batches which cannot list their columns cannot give a whole row.
+	ct, err := Compile(NewLeafNode("eq (len .) 6"))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if _, err := ct.EvaluateBatch(onlyColumns{cs}); err == nil {
+		t.Errorf("EvaluateBatch() expected an error for a batch without column names\n")
+	}
+}
+
+// onlyColumns hides the `ColumnNamer` implementation of a batch.
+type onlyColumns struct {
+	cs Columns
+}
+
+func (o onlyColumns) Len() int                            { return o.cs.Len() }
+func (o onlyColumns) Column(name string) (*Column, error) { return o.cs.Column(name) }
@@ -0,0 +1,465 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"math/big"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// maxBDDNodes bounds the nodes of a `BDD`, whose size may grow exponentially
+// with the number of distinct leaves for some trees.
+const maxBDDNodes = 1 << 20
+
+// maxBDDOperatorChildren bounds the children of the nodes of registered
+// operators in a `BDD`, whose results are enumerated.
+const maxBDDOperatorChildren = 16
+
+// The terminals of every `BDD`.
+const (
+	bddFalse = 0
+	bddTrue  = 1
+)
+
+// BDD is a reduced ordered binary decision diagram of a tree, see
+// `Node.ToBDD`: the function of the tree of its leaves as a graph in which
+// every path from the root tests each leaf at most once, in a fixed order,
+// and equal subgraphs are shared.  Equivalent trees over the same leaves have
+// the same diagram, so that it decides equivalence at once, and evaluating it
+// tests each leaf at most once.
+type BDD struct {
+	leaves []string
+	kinds  []Operator // of the leaves, leaf or advanced
+	m      *bddManager
+	root   int
+}
+
+// ToBDD returns the binary decision diagram of the tree, whose variables are
+// its distinct leaves in the order they first occur, named as by
+// `TruthTable`.  As with `Satisfiable`, the leaves `true` and `false` are
+// constants and a leaf `not X` is the negation of the leaf `X`, while other
+// leaves are independent of one another.  Trees whose diagrams have more
+// than a million nodes, or with nodes of registered operators of more than 16
+// children, fail with an error wrapping `ErrLimitExceeded`.
+func (n *Node) ToBDD() (*BDD, error) {
+	e, vars, err := n.boolean(true)
+	if err != nil {
+		return nil, err
+	}
+	b := &BDD{leaves: vars, kinds: make([]Operator, len(vars)), m: newBDDManager(len(vars))}
+	e.leaves(func(l *boolExpr) {
+		if l.variable >= 0 {
+			b.kinds[l.variable] = l.op
+		}
+	})
+	if b.root, err = b.m.expr(e); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Leaves returns the leaves of the diagram, its inputs, in order.
+func (b *BDD) Leaves() []string {
+	return append([]string{}, b.leaves...)
+}
+
+// Size returns the number of decision nodes of the diagram.
+func (b *BDD) Size() int {
+	seen := map[int]bool{}
+	var walk func(u int)
+	walk = func(u int) {
+		if u <= bddTrue || seen[u] {
+			return
+		}
+		seen[u] = true
+		walk(b.m.nodes[u].lo)
+		walk(b.m.nodes[u].hi)
+	}
+	walk(b.root)
+	return len(seen)
+}
+
+// Evaluate returns the result of the tree given the results of its leaves,
+// in the order of `Leaves`, for evaluating a tree many times over leaves
+// whose results are computed beforehand.  It panics unless there is a result
+// for every leaf.
+func (b *BDD) Evaluate(inputs []bool) bool {
+	if len(inputs) != len(b.leaves) {
+		panic(fmt.Sprintf("logictree: BDD.Evaluate given %d inputs for %d leaves", len(inputs), len(b.leaves)))
+	}
+	u := b.root
+	for u > bddTrue {
+		if n := b.m.nodes[u]; inputs[n.v] {
+			u = n.hi
+		} else {
+			u = n.lo
+		}
+	}
+	return u == bddTrue
+}
+
+// SatCount returns the number of combinations of the results of the leaves
+// which make the tree true, out of 2^len(Leaves()).
+func (b *BDD) SatCount() *big.Int {
+	memo := map[int]*big.Int{}
+	var count func(u int) *big.Int
+	count = func(u int) *big.Int {
+		switch u {
+		case bddFalse:
+			return big.NewInt(0)
+		case bddTrue:
+			return big.NewInt(1)
+		}
+		if c, ok := memo[u]; ok {
+			return c
+		}
+		n := b.m.nodes[u]
+		lo := new(big.Int).Lsh(count(n.lo), uint(b.m.level(n.lo)-n.v-1))
+		hi := new(big.Int).Lsh(count(n.hi), uint(b.m.level(n.hi)-n.v-1))
+		memo[u] = lo.Add(lo, hi)
+		return memo[u]
+	}
+	return new(big.Int).Lsh(count(b.root), uint(b.m.level(b.root)))
+}
+
+// Equivalent reports whether the trees of `b` and `o` have the same result
+// for every combination of the results of their leaves, a leaf found in only
+// one of them being an input the other ignores.  It fails as `ToBDD` does if
+// the diagram of both is too large.
+func (b *BDD) Equivalent(o *BDD) (bool, error) {
+	index := map[string]int{}
+	for i, name := range b.leaves {
+		index[name] = i
+	}
+	omap := make([]int, len(o.leaves))
+	for i, name := range o.leaves {
+		j, ok := index[name]
+		if !ok {
+			j = len(index)
+			index[name] = j
+		}
+		omap[i] = j
+	}
+	if len(index) == len(b.leaves) && len(o.leaves) == len(b.leaves) && equalInts(omap) {
+		return b.m.equal(b.root, o.m, o.root), nil
+	}
+
+	// The diagrams are rebuilt over all of their leaves.
+	m := newBDDManager(len(index))
+	bmap := make([]int, len(b.leaves))
+	for i := range bmap {
+		bmap[i] = i
+	}
+	broot, err := m.copy(b.m, b.root, bmap)
+	if err != nil {
+		return false, err
+	}
+	oroot, err := m.copy(o.m, o.root, omap)
+	if err != nil {
+		return false, err
+	}
+	return broot == oroot, nil
+}
+
+// equalInts reports whether `m` maps every index to itself.
+func equalInts(m []int) bool {
+	for i, j := range m {
+		if i != j {
+			return false
+		}
+	}
+	return true
+}
+
+// Node returns a tree of the function of the diagram: a decision on each of
+// its nodes, written as an `and` or an `or` of the leaf and the decision on
+// the rest where either branch is a constant, and as an `if` node otherwise.
+func (b *BDD) Node() *Node {
+	memo := map[int]*Node{}
+	var node func(u int) *Node
+	node = func(u int) *Node {
+		switch u {
+		case bddFalse:
+			return constantNode(false)
+		case bddTrue:
+			return constantNode(true)
+		}
+		if n, ok := memo[u]; ok {
+			return n.copy()
+		}
+		n := b.m.nodes[u]
+		leaf := &Node{Op: b.kinds[n.v], Leaf: b.leaves[n.v]}
+		var not *Node
+		if b.kinds[n.v] == OperatorLeaf {
+			not = NewLeafNode("not " + b.leaves[n.v])
+		}
+
+		var r *Node
+		switch {
+		case n.lo == bddFalse && n.hi == bddTrue:
+			r = leaf
+		case n.lo == bddTrue && n.hi == bddFalse && not != nil:
+			r = not
+		case n.lo == bddFalse:
+			r = NewNode(OperatorAnd, leaf, node(n.hi))
+		case n.hi == bddTrue:
+			r = NewNode(OperatorOr, leaf, node(n.lo))
+		case n.hi == bddFalse && not != nil:
+			r = NewNode(OperatorAnd, not, node(n.lo))
+		case n.lo == bddTrue && not != nil:
+			r = NewNode(OperatorOr, not, node(n.hi))
+		default:
+			r = NewIfNode(leaf, node(n.hi), node(n.lo))
+		}
+		memo[u] = r
+		return r
+	}
+	return node(b.root)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// bddNode is a decision on the variable `v`, leading to `lo` if it is false
+// and to `hi` if it is true.
+type bddNode struct {
+	v      int
+	lo, hi int
+}
+
+// bddManager holds the nodes of diagrams over `vars` variables, each node
+// unique, with the terminals `bddFalse` and `bddTrue` first.
+type bddManager struct {
+	vars   int
+	nodes  []bddNode
+	unique map[bddNode]int
+	ites   map[[3]int]int
+}
+
+func newBDDManager(vars int) *bddManager {
+	return &bddManager{
+		vars:   vars,
+		nodes:  []bddNode{{v: vars}, {v: vars}},
+		unique: map[bddNode]int{},
+		ites:   map[[3]int]int{},
+	}
+}
+
+// level returns the variable of the node `u`, that of the terminals being
+// the number of variables.
+func (m *bddManager) level(u int) int {
+	return m.nodes[u].v
+}
+
+// mk returns the node deciding on `v` between `lo` and `hi`.
+func (m *bddManager) mk(v, lo, hi int) (int, error) {
+	if lo == hi {
+		return lo, nil
+	}
+	n := bddNode{v: v, lo: lo, hi: hi}
+	if u, ok := m.unique[n]; ok {
+		return u, nil
+	}
+	if len(m.nodes) >= maxBDDNodes {
+		return 0, fmt.Errorf("%w: more than %d nodes in decision diagram", ErrLimitExceeded, maxBDDNodes)
+	}
+	m.nodes = append(m.nodes, n)
+	m.unique[n] = len(m.nodes) - 1
+	return len(m.nodes) - 1, nil
+}
+
+// ite returns the node of "if f then g else h".
+func (m *bddManager) ite(f, g, h int) (int, error) {
+	switch {
+	case f == bddTrue:
+		return g, nil
+	case f == bddFalse:
+		return h, nil
+	case g == h:
+		return g, nil
+	case g == bddTrue && h == bddFalse:
+		return f, nil
+	}
+	key := [3]int{f, g, h}
+	if u, ok := m.ites[key]; ok {
+		return u, nil
+	}
+
+	v := min(m.level(f), m.level(g), m.level(h))
+	f0, f1 := m.cofactors(f, v)
+	g0, g1 := m.cofactors(g, v)
+	h0, h1 := m.cofactors(h, v)
+	lo, err := m.ite(f0, g0, h0)
+	if err != nil {
+		return 0, err
+	}
+	hi, err := m.ite(f1, g1, h1)
+	if err != nil {
+		return 0, err
+	}
+	u, err := m.mk(v, lo, hi)
+	if err != nil {
+		return 0, err
+	}
+	m.ites[key] = u
+	return u, nil
+}
+
+// cofactors returns the nodes of `u` with the variable `v` false and true.
+func (m *bddManager) cofactors(u, v int) (int, int) {
+	if m.level(u) != v {
+		return u, u
+	}
+	return m.nodes[u].lo, m.nodes[u].hi
+}
+
+// expr returns the node of the expression `e`.
+func (m *bddManager) expr(e *boolExpr) (int, error) {
+	if e.custom != nil {
+		return m.custom(e)
+	}
+	switch e.op {
+	case OperatorAnd, OperatorOr:
+		r, d := bddTrue, bddFalse
+		if decisive(e.op) {
+			r, d = bddFalse, bddTrue
+		}
+		for _, c := range e.children {
+			u, err := m.expr(c)
+			if err != nil {
+				return 0, err
+			}
+			if e.op == OperatorAnd {
+				r, err = m.ite(r, u, bddFalse)
+			} else {
+				r, err = m.ite(r, bddTrue, u)
+			}
+			if err != nil {
+				return 0, err
+			}
+			if r == d {
+				break
+			}
+		}
+		return r, nil
+	case OperatorIf:
+		us := make([]int, 3)
+		for i, c := range e.children {
+			var err error
+			if us[i], err = m.expr(c); err != nil {
+				return 0, err
+			}
+		}
+		return m.ite(us[0], us[1], us[2])
+	}
+	if e.variable < 0 {
+		if e.constant {
+			return bddTrue, nil
+		}
+		return bddFalse, nil
+	}
+	if e.negated {
+		return m.mk(e.variable, bddTrue, bddFalse)
+	}
+	return m.mk(e.variable, bddFalse, bddTrue)
+}
+
+// custom returns the node of the expression `e` of a registered operator,
+// the `or` of every combination of the results of its children for which it
+// is true.
+func (m *bddManager) custom(e *boolExpr) (int, error) {
+	if len(e.children) > maxBDDOperatorChildren {
+		return 0, fmt.Errorf("%w: %s node of %d children, at most %d are enumerated", ErrLimitExceeded, e.op, len(e.children), maxBDDOperatorChildren)
+	}
+	us := make([]int, len(e.children))
+	for i, c := range e.children {
+		var err error
+		if us[i], err = m.expr(c); err != nil {
+			return 0, err
+		}
+	}
+
+	r := bddFalse
+	results := make([]bool, len(us))
+	for bits := 0; bits < 1<<len(us); bits++ {
+		for i := range results {
+			results[i] = bits&(1<<i) != 0
+		}
+		if !e.custom.Evaluate(results) {
+			continue
+		}
+		product := bddTrue
+		for i, u := range us {
+			var err error
+			if results[i] {
+				product, err = m.ite(product, u, bddFalse)
+			} else {
+				product, err = m.ite(u, bddFalse, product)
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+		var err error
+		if r, err = m.ite(r, bddTrue, product); err != nil {
+			return 0, err
+		}
+	}
+	return r, nil
+}
+
+// copy returns the node of the node `u` of `from`, whose variables are those
+// of `vars` here.
+func (m *bddManager) copy(from *bddManager, u int, vars []int) (int, error) {
+	memo := map[int]int{}
+	var walk func(u int) (int, error)
+	walk = func(u int) (int, error) {
+		if u <= bddTrue {
+			return u, nil
+		}
+		if r, ok := memo[u]; ok {
+			return r, nil
+		}
+		n := from.nodes[u]
+		lo, err := walk(n.lo)
+		if err != nil {
+			return 0, err
+		}
+		hi, err := walk(n.hi)
+		if err != nil {
+			return 0, err
+		}
+		v, err := m.mk(vars[n.v], bddFalse, bddTrue)
+		if err != nil {
+			return 0, err
+		}
+		r, err := m.ite(v, hi, lo)
+		if err != nil {
+			return 0, err
+		}
+		memo[u] = r
+		return r, nil
+	}
+	return walk(u)
+}
+
+// equal reports whether the node `u` and the node `w` of `o`, over the same
+// variables, are the same function.
+func (m *bddManager) equal(u int, o *bddManager, w int) bool {
+	type pair struct{ u, w int }
+	seen := map[pair]bool{}
+	var walk func(u, w int) bool
+	walk = func(u, w int) bool {
+		if u <= bddTrue || w <= bddTrue {
+			return u == w
+		}
+		if seen[pair{u, w}] {
+			return true
+		}
+		seen[pair{u, w}] = true
+		a, b := m.nodes[u], o.nodes[w]
+		return a.v == b.v && walk(a.lo, b.lo) && walk(a.hi, b.hi)
+	}
+	return walk(u, w)
+}
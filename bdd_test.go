@@ -0,0 +1,113 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestBDD(t *testing.T) {
+	for _, tc := range []struct {
+		tree     *Node
+		size     int
+		satCount int64
+	}{
+		{NewLeafNode(".A"), 1, 1},
+		{NewNode(OperatorAnd, NewLeafNode(".A"), NewLeafNode(".B")), 2, 1},
+		{NewNode(OperatorOr, NewLeafNode(".A"), NewLeafNode(".B"), NewLeafNode(".C")), 3, 7},
+		{NewNode(OperatorAnd, NewLeafNode(".A"), NewLeafNode("not .A")), 0, 0},
+		{NewNode(OperatorOr, NewLeafNode(".A"), NewLeafNode("true")), 0, 2},
+		{NewNode(OperatorOr, NewNode(OperatorAnd, NewLeafNode(".A"), NewLeafNode(".B")), NewNode(OperatorAnd, NewLeafNode("(.A)"), NewLeafNode(".C"))), 3, 3},
+		{NewIfNode(NewLeafNode(".A"), NewLeafNode(".B"), NewLeafNode(".C")), 3, 4},
+		{NewNode(OperatorOr, NewLeafNode(".A"), NewAdvancedLeafNode("{{ .B }}")), 2, 3},
+	} {
+		b, err := tc.tree.ToBDD()
+		if err != nil {
+			t.Fatalf("ToBDD(%s) error: %s\n", tc.tree, err.Error())
+		}
+		if b.Size() != tc.size || b.SatCount().Int64() != tc.satCount {
+			t.Errorf("ToBDD(%s) expected=%d/%d actual=%d/%d\n", tc.tree, tc.size, tc.satCount, b.Size(), b.SatCount())
+		}
+
+		// The diagram, and the tree it returns, agree with the truth table.
+		back, err := b.Node().ToBDD()
+		if err != nil {
+			t.Fatalf("ToBDD(%s) error: %s\n", b.Node(), err.Error())
+		}
+		if ok, err := b.Equivalent(back); err != nil || !ok {
+			t.Errorf("Equivalent(%s, %s) expected=true actual=%v %v\n", tc.tree, b.Node(), ok, err)
+		}
+		tt, err := tc.tree.TruthTable()
+		if err != nil {
+			t.Fatalf("TruthTable(%s) error: %s\n", tc.tree, err.Error())
+		}
+		if len(tt.Leaves) != len(b.Leaves()) {
+			continue // `not` leaves are inputs of their own in truth tables
+		}
+		for _, row := range tt.Rows {
+			if b.Evaluate(row.Inputs) != row.Result {
+				t.Errorf("Evaluate(%s, %v) expected=%v\n", tc.tree, row.Inputs, row.Result)
+			}
+		}
+	}
+}
+
+func TestBDDEquivalent(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     *Node
+		expected bool
+	}{
+		{NewNode(OperatorAnd, NewLeafNode(".A"), NewLeafNode(".B")), NewNode(OperatorAnd, NewLeafNode(".B"), NewLeafNode(".A")), true},
+		{NewNode(OperatorAnd, NewLeafNode(".A"), NewLeafNode(".B")), NewNode(OperatorOr, NewLeafNode(".B"), NewLeafNode(".A")), false},
+		{
+			NewNode(OperatorOr, NewNode(OperatorAnd, NewLeafNode(".A"), NewLeafNode(".B")), NewNode(OperatorAnd, NewLeafNode(".A"), NewLeafNode(".C"))),
+			NewNode(OperatorAnd, NewLeafNode(".A"), NewNode(OperatorOr, NewLeafNode(".C"), NewLeafNode(".B"))),
+			true,
+		},
+		// Leaves other than `not` are independent of one another.
+		{NewLeafNode("not (and .A .B)"), NewNode(OperatorOr, NewLeafNode("not .A"), NewLeafNode("not .B")), false},
+		{NewNode(OperatorOr, NewLeafNode(".A"), NewNode(OperatorAnd, NewLeafNode(".A"), NewLeafNode(".B"))), NewLeafNode(".A"), true},
+		{NewNode(OperatorOr, NewLeafNode(".A"), NewLeafNode(".B")), NewLeafNode(".A"), false},
+		{NewIfNode(NewLeafNode(".A"), NewLeafNode(".B"), NewLeafNode(".C")), NewNode(OperatorOr, NewNode(OperatorAnd, NewLeafNode(".A"), NewLeafNode(".B")), NewNode(OperatorAnd, NewLeafNode("not .A"), NewLeafNode(".C"))), true},
+	} {
+		a, err := tc.a.ToBDD()
+		if err != nil {
+			t.Fatalf("ToBDD(%s) error: %s\n", tc.a, err.Error())
+		}
+		b, err := tc.b.ToBDD()
+		if err != nil {
+			t.Fatalf("ToBDD(%s) error: %s\n", tc.b, err.Error())
+		}
+		for _, pair := range [][2]*BDD{{a, b}, {b, a}} {
+			if ok, err := pair[0].Equivalent(pair[1]); err != nil || ok != tc.expected {
+				t.Errorf("Equivalent(%s, %s) expected=%v actual=%v %v\n", tc.a, tc.b, tc.expected, ok, err)
+			}
+		}
+	}
+}
+
+func TestBDDOperators(t *testing.T) {
+	registerTestOperator(t, "majority", majority)
+
+	tree := NewNode("majority", NewLeafNode(".A"), NewLeafNode(".B"), NewLeafNode(".C"))
+	b, err := tree.ToBDD()
+	if err != nil {
+		t.Fatalf("ToBDD() error: %s\n", err.Error())
+	}
+	if b.SatCount().Int64() != 4 || !b.Evaluate([]bool{true, false, true}) || b.Evaluate([]bool{false, false, true}) {
+		t.Errorf("ToBDD() expected 4 combinations of at least two, got %s\n", b.SatCount())
+	}
+
+	// Operators of too many children are not enumerated.
+	wide := NewNode("majority")
+	for i := 0; i <= maxBDDOperatorChildren; i++ {
+		wide.Nodes = append(wide.Nodes, NewLeafNode(fmt.Sprintf(".L%d", i)))
+	}
+	if _, err := wide.ToBDD(); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("ToBDD() expected=%v actual=%v\n", ErrLimitExceeded, err)
+	}
+}
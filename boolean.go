@@ -0,0 +1,270 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"text/template/parse"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// boolExpr is the structure of a tree with its leaves as boolean variables,
+// for the analyses which treat each distinct leaf as an input that may be
+// true or false independently of the others.
+type boolExpr struct {
+	op       Operator
+	custom   OperatorImpl // set if `op` is a registered operator
+	children []*boolExpr
+
+	// Leaves at `path` are named by `leafVariable`, and are the variable of
+	// the index `variable`, negated if `negated` is set, or the constant
+	// `constant` if `variable` is negative.
+	path     string
+	name     string
+	variable int
+	negated  bool
+	constant bool
+}
+
+// boolean returns the tree as a `boolExpr` and the names of its variables,
+// in the order they first occur.  Leaves which are equal but for their
+// whitespace and redundant parentheses are the same variable, named by the
+// normalized leaf; advanced leaves are named as they are written.  If
+// `literals` is set, the leaves `true` and `false` are constants and a leaf
+// `not X` is the negation of the variable of the leaf `X`.
+func (n *Node) boolean(literals bool) (*boolExpr, []string, error) {
+	b := &booleanBuilder{literals: literals, index: map[string]int{}}
+	e, err := b.expr(n, "/")
+	if err != nil {
+		return nil, nil, err
+	}
+	return e, b.vars, nil
+}
+
+type booleanBuilder struct {
+	literals bool
+	index    map[string]int
+	vars     []string
+}
+
+func (b *booleanBuilder) expr(n *Node, path string) (*boolExpr, error) {
+	switch n.Op {
+	case OperatorLeaf, OperatorAdvanced:
+		e := &boolExpr{op: n.Op, path: path, name: leafVariable(n)}
+		name := e.name
+		if b.literals && n.Op == OperatorLeaf {
+			var constant, ok bool
+			if name, e.negated, constant, ok = leafLiteral(name); !ok {
+				e.variable, e.constant = -1, constant
+				return e, nil
+			}
+		}
+		i, ok := b.index[name]
+		if !ok {
+			i = len(b.vars)
+			b.index[name] = i
+			b.vars = append(b.vars, name)
+		}
+		e.variable = i
+		return e, nil
+	case OperatorAnd, OperatorOr, OperatorIf:
+		if len(n.Nodes) == 0 {
+			return nil, nodeError(path, n, ErrEmptyNode)
+		}
+		if n.Op == OperatorIf && len(n.Nodes) != 3 {
+			return nil, nodeError(path, n, fmt.Errorf("%w: if needs exactly three children", ErrInvalidOperator))
+		}
+		e := &boolExpr{op: n.Op, children: make([]*boolExpr, len(n.Nodes))}
+		for i, c := range n.Nodes {
+			var err error
+			if e.children[i], err = b.expr(c, childPath(path, i)); err != nil {
+				return nil, err
+			}
+		}
+		return e, nil
+	}
+	if n.Op == OperatorSwitch {
+		return b.switchExpr(n, path)
+	}
+	if isCustom(n.Op) && len(n.Nodes) > 0 {
+		impl, err := bindOperator(n)
+		if err != nil {
+			return nil, nodeError(path, n, err)
+		}
+		e := &boolExpr{op: n.Op, custom: impl, children: make([]*boolExpr, len(n.Nodes))}
+		for i, c := range n.Nodes {
+			var err error
+			if e.children[i], err = b.expr(c, childPath(path, i)); err != nil {
+				return nil, err
+			}
+		}
+		return e, nil
+	} else if isCustom(n.Op) {
+		return nil, nodeError(path, n, ErrEmptyNode)
+	}
+	return nil, nodeError(path, n, fmt.Errorf("%w: %q", ErrInvalidOperator, string(n.Op)))
+}
+
+// switchExpr returns the switch `n` at `path` as the `if` expressions
+// choosing between its cases in turn, the guard of each case the variable of
+// the leaf `caseGuard` at the path of the case.
+func (b *booleanBuilder) switchExpr(n *Node, path string) (*boolExpr, error) {
+	if err := n.validateSwitch(path, false); err != nil {
+		return nil, err
+	}
+	e := &boolExpr{op: OperatorLeaf, path: path, variable: -1}
+	for i, c := range n.Nodes {
+		if c.Leaf == "" {
+			var err error
+			if e, err = b.expr(c.Nodes[0], childPath(childPath(path, i), 0)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for i := len(n.Nodes) - 1; i >= 0; i-- {
+		c := n.Nodes[i]
+		if c.Leaf == "" {
+			continue
+		}
+		cp := childPath(path, i)
+		guard, err := b.expr(caseGuard(n, c), cp)
+		if err != nil {
+			return nil, err
+		}
+		branch, err := b.expr(c.Nodes[0], childPath(cp, 0))
+		if err != nil {
+			return nil, err
+		}
+		e = &boolExpr{op: OperatorIf, children: []*boolExpr{guard, branch, e}}
+	}
+	return e, nil
+}
+
+// leafVariable returns the name of the leaf `n` as a variable.
+func leafVariable(n *Node) string {
+	if n.Op == OperatorAdvanced {
+		return n.Leaf
+	}
+	s, _ := normalizeLeaf(n.Leaf, false)
+	return "(" + s + ")"
+}
+
+// leafLiteral returns the variable of the normalized leaf `name`, stripping
+// any calls to `not` and reporting whether they negate it, or reports that
+// the leaf is not a variable but the constant `constant`.
+func leafLiteral(name string) (variable string, negated, constant, ok bool) {
+	for {
+		t, err := parseLeaf(name)
+		if err != nil {
+			return name, negated, false, true
+		}
+		p, ok := leafPipe(t)
+		if !ok || len(p.Decl) > 0 || len(p.Cmds) != 1 {
+			return name, negated, false, true
+		}
+		args := p.Cmds[0].Args
+		if len(args) == 1 {
+			if c, ok := args[0].(*parse.BoolNode); ok {
+				return "", false, c.True != negated, false
+			}
+		}
+		if len(args) != 2 || calls(p.Cmds[0]) != "not" {
+			return name, negated, false, true
+		}
+		s, _ := normalizeLeaf(args[1].String(), false)
+		name, negated = "("+s+")", !negated
+	}
+}
+
+// eval returns the result of the expression given the value of each
+// variable.
+func (e *boolExpr) eval(vals []bool) bool {
+	if e.custom != nil {
+		results := make([]bool, len(e.children))
+		for i, c := range e.children {
+			results[i] = c.eval(vals)
+		}
+		return e.custom.Evaluate(results)
+	}
+	switch e.op {
+	case OperatorAnd:
+		for _, c := range e.children {
+			if !c.eval(vals) {
+				return false
+			}
+		}
+		return true
+	case OperatorOr:
+		for _, c := range e.children {
+			if c.eval(vals) {
+				return true
+			}
+		}
+		return false
+	case OperatorIf:
+		if e.children[0].eval(vals) {
+			return e.children[1].eval(vals)
+		}
+		return e.children[2].eval(vals)
+	}
+	if e.variable < 0 {
+		return e.constant
+	}
+	return vals[e.variable] != e.negated
+}
+
+// eval3 returns the result of the expression given the values of its
+// variables, some of which may be unknown.
+func (e *boolExpr) eval3(vals []Truth) Truth {
+	if e.custom != nil {
+		vs := make([]Truth, len(e.children))
+		for i, c := range e.children {
+			vs[i] = c.eval3(vals)
+		}
+		return customTruth(e.custom, vs)
+	}
+	switch e.op {
+	case OperatorAnd, OperatorOr:
+		d, v := False, True // the deciding child result and the result otherwise
+		if decisive(e.op) {
+			d, v = True, False
+		}
+		for _, c := range e.children {
+			switch c.eval3(vals) {
+			case d:
+				return d
+			case Unknown:
+				v = Unknown
+			}
+		}
+		return v
+	case OperatorIf:
+		return ifTruth(e.children[0].eval3(vals), e.children[1].eval3(vals), e.children[2].eval3(vals))
+	}
+	if e.variable < 0 {
+		return truth(e.constant)
+	}
+	if vals[e.variable] == Unknown {
+		return Unknown
+	}
+	return truth((vals[e.variable] == True) != e.negated)
+}
+
+// truth returns `v` as a `Truth`.
+func truth(v bool) Truth {
+	if v {
+		return True
+	}
+	return False
+}
+
+// leaves calls `fn` with every leaf of the expression.
+func (e *boolExpr) leaves(fn func(*boolExpr)) {
+	if e.children == nil {
+		fn(e)
+	}
+	for _, c := range e.children {
+		c.leaves(fn)
+	}
+}
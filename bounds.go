@@ -0,0 +1,278 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"sort"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// PruneBounds returns a copy of the tree in which the comparisons of a field
+// with literals under every `and` node, such as `ge .X 4`, are checked
+// against each other as intervals: an `and` whose comparisons of a field can
+// never hold together, such as `gt .X 5` and `lt .X 3`, is replaced by the
+// leaf `(false)`, and comparisons implied by the others of their field are
+// removed, keeping the stronger bound of `ge .X 4` and `ge .X 6` and the
+// equality of `eq .X 5` and `lt .X 10`.  `opts` are the options the tree
+// will later be compiled with, such as `WithFuncs`: as for `Lint`, only the
+// comparisons of `StdFuncs` are analyzed, including `between`, `oneOf` and
+// `in` if they are given.
+//
+// The constants left are folded as by `PartialEval`: an `and` with a false
+// child and an `or` with a true child are replaced by that constant, other
+// constant children of `and` and `or` nodes are removed, and a node left with
+// one child is replaced by it.  Analysis assumes that the fields compared
+// hold values of the type of their literals: for other values, such as
+// missing fields, the comparisons removed may have failed rather than
+// evaluated to false.  The tree is not modified, the pruned tree shares no
+// nodes with it.
+func (n *Node) PruneBounds(opts ...Option) (*Node, error) {
+	ct, err := Compile(n, opts...)
+	if err != nil {
+		return nil, err
+	}
+	c := &compiler{opts: &ct.opts}
+	c.funcs = c.leafFuncs()
+	r, _ := c.pruneBounds(n)
+	return r, nil
+}
+
+// pruneBounds returns the pruned copy of `n`, and whether it is a constant.
+func (c *compiler) pruneBounds(n *Node) (*Node, bool) {
+	if n.isLeaf() {
+		return &Node{Op: n.Op, Leaf: n.Leaf}, n.Op == OperatorLeaf && isConstantLeaf(n.Leaf)
+	}
+	if n.Op != OperatorAnd && n.Op != OperatorOr {
+		m := &Node{Op: n.Op, Leaf: n.Leaf, Nodes: make([]*Node, len(n.Nodes))}
+		for i, child := range n.Nodes {
+			m.Nodes[i], _ = c.pruneBounds(child)
+		}
+		return m, false
+	}
+
+	d := decisive(n.Op)
+	rest := []*Node{}
+	for _, child := range n.Nodes {
+		r, constant := c.pruneBounds(child)
+		if !constant {
+			rest = append(rest, r)
+			continue
+		}
+		if constantValue(r) == d {
+			return constantNode(d), true
+		}
+	}
+	if n.Op == OperatorAnd {
+		var ok bool
+		if rest, ok = c.pruneComparisons(rest); !ok {
+			return constantNode(false), true
+		}
+	}
+
+	switch len(rest) {
+	case 0:
+		return constantNode(!d), true
+	case 1:
+		return rest[0], isConstantLeaf(rest[0].Leaf) && rest[0].Op == OperatorLeaf
+	}
+	return NewNode(n.Op, rest...), false
+}
+
+// pruneComparisons removes the comparisons among the children `nodes` of an
+// `and` node which the others of their field imply, returning false if those
+// of a field can never hold together.
+func (c *compiler) pruneComparisons(nodes []*Node) ([]*Node, bool) {
+	groups := map[string][]int{}
+	fields := []string{}
+	cmps := make([]*comparison, len(nodes))
+	for i, n := range nodes {
+		if n.Op != OperatorLeaf {
+			continue
+		}
+		t, err := parseLeaf(n.Leaf)
+		if err != nil {
+			continue
+		}
+		cmp, ok := leafComparison(t)
+		if !ok || !c.isStd(cmp.fn) {
+			continue
+		}
+		cmps[i] = cmp
+		field := strings.Join(cmp.field, ".")
+		if _, ok := groups[field]; !ok {
+			fields = append(fields, field)
+		}
+		groups[field] = append(groups[field], i)
+	}
+
+	removed := make([]bool, len(nodes))
+	for _, field := range fields {
+		group := groups[field]
+		gcmps := make([]*comparison, len(group))
+		for k, i := range group {
+			gcmps[k] = cmps[i]
+		}
+		rows, ok := boundRows(gcmps)
+		if !ok {
+			continue
+		}
+
+		// The last of comparisons implying each other, such as duplicates,
+		// are removed first.
+		kept := make([]bool, len(group))
+		for k := range kept {
+			kept[k] = true
+		}
+		satisfiable := false
+		for _, row := range rows {
+			satisfiable = satisfiable || allKept(row, kept, -1)
+		}
+		if !satisfiable {
+			return nil, false
+		}
+		for k := len(group) - 1; k >= 0; k-- {
+			implied := true
+			for _, row := range rows {
+				if allKept(row, kept, k) && !row[k] {
+					implied = false
+					break
+				}
+			}
+			if implied {
+				kept[k] = false
+				removed[group[k]] = true
+			}
+		}
+	}
+
+	rest := []*Node{}
+	for i, n := range nodes {
+		if !removed[i] {
+			rest = append(rest, n)
+		}
+	}
+	return rest, true
+}
+
+// allKept reports whether every comparison of `row` which is kept, other
+// than the `skip`th, holds.
+func allKept(row, kept []bool, skip int) bool {
+	for k, v := range row {
+		if kept[k] && k != skip && !v {
+			return false
+		}
+	}
+	return true
+}
+
+// isConstantLeaf reports whether the leaf expression `leaf` is a constant
+// returned by `constantNode`.
+func isConstantLeaf(leaf string) bool {
+	return leaf == constantNode(true).Leaf || leaf == constantNode(false).Leaf
+}
+
+// boundRows returns whether each of the comparisons `cmps`, of a single
+// field, holds for each of the values of `boundCandidates`, a row per value,
+// or false if their literals are not all of the same type or cannot be
+// compared.
+func boundRows(cmps []*comparison) ([][]bool, bool) {
+	var values []interface{}
+	for _, cmp := range cmps {
+		values = append(values, cmp.values...)
+	}
+	candidates, ok := boundCandidates(values)
+	if !ok {
+		return nil, false
+	}
+	rows := make([][]bool, len(candidates))
+	for i, v := range candidates {
+		rows[i] = make([]bool, len(cmps))
+		for k, cmp := range cmps {
+			var err error
+			if rows[i][k], err = cmp.holds(v); err != nil {
+				return nil, false
+			}
+		}
+	}
+	return rows, true
+}
+
+// holds reports whether the comparison holds for the field value `v`.
+func (cmp *comparison) holds(v interface{}) (bool, error) {
+	switch cmp.op {
+	case "eq":
+		for _, w := range cmp.values {
+			if equal(v, w) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "ne":
+		return !equal(v, cmp.values[0]), nil
+	case "between":
+		lo, err := compare(v, cmp.values[0])
+		if err != nil {
+			return false, err
+		}
+		hi, err := compare(v, cmp.values[1])
+		return lo >= 0 && hi <= 0, err
+	}
+	o, err := compare(v, cmp.values[0])
+	if err != nil {
+		return false, err
+	}
+	switch cmp.op {
+	case "lt":
+		return o < 0, nil
+	case "le":
+		return o <= 0, nil
+	case "gt":
+		return o > 0, nil
+	}
+	return o >= 0, nil
+}
+
+// boundCandidates returns values standing for every value of the type of the
+// literals `values`: as every comparison holds the same way between two
+// consecutive literals, those are the literals themselves, a value between
+// each two of them, and a value beyond either end.
+func boundCandidates(values []interface{}) ([]interface{}, bool) {
+	var nums []float64
+	var strs []string
+	bools := 0
+	for _, v := range values {
+		if f, ok := toNumber(v); ok {
+			nums = append(nums, f.float())
+		} else if s, ok := v.(string); ok {
+			strs = append(strs, s)
+		} else if _, ok := v.(bool); ok {
+			bools++
+		}
+	}
+
+	var candidates []interface{}
+	switch len(values) {
+	case len(nums):
+		// The literals are kept as they are, so that large integers are
+		// compared exactly.
+		sort.Float64s(nums)
+		candidates = append(candidates, values...)
+		candidates = append(candidates, nums[0]-1, nums[len(nums)-1]+1)
+		for i := 1; i < len(nums); i++ {
+			candidates = append(candidates, nums[i-1]+(nums[i]-nums[i-1])/2)
+		}
+	case len(strs):
+		// "" is the least string, and s+"\x00" the least one after s.
+		candidates = append(candidates, "")
+		for _, s := range strs {
+			candidates = append(candidates, s, s+"\x00")
+		}
+	case bools:
+		candidates = append(candidates, true, false)
+	default:
+		return nil, false
+	}
+	return candidates, true
+}
@@ -0,0 +1,63 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestPruneBounds(t *testing.T) {
+	std := WithFuncs(StdFuncs())
+	for _, tc := range []struct {
+		tree     *Node
+		opts     []Option
+		expected string
+	}{
+		{NewNode(OperatorAnd, NewLeafNode("ge .X 4"), NewLeafNode("ge .X 6")), nil, ".X >= 6"},
+		{NewNode(OperatorAnd, NewLeafNode("ge .X 6"), NewLeafNode("ge .X 4")), nil, ".X >= 6"},
+		{NewNode(OperatorAnd, NewLeafNode("ge .X 4"), NewLeafNode("lt .X 10"), NewLeafNode("gt .X 5"), NewLeafNode("le .X 20")), nil, ".X < 10 AND .X > 5"},
+		{NewNode(OperatorAnd, NewLeafNode("eq .X 5"), NewLeafNode("lt .X 10"), NewLeafNode("ne .X 7")), nil, ".X == 5"},
+		{NewNode(OperatorAnd, NewLeafNode("gt .X 5"), NewLeafNode("ge .X 5.5")), nil, ".X >= 5.5"},
+		{NewNode(OperatorAnd, NewLeafNode("gt 5 .X"), NewLeafNode("lt .X 3")), nil, ".X < 3"},
+		{NewNode(OperatorAnd, NewLeafNode("ge .X 4"), NewLeafNode("ge .X 4")), nil, ".X >= 4"},
+		{NewNode(OperatorAnd, NewLeafNode("ge .X 4"), NewLeafNode("ge .Y 6"), NewLeafNode(".Z")), nil, ".X >= 4 AND .Y >= 6 AND .Z"},
+		{NewNode(OperatorAnd, NewLeafNode(`ge .S "b"`), NewLeafNode(`gt .S "a"`)), nil, `.S >= "b"`},
+		{NewNode(OperatorAnd, NewLeafNode("between .X 1 10"), NewLeafNode("ge .X 3")), []Option{std}, ".X BETWEEN 1 AND 10 AND .X >= 3"},
+		{NewNode(OperatorAnd, NewLeafNode(`in .C ["US", "CA"]`), NewLeafNode(`ne .C "CA"`)), []Option{std}, `.C in ["US", "CA"] AND .C != "CA"`},
+		{NewNode(OperatorAnd, NewLeafNode(`in .C ["US", "CA"]`), NewLeafNode(`eq .C "CA"`)), []Option{std}, `.C == "CA"`},
+
+		// Comparisons which never hold together make their `and` false, which
+		// is folded into its parents.
+		{NewNode(OperatorAnd, NewLeafNode("gt .X 5"), NewLeafNode("lt .X 3")), nil, "false"},
+		{NewNode(OperatorAnd, NewLeafNode("gt .X 5"), NewLeafNode("le .X 5"), NewLeafNode(".Z")), nil, "false"},
+		{NewNode(OperatorOr, NewNode(OperatorAnd, NewLeafNode("gt .X 5"), NewLeafNode("lt .X 3")), NewLeafNode(".Z")), nil, ".Z"},
+		{NewNode(OperatorAnd, NewNode(OperatorOr, NewLeafNode(`eq .C "US"`), NewLeafNode(`eq .C "CA"`)), NewNode(OperatorAnd, NewLeafNode(`eq .C "US"`), NewLeafNode(`eq .C "CA"`))), nil, "false"},
+		{NewNode(OperatorAnd, NewLeafNode("eq .B true"), NewLeafNode("eq .B false")), nil, "false"},
+
+		// Comparisons which are not analyzed are kept.
+		{NewNode(OperatorAnd, NewLeafNode("gt .X 5"), NewLeafNode(`lt .X "a"`)), nil, `.X > 5 AND .X < "a"`},
+		{NewNode(OperatorAnd, NewLeafNode("gt .X 5"), NewLeafNode("lt .X (len .Y)")), nil, ".X > 5 AND .X < len(.Y)"},
+		{NewNode(OperatorOr, NewLeafNode("gt .X 5"), NewLeafNode("gt .X 3")), nil, ".X > 5 OR .X > 3"},
+		{NewIfNode(NewNode(OperatorAnd, NewLeafNode("gt .X 5"), NewLeafNode("gt .X 3")), NewLeafNode(".A"), NewLeafNode(".B")), nil, "IF .X > 5 THEN .A ELSE .B"},
+	} {
+		r, err := tc.tree.PruneBounds(tc.opts...)
+		if err != nil {
+			t.Fatalf("PruneBounds(%s) error: %s\n", tc.tree, err.Error())
+		}
+		if r.String() != tc.expected {
+			t.Errorf("PruneBounds(%s) expected=%s actual=%s\n", tc.tree, tc.expected, r)
+		}
+	}
+
+	// The tree is not modified, and trees which do not compile fail.
+	tree := NewNode(OperatorAnd, NewLeafNode("ge .X 4"), NewLeafNode("ge .X 6"))
+	before := tree.String()
+	if _, err := tree.PruneBounds(); err != nil || tree.String() != before {
+		t.Errorf("PruneBounds() expected=%s actual=%s %v\n", before, tree, err)
+	}
+	if _, err := NewLeafNode("unknown .X").PruneBounds(); err == nil {
+		t.Errorf("PruneBounds() expected an error\n")
+	}
+}
@@ -0,0 +1,72 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Budget bounds the work of every evaluation of a tree, for services which
+// evaluate rules written by their tenants and must not let a single rule,
+// adversarial or mistaken, take them over.  A zero field is no limit.
+type Budget struct {
+	// MaxLeaves is the number of leaves an evaluation may execute.  Results
+	// which are cached or shared are not counted.
+	MaxLeaves int
+
+	// MaxTime is the wall time an evaluation may take.  A leaf whose
+	// function is still running when it is spent is abandoned, as for a
+	// context which is done, see `EvaluateContext`.
+	MaxTime time.Duration
+}
+
+// WithBudget limits every evaluation of the tree to the budget `b`.  An
+// evaluation which exceeds it stops with a `*NodeError` wrapping
+// `ErrBudgetExceeded`, located at the node it stopped at: the leaf which
+// would have exceeded `MaxLeaves`, or the node evaluated once `MaxTime` was
+// spent.  Evaluations stopped by the deadline of the caller's context, if it
+// is earlier, fail as they do without a budget.
+//
+// The budget applies to every way of evaluating the tree natively, including
+// `Explain`, `EvaluateKleene` and `Score`, but not to `EvaluateBatch`, which
+// evaluates whole columns at once.
+func WithBudget(b Budget) Option {
+	return func(o *compileOptions) {
+		o.budget = b
+	}
+}
+
+// leafBudget counts the leaves executed by an evaluation, shared by the
+// states of its parallel branches.
+type leafBudget struct {
+	max   int64
+	spent atomic.Int64
+}
+
+// spend counts the execution of the leaf `cn`, failing once more than the
+// budget's leaves have been executed.
+func (b *leafBudget) spend(cn *compiledNode) error {
+	if b.spent.Add(1) > b.max {
+		return nodeError(cn.path, cn.node, fmt.Errorf("%w: more than %d leaves evaluated", ErrBudgetExceeded, b.max))
+	}
+	return nil
+}
+
+// withBudget sets up the budget `b` for the evaluation of `st`: the counter
+// of its leaves, and a context whose deadline is that of its time, which
+// `releaseState` cancels.
+func (st *evalState) withBudget(b Budget) {
+	if b.MaxLeaves > 0 {
+		st.leaves = &leafBudget{max: int64(b.MaxLeaves)}
+	}
+	if b.MaxTime > 0 {
+		cause := fmt.Errorf("%w: evaluation took longer than %v", ErrBudgetExceeded, b.MaxTime)
+		st.ctx, st.cancel = context.WithTimeoutCause(st.ctx, b.MaxTime, cause)
+		st.stop = st.ctx
+	}
+}
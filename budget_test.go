@@ -0,0 +1,111 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"text/template"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestBudgetLeaves(t *testing.T) {
+	tree := NewNode(OperatorAnd, NewLeafNode("true"), NewNode(OperatorOr, NewLeafNode("false"), NewLeafNode("true")), NewLeafNode("true"))
+	for _, tc := range []struct {
+		opts []Option
+		max  int
+		path string // at which the budget is exceeded, if it is
+	}{
+		{nil, 4, ""},
+		{nil, 3, "/2"},
+		{nil, 1, "/1/0"},
+		{[]Option{WithParallelism(4)}, 4, ""},
+		{[]Option{WithSharedEvaluation()}, 3, ""},
+	} {
+		ct, err := Compile(tree, append(tc.opts, WithBudget(Budget{MaxLeaves: tc.max}))...)
+		if err != nil {
+			t.Fatalf("Compile() error: %s\n", err.Error())
+		}
+		// Every evaluation has a budget of its own.
+		for i := 0; i < 2; i++ {
+			v, err := ct.Evaluate(nil)
+			var ne *NodeError
+			switch {
+			case tc.path == "" && (err != nil || !v):
+				t.Errorf("Evaluate(%d) expected=true actual=%v %v\n", tc.max, v, err)
+			case tc.path != "" && (!errors.Is(err, ErrBudgetExceeded) || !errors.As(err, &ne)):
+				t.Errorf("Evaluate(%d) expected=%v actual=%v\n", tc.max, ErrBudgetExceeded, err)
+			case tc.path != "" && ne.Path != tc.path:
+				t.Errorf("Evaluate(%d) expected=%s actual=%s\n", tc.max, tc.path, ne.Path)
+			}
+		}
+	}
+
+	// Leaves which are not executed are not counted.
+	ct, err := Compile(NewNode(OperatorOr, NewLeafNode("true"), NewLeafNode("false"), NewLeafNode("false")), WithBudget(Budget{MaxLeaves: 1}))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if v, err := ct.Evaluate(nil); err != nil || !v {
+		t.Errorf("Evaluate() expected=true actual=%v %v\n", v, err)
+	}
+
+	// The other ways of evaluating a tree have the same budget.
+	ct, err = Compile(tree, WithBudget(Budget{MaxLeaves: 2}))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if _, err := ct.Explain(nil); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Explain() expected=%v actual=%v\n", ErrBudgetExceeded, err)
+	}
+	if _, err := ct.EvaluateKleene(nil); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("EvaluateKleene() expected=%v actual=%v\n", ErrBudgetExceeded, err)
+	}
+	if _, err := ct.Score(nil, ScoreOptions{}); !errors.Is(err, ErrBudgetExceeded) {
+		t.Errorf("Score() expected=%v actual=%v\n", ErrBudgetExceeded, err)
+	}
+}
+
+func TestBudgetTime(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	fm := template.FuncMap{
+		"hang": func() bool {
+			<-release
+			return true
+		},
+	}
+
+	for _, opts := range [][]Option{
+		{WithFuncs(fm)},
+		{WithFuncs(fm), WithParallelism(2)},
+	} {
+		tree := NewNode(OperatorAnd, NewLeafNode("true"), NewNode(OperatorOr, NewLeafNode("false"), NewLeafNode("hang")))
+		ct, err := Compile(tree, append(opts, WithBudget(Budget{MaxTime: 10 * time.Millisecond}))...)
+		if err != nil {
+			t.Fatalf("Compile() error: %s\n", err.Error())
+		}
+
+		var ne *NodeError
+		_, err = ct.Evaluate(nil)
+		if !errors.Is(err, ErrBudgetExceeded) || !errors.As(err, &ne) || ne.Path != "/1/1" {
+			t.Errorf("Evaluate() expected=%v at /1/1 actual=%v\n", ErrBudgetExceeded, err)
+		}
+
+		// A deadline of the caller which is earlier stops the evaluation as
+		// it does without a budget.
+		ct, err = Compile(tree, append(opts, WithBudget(Budget{MaxTime: time.Hour}))...)
+		if err != nil {
+			t.Fatalf("Compile() error: %s\n", err.Error())
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err = ct.EvaluateContext(ctx, nil)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("EvaluateContext() expected=%v actual=%v\n", context.DeadlineExceeded, err)
+		}
+	}
+}
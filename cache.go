@@ -0,0 +1,286 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// WithCache memoizes the results of up to `size` evaluations, keyed by a hash
+// of the values of the fields the tree reads, as returned by `Fields`, and
+// evicting the least recently used.  Trees evaluated repeatedly against data
+// whose fields take few distinct values, such as similar events, are then
+// evaluated once per distinct set of values.
+//
+// Only the fields are hashed, so trees which may read the data other than
+// through them (see `Opaque`), and trees with leaves which reference no
+// fields, such as `inWindow "09:00" "17:00"`, are never cached, and neither
+// is data holding values which cannot be hashed, such as functions or fields
+// reached through methods.  Errors are not cached and cached results do not
+// call hooks.  A `size` less than one disables the cache.
+func WithCache(size int) Option {
+	return func(o *compileOptions) {
+		o.cacheSize = size
+	}
+}
+
+// CacheStats counts the evaluations of a tree compiled `WithCache`.
+type CacheStats struct {
+	Hits   int `json:"hits"`
+	Misses int `json:"misses"`
+	Len    int `json:"len"`
+}
+
+// CacheStats returns the hits and misses of the cache of the tree and the
+// number of results it holds, all zero if the tree is not cached.
+func (ct *CompiledTree) CacheStats() CacheStats {
+	if ct.cache == nil {
+		return CacheStats{}
+	}
+	c := ct.cache
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses, Len: c.order.Len()}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+type cacheKey [sha256.Size]byte
+
+// resultCache is an LRU cache of the results of a tree.
+type resultCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[cacheKey]*list.Element
+	order   *list.List // of *cacheEntry, most recently used first
+
+	hits, misses int
+}
+
+type cacheEntry struct {
+	key cacheKey
+	v   bool
+}
+
+// newResultCache returns a cache for the tree rooted at `cn`, or nil if its
+// results cannot be cached.
+func newResultCache(cn *compiledNode, size int) *resultCache {
+	if size < 1 || cn.volatile {
+		return nil
+	}
+	return &resultCache{size: size, entries: map[cacheKey]*list.Element{}, order: list.New()}
+}
+
+func (c *resultCache) get(k cacheKey) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[k]
+	if !ok {
+		c.misses++
+		return false, false
+	}
+	c.hits++
+	c.order.MoveToFront(e)
+	return e.Value.(*cacheEntry).v, true
+}
+
+func (c *resultCache) put(k cacheKey, v bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[k]; ok {
+		e.Value.(*cacheEntry).v = v
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[k] = c.order.PushFront(&cacheEntry{key: k, v: v})
+	if c.order.Len() > c.size {
+		last := c.order.Back()
+		c.order.Remove(last)
+		delete(c.entries, last.Value.(*cacheEntry).key)
+	}
+}
+
+// dataKey returns the hash of the values of `fields` in `data`, or false if
+// any of them cannot be hashed.
+func dataKey(data interface{}, fields [][]string) (cacheKey, bool) {
+	h := &keyHasher{buf: make([]byte, 0, 256)}
+	for _, f := range fields {
+		v, ok := fieldValue(data, f)
+		if !ok {
+			// Methods cannot be called without side effects.
+			return cacheKey{}, false
+		}
+		if !h.value(v) {
+			return cacheKey{}, false
+		}
+	}
+	return sha256.Sum256(h.buf), true
+}
+
+// fieldValue returns the value of the field `path` in `data`, invalid if it
+// is missing, or false if it is reached through a method.
+func fieldValue(data interface{}, path []string) (reflect.Value, bool) {
+	v := reflect.ValueOf(data)
+	for _, name := range path {
+		if v.IsValid() && v.MethodByName(name).IsValid() {
+			return reflect.Value{}, false
+		}
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, true
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return reflect.Value{}, true
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		case reflect.Struct:
+			sf, ok := v.Type().FieldByName(name)
+			if !ok || !sf.IsExported() {
+				return reflect.Value{}, true
+			}
+			v = v.FieldByIndex(sf.Index)
+		default:
+			return reflect.Value{}, true
+		}
+		if !v.IsValid() {
+			return v, true
+		}
+	}
+	return v, true
+}
+
+// maxKeyDepth bounds the nesting of the values hashed, so that cyclic data is
+// never cached rather than recursed into forever.
+const maxKeyDepth = 32
+
+// keyHasher encodes values unambiguously, with their types, for hashing.
+type keyHasher struct {
+	buf   []byte
+	depth int
+}
+
+func (h *keyHasher) str(s string) {
+	h.buf = binary.AppendUvarint(h.buf, uint64(len(s)))
+	h.buf = append(h.buf, s...)
+}
+
+// value appends `v`, reporting false if it holds a value which cannot be
+// encoded.
+func (h *keyHasher) value(v reflect.Value) bool {
+	if h.depth++; h.depth > maxKeyDepth {
+		return false
+	}
+	defer func() { h.depth-- }()
+
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			break
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		h.buf = append(h.buf, 0)
+		return true
+	}
+	h.str(v.Type().String())
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			h.buf = append(h.buf, 1)
+		} else {
+			h.buf = append(h.buf, 0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		h.buf = binary.AppendVarint(h.buf, v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		h.buf = binary.AppendUvarint(h.buf, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		h.buf = binary.LittleEndian.AppendUint64(h.buf, math.Float64bits(v.Float()))
+	case reflect.String:
+		h.str(v.String())
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			h.buf = append(h.buf, 0)
+			return true
+		}
+		h.buf = append(h.buf, 1)
+		if v.Kind() != reflect.Map {
+			return h.elems(v)
+		}
+		keys := v.MapKeys()
+		if v.Type().Key().Kind() != reflect.String {
+			return false
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		h.buf = binary.AppendUvarint(h.buf, uint64(len(keys)))
+		for _, k := range keys {
+			h.str(k.String())
+			if !h.value(v.MapIndex(k)) {
+				return false
+			}
+		}
+	case reflect.Array:
+		return h.elems(v)
+	case reflect.Struct:
+		// Unexported fields, such as those of `time.Time`, are part of the
+		// value but cannot be read, so such structs are encoded as text if
+		// they can be.
+		if hasUnexported(v.Type()) {
+			if !v.CanInterface() {
+				return false
+			}
+			m, ok := v.Interface().(encoding.TextMarshaler)
+			if !ok {
+				return false
+			}
+			text, err := m.MarshalText()
+			if err != nil {
+				return false
+			}
+			h.str(string(text))
+			return true
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if !h.value(v.Field(i)) {
+				return false
+			}
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// elems appends the elements of the slice or array `v`.
+func (h *keyHasher) elems(v reflect.Value) bool {
+	h.buf = binary.AppendUvarint(h.buf, uint64(v.Len()))
+	for i := 0; i < v.Len(); i++ {
+		if !h.value(v.Index(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasUnexported(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			return true
+		}
+	}
+	return false
+}
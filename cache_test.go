@@ -0,0 +1,136 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+	"text/template"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestCache(t *testing.T) {
+	calls := 0
+	fm := template.FuncMap{"count": func(v interface{}) interface{} {
+		calls++
+		return v
+	}}
+	n := NewNode(OperatorAnd, NewLeafNode("gt (count .A) 1"), NewLeafNode("eq .B.C 2"))
+	ct, err := Compile(n, WithFuncs(fm), WithCache(2))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	data := func(a, c int) map[string]interface{} {
+		// Values of fields the tree does not read are not part of the key.
+		return map[string]interface{}{"A": a, "B": map[string]interface{}{"C": c, "D": time.Now().UnixNano()}}
+	}
+	for i, tc := range []struct {
+		data     map[string]interface{}
+		expected bool
+		calls    int
+		stats    CacheStats
+	}{
+		{data(2, 2), true, 1, CacheStats{Hits: 0, Misses: 1, Len: 1}},
+		{data(2, 2), true, 1, CacheStats{Hits: 1, Misses: 1, Len: 1}},
+		{data(0, 2), false, 2, CacheStats{Hits: 1, Misses: 2, Len: 2}},
+		{data(2, 3), false, 3, CacheStats{Hits: 1, Misses: 3, Len: 2}},
+		// data(2, 2) was the least recently used, and evicted.
+		{data(2, 2), true, 4, CacheStats{Hits: 1, Misses: 4, Len: 2}},
+		{data(2, 3), false, 4, CacheStats{Hits: 2, Misses: 4, Len: 2}},
+	} {
+		if v, err := ct.Evaluate(tc.data); err != nil || v != tc.expected {
+			t.Errorf("Evaluate(%d) expected=%v actual=%v err=%v\n", i, tc.expected, v, err)
+		}
+		if calls != tc.calls {
+			t.Errorf("Evaluate(%d) expected calls=%d actual=%d\n", i, tc.calls, calls)
+		}
+		if s := ct.CacheStats(); s != tc.stats {
+			t.Errorf("CacheStats(%d) expected=%+v actual=%+v\n", i, tc.stats, s)
+		}
+	}
+
+	// Errors are not cached.
+	if _, err := ct.Evaluate(map[string]interface{}{"A": "x"}); err == nil {
+		t.Errorf("Evaluate() expected an error\n")
+	}
+	if s := ct.CacheStats(); s.Len != 2 {
+		t.Errorf("CacheStats() expected Len=2 actual=%d\n", s.Len)
+	}
+}
+
+func TestCacheUncached(t *testing.T) {
+	fm := template.FuncMap{"tick": func() bool { return true }}
+	for _, tc := range []struct {
+		name string
+		n    *Node
+		opts []Option
+	}{
+		{"no cache", NewLeafNode("eq .A 1"), nil},
+		{"zero size", NewLeafNode("eq .A 1"), []Option{WithCache(0)}},
+		{"no fields", NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("tick")), []Option{WithCache(4)}},
+		{"opaque", NewLeafNode("eq (len .) 1"), []Option{WithCache(4)}},
+		{"advanced", NewAdvancedLeafNode("{{ eq .A 1 }}"), []Option{WithCache(4)}},
+	} {
+		ct, err := Compile(tc.n, append([]Option{WithFuncs(fm)}, tc.opts...)...)
+		if err != nil {
+			t.Fatalf("Compile(%s) error: %s\n", tc.name, err.Error())
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := ct.Evaluate(map[string]interface{}{"A": 1}); err != nil {
+				t.Fatalf("Evaluate(%s) error: %s\n", tc.name, err.Error())
+			}
+		}
+		if s := ct.CacheStats(); s != (CacheStats{}) {
+			t.Errorf("CacheStats(%s) expected none actual=%+v\n", tc.name, s)
+		}
+	}
+}
+
+type cacheOrder struct {
+	Placed time.Time
+	Items  []string
+	Total  *float64
+	Notify func()
+}
+
+func TestCacheKeys(t *testing.T) {
+	total := 5.0
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	fields := [][]string{{"Placed"}, {"Items"}, {"Total"}}
+	for _, tc := range []struct {
+		a, b     interface{}
+		equal    bool
+		hashable bool
+	}{
+		{cacheOrder{Placed: at, Items: []string{"milk"}}, cacheOrder{Placed: at, Items: []string{"milk"}}, true, true},
+		{cacheOrder{Placed: at, Total: &total}, cacheOrder{Placed: at, Total: &total}, true, true},
+		{cacheOrder{Placed: at}, cacheOrder{Placed: at.Add(time.Second)}, false, true},
+		{cacheOrder{Placed: at, Items: []string{"milk", "eggs"}}, cacheOrder{Placed: at, Items: []string{"milkeggs"}}, false, true},
+		{cacheOrder{Placed: at, Items: []string{}}, cacheOrder{Placed: at}, false, true},
+		// An unread field does not matter, even if it cannot be hashed.
+		{cacheOrder{Placed: at, Notify: func() {}}, cacheOrder{Placed: at}, true, true},
+		{map[string]interface{}{"Total": 1}, map[string]interface{}{"Total": 1.0}, false, true},
+		{map[string]interface{}{"Total": nil}, map[string]interface{}{}, false, true},
+		{map[string]interface{}{"Total": func() {}}, map[string]interface{}{}, false, false},
+		{map[string]interface{}{"Items": map[int]string{1: "milk"}}, map[string]interface{}{}, false, false},
+	} {
+		ka, oka := dataKey(tc.a, fields)
+		kb, okb := dataKey(tc.b, fields)
+		if oka != tc.hashable || !okb {
+			t.Errorf("dataKey(%v) expected=%v actual=%v\n", tc.a, tc.hashable, oka)
+			continue
+		}
+		if tc.hashable && (ka == kb) != tc.equal {
+			t.Errorf("dataKey(%v) == dataKey(%v) expected=%v actual=%v\n", tc.a, tc.b, tc.equal, ka == kb)
+		}
+	}
+
+	// Cyclic data is not hashed.
+	cyclic := map[string]interface{}{}
+	cyclic["Items"] = cyclic
+	if _, ok := dataKey(cyclic, fields); ok {
+		t.Errorf("dataKey(cyclic) expected=false actual=true\n")
+	}
+}
@@ -0,0 +1,74 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Canonicalize returns a copy of the tree in a canonical form, so that trees
+// which differ only in the order of the children of `and` and `or` nodes, or
+// in the whitespace and redundant parentheses of their leaves, are equal.
+// The children of registered operators, see `RegisterOperator`, and of `if`
+// nodes keep their order.
+// Children are sorted by their canonical encoding.  The text of advanced
+// leaves is kept as it is, since it is part of their output.
+//
+// Sorting assumes the children are free of side effects: the evaluation of a
+// canonical tree may short-circuit, or fail, at a different child.  The tree
+// is not modified.
+func (n *Node) Canonicalize() *Node {
+	c, _ := n.canonicalize()
+	return c
+}
+
+// canonicalize returns the canonical copy of the node and its encoding.
+func (n *Node) canonicalize() (*Node, []byte) {
+	c := &Node{Op: n.Op, Leaf: n.Leaf}
+	if n.Op == OperatorLeaf {
+		s, _ := normalizeLeaf(n.Leaf, false)
+		c.Leaf = "(" + s + ")"
+	}
+
+	if len(n.Nodes) > 0 {
+		keys := make([][]byte, len(n.Nodes))
+		c.Nodes = make([]*Node, len(n.Nodes))
+		for i, child := range n.Nodes {
+			c.Nodes[i], keys[i] = child.canonicalize()
+		}
+		if !keepsOrder(n.Op) {
+			sort.Sort(&byKey{c.Nodes, keys})
+		}
+	}
+
+	bs, _ := json.Marshal(c)
+	return c, bs
+}
+
+// byKey sorts nodes by their canonical encodings.
+type byKey struct {
+	nodes []*Node
+	keys  [][]byte
+}
+
+func (s *byKey) Len() int           { return len(s.nodes) }
+func (s *byKey) Less(i, j int) bool { return string(s.keys[i]) < string(s.keys[j]) }
+func (s *byKey) Swap(i, j int) {
+	s.nodes[i], s.nodes[j] = s.nodes[j], s.nodes[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+// Fingerprint returns the hex encoded SHA-256 of the canonical encoding of the
+// tree, the JSON of `Canonicalize`, for deduplicating and caching rules by
+// their content.  Equivalent trees as described by `Canonicalize` share a
+// fingerprint whatever the order of the fields they were decoded from.
+func (n *Node) Fingerprint() string {
+	_, bs := n.canonicalize()
+	sum := sha256.Sum256(bs)
+	return hex.EncodeToString(sum[:])
+}
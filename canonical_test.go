@@ -0,0 +1,77 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestCanonicalize(t *testing.T) {
+	a := NewNode(OperatorOr,
+		NewLeafNode("gt .Toothpaste 5"),
+		NewNode(OperatorAnd,
+			NewLeafNode("le   .Milk 6"),
+			NewLeafNode("(ge .Milk 4)")))
+	b := NewNode(OperatorOr,
+		NewNode(OperatorAnd,
+			NewLeafNode("ge .Milk 4"),
+			NewLeafNode("le .Milk 6")),
+		NewLeafNode(" gt .Toothpaste  5 "))
+
+	ca, cb := a.Canonicalize(), b.Canonicalize()
+	if !reflect.DeepEqual(ca, cb) {
+		t.Errorf("Canonicalize() expected equal trees, got %s and %s\n", ca, cb)
+	}
+	if !reflect.DeepEqual(ca.Canonicalize(), ca) {
+		t.Errorf("Canonicalize() expected a canonical tree to be unchanged\n")
+	}
+	if a.Nodes[0].Leaf != "(gt .Toothpaste 5)" || a.Nodes[1].Nodes[0].Leaf != "(le   .Milk 6)" {
+		t.Errorf("Canonicalize() modified the tree: %s\n", a)
+	}
+
+	expected := NewNode(OperatorOr,
+		NewLeafNode("gt .Toothpaste 5"),
+		NewNode(OperatorAnd, NewLeafNode("ge .Milk 4"), NewLeafNode("le .Milk 6")))
+	if !reflect.DeepEqual(ca, expected.Canonicalize()) {
+		t.Errorf("Canonicalize() expected=%s actual=%s\n", expected, ca)
+	}
+
+	adv := NewAdvancedLeafNode("{{ true }}  ")
+	if c := adv.Canonicalize(); c.Leaf != adv.Leaf {
+		t.Errorf("Canonicalize() expected an advanced leaf to be kept, got %q\n", c.Leaf)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	var a, b Node
+	if err := json.Unmarshal([]byte(`{"Op": "and", "Nodes": [
+		{"Op": "leaf", "Leaf": "(eq .A 1)"},
+		{"Leaf": "(eq .B  2)", "Op": "leaf"}]}`), &a); err != nil {
+		t.Fatalf("Unmarshal() error: %s\n", err.Error())
+	}
+	if err := json.Unmarshal([]byte(`{"Nodes": [{"Leaf": "(eq .B 2)", "Op": "leaf"}, {"Op": "leaf", "Leaf": "((eq .A 1))"}], "Op": "and"}`), &b); err != nil {
+		t.Fatalf("Unmarshal() error: %s\n", err.Error())
+	}
+
+	fa, fb := a.Fingerprint(), b.Fingerprint()
+	if len(fa) != 64 || fa != fb {
+		t.Errorf("Fingerprint() expected equal fingerprints, got %s and %s\n", fa, fb)
+	}
+	if fa != a.Canonicalize().Fingerprint() {
+		t.Errorf("Fingerprint() expected the canonical tree to share the fingerprint\n")
+	}
+
+	for _, n := range []*Node{
+		NewNode(OperatorOr, NewLeafNode("eq .A 1"), NewLeafNode("eq .B 2")),
+		NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("eq .B 3")),
+		NewNode(OperatorAnd, NewLeafNode("eq .A 1")),
+	} {
+		if f := n.Fingerprint(); f == fa {
+			t.Errorf("Fingerprint(%s) expected to differ from %s\n", n, fa)
+		}
+	}
+}
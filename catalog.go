@@ -0,0 +1,162 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"sort"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Catalog holds the messages describing the leaves of trees in the languages
+// of the people their decisions are explained to, so that explanations are
+// rendered in the locale of each reader, see `Catalog.Reasons`.  Messages are
+// keyed by the ID of the leaf they describe, see `MessageID`, since a leaf
+// means the same in every tree it is part of.  Catalogs are typically decoded
+// from JSON files kept with the rules.
+type Catalog struct {
+	// Messages are the messages of every locale, such as "fr" or "fr-CA",
+	// by message ID.
+	Messages map[string]map[string]string `json:"Messages"`
+
+	// Fallbacks are the locales whose messages are used, in order, for
+	// those a locale lacks, before those of its parent locale, see
+	// `Locales`.
+	Fallbacks map[string][]string `json:"Fallbacks,omitempty"`
+
+	// Default is the locale of last resort, such as "en", whose messages
+	// are used for those every other locale lacks.
+	Default string `json:"Default,omitempty"`
+}
+
+// MessageID returns the ID of the messages describing the leaf `leaf`: its
+// expression without surrounding whitespace and enclosing parentheses, so
+// that the leaf `(ge .Age 18)`, written by `NewLeafNode("ge .Age 18")`, has
+// the messages keyed "ge .Age 18".  Advanced leaves are identified by their
+// template.
+func MessageID(leaf string) string {
+	leaf = strings.TrimSpace(leaf)
+	for strings.HasPrefix(leaf, "(") && closingParen(leaf) == len(leaf)-1 {
+		leaf = strings.TrimSpace(leaf[1 : len(leaf)-1])
+	}
+	return leaf
+}
+
+// Locales returns the locales whose messages are used for the reader of
+// `locale`, in order: the locale itself, then its `Fallbacks`, each followed
+// by its own, then its parent locale, "fr" for "fr-CA", followed by its
+// fallbacks and parents in turn, and finally the `Default` locale.
+func (c *Catalog) Locales(locale string) []string {
+	return c.locales(locale, c.Default)
+}
+
+// locales returns the `Locales` of `locale` with the locale of last resort
+// `def`, if any.
+func (c *Catalog) locales(locale, def string) []string {
+	seen := map[string]bool{}
+	locales := []string{}
+	var add func(l string)
+	add = func(l string) {
+		for ; l != ""; l = parentLocale(l) {
+			if seen[l] {
+				continue
+			}
+			seen[l] = true
+			locales = append(locales, l)
+			for _, f := range c.Fallbacks[l] {
+				add(f)
+			}
+		}
+	}
+	add(locale)
+	add(def)
+	return locales
+}
+
+// parentLocale returns the locale `l` without its last subtag, "zh-Hant" for
+// "zh-Hant-TW", or the empty string for a language alone.
+func parentLocale(l string) string {
+	if i := strings.LastIndexAny(l, "-_"); i > 0 {
+		return l[:i]
+	}
+	return ""
+}
+
+// Message returns the message with the ID `id` of the first of the `Locales`
+// of `locale` which has one, with that locale, or false if none does.
+func (c *Catalog) Message(locale, id string) (string, string, bool) {
+	for _, l := range c.Locales(locale) {
+		if msg, ok := c.Messages[l][id]; ok {
+			return msg, l, true
+		}
+	}
+	return "", "", false
+}
+
+// Reason is a leaf of the `Flips` of an `Explanation`, one of the conditions
+// its result came down to, with its message for the reader, see
+// `Catalog.Reasons`.
+type Reason struct {
+	LeafFlip
+
+	// Message is the message of the leaf, or its `Infix` if the catalog has
+	// none, and Locale the locale of the message, empty for the latter.
+	Message string `json:"Message"`
+	Locale  string `json:"Locale,omitempty"`
+}
+
+// Reasons returns the `Flips` of the explanation `e` with their messages in
+// `locale`, or in the first locale of its fallback chain having them, see
+// `Locales`: why a rejection was a rejection, or what an acceptance relies
+// on, in words the reader understands.  The leaf of a flip is named as in
+// `TruthTable`, so that the message of a leaf `not X` is that of `X`, and its
+// `Result` whether `X` held.
+func (c *Catalog) Reasons(e *Explanation, locale string) []Reason {
+	reasons := make([]Reason, 0, len(e.Flips))
+	for _, f := range e.Flips {
+		r := Reason{LeafFlip: f}
+		var ok bool
+		if r.Message, r.Locale, ok = c.Message(locale, MessageID(f.Leaf)); !ok {
+			r.Message = (&Node{Op: OperatorLeaf, Leaf: f.Leaf}).Infix()
+		}
+		reasons = append(reasons, r)
+	}
+	return reasons
+}
+
+// Missing reports the translations which the catalog lacks for the leaves of
+// `trees`, the active rules: the IDs of their messages which none of the
+// `Locales` of each of `locales` has, but for the `Default` locale of last
+// resort, by locale and sorted, so that they are written before the rules go
+// live.  Locales lacking none are left out.  The leaves `true` and `false`
+// need no messages, and references are not followed: trees using them are
+// resolved first, see `Resolve`.
+func (c *Catalog) Missing(locales []string, trees ...*Node) map[string][]string {
+	ids := map[string]bool{}
+	for _, n := range trees {
+		n.walkUsage(func(string) {}, func(l *Node) {
+			if id := MessageID(l.Leaf); id != "true" && id != "false" {
+				ids[id] = true
+			}
+		})
+	}
+
+	missing := map[string][]string{}
+	for _, locale := range locales {
+		chain := c.locales(locale, "")
+		for id := range ids {
+			found := false
+			for _, l := range chain {
+				if _, found = c.Messages[l][id]; found {
+					break
+				}
+			}
+			if !found {
+				missing[locale] = append(missing[locale], id)
+			}
+		}
+		sort.Strings(missing[locale])
+	}
+	return missing
+}
@@ -0,0 +1,98 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func testCatalog() *Catalog {
+	return &Catalog{
+		Messages: map[string]map[string]string{
+			"en":    {"ge .Age 18": "You must be 18 or older.", `eq .Country "US"`: "You must live in the US.", "gt .Amount 100": "The order must be over 100."},
+			"fr":    {"ge .Age 18": "Vous devez avoir 18 ans ou plus."},
+			"fr-CA": {`eq .Country "US"`: "Vous devez habiter aux États-Unis."},
+			"de":    {"ge .Age 18": "Sie müssen mindestens 18 Jahre alt sein."},
+		},
+		Fallbacks: map[string][]string{"de-CH": {"fr-CA"}},
+		Default:   "en",
+	}
+}
+
+func TestCatalogLocales(t *testing.T) {
+	c := testCatalog()
+	for _, tc := range []struct {
+		locale   string
+		expected []string
+	}{
+		{"fr-CA", []string{"fr-CA", "fr", "en"}},
+		{"de-CH", []string{"de-CH", "fr-CA", "fr", "de", "en"}},
+		{"en-GB", []string{"en-GB", "en"}},
+		{"", []string{"en"}},
+	} {
+		if actual := c.Locales(tc.locale); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("Locales(%s) expected=%v actual=%v\n", tc.locale, tc.expected, actual)
+		}
+	}
+
+	for _, tc := range []struct {
+		locale, id       string
+		expected, source string
+	}{
+		{"fr-CA", "(ge .Age 18)", "Vous devez avoir 18 ans ou plus.", "fr"},
+		{"fr-CA", `eq .Country "US"`, "Vous devez habiter aux États-Unis.", "fr-CA"},
+		{"de", "gt .Amount 100", "The order must be over 100.", "en"},
+		{"de", "lt .Amount 5", "", ""},
+	} {
+		msg, locale, _ := c.Message(tc.locale, MessageID(tc.id))
+		if msg != tc.expected || locale != tc.source {
+			t.Errorf("Message(%s, %s) expected=%s (%s) actual=%s (%s)\n", tc.locale, tc.id, tc.expected, tc.source, msg, locale)
+		}
+	}
+}
+
+func TestCatalogReasons(t *testing.T) {
+	tree := NewNode(OperatorAnd, NewLeafNode("ge .Age 18"), NewLeafNode(`eq .Country "US"`), NewLeafNode("lt .Amount 5"))
+	ct, err := Compile(tree)
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	x, err := ct.Explain(map[string]interface{}{"Age": 16, "Country": "US", "Amount": 1})
+	if err != nil {
+		t.Fatalf("Explain() error: %s\n", err.Error())
+	}
+	reasons := testCatalog().Reasons(x, "fr-CA")
+	bs, _ := json.Marshal(reasons)
+	if expected := `[{"Leaf":"(ge .Age 18)","Result":false,"Paths":["/0"],"Message":"Vous devez avoir 18 ans ou plus.","Locale":"fr"}]`; string(bs) != expected {
+		t.Errorf("Reasons() expected=%s actual=%s\n", expected, bs)
+	}
+
+	// Leaves without messages are described by their infix.
+	x, err = ct.Explain(map[string]interface{}{"Age": 20, "Country": "US", "Amount": 10})
+	if err != nil {
+		t.Fatalf("Explain() error: %s\n", err.Error())
+	}
+	if reasons := testCatalog().Reasons(x, "fr"); len(reasons) != 1 || reasons[0].Message != ".Amount < 5" || reasons[0].Locale != "" {
+		t.Errorf("Reasons() expected the infix of the leaf, got %+v\n", reasons)
+	}
+}
+
+func TestCatalogMissing(t *testing.T) {
+	trees := []*Node{
+		NewNode(OperatorAnd, NewLeafNode("ge .Age 18"), NewLeafNode(`eq .Country "US"`), NewLeafNode("true")),
+		NewNode(OperatorOr, NewLeafNode("gt .Amount 100"), NewLeafNode("ge .Age 18")),
+	}
+	missing := testCatalog().Missing([]string{"en", "en-GB", "fr-CA", "de-CH", "es"}, trees...)
+	expected := map[string][]string{
+		"fr-CA": {"gt .Amount 100"},
+		"de-CH": {"gt .Amount 100"},
+		"es":    {`eq .Country "US"`, "ge .Age 18", "gt .Amount 100"},
+	}
+	if !reflect.DeepEqual(missing, expected) {
+		t.Errorf("Missing() expected=%v actual=%v\n", expected, missing)
+	}
+}
@@ -0,0 +1,310 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// CBOR (RFC 8949) major types.
+const (
+	cborBytes  = 2 << 5
+	cborText   = 3 << 5
+	cborArray  = 4 << 5
+	cborMap    = 5 << 5
+	cborTag    = 6 << 5
+	cborSimple = 7 << 5
+
+	cborNull = cborSimple | 22
+)
+
+// maxCBORDepth bounds the nesting of CBOR documents decoded without a
+// `MaxDepth`, as `encoding/json` bounds JSON documents.
+const maxCBORDepth = 10000
+
+// MarshalCBOR encodes the tree as CBOR, a compact binary encoding of the
+// same data model as its JSON encoding: every node is a map with the keys
+// "Op", "Nodes" and "Leaf", those which are empty left out.  As in JSON,
+// invalid UTF-8 in strings is replaced with U+FFFD.
+func (n *Node) MarshalCBOR() ([]byte, error) {
+	return appendCBORNode(nil, n), nil
+}
+
+// UnmarshalCBOR decodes a tree encoded by `MarshalCBOR`, replacing `n`.  No
+// decoding limits apply, see `DecodeOptions.UnmarshalCBOR`.
+func (n *Node) UnmarshalCBOR(data []byte) error {
+	d, err := DecodeOptions{}.UnmarshalCBOR(data)
+	if err != nil {
+		return err
+	}
+	*n = *d
+	return nil
+}
+
+// UnmarshalCBOR decodes a tree encoded as CBOR within the limits of `o`, as
+// `Unmarshal` decodes JSON.  Indefinite length items are not supported.
+func (o DecodeOptions) UnmarshalCBOR(data []byte) (*Node, error) {
+	d := &cborDecoder{data: data, opts: o}
+	n, err := d.node("$", 1)
+	if err := joinErrors(append(d.strict, err)...); err != nil {
+		return nil, err
+	}
+	if len(d.data) != 0 {
+		return nil, fmt.Errorf("invalid tree: unexpected data after the root node")
+	}
+	return n, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func appendCBORNode(b []byte, n *Node) []byte {
+	if n == nil {
+		return append(b, cborNull)
+	}
+	fields := uint64(1)
+	if len(n.Nodes) > 0 {
+		fields++
+	}
+	if n.Leaf != "" {
+		fields++
+	}
+
+	b = appendCBORHead(b, cborMap, fields)
+	b = appendCBORText(b, "Op")
+	b = appendCBORText(b, string(n.Op))
+	if len(n.Nodes) > 0 {
+		b = appendCBORText(b, "Nodes")
+		b = appendCBORHead(b, cborArray, uint64(len(n.Nodes)))
+		for _, c := range n.Nodes {
+			b = appendCBORNode(b, c)
+		}
+	}
+	if n.Leaf != "" {
+		b = appendCBORText(b, "Leaf")
+		b = appendCBORText(b, n.Leaf)
+	}
+	return b
+}
+
+// appendCBORHead appends the head of an item of type `major` with the
+// argument `v`, its length or value.
+func appendCBORHead(b []byte, major byte, v uint64) []byte {
+	switch {
+	case v < 24:
+		return append(b, major|byte(v))
+	case v <= 0xff:
+		return append(b, major|24, byte(v))
+	case v <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(v))
+	case v <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(v))
+	}
+	return binary.BigEndian.AppendUint64(append(b, major|27), v)
+}
+
+func appendCBORText(b []byte, s string) []byte {
+	if !utf8.ValidString(s) {
+		var sb strings.Builder
+		for i := 0; i < len(s); {
+			r, w := utf8.DecodeRuneInString(s[i:])
+			sb.WriteRune(r) // utf8.RuneError for each invalid byte
+			i += w
+		}
+		s = sb.String()
+	}
+	return append(appendCBORHead(b, cborText, uint64(len(s))), s...)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// cborDecoder reads a single tree from the front of `data`.
+type cborDecoder struct {
+	data   []byte
+	opts   DecodeOptions
+	nodes  int
+	strict []error // the problems found by strict decoding, see `strictField`
+}
+
+// head reads the head of the next item, returning its major type and
+// argument.
+func (d *cborDecoder) head(path string) (byte, uint64, error) {
+	if len(d.data) == 0 {
+		return 0, 0, decodeErrorf(path, "unexpected end of input")
+	}
+	major, info := d.data[0]&0xe0, d.data[0]&0x1f
+	d.data = d.data[1:]
+	if info < 24 {
+		return major, uint64(info), nil
+	}
+	if info > 27 {
+		return 0, 0, decodeErrorf(path, "unsupported CBOR item 0x%02x", major|info)
+	}
+	size := 1 << (info - 24)
+	if len(d.data) < size {
+		return 0, 0, decodeErrorf(path, "unexpected end of input")
+	}
+	var v uint64
+	for _, c := range d.data[:size] {
+		v = v<<8 | uint64(c)
+	}
+	d.data = d.data[size:]
+	return major, v, nil
+}
+
+// isCBORNull reports whether the head `major` and `v` is null.
+func isCBORNull(major byte, v uint64) bool {
+	return major == cborSimple && v == cborNull&0x1f
+}
+
+// bytes reads `n` bytes of the content of an item.
+func (d *cborDecoder) bytes(path string, n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)) {
+		return nil, decodeErrorf(path, "unexpected end of input")
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b, nil
+}
+
+// string reads a text string, or null as the empty string.
+func (d *cborDecoder) string(path string) (string, error) {
+	major, v, err := d.head(path)
+	if err != nil {
+		return "", err
+	}
+	if isCBORNull(major, v) {
+		return "", nil
+	}
+	if major != cborText {
+		return "", decodeErrorf(path, "expected a string")
+	}
+	b, err := d.bytes(path, v)
+	return string(b), err
+}
+
+func (d *cborDecoder) node(path string, depth int) (*Node, error) {
+	if d.opts.MaxDepth > 0 && depth > d.opts.MaxDepth {
+		return nil, limitErrorf(path, "deeper than %d nodes", d.opts.MaxDepth)
+	}
+	if depth > maxCBORDepth {
+		return nil, limitErrorf(path, "deeper than %d nodes", maxCBORDepth)
+	}
+	if d.nodes++; d.opts.MaxNodes > 0 && d.nodes > d.opts.MaxNodes {
+		return nil, limitErrorf(path, "more than %d nodes", d.opts.MaxNodes)
+	}
+
+	major, fields, err := d.head(path)
+	if err != nil {
+		return nil, err
+	}
+	if major != cborMap {
+		return nil, decodeErrorf(path, "expected a map")
+	}
+
+	n := &Node{}
+	seen := map[string]bool{}
+	for ; fields > 0; fields-- {
+		key, err := d.string(path)
+		if err != nil {
+			return nil, err
+		}
+		if d.opts.Strict {
+			d.strict = append(d.strict, strictField(seen, path, key))
+		}
+
+		// Keys are matched as `json.Unmarshal` matches them, ignoring case.
+		switch {
+		case strings.EqualFold(key, "Op"):
+			s, err := d.string(path + ".Op")
+			if err != nil {
+				return nil, err
+			}
+			n.Op = normalizeOperator(s)
+		case strings.EqualFold(key, "Leaf"):
+			if n.Leaf, err = d.string(path + ".Leaf"); err != nil {
+				return nil, err
+			}
+			if d.opts.MaxLeafLen > 0 && len(n.Leaf) > d.opts.MaxLeafLen {
+				return nil, limitErrorf(path+".Leaf", "longer than %d bytes", d.opts.MaxLeafLen)
+			}
+		case strings.EqualFold(key, "Nodes"):
+			if n.Nodes, err = d.children(path+".Nodes", depth); err != nil {
+				return nil, err
+			}
+		default:
+			if err := d.skip(path+"."+key, depth); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if d.opts.Strict {
+		d.strict = append(d.strict, strictNode(n, seen, path))
+	}
+	if err := normalizeNode(n); err != nil {
+		return nil, fmt.Errorf("invalid tree at %s: %w", path, err)
+	}
+	return n, nil
+}
+
+// children reads the children of the node at `depth`, or null as none.
+func (d *cborDecoder) children(path string, depth int) ([]*Node, error) {
+	major, count, err := d.head(path)
+	if err != nil {
+		return nil, err
+	}
+	if isCBORNull(major, count) {
+		return nil, nil
+	}
+	if major != cborArray {
+		return nil, decodeErrorf(path, "expected an array")
+	}
+	if count > uint64(len(d.data)) {
+		return nil, decodeErrorf(path, "unexpected end of input")
+	}
+
+	nodes := make([]*Node, 0, count)
+	for i := uint64(0); i < count; i++ {
+		c, err := d.node(path+"["+strconv.FormatUint(i, 10)+"]", depth+1)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, c)
+	}
+	return nodes, nil
+}
+
+// skip discards the value of an unknown field, nested `depth` deep.
+func (d *cborDecoder) skip(path string, depth int) error {
+	if depth > maxCBORDepth {
+		return limitErrorf(path, "deeper than %d items", maxCBORDepth)
+	}
+	major, v, err := d.head(path)
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborBytes, cborText:
+		_, err = d.bytes(path, v)
+		return err
+	case cborArray, cborMap:
+		items := v
+		if major == cborMap {
+			items *= 2
+		}
+		for ; items > 0; items-- {
+			if err := d.skip(path, depth+1); err != nil {
+				return err
+			}
+		}
+	case cborTag:
+		return d.skip(path, depth+1)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// jsonToCBOR transcodes a JSON document to CBOR, keeping the order and any
+// repetition of the fields of objects.
+func jsonToCBOR(src string) ([]byte, error) {
+	dec := json.NewDecoder(strings.NewReader(src))
+	dec.UseNumber()
+	return transcodeValue(dec, nil)
+}
+
+func transcodeValue(dec *json.Decoder, b []byte) ([]byte, error) {
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch v := t.(type) {
+	case nil:
+		return append(b, cborNull), nil
+	case bool:
+		if v {
+			return append(b, cborSimple|21), nil
+		}
+		return append(b, cborSimple|20), nil
+	case string:
+		return appendCBORText(b, v), nil
+	case json.Number:
+		if i, err := v.Int64(); err == nil && i >= 0 {
+			return appendCBORHead(b, 0, uint64(i)), nil
+		} else if err == nil {
+			return appendCBORHead(b, 1<<5, uint64(-1-i)), nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return binary.BigEndian.AppendUint64(append(b, cborSimple|27), math.Float64bits(f)), nil
+	}
+
+	var items []byte
+	count := uint64(0)
+	for ; dec.More(); count++ {
+		if items, err = transcodeValue(dec, items); err != nil {
+			return nil, err
+		}
+		if t == json.Delim('{') {
+			if items, err = transcodeValue(dec, items); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	major := byte(cborArray)
+	if t == json.Delim('{') {
+		major = cborMap
+	}
+	return append(appendCBORHead(b, major, count), items...), nil
+}
+
+func TestCBOR(t *testing.T) {
+	for _, n := range []*Node{pricesTree(), NewLeafNode("true"), NewNode(OperatorAnd), NewNode(OperatorOr, NewNode(OperatorAnd))} {
+		bs, err := n.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("MarshalCBOR() error: %s\n", err.Error())
+		}
+		js, _ := json.Marshal(n)
+		if len(bs) >= len(js) {
+			t.Errorf("MarshalCBOR() expected fewer than %d bytes, actual=%d\n", len(js), len(bs))
+		}
+
+		var actual Node
+		if err := actual.UnmarshalCBOR(bs); err != nil {
+			t.Errorf("UnmarshalCBOR(%x) error: %s\n", bs, err.Error())
+			continue
+		}
+		var expected Node
+		if err := json.Unmarshal(js, &expected); err != nil {
+			t.Fatalf("Unmarshal() error: %s\n", err.Error())
+		}
+		if !reflect.DeepEqual(&actual, &expected) {
+			t.Errorf("UnmarshalCBOR(%x) expected=%#v actual=%#v\n", bs, &expected, &actual)
+		}
+	}
+
+	// The encoding of a leaf, byte for byte.
+	bs, _ := NewLeafNode("true").MarshalCBOR()
+	expected := []byte{0xa2, 0x62, 'O', 'p', 0x64, 'l', 'e', 'a', 'f', 0x64, 'L', 'e', 'a', 'f', 0x66, '(', 't', 'r', 'u', 'e', ')'}
+	if !bytes.Equal(bs, expected) {
+		t.Errorf("MarshalCBOR() expected=%x actual=%x\n", expected, bs)
+	}
+}
+
+func TestCBORLimits(t *testing.T) {
+	wide := `{"Op": "or", "Nodes": [` + strings.Repeat(`{"Op": "leaf", "Leaf": "true"},`, 10) + `{"Op": "leaf", "Leaf": "true"}]}`
+	for _, tc := range []struct {
+		opts DecodeOptions
+		src  string
+		err  string
+	}{
+		{DecodeOptions{}, deepTree(500), ""},
+		{DecodeOptions{MaxDepth: 3}, deepTree(4), "limit exceeded at $.Nodes[0].Nodes[0].Nodes[0]: deeper than 3 nodes"},
+		{DecodeOptions{MaxNodes: 11}, wide, "limit exceeded at $.Nodes[10]: more than 11 nodes"},
+		{DecodeOptions{MaxLeafLen: 3}, `{"Op": "leaf", "Leaf": "true"}`, "limit exceeded at $.Leaf: longer than 3 bytes"},
+		{DecodeOptions{}, `{"op": "or", "Extra": {"a": [1, -2, 3.5, true, null, {"b": "c"}]}, "nodes": [{"Op": "leaf", "Leaf": null}]}`, ""},
+		{DecodeOptions{}, `{"Op": "and", "Nodes": [null]}`, "invalid tree at $.Nodes[0]: expected a map"},
+		{DecodeOptions{}, `{"Op": 1}`, "invalid tree at $.Op: expected a string"},
+		{DecodeOptions{}, `{"Op": "and", "Nodes": {}}`, "invalid tree at $.Nodes: expected an array"},
+		{DecodeOptions{}, `[]`, "invalid tree at $: expected a map"},
+		{DecodeOptions{Strict: true}, `{"Op": "or", "Nodes": [{"Op": "leaf", "Leaf": "(true)", "Comment": "x"}]}`, "invalid tree at $.Nodes[0].Comment: unknown field"},
+		{DecodeOptions{Strict: true}, `{"Op": "leaf", "Op": "and"}`, "invalid tree at $.Op: repeated field"},
+		{DecodeOptions{Strict: true}, `{"Op": "or", "Nodes": [{"Leaf": "(true)"}]}`, "invalid tree at $.Nodes[0]: missing Op"},
+		{DecodeOptions{Strict: true}, `{"Op": "and", "Leaf": "(true)", "Nodes": []}`, "invalid tree at $.Leaf: and node with a leaf"},
+		{DecodeOptions{Strict: true}, `{"Op": "or", "Nodes": [{"Op": "leaf", "Leaf": "(true)", "Comment": "x"}, {"Leaf": "(true)"}]}`, "invalid tree at $.Nodes[0].Comment: unknown field\ninvalid tree at $.Nodes[1]: missing Op"},
+	} {
+		bs, err := jsonToCBOR(tc.src)
+		if err != nil {
+			t.Fatalf("jsonToCBOR(%.40s) error: %s\n", tc.src, err.Error())
+		}
+		_, err = tc.opts.UnmarshalCBOR(bs)
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("UnmarshalCBOR(%.40s) unexpected error: %s\n", tc.src, err.Error())
+		case tc.err != "" && (err == nil || err.Error() != tc.err):
+			t.Errorf("UnmarshalCBOR(%.40s) expected error=%q actual=%v\n", tc.src, tc.err, err)
+		case strings.HasPrefix(tc.err, "limit") && !errors.Is(err, ErrLimitExceeded):
+			t.Errorf("UnmarshalCBOR(%.40s) expected=%v actual=%v\n", tc.src, ErrLimitExceeded, err)
+		}
+	}
+
+	deep := NewLeafNode("true")
+	for i := 0; i < maxCBORDepth; i++ {
+		deep = NewNode(OperatorAnd, deep)
+	}
+	bs, _ := deep.MarshalCBOR()
+	expected := "limit exceeded at $" + strings.Repeat(".Nodes[0]", maxCBORDepth) + ": deeper than 10000 nodes"
+	if _, err := (DecodeOptions{}).UnmarshalCBOR(bs); err == nil || err.Error() != expected {
+		t.Errorf("UnmarshalCBOR() expected error=%.60q actual=%.60v\n", expected, err)
+	}
+
+	leaf, _ := NewLeafNode("true").MarshalCBOR()
+	for _, bs := range [][]byte{
+		nil,
+		leaf[:len(leaf)-1],
+		append(leaf, 0xf6),
+		{0xbf, 0xff}, // indefinite length map
+		{0xa1, 0x65, 'N', 'o', 'd', 'e', 's', 0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, // huge array
+	} {
+		if _, err := (DecodeOptions{}).UnmarshalCBOR(bs); err == nil {
+			t.Errorf("UnmarshalCBOR(%x) expected an error\n", bs)
+		}
+	}
+}
+
+// FuzzCBOR checks that trees decoded from JSON survive a round trip through
+// CBOR unchanged, and that the CBOR of the same document decodes to the
+// same tree.
+func FuzzCBOR(f *testing.F) {
+	for _, src := range []string{
+		`{"Op": "leaf", "Leaf": "(true)"}`,
+		`{"Op": "and", "Nodes": [{"Op": "leaf", "Leaf": "(eq .A 1)"}, {"Op": "or", "Nodes": []}]}`,
+		`{"op": "or", "Extra": [1, 2.5, {"x": null}], "nodes": [{"Op": "advanced", "Leaf": "{{ true }}"}]}`,
+		`{"Op": "leaf", "Leaf": "é😀"}`,
+		deepTree(10),
+	} {
+		f.Add(src)
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		DefaultDecodeOptions.UnmarshalCBOR([]byte(src)) // must not panic
+
+		n, err := SafeUnmarshal([]byte(src))
+		if err != nil {
+			return
+		}
+		bs, err := n.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("MarshalCBOR() error: %s\n", err.Error())
+		}
+		actual, err := DefaultDecodeOptions.UnmarshalCBOR(bs)
+		if err != nil {
+			t.Fatalf("UnmarshalCBOR(%x) error: %s\n", bs, err.Error())
+		}
+		// As with JSON, empty children are not encoded and decode as nil.
+		js, _ := json.Marshal(n)
+		if actualJS, _ := json.Marshal(actual); !bytes.Equal(actualJS, js) {
+			t.Fatalf("UnmarshalCBOR(MarshalCBOR(%s)) expected=%s actual=%s\n", src, js, actualJS)
+		}
+
+		// The document transcoded to CBOR decodes to the same tree.
+		if bs, err = jsonToCBOR(src); err != nil {
+			return
+		}
+		if actual, err = DefaultDecodeOptions.UnmarshalCBOR(bs); err != nil || !reflect.DeepEqual(actual, n) {
+			t.Fatalf("UnmarshalCBOR(%x) expected=%#v actual=%#v (%v)\n", bs, n, actual, err)
+		}
+	})
+}
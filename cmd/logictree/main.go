@@ -0,0 +1,535 @@
+// Command logictree evaluates, formats, draws and converts logictree trees
+// from the shell, for pipelines and CI checks:
+//
+//	logictree eval [-std] [-missing policy] [-explain] tree data
+//	logictree coverage [-std] [-missing policy] tree records
+//	logictree fmt [-w] [-check] [-sort] tree...
+//	logictree lint [-std] tree...
+//	logictree messages -catalog catalog [-locales list] tree...
+//	logictree viz [-format tree|dot|mermaid] [-ascii] tree
+//	logictree convert [-from format] -to json|yaml|sexpr tree
+//
+// Trees are read from JSON files, as `logictree.Document`s or bare trees,
+// from YAML files holding the same documents, or from S-expressions, chosen by
+// `-from` or by the extension of the file (`.yaml` or `.yml`, `.sexpr`, and
+// JSON otherwise), `-` being the standard input.  `eval` prints the result of
+// the tree for the JSON document `data` and exits with 0 if it is true, 1 if
+// it is false and 2 on errors, as every command does.  `coverage` evaluates
+// the tree for every record of the JSON array `records` and prints how often
+// each leaf was true, false, failed, and decided the result, exiting with 1
+// if any leaf never did.  `fmt` rewrites trees in
+// the canonical layout of their format, with their operators and leaves
+// formatted by `logictree.Node.Format`, and `fmt -check` exits with 1 if any
+// is not.  `lint` lists the suspicious nodes of trees, see `logictree.Lint`,
+// and exits with 1 if any.  `messages` lists the messages which the JSON
+// `logictree.Catalog` `catalog` lacks for the leaves of trees in each of the
+// comma separated locales, by default every locale of the catalog but its
+// default, see `logictree.Catalog.Missing`, and exits with 1 if any.  `fmt`
+// and `convert` write bare trees.
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+const usage = `usage:
+	logictree eval [-std] [-missing policy] [-explain] tree data
+	logictree coverage [-std] [-missing policy] tree records
+	logictree fmt [-w] [-check] [-sort] tree...
+	logictree lint [-std] tree...
+	logictree messages -catalog catalog [-locales list] tree...
+	logictree viz [-format tree|dot|mermaid] [-ascii] tree
+	logictree convert [-from format] -to json|yaml|sexpr tree
+`
+
+const fromUsage = "format of the tree: json, yaml or sexpr, by default that of its extension or json"
+
+// Exit statuses.
+const (
+	exitTrue  = 0
+	exitFalse = 1
+	exitError = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// cli holds the streams of a single run.
+type cli struct {
+	stdin          io.Reader
+	stdout, stderr io.Writer
+}
+
+// run runs the command `args` and returns its exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitError
+	}
+	var err error
+	status := exitTrue
+	switch args[0] {
+	case "eval":
+		status, err = c.eval(args[1:])
+	case "coverage":
+		status, err = c.coverage(args[1:])
+	case "fmt":
+		status, err = c.fmt(args[1:])
+	case "lint":
+		status, err = c.lint(args[1:])
+	case "messages":
+		status, err = c.messages(args[1:])
+	case "viz":
+		err = c.viz(args[1:])
+	case "convert":
+		err = c.convert(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitTrue
+	default:
+		err = fmt.Errorf("unknown command %q\n%s", args[0], usage)
+	}
+	if err != nil {
+		if err != flag.ErrHelp {
+			fmt.Fprintf(stderr, "logictree %s: %s\n", args[0], err.Error())
+		}
+		return exitError
+	}
+	return status
+}
+
+// flags returns the flag set of the command `name`.
+func (c *cli) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// eval evaluates a tree against a data document.
+func (c *cli) eval(args []string) (int, error) {
+	fs := c.flags("eval")
+	std := fs.Bool("std", false, "provide the standard functions, see logictree.StdFuncs")
+	explain := fs.Bool("explain", false, "print the explanation of the result as JSON")
+	from := fs.String("from", "", fromUsage)
+	var missing logictree.MissingPolicy
+	fs.TextVar(&missing, "missing", logictree.MissingDefault, "policy for missing fields: default, false, true or error")
+	if err := fs.Parse(args); err != nil {
+		return exitError, err
+	}
+	if fs.NArg() != 2 {
+		return exitError, fmt.Errorf("expected a tree and a data file")
+	}
+
+	n, err := c.readTree(fs.Arg(0), *from)
+	if err != nil {
+		return exitError, err
+	}
+	src, err := c.read(fs.Arg(1))
+	if err != nil {
+		return exitError, err
+	}
+	var data interface{}
+	if err := json.Unmarshal(src, &data); err != nil {
+		return exitError, fmt.Errorf("%s: %w", fs.Arg(1), err)
+	}
+
+	ct, err := logictree.Compile(n, compileOptions(*std, missing)...)
+	if err != nil {
+		return exitError, err
+	}
+
+	var result bool
+	if *explain {
+		e, err := ct.Explain(data)
+		if err != nil {
+			return exitError, err
+		}
+		bs, err := json.MarshalIndent(e, "", "  ")
+		if err != nil {
+			return exitError, err
+		}
+		fmt.Fprintln(c.stdout, string(bs))
+		result = e.Result
+	} else {
+		if result, err = ct.Evaluate(data); err != nil {
+			return exitError, err
+		}
+		fmt.Fprintln(c.stdout, result)
+	}
+	if !result {
+		return exitFalse, nil
+	}
+	return exitTrue, nil
+}
+
+// coverage reports how often each leaf of a tree decided its result over a
+// set of records.
+func (c *cli) coverage(args []string) (int, error) {
+	fs := c.flags("coverage")
+	std := fs.Bool("std", false, "provide the standard functions, see logictree.StdFuncs")
+	from := fs.String("from", "", fromUsage)
+	var missing logictree.MissingPolicy
+	fs.TextVar(&missing, "missing", logictree.MissingDefault, "policy for missing fields: default, false, true or error")
+	if err := fs.Parse(args); err != nil {
+		return exitError, err
+	}
+	if fs.NArg() != 2 {
+		return exitError, fmt.Errorf("expected a tree and a records file")
+	}
+
+	n, err := c.readTree(fs.Arg(0), *from)
+	if err != nil {
+		return exitError, err
+	}
+	src, err := c.read(fs.Arg(1))
+	if err != nil {
+		return exitError, err
+	}
+	var records []interface{}
+	if err := json.Unmarshal(src, &records); err != nil {
+		return exitError, fmt.Errorf("%s: %w", fs.Arg(1), err)
+	}
+	ct, err := logictree.Compile(n, compileOptions(*std, missing)...)
+	if err != nil {
+		return exitError, err
+	}
+	cov, err := ct.Coverage(records)
+	if err != nil {
+		return exitError, err
+	}
+
+	status := exitTrue
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "PATH\tTRUE\tFALSE\tFAILED\tDECISIVE\tLEAF\n")
+	for _, lc := range cov.Leaves {
+		mark := ""
+		if lc.Dead() {
+			mark, status = "  (dead)", exitFalse
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%s%s\n", lc.Path, lc.True, lc.False, lc.Failed, lc.Decisive, lc.Leaf, mark)
+	}
+	if err := tw.Flush(); err != nil {
+		return exitError, err
+	}
+	fmt.Fprintf(c.stdout, "%d records, %d failed\n", cov.Total, cov.Failed)
+	return status, nil
+}
+
+// fmt rewrites trees in the canonical layout of their format.
+func (c *cli) fmt(args []string) (int, error) {
+	fs := c.flags("fmt")
+	write := fs.Bool("w", false, "write the result to the files rather than to the standard output")
+	check := fs.Bool("check", false, "list the files which are not formatted, and exit with 1 if any")
+	sorted := fs.Bool("sort", false, "sort the children of and and or nodes")
+	from := fs.String("from", "", fromUsage)
+	if err := fs.Parse(args); err != nil {
+		return exitError, err
+	}
+	if fs.NArg() == 0 {
+		return exitError, fmt.Errorf("expected at least one tree")
+	}
+
+	var opts []logictree.FormatOption
+	if *sorted {
+		opts = append(opts, logictree.FormatSortChildren())
+	}
+
+	status := exitTrue
+	for _, path := range fs.Args() {
+		src, err := c.read(path)
+		if err != nil {
+			return exitError, err
+		}
+		format := fileFormat(path, *from)
+		n, err := decodeTree(src, format)
+		if err != nil {
+			return exitError, fmt.Errorf("%s: %w", path, err)
+		}
+		out, err := encodeTree(n.Format(opts...), format)
+		if err != nil {
+			return exitError, fmt.Errorf("%s: %w", path, err)
+		}
+		switch {
+		case *check:
+			if !bytes.Equal(src, out) {
+				fmt.Fprintln(c.stdout, path)
+				status = exitFalse
+			}
+		case *write && path != "-":
+			if !bytes.Equal(src, out) {
+				if err := os.WriteFile(path, out, 0644); err != nil {
+					return exitError, err
+				}
+			}
+		default:
+			c.stdout.Write(out)
+		}
+	}
+	return status, nil
+}
+
+// lint lists the suspicious nodes of trees.
+func (c *cli) lint(args []string) (int, error) {
+	fs := c.flags("lint")
+	std := fs.Bool("std", false, "provide the standard functions, see logictree.StdFuncs")
+	from := fs.String("from", "", fromUsage)
+	if err := fs.Parse(args); err != nil {
+		return exitError, err
+	}
+	if fs.NArg() == 0 {
+		return exitError, fmt.Errorf("expected at least one tree")
+	}
+
+	status := exitTrue
+	for _, path := range fs.Args() {
+		n, err := c.readTree(path, *from)
+		if err != nil {
+			return exitError, err
+		}
+		for _, issue := range logictree.Lint(n, compileOptions(*std, logictree.MissingDefault)...) {
+			fmt.Fprintf(c.stdout, "%s:%s\n", path, issue)
+			status = exitFalse
+		}
+	}
+	return status, nil
+}
+
+// messages lists the messages a catalog lacks for the leaves of trees.
+func (c *cli) messages(args []string) (int, error) {
+	fs := c.flags("messages")
+	path := fs.String("catalog", "", "JSON file of the logictree.Catalog")
+	list := fs.String("locales", "", "comma separated locales, by default every locale of the catalog but its default")
+	from := fs.String("from", "", fromUsage)
+	if err := fs.Parse(args); err != nil {
+		return exitError, err
+	}
+	if fs.NArg() == 0 || *path == "" {
+		return exitError, fmt.Errorf("expected -catalog and at least one tree")
+	}
+
+	src, err := c.read(*path)
+	if err != nil {
+		return exitError, err
+	}
+	var catalog logictree.Catalog
+	if err := json.Unmarshal(src, &catalog); err != nil {
+		return exitError, fmt.Errorf("%s: %w", *path, err)
+	}
+	var locales []string
+	if *list != "" {
+		locales = strings.Split(*list, ",")
+	} else {
+		for l := range catalog.Messages {
+			if l != catalog.Default {
+				locales = append(locales, l)
+			}
+		}
+		sort.Strings(locales)
+	}
+	var trees []*logictree.Node
+	for _, p := range fs.Args() {
+		n, err := c.readTree(p, *from)
+		if err != nil {
+			return exitError, err
+		}
+		trees = append(trees, n)
+	}
+
+	status := exitTrue
+	missing := catalog.Missing(locales, trees...)
+	for _, l := range locales {
+		for _, id := range missing[l] {
+			fmt.Fprintf(c.stdout, "%s: %s\n", l, id)
+			status = exitFalse
+		}
+	}
+	return status, nil
+}
+
+// viz draws a tree.
+func (c *cli) viz(args []string) error {
+	fs := c.flags("viz")
+	format := fs.String("format", "tree", "drawing: tree, dot or mermaid")
+	ascii := fs.Bool("ascii", false, "draw trees with ASCII characters")
+	from := fs.String("from", "", fromUsage)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("expected a tree")
+	}
+	n, err := c.readTree(fs.Arg(0), *from)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "tree":
+		var opts []logictree.PrintOption
+		if *ascii {
+			opts = append(opts, logictree.PrintASCII())
+		}
+		return n.PrettyPrint(c.stdout, opts...)
+	case "dot":
+		return n.PrintDOT(c.stdout)
+	case "mermaid":
+		return n.PrintMermaid(c.stdout)
+	}
+	return fmt.Errorf("unknown drawing %q", *format)
+}
+
+// convert writes a tree in another format.
+func (c *cli) convert(args []string) error {
+	fs := c.flags("convert")
+	from := fs.String("from", "", fromUsage)
+	to := fs.String("to", "", "format to write: json, yaml or sexpr")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 || *to == "" {
+		return fmt.Errorf("expected a tree and -to")
+	}
+	n, err := c.readTree(fs.Arg(0), *from)
+	if err != nil {
+		return err
+	}
+	out, err := encodeTree(n, *to)
+	if err != nil {
+		return err
+	}
+	_, err = c.stdout.Write(out)
+	return err
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// read returns the contents of the file `path`, or of the standard input for
+// `-`.
+func (c *cli) read(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(c.stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// readTree decodes the tree of the file `path`, in the format given by
+// `fileFormat`.
+func (c *cli) readTree(path, from string) (*logictree.Node, error) {
+	format := fileFormat(path, from)
+	src, err := c.read(path)
+	if err != nil {
+		return nil, err
+	}
+	n, err := decodeTree(src, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return n, nil
+}
+
+// compileOptions returns the options of the `-std` and `-missing` flags.
+func compileOptions(std bool, missing logictree.MissingPolicy) []logictree.Option {
+	opts := []logictree.Option{logictree.WithMissing(missing)}
+	if std {
+		opts = append(opts, logictree.WithFuncs(logictree.StdFuncs()))
+	}
+	return opts
+}
+
+// fileFormat returns the format of the file `path`: `from` if it is set,
+// and otherwise that of its extension, JSON by default.
+func fileFormat(path, from string) string {
+	if from != "" {
+		return from
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".sexpr":
+		return "sexpr"
+	}
+	return "json"
+}
+
+// decodeTree decodes a tree in `format`.
+func decodeTree(src []byte, format string) (*logictree.Node, error) {
+	switch format {
+	case "json":
+		return logictree.UnmarshalDocument(src)
+	case "yaml":
+		var v interface{}
+		if err := yaml.Unmarshal(src, &v); err != nil {
+			return nil, err
+		}
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return logictree.UnmarshalDocument(bs)
+	case "sexpr":
+		return logictree.ParseSexpr(string(src))
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// encodeTree encodes a tree in `format`.
+func encodeTree(n *logictree.Node, format string) ([]byte, error) {
+	switch format {
+	case "json":
+		bs, err := json.MarshalIndent(n, "", "  ")
+		return append(bs, '\n'), err
+	case "yaml":
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(yamlNode(n)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), enc.Close()
+	case "sexpr":
+		return []byte(n.Sexpr() + "\n"), nil
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+// yamlNode returns the YAML mapping of a tree, with its fields in the order
+// JSON encodes them.
+func yamlNode(n *logictree.Node) *yaml.Node {
+	m := &yaml.Node{Kind: yaml.MappingNode}
+	add := func(key string, v *yaml.Node) {
+		m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, v)
+	}
+	add("Op", &yaml.Node{Kind: yaml.ScalarNode, Value: string(n.Op)})
+	if len(n.Nodes) > 0 {
+		nodes := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, c := range n.Nodes {
+			nodes.Content = append(nodes.Content, yamlNode(c))
+		}
+		add("Nodes", nodes)
+	}
+	if n.Leaf != "" {
+		add("Leaf", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: n.Leaf})
+	}
+	return m
+}
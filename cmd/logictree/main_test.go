@@ -0,0 +1,115 @@
+package main
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+const treeJSON = `{
+  "Op": "and",
+  "Nodes": [
+    {
+      "Op": "leaf",
+      "Leaf": "(ge .Age 18)"
+    },
+    {
+      "Op": "leaf",
+      "Leaf": "(in .Country [\"US\", \"CA\"])"
+    }
+  ]
+}
+`
+
+const treeYAML = `Op: and
+Nodes:
+  - Op: leaf
+    Leaf: (ge .Age 18)
+  - Op: leaf
+    Leaf: (in .Country ["US", "CA"])
+`
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"tree.json":   treeJSON,
+		"tree.yaml":   treeYAML,
+		"doc.json":    `{"Version": 1, "Tree": {"Op": "leaf", "Leaf": "(.A)"}}`,
+		"adult.json":  `{"Age": 20, "Country": "US"}`,
+		"minor.json":  `{"Age": 16, "Country": "US"}`,
+		"bad.json":    `{"Op": "and"`,
+		"msgs.json":   `{"Messages": {"en": {"ge .Age 18": "You must be 18 or older."}, "fr": {"ge .Age 18": "Vous devez avoir 18 ans ou plus.", "in .Country [\"US\", \"CA\"]": "Vous devez habiter en Amérique du Nord."}, "de": {}}, "Default": "en"}`,
+		"people.json": `[{"Age": 20, "Country": "US"}, {"Age": 16, "Country": "US"}, {"Age": 20, "Country": "FR"}]`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile(%s) error: %s\n", name, err.Error())
+		}
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	for _, tc := range []struct {
+		args     []string
+		stdin    string
+		status   int
+		expected string
+	}{
+		{[]string{"eval", "-std", path("tree.json"), path("adult.json")}, "", exitTrue, "true\n"},
+		{[]string{"eval", "-std", path("tree.yaml"), path("minor.json")}, "", exitFalse, "false\n"},
+		{[]string{"eval", "-std", "-from", "sexpr", "-", path("adult.json")}, `(and (ge .Age 21) (true))`, exitFalse, "false\n"},
+		{[]string{"eval", "-std", "-missing", "error", path("tree.json"), "-"}, `{"Age": 20}`, exitError, ""},
+		{[]string{"eval", path("doc.json"), "-"}, `{"A": true}`, exitTrue, "true\n"},
+		{[]string{"eval", path("bad.json"), path("adult.json")}, "", exitError, ""},
+		{[]string{"eval", path("tree.json")}, "", exitError, ""},
+
+		{[]string{"coverage", "-std", path("tree.json"), path("people.json")}, "", exitTrue, "PATH  TRUE  FALSE  FAILED  DECISIVE  LEAF\n/0    2     1      0       2         (ge .Age 18)\n/1    2     1      0       2         (in .Country [\"US\", \"CA\"])\n3 records, 0 failed\n"},
+		{[]string{"coverage", "-from", "sexpr", "-", path("people.json")}, `(or (ge .Age 18) (and (ge .Age 18) (eq .Country "FR")))`, exitFalse, "PATH  TRUE  FALSE  FAILED  DECISIVE  LEAF\n/0    2     1      0       3         (ge .Age 18)\n/1/0  2     1      0       3         (ge .Age 18)\n/1/1  1     2      0       0         (eq .Country \"FR\")  (dead)\n3 records, 0 failed\n"},
+		{[]string{"coverage", path("tree.json"), path("adult.json")}, "", exitError, ""},
+
+		{[]string{"convert", "-to", "yaml", path("tree.json")}, "", exitTrue, treeYAML},
+		{[]string{"convert", "-to", "json", path("tree.yaml")}, "", exitTrue, treeJSON},
+		{[]string{"convert", "-to", "sexpr", path("tree.yaml")}, "", exitTrue, "(and\n  (ge .Age 18)\n  (in .Country [\"US\", \"CA\"]))\n"},
+		{[]string{"convert", "-to", "toml", path("tree.yaml")}, "", exitError, ""},
+
+		{[]string{"fmt", "-check", path("tree.json"), path("tree.yaml")}, "", exitTrue, ""},
+		{[]string{"fmt", "-check", path("doc.json")}, "", exitFalse, path("doc.json") + "\n"},
+		{[]string{"fmt", "-"}, `{"Op":"leaf","Leaf":"(.A)"}`, exitTrue, "{\n  \"Op\": \"leaf\",\n  \"Leaf\": \"(.A)\"\n}\n"},
+		{[]string{"fmt", "-sort", "-from", "sexpr", "-"}, `(AND ( lt .B  2 ) (gt .A 1))`, exitTrue, "(and\n  (gt .A 1)\n  (lt .B 2))\n"},
+
+		{[]string{"viz", "-ascii", path("tree.json")}, "", exitTrue, "and\n|-- (ge .Age 18)\n`-- (in .Country [\"US\", \"CA\"])\n"},
+		{[]string{"viz", "-format", "mermaid", path("doc.json")}, "", exitTrue, "flowchart TD\n\tn(\"(.A)\")\n"},
+		{[]string{"viz", "-format", "png", path("doc.json")}, "", exitError, ""},
+
+		{[]string{"lint", "-std", path("tree.json"), path("tree.yaml")}, "", exitTrue, ""},
+		{[]string{"lint", "-from", "sexpr", "-"}, `(and (gt .X 5) (or (ge .Age 18) (ge .Age 18)) (lt .X 3))`, exitFalse, "-:/1/1: duplicate of /1/0 (duplicate)\n-:/2: never holds with /0 (contradiction)\n"},
+		{[]string{"lint"}, "", exitError, ""},
+
+		{[]string{"messages", "-catalog", path("msgs.json"), path("tree.json")}, "", exitFalse, "de: ge .Age 18\nde: in .Country [\"US\", \"CA\"]\n"},
+		{[]string{"messages", "-catalog", path("msgs.json"), "-locales", "fr-CA,en", path("tree.yaml")}, "", exitFalse, "en: in .Country [\"US\", \"CA\"]\n"},
+		{[]string{"messages", "-catalog", path("msgs.json"), "-locales", "fr", path("tree.json")}, "", exitTrue, ""},
+		{[]string{"messages", path("tree.json")}, "", exitError, ""},
+		{nil, "", exitError, ""},
+	} {
+		var stdout, stderr strings.Builder
+		status := run(tc.args, strings.NewReader(tc.stdin), &stdout, &stderr)
+		if status != tc.status || tc.expected != "" && stdout.String() != tc.expected {
+			t.Errorf("run(%v) expected=%d %q actual=%d %q %s\n", tc.args, tc.status, tc.expected, status, stdout.String(), stderr.String())
+		}
+		if status == exitError && stderr.Len() == 0 {
+			t.Errorf("run(%v) expected an error message\n", tc.args)
+		}
+	}
+
+	// Formatting rewrites files in place.
+	if status := run([]string{"fmt", "-w", path("doc.json")}, nil, &strings.Builder{}, &strings.Builder{}); status != exitTrue {
+		t.Errorf("run(fmt -w) expected=%d actual=%d\n", exitTrue, status)
+	}
+	if status := run([]string{"fmt", "-check", path("doc.json")}, nil, &strings.Builder{}, &strings.Builder{}); status != exitTrue {
+		t.Errorf("run(fmt -check) expected=%d actual=%d\n", exitTrue, status)
+	}
+}
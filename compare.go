@@ -0,0 +1,93 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Comparison is the outcome of evaluating two trees over the same records,
+// see `Compare`.
+type Comparison struct {
+	// Total is the number of records compared, and Agreed the number for
+	// which the trees had the same outcome.
+	Total  int `json:"Total"`
+	Agreed int `json:"Agreed"`
+
+	// Agreement is the fraction of the records for which the trees agreed,
+	// 1 if there are none.
+	Agreement float64 `json:"Agreement"`
+
+	// Divergences are the records for which they did not, in order.
+	Divergences []Divergence `json:"Divergences"`
+}
+
+// Divergence is a record for which two compared trees had different outcomes:
+// different results, or an error from only one of them.  The explanations
+// of the results are given for the trees which did not fail.
+type Divergence struct {
+	Index int         `json:"Index"`
+	Data  interface{} `json:"Data"`
+
+	A        bool         `json:"A"`
+	ErrorA   string       `json:"ErrorA,omitempty"`
+	ExplainA *Explanation `json:"ExplainA,omitempty"`
+
+	B        bool         `json:"B"`
+	ErrorB   string       `json:"ErrorB,omitempty"`
+	ExplainB *Explanation `json:"ExplainB,omitempty"`
+}
+
+// Compare evaluates the trees `a` and `b`, both compiled with `opts`, against
+// every record of `data`, and reports how often they agree and the records
+// for which they diverge, with the explanation of each result, so that a
+// rewritten rule can be checked against the one it replaces before it does.
+// The trees agree on a record if both evaluate it to the same result, or if
+// both fail.  Compare fails only if either tree fails to compile, with an
+// error naming it.
+func Compare(a, b *Node, data []interface{}, opts ...Option) (*Comparison, error) {
+	ca, err := Compile(a, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("a: %w", err)
+	}
+	cb, err := Compile(b, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("b: %w", err)
+	}
+	return CompareCompiled(ca, cb, data), nil
+}
+
+// CompareCompiled is `Compare` for trees which are already compiled, such as
+// the one in production and its candidate replacement.
+func CompareCompiled(a, b *CompiledTree, data []interface{}) *Comparison {
+	c := &Comparison{Total: len(data), Divergences: []Divergence{}}
+	for i, d := range data {
+		ra, erra := a.Evaluate(d)
+		rb, errb := b.Evaluate(d)
+		if (erra != nil) == (errb != nil) && (erra != nil || ra == rb) {
+			c.Agreed++
+			continue
+		}
+
+		div := Divergence{Index: i, Data: d, A: ra, B: rb}
+		if erra != nil {
+			div.ErrorA = erra.Error()
+		} else if e, err := a.Explain(d); err == nil {
+			div.ExplainA = e
+		}
+		if errb != nil {
+			div.ErrorB = errb.Error()
+		} else if e, err := b.Explain(d); err == nil {
+			div.ExplainB = e
+		}
+		c.Divergences = append(c.Divergences, div)
+	}
+
+	c.Agreement = 1
+	if c.Total > 0 {
+		c.Agreement = float64(c.Agreed) / float64(c.Total)
+	}
+	return c
+}
@@ -0,0 +1,49 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestCompare(t *testing.T) {
+	old := NewNode(OperatorAnd, NewLeafNode("ge .Age 18"), NewLeafNode("gt .Amount 100"))
+	rewritten := NewNode(OperatorAnd, NewLeafNode("gt .Amount 100"), NewLeafNode("gt .Age 18"))
+	data := []interface{}{
+		map[string]interface{}{"Age": 30, "Amount": 200},
+		map[string]interface{}{"Age": 18, "Amount": 200},
+		map[string]interface{}{"Age": 18, "Amount": 50},
+		map[string]interface{}{"Age": "x", "Amount": 200},
+		map[string]interface{}{"Age": "x", "Amount": 50},
+	}
+
+	c, err := Compare(old, rewritten, data)
+	if err != nil {
+		t.Fatalf("Compare() error: %s\n", err.Error())
+	}
+	if c.Total != 5 || c.Agreed != 3 || math.Abs(c.Agreement-0.6) > 1e-9 || len(c.Divergences) != 2 {
+		t.Fatalf("Compare() expected=5,3,0.6 actual=%d,%d,%v %v\n", c.Total, c.Agreed, c.Agreement, c.Divergences)
+	}
+
+	// The age of 18 differs.
+	d := c.Divergences[0]
+	if d.Index != 1 || !d.A || d.B || d.ExplainA == nil || d.ExplainB == nil || len(d.ExplainB.Flips) != 1 || d.ExplainB.Flips[0].Paths[0] != "/1" {
+		t.Errorf("Divergences[0] expected the age of 18, got %+v\n", d)
+	}
+	// Only the rewritten tree gets as far as the invalid age.
+	d = c.Divergences[1]
+	if d.Index != 4 || d.ErrorA == "" || d.ExplainA != nil || d.B || d.ErrorB != "" || d.ExplainB == nil {
+		t.Errorf("Divergences[1] expected the error of a, got %+v\n", d)
+	}
+
+	if c, err := Compare(old, old, nil); err != nil || c.Agreement != 1 || len(c.Divergences) != 0 {
+		t.Errorf("Compare(nil) expected=1 actual=%v err=%v\n", c, err)
+	}
+	if _, err := Compare(old, NewNode(OperatorAnd), data); !errors.Is(err, ErrEmptyNode) {
+		t.Errorf("Compare() expected=%v actual=%v\n", ErrEmptyNode, err)
+	}
+}
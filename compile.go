@@ -0,0 +1,382 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Option configures how a tree is compiled.
+type Option func(*compileOptions)
+
+type compileOptions struct {
+	funcs       template.FuncMap
+	parallelism int
+	missing     MissingPolicy
+	hooks       EvalHooks
+	incremental bool
+	backend     EvalBackend
+	shared      bool
+	cacheSize   int
+	resolver    Resolver
+	allowed     map[string]bool // nil unless compiled `WithAllowedFuncs`
+	budget      Budget
+	tracing     *tracing // nil unless compiled `WithTracer`
+	metrics     Metrics
+	logging     *logging // nil unless compiled `WithLogger`
+}
+
+// WithFuncs adds the `template.FuncMap` made available to the leaves of the
+// tree when it is evaluated.  Functions given by several `WithFuncs` options
+// are merged, those of later options replacing those of the same name, so
+// that custom functions can be given after `Config.Options` enables
+// `StdFuncs`.
+func WithFuncs(fm template.FuncMap) Option {
+	return func(o *compileOptions) {
+		merged := template.FuncMap{}
+		for k, v := range o.funcs {
+			merged[k] = v
+		}
+		for k, v := range fm {
+			merged[k] = v
+		}
+		o.funcs = merged
+	}
+}
+
+// WithParallelism evaluates the children of `and` and `or` nodes concurrently,
+// with at most `n` leaves executing at any one time.  As soon as a child
+// decides the result of its parent the remaining siblings are not started.
+// Values of `n` less than two evaluate sequentially (the default).
+func WithParallelism(n int) Option {
+	return func(o *compileOptions) {
+		o.parallelism = n
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// CompiledTree is a tree whose leaves have each been compiled into their own
+// template and which is ready to be evaluated against any number of data
+// contexts.  Nodes are evaluated natively, short-circuiting `and` and `or`.
+type CompiledTree struct {
+	root   *Node
+	eval   *compiledNode
+	opts   compileOptions
+	shared int          // the number of repeated subtrees, see `WithSharedEvaluation`
+	cache  *resultCache // nil unless compiled `WithCache`
+	fields [][]string   // the fields hashed by the cache
+
+	fingerprint string // of the root, set if the tree is traced or logged
+
+	mu sync.Mutex // serializes evaluations of an incremental tree
+}
+
+// compiledNode mirrors a `Node` with its leaf template parsed.
+type compiledNode struct {
+	node     *Node
+	path     string
+	tmpl     *template.Template
+	backend  EvalBackend // set, with prog, if a leaf is compiled by a backend
+	prog     interface{}
+	fields   [][]string    // fields referenced by a leaf, or by the guard of a case or a switch
+	opaque   bool          // a leaf is advanced or reads the data other than through fields
+	cmp      *comparison   // set if a leaf can be evaluated column-wise, or a switch looks up its field
+	index    []*fieldIndex // how the parts of the field of cmp are read, see `compareRow`
+	custom   OperatorImpl  // set for nodes of registered operators
+	sw       *switchIndex  // set for switches, whose cases hold the leaf of their guard
+	children []*compiledNode
+
+	volatile bool      // the result is never cached, see `WithIncremental`
+	cache    nodeCache // guarded by the tree's mutex
+
+	share int // the index of the result of a repeated subtree, or -1
+}
+
+// Compile validates and compiles the tree rooted at `n`.  Leaves are parsed
+// one by one, and those which do not parse, including those calling functions
+// which are not defined, fail with a `*ParseError` locating the problem.
+//
+// Literal patterns given to `matches` are compiled here, once, rather than on
+// every evaluation.  The precompiled `matches` is provided to every tree
+// unless `WithFuncs` supplies a custom function of that name.  Leaves may use
+// list literals such as `in .Country ["US", "CA"]`, which are rewritten into
+// calls to `list`, also provided to every tree.  The numeric comparisons from
+// `StdFuncs` replace the template builtins `eq`, `ne`, `lt`, `le`, `gt` and
+// `ge` unless `WithFuncs` supplies functions of those names, so that data
+// decoded from JSON compares correctly against integer literals.
+func Compile(n *Node, opts ...Option) (*CompiledTree, error) {
+	o := compileOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	ct, err := compile(n, o)
+	if err != nil && o.metrics != nil {
+		o.metrics.ObserveCompileError(err)
+	}
+	return ct, err
+}
+
+// compile is `Compile` with the options `o`.
+func compile(n *Node, o compileOptions) (*CompiledTree, error) {
+	if o.resolver != nil && n.hasRefs() {
+		var err error
+		if n, err = n.Resolve(o.resolver); err != nil {
+			return nil, err
+		}
+	}
+	if err := n.validate("/", o.backend == nil); err != nil {
+		return nil, err
+	}
+
+	c := &compiler{
+		opts:     &o,
+		patterns: map[string]*regexp.Regexp{},
+	}
+	c.funcs = c.leafFuncs()
+
+	cn, err := c.compileNode(n, "/")
+	if err != nil {
+		return nil, err
+	}
+	cn.markVolatile()
+	shared := 0
+	if o.shared {
+		shared = cn.markShared()
+	}
+
+	ct := &CompiledTree{
+		root:   n,
+		eval:   cn,
+		opts:   o,
+		shared: shared,
+	}
+	if ct.cache = newResultCache(cn, o.cacheSize); ct.cache != nil {
+		ct.fields = ct.Fields()
+	}
+	if o.tracing != nil || o.logging != nil {
+		ct.fingerprint = n.Fingerprint()
+	}
+	return ct, nil
+}
+
+// compiler holds the state used while compiling a single tree.
+type compiler struct {
+	opts     *compileOptions
+	funcs    template.FuncMap
+	patterns map[string]*regexp.Regexp // read only once compilation completes
+}
+
+// leafFuncs returns the functions available to leaves: those given by the
+// caller plus `list`, the numeric comparisons and the precompiled `matches`.
+func (c *compiler) leafFuncs() template.FuncMap {
+	fm := template.FuncMap{}
+	for k, v := range c.opts.funcs {
+		fm[k] = v
+	}
+
+	if f, ok := fm["matches"]; !ok || reflect.ValueOf(f).Pointer() == reflect.ValueOf(stdMatches).Pointer() {
+		fm["matches"] = c.matches
+	}
+	std := StdFuncs()
+	for _, name := range []string{"list", "eq", "ne", "lt", "le", "gt", "ge"} {
+		if _, ok := fm[name]; !ok {
+			fm[name] = std[name]
+		}
+	}
+	return fm
+}
+
+// isStd reports whether the leaves of the tree call the function of that name
+// from `StdFuncs`.
+func (c *compiler) isStd(name string) bool {
+	f, ok := c.funcs[name]
+	std, sok := StdFuncs()[name]
+	return ok && sok && reflect.ValueOf(f).Pointer() == reflect.ValueOf(std).Pointer()
+}
+
+// matches is `stdMatches` using the patterns compiled with the tree.
+func (c *compiler) matches(s, pattern string) (bool, error) {
+	if re, ok := c.patterns[pattern]; ok {
+		return re.MatchString(s), nil
+	}
+	return stdMatches(s, pattern)
+}
+
+func (c *compiler) compileNode(n *Node, path string) (*compiledNode, error) {
+	cn := &compiledNode{node: n, path: path}
+	switch n.Op {
+	case OperatorLeaf, OperatorAdvanced:
+		if n.Op == OperatorLeaf && c.opts.backend != nil {
+			if err := c.compileBackendLeaf(cn); err != nil {
+				return nil, nodeError(path, n, err)
+			}
+			break
+		}
+		if c.opts.allowed != nil {
+			if err := c.checkAllowed(n); err != nil {
+				return nil, nodeError(path, n, err)
+			}
+		}
+		if err := c.compileTemplate(cn, n); err != nil {
+			return nil, err
+		}
+	case OperatorAnd, OperatorOr, OperatorIf:
+		if err := c.compileChildren(cn); err != nil {
+			return nil, err
+		}
+	case OperatorSwitch:
+		if err := c.compileSwitch(cn); err != nil {
+			return nil, err
+		}
+	default:
+		impl, err := bindOperator(n)
+		if err != nil {
+			return nil, nodeError(path, n, err)
+		}
+		cn.custom = impl
+		if err := c.compileChildren(cn); err != nil {
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// compileTemplate compiles the template of the leaf `n` into `cn`.
+func (c *compiler) compileTemplate(cn *compiledNode, n *Node) error {
+	src := n.Leaf
+	if n.Op == OperatorLeaf {
+		src = "{{ " + leafSource(n.Leaf) + " }}"
+	}
+	tmpl, err := template.New("leaf").Funcs(c.funcs).Parse(src)
+	if err != nil {
+		if perr := leafParseError(n, c.funcs); perr != nil {
+			err = perr
+		}
+		return nodeError(cn.path, cn.node, err)
+	}
+	for _, p := range literalPatterns(tmpl.Tree) {
+		if _, ok := c.patterns[p]; !ok {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nodeError(cn.path, cn.node, fmt.Errorf("%w %q: %v", ErrInvalidPattern, p, err))
+			}
+			c.patterns[p] = re
+		}
+	}
+	if c.opts.missing == MissingIsError {
+		tmpl.Option("missingkey=error")
+	}
+	cn.tmpl = tmpl
+	cn.fields = leafFields(tmpl.Tree)
+	// The output of an advanced leaf is the whole of its template, so it is
+	// never folded or cached however few fields it reads.
+	cn.opaque = n.Op == OperatorAdvanced || opaqueLeaf(tmpl.Tree)
+	if cmp, ok := leafComparison(tmpl.Tree); ok && c.isStd(cmp.fn) {
+		cn.cmp = cmp
+		cn.index = make([]*fieldIndex, len(cmp.field))
+		for i := range cn.index {
+			cn.index[i] = &fieldIndex{}
+		}
+	}
+	return nil
+}
+
+// compileChildren compiles the children of the node `cn`, of which there
+// must be at least one.
+func (c *compiler) compileChildren(cn *compiledNode) error {
+	if len(cn.node.Nodes) == 0 {
+		return nodeError(cn.path, cn.node, ErrEmptyNode)
+	}
+	for i, child := range cn.node.Nodes {
+		cc, err := c.compileNode(child, childPath(cn.path, i))
+		if err != nil {
+			return err
+		}
+		cn.children = append(cn.children, cc)
+	}
+	return nil
+}
+
+// Root returns the tree that was compiled.
+func (ct *CompiledTree) Root() *Node {
+	return ct.root
+}
+
+// Fields returns the distinct fields of the data referenced by the leaves of
+// the tree, such as ["Dairy", "Milk"] for `.Dairy.Milk`, in the order they
+// first appear.  Fields reached through variables other than `$`, or through
+// the results of functions, are not included.
+func (ct *CompiledTree) Fields() [][]string {
+	seen := map[string]bool{}
+	fields := [][]string{}
+	var walk func(cn *compiledNode)
+	walk = func(cn *compiledNode) {
+		for _, f := range cn.fields {
+			k := strings.Join(f, ".")
+			if !seen[k] {
+				seen[k] = true
+				fields = append(fields, f)
+			}
+		}
+		for _, c := range cn.children {
+			walk(c)
+		}
+	}
+	walk(ct.eval)
+	return fields
+}
+
+// Opaque reports whether any leaf of the tree may read the data other than
+// through the fields returned by `Fields`, such as `len .` or
+// `index . "Milk"`, or is an advanced leaf.  Such trees need the whole of the
+// data rather than its fields alone.
+func (ct *CompiledTree) Opaque() bool {
+	var walk func(cn *compiledNode) bool
+	walk = func(cn *compiledNode) bool {
+		if cn.opaque {
+			return true
+		}
+		for _, c := range cn.children {
+			if walk(c) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(ct.eval)
+}
+
+// Evaluate executes the compiled tree against `data` and returns the truthy
+// result.
+func (ct *CompiledTree) Evaluate(data interface{}) (bool, error) {
+	return ct.EvaluateContext(context.Background(), data)
+}
+
+// childPath returns the path of the `i`th child of the node at `path`.  The
+// root is addressed as "/" and its second child as "/1".
+func childPath(path string, i int) string {
+	return strings.TrimSuffix(path, "/") + "/" + strconv.Itoa(i)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// parseResult converts the rendered output of a template into a boolean.
+func parseResult(s string) (bool, error) {
+	switch strings.TrimSpace(s) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	return false, fmt.Errorf("%w: %q", ErrNotBoolean, s)
+}
@@ -0,0 +1,53 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+// All returns a tree which is true if every one of `trees` is, composing
+// rules from a library of independent sub-rules.  The trees are copied, so
+// that the result shares no nodes with them and either may be changed
+// without affecting the other, and the children of those whose root is an
+// `and` become children of the new root, rather than nesting it.  A single
+// tree is returned as a copy, and no trees as the leaf `true`.  Nil trees
+// are skipped.
+func All(trees ...*Node) *Node {
+	return compose(OperatorAnd, trees)
+}
+
+// Any returns a tree which is true if any one of `trees` is, as `All` does
+// for `or`.  No trees are the leaf `false`.
+func Any(trees ...*Node) *Node {
+	return compose(OperatorOr, trees)
+}
+
+// Negate returns a copy of the tree rooted at `n` with the opposite result,
+// the negation pushed down to its leaves as for the `!` nodes of decoded
+// trees: `and` and `or` are swapped, `if` nodes and switches negate their
+// branches and leaves are wrapped in `not`.  Trees with advanced leaves or
+// registered operators cannot be negated, and fail with an error wrapping
+// `ErrInvalidOperator`.
+func Negate(n *Node) (*Node, error) {
+	return negate(n.copy())
+}
+
+// compose returns the tree combining copies of `trees` with `op`.
+func compose(op Operator, trees []*Node) *Node {
+	var nodes []*Node
+	for _, t := range trees {
+		switch {
+		case t == nil:
+		case t.Op == op:
+			for _, c := range t.Nodes {
+				nodes = append(nodes, c.copy())
+			}
+		default:
+			nodes = append(nodes, t.copy())
+		}
+	}
+	switch len(nodes) {
+	case 0:
+		return constantNode(op == OperatorAnd)
+	case 1:
+		return nodes[0]
+	}
+	return NewNode(op, nodes...)
+}
@@ -0,0 +1,90 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestCompose(t *testing.T) {
+	adult := NewLeafNode("ge .Age 18")
+	local := NewNode(OperatorOr, NewLeafNode(`eq .City "SF"`), NewLeafNode(`eq .City "LA"`))
+	vip := NewNode(OperatorAnd, NewLeafNode("gt .Spent 1000"), NewLeafNode("not .Banned"))
+
+	for _, tc := range []struct {
+		name     string
+		actual   *Node
+		expected string
+	}{
+		{"All", All(adult, local, vip), `.Age >= 18 AND (.City == "SF" OR .City == "LA") AND .Spent > 1000 AND NOT .Banned`},
+		{"Any", Any(adult, local, vip), `.Age >= 18 OR .City == "SF" OR .City == "LA" OR (.Spent > 1000 AND NOT .Banned)`},
+		{"All", All(nil, adult), ".Age >= 18"},
+		{"All", All(), "true"},
+		{"Any", Any(), "false"},
+		{"Any", Any(All(adult, vip), Any(local)), `(.Age >= 18 AND .Spent > 1000 AND NOT .Banned) OR .City == "SF" OR .City == "LA"`},
+	} {
+		if actual := tc.actual.Infix(); actual != tc.expected {
+			t.Errorf("%s() expected=%s actual=%s\n", tc.name, tc.expected, actual)
+		}
+	}
+
+	// The trees composed share no nodes with their parts.
+	all := All(adult, local)
+	all.Nodes[0].Leaf = "(false)"
+	all.Nodes[1].Nodes[0].Leaf = "(false)"
+	if adult.Leaf != "(ge .Age 18)" || local.Nodes[0].Leaf != `(eq .City "SF")` {
+		t.Errorf("All() expected copies of its trees, got %s and %s\n", adult.Infix(), local.Infix())
+	}
+
+	n, err := Negate(All(adult, local))
+	if err != nil {
+		t.Fatalf("Negate() error: %s\n", err.Error())
+	}
+	if expected := `NOT (.Age >= 18) OR (NOT (.City == "SF") AND NOT (.City == "LA"))`; n.Infix() != expected {
+		t.Errorf("Negate() expected=%s actual=%s\n", expected, n.Infix())
+	}
+	cond := NewIfNode(adult, vip, local)
+	n, err = Negate(cond)
+	if err != nil {
+		t.Fatalf("Negate() error: %s\n", err.Error())
+	}
+	n.Nodes[0].Leaf = "(false)"
+	if adult.Leaf != "(ge .Age 18)" {
+		t.Errorf("Negate() expected a copy of its tree, got %s\n", adult.Infix())
+	}
+	if _, err := Negate(NewAdvancedLeafNode("{{ .A }}")); !errors.Is(err, ErrInvalidOperator) {
+		t.Errorf("Negate() expected=%v actual=%v\n", ErrInvalidOperator, err)
+	}
+
+	// Leaves built by hand need not be parenthesized.
+	bare := NewNode(OperatorAnd, &Node{Op: OperatorLeaf, Leaf: "eq .A 1"}, NewNode(OperatorOr, &Node{Op: OperatorLeaf, Leaf: "gt .B 2"}, &Node{Op: OperatorLeaf, Leaf: ".C"}))
+	neg, err := Negate(bare)
+	if err != nil {
+		t.Fatalf("Negate() error: %s\n", err.Error())
+	}
+	ctBare, err := Compile(bare)
+	if err != nil {
+		t.Fatalf("Compile(%s) error: %s\n", bare, err.Error())
+	}
+	ctNeg, err := Compile(neg)
+	if err != nil {
+		t.Fatalf("Compile(%s) error: %s\n", neg, err.Error())
+	}
+	for _, data := range []map[string]interface{}{
+		{"A": 1, "B": 3, "C": false},
+		{"A": 1, "B": 2, "C": false},
+		{"A": 1, "B": 2, "C": true},
+		{"A": 2, "B": 3, "C": true},
+	} {
+		expected, err := ctBare.Evaluate(data)
+		if err != nil {
+			t.Fatalf("Evaluate(%s) error: %s\n", bare, err.Error())
+		}
+		if actual, err := ctNeg.Evaluate(data); err != nil || actual != !expected {
+			t.Errorf("Evaluate(%s, %v) expected=%v actual=%v err=%v\n", neg, data, !expected, actual, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Config declares how trees are compiled, for deployment tooling which keeps
+// that configuration in JSON or YAML files rather than in code.  The zero
+// value compiles as `Compile` does without options.  Settings which cannot be
+// written down, such as custom functions, hooks and backends, are still given
+// as options following those of the config:
+//
+//	opts := append(cfg.Options(), logictree.WithHooks(h))
+//	ct, err := logictree.Compile(tree, opts...)
+type Config struct {
+	// StdFuncs makes `StdFuncs` available to leaves, see `WithFuncs`.
+	StdFuncs bool `json:"stdFuncs,omitempty" yaml:"stdFuncs,omitempty"`
+
+	// Parallelism bounds the leaves executed concurrently, see
+	// `WithParallelism`.
+	Parallelism int `json:"parallelism,omitempty" yaml:"parallelism,omitempty"`
+
+	// Missing is the policy for missing fields, written as "default",
+	// "false" or "error", see `WithMissing`.
+	Missing MissingPolicy `json:"missing,omitempty" yaml:"missing,omitempty"`
+
+	// Incremental caches node results between evaluations, see
+	// `WithIncremental`.
+	Incremental bool `json:"incremental,omitempty" yaml:"incremental,omitempty"`
+
+	// AllowedFuncs, unless empty, are the only functions leaves may call,
+	// see `WithAllowedFuncs`.
+	AllowedFuncs []string `json:"allowedFuncs,omitempty" yaml:"allowedFuncs,omitempty"`
+}
+
+// Validate checks that every setting of the config is within range,
+// returning an error wrapping `ErrInvalidConfig` otherwise.
+func (c Config) Validate() error {
+	if c.Parallelism < 0 {
+		return fmt.Errorf("%w: parallelism %d is negative", ErrInvalidConfig, c.Parallelism)
+	}
+	if _, err := c.Missing.MarshalText(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Options returns the options equivalent to the config.
+func (c Config) Options() []Option {
+	opts := []Option{
+		WithParallelism(c.Parallelism),
+		WithMissing(c.Missing),
+	}
+	if c.StdFuncs {
+		opts = append(opts, WithFuncs(StdFuncs()))
+	}
+	if c.Incremental {
+		opts = append(opts, WithIncremental())
+	}
+	if len(c.AllowedFuncs) > 0 {
+		opts = append(opts, WithAllowedFuncs(c.AllowedFuncs...))
+	}
+	return opts
+}
+
+// CompileConfig validates `c` and compiles the tree rooted at `n` with its
+// options followed by `opts`.
+func CompileConfig(n *Node, c Config, opts ...Option) (*CompiledTree, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return Compile(n, append(c.Options(), opts...)...)
+}
@@ -0,0 +1,101 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestConfigJSON(t *testing.T) {
+	for _, tc := range []struct {
+		src      string
+		expected Config
+		err      error
+	}{
+		{`{}`, Config{}, nil},
+		{`{"stdFuncs": true, "parallelism": 4, "missing": "false", "incremental": true}`,
+			Config{StdFuncs: true, Parallelism: 4, Missing: MissingIsFalse, Incremental: true}, nil},
+		{`{"missing": "error"}`, Config{Missing: MissingIsError}, nil},
+		{`{"allowedFuncs": ["eq", "gt"]}`, Config{AllowedFuncs: []string{"eq", "gt"}}, nil},
+		{`{"missing": "sometimes"}`, Config{}, ErrInvalidConfig},
+	} {
+		var c Config
+		err := json.Unmarshal([]byte(tc.src), &c)
+		if !errors.Is(err, tc.err) {
+			t.Errorf("Unmarshal(%s) expected error=%v actual=%v\n", tc.src, tc.err, err)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(c, tc.expected) {
+			t.Errorf("Unmarshal(%s) expected=%+v actual=%+v\n", tc.src, tc.expected, c)
+		}
+	}
+
+	bs, err := json.Marshal(Config{Missing: MissingIsError, Parallelism: 2})
+	if err != nil {
+		t.Fatalf("Marshal() error: %s\n", err.Error())
+	}
+	if expected := `{"parallelism":2,"missing":"error"}`; string(bs) != expected {
+		t.Errorf("Marshal() expected=%s actual=%s\n", expected, bs)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		c   Config
+		err error
+	}{
+		{Config{}, nil},
+		{Config{Parallelism: 8, Missing: MissingIsError}, nil},
+		{Config{Parallelism: -1}, ErrInvalidConfig},
+		{Config{Missing: MissingPolicy(7)}, ErrInvalidConfig},
+	} {
+		if err := tc.c.Validate(); !errors.Is(err, tc.err) {
+			t.Errorf("Validate(%+v) expected=%v actual=%v\n", tc.c, tc.err, err)
+		}
+		if _, err := CompileConfig(pricesTree(), tc.c); !errors.Is(err, tc.err) {
+			t.Errorf("CompileConfig(%+v) expected=%v actual=%v\n", tc.c, tc.err, err)
+		}
+	}
+}
+
+func TestCompileConfig(t *testing.T) {
+	ct, err := CompileConfig(NewLeafNode(`hasPrefix .Name "lo"`), Config{StdFuncs: true, Missing: MissingIsFalse})
+	if err != nil {
+		t.Fatalf("CompileConfig() error: %s\n", err.Error())
+	}
+	for _, tc := range []struct {
+		data     map[string]interface{}
+		expected bool
+	}{
+		{map[string]interface{}{"Name": "logictree"}, true},
+		{map[string]interface{}{}, false},
+	} {
+		if v, err := ct.Evaluate(tc.data); err != nil || v != tc.expected {
+			t.Errorf("Evaluate(%v) expected=%v actual=%v (%v)\n", tc.data, tc.expected, v, err)
+		}
+	}
+}
+
+func TestCompileConfigFuncs(t *testing.T) {
+	// Custom functions are merged over `StdFuncs`, replacing those of the
+	// same name.
+	fm := template.FuncMap{
+		"shout":     strings.ToUpper,
+		"hasPrefix": func(s, p string) bool { return strings.HasPrefix(strings.ToLower(s), p) },
+	}
+	n := NewNode(OperatorAnd, NewLeafNode(`hasPrefix .Name "lo"`), NewLeafNode(`eq (shout .Name) "LOGICTREE"`), NewLeafNode("between .N 1 3"))
+	ct, err := CompileConfig(n, Config{StdFuncs: true}, WithFuncs(fm))
+	if err != nil {
+		t.Fatalf("CompileConfig() error: %s\n", err.Error())
+	}
+	if v, err := ct.Evaluate(map[string]interface{}{"Name": "LogicTree", "N": 2}); err != nil || !v {
+		t.Errorf("Evaluate() expected=true actual=%v (%v)\n", v, err)
+	}
+}
@@ -0,0 +1,154 @@
+// Package conformance publishes the fixture corpus which defines how
+// serialized logictree trees evaluate, so that implementations in other
+// languages can verify they behave identically to the Go package.
+//
+// The fixtures live in the `cases` directory as JSON arrays of `Case`
+// objects and are also embedded in this package.  Leaves use the functions
+// from `logictree.StdFuncs`.
+package conformance
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Error kinds used by the `Error` field of a `Case`.
+const (
+	ErrorEmptyNode       = "empty_node"       // an and/or node has no children
+	ErrorInvalidOperator = "invalid_operator" // a node's Op is not known
+	ErrorNotBoolean      = "not_boolean"      // a leaf rendered something other than true/false
+	ErrorParse           = "parse"            // a leaf is not a valid expression
+	ErrorInvalidPattern  = "invalid_pattern"  // a literal pattern given to matches is not a valid regexp
+	ErrorExecute         = "execute"          // a leaf failed while being evaluated
+	ErrorNotExpression   = "not_expression"   // a leaf is more than a single expression
+)
+
+//go:embed cases/*.json
+var fixtures embed.FS
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Case is a single conformance fixture: evaluating `Tree` against `Data`
+// must produce `Expected`, or fail with the kind of error named by `Error`.
+type Case struct {
+	Name        string          `json:"Name"`
+	Description string          `json:"Description,omitempty"`
+	Tree        json.RawMessage `json:"Tree"`
+	Data        json.RawMessage `json:"Data,omitempty"`
+	Expected    bool            `json:"Expected,omitempty"`
+	Error       string          `json:"Error,omitempty"`
+}
+
+// Evaluator evaluates the JSON encoded `tree` against the JSON encoded
+// `data`, which may be empty.
+type Evaluator func(tree, data []byte) (bool, error)
+
+// Cases returns every fixture in the corpus, ordered by file name and then by
+// position within the file.
+func Cases() ([]Case, error) {
+	names, err := fs.Glob(fixtures, "cases/*.json")
+	if err != nil {
+		return nil, err
+	}
+
+	cases := []Case{}
+	for _, name := range names {
+		bs, err := fixtures.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+
+		cs := []Case{}
+		if err := json.Unmarshal(bs, &cs); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		cases = append(cases, cs...)
+	}
+	return cases, nil
+}
+
+// Run executes every fixture against `eval` as a subtest of `t`.  Only the
+// presence of an error is checked for error cases, since other
+// implementations are not expected to reproduce Go's error values.
+func Run(t *testing.T, eval Evaluator) {
+	cases, err := Cases()
+	if err != nil {
+		t.Fatalf("conformance: loading cases: %s\n", err.Error())
+	}
+
+	for _, c := range cases {
+		c := c
+		t.Run(c.Name, func(t *testing.T) {
+			v, err := eval(c.Tree, c.Data)
+			switch {
+			case c.Error != "" && err == nil:
+				t.Errorf("expected a %s error, got result=%v\n", c.Error, v)
+			case c.Error == "" && err != nil:
+				t.Errorf("unexpected error: %s\n", err.Error())
+			case c.Error == "" && v != c.Expected:
+				t.Errorf("expected=%v actual=%v\n", c.Expected, v)
+			}
+		})
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Reference is the `Evaluator` implemented by the Go package.
+func Reference(tree, data []byte) (bool, error) {
+	n := &logictree.Node{}
+	if err := json.Unmarshal(tree, n); err != nil {
+		return false, err
+	}
+
+	var d interface{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &d); err != nil {
+			return false, err
+		}
+	}
+
+	ct, err := logictree.Compile(n, logictree.WithFuncs(logictree.StdFuncs()))
+	if err != nil {
+		return false, err
+	}
+	return ct.Evaluate(d)
+}
+
+// ErrorKind classifies an error returned by `Reference` into one of the
+// error kinds used by the fixtures.
+func ErrorKind(err error) string {
+	var execErr template.ExecError
+	var parseErr *logictree.ParseError
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, logictree.ErrEmptyNode):
+		return ErrorEmptyNode
+	case errors.Is(err, logictree.ErrInvalidOperator):
+		return ErrorInvalidOperator
+	case errors.Is(err, logictree.ErrNotBoolean):
+		return ErrorNotBoolean
+	case errors.Is(err, logictree.ErrInvalidPattern):
+		return ErrorInvalidPattern
+	case errors.Is(err, logictree.ErrNotExpression):
+		return ErrorNotExpression
+	case errors.As(err, &execErr):
+		return ErrorExecute
+	case errors.As(err, &parseErr), strings.Contains(err.Error(), "template:"):
+		return ErrorParse
+	}
+	return ""
+}
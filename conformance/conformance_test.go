@@ -0,0 +1,30 @@
+package conformance
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestReference(t *testing.T) {
+	Run(t, Reference)
+}
+
+func TestReferenceErrorKinds(t *testing.T) {
+	cases, err := Cases()
+	if err != nil {
+		t.Fatalf("Cases() error: %s\n", err.Error())
+	}
+
+	for _, c := range cases {
+		if c.Error == "" {
+			continue
+		}
+		_, err := Reference(c.Tree, c.Data)
+		if k := ErrorKind(err); k != c.Error {
+			t.Errorf("%s: expected error kind=%s actual=%s (%v)\n", c.Name, c.Error, k, err)
+		}
+	}
+}
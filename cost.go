@@ -0,0 +1,263 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// LeafCost is what evaluating a leaf costs, in any unit, and its selectivity,
+// the probability that it is true.
+type LeafCost struct {
+	Cost        float64 `json:"cost" yaml:"cost"`
+	Selectivity float64 `json:"selectivity" yaml:"selectivity"`
+}
+
+// DefaultLeafCost is the cost of leaves a `CostModel` has none for, unless it
+// sets `Default`.
+var DefaultLeafCost = LeafCost{Cost: 1, Selectivity: 0.5}
+
+// CostModel assigns costs to the leaves of trees, so that the children of
+// `and` and `or` nodes can be ordered to evaluate cheap and decisive leaves
+// first, such as a comparison before a leaf calling out to a service.
+type CostModel struct {
+	// Leaves maps a leaf to its cost.  Leaves are written as in trees,
+	// whitespace and redundant parentheses aside, so "gt .Price 5" is the
+	// cost of `(gt  .Price 5)`.  Advanced leaves are written as they are.
+	Leaves map[string]LeafCost `json:"leaves,omitempty" yaml:"leaves,omitempty"`
+
+	// Default is the cost of other leaves, `DefaultLeafCost` if zero.  The
+	// leaves `true` and `false` are free and decided.
+	Default LeafCost `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// Validate checks that every cost is non-negative and every selectivity is
+// within [0, 1], returning an error wrapping `ErrInvalidConfig` otherwise.
+func (m CostModel) Validate() error {
+	check := func(name string, c LeafCost) error {
+		if c.Cost < 0 || math.IsNaN(c.Cost) || math.IsInf(c.Cost, 0) {
+			return fmt.Errorf("%w: cost of %s is %v", ErrInvalidConfig, name, c.Cost)
+		}
+		if !(c.Selectivity >= 0 && c.Selectivity <= 1) {
+			return fmt.Errorf("%w: selectivity of %s is %v, not within [0, 1]", ErrInvalidConfig, name, c.Selectivity)
+		}
+		return nil
+	}
+	if err := check("default", m.Default); err != nil {
+		return err
+	}
+	for leaf, c := range m.Leaves {
+		if err := check(fmt.Sprintf("%q", leaf), c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Cost returns the expected cost of evaluating the tree rooted at `n`, and
+// the probability that it is true, taking every leaf as independent of the
+// others and `and` and `or` as short-circuiting from their first child.
+func (m CostModel) Cost(n *Node) (float64, float64, error) {
+	if err := m.Validate(); err != nil {
+		return 0, 0, err
+	}
+	if err := n.validate("/", false); err != nil {
+		return 0, 0, err
+	}
+	c, p := m.costs().cost(n)
+	return c, p, nil
+}
+
+// Reorder returns a copy of the tree rooted at `n` with the children of every
+// `and` and `or` ordered so that its expected cost, as given by `Cost`, is
+// least: an `and` first evaluates the children which are cheapest for how
+// likely they are to be false, and an `or` those which are cheapest for how
+// likely they are to be true.  Children which are alike keep their order.
+//
+// Like `Canonicalize`, reordering assumes leaves are free of side effects.
+// The tree is not modified.
+func (m CostModel) Reorder(n *Node) (*Node, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if err := n.validate("/", false); err != nil {
+		return nil, err
+	}
+	r, _, _ := m.costs().reorder(n)
+	return r, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// leafCosts is a `CostModel` with its leaves normalized.
+type leafCosts struct {
+	leaves map[string]LeafCost
+	def    LeafCost
+}
+
+func (m CostModel) costs() *leafCosts {
+	lc := &leafCosts{leaves: map[string]LeafCost{}, def: m.Default}
+	if lc.def == (LeafCost{}) {
+		lc.def = DefaultLeafCost
+	}
+	for leaf, c := range m.Leaves {
+		lc.leaves[leafVariable(NewLeafNode(leaf))] = c
+		lc.leaves[leaf] = c
+	}
+	return lc
+}
+
+// leaf returns the cost of the leaf `n`.
+func (lc *leafCosts) leaf(n *Node) LeafCost {
+	name := leafVariable(n)
+	if n.Op == OperatorLeaf {
+		if _, _, constant, ok := leafLiteral(name); !ok {
+			c := LeafCost{}
+			if constant {
+				c.Selectivity = 1
+			}
+			return c
+		}
+	}
+	if c, ok := lc.leaves[name]; ok {
+		return c
+	}
+	return lc.def
+}
+
+// cost returns the expected cost of the node and its probability of being
+// true, evaluating its children in the order they are in.
+func (lc *leafCosts) cost(n *Node) (float64, float64) {
+	if n.isLeaf() {
+		c := lc.leaf(n)
+		return c.Cost, c.Selectivity
+	}
+	if n.Op == OperatorSwitch {
+		// A switch costs as the `if` nodes testing its cases in turn.
+		return lc.cost(expandSwitch(n))
+	}
+	costs, probs := make([]float64, len(n.Nodes)), make([]float64, len(n.Nodes))
+	for i, c := range n.Nodes {
+		costs[i], probs[i] = lc.cost(c)
+	}
+	return combineCosts(n.Op, costs, probs)
+}
+
+// reorder returns a reordered copy of the node with its expected cost and
+// probability of being true.
+func (lc *leafCosts) reorder(n *Node) (*Node, float64, float64) {
+	if n.isLeaf() {
+		c := lc.leaf(n)
+		return &Node{Op: n.Op, Leaf: n.Leaf}, c.Cost, c.Selectivity
+	}
+	if n.Op == OperatorSwitch {
+		// The cases of a switch keep their order, their children are
+		// reordered.
+		r := &Node{Op: n.Op, Leaf: n.Leaf, Nodes: make([]*Node, len(n.Nodes))}
+		for i, c := range n.Nodes {
+			r.Nodes[i] = &Node{Op: c.Op, Leaf: c.Leaf}
+			for _, child := range c.Nodes {
+				rc, _, _ := lc.reorder(child)
+				r.Nodes[i].Nodes = append(r.Nodes[i].Nodes, rc)
+			}
+		}
+		cost, prob := lc.cost(r)
+		return r, cost, prob
+	}
+
+	type child struct {
+		n          *Node
+		cost, prob float64
+	}
+	children := make([]child, len(n.Nodes))
+	for i, c := range n.Nodes {
+		children[i].n, children[i].cost, children[i].prob = lc.reorder(c)
+	}
+
+	if keepsOrder(n.Op) {
+		// The order of the children of registered operators and conditions
+		// is kept.
+		r := &Node{Op: n.Op, Leaf: n.Leaf, Nodes: make([]*Node, len(children))}
+		costs, probs := make([]float64, len(children)), make([]float64, len(children))
+		for i, c := range children {
+			r.Nodes[i], costs[i], probs[i] = c.n, c.cost, c.prob
+		}
+		cost, prob := combineCosts(n.Op, costs, probs)
+		return r, cost, prob
+	}
+
+	// The chance that a child decides its parent and stops the evaluation.
+	d := decisive(n.Op)
+	stops := func(c child) float64 {
+		if d {
+			return c.prob
+		}
+		return 1 - c.prob
+	}
+	// Children are ordered by their cost for each evaluation they stop, which
+	// minimizes the expected cost of independent children.
+	rank := func(c child) float64 {
+		if stops(c) == 0 {
+			return math.Inf(1)
+		}
+		return c.cost / stops(c)
+	}
+	sort.SliceStable(children, func(i, j int) bool {
+		ri, rj := rank(children[i]), rank(children[j])
+		if ri == rj {
+			// Of free children, or never decisive ones, the likelier to stop
+			// goes first.
+			return stops(children[i]) > stops(children[j])
+		}
+		return ri < rj
+	})
+
+	r := &Node{Op: n.Op, Nodes: make([]*Node, len(children))}
+	costs, probs := make([]float64, len(children)), make([]float64, len(children))
+	for i, c := range children {
+		r.Nodes[i], costs[i], probs[i] = c.n, c.cost, c.prob
+	}
+	cost, prob := combineCosts(n.Op, costs, probs)
+	return r, cost, prob
+}
+
+// combineCosts returns the expected cost and probability of being true of an
+// `and` or `or` of independent children with the `costs` and `probs` given,
+// in order.
+func combineCosts(op Operator, costs, probs []float64) (float64, float64) {
+	if op == OperatorIf {
+		// The condition is always evaluated, and then one of the branches.
+		p := probs[0]
+		return costs[0] + p*costs[1] + (1-p)*costs[2], p*probs[1] + (1-p)*probs[2]
+	}
+	if isCustom(op) {
+		// Every child of a registered operator is evaluated, and how likely
+		// it is to be true is not known.
+		cost := 0.0
+		for _, c := range costs {
+			cost += c
+		}
+		return cost, 0.5
+	}
+
+	// Each child is only evaluated if those before it left the result
+	// undecided.
+	d := decisive(op)
+	cost, undecided := 0.0, 1.0
+	for i := range costs {
+		cost += undecided * costs[i]
+		if d {
+			undecided *= 1 - probs[i]
+		} else {
+			undecided *= probs[i]
+		}
+	}
+	if d {
+		return cost, 1 - undecided
+	}
+	return cost, undecided
+}
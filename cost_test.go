@@ -0,0 +1,89 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestCostModelReorder(t *testing.T) {
+	m := CostModel{Leaves: map[string]LeafCost{
+		"remoteCheck .User":       {Cost: 100, Selectivity: 0.9},
+		"gt .Price 5":             {Cost: 1, Selectivity: 0.1},
+		"eq .Country \"US\"":      {Cost: 1, Selectivity: 0.8},
+		"(hasPrefix .Name \"o\")": {Cost: 2, Selectivity: 0.5},
+	}}
+	for _, tc := range []struct {
+		n        *Node
+		expected string
+	}{
+		// The selective and cheap comparison rejects first.
+		{NewNode(OperatorAnd, NewLeafNode("remoteCheck .User"), NewLeafNode(`eq  .Country "US"`), NewLeafNode("gt .Price 5")),
+			`and ((gt .Price 5)) (and ((eq  .Country "US")) ((remoteCheck .User)))`},
+		// An `or` accepts on its likeliest cheap children first.
+		{NewNode(OperatorOr, NewLeafNode("gt .Price 5"), NewLeafNode(`hasPrefix .Name "o"`), NewLeafNode(`eq .Country "US"`)),
+			`or ((eq .Country "US")) (or ((hasPrefix .Name "o")) ((gt .Price 5)))`},
+		{NewNode(OperatorAnd, NewLeafNode("remoteCheck .User"), NewLeafNode("false")),
+			`and ((false)) ((remoteCheck .User))`},
+		// Unknown leaves keep their order, subtrees are ordered by their own
+		// cost.
+		{NewNode(OperatorAnd,
+			NewNode(OperatorOr, NewLeafNode("eq .A 1"), NewLeafNode("remoteCheck .User")),
+			NewLeafNode("eq .B 1"),
+			NewLeafNode("eq .C 1")),
+			`and ((eq .B 1)) (and ((eq .C 1)) (or ((eq .A 1)) ((remoteCheck .User))))`},
+	} {
+		r, err := m.Reorder(tc.n)
+		if err != nil {
+			t.Fatalf("Reorder(%s) error: %s\n", tc.n, err.Error())
+		}
+		if actual := mustCombine(t, r); actual != tc.expected {
+			t.Errorf("Reorder(%s) expected=%s actual=%s\n", tc.n, tc.expected, actual)
+		}
+		before, _, _ := m.Cost(tc.n)
+		after, _, _ := m.Cost(r)
+		if after > before {
+			t.Errorf("Reorder(%s) expected a cost of at most %v, actual=%v\n", tc.n, before, after)
+		}
+	}
+}
+
+func TestCostModelCost(t *testing.T) {
+	m := CostModel{Default: LeafCost{Cost: 2, Selectivity: 0.5}}
+	for _, tc := range []struct {
+		n          *Node
+		cost, prob float64
+	}{
+		{NewLeafNode("eq .A 1"), 2, 0.5},
+		{NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("eq .B 1")), 3, 0.25},
+		{NewNode(OperatorOr, NewLeafNode("eq .A 1"), NewLeafNode("eq .B 1"), NewLeafNode("eq .C 1")), 3.5, 0.875},
+		{NewNode(OperatorOr, NewLeafNode("true"), NewLeafNode("eq .B 1")), 0, 1},
+	} {
+		cost, prob, err := m.Cost(tc.n)
+		if err != nil {
+			t.Fatalf("Cost(%s) error: %s\n", tc.n, err.Error())
+		}
+		if math.Abs(cost-tc.cost) > 1e-9 || math.Abs(prob-tc.prob) > 1e-9 {
+			t.Errorf("Cost(%s) expected=%v %v actual=%v %v\n", tc.n, tc.cost, tc.prob, cost, prob)
+		}
+	}
+}
+
+func TestCostModelValidate(t *testing.T) {
+	for _, m := range []CostModel{
+		{Default: LeafCost{Cost: -1, Selectivity: 0.5}},
+		{Leaves: map[string]LeafCost{"eq .A 1": {Cost: 1, Selectivity: 1.5}}},
+		{Leaves: map[string]LeafCost{"eq .A 1": {Cost: math.NaN(), Selectivity: 0.5}}},
+	} {
+		if _, err := m.Reorder(NewLeafNode("eq .A 1")); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("Reorder() with %+v expected=%v actual=%v\n", m, ErrInvalidConfig, err)
+		}
+	}
+	if _, err := (CostModel{}).Reorder(NewNode(OperatorAnd)); !errors.Is(err, ErrEmptyNode) {
+		t.Errorf("Reorder() expected=%v actual=%v\n", ErrEmptyNode, err)
+	}
+}
@@ -0,0 +1,125 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Coverage is the outcome of evaluating a tree over a corpus of records, see
+// `(*CompiledTree).Coverage`.
+type Coverage struct {
+	// Total is the number of records evaluated, and Failed the number the
+	// tree failed on, which are not counted by its leaves.
+	Total  int `json:"Total"`
+	Failed int `json:"Failed"`
+
+	// Leaves are the leaves of the tree, in order.
+	Leaves []LeafCoverage `json:"Leaves"`
+}
+
+// LeafCoverage counts the results of a single leaf over a corpus.  The cases
+// of a switch count as the leaves which compare its field with their values,
+// named as in `TruthTable`.
+type LeafCoverage struct {
+	Path string `json:"Path"`
+	Leaf string `json:"Leaf"`
+
+	// True and False are the number of records the leaf was true and false
+	// for, and Failed the number it failed on where `Evaluate` would not
+	// have evaluated it.
+	True   int `json:"True"`
+	False  int `json:"False"`
+	Failed int `json:"Failed"`
+
+	// Decisive is the number of records for which the leaf decided the
+	// result: flipping it alone would have flipped the result of the tree.
+	// Leaves which are equal are flipped together.
+	Decisive int `json:"Decisive"`
+}
+
+// Dead reports whether the leaf never decided the result of the tree, such
+// as a clause subsumed by another, or one no record ever reached.
+func (lc LeafCoverage) Dead() bool {
+	return lc.Decisive == 0
+}
+
+// Coverage explains the result of the tree for every record of `data`, see
+// `Explain`, and counts how often each of its leaves was true, false, and
+// decisive, so that clauses which never change a decision can be found and
+// removed.  Records the tree fails on are counted as failed.  Coverage fails
+// only for trees whose leaves cannot be treated as boolean inputs, as for
+// `Satisfiable`.
+func (ct *CompiledTree) Coverage(data []interface{}) (*Coverage, error) {
+	e, vars, err := ct.root.boolean(true)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Coverage{Total: len(data), Leaves: []LeafCoverage{}}
+	index := map[string]int{}
+	var leaves []*boolExpr
+	e.leaves(func(l *boolExpr) {
+		// The guards of a switch occur once per case, and a switch without
+		// a default ends with a constant of its own.
+		if _, ok := index[l.path]; ok || l.name == "" {
+			return
+		}
+		index[l.path] = len(leaves)
+		leaves = append(leaves, l)
+		lc := LeafCoverage{Path: l.path, Leaf: l.name}
+		if m, err := ct.root.At(l.path); err == nil && m.isLeaf() {
+			lc.Leaf = m.Leaf
+		}
+		c.Leaves = append(c.Leaves, lc)
+	})
+
+	for _, d := range data {
+		st := ct.newState(context.Background())
+		st.hooks, st.sem = nil, nil
+		results := map[string]bool{}
+		root, err := ct.eval.explain(st, d, results, false)
+		releaseState(st)
+		if err != nil {
+			c.Failed++
+			continue
+		}
+
+		vals := make([]Truth, len(vars))
+		for v := range vals {
+			vals[v] = Unknown
+		}
+		for i, l := range leaves {
+			r, ok := results[l.path]
+			switch {
+			case !ok:
+				c.Leaves[i].Failed++
+				continue
+			case r:
+				c.Leaves[i].True++
+			default:
+				c.Leaves[i].False++
+			}
+			if l.variable >= 0 {
+				vals[l.variable] = truth(r != l.negated)
+			}
+		}
+		decisive := map[int]bool{}
+		for i, l := range leaves {
+			if l.variable < 0 || vals[l.variable] == Unknown {
+				continue
+			}
+			flips, ok := decisive[l.variable]
+			if !ok {
+				flips = e.flipsTo(vals, []int{l.variable}, !root.Result)
+				decisive[l.variable] = flips
+			}
+			if flips {
+				c.Leaves[i].Decisive++
+			}
+		}
+	}
+	return c, nil
+}
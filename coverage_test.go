@@ -0,0 +1,83 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestCoverage(t *testing.T) {
+	n := NewNode(OperatorOr,
+		NewNode(OperatorAnd, NewLeafNode("ge .Age 18"), NewLeafNode("gt .Amount 100")),
+		NewLeafNode("gt .Amount 1000"),
+		NewNode(OperatorAnd, NewLeafNode("ge .Age 18"), NewLeafNode("gt .Amount 100"), NewLeafNode("eq .Vip true")),
+	)
+	ct, err := Compile(n)
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	data := []interface{}{
+		map[string]interface{}{"Age": 30, "Amount": 200, "Vip": true},
+		map[string]interface{}{"Age": 17, "Amount": 200, "Vip": false},
+		map[string]interface{}{"Age": 18, "Amount": 50, "Vip": true},
+		map[string]interface{}{"Age": 17, "Amount": 5000, "Vip": false},
+		map[string]interface{}{"Age": "x", "Amount": 200, "Vip": true},
+	}
+
+	c, err := ct.Coverage(data)
+	if err != nil {
+		t.Fatalf("Coverage() error: %s\n", err.Error())
+	}
+	if c.Total != 5 || c.Failed != 1 || len(c.Leaves) != 6 {
+		t.Fatalf("Coverage() expected=5,1,6 actual=%d,%d,%d\n", c.Total, c.Failed, len(c.Leaves))
+	}
+	for i, tc := range []struct {
+		path                            string
+		leaf                            string
+		isTrue, isFalse, decisive, dead int
+	}{
+		{"/0/0", "(ge .Age 18)", 2, 2, 2, 0},
+		{"/0/1", "(gt .Amount 100)", 3, 1, 2, 0},
+		{"/1", "(gt .Amount 1000)", 1, 3, 3, 0},
+		{"/2/0", "(ge .Age 18)", 2, 2, 2, 0},
+		{"/2/1", "(gt .Amount 100)", 3, 1, 2, 0},
+		// The last clause is subsumed by the first.
+		{"/2/2", "(eq .Vip true)", 2, 2, 0, 1},
+	} {
+		lc := c.Leaves[i]
+		if lc.Path != tc.path || lc.Leaf != tc.leaf || lc.True != tc.isTrue || lc.False != tc.isFalse || lc.Decisive != tc.decisive || lc.Dead() != (tc.dead == 1) {
+			t.Errorf("Coverage() leaf %d expected=%+v actual=%+v\n", i, tc, lc)
+		}
+	}
+
+	if c, err := ct.Coverage(nil); err != nil || c.Total != 0 || len(c.Leaves) != 6 || !c.Leaves[0].Dead() {
+		t.Errorf("Coverage(nil) expected an empty coverage, actual=%+v err=%v\n", c, err)
+	}
+}
+
+func TestCoverageSwitch(t *testing.T) {
+	n := NewSwitchNode(".Kind",
+		NewCaseNode(NewLeafNode("gt .N 1"), "a"),
+		NewCaseNode(NewLeafNode("true"), "b"),
+	)
+	ct, err := Compile(n)
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	c, err := ct.Coverage([]interface{}{
+		map[string]interface{}{"Kind": "a", "N": 2},
+		map[string]interface{}{"Kind": "b", "N": 0},
+	})
+	if err != nil {
+		t.Fatalf("Coverage() error: %s\n", err.Error())
+	}
+	paths := []string{}
+	for _, lc := range c.Leaves {
+		paths = append(paths, lc.Path)
+	}
+	if len(c.Leaves) != 4 || c.Leaves[3].Leaf != "(true)" || c.Leaves[0].Leaf != `(eq .Kind "a")` || !c.Leaves[3].Dead() || c.Leaves[0].Decisive != 2 {
+		t.Errorf("Coverage() expected the cases and their leaves, actual=%v %+v\n", paths, c.Leaves)
+	}
+}
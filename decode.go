@@ -0,0 +1,299 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// DecodeOptions limits the trees decoded from JSON, so that untrusted rule
+// documents cannot construct trees deep enough to exhaust the stack or large
+// enough to exhaust memory.  Limits are enforced as the document is read,
+// before the offending part of it is, and a zero limit is no limit.  Strict
+// decoding additionally rejects malformed documents.
+type DecodeOptions struct {
+	// MaxDepth is the maximum depth of a node, the root being at depth 1.
+	MaxDepth int `json:"maxDepth,omitempty" yaml:"maxDepth,omitempty"`
+
+	// MaxNodes is the maximum number of nodes in the tree.
+	MaxNodes int `json:"maxNodes,omitempty" yaml:"maxNodes,omitempty"`
+
+	// MaxLeafLen is the maximum length of a leaf, in bytes.
+	MaxLeafLen int `json:"maxLeafLen,omitempty" yaml:"maxLeafLen,omitempty"`
+
+	// Strict rejects malformed documents which `json.Unmarshal` accepts:
+	// fields other than "Op", "Nodes", "Leaf", "Ref" and "Payload", including
+	// those differing only in case, repeated fields, nodes without an "Op" or
+	// a "Ref", leaves with "Nodes", nodes with both a "Leaf" and a "Payload",
+	// and `and` / `or` nodes with a "Leaf".
+	// Every such problem of the document is reported, joined as by
+	// `errors.Join`, along with the error which stopped decoding, if any.
+	Strict bool `json:"strict,omitempty" yaml:"strict,omitempty"`
+}
+
+// DefaultDecodeOptions are the limits used by `SafeUnmarshal`.
+var DefaultDecodeOptions = DecodeOptions{
+	MaxDepth:   64,
+	MaxNodes:   10000,
+	MaxLeafLen: 4096,
+}
+
+// SafeUnmarshal decodes a tree from JSON within `DefaultDecodeOptions`.
+func SafeUnmarshal(data []byte) (*Node, error) {
+	return DefaultDecodeOptions.Unmarshal(data)
+}
+
+// Unmarshal decodes the tree encoded as JSON in `data`, as `json.Unmarshal`
+// would but within the limits of `o`.  Exceeding a limit fails with an error
+// wrapping `ErrLimitExceeded`; errors name the offending part of the
+// document by its JSON path, such as `$.Nodes[1].Leaf`.  The encoding of
+// children as null, which would decode as nil nodes, is rejected.
+func (o DecodeOptions) Unmarshal(data []byte) (*Node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	n, err := o.Decode(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid tree: unexpected data after the root node")
+	}
+	return n, nil
+}
+
+// Decode reads the next tree from `dec` within the limits of `o`, as
+// `Unmarshal` does, for trees embedded in larger documents or streams.
+func (o DecodeOptions) Decode(dec *json.Decoder) (*Node, error) {
+	d := &decoder{dec: dec, opts: o}
+	n, err := d.node("$", 1)
+	if err := joinErrors(append(d.strict, err)...); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// decoder reads a single tree from a stream of JSON tokens.
+type decoder struct {
+	dec    *json.Decoder
+	opts   DecodeOptions
+	nodes  int
+	strict []error // the problems found by strict decoding, see `strictField`
+}
+
+// token reads the next token, reporting the end of the input as an error.
+func (d *decoder) token(path string) (json.Token, error) {
+	t, err := d.dec.Token()
+	if errors.Is(err, io.EOF) {
+		return nil, decodeErrorf(path, "unexpected end of input")
+	}
+	if err != nil {
+		return nil, decodeErrorf(path, "%v", err)
+	}
+	return t, nil
+}
+
+// node decodes the node at `path` and `depth` within the tree.
+func (d *decoder) node(path string, depth int) (*Node, error) {
+	if d.opts.MaxDepth > 0 && depth > d.opts.MaxDepth {
+		return nil, limitErrorf(path, "deeper than %d nodes", d.opts.MaxDepth)
+	}
+	if d.nodes++; d.opts.MaxNodes > 0 && d.nodes > d.opts.MaxNodes {
+		return nil, limitErrorf(path, "more than %d nodes", d.opts.MaxNodes)
+	}
+
+	t, err := d.token(path)
+	if err != nil {
+		return nil, err
+	}
+	if t != json.Delim('{') {
+		return nil, decodeErrorf(path, "expected an object, got %v", t)
+	}
+
+	n := &Node{}
+	ref := ""
+	var payload json.RawMessage
+	seen := map[string]bool{}
+	for d.dec.More() {
+		t, err := d.token(path)
+		if err != nil {
+			return nil, err
+		}
+		key := t.(string)
+		if d.opts.Strict {
+			d.strict = append(d.strict, strictField(seen, path, key))
+		}
+
+		// Keys are matched as `json.Unmarshal` matches them, ignoring case.
+		switch {
+		case strings.EqualFold(key, "Op"):
+			s, err := d.string(path + ".Op")
+			if err != nil {
+				return nil, err
+			}
+			n.Op = normalizeOperator(s)
+		case strings.EqualFold(key, "Leaf"):
+			if n.Leaf, err = d.string(path + ".Leaf"); err != nil {
+				return nil, err
+			}
+			if d.opts.MaxLeafLen > 0 && len(n.Leaf) > d.opts.MaxLeafLen {
+				return nil, limitErrorf(path+".Leaf", "longer than %d bytes", d.opts.MaxLeafLen)
+			}
+		case strings.EqualFold(key, "Nodes"):
+			if n.Nodes, err = d.children(path+".Nodes", depth); err != nil {
+				return nil, err
+			}
+		case strings.EqualFold(key, "Ref"):
+			if ref, err = d.string(path + ".Ref"); err != nil {
+				return nil, err
+			}
+			if d.opts.MaxLeafLen > 0 && len(ref) > d.opts.MaxLeafLen {
+				return nil, limitErrorf(path+".Ref", "longer than %d bytes", d.opts.MaxLeafLen)
+			}
+		case strings.EqualFold(key, "Payload"):
+			if err := d.dec.Decode(&payload); err != nil {
+				return nil, decodeErrorf(path+".Payload", "%v", err)
+			}
+			if d.opts.MaxLeafLen > 0 && len(payload) > d.opts.MaxLeafLen {
+				return nil, limitErrorf(path+".Payload", "longer than %d bytes", d.opts.MaxLeafLen)
+			}
+		default:
+			if err := d.skip(path + "." + key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if _, err := d.token(path); err != nil {
+		return nil, err
+	}
+	if ref != "" {
+		if err := expandRef(n, ref); err != nil {
+			return nil, fmt.Errorf("invalid tree at %s: %w", path, err)
+		}
+	}
+	if payload != nil {
+		if err := setPayload(n, payload); err != nil {
+			return nil, fmt.Errorf("invalid tree at %s.Payload: %w", path, err)
+		}
+	}
+
+	if d.opts.Strict {
+		d.strict = append(d.strict, strictNode(n, seen, path))
+	}
+	if err := normalizeNode(n); err != nil {
+		return nil, fmt.Errorf("invalid tree at %s: %w", path, err)
+	}
+	return n, nil
+}
+
+// string decodes a string, or null as the empty string.
+func (d *decoder) string(path string) (string, error) {
+	t, err := d.token(path)
+	if err != nil {
+		return "", err
+	}
+	switch v := t.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	return "", decodeErrorf(path, "expected a string, got %v", t)
+}
+
+// children decodes the children of the node at `depth`, or null as none.
+func (d *decoder) children(path string, depth int) ([]*Node, error) {
+	t, err := d.token(path)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, nil
+	}
+	if t != json.Delim('[') {
+		return nil, decodeErrorf(path, "expected an array, got %v", t)
+	}
+
+	nodes := []*Node{}
+	for i := 0; d.dec.More(); i++ {
+		c, err := d.node(path+"["+strconv.Itoa(i)+"]", depth+1)
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, c)
+	}
+	if _, err := d.token(path); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// skip discards the value of an unknown field, token by token.
+func (d *decoder) skip(path string) error {
+	depth := 0
+	for {
+		t, err := d.token(path)
+		if err != nil {
+			return err
+		}
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// decodeErrorf returns a decoding error for the value at `path`.
+func decodeErrorf(path, format string, args ...interface{}) error {
+	return fmt.Errorf("invalid tree at %s: %s", path, fmt.Sprintf(format, args...))
+}
+
+// limitErrorf returns an error wrapping `ErrLimitExceeded` for the value at
+// `path`.
+func limitErrorf(path, format string, args ...interface{}) error {
+	return fmt.Errorf("%w at %s: %s", ErrLimitExceeded, path, fmt.Sprintf(format, args...))
+}
+
+// strictField checks the field `key` of the node at `path` in strict
+// decoding, recording it in `seen`.  Decoding carries on past the problems
+// of strict decoding, so that all of them are reported.
+func strictField(seen map[string]bool, path, key string) error {
+	if key != "Op" && key != "Nodes" && key != "Leaf" && key != "Ref" && key != "Payload" {
+		return decodeErrorf(path+"."+key, "unknown field")
+	}
+	if seen[key] {
+		return decodeErrorf(path+"."+key, "repeated field")
+	}
+	seen[key] = true
+	return nil
+}
+
+// strictNode checks the node `n` at `path`, with the fields `seen`, once
+// decoded in strict decoding.
+func strictNode(n *Node, seen map[string]bool, path string) error {
+	_, payload := payloadOperator(n.Op)
+	switch {
+	case !seen["Op"] && !seen["Ref"] || n.Op == "":
+		return decodeErrorf(path, "missing Op")
+	case n.isLeaf() && n.Nodes != nil:
+		return decodeErrorf(path+".Nodes", "%s node with children", n.Op)
+	case seen["Leaf"] && seen["Payload"]:
+		return decodeErrorf(path+".Payload", "%s node with both a payload and a leaf", n.Op)
+	case !n.isLeaf() && !isSwitchPart(n.Op) && !payload && n.Op != OperatorRef && seen["Leaf"] && n.Leaf != "":
+		return decodeErrorf(path+".Leaf", "%s node with a leaf", n.Op)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// deepTree returns the JSON of a chain of `and` nodes `depth` deep.
+func deepTree(depth int) string {
+	return strings.Repeat(`{"Op": "and", "Nodes": [`, depth-1) + `{"Op": "leaf", "Leaf": "true"}` + strings.Repeat(`]}`, depth-1)
+}
+
+func TestSafeUnmarshal(t *testing.T) {
+	for _, n := range []*Node{pricesTree(), NewLeafNode("true"), NewNode(OperatorAnd)} {
+		bs, err := json.Marshal(n)
+		if err != nil {
+			t.Fatalf("Marshal() error: %s\n", err.Error())
+		}
+		var expected Node
+		if err := json.Unmarshal(bs, &expected); err != nil {
+			t.Fatalf("Unmarshal() error: %s\n", err.Error())
+		}
+		actual, err := SafeUnmarshal(bs)
+		if err != nil {
+			t.Errorf("SafeUnmarshal(%s) error: %s\n", bs, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(actual, &expected) {
+			t.Errorf("SafeUnmarshal(%s) expected=%#v actual=%#v\n", bs, &expected, actual)
+		}
+	}
+
+	n, err := SafeUnmarshal([]byte(`{"op": "or", "Extra": {"a": [1, {"b": 2}]}, "nodes": [{"Op": "leaf", "Leaf": null, "LEAF": "(true)"}], "Leaf": null}`))
+	if err != nil {
+		t.Fatalf("SafeUnmarshal() error: %s\n", err.Error())
+	}
+	if expected := NewNode(OperatorOr, NewLeafNode("true")); !reflect.DeepEqual(n, expected) {
+		t.Errorf("SafeUnmarshal() expected=%#v actual=%#v\n", expected, n)
+	}
+}
+
+func TestDecodeLimits(t *testing.T) {
+	wide := `{"Op": "or", "Nodes": [` + strings.Repeat(`{"Op": "leaf", "Leaf": "true"},`, 10) + `{"Op": "leaf", "Leaf": "true"}]}`
+	for _, tc := range []struct {
+		opts DecodeOptions
+		src  string
+		err  string
+	}{
+		{DecodeOptions{}, deepTree(500), ""},
+		{DecodeOptions{MaxDepth: 3}, deepTree(3), ""},
+		{DecodeOptions{MaxDepth: 3}, deepTree(4), "limit exceeded at $.Nodes[0].Nodes[0].Nodes[0]: deeper than 3 nodes"},
+		{DefaultDecodeOptions, deepTree(100000), "limit exceeded at $" + strings.Repeat(".Nodes[0]", 64) + ": deeper than 64 nodes"},
+		{DecodeOptions{MaxNodes: 12}, wide, ""},
+		{DecodeOptions{MaxNodes: 11}, wide, "limit exceeded at $.Nodes[10]: more than 11 nodes"},
+		{DecodeOptions{MaxLeafLen: 4}, `{"Op": "leaf", "Leaf": "true"}`, ""},
+		{DecodeOptions{MaxLeafLen: 3}, `{"Op": "leaf", "Leaf": "true"}`, "limit exceeded at $.Leaf: longer than 3 bytes"},
+		{DecodeOptions{}, `{"Op": "and", "Nodes": [null]}`, "invalid tree at $.Nodes[0]: expected an object"},
+		{DecodeOptions{}, `{"Op": 1}`, "invalid tree at $.Op: expected a string"},
+		{DecodeOptions{}, `{"Op": "and", "Nodes": {}}`, "invalid tree at $.Nodes: expected an array"},
+		{DecodeOptions{}, `{"Op": "and", "Nodes": [`, "invalid tree at $.Nodes"},
+		{DecodeOptions{}, `{"Op": "leaf"} {}`, "invalid tree: unexpected data"},
+		{DecodeOptions{}, `[]`, "invalid tree at $: expected an object"},
+	} {
+		_, err := tc.opts.Unmarshal([]byte(tc.src))
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("Unmarshal(%.40s) unexpected error: %s\n", tc.src, err.Error())
+		case tc.err != "" && (err == nil || !strings.HasPrefix(err.Error(), tc.err)):
+			t.Errorf("Unmarshal(%.40s) expected error=%q actual=%v\n", tc.src, tc.err, err)
+		case strings.HasPrefix(tc.err, "limit") && !errors.Is(err, ErrLimitExceeded):
+			t.Errorf("Unmarshal(%.40s) expected=%v actual=%v\n", tc.src, ErrLimitExceeded, err)
+		}
+	}
+}
+
+func TestDecodeStream(t *testing.T) {
+	dec := json.NewDecoder(strings.NewReader(`{"Op": "leaf", "Leaf": "(true)"} {"Op": "leaf", "Leaf": "(false)"}`))
+	for _, expected := range []string{"(true)", "(false)"} {
+		n, err := DefaultDecodeOptions.Decode(dec)
+		if err != nil || n.Leaf != expected {
+			t.Errorf("Decode() expected=%s actual=%v (%v)\n", expected, n, err)
+		}
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	strict := DecodeOptions{Strict: true}
+	for _, tc := range []struct {
+		src string
+		err string
+	}{
+		{`{"Op": "or", "Nodes": [{"Op": "leaf", "Leaf": "(true)"}, {"Op": "advanced", "Leaf": "{{ true }}"}]}`, ""},
+		{`{"Op": "and", "Leaf": "", "Nodes": null}`, ""},
+		{`{"Op": "leaf", "Leaf": "(true)", "Nodes": null}`, ""},
+		{`{"Op": "or", "Nodes": [{"Op": "leaf", "Leaf": "(true)", "Comment": "x"}]}`, "invalid tree at $.Nodes[0].Comment: unknown field"},
+		{`{"op": "leaf", "Leaf": "(true)"}`, "invalid tree at $.op: unknown field\ninvalid tree at $: missing Op"},
+		{`{"Op": "leaf", "Op": "and"}`, "invalid tree at $.Op: repeated field"},
+		{`{"Op": "or", "Nodes": [{"Leaf": "(true)"}]}`, "invalid tree at $.Nodes[0]: missing Op"},
+		{`{"Op": "", "Leaf": "(true)"}`, "invalid tree at $: missing Op"},
+		{`{"Op": "or", "Nodes": [{"Op": "leaf", "Leaf": "(true)", "Nodes": []}]}`, "invalid tree at $.Nodes[0].Nodes: leaf node with children"},
+		{`{"Op": "advanced", "Leaf": "{{ true }}", "Nodes": [{"Op": "leaf"}]}`, "invalid tree at $.Nodes: advanced node with children"},
+		{`{"Op": "and", "Leaf": "(true)", "Nodes": [{"Op": "leaf", "Leaf": "(true)"}]}`, "invalid tree at $.Leaf: and node with a leaf"},
+	} {
+		_, err := strict.Unmarshal([]byte(tc.src))
+		switch {
+		case tc.err == "" && err != nil:
+			t.Errorf("Unmarshal(%s) unexpected error: %s\n", tc.src, err.Error())
+		case tc.err != "" && (err == nil || err.Error() != tc.err):
+			t.Errorf("Unmarshal(%s) expected error=%q actual=%v\n", tc.src, tc.err, err)
+		}
+		if _, err := SafeUnmarshal([]byte(tc.src)); err != nil {
+			t.Errorf("SafeUnmarshal(%s) expected lenient decoding, got: %s\n", tc.src, err.Error())
+		}
+	}
+}
+
+func TestDecodeStrictAll(t *testing.T) {
+	strict := DecodeOptions{Strict: true}
+	for _, tc := range []struct {
+		src string
+		err string
+	}{
+		{`{"Op": "or", "Nodes": [{"Op": "leaf", "Leaf": "(true)", "Comment": "x"}, {"Leaf": "(true)"}], "Op": "or"}`,
+			"invalid tree at $.Nodes[0].Comment: unknown field\ninvalid tree at $.Nodes[1]: missing Op\ninvalid tree at $.Op: repeated field"},
+		{`{"Op": "or", "Nodes": [{"Op": "leaf", "Leaf": "(true)", "Comment": "x"}, {"Op": 1}]}`,
+			"invalid tree at $.Nodes[0].Comment: unknown field\ninvalid tree at $.Nodes[1].Op: expected a string, got 1"},
+		{`{"Op": "leaf", "Leaf": "(true)", "Nodes": [{"Op": "leaf", "Nodes": []}]}`,
+			"invalid tree at $.Nodes[0].Nodes: leaf node with children\ninvalid tree at $.Nodes: leaf node with children"},
+	} {
+		_, err := strict.Unmarshal([]byte(tc.src))
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("Unmarshal(%s) expected error=%q actual=%v\n", tc.src, tc.err, err)
+		}
+	}
+
+	doc := `{"Version": 1, "Tree": {"Op": "leaf", "Leaf": "(true)", "Comment": "x"}, "B": 1, "A": 2}`
+	expected := "invalid document: unknown field \"A\"\ninvalid document: unknown field \"B\"\ninvalid tree at $.Comment: unknown field"
+	if _, err := strict.UnmarshalDocument([]byte(doc)); err == nil || err.Error() != expected {
+		t.Errorf("UnmarshalDocument() expected error=%q actual=%v\n", expected, err)
+	}
+}
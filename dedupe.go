@@ -0,0 +1,135 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"sort"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Kinds of `LeafCluster`.
+const (
+	MatchExact      = "exact"      // leaves are identical strings
+	MatchNormalized = "normalized" // leaves differ only in whitespace and parentheses
+	MatchLiterals   = "literals"   // leaves differ only in their literal values
+)
+
+// LeafOccurrence locates a leaf within a set of rules.
+type LeafOccurrence struct {
+	Rule string `json:"Rule"`
+	Path string `json:"Path"`
+	Leaf string `json:"Leaf"`
+}
+
+// LeafCluster is a group of leaves across a set of rules that could share a
+// single definition.
+type LeafCluster struct {
+	Kind        string           `json:"Kind"`
+	Expr        string           `json:"Expr"`
+	Occurrences []LeafOccurrence `json:"Occurrences"`
+	Rules       int              `json:"Rules"`
+	Suggestion  string           `json:"Suggestion"`
+
+	// Savings is the number of leaf evaluations that memoizing a shared
+	// definition would save when every occurrence is evaluated once.  It is
+	// zero for clusters whose leaves test different values.
+	Savings int `json:"Savings"`
+}
+
+// DedupeLeaves clusters the leaves of `rules`, keyed by rule name, that are
+// repeated either exactly, after normalizing whitespace and parentheses, or
+// with only their literal values differing.  Each occurrence is reported in
+// the most specific cluster it belongs to.  Clusters are ordered by
+// decreasing savings, then occurrences and then expression.
+func DedupeLeaves(rules map[string]*Node) []LeafCluster {
+	names := make([]string, 0, len(rules))
+	for name := range rules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	all := []LeafOccurrence{}
+	for _, name := range names {
+		rules[name].walkLeaves("/", func(path string, n *Node) {
+			all = append(all, LeafOccurrence{Rule: name, Path: path, Leaf: n.Leaf})
+		})
+	}
+
+	clusters := []LeafCluster{}
+	claimed := make([]bool, len(all))
+	for _, kind := range []string{MatchExact, MatchNormalized, MatchLiterals} {
+		groups := map[string][]int{}
+		keys := []string{}
+		for i, o := range all {
+			if claimed[i] {
+				continue
+			}
+			k := clusterKey(kind, o.Leaf)
+			if _, ok := groups[k]; !ok {
+				keys = append(keys, k)
+			}
+			groups[k] = append(groups[k], i)
+		}
+
+		for _, k := range keys {
+			idx := groups[k]
+			if len(idx) < 2 {
+				continue
+			}
+			c := LeafCluster{Kind: kind, Expr: k}
+			rs := map[string]bool{}
+			for _, i := range idx {
+				claimed[i] = true
+				c.Occurrences = append(c.Occurrences, all[i])
+				rs[all[i].Rule] = true
+			}
+			c.Rules = len(rs)
+			c.Suggestion = "extract into a shared named subtree"
+			c.Savings = len(idx) - 1
+			if kind == MatchLiterals {
+				c.Suggestion = "extract the differing literals into constants of a parameterized subtree"
+				c.Savings = 0
+			}
+			clusters = append(clusters, c)
+		}
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		a, b := clusters[i], clusters[j]
+		if a.Savings != b.Savings {
+			return a.Savings > b.Savings
+		}
+		if len(a.Occurrences) != len(b.Occurrences) {
+			return len(a.Occurrences) > len(b.Occurrences)
+		}
+		return a.Expr < b.Expr
+	})
+	return clusters
+}
+
+func clusterKey(kind, leaf string) string {
+	switch kind {
+	case MatchNormalized:
+		s, _ := normalizeLeaf(leaf, false)
+		return s
+	case MatchLiterals:
+		s, _ := normalizeLeaf(leaf, true)
+		return s
+	}
+	return leaf
+}
+
+// walkLeaves calls `fn` with every leaf in the tree and its path.
+func (n *Node) walkLeaves(path string, fn func(string, *Node)) {
+	if n.Op == OperatorLeaf {
+		fn(path, n)
+		return
+	}
+	if n.Op == OperatorAdvanced {
+		return
+	}
+	for i, c := range n.Nodes {
+		c.walkLeaves(childPath(path, i), fn)
+	}
+}
@@ -0,0 +1,69 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestNormalizeLeaf(t *testing.T) {
+	for _, tc := range []struct {
+		leaf     string
+		abstract bool
+		expected string
+		literals int
+	}{
+		{"(gt .Milk 4)", false, "gt .Milk 4", 0},
+		{"((gt   .Milk  (len .X)))", false, "gt .Milk (len .X)", 0},
+		{`eq .Name "a" | not`, false, `eq .Name "a" | not`, 0},
+		{`(between .Milk 4 6)`, true, "between .Milk ? ?", 2},
+		{"gt .Milk (", false, "gt .Milk (", 0},
+	} {
+		s, lits := normalizeLeaf(tc.leaf, tc.abstract)
+		if s != tc.expected || len(lits) != tc.literals {
+			t.Errorf("normalizeLeaf(%s) expected=%s/%d actual=%s/%d\n", tc.leaf, tc.expected, tc.literals, s, len(lits))
+		}
+	}
+}
+
+func TestDedupeLeaves(t *testing.T) {
+	rules := map[string]*Node{
+		"a": NewNode(OperatorAnd,
+			NewLeafNode("ge .Milk 4"),
+			NewLeafNode("gt .Toothpaste 5")),
+		"b": NewNode(OperatorOr,
+			NewLeafNode("ge .Milk 4"),
+			NewLeafNode("gt  .Toothpaste 5"),
+			NewLeafNode("le .Onions 2")),
+		"c": NewNode(OperatorOr,
+			NewLeafNode("ge .Milk 4"),
+			NewLeafNode("le .Onions 3")),
+	}
+
+	cs := DedupeLeaves(rules)
+	if len(cs) != 3 {
+		t.Fatalf("DedupeLeaves() expected 3 clusters, got %#v\n", cs)
+	}
+
+	for i, tc := range []struct {
+		kind        string
+		expr        string
+		occurrences int
+		savings     int
+	}{
+		{MatchExact, "(ge .Milk 4)", 3, 2},
+		{MatchNormalized, "gt .Toothpaste 5", 2, 1},
+		{MatchLiterals, "le .Onions ?", 2, 0},
+	} {
+		c := cs[i]
+		if c.Kind != tc.kind || c.Expr != tc.expr || len(c.Occurrences) != tc.occurrences || c.Savings != tc.savings {
+			t.Errorf("DedupeLeaves()[%d] expected=%v actual=%#v\n", i, tc, c)
+		}
+	}
+
+	if o := cs[0].Occurrences[1]; o.Rule != "b" || o.Path != "/0" {
+		t.Errorf("DedupeLeaves() expected occurrence b:/0, got %#v\n", o)
+	}
+}
@@ -0,0 +1,182 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ChangeKind is the kind of a `Change` between two trees.
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+)
+
+// Change is a single difference between two versions of a tree.
+type Change struct {
+	Kind ChangeKind `json:"Kind"`
+
+	// Path is the path of the node in the old tree, or in the new tree for
+	// added nodes.
+	Path string `json:"Path"`
+
+	// Old and New are the node before and after the change, Old is nil for
+	// added nodes and New for removed ones.  A modified `and` or `or` node
+	// changed only its operator, changes to its children are reported
+	// separately.
+	Old *Node `json:"Old,omitempty"`
+	New *Node `json:"New,omitempty"`
+}
+
+// String renders the change on one line, such as
+// `~ /0/1: (le .Milk 6) -> (le .Milk 7)`, `+ /2: (eq .C 3)` or
+// `- /1: .A == 1 AND .B == 2`.  Leaves are written as they are and subtrees
+// in infix, see `Infix`.
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return "+ " + c.Path + ": " + diffLabel(c.New)
+	case ChangeRemoved:
+		return "- " + c.Path + ": " + diffLabel(c.Old)
+	}
+	if !c.Old.isLeaf() && !c.New.isLeaf() {
+		return "~ " + c.Path + ": " + string(c.Old.Op) + " -> " + string(c.New.Op)
+	}
+	return "~ " + c.Path + ": " + diffLabel(c.Old) + " -> " + diffLabel(c.New)
+}
+
+func diffLabel(n *Node) string {
+	if n.isLeaf() {
+		return n.Leaf
+	}
+	return n.Infix()
+}
+
+// FormatDiff renders `changes` one per line, for review tooling.
+func FormatDiff(changes []Change) string {
+	var b strings.Builder
+	for _, c := range changes {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Diff returns the changes turning the tree `a` into `b`, in the order of the
+// nodes of the trees.  The children of `and` and `or` nodes are aligned by
+// their longest common subsequence of unchanged children, so that inserting
+// or removing a clause is reported as such rather than as a modification of
+// every clause after it.  Unaligned children at the same position are diffed
+// with one another, the rest are added or removed.  Either tree may be nil.
+func Diff(a, b *Node) []Change {
+	d := &differ{changes: []Change{}}
+	d.node(a, b, "/", "/")
+	return d.changes
+}
+
+type differ struct {
+	changes []Change
+}
+
+func (d *differ) add(kind ChangeKind, path string, old, new *Node) {
+	d.changes = append(d.changes, Change{Kind: kind, Path: path, Old: old, New: new})
+}
+
+// node diffs `a` at `pa` in the old tree with `b` at `pb` in the new one.
+func (d *differ) node(a, b *Node, pa, pb string) {
+	switch {
+	case a == nil && b == nil:
+		return
+	case a == nil:
+		d.add(ChangeAdded, pb, nil, b)
+		return
+	case b == nil:
+		d.add(ChangeRemoved, pa, a, nil)
+		return
+	}
+
+	if a.isLeaf() || b.isLeaf() {
+		if !equalNodes(a, b) {
+			d.add(ChangeModified, pa, a, b)
+		}
+		return
+	}
+	if a.Op != b.Op {
+		d.add(ChangeModified, pa, a, b)
+	}
+	d.children(a.Nodes, b.Nodes, pa, pb)
+}
+
+// children diffs the children of two nodes, aligned by the longest common
+// subsequence of equal children.
+func (d *differ) children(as, bs []*Node, pa, pb string) {
+	// lcs[i][j] is the length of the longest common subsequence of as[i:]
+	// and bs[j:].
+	lcs := make([][]int, len(as)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bs)+1)
+	}
+	for i := len(as) - 1; i >= 0; i-- {
+		for j := len(bs) - 1; j >= 0; j-- {
+			switch {
+			case equalNodes(as[i], bs[j]):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(as) || j < len(bs) {
+		if i < len(as) && j < len(bs) && equalNodes(as[i], bs[j]) {
+			i, j = i+1, j+1
+			continue
+		}
+
+		// Collect the gap up to the next pair of aligned children.
+		gi, gj := i, j
+		for gi < len(as) || gj < len(bs) {
+			if gi < len(as) && gj < len(bs) && equalNodes(as[gi], bs[gj]) {
+				break
+			}
+			if gj == len(bs) || (gi < len(as) && lcs[gi+1][gj] >= lcs[gi][gj+1]) {
+				gi++
+			} else {
+				gj++
+			}
+		}
+
+		for ; i < gi && j < gj; i, j = i+1, j+1 {
+			d.node(as[i], bs[j], childPath(pa, i), childPath(pb, j))
+		}
+		for ; i < gi; i++ {
+			d.add(ChangeRemoved, childPath(pa, i), as[i], nil)
+		}
+		for ; j < gj; j++ {
+			d.add(ChangeAdded, childPath(pb, j), nil, bs[j])
+		}
+	}
+}
+
+// equalNodes reports whether two trees are identical.
+func equalNodes(a, b *Node) bool {
+	if a.Op != b.Op || a.Leaf != b.Leaf || len(a.Nodes) != len(b.Nodes) {
+		return false
+	}
+	for i := range a.Nodes {
+		if !equalNodes(a.Nodes[i], b.Nodes[i]) {
+			return false
+		}
+	}
+	return true
+}
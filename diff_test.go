@@ -0,0 +1,71 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestDiff(t *testing.T) {
+	for _, tc := range []struct {
+		a, b     *Node
+		expected string
+	}{
+		{pricesTree(), pricesTree(), ""},
+		{NewLeafNode("eq .A 1"), NewLeafNode("eq .A 2"), "~ /: (eq .A 1) -> (eq .A 2)\n"},
+		{nil, NewLeafNode("true"), "+ /: (true)\n"},
+		{NewLeafNode("true"), nil, "- /: (true)\n"},
+		{
+			NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("eq .B 2"), NewLeafNode("eq .C 3")),
+			NewNode(OperatorAnd, NewLeafNode("eq .X 0"), NewLeafNode("eq .A 1"), NewLeafNode("eq .B 2"), NewLeafNode("eq .C 3")),
+			"+ /0: (eq .X 0)\n",
+		},
+		{
+			NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("eq .B 2"), NewLeafNode("eq .C 3")),
+			NewNode(OperatorOr, NewLeafNode("eq .A 1"), NewLeafNode("eq .C 3")),
+			"~ /: and -> or\n- /1: (eq .B 2)\n",
+		},
+		{
+			NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("eq .B 2"), NewLeafNode("eq .C 3")),
+			NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("eq .B 5"), NewLeafNode("eq .C 3"), NewLeafNode("eq .D 4")),
+			"~ /1: (eq .B 2) -> (eq .B 5)\n+ /3: (eq .D 4)\n",
+		},
+		{
+			NewNode(OperatorOr,
+				NewNode(OperatorAnd, NewLeafNode("ge .Milk 4"), NewLeafNode("le .Milk 6")),
+				NewLeafNode("gt .Toothpaste 5")),
+			NewNode(OperatorOr,
+				NewLeafNode("eq .Sale true"),
+				NewNode(OperatorAnd, NewLeafNode("ge .Milk 4"), NewLeafNode("le .Milk 7")),
+				NewLeafNode("gt .Toothpaste 5")),
+			"~ /0: .Milk >= 4 AND .Milk <= 6 -> (eq .Sale true)\n+ /1: .Milk >= 4 AND .Milk <= 7\n",
+		},
+		{
+			NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("eq .B 2")),
+			NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewNode(OperatorOr, NewLeafNode("eq .B 2"), NewLeafNode("eq .B 3"))),
+			"~ /1: (eq .B 2) -> .B == 2 OR .B == 3\n",
+		},
+	} {
+		changes := Diff(tc.a, tc.b)
+		if actual := FormatDiff(changes); actual != tc.expected {
+			t.Errorf("Diff(%s, %s) expected=\n%s\nactual=\n%s\n", tc.a, tc.b, tc.expected, actual)
+		}
+	}
+}
+
+func TestDiffChanges(t *testing.T) {
+	a := NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("eq .B 2"))
+	b := NewNode(OperatorAnd, NewLeafNode("eq .B 2"), NewLeafNode("eq .C 3"))
+	changes := Diff(a, b)
+	if len(changes) != 2 {
+		t.Fatalf("Diff() expected=2 changes actual=%d\n", len(changes))
+	}
+	if c := changes[0]; c.Kind != ChangeRemoved || c.Path != "/0" || c.Old != a.Nodes[0] || c.New != nil {
+		t.Errorf("Diff() expected /0 to be removed, got %+v\n", c)
+	}
+	if c := changes[1]; c.Kind != ChangeAdded || c.Path != "/1" || c.Old != nil || c.New != b.Nodes[1] {
+		t.Errorf("Diff() expected /1 to be added, got %+v\n", c)
+	}
+}
@@ -0,0 +1,117 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"fmt"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NodeError is the error of a single node of a tree, as returned when the tree
+// is validated, compiled, evaluated or translated, so that callers can locate
+// the node which failed, such as to highlight it in an editor.  `Path` is the
+// path of the node, see `At`, and `Op` and `Leaf` are those of the node.  The
+// error is written as its path followed by `Err`, which wraps the sentinel
+// errors of this package:
+//
+//	var ne *logictree.NodeError
+//	if errors.As(err, &ne) && errors.Is(err, logictree.ErrEmptyNode) {
+//		fmt.Printf("%s has no children\n", ne.Path)
+//	}
+//
+// Errors of trees referenced by other trees are located in the tree which
+// references them.
+type NodeError struct {
+	Path string
+	Op   Operator
+	Leaf string
+	Err  error
+}
+
+func (e *NodeError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError is the error of a leaf which does not parse, located within the
+// leaf rather than within the template a tree is combined into.  `Offset` is
+// the byte offset in `Leaf` at which the leaf stops being valid, such as that
+// of a parenthesis left unclosed or of an unknown function, and `Msg` the
+// message of the template parser, without its position.  The `*NodeError`
+// of the leaf wraps it:
+//
+//	var pe *logictree.ParseError
+//	if errors.As(err, &pe) {
+//		fmt.Printf("%s\n%s^\n", pe.Leaf, strings.Repeat(" ", pe.Offset))
+//	}
+type ParseError struct {
+	Leaf   string
+	Offset int
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parse error at offset %d: %s", e.Offset, e.Msg)
+}
+
+// nodeError returns `err` as the error of the node `n` at `path`, unless it
+// already is the error of a node, such as a descendant of `n`.
+func nodeError(path string, n *Node, err error) error {
+	var ne *NodeError
+	if errors.As(err, &ne) {
+		return err
+	}
+	return &NodeError{Path: path, Op: n.Op, Leaf: n.Leaf, Err: err}
+}
+
+// NodeErrors returns the errors of the nodes joined in `err`, as returned by
+// `Validate`, which reports every problem of a tree at once, in order.  An
+// error which is that of a single node gives only it, and one which is not
+// that of a node gives none.
+func NodeErrors(err error) []*NodeError {
+	var nes []*NodeError
+	for _, e := range splitErrors(err) {
+		var ne *NodeError
+		if errors.As(e, &ne) {
+			nes = append(nes, ne)
+		}
+	}
+	return nes
+}
+
+// joinErrors returns the errors `errs`, flattening those which are already
+// joined, as a single error: nil if there are none, the error itself if there
+// is one and their `errors.Join` otherwise, whose message has each on a line.
+func joinErrors(errs ...error) error {
+	var flat []error
+	for _, err := range errs {
+		flat = append(flat, splitErrors(err)...)
+	}
+	switch len(flat) {
+	case 0:
+		return nil
+	case 1:
+		return flat[0]
+	}
+	return errors.Join(flat...)
+}
+
+// splitErrors returns the errors joined in `err`, or `err` alone.
+func splitErrors(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, e := range j.Unwrap() {
+			errs = append(errs, splitErrors(e)...)
+		}
+		return errs
+	}
+	return []error{err}
+}
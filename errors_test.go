@@ -0,0 +1,133 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestNodeError(t *testing.T) {
+	tree := func(c *Node) *Node {
+		return NewNode(OperatorOr, NewLeafNode("eq .A 1"), NewNode(OperatorAnd, NewLeafNode("true"), c))
+	}
+	compile := func(n *Node) error {
+		_, err := Compile(n)
+		return err
+	}
+	evaluate := func(n *Node) error {
+		ct, err := Compile(n, WithMissing(MissingIsError))
+		if err != nil {
+			return err
+		}
+		_, err = ct.Evaluate(map[string]interface{}{"A": 2})
+		return err
+	}
+	combine := func(n *Node) error {
+		_, err := n.Combine()
+		return err
+	}
+
+	for _, tc := range []struct {
+		name     string
+		fn       func(*Node) error
+		tree     *Node
+		sentinel error
+		expected NodeError
+		message  string
+	}{
+		{"Validate", (*Node).Validate, tree(NewNode(OperatorOr)), ErrEmptyNode,
+			NodeError{Path: "/1/1", Op: OperatorOr}, "/1/1: empty node cannot be merged"},
+		{"Validate", (*Node).Validate, tree(NewLeafNode("$x := 1")), ErrNotExpression,
+			NodeError{Path: "/1/1", Op: OperatorLeaf, Leaf: "($x := 1)"}, ""},
+		{"Compile", compile, tree(NewNode("xor", NewLeafNode("true"))), ErrInvalidOperator,
+			NodeError{Path: "/1/1", Op: "xor"}, `/1/1: invalid operator: "xor"`},
+		{"Compile", compile, tree(NewLeafNode(`matches .A "("`)), ErrInvalidPattern,
+			NodeError{Path: "/1/1", Op: OperatorLeaf, Leaf: `(matches .A "(")`}, ""},
+		{"Evaluate", evaluate, tree(NewLeafNode("eq .B 1")), ErrMissingField,
+			NodeError{Path: "/1/1", Op: OperatorLeaf, Leaf: "(eq .B 1)"}, "/1/1: missing field: .B"},
+		{"Evaluate", evaluate, tree(NewLeafNode(`print "maybe"`)), ErrNotBoolean,
+			NodeError{Path: "/1/1", Op: OperatorLeaf, Leaf: `(print "maybe")`}, `/1/1: tree did not evaluate to a boolean: "maybe"`},
+		{"Combine", combine, tree(NewNode(OperatorAnd)), ErrEmptyNode,
+			NodeError{Path: "/1/1", Op: OperatorAnd}, "/1/1: empty node cannot be merged"},
+		{"Combine", combine, tree(NewRefNode("x")), ErrUnresolvedRef,
+			NodeError{Path: "/1/1", Op: OperatorRef, Leaf: "x"}, `/1/1: unresolved reference: "x"`},
+	} {
+		err := tc.fn(tc.tree)
+		var ne *NodeError
+		if !errors.As(err, &ne) || !errors.Is(err, tc.sentinel) {
+			t.Errorf("%s() expected a NodeError wrapping %v, got %v\n", tc.name, tc.sentinel, err)
+			continue
+		}
+		if ne.Path != tc.expected.Path || ne.Op != tc.expected.Op || ne.Leaf != tc.expected.Leaf {
+			t.Errorf("%s() expected=%s %s %q actual=%s %s %q\n", tc.name, tc.expected.Path, tc.expected.Op, tc.expected.Leaf, ne.Path, ne.Op, ne.Leaf)
+		}
+		if tc.message != "" && err.Error() != tc.message {
+			t.Errorf("%s() expected=%q actual=%q\n", tc.name, tc.message, err.Error())
+		}
+	}
+
+	// Evaluations which are stopped name the node they stopped at.
+	ct, err := Compile(tree(NewLeafNode("true")))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var ne *NodeError
+	if _, err := ct.EvaluateContext(ctx, nil); !errors.As(err, &ne) || ne.Path != "/" || !errors.Is(err, context.Canceled) {
+		t.Errorf("EvaluateContext() expected a NodeError at / wrapping %v, got %v\n", context.Canceled, err)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	validate := func(n *Node) error {
+		return n.Validate()
+	}
+	compile := func(n *Node) error {
+		_, err := Compile(n)
+		return err
+	}
+	getTemplate := func(n *Node) error {
+		_, err := n.GetTemplate(template.FuncMap{"known": func() bool { return true }})
+		return err
+	}
+	for _, tc := range []struct {
+		name    string
+		fn      func(*Node) error
+		leaf    *Node
+		offset  int
+		message string
+	}{
+		{"Validate", validate, &Node{Op: OperatorLeaf, Leaf: "gt .X ("}, 6, "unclosed left paren"},
+		{"Validate", validate, &Node{Op: OperatorLeaf, Leaf: "and (eq .A 1) (gt .B 2"}, 14, "unclosed left paren"},
+		{"Validate", validate, &Node{Op: OperatorLeaf, Leaf: "eq .A 1x"}, 6, `bad number syntax: "1x"`},
+		{"Validate", validate, &Node{Op: OperatorLeaf, Leaf: `eq .A "abc`}, 6, "unterminated quoted string"},
+		{"Validate", validate, &Node{Op: OperatorLeaf, Leaf: "eq .A\n 1 ]"}, 9, `unexpected "]" in operand`},
+		{"Validate", validate, NewAdvancedLeafNode("{{ if .A }}true{{ else }}"), 24, "unexpected EOF"},
+		{"Compile", compile, &Node{Op: OperatorLeaf, Leaf: "and (foo .A) (eq .B 1)"}, 5, `function "foo" not defined`},
+		{"Compile", compile, &Node{Op: OperatorLeaf, Leaf: "eq .A 1 )"}, 8, "unexpected right paren"},
+		{"GetTemplate", getTemplate, &Node{Op: OperatorLeaf, Leaf: "known .A | | not"}, 11, `unexpected "|" in command`},
+		{"GetTemplate", getTemplate, &Node{Op: OperatorLeaf, Leaf: "eq .A (unknown .B)"}, 7, `function "unknown" not defined`},
+	} {
+		err := tc.fn(NewNode(OperatorAnd, NewLeafNode("true"), NewNode(OperatorOr, NewLeafNode("eq .A 1"), tc.leaf)))
+		var ne *NodeError
+		var pe *ParseError
+		if !errors.As(err, &ne) || !errors.As(err, &pe) {
+			t.Errorf("%s(%q) expected a ParseError, got %v\n", tc.name, tc.leaf.Leaf, err)
+			continue
+		}
+		if ne.Path != "/1/1" || pe.Leaf != tc.leaf.Leaf || pe.Offset != tc.offset || pe.Msg != tc.message {
+			t.Errorf("%s(%q) expected=/1/1 %d %q actual=%s %d %q\n", tc.name, tc.leaf.Leaf, tc.offset, tc.message, ne.Path, pe.Offset, pe.Msg)
+		}
+	}
+
+	// Trees whose leaves parse keep their templates.
+	if err := getTemplate(NewNode(OperatorOr, NewLeafNode("known .A"), NewLeafNode("eq .B 1"))); err != nil {
+		t.Errorf("GetTemplate() unexpected error: %s\n", err.Error())
+	}
+}
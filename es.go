@@ -0,0 +1,182 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"strings"
+	"text/template/parse"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ToESQuery converts the tree into an Elasticsearch query, to be marshaled as
+// JSON and used as the "query" of a search.  An `and` becomes a `bool` query
+// whose children `must` match, an `or` one where at least one `should` match
+// and `not (...)` one where it `must_not`.  An `if` becomes an `or` of the
+// condition and its then branch, and of the negated condition and its else
+// branch, and a switch an `or` of its cases each with the guards of those
+// before it negated.  Fields become dotted field names, such as "Store.City" for
+// `.Store.City`.
+//
+// Leaves must be one of the structured forms accepted by `ToSQL`.  Equality
+// becomes a `term` or `terms` query, orderings and `between` a `range` query,
+// `hasPrefix` a `prefix` query, `contains` and `hasSuffix` a `wildcard`
+// query, `matches` a `regexp` query (which uses Lucene's syntax and must match
+// the whole value) and the literals `true` and `false` `match_all` and
+// `match_none`.  Any other leaf fails with `ErrNotTranslatable`.  Note that
+// `term` queries against analyzed text fields rarely match, so fields should
+// be mapped as keywords.
+func (n *Node) ToESQuery() (map[string]interface{}, error) {
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+	return esNode(n, "/")
+}
+
+func esNode(n *Node, path string) (map[string]interface{}, error) {
+	if n.Op == OperatorAdvanced {
+		return nil, nodeError(path, n, fmt.Errorf("%w: advanced leaf", ErrNotTranslatable))
+	}
+	if isCustom(n.Op) {
+		return nil, nodeError(path, n, fmt.Errorf("%w: operator %s", ErrNotTranslatable, n.Op))
+	}
+	if n.Op == OperatorLeaf {
+		t, err := parseLeaf(n.Leaf)
+		if err != nil {
+			return nil, nodeError(path, n, err)
+		}
+		p, ok := leafPipe(t)
+		if !ok {
+			return nil, nodeError(path, n, fmt.Errorf("%w: %s", ErrNotTranslatable, n.Leaf))
+		}
+		q, ok := esPipe(p)
+		if !ok {
+			return nil, nodeError(path, n, fmt.Errorf("%w: %s", ErrNotTranslatable, n.Leaf))
+		}
+		return q, nil
+	}
+	if n.Op == OperatorSwitch {
+		return esSwitch(n, path)
+	}
+
+	qs := make([]interface{}, len(n.Nodes))
+	for i, c := range n.Nodes {
+		q, err := esNode(c, childPath(path, i))
+		if err != nil {
+			return nil, err
+		}
+		qs[i] = q
+	}
+	if n.Op == OperatorIf {
+		// Either the condition and the then branch match, or the condition
+		// does not and the else branch does.
+		then := esBool("must", []interface{}{qs[0], qs[1]})
+		els := esBool("must", []interface{}{qs[2]})
+		els["bool"].(map[string]interface{})["must_not"] = []interface{}{qs[0]}
+		return esBool("should", []interface{}{then, els}, "minimum_should_match", 1), nil
+	}
+	if n.Op == OperatorOr {
+		return esBool("should", qs, "minimum_should_match", 1), nil
+	}
+	return esBool("must", qs), nil
+}
+
+// esSwitch translates the switch `n` into an `or` of each case, whose guard
+// and child match and the guards of the cases before it do not, and of the
+// default, if any, with no guard matching.
+func esSwitch(n *Node, path string) (map[string]interface{}, error) {
+	guards, qs := []interface{}{}, []interface{}{}
+	var def map[string]interface{}
+	for i, c := range n.Nodes {
+		cp := childPath(path, i)
+		q, err := esNode(c.Nodes[0], childPath(cp, 0))
+		if err != nil {
+			return nil, err
+		}
+		if c.Leaf == "" {
+			def = q
+			continue
+		}
+		g, err := esNode(caseGuard(n, c), cp)
+		if err != nil {
+			return nil, err
+		}
+		qs = append(qs, esBool("must", []interface{}{g, q}, "must_not", append([]interface{}{}, guards...)))
+		guards = append(guards, g)
+	}
+	if def != nil {
+		qs = append(qs, esBool("must", []interface{}{def}, "must_not", guards))
+	}
+	return esBool("should", qs, "minimum_should_match", 1), nil
+}
+
+// esBool returns a bool query with the `occur` clauses `qs` and any further
+// key value pairs in `kvs`.
+func esBool(occur string, qs []interface{}, kvs ...interface{}) map[string]interface{} {
+	b := map[string]interface{}{occur: qs}
+	for i := 0; i+1 < len(kvs); i += 2 {
+		b[kvs[i].(string)] = kvs[i+1]
+	}
+	return map[string]interface{}{"bool": b}
+}
+
+// esQuery returns the query `{kind: {field: v}}`.
+func esQuery(kind string, field []string, v interface{}) map[string]interface{} {
+	return map[string]interface{}{kind: map[string]interface{}{strings.Join(field, "."): v}}
+}
+
+// esPipe translates a leaf pipeline, returning false if it is not structured.
+func esPipe(p *parse.PipeNode) (map[string]interface{}, bool) {
+	if v, ok := pipeConstant(p); ok {
+		if v {
+			return map[string]interface{}{"match_all": map[string]interface{}{}}, true
+		}
+		return map[string]interface{}{"match_none": map[string]interface{}{}}, true
+	}
+	if inner, ok := pipeNot(p); ok {
+		q, ok := esPipe(inner)
+		return esBool("must_not", []interface{}{q}), ok
+	}
+	if m, ok := pipeStringMatch(p); ok {
+		return esStringMatch(m), true
+	}
+	if c, ok := pipeComparison(p); ok {
+		return esComparison(c), true
+	}
+	return nil, false
+}
+
+func esComparison(c *comparison) map[string]interface{} {
+	switch c.op {
+	case "eq":
+		if len(c.values) == 1 {
+			return esQuery("term", c.field, c.values[0])
+		}
+		return esQuery("terms", c.field, c.values)
+	case "ne":
+		return esBool("must_not", []interface{}{esQuery("term", c.field, c.values[0])})
+	case "between":
+		return esQuery("range", c.field, map[string]interface{}{"gte": c.values[0], "lte": c.values[1]})
+	}
+	return esQuery("range", c.field, map[string]interface{}{esRanges[c.op]: c.values[0]})
+}
+
+var esRanges = map[string]string{
+	"lt": "lt", "le": "lte", "gt": "gt", "ge": "gte",
+}
+
+func esStringMatch(m *stringMatch) map[string]interface{} {
+	switch m.fn {
+	case "hasPrefix":
+		return esQuery("prefix", m.field, m.text)
+	case "matches":
+		return esQuery("regexp", m.field, m.text)
+	case "hasSuffix":
+		return esQuery("wildcard", m.field, "*"+wildcardEscaper.Replace(m.text))
+	}
+	return esQuery("wildcard", m.field, "*"+wildcardEscaper.Replace(m.text)+"*")
+}
+
+// wildcardEscaper escapes the wildcards of a `wildcard` query.
+var wildcardEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`)
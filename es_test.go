@@ -0,0 +1,70 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestToESQuery(t *testing.T) {
+	n := NewNode(OperatorOr,
+		NewNode(OperatorAnd,
+			NewLeafNode("between .Milk 4 6"),
+			NewLeafNode(`in .Store.City ["SF", "LA"]`),
+			NewLeafNode(`ne .Aisle "health"`)),
+		NewLeafNode("lt 5 .Toothpaste"),
+		NewLeafNode(`not (contains .Name "5*")`))
+
+	q, err := n.ToESQuery()
+	if err != nil {
+		t.Fatalf("ToESQuery() error: %s\n", err.Error())
+	}
+	bs, err := json.Marshal(q)
+	if err != nil {
+		t.Fatalf("json.Marshal() error: %s\n", err.Error())
+	}
+
+	expected := `{"bool":{"minimum_should_match":1,"should":[` +
+		`{"bool":{"must":[` +
+		`{"range":{"Milk":{"gte":4,"lte":6}}},` +
+		`{"terms":{"Store.City":["SF","LA"]}},` +
+		`{"bool":{"must_not":[{"term":{"Aisle":"health"}}]}}]}},` +
+		`{"range":{"Toothpaste":{"gt":5}}},` +
+		`{"bool":{"must_not":[{"wildcard":{"Name":"*5\\**"}}]}}]}}`
+	if string(bs) != expected {
+		t.Errorf("ToESQuery() expected=%s\nactual=%s\n", expected, string(bs))
+	}
+}
+
+func TestToESQueryLeaves(t *testing.T) {
+	for _, tc := range []struct {
+		leaf     string
+		expected string
+	}{
+		{`eq .Name "milk"`, `{"term":{"Name":"milk"}}`},
+		{"le .Price 1.5", `{"range":{"Price":{"lte":1.5}}}`},
+		{`hasPrefix .Name "mi"`, `{"prefix":{"Name":"mi"}}`},
+		{`hasSuffix .Name "k"`, `{"wildcard":{"Name":"*k"}}`},
+		{`matches .Name "mi.*"`, `{"regexp":{"Name":"mi.*"}}`},
+		{"false", `{"match_none":{}}`},
+	} {
+		q, err := NewLeafNode(tc.leaf).ToESQuery()
+		if err != nil {
+			t.Errorf("ToESQuery(%q) error: %s\n", tc.leaf, err.Error())
+			continue
+		}
+		bs, _ := json.Marshal(q)
+		if string(bs) != tc.expected {
+			t.Errorf("ToESQuery(%q) expected=%s actual=%s\n", tc.leaf, tc.expected, string(bs))
+		}
+	}
+
+	_, err := NewNode(OperatorOr, NewLeafNode("eq .A 1"), NewLeafNode("gt (len .Tags) 2")).ToESQuery()
+	if !errors.Is(err, ErrNotTranslatable) {
+		t.Errorf("ToESQuery() expected=%v actual=%v\n", ErrNotTranslatable, err)
+	}
+}
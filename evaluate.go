@@ -0,0 +1,334 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// evalState is shared by every node visited during a single evaluation.
+type evalState struct {
+	ctx  context.Context // the caller's context, running leaves are abandoned when done
+	stop context.Context // done once no further nodes should be started
+	sem  chan struct{}   // bounds concurrently executing leaves, nil if sequential
+
+	missing MissingPolicy
+	hooks   *EvalHooks     // nil if no hooks are set
+	cached  bool           // node results are cached, see `WithIncremental`
+	shared  *sharedResults // nil unless subtrees are shared, see `WithSharedEvaluation`
+
+	leaves  *leafBudget        // nil unless the leaves are limited or counted, see `WithBudget`
+	cancel  context.CancelFunc // releases the deadline of the budget, if any
+	tracing *tracing           // nil unless the evaluation is traced, see `WithTracer`
+}
+
+// stopped returns a non-nil error if no further nodes should be evaluated,
+// wrapping the caller's context error, or that of the exceeded budget, with
+// the path of node `cn`.
+func (st *evalState) stopped(cn *compiledNode) error {
+	if err := st.ctx.Err(); err != nil {
+		if cause := context.Cause(st.ctx); errors.Is(cause, ErrBudgetExceeded) {
+			return nodeError(cn.path, cn.node, cause)
+		}
+		return nodeError(cn.path, cn.node, fmt.Errorf("evaluation stopped: %w", err))
+	}
+	return st.stop.Err()
+}
+
+// decisive returns the child result which decides the result of `op`.
+func decisive(op Operator) bool {
+	return op == OperatorOr
+}
+
+// EvaluateContext is like `Evaluate` but stops as soon as `ctx` is cancelled
+// or its deadline passes, returning the context's error wrapped with the path
+// of the node at which evaluation stopped.  A leaf whose custom function is
+// still running when that happens is abandoned; it keeps running in the
+// background until the function returns but its result is discarded.
+func (ct *CompiledTree) EvaluateContext(ctx context.Context, data interface{}) (bool, error) {
+	if ct.opts.metrics == nil && ct.opts.logging == nil {
+		return ct.evaluateTraced(ctx, data)
+	}
+	start := time.Now()
+	v, err := ct.evaluateTraced(ctx, data)
+	d := time.Since(start)
+	if ct.opts.metrics != nil {
+		ct.opts.metrics.ObserveEvaluation(v, err, d)
+	}
+	if ct.opts.logging != nil {
+		ct.logEvaluation(ctx, data, v, err, d)
+	}
+	return v, err
+}
+
+// evaluateContext is `EvaluateContext`, also returning the number of leaves
+// executed if they are counted, see `leafBudget`.
+func (ct *CompiledTree) evaluateContext(ctx context.Context, data interface{}) (bool, int, error) {
+	var k cacheKey
+	keyed := false
+	if ct.cache != nil {
+		if k, keyed = dataKey(data, ct.fields); keyed {
+			if v, hit := ct.cache.get(k); hit {
+				return v, 0, nil
+			}
+		}
+	}
+
+	st := ct.newState(ctx)
+	defer releaseState(st)
+	if ct.opts.incremental {
+		st.cached = true
+		ct.mu.Lock()
+		defer ct.mu.Unlock()
+	}
+	v, err := ct.eval.evaluate(st, data)
+	if err == nil && keyed {
+		ct.cache.put(k, v)
+	}
+	leaves := 0
+	if st.leaves != nil {
+		leaves = int(st.leaves.spent.Load())
+	}
+	return v, leaves, err
+}
+
+// newState returns the state for a single evaluation of the tree.
+func (ct *CompiledTree) newState(ctx context.Context) *evalState {
+	st := statePool.Get().(*evalState)
+	*st = evalState{ctx: ctx, stop: ctx, missing: ct.opts.missing}
+	if h := &ct.opts.hooks; h.OnNodeStart != nil || h.OnNodeEnd != nil || h.OnLeafResult != nil {
+		st.hooks = h
+	}
+	if ct.opts.parallelism > 1 {
+		st.sem = make(chan struct{}, ct.opts.parallelism)
+	}
+	if ct.shared > 0 {
+		st.shared = newSharedResults(ct.shared)
+	}
+	st.withBudget(ct.opts.budget)
+	if ct.opts.tracing != nil {
+		st.tracing = ct.opts.tracing
+		if st.leaves == nil {
+			st.leaves = &leafBudget{max: math.MaxInt64}
+		}
+	}
+	return st
+}
+
+// statePool holds the states of finished sequential evaluations.
+var statePool = sync.Pool{
+	New: func() interface{} { return &evalState{} },
+}
+
+// releaseState returns the state of a finished evaluation to the pool, unless
+// the evaluation was parallel: children it abandoned may still hold it.  The
+// deadline of its budget is released either way.
+func releaseState(st *evalState) {
+	if st.cancel != nil {
+		st.cancel()
+	}
+	if st.sem == nil {
+		*st = evalState{}
+		statePool.Put(st)
+	}
+}
+
+func (cn *compiledNode) evaluate(st *evalState, data interface{}) (bool, error) {
+	if err := st.stopped(cn); err != nil {
+		return false, err
+	}
+	if st.cached && cn.cache.valid {
+		return cn.cache.v, nil
+	}
+	if st.shared != nil && cn.share >= 0 {
+		if v, ok := st.shared.get(cn.share); ok {
+			return v, nil
+		}
+		v, err := cn.evaluateCached(st, data)
+		if err == nil {
+			st.shared.set(cn.share, v)
+		}
+		return v, err
+	}
+	return cn.evaluateCached(st, data)
+}
+
+// evaluateCached evaluates the node, caching its result if the tree is
+// incremental.
+func (cn *compiledNode) evaluateCached(st *evalState, data interface{}) (bool, error) {
+	if st.cached && !cn.volatile {
+		v, err := cn.evaluateTraced(st, data)
+		if err == nil {
+			cn.cache = nodeCache{valid: true, v: v}
+		}
+		return v, err
+	}
+	return cn.evaluateTraced(st, data)
+}
+
+// evaluateHooked evaluates the node, calling any hooks around it.
+func (cn *compiledNode) evaluateHooked(st *evalState, data interface{}) (bool, error) {
+	if st.hooks == nil {
+		return cn.evaluateNode(st, data)
+	}
+
+	if st.hooks.OnNodeStart != nil {
+		st.hooks.OnNodeStart(cn.path, cn.node)
+	}
+	v, err := cn.evaluateNode(st, data)
+	if st.hooks.OnNodeEnd != nil {
+		st.hooks.OnNodeEnd(cn.path, cn.node, v, err)
+	}
+	return v, err
+}
+
+func (cn *compiledNode) evaluateNode(st *evalState, data interface{}) (bool, error) {
+	if cn.node.isLeaf() {
+		return cn.evaluateLeaf(st, data)
+	}
+	if cn.custom != nil {
+		return cn.evaluateCustom(st, data)
+	}
+	if cn.node.Op == OperatorIf {
+		return cn.evaluateIf(st, data)
+	}
+	if cn.sw != nil {
+		return cn.evaluateSwitch(st, data)
+	}
+	if st.sem != nil && len(cn.children) > 1 {
+		return cn.evaluateParallel(st, data)
+	}
+
+	d := decisive(cn.node.Op)
+	for _, c := range cn.children {
+		v, err := c.evaluate(st, data)
+		if err != nil {
+			return false, err
+		}
+		if v == d {
+			return d, nil
+		}
+	}
+	return !d, nil
+}
+
+func (cn *compiledNode) evaluateLeaf(st *evalState, data interface{}) (bool, error) {
+	if st.hooks == nil || st.hooks.OnLeafResult == nil {
+		_, v, err := cn.runLeaf(st, data)
+		return v, err
+	}
+	out, v, err := cn.runLeaf(st, data)
+	st.hooks.OnLeafResult(cn.path, cn.node.Leaf, out, v, err)
+	return v, err
+}
+
+// runLeaf evaluates a leaf, returning its rendered output and result.
+func (cn *compiledNode) runLeaf(st *evalState, data interface{}) (string, bool, error) {
+	if st.missing != MissingDefault {
+		if f, ok := missingField(data, cn.fields); ok {
+			if st.missing == MissingIsFalse {
+				return "", false, nil
+			}
+			return "", false, nodeError(cn.path, cn.node, fmt.Errorf("%w: %s", ErrMissingField, f))
+		}
+	}
+
+	out, err := cn.renderLeaf(st, data)
+	if err != nil {
+		return out, false, nodeError(cn.path, cn.node, err)
+	}
+	v, err := parseResult(out)
+	if err != nil {
+		return out, false, nodeError(cn.path, cn.node, err)
+	}
+	return out, v, nil
+}
+
+// renderLeaf executes a leaf, waiting for a slot if the evaluation
+// is bounded and abandoning it if the caller's context is done.
+func (cn *compiledNode) renderLeaf(st *evalState, data interface{}) (string, error) {
+	if st.leaves != nil {
+		if err := st.leaves.spend(cn); err != nil {
+			return "", err
+		}
+	}
+	if st.sem != nil {
+		select {
+		case st.sem <- struct{}{}:
+			defer func() { <-st.sem }()
+		case <-st.stop.Done():
+			return "", st.stopped(cn)
+		}
+		if err := st.stopped(cn); err != nil {
+			return "", err
+		}
+	}
+
+	if st.ctx.Done() == nil {
+		return cn.execLeaf(data)
+	}
+
+	type result struct {
+		out string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		out, err := cn.execLeaf(data)
+		done <- result{out, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.out, r.err
+	case <-st.ctx.Done():
+		return "", st.stopped(cn)
+	}
+}
+
+// evaluateParallel evaluates every child concurrently and returns as soon as
+// the result is decided.  Children which have not yet started are cancelled,
+// those already executing are waited on so that, unless the caller's context
+// is done, no evaluation outlives the call.
+func (cn *compiledNode) evaluateParallel(st *evalState, data interface{}) (bool, error) {
+	stop, cancel := context.WithCancel(st.stop)
+	sub := &evalState{ctx: st.ctx, stop: stop, sem: st.sem, missing: st.missing, hooks: st.hooks, cached: st.cached, shared: st.shared, leaves: st.leaves, tracing: st.tracing}
+
+	type result struct {
+		v   bool
+		err error
+	}
+	results := make(chan result, len(cn.children))
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	for _, c := range cn.children {
+		wg.Add(1)
+		go func(c *compiledNode) {
+			defer wg.Done()
+			v, err := c.evaluate(sub, data)
+			results <- result{v, err}
+		}(c)
+	}
+
+	d := decisive(cn.node.Op)
+	for range cn.children {
+		r := <-results
+		if r.err != nil {
+			return false, r.err
+		}
+		if r.v == d {
+			return d, nil
+		}
+	}
+	return !d, nil
+}
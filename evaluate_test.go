@@ -0,0 +1,268 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"text/template"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type prices struct {
+	Milk       int
+	Onions     int
+	Toothpaste int
+}
+
+func pricesTree() *Node {
+	return NewNode(OperatorOr,
+		NewNode(OperatorAnd,
+			NewNode(OperatorAnd,
+				NewLeafNode("ge .Milk 4"),
+				NewLeafNode("le .Milk 6")),
+			NewNode(OperatorAnd,
+				NewLeafNode("ge .Onions 1"),
+				NewLeafNode("le .Onions 2"))),
+		NewLeafNode("gt .Toothpaste 5"))
+}
+
+func TestEvaluate(t *testing.T) {
+	for _, opts := range [][]Option{
+		nil,
+		{WithParallelism(4)},
+	} {
+		ct, err := Compile(pricesTree(), opts...)
+		if err != nil {
+			t.Fatalf("Compile() error: %s\n", err.Error())
+		}
+
+		for _, tc := range []struct {
+			p        prices
+			expected bool
+		}{
+			{prices{5, 0, 4}, false},
+			{prices{5, 2, 4}, true},
+			{prices{5, 0, 8}, true},
+			{prices{9, 2, 4}, false},
+		} {
+			v, err := ct.Evaluate(&tc.p)
+			if err != nil {
+				t.Errorf("Evaluate() error: %s\n", err.Error())
+			}
+			if v != tc.expected {
+				t.Errorf("Evaluate(%#v) expected=%v actual=%v\n", tc.p, tc.expected, v)
+			}
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, n := range []*Node{
+		NewNode(OperatorAnd),
+		NewNode("xor", NewLeafNode("true")),
+		{Op: OperatorLeaf, Leaf: "}} {{"},
+	} {
+		if _, err := Compile(n); err == nil {
+			t.Errorf("Compile(%#v) expected an error\n", n)
+		}
+	}
+
+	ct, err := Compile(NewLeafNode(`print "maybe"`))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if _, err := ct.Evaluate(nil); err == nil {
+		t.Errorf("Evaluate() expected a non-boolean error\n")
+	}
+}
+
+func TestEvaluateParallel(t *testing.T) {
+	var running, peak, calls int32
+	fm := template.FuncMap{
+		"slow": func(v bool) bool {
+			atomic.AddInt32(&calls, 1)
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			if !v {
+				time.Sleep(20 * time.Millisecond)
+			}
+			return v
+		},
+	}
+
+	leaves := []*Node{NewLeafNode("slow true")}
+	for i := 0; i < 16; i++ {
+		leaves = append(leaves, NewLeafNode("slow false"))
+	}
+
+	ct, err := Compile(NewNode(OperatorOr, leaves...), WithFuncs(fm), WithParallelism(2))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	v, err := ct.Evaluate(nil)
+	if err != nil {
+		t.Fatalf("Evaluate() error: %s\n", err.Error())
+	}
+	if !v {
+		t.Errorf("Evaluate() expected=true actual=false\n")
+	}
+	if p := atomic.LoadInt32(&peak); p > 2 {
+		t.Errorf("Evaluate() ran %d leaves concurrently, expected at most 2\n", p)
+	}
+	if c := atomic.LoadInt32(&calls); c == int32(len(leaves)) {
+		t.Errorf("Evaluate() expected remaining leaves to be cancelled, all %d ran\n", c)
+	}
+	if r := atomic.LoadInt32(&running); r != 0 {
+		t.Errorf("Evaluate() returned with %d leaves still running\n", r)
+	}
+}
+
+func TestEvaluateContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	fm := template.FuncMap{
+		"hang": func() bool {
+			<-release
+			return true
+		},
+	}
+
+	for _, opts := range [][]Option{
+		{WithFuncs(fm)},
+		{WithFuncs(fm), WithParallelism(2)},
+	} {
+		ct, err := Compile(NewNode(OperatorAnd,
+			NewLeafNode("true"),
+			NewNode(OperatorOr,
+				NewLeafNode("false"),
+				NewLeafNode("hang"))), opts...)
+		if err != nil {
+			t.Fatalf("Compile() error: %s\n", err.Error())
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		_, err = ct.EvaluateContext(ctx, nil)
+		cancel()
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("EvaluateContext() expected deadline exceeded, got: %v\n", err)
+		}
+		if !strings.Contains(err.Error(), "/1/1") {
+			t.Errorf("EvaluateContext() expected the error to name node /1/1, got: %s\n", err.Error())
+		}
+	}
+
+	ct, err := Compile(pricesTree())
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ct.EvaluateContext(ctx, &prices{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("EvaluateContext() expected canceled, got: %v\n", err)
+	}
+}
+
+func TestEvaluateAllocs(t *testing.T) {
+	ct, err := Compile(pricesTree())
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	for _, data := range []interface{}{
+		map[string]interface{}{"Milk": 5, "Onions": 3, "Toothpaste": 4},
+		&prices{Milk: 5, Onions: 3, Toothpaste: 4},
+	} {
+		allocs := testing.AllocsPerRun(100, func() {
+			if _, err := ct.Evaluate(data); err != nil {
+				t.Fatalf("Evaluate() error: %s\n", err.Error())
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("Evaluate(%T) expected allocs=0 actual=%v\n", data, allocs)
+		}
+	}
+}
+
+// BenchmarkEvaluate measures evaluating a compiled tree, whose comparisons
+// skip their templates, against executing the template of the whole tree.
+func BenchmarkEvaluate(b *testing.B) {
+	n := pricesTree()
+	data := map[string]interface{}{"Milk": 5, "Onions": 3, "Toothpaste": 4}
+	fm := template.FuncMap{"id": func(v interface{}) interface{} { return v }}
+
+	ct, err := Compile(n)
+	if err != nil {
+		b.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	b.Run("compiled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := ct.Evaluate(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	// Leaves calling other functions are rendered by their templates.
+	calls, err := Compile(NewNode(OperatorOr,
+		NewLeafNode("ge (id .Milk) 4"),
+		NewLeafNode("gt (id .Toothpaste) 5")), WithFuncs(fm))
+	if err != nil {
+		b.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	b.Run("compiled/templates", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := calls.Evaluate(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	// Executing the template of the whole tree, parsed for every evaluation
+	// without the cache of `GetTemplate`.
+	b.Run("parse", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			e, err := n.Combine()
+			if err != nil {
+				b.Fatal(err)
+			}
+			tmpl, err := template.New("tree").Funcs(StdFuncs()).Parse("{{ " + e + " }}")
+			if err != nil {
+				b.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("GetTemplate", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			tmpl, err := n.GetTemplate(StdFuncs())
+			if err != nil {
+				b.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
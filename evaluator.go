@@ -0,0 +1,91 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Evaluator evaluates a tree against a snapshot of data which changes a few
+// fields at a time, such as continuously updated metrics.  After each delta
+// only the leaves referencing changed fields, and their ancestors, are
+// re-evaluated, as described by `WithIncremental`, using a cache private to
+// the evaluator.  It is safe for concurrent use, evaluations are serialized.
+type Evaluator struct {
+	ct   *CompiledTree
+	root *compiledNode // a copy of the tree holding this evaluator's cache
+
+	mu     sync.Mutex
+	data   map[string]interface{}
+	result bool
+	known  bool // `result` is the last successful result, to compare deltas with
+}
+
+// NewEvaluator returns an evaluator of the tree against `data`, which must
+// not be modified other than through the evaluator.
+func (ct *CompiledTree) NewEvaluator(data map[string]interface{}) *Evaluator {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return &Evaluator{ct: ct, root: ct.eval.clone(), data: data}
+}
+
+// Evaluate returns the result of the tree for the current snapshot.
+func (e *Evaluator) Evaluate(ctx context.Context) (bool, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.evaluate(ctx)
+}
+
+// Apply sets the fields of `delta`, written as in leaves with or without the
+// leading dot, as `DataHandle.Set` does, and re-evaluates the tree.  It
+// returns the new result and whether it differs from the result before the
+// delta, which is evaluated first if need be; `changed` is true if that
+// failed.  On error the delta is applied regardless, and the next delta is
+// compared with the last successful result.
+func (e *Evaluator) Apply(ctx context.Context, delta map[string]interface{}) (result, changed bool, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.known {
+		e.evaluate(ctx)
+	}
+	last, known := e.result, e.known
+
+	// Fields are set in order, so that "A" is set before "A.B".
+	fields := make([]string, 0, len(delta))
+	for f := range delta {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	for _, f := range fields {
+		setField(e.data, strings.Split(strings.TrimPrefix(f, "."), "."), delta[f])
+	}
+	if len(fields) > 0 {
+		e.root.invalidate(fieldPaths(fields))
+	}
+
+	v, err := e.evaluate(ctx)
+	if err != nil {
+		return false, false, err
+	}
+	return v, !known || v != last, nil
+}
+
+// evaluate evaluates the current snapshot, recording its result.
+func (e *Evaluator) evaluate(ctx context.Context) (bool, error) {
+	st := e.ct.newState(ctx)
+	defer releaseState(st)
+	st.cached = true
+	v, err := e.root.evaluate(st, e.data)
+	if err != nil {
+		return false, err
+	}
+	e.result, e.known = v, true
+	return v, nil
+}
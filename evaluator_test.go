@@ -0,0 +1,88 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"testing"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestEvaluator(t *testing.T) {
+	calls := map[string]int{}
+	count := func(name string, v interface{}) interface{} {
+		calls[name]++
+		return v
+	}
+	n := NewNode(OperatorAnd,
+		NewLeafNode(`ge (count "cpu" .Host.CPU) 90`),
+		NewNode(OperatorOr,
+			NewLeafNode(`gt (count "errors" .Errors) 10`),
+			NewLeafNode(`gt (count "latency" .Latency) 500`)))
+	ct, err := Compile(n, WithFuncs(template.FuncMap{"count": count}))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	ctx := context.Background()
+	e := ct.NewEvaluator(map[string]interface{}{
+		"Host":    map[string]interface{}{"CPU": 95},
+		"Errors":  0,
+		"Latency": 600,
+	})
+	if v, err := e.Evaluate(ctx); err != nil || !v {
+		t.Fatalf("Evaluate() expected=true actual=%v err=%v\n", v, err)
+	}
+
+	for i, tc := range []struct {
+		delta    map[string]interface{}
+		expected bool
+		changed  bool
+		err      bool
+		calls    map[string]int
+	}{
+		{map[string]interface{}{}, true, false, false, map[string]int{"cpu": 1, "errors": 1, "latency": 1}},
+		{map[string]interface{}{"Errors": 20}, true, false, false, map[string]int{"cpu": 1, "errors": 2, "latency": 1}},
+		{map[string]interface{}{".Host.CPU": 50}, false, true, false, map[string]int{"cpu": 2, "errors": 2, "latency": 1}},
+		{map[string]interface{}{"Latency": 0, "Errors": 0}, false, false, false, map[string]int{"cpu": 2, "errors": 2, "latency": 1}},
+		{map[string]interface{}{"Host": map[string]interface{}{"CPU": 99}}, false, false, false, map[string]int{"cpu": 3, "errors": 3, "latency": 2}},
+		// A failed evaluation leaves the last result to compare with.
+		{map[string]interface{}{"Errors": "many"}, false, false, true, map[string]int{"cpu": 3, "errors": 4, "latency": 2}},
+		{map[string]interface{}{"Errors": 11}, true, true, false, map[string]int{"cpu": 3, "errors": 5, "latency": 2}},
+	} {
+		v, changed, err := e.Apply(ctx, tc.delta)
+		if (err != nil) != tc.err {
+			t.Errorf("Apply(%d) expected err=%v actual=%v\n", i, tc.err, err)
+		}
+		if v != tc.expected || changed != tc.changed {
+			t.Errorf("Apply(%d) expected=%v,%v actual=%v,%v\n", i, tc.expected, tc.changed, v, changed)
+		}
+		for k, c := range tc.calls {
+			if calls[k] != c {
+				t.Errorf("Apply(%d) expected %s calls=%d actual=%d\n", i, k, c, calls[k])
+			}
+		}
+	}
+}
+
+func TestEvaluatorFirstApply(t *testing.T) {
+	ct, err := Compile(NewLeafNode("gt .A 1"))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	ctx := context.Background()
+
+	// The snapshot before the first delta is evaluated for comparison.
+	e := ct.NewEvaluator(map[string]interface{}{"A": 2})
+	if v, changed, err := e.Apply(ctx, map[string]interface{}{"A": 3}); err != nil || !v || changed {
+		t.Errorf("Apply() expected=true,false actual=%v,%v err=%v\n", v, changed, err)
+	}
+
+	// A snapshot which fails has no result to compare with.
+	e = ct.NewEvaluator(nil)
+	if v, changed, err := e.Apply(ctx, map[string]interface{}{"A": 0}); err != nil || v || !changed {
+		t.Errorf("Apply() expected=false,true actual=%v,%v err=%v\n", v, changed, err)
+	}
+}
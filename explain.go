@@ -0,0 +1,375 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ExplanationVersion is the version of the JSON encoding of `Explanation`s,
+// described by `ExplanationSchema`.  Fields may be added to the encoding
+// within a version, but none are renamed, retyped or removed.
+const ExplanationVersion = 1
+
+// Explanation is the result of a tree for a piece of data, node by node.  Its
+// JSON encoding, for frontends rendering the breakdown of decisions, is
+// specified by `ExplanationSchema`.
+type Explanation struct {
+	Version int            `json:"Version"` // `ExplanationVersion`
+	Result  bool           `json:"Result"`
+	Root    *ExplainedNode `json:"Root"`
+
+	// Flips is a smallest set of leaves whose results would all have to
+	// differ for the tree to have the other result: the conditions a
+	// rejection came down to, or those which would have to fail for an
+	// acceptance to be overturned.  It is empty if no leaves can change the
+	// result, as when it is decided by the leaves `true` and `false`.
+	Flips []LeafFlip `json:"Flips"`
+}
+
+// ExplainedNode is the result of a single node of an `Explanation`.
+// `Output` is the rendered output of a leaf, and `Values` the values of the
+// fields it reads keyed by field, such as ".Order.Total", null for those
+// which are missing.  Values which do not encode as JSON are rendered as by
+// `fmt.Sprint`.  `Error` is set, and `Result` false, for a leaf which failed
+// after `Evaluate` would have stopped.
+type ExplainedNode struct {
+	Path   string                 `json:"Path"`
+	Op     Operator               `json:"Op"`
+	Leaf   string                 `json:"Leaf,omitempty"`
+	Output string                 `json:"Output,omitempty"`
+	Values map[string]interface{} `json:"Values,omitempty"`
+	Error  string                 `json:"Error,omitempty"`
+	Result bool                   `json:"Result"`
+	Nodes  []*ExplainedNode       `json:"Nodes,omitempty"`
+}
+
+// LeafFlip is a leaf of `Explanation.Flips` with its result.  Leaves are
+// named as in `TruthTable`, and a leaf `not X` is flipped by flipping `X`,
+// so `Paths` lists every leaf of the tree which would flip with it.
+type LeafFlip struct {
+	Leaf   string   `json:"Leaf"`
+	Result bool     `json:"Result"`
+	Paths  []string `json:"Paths"`
+}
+
+// maxFlipSteps bounds the combinations of leaves `Explain` tries flipping.
+const maxFlipSteps = 1 << 16
+
+// Explain evaluates the tree against `data` as `Evaluate` does, but without
+// short-circuiting, so as to report the result of every node.  Hooks are not
+// called and leaves are evaluated sequentially.  Explain fails only where
+// `Evaluate` would: a leaf which fails after a sibling, or a sibling of an
+// ancestor, decided its parent is reported with its `Error` instead, such as
+// the guard `gt .Order.Total 5` of `or (true) (gt .Order.Total 5)` for data
+// without an order.
+//
+// The result of each leaf is then taken as a boolean input, as in
+// `Satisfiable`, to find the fewest leaves which would have to be flipped to
+// flip the result of the tree.  The results of failed leaves are unknown, and
+// flips are only chosen if they flip the tree whatever those results are.  The set found is the smallest unless the
+// tree has so many leaves that checking every smaller combination is not
+// practical, in which case it is a small one.
+func (ct *CompiledTree) Explain(data interface{}) (*Explanation, error) {
+	return ct.ExplainContext(context.Background(), data)
+}
+
+// ExplainContext is like `Explain` but stops once `ctx` is done, as
+// `EvaluateContext` does.
+func (ct *CompiledTree) ExplainContext(ctx context.Context, data interface{}) (*Explanation, error) {
+	st := ct.newState(ctx)
+	defer releaseState(st)
+	st.hooks, st.sem = nil, nil
+
+	results := map[string]bool{}
+	root, err := ct.eval.explain(st, data, results, false)
+	if err != nil {
+		return nil, err
+	}
+	return &Explanation{
+		Version: ExplanationVersion,
+		Result:  root.Result,
+		Root:    root,
+		Flips:   leafFlips(ct.root, results),
+	}, nil
+}
+
+// explain returns the result of the node and records those of its leaves in
+// `results`.  `skipped` is set if `Evaluate` would not evaluate the node, so
+// that its leaves may fail.
+func (cn *compiledNode) explain(st *evalState, data interface{}, results map[string]bool, skipped bool) (*ExplainedNode, error) {
+	if err := st.stopped(cn); err != nil {
+		return nil, err
+	}
+
+	en := &ExplainedNode{Path: cn.path, Op: cn.node.Op, Leaf: cn.node.Leaf}
+	if cn.node.isLeaf() {
+		en.Values = explainValues(data, cn.fields)
+		out, v, err := cn.runLeaf(st, data)
+		if err != nil && skipped {
+			en.Error = err.Error()
+			return en, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		en.Output, en.Result = out, v
+		results[cn.path] = v
+		return en, nil
+	}
+
+	if cn.custom != nil {
+		vs := make([]bool, len(cn.children))
+		for i, c := range cn.children {
+			ce, err := c.explain(st, data, results, skipped)
+			if err != nil {
+				return nil, err
+			}
+			en.Nodes = append(en.Nodes, ce)
+			vs[i] = ce.Result
+		}
+		en.Result = cn.custom.Evaluate(vs)
+		return en, nil
+	}
+
+	if cn.sw != nil {
+		return cn.explainSwitch(st, data, results, skipped, en)
+	}
+
+	if cn.node.Op == OperatorIf {
+		cond, err := cn.children[0].explain(st, data, results, skipped)
+		if err != nil {
+			return nil, err
+		}
+		en.Nodes = append(en.Nodes, cond)
+		for i, c := range cn.children[1:] {
+			// The branch not taken is skipped.
+			ce, err := c.explain(st, data, results, skipped || cond.Result != (i == 0))
+			if err != nil {
+				return nil, err
+			}
+			en.Nodes = append(en.Nodes, ce)
+		}
+		en.Result = en.Nodes[2].Result
+		if cond.Result {
+			en.Result = en.Nodes[1].Result
+		}
+		return en, nil
+	}
+
+	d := decisive(cn.node.Op)
+	en.Result = !d
+	for _, c := range cn.children {
+		ce, err := c.explain(st, data, results, skipped || en.Result == d)
+		if err != nil {
+			return nil, err
+		}
+		en.Nodes = append(en.Nodes, ce)
+		if ce.Result == d {
+			en.Result = d
+		}
+	}
+	return en, nil
+}
+
+// explainValues returns the values of the `fields` of `data` as reported by
+// `ExplainedNode.Values`, or nil if there are none.  Fields reached through
+// methods are left out.
+func explainValues(data interface{}, fields [][]string) map[string]interface{} {
+	var values map[string]interface{}
+	for _, f := range fields {
+		rv, ok := fieldValue(data, f)
+		if !ok {
+			continue
+		}
+		var v interface{}
+		if rv.IsValid() {
+			v = rv.Interface()
+			if _, err := json.Marshal(v); err != nil {
+				v = fmt.Sprint(v)
+			}
+		}
+		if values == nil {
+			values = map[string]interface{}{}
+		}
+		values["."+strings.Join(f, ".")] = v
+	}
+	return values
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// leafFlips returns the fewest leaves of the tree rooted at `n` which flip
+// its result, given the `results` of its leaves by path.  Leaves without a
+// result are unknown, and never flipped.
+func leafFlips(n *Node, results map[string]bool) []LeafFlip {
+	e, vars, err := n.boolean(true)
+	if err != nil {
+		return []LeafFlip{}
+	}
+
+	vals := make([]Truth, len(vars))
+	for v := range vals {
+		vals[v] = Unknown
+	}
+	paths := make([][]string, len(vars))
+	e.leaves(func(l *boolExpr) {
+		if l.variable < 0 {
+			return
+		}
+		if r, ok := results[l.path]; ok {
+			vals[l.variable] = truth(r != l.negated)
+		}
+		paths[l.variable] = append(paths[l.variable], l.path)
+	})
+	// The explained tree has a known result.
+	target := e.eval3(vals) != True
+
+	// Flips found as if every leaf were independent bound the search.
+	best, ok := e.flips(vals, target)
+	ok = ok && e.flipsTo(vals, best, target)
+	limit := len(vars)
+	if ok {
+		limit = len(best) - 1
+	}
+	steps := 0
+	for k := 1; k <= limit && steps < maxFlipSteps; k++ {
+		if s, found := e.searchFlips(vals, nil, 0, k, target, &steps); found {
+			best, ok = s, true
+			break
+		}
+	}
+
+	flips := []LeafFlip{}
+	if !ok {
+		return flips
+	}
+	for v := range vars {
+		for _, b := range best {
+			if b == v {
+				flips = append(flips, LeafFlip{Leaf: vars[v], Result: vals[v] == True, Paths: paths[v]})
+			}
+		}
+	}
+	return flips
+}
+
+// flips returns the variables to flip to make the expression `target`,
+// choosing as few as it can as if no variable were repeated, or reports that
+// the expression cannot be made `target`.
+func (e *boolExpr) flips(vals []Truth, target bool) ([]int, bool) {
+	if e.custom != nil {
+		// Only the search finds which children to flip.
+		return nil, e.eval3(vals) == truth(target)
+	}
+	switch e.op {
+	case OperatorAnd, OperatorOr:
+		if decisive(e.op) == target {
+			// Any one child decides the result.
+			var best []int
+			found := false
+			for _, c := range e.children {
+				if s, ok := c.flips(vals, target); ok && (!found || len(s) < len(best)) {
+					best, found = s, true
+				}
+			}
+			return best, found
+		}
+
+		var all []int
+		for _, c := range e.children {
+			s, ok := c.flips(vals, target)
+			if !ok {
+				return nil, false
+			}
+			all = unionVars(all, s)
+		}
+		return all, true
+	case OperatorIf:
+		cond, then, els := e.children[0], e.children[1], e.children[2]
+		// Either the branch taken is flipped, or the condition and the other
+		// branch are, and both branches if the condition is unknown.
+		best, found := []int(nil), false
+		for _, choice := range []bool{true, false} {
+			branch, other := then, els
+			if !choice {
+				branch, other = els, then
+			}
+			c := cond.eval3(vals)
+			s, ok := branch.flips(vals, target)
+			switch {
+			case c == Unknown:
+				o, ok2 := other.flips(vals, target)
+				s, ok = unionVars(s, o), ok && ok2
+			case c != truth(choice):
+				o, ok2 := cond.flips(vals, choice)
+				s, ok = unionVars(s, o), ok && ok2
+			}
+			if ok && (!found || len(s) < len(best)) {
+				best, found = s, true
+			}
+		}
+		return best, found
+	}
+
+	if e.variable < 0 {
+		return nil, e.constant == target
+	}
+	if vals[e.variable] == Unknown {
+		return nil, false
+	}
+	if ((vals[e.variable] == True) != e.negated) == target {
+		return nil, true
+	}
+	return []int{e.variable}, true
+}
+
+// flipsTo reports whether flipping the known variables `flips` makes the
+// expression `target`.
+func (e *boolExpr) flipsTo(vals []Truth, flips []int, target bool) bool {
+	flipped := append([]Truth(nil), vals...)
+	for _, v := range flips {
+		flipped[v] = truth(flipped[v] != True)
+	}
+	return e.eval3(flipped) == truth(target)
+}
+
+// searchFlips tries every combination of `k` known variables which adds
+// those from `start` on to `chosen`, returning the first which makes the
+// expression `target`.
+func (e *boolExpr) searchFlips(vals []Truth, chosen []int, start, k int, target bool, steps *int) ([]int, bool) {
+	if len(chosen) == k {
+		*steps++
+		return chosen, e.flipsTo(vals, chosen, target)
+	}
+	for i := start; i < len(vals) && *steps < maxFlipSteps; i++ {
+		if vals[i] == Unknown {
+			continue
+		}
+		if s, ok := e.searchFlips(vals, append(chosen[:len(chosen):len(chosen)], i), i+1, k, target, steps); ok {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// unionVars returns the variables of `a` followed by those of `b` which are
+// not in `a`.
+func unionVars(a, b []int) []int {
+	all := append([]int(nil), a...)
+next:
+	for _, v := range b {
+		for _, u := range all {
+			if u == v {
+				continue next
+			}
+		}
+		all = append(all, v)
+	}
+	return all
+}
@@ -0,0 +1,139 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestExplain(t *testing.T) {
+	ct, err := Compile(pricesTree())
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	x, err := ct.Explain(map[string]interface{}{"Milk": 3, "Onions": 5, "Toothpaste": 2})
+	if err != nil {
+		t.Fatalf("Explain() error: %s\n", err.Error())
+	}
+	if x.Result || x.Root.Result || x.Root.Path != "/" || len(x.Root.Nodes) != 2 {
+		t.Errorf("Explain() unexpected explanation: %+v\n", x.Root)
+	}
+	// Every leaf is evaluated, even those after a false child of an `and`.
+	milk := x.Root.Nodes[0].Nodes[0]
+	if leaf := milk.Nodes[1]; leaf.Path != "/0/0/1" || leaf.Leaf != "(le .Milk 6)" || leaf.Output != "true" || !leaf.Result {
+		t.Errorf("Explain() unexpected leaf: %+v\n", leaf)
+	}
+	if expected := []LeafFlip{{Leaf: "(gt .Toothpaste 5)", Result: false, Paths: []string{"/1"}}}; !reflect.DeepEqual(x.Flips, expected) {
+		t.Errorf("Explain() expected flips=%+v actual=%+v\n", expected, x.Flips)
+	}
+}
+
+func TestExplainFlips(t *testing.T) {
+	data := map[string]interface{}{"A": 1, "B": 1, "C": 0, "X": 0, "Y": 0, "Milk": 3, "Onions": 5}
+	for _, tc := range []struct {
+		n        *Node
+		result   bool
+		expected []LeafFlip
+	}{
+		// Rejected because of exactly two conditions.
+		{NewNode(OperatorAnd, NewLeafNode("ge .Milk 4"), NewLeafNode("le .Milk 6"), NewLeafNode("le .Onions 2")), false, []LeafFlip{
+			{Leaf: "(ge .Milk 4)", Result: false, Paths: []string{"/0"}},
+			{Leaf: "(le .Onions 2)", Result: false, Paths: []string{"/2"}},
+		}},
+		{NewNode(OperatorOr,
+			NewNode(OperatorAnd, NewLeafNode("eq .C 1"), NewLeafNode("eq .A 1")),
+			NewNode(OperatorAnd, NewLeafNode("eq .C 1"), NewLeafNode("eq .B 1"))), false, []LeafFlip{
+			{Leaf: "(eq .C 1)", Result: false, Paths: []string{"/0/0", "/1/0"}},
+		}},
+		{NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("not (eq .B 1)")), false, []LeafFlip{
+			{Leaf: "(eq .B 1)", Result: true, Paths: []string{"/1"}},
+		}},
+		{NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("eq .B 1")), true, []LeafFlip{
+			{Leaf: "(eq .A 1)", Result: true, Paths: []string{"/0"}},
+		}},
+		// Flipping the first leaf satisfies the first `or` but fails the
+		// second, only the second leaf flips the tree.
+		{NewNode(OperatorAnd,
+			NewNode(OperatorOr, NewLeafNode("eq .C 1"), NewLeafNode("eq .X 1")),
+			NewNode(OperatorOr, NewLeafNode("not (eq .C 1)"), NewLeafNode("eq .Y 1"))), false, []LeafFlip{
+			{Leaf: "(eq .X 1)", Result: false, Paths: []string{"/0/1"}},
+		}},
+		{NewNode(OperatorAnd, NewLeafNode("false"), NewLeafNode("eq .A 1")), false, []LeafFlip{}},
+	} {
+		ct, err := Compile(tc.n, WithFuncs(StdFuncs()))
+		if err != nil {
+			t.Fatalf("Compile(%s) error: %s\n", tc.n, err.Error())
+		}
+		x, err := ct.Explain(data)
+		if err != nil {
+			t.Errorf("Explain(%s) error: %s\n", tc.n, err.Error())
+			continue
+		}
+		if x.Result != tc.result || !reflect.DeepEqual(x.Flips, tc.expected) {
+			t.Errorf("Explain(%s) expected=%v %+v actual=%v %+v\n", tc.n, tc.result, tc.expected, x.Result, x.Flips)
+		}
+	}
+}
+
+func TestExplainShortCircuit(t *testing.T) {
+	data := map[string]interface{}{"A": 1, "B": 1}
+	for _, tc := range []struct {
+		n        *Node
+		result   bool
+		failed   string
+		expected []LeafFlip
+	}{
+		// The guard only fails where `Evaluate` would never evaluate it.
+		{NewNode(OperatorOr, NewLeafNode("true"), NewLeafNode("gt .Order.Total 5")), true, "/1", []LeafFlip{}},
+		{NewNode(OperatorAnd,
+			NewLeafNode("eq .A 1"),
+			NewNode(OperatorOr, NewLeafNode("eq .B 1"), NewLeafNode("gt .Order.Total 5"))), true, "/1/1", []LeafFlip{
+			{Leaf: "(eq .A 1)", Result: true, Paths: []string{"/0"}},
+		}},
+		{NewNode(OperatorAnd, NewLeafNode("eq .A 2"), NewNode(OperatorAnd, NewLeafNode("ge .B 0"), NewLeafNode("gt .Order.Total 5"))), false, "/1/1", []LeafFlip{}},
+	} {
+		ct, err := Compile(tc.n)
+		if err != nil {
+			t.Fatalf("Compile(%s) error: %s\n", tc.n, err.Error())
+		}
+		if v, err := ct.Evaluate(data); err != nil || v != tc.result {
+			t.Fatalf("Evaluate(%s) expected=%v actual=%v err=%v\n", tc.n, tc.result, v, err)
+		}
+		x, err := ct.Explain(data)
+		if err != nil {
+			t.Errorf("Explain(%s) error: %s\n", tc.n, err.Error())
+			continue
+		}
+		if x.Result != tc.result || !reflect.DeepEqual(x.Flips, tc.expected) {
+			t.Errorf("Explain(%s) expected=%v %+v actual=%v %+v\n", tc.n, tc.result, tc.expected, x.Result, x.Flips)
+		}
+		var failed []string
+		var walk func(en *ExplainedNode)
+		walk = func(en *ExplainedNode) {
+			if en.Error != "" {
+				failed = append(failed, en.Path)
+			}
+			for _, c := range en.Nodes {
+				walk(c)
+			}
+		}
+		walk(x.Root)
+		if !reflect.DeepEqual(failed, []string{tc.failed}) {
+			t.Errorf("Explain(%s) expected failed=%v actual=%v\n", tc.n, tc.failed, failed)
+		}
+	}
+}
+
+func TestExplainErrors(t *testing.T) {
+	ct, err := Compile(pricesTree(), WithMissing(MissingIsError))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if _, err := ct.Explain(map[string]interface{}{"Milk": 5}); !errors.Is(err, ErrMissingField) {
+		t.Errorf("Explain() expected=%v actual=%v\n", ErrMissingField, err)
+	}
+}
@@ -0,0 +1,99 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ExplanationSchema is the JSON Schema of the JSON encoding of an
+// `Explanation` of `ExplanationVersion`, as served by `logictreehttp` and
+// printed by `logictree eval -explain`, for frontends rendering the
+// breakdown of decisions to validate against or generate their types from.
+const ExplanationSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "$id": "https://github.com/sabhiram/logictree/explanation-v1.schema.json",
+  "title": "Explanation",
+  "description": "The result of a logictree tree for a piece of data, node by node.",
+  "type": "object",
+  "required": ["Version", "Result", "Root", "Flips"],
+  "properties": {
+    "Version": {
+      "description": "The version of the encoding, 1.",
+      "const": 1
+    },
+    "Result": {
+      "description": "The result of the tree.",
+      "type": "boolean"
+    },
+    "Root": {
+      "description": "The root of the tree.",
+      "$ref": "#/$defs/node"
+    },
+    "Flips": {
+      "description": "A smallest set of leaves whose results would all have to differ for the tree to have the other result, empty if no leaves can change it.",
+      "type": "array",
+      "items": { "$ref": "#/$defs/flip" }
+    }
+  },
+  "$defs": {
+    "node": {
+      "description": "The result of a node of the tree.",
+      "type": "object",
+      "required": ["Path", "Op", "Result"],
+      "properties": {
+        "Path": {
+          "description": "The path of the node, / for the root and /1/0 for the first child of its second child.",
+          "type": "string"
+        },
+        "Op": {
+          "description": "The operator of the node: leaf, advanced, and, or, if, switch, case, or that of a registered operator.",
+          "type": "string"
+        },
+        "Leaf": {
+          "description": "The expression of a leaf, the template of an advanced leaf, or the value of a case.",
+          "type": "string"
+        },
+        "Output": {
+          "description": "The rendered output of a leaf.",
+          "type": "string"
+        },
+        "Values": {
+          "description": "The values of the fields a leaf reads, keyed by field such as .Order.Total, null for those which are missing.",
+          "type": "object",
+          "additionalProperties": true
+        },
+        "Error": {
+          "description": "The error of a leaf which failed where the evaluation of the tree would have short-circuited, or of a switch which could not choose a case.",
+          "type": "string"
+        },
+        "Result": {
+          "description": "The result of the node, false if it failed.",
+          "type": "boolean"
+        },
+        "Nodes": {
+          "description": "The children of the node, in order.",
+          "type": "array",
+          "items": { "$ref": "#/$defs/node" }
+        }
+      }
+    },
+    "flip": {
+      "description": "A leaf whose result would have to differ, with its result.",
+      "type": "object",
+      "required": ["Leaf", "Result", "Paths"],
+      "properties": {
+        "Leaf": {
+          "description": "The normalized expression of the leaf.",
+          "type": "string"
+        },
+        "Result": {
+          "description": "The result of the leaf.",
+          "type": "boolean"
+        },
+        "Paths": {
+          "description": "The paths of every leaf of the tree which would flip with it.",
+          "type": "array",
+          "items": { "type": "string" }
+        }
+      }
+    }
+  }
+}
+`
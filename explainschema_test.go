@@ -0,0 +1,139 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// validateSchema checks `v` against the subset of JSON Schema which
+// `ExplanationSchema` uses.  Objects may only hold the properties the schema
+// lists, unless it allows others, so that every field encoded is documented.
+func validateSchema(root, schema map[string]interface{}, v interface{}, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		def := root["$defs"].(map[string]interface{})[strings.TrimPrefix(ref, "#/$defs/")]
+		if def == nil {
+			return fmt.Errorf("%s: unknown $ref %s", path, ref)
+		}
+		return validateSchema(root, def.(map[string]interface{}), v, path)
+	}
+	if c, ok := schema["const"]; ok && c != v {
+		return fmt.Errorf("%s: expected %v, got %v", path, c, v)
+	}
+
+	switch schema["type"] {
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: expected a boolean, got %v", path, v)
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%s: expected a string, got %v", path, v)
+		}
+	case "array":
+		a, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected an array, got %v", path, v)
+		}
+		for i, item := range a {
+			if err := validateSchema(root, schema["items"].(map[string]interface{}), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %v", path, v)
+		}
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, k := range required {
+				if _, ok := o[k.(string)]; !ok {
+					return fmt.Errorf("%s: missing %s", path, k)
+				}
+			}
+		}
+		if schema["additionalProperties"] == true {
+			break
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(o))
+		for k := range o {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p, ok := props[k]
+			if !ok {
+				return fmt.Errorf("%s: undocumented field %s", path, k)
+			}
+			if err := validateSchema(root, p.(map[string]interface{}), o[k], path+"."+k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func TestExplanationSchema(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(ExplanationSchema), &schema); err != nil {
+		t.Fatalf("Unmarshal(ExplanationSchema) error: %s\n", err.Error())
+	}
+	if schema["properties"].(map[string]interface{})["Version"].(map[string]interface{})["const"] != float64(ExplanationVersion) {
+		t.Errorf("ExplanationSchema expected version %d\n", ExplanationVersion)
+	}
+
+	guarded := NewNode(OperatorOr, NewLeafNode("true"), NewLeafNode("gt .Order.Total 5"))
+	sw := NewSwitchNode(".Country", NewCaseNode(NewLeafNode("ge .Age 21"), "US"), NewCaseNode(NewLeafNode("ge .Age 18")))
+	for _, tc := range []struct {
+		tree *Node
+		data interface{}
+	}{
+		{pricesTree(), map[string]interface{}{"Milk": 3, "Onions": 5, "Toothpaste": 2}},
+		{guarded, map[string]interface{}{}},
+		{NewIfNode(NewLeafNode(".A"), sw, NewAdvancedLeafNode("{{ .B }}")), map[string]interface{}{"A": true, "Country": "US", "Age": 20}},
+		{NewLeafNode("eq .Ch 1"), map[string]interface{}{"Ch": make(chan int)}},
+	} {
+		ct, err := Compile(tc.tree, WithFuncs(StdFuncs()))
+		if err != nil {
+			t.Fatalf("Compile() error: %s\n", err.Error())
+		}
+		x, err := ct.Explain(tc.data)
+		if err != nil {
+			t.Fatalf("Explain(%s) error: %s\n", tc.tree, err.Error())
+		}
+		bs, err := json.Marshal(x)
+		if err != nil {
+			t.Fatalf("Marshal(%s) error: %s\n", tc.tree, err.Error())
+		}
+		var v interface{}
+		if err := json.Unmarshal(bs, &v); err != nil {
+			t.Fatalf("Unmarshal() error: %s\n", err.Error())
+		}
+		if err := validateSchema(schema, schema, v, "$"); err != nil {
+			t.Errorf("Explain(%s) does not match ExplanationSchema: %s\n%s\n", tc.tree, err.Error(), bs)
+		}
+	}
+}
+
+func TestExplainValues(t *testing.T) {
+	type order struct{ Total float64 }
+	ct, err := Compile(NewNode(OperatorAnd, NewLeafNode("gt .Order.Total .Min"), NewLeafNode("eq .Country \"US\"")))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	x, err := ct.Explain(map[string]interface{}{"Order": order{Total: 12.5}, "Min": 10})
+	if err != nil {
+		t.Fatalf("Explain() error: %s\n", err.Error())
+	}
+	bs, _ := json.Marshal([]map[string]interface{}{x.Root.Nodes[0].Values, x.Root.Nodes[1].Values})
+	if expected := `[{".Min":10,".Order.Total":12.5},{".Country":null}]`; string(bs) != expected {
+		t.Errorf("Explain() expected=%s actual=%s\n", expected, bs)
+	}
+}
@@ -0,0 +1,237 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// bufPool holds the buffers leaf templates are rendered into.
+var bufPool = sync.Pool{
+	New: func() interface{} { return &bytes.Buffer{} },
+}
+
+// executeLeaf renders a leaf template against `data`.
+func executeLeaf(tmpl *template.Template, data interface{}) (string, error) {
+	buf := bufPool.Get().(*bytes.Buffer)
+	defer func() {
+		buf.Reset()
+		bufPool.Put(buf)
+	}()
+
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	// The usual outputs are returned without copying the buffer.
+	switch out := bytes.TrimSpace(buf.Bytes()); {
+	case len(out) == buf.Len() && string(out) == "true":
+		return "true", nil
+	case len(out) == buf.Len() && string(out) == "false":
+		return "false", nil
+	}
+	return buf.String(), nil
+}
+
+// compareRow evaluates the comparison leaf `cn` against `data` without its
+// template, reporting false if it cannot: if its field is not found as simply
+// as in a map or an exported struct field, or if the comparison fails, so
+// that the template reports the error as it always has.
+func (cn *compiledNode) compareRow(data interface{}) (bool, bool) {
+	o, ok := cn.lookupField(data)
+	if !ok {
+		return false, false
+	}
+
+	c := cn.cmp
+	switch c.op {
+	case "eq":
+		for _, l := range c.values {
+			if o.equal(l) {
+				return true, true
+			}
+		}
+		return false, true
+	case "ne":
+		return !o.equal(c.values[0]), true
+	case "between":
+		lo, err := o.compare(c.values[0])
+		if err != nil {
+			return false, false
+		}
+		hi, err := o.compare(c.values[1])
+		if err != nil {
+			return false, false
+		}
+		return lo >= 0 && hi <= 0, true
+	}
+
+	r, err := o.compare(c.values[0])
+	if err != nil {
+		return false, false
+	}
+	switch c.op {
+	case "lt":
+		return r < 0, true
+	case "le":
+		return r <= 0, true
+	case "gt":
+		return r > 0, true
+	case "ge":
+		return r >= 0, true
+	}
+	return false, false
+}
+
+// operand is the field of a comparison leaf, as a value or, if read from a
+// struct, as a number or a string, which passing as a value would allocate.
+type operand struct {
+	v   interface{}
+	n   number
+	s   string
+	num bool
+	str bool
+}
+
+// equal is `equal` of the operand and `l`.
+func (o operand) equal(l interface{}) bool {
+	switch {
+	case o.num:
+		ln, ok := toNumber(l)
+		return ok && compareNumbers(o.n, ln) == 0
+	case o.str:
+		ls, ok := l.(string)
+		return ok && o.s == ls
+	}
+	return equal(o.v, l)
+}
+
+// compare is `compare` of the operand and `l`.
+func (o operand) compare(l interface{}) (int, error) {
+	switch {
+	case o.num:
+		if ln, ok := toNumber(l); ok {
+			if c := compareNumbers(o.n, ln); c != 2 {
+				return c, nil
+			}
+		}
+		return 0, errNoFastPath
+	case o.str:
+		if ls, ok := l.(string); ok {
+			return compareOrdered(o.s, ls), nil
+		}
+		return 0, errNoFastPath
+	}
+	return compare(o.v, l)
+}
+
+// errNoFastPath fails a comparison on the fast path, leaving the template to
+// fail it with its own error.
+var errNoFastPath = errors.New("no fast path")
+
+// lookupField returns the field of the comparison leaf `cn` in `data` as a
+// template would pass it to a function, or false if it is missing or takes
+// more than indexing maps and reading exported fields of structs without
+// methods to find.
+func (cn *compiledNode) lookupField(data interface{}) (operand, bool) {
+	v := data
+	for i, name := range cn.cmp.field {
+		if m, ok := v.(map[string]interface{}); ok {
+			if v, ok = m[name]; !ok {
+				return operand{}, false
+			}
+			continue
+		}
+
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return operand{}, false
+			}
+			rv = rv.Elem()
+		}
+		if !rv.IsValid() {
+			return operand{}, false
+		}
+		fi := cn.index[i].get(rv.Type(), name)
+		switch {
+		case !fi.ok:
+			return operand{}, false
+		case fi.key.IsValid():
+			rv = rv.MapIndex(fi.key)
+		default:
+			var err error
+			if rv, err = rv.FieldByIndexErr(fi.index); err != nil {
+				return operand{}, false
+			}
+		}
+		if !rv.IsValid() {
+			return operand{}, false
+		}
+
+		if i == len(cn.cmp.field)-1 {
+			switch rv.Kind() {
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				return operand{n: number{kind: reflect.Int, i: rv.Int()}, num: true}, true
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+				return operand{n: number{kind: reflect.Uint, u: rv.Uint()}, num: true}, true
+			case reflect.Float32, reflect.Float64:
+				return operand{n: number{kind: reflect.Float64, f: rv.Float()}, num: true}, true
+			case reflect.String:
+				// Values of named string types equal no literal.
+				if rv.Type() == stringType {
+					return operand{s: rv.String(), str: true}, true
+				}
+			}
+		}
+		v = rv.Interface()
+	}
+	return operand{v: v}, true
+}
+
+var stringType = reflect.TypeOf("")
+
+// fieldIndex caches how a part of the field of a comparison leaf is read from
+// the type of value it was last read from, so that reading it again does not
+// allocate.
+type fieldIndex struct {
+	last atomic.Pointer[typeIndex]
+}
+
+// typeIndex is how the field `name` is read from values of type `t`: by the
+// index of a struct field, by the `key` of a map, or not at all.
+type typeIndex struct {
+	t     reflect.Type
+	name  string
+	ok    bool
+	index []int
+	key   reflect.Value
+}
+
+func (f *fieldIndex) get(t reflect.Type, name string) *typeIndex {
+	if ti := f.last.Load(); ti != nil && ti.t == t && ti.name == name {
+		return ti
+	}
+
+	ti := &typeIndex{t: t, name: name}
+	// Templates call methods in preference to fields of the same name.
+	if t.NumMethod() == 0 && reflect.PointerTo(t).NumMethod() == 0 {
+		switch t.Kind() {
+		case reflect.Struct:
+			sf, ok := t.FieldByName(name)
+			ti.ok, ti.index = ok && sf.IsExported(), sf.Index
+		case reflect.Map:
+			if t.Key() == stringType {
+				ti.ok, ti.key = true, reflect.ValueOf(name)
+			}
+		}
+	}
+	f.last.Store(ti)
+	return ti
+}
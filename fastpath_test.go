@@ -0,0 +1,88 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type fastName string
+
+type fastOrder struct {
+	Total    float64
+	Items    uint
+	Customer string
+	Name     fastName
+	Notes    interface{}
+	Address  *fastAddress
+	secret   int
+}
+
+type fastAddress struct {
+	City string
+}
+
+type fastMethods struct {
+	Total int
+}
+
+func (fastMethods) Count() int { return 1 }
+
+func TestCompareRow(t *testing.T) {
+	order := &fastOrder{Total: 12.5, Items: 3, Customer: "ann", Name: "ann", Notes: 2, Address: &fastAddress{City: "Oslo"}, secret: 1}
+	for _, tc := range []struct {
+		leaf string
+		data interface{}
+		fast bool // evaluated without the template
+	}{
+		{"gt .Total 10", order, true},
+		{"between .Items 1 3", order, true},
+		{"eq .Items -1", order, true},
+		{"ge .Items -1", order, true},
+		{"eq .Customer \"bob\" \"ann\"", order, true},
+		{"lt .Customer \"bob\"", order, true},
+		{"eq .Name \"ann\"", order, true},
+		{"ne .Notes 2", order, true},
+		{"eq .Address.City \"Oslo\"", order, true},
+		{"eq .Address.City \"Oslo\"", fastOrder{Address: &fastAddress{City: "Oslo"}}, true},
+		{"eq .A.B 1.0", map[string]interface{}{"A": map[string]interface{}{"B": 1}}, true},
+		{"eq .A.B 1", map[string]interface{}{"A": map[string]int{"B": 1}}, true},
+		{"lt .A 1", map[string]interface{}{"A": math.NaN()}, false},
+		{"lt .Name \"bob\"", order, false},
+		{"gt .A 1", map[string]interface{}{"A": "x"}, false},
+		{"gt .Total 1", fastMethods{Total: 2}, false},
+		{"gt .secret 0", order, false},
+		{"eq .Address.City \"Oslo\"", &fastOrder{}, false},
+		{"eq .A 1", map[string]interface{}{}, false},
+		{"eq .A 1", map[int]interface{}{}, false},
+	} {
+		ct, err := Compile(NewLeafNode(tc.leaf), WithFuncs(StdFuncs()))
+		if err != nil {
+			t.Fatalf("Compile(%s) error: %s\n", tc.leaf, err.Error())
+		}
+		cn := ct.eval
+		if cn.cmp == nil {
+			t.Fatalf("Compile(%s) expected a comparison\n", tc.leaf)
+		}
+
+		v, ok := cn.compareRow(tc.data)
+		if ok != tc.fast {
+			t.Errorf("compareRow(%s, %T) expected fast=%v actual=%v\n", tc.leaf, tc.data, tc.fast, ok)
+		}
+		if !ok {
+			continue
+		}
+		out, err := executeLeaf(cn.tmpl, tc.data)
+		if err != nil {
+			t.Errorf("compareRow(%s, %T) expected=error actual=%v\n", tc.leaf, tc.data, v)
+			continue
+		}
+		if expected, _ := strconv.ParseBool(out); v != expected {
+			t.Errorf("compareRow(%s, %T) expected=%v actual=%v\n", tc.leaf, tc.data, expected, v)
+		}
+	}
+}
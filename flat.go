@@ -0,0 +1,109 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"sort"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// FlatNode is a node of a tree flattened by `Flatten` into a list, one row
+// of a relational table, so that trees are stored and edited row by row and
+// their clauses queried individually.
+type FlatNode struct {
+	// ID identifies the node within its tree, from 1.
+	ID int `json:"ID"`
+
+	// Parent is the ID of the parent of the node, 0 for the root.
+	Parent int `json:"Parent"`
+
+	// Position orders the node among the children of its parent.
+	Position int `json:"Position"`
+
+	Op   Operator `json:"Op"`
+	Leaf string   `json:"Leaf,omitempty"`
+}
+
+// Flatten returns the nodes of the tree as a list, in depth first order, the
+// root first.  Nodes are numbered from 1 in that order, and the children of
+// every node are at the positions 0, 1, 2 and so on.
+func (n *Node) Flatten() []FlatNode {
+	var rows []FlatNode
+	var flatten func(n *Node, parent, position int)
+	flatten = func(n *Node, parent, position int) {
+		id := len(rows) + 1
+		rows = append(rows, FlatNode{ID: id, Parent: parent, Position: position, Op: n.Op, Leaf: n.Leaf})
+		for i, c := range n.Nodes {
+			flatten(c, id, i)
+		}
+	}
+	flatten(n, 0, 0)
+	return rows
+}
+
+// Unflatten returns the tree of the nodes `rows`, the inverse of `Flatten`.
+// The rows may be in any order, the IDs any positive numbers, and the
+// positions of the children of a node need not be consecutive, so that rows
+// may be inserted and deleted one at a time.  It fails unless there is
+// exactly one root, every node has a distinct ID and a parent among the
+// rows, and the children of every node a distinct position.  The tree is not
+// validated, see `Validate`.
+func Unflatten(rows []FlatNode) (*Node, error) {
+	nodes := make(map[int]*Node, len(rows))
+	for _, r := range rows {
+		if r.ID <= 0 {
+			return nil, fmt.Errorf("invalid flat tree: node %d: ID is not positive", r.ID)
+		}
+		if _, ok := nodes[r.ID]; ok {
+			return nil, fmt.Errorf("invalid flat tree: node %d: duplicate ID", r.ID)
+		}
+		nodes[r.ID] = &Node{Op: r.Op, Leaf: r.Leaf}
+	}
+
+	// Children are attached in the order of their positions.
+	sorted := append([]FlatNode(nil), rows...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Parent != sorted[j].Parent {
+			return sorted[i].Parent < sorted[j].Parent
+		}
+		return sorted[i].Position < sorted[j].Position
+	})
+	var root *Node
+	for i, r := range sorted {
+		if r.Parent == 0 {
+			if root != nil {
+				return nil, fmt.Errorf("invalid flat tree: node %d: more than one root", r.ID)
+			}
+			root = nodes[r.ID]
+			continue
+		}
+		parent, ok := nodes[r.Parent]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("invalid flat tree: node %d: no parent %d", r.ID, r.Parent)
+		case i > 0 && sorted[i-1].Parent == r.Parent && sorted[i-1].Position == r.Position:
+			return nil, fmt.Errorf("invalid flat tree: node %d: duplicate position %d in node %d", r.ID, r.Position, r.Parent)
+		}
+		parent.Nodes = append(parent.Nodes, nodes[r.ID])
+	}
+	if root == nil {
+		return nil, fmt.Errorf("invalid flat tree: no root")
+	}
+
+	// Nodes which are not reached from the root are in cycles.
+	reached := 0
+	var count func(n *Node)
+	count = func(n *Node) {
+		reached++
+		for _, c := range n.Nodes {
+			count(c)
+		}
+	}
+	count(root)
+	if reached != len(nodes) {
+		return nil, fmt.Errorf("invalid flat tree: %d nodes not reached from the root", len(nodes)-reached)
+	}
+	return root, nil
+}
@@ -0,0 +1,69 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestFlatten(t *testing.T) {
+	tree := NewNode(OperatorAnd,
+		NewLeafNode("ge .Age 18"),
+		NewNode(OperatorOr, NewLeafNode(`eq .City "SF"`), NewLeafNode(`eq .City "LA"`)))
+	expected := []FlatNode{
+		{ID: 1, Parent: 0, Position: 0, Op: OperatorAnd},
+		{ID: 2, Parent: 1, Position: 0, Op: OperatorLeaf, Leaf: "(ge .Age 18)"},
+		{ID: 3, Parent: 1, Position: 1, Op: OperatorOr},
+		{ID: 4, Parent: 3, Position: 0, Op: OperatorLeaf, Leaf: `(eq .City "SF")`},
+		{ID: 5, Parent: 3, Position: 1, Op: OperatorLeaf, Leaf: `(eq .City "LA")`},
+	}
+	rows := tree.Flatten()
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Flatten() expected=%v actual=%v\n", expected, rows)
+	}
+	n, err := Unflatten(rows)
+	if err != nil {
+		t.Fatalf("Unflatten() error: %s\n", err.Error())
+	}
+	if !reflect.DeepEqual(n, tree) {
+		t.Errorf("Unflatten() expected=%v actual=%v\n", tree, n)
+	}
+
+	// Rows edited one at a time, in any order, with gaps in their IDs and
+	// positions.
+	edited := []FlatNode{
+		{ID: 40, Parent: 30, Position: 5, Op: OperatorLeaf, Leaf: `(eq .City "NY")`},
+		{ID: 30, Parent: 10, Position: 1, Op: OperatorOr},
+		{ID: 10, Op: OperatorAnd},
+		{ID: 41, Parent: 30, Position: 2, Op: OperatorLeaf, Leaf: `(eq .City "SF")`},
+		{ID: 20, Parent: 10, Position: 0, Op: OperatorLeaf, Leaf: "(ge .Age 18)"},
+	}
+	n, err = Unflatten(edited)
+	if err != nil {
+		t.Fatalf("Unflatten() error: %s\n", err.Error())
+	}
+	if actual := n.String(); actual != `.Age >= 18 AND (.City == "SF" OR .City == "NY")` {
+		t.Errorf("Unflatten() expected=%s actual=%s\n", `.Age >= 18 AND (.City == "SF" OR .City == "NY")`, actual)
+	}
+
+	for _, tc := range []struct {
+		rows     []FlatNode
+		expected string
+	}{
+		{nil, "no root"},
+		{[]FlatNode{{ID: 0, Op: OperatorAnd}}, "ID is not positive"},
+		{[]FlatNode{{ID: 1, Op: OperatorAnd}, {ID: 1, Parent: 1, Op: OperatorLeaf}}, "duplicate ID"},
+		{[]FlatNode{{ID: 1, Op: OperatorAnd}, {ID: 2, Op: OperatorAnd}}, "more than one root"},
+		{[]FlatNode{{ID: 1, Op: OperatorAnd}, {ID: 2, Parent: 3, Op: OperatorLeaf}}, "node 2: no parent 3"},
+		{[]FlatNode{{ID: 1, Op: OperatorAnd}, {ID: 2, Parent: 1, Position: 1}, {ID: 3, Parent: 1, Position: 1}}, "node 3: duplicate position 1 in node 1"},
+		{[]FlatNode{{ID: 1, Op: OperatorAnd}, {ID: 2, Parent: 3}, {ID: 3, Parent: 2}}, "2 nodes not reached from the root"},
+	} {
+		if _, err := Unflatten(tc.rows); err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("Unflatten(%v) expected=%s actual=%v\n", tc.rows, tc.expected, err)
+		}
+	}
+}
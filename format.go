@@ -0,0 +1,176 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// FormatOption configures how a tree is laid out by `Format`.
+type FormatOption func(*formatOptions)
+
+type formatOptions struct {
+	sort bool
+}
+
+// FormatSortChildren sorts the children of `and` and `or` nodes, as
+// `Canonicalize` does, so that versions of a rule which only reorder them
+// format the same.  Like `Canonicalize`, it assumes the children are free of
+// side effects.
+func FormatSortChildren() FormatOption {
+	return func(o *formatOptions) {
+		o.sort = true
+	}
+}
+
+// Format returns a copy of the tree laid out canonically, so that the diffs
+// between versions of a hand edited rule only show the changes which matter.
+// Operators are spelled in lower case, with their aliases such as `&&`
+// replaced, and leaves have single spaces between their words, none within
+// their parentheses and brackets, and a single pair of parentheses around
+// them, as made by `NewLeafNode`:
+//
+//	"((gt  .Age  18 ))"            => "(gt .Age 18)"
+//	"in .Country [ \"US\" ,\"CA\"]" => "(in .Country [\"US\", \"CA\"])"
+//
+// Unlike `Canonicalize`, leaves keep their macros and list literals and
+// children keep their order, unless `FormatSortChildren` is given.  Leaves
+// which do not parse and advanced leaves, whose text is their output, are
+// kept as they are.  The tree is not modified.
+func (n *Node) Format(opts ...FormatOption) *Node {
+	o := formatOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c, _ := n.format(&o)
+	return c
+}
+
+// format returns the formatted copy of the node and its encoding, by which
+// its parent sorts it.
+func (n *Node) format(o *formatOptions) (*Node, []byte) {
+	c := &Node{Op: n.Op, Leaf: n.Leaf}
+	if op := normalizeOperator(string(n.Op)); op != operatorNot {
+		c.Op = op
+	}
+	if c.Op == OperatorLeaf {
+		c.Leaf = formatLeaf(n.Leaf)
+	}
+
+	if len(n.Nodes) > 0 {
+		keys := make([][]byte, len(n.Nodes))
+		c.Nodes = make([]*Node, len(n.Nodes))
+		for i, child := range n.Nodes {
+			c.Nodes[i], keys[i] = child.format(o)
+		}
+		if o.sort && !keepsOrder(c.Op) {
+			sort.Stable(&byKey{c.Nodes, keys})
+		}
+	}
+
+	var bs []byte
+	if o.sort {
+		bs, _ = json.Marshal(c)
+	}
+	return c, bs
+}
+
+// formatLeaf returns the leaf expression `leaf` formatted as by `Format`, or
+// `leaf` itself if it does not parse, or would not parse the same once
+// formatted.
+func formatLeaf(leaf string) string {
+	if _, err := parseLeaf(leaf); err != nil {
+		return leaf
+	}
+
+	var b strings.Builder
+	var quote rune
+	space, escaped := false, false
+	for _, r := range leaf {
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\' && quote != '`':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+			b.WriteRune(r)
+			continue
+		}
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+
+		last := byte(0)
+		if b.Len() > 0 {
+			last = b.String()[b.Len()-1]
+		}
+		switch {
+		case r == ')' || r == ']' || r == ',':
+			space = false
+		case r == '|':
+			space = last != 0 && last != '(' && last != '['
+		case last == '(' || last == '[':
+			space = false
+		case last == ',' || last == '|':
+			space = true
+		}
+		if space && last != 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		if r == '"' || r == '`' || r == '\'' {
+			quote = r
+		}
+		b.WriteRune(r)
+	}
+
+	s := b.String()
+	for strings.HasPrefix(s, "(") && closingParen(s) == len(s)-1 {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	s = "(" + s + ")"
+
+	before, _ := normalizeLeaf(leaf, false)
+	if after, _ := normalizeLeaf(s, false); after != before {
+		return leaf
+	}
+	return s
+}
+
+// closingParen returns the offset of the parenthesis closing that which
+// opens `s`, or -1 if there is none.
+func closingParen(s string) int {
+	var quote rune
+	depth, escaped := 0, false
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\' && quote != '`':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+		case r == '"' || r == '`' || r == '\'':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
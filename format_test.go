@@ -0,0 +1,73 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestFormatLeaf(t *testing.T) {
+	for _, tc := range []struct {
+		leaf     string
+		expected string
+	}{
+		{"(gt .Age 18)", "(gt .Age 18)"},
+		{"gt .Age 18", "(gt .Age 18)"},
+		{"((gt  .Age\t18 ))", "(gt .Age 18)"},
+		{"( gt .Age ( len  .Items ) )", "(gt .Age (len .Items))"},
+		{"(gt .A 1) | not", "((gt .A 1) | not)"},
+		{".A|not", "(.A | not)"},
+		{`in .Country [ "US" ,"CA"]`, `(in .Country ["US", "CA"])`},
+		{`eq .Name "a  b" ` + "`c  d`", `(eq .Name "a  b" ` + "`c  d`)"},
+		{`eq .Name "say \"(  hi\"" '('`, `(eq .Name "say \"(  hi\"" '(')`},
+		{"gt .X (", "gt .X ("},
+		{"", ""},
+	} {
+		if actual := formatLeaf(tc.leaf); actual != tc.expected {
+			t.Errorf("formatLeaf(%q) expected=%q actual=%q\n", tc.leaf, tc.expected, actual)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	n := &Node{Op: "OR", Nodes: []*Node{
+		{Op: "AND", Nodes: []*Node{{Op: "LEAF", Leaf: "lt .B  2"}, {Op: OperatorLeaf, Leaf: "( gt .A 1 )"}}},
+		{Op: OperatorAdvanced, Leaf: "{{ if  .C }}true{{ end }}"},
+		{Op: "&&", Nodes: []*Node{{Op: OperatorLeaf, Leaf: "(.D)"}}},
+	}}
+	before := n.String()
+
+	for _, tc := range []struct {
+		opts     []FormatOption
+		expected string
+	}{
+		{nil, `{"Op":"or","Nodes":[{"Op":"and","Nodes":[{"Op":"leaf","Leaf":"(lt .B 2)"},{"Op":"leaf","Leaf":"(gt .A 1)"}]},{"Op":"advanced","Leaf":"{{ if  .C }}true{{ end }}"},{"Op":"and","Nodes":[{"Op":"leaf","Leaf":"(.D)"}]}]}`},
+		{[]FormatOption{FormatSortChildren()}, `{"Op":"or","Nodes":[{"Op":"advanced","Leaf":"{{ if  .C }}true{{ end }}"},{"Op":"and","Nodes":[{"Op":"leaf","Leaf":"(.D)"}]},{"Op":"and","Nodes":[{"Op":"leaf","Leaf":"(gt .A 1)"},{"Op":"leaf","Leaf":"(lt .B 2)"}]}]}`},
+	} {
+		bs, err := json.Marshal(n.Format(tc.opts...))
+		if err != nil {
+			t.Fatalf("Marshal() error: %s\n", err.Error())
+		}
+		if string(bs) != tc.expected {
+			t.Errorf("Format(%d) expected=%s actual=%s\n", len(tc.opts), tc.expected, bs)
+		}
+	}
+
+	// The tree is not modified, and sorting its children keeps its
+	// fingerprint.
+	if n.String() != before {
+		t.Errorf("Format() expected=%s actual=%s\n", before, n.String())
+	}
+	if n.Format().Fingerprint() != n.Format(FormatSortChildren()).Fingerprint() {
+		t.Errorf("Format() expected the same fingerprint\n")
+	}
+
+	// Formatting twice changes nothing.
+	f := n.Format()
+	if f.Format().String() != f.String() {
+		t.Errorf("Format() expected=%s actual=%s\n", f, f.Format())
+	}
+}
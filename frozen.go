@@ -0,0 +1,136 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// FrozenTree is an immutable tree, safe to read and evaluate from any number
+// of goroutines without locks while it is being edited: its edits return new
+// trees, which share the subtrees they left unchanged with the tree they were
+// made from, rather than copying the whole tree or changing it in place.
+// Its nodes are only reachable as copies, see `Node`, and as frozen subtrees.
+type FrozenTree struct {
+	root *Node
+}
+
+// Freeze returns the tree rooted at `n` as a `FrozenTree`, of a copy of the
+// tree, so that `n` may still be changed.
+func Freeze(n *Node) *FrozenTree {
+	return &FrozenTree{root: n.copy()}
+}
+
+// Node returns a copy of the tree, which may be changed.
+func (t *FrozenTree) Node() *Node {
+	return t.root.copy()
+}
+
+// Op returns the operator of the root.
+func (t *FrozenTree) Op() Operator {
+	return t.root.Op
+}
+
+// Leaf returns the leaf of the root, of a leaf or advanced leaf, and the
+// field or guard of a switch or case.
+func (t *FrozenTree) Leaf() string {
+	return t.root.Leaf
+}
+
+// Len returns the number of children of the root.
+func (t *FrozenTree) Len() int {
+	return len(t.root.Nodes)
+}
+
+// Child returns the `i`th child of the root, which must have one.
+func (t *FrozenTree) Child(i int) *FrozenTree {
+	return &FrozenTree{root: t.root.Nodes[i]}
+}
+
+// At returns the subtree at `path`, as `Node.At` does.
+func (t *FrozenTree) At(path string) (*FrozenTree, error) {
+	n, err := t.root.At(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FrozenTree{root: n}, nil
+}
+
+// Compile compiles the tree, as `Compile` does, without copying it.
+func (t *FrozenTree) Compile(opts ...Option) (*CompiledTree, error) {
+	return Compile(t.root, opts...)
+}
+
+// Fingerprint returns the fingerprint of the tree, see `Node.Fingerprint`.
+func (t *FrozenTree) Fingerprint() string {
+	return t.root.Fingerprint()
+}
+
+// String returns the tree in infix notation, see `Node.String`.
+func (t *FrozenTree) String() string {
+	return t.root.String()
+}
+
+// MarshalJSON encodes the tree as its root node.
+func (t *FrozenTree) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.root)
+}
+
+// Patch returns the tree patched by `ops`, as `Node.ApplyPatch` does,
+// sharing the subtrees the operations do not reach with `t`.  Only the nodes
+// on the paths to those edited are copied.
+func (t *FrozenTree) Patch(ops []PatchOp) (*FrozenTree, error) {
+	root := t.root
+	for i, op := range ops {
+		var err error
+		if root, err = applyPatchOp(root.spine(op.Path), op); err != nil {
+			return nil, fmt.Errorf("%w: op %d (%s %s): %v", ErrInvalidPatch, i, op.Op, op.Path, err)
+		}
+	}
+	if err := root.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: patched tree: %w", ErrInvalidPatch, err)
+	}
+	return &FrozenTree{root: root}, nil
+}
+
+// Replace returns the tree with the subtree at `path` replaced by a copy of
+// `n`, see `Patch`.
+func (t *FrozenTree) Replace(path string, n *Node) (*FrozenTree, error) {
+	return t.Patch([]PatchOp{{Op: PatchReplace, Path: path, Value: n}})
+}
+
+// Insert returns the tree with a copy of `n` inserted as the child at
+// `path`, or appended for a path ending in "/-", see `Patch`.
+func (t *FrozenTree) Insert(path string, n *Node) (*FrozenTree, error) {
+	return t.Patch([]PatchOp{{Op: PatchAdd, Path: path, Value: n}})
+}
+
+// Remove returns the tree without the subtree at `path`, see `Patch`.
+func (t *FrozenTree) Remove(path string) (*FrozenTree, error) {
+	return t.Patch([]PatchOp{{Op: PatchRemove, Path: path}})
+}
+
+// spine returns a copy of the tree in which the nodes on `path`, as far as
+// they exist, are copied, with children of their own, and every other node
+// is shared, so that a patch of `path` leaves the tree unchanged.
+func (n *Node) spine(path string) *Node {
+	idx, err := parsePath(strings.TrimSuffix(path, "/-"))
+	if err != nil {
+		idx = nil
+	}
+	root := &Node{Op: n.Op, Leaf: n.Leaf, Nodes: append([]*Node(nil), n.Nodes...)}
+	c := root
+	for _, i := range idx {
+		if i >= len(c.Nodes) {
+			break
+		}
+		child := c.Nodes[i]
+		c.Nodes[i] = &Node{Op: child.Op, Leaf: child.Leaf, Nodes: append([]*Node(nil), child.Nodes...)}
+		c = c.Nodes[i]
+	}
+	return root
+}
@@ -0,0 +1,104 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestFrozenTree(t *testing.T) {
+	n := NewNode(OperatorAnd,
+		NewNode(OperatorOr, NewLeafNode("eq .A 1"), NewLeafNode("eq .A 2")),
+		NewNode(OperatorOr, NewLeafNode("eq .B 1"), NewLeafNode("eq .B 2")))
+	ft := Freeze(n)
+	n.Nodes[0].Leaf = "(false)"
+	if ft.String() != "(.A == 1 OR .A == 2) AND (.B == 1 OR .B == 2)" {
+		t.Errorf("Freeze() expected a copy of its tree, got %s\n", ft)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		edit     func() (*FrozenTree, error)
+		expected string
+		shared   []string // the paths of the subtrees shared with `ft`
+	}{
+		{"Replace", func() (*FrozenTree, error) { return ft.Replace("/1/0", NewLeafNode("eq .B 3")) }, "(.A == 1 OR .A == 2) AND (.B == 3 OR .B == 2)", []string{"/0", "/1/1"}},
+		{"Insert", func() (*FrozenTree, error) { return ft.Insert("/0/-", NewLeafNode("eq .A 3")) }, "(.A == 1 OR .A == 2 OR .A == 3) AND (.B == 1 OR .B == 2)", []string{"/0/0", "/0/1", "/1"}},
+		{"Insert", func() (*FrozenTree, error) { return ft.Insert("/0", NewLeafNode("eq .C 1")) }, ".C == 1 AND (.A == 1 OR .A == 2) AND (.B == 1 OR .B == 2)", nil},
+		{"Remove", func() (*FrozenTree, error) { return ft.Remove("/0/1") }, ".A == 1 AND (.B == 1 OR .B == 2)", []string{"/0/0", "/1"}},
+		{"Replace", func() (*FrozenTree, error) { return ft.Replace("/", NewLeafNode("true")) }, "true", nil},
+	} {
+		edited, err := tc.edit()
+		if err != nil {
+			t.Fatalf("%s() error: %s\n", tc.name, err.Error())
+		}
+		if edited.String() != tc.expected {
+			t.Errorf("%s() expected=%s actual=%s\n", tc.name, tc.expected, edited)
+		}
+		for _, path := range tc.shared {
+			before, _ := ft.root.At(path)
+			if _, ok := edited.root.PathOf(before); !ok {
+				t.Errorf("%s() expected %s to be shared\n", tc.name, path)
+			}
+		}
+		if ft.String() != "(.A == 1 OR .A == 2) AND (.B == 1 OR .B == 2)" {
+			t.Errorf("%s() expected the tree to be unchanged, got %s\n", tc.name, ft)
+		}
+	}
+
+	// Edits which fail, including those leaving an invalid tree, leave the
+	// tree unchanged.
+	for _, path := range []string{"/2/0", "/0/0/0", "bad"} {
+		if _, err := ft.Replace(path, NewLeafNode("true")); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("Replace(%s) expected=%v actual=%v\n", path, ErrInvalidPatch, err)
+		}
+	}
+	if _, err := ft.Replace("/0", NewNode(OperatorAnd)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("Replace() expected=%v actual=%v\n", ErrInvalidPatch, err)
+	}
+	if ft.String() != "(.A == 1 OR .A == 2) AND (.B == 1 OR .B == 2)" {
+		t.Errorf("Replace() expected the tree to be unchanged, got %s\n", ft)
+	}
+
+	if ft.Op() != OperatorAnd || ft.Len() != 2 || ft.Child(1).Child(0).Leaf() != "(eq .B 1)" {
+		t.Errorf("FrozenTree expected its nodes, got %s %d %s\n", ft.Op(), ft.Len(), ft.Child(1).Child(0).Leaf())
+	}
+	if sub, err := ft.At("/1"); err != nil || sub.Fingerprint() != n.Nodes[1].Fingerprint() {
+		t.Errorf("At(/1) expected=%v actual=%v %v\n", n.Nodes[1], sub, err)
+	}
+	thawed := ft.Node()
+	thawed.Nodes[0] = NewLeafNode("false")
+	if ft.Child(0).Op() != OperatorOr {
+		t.Errorf("Node() expected a copy of the tree\n")
+	}
+
+	// Evaluations of the tree run while it is edited.
+	ct, err := ft.Compile()
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if v, err := ct.Evaluate(map[string]interface{}{"A": 1, "B": 2}); err != nil || !v {
+					t.Errorf("Evaluate() expected=true actual=%v %v\n", v, err)
+					return
+				}
+			}
+		}()
+	}
+	edited := ft
+	for j := 0; j < 100; j++ {
+		if edited, err = edited.Replace("/1/1", NewLeafNode("eq .B 3")); err != nil {
+			t.Fatalf("Replace() error: %s\n", err.Error())
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,389 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// StdFuncs returns a new `template.FuncMap` containing a standard set of
+// predicates for use in leaves:
+//
+//	eq, ne, lt, le, gt, ge  comparisons which work across int, uint and float
+//	between v lo hi         lo <= v <= hi
+//	in v collection         v is an element of a slice, a key of a map, or a
+//	                        substring of a string
+//	oneOf v a b ...         v is equal to any of a, b, ...
+//	list a b ...            a list of the arguments, also written [a, b, ...]
+//	anyOf c a b ...         collection c contains any of a, b, ...
+//	allOf c a b ...         collection c contains all of a, b, ...
+//	intersects c d          collections c and d share an element
+//	contains s sub          strings.Contains
+//	hasPrefix s prefix      strings.HasPrefix
+//	hasSuffix s suffix      strings.HasSuffix
+//	matches s pattern       s matches the regular expression pattern
+//	before a b              time a is before time b
+//	after a b               time a is after time b
+//	within t d              t is no older than duration d and not in the future
+//	olderThan t d           t is more than duration d in the past
+//	inWindow "09:00" "17:00" [t]
+//	                        the time of day of t (or now) is within the window,
+//	                        wrapping around midnight if start is after end
+//
+// A single list given to `anyOf` or `allOf` is expanded, so that
+// `anyOf .Tags ["a", "b"]` and `anyOf .Tags "a" "b"` are equivalent.  Times
+// may be `time.Time` values or RFC3339 strings and durations may be
+// `time.Duration` values or strings such as "24h".
+//
+// The comparisons replace the template builtins of the same name, and are
+// provided by `Compile` by default.  Numbers, including `json.Number`,
+// compare by value regardless of their Go type, strings compare
+// lexicographically and values of different non-numeric types are never
+// equal.
+func StdFuncs() template.FuncMap {
+	return template.FuncMap{
+		"eq":         stdEq,
+		"ne":         stdNe,
+		"lt":         stdLt,
+		"le":         stdLe,
+		"gt":         stdGt,
+		"ge":         stdGe,
+		"between":    stdBetween,
+		"in":         stdIn,
+		"oneOf":      stdOneOf,
+		"list":       stdList,
+		"anyOf":      stdAnyOf,
+		"allOf":      stdAllOf,
+		"intersects": stdIntersects,
+		"contains":   strings.Contains,
+		"hasPrefix":  strings.HasPrefix,
+		"hasSuffix":  strings.HasSuffix,
+		"matches":    stdMatches,
+		"before":     timeBefore,
+		"after":      timeAfter,
+		"within":     timeWithin,
+		"olderThan":  timeOlderThan,
+		"inWindow":   timeInWindow,
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+func stdEq(a interface{}, bs ...interface{}) (bool, error) {
+	if len(bs) == 0 {
+		return false, errors.New("eq: missing argument for comparison")
+	}
+	for _, b := range bs {
+		if equal(a, b) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func stdNe(a, b interface{}) bool {
+	return !equal(a, b)
+}
+
+func stdLt(a, b interface{}) (bool, error) {
+	c, err := compare(a, b)
+	return c < 0, err
+}
+
+func stdLe(a, b interface{}) (bool, error) {
+	c, err := compare(a, b)
+	return c <= 0, err
+}
+
+func stdGt(a, b interface{}) (bool, error) {
+	c, err := compare(a, b)
+	return c > 0, err
+}
+
+func stdGe(a, b interface{}) (bool, error) {
+	c, err := compare(a, b)
+	return c >= 0, err
+}
+
+func stdBetween(v, lo, hi interface{}) (bool, error) {
+	l, err := compare(v, lo)
+	if err != nil {
+		return false, err
+	}
+	h, err := compare(v, hi)
+	if err != nil {
+		return false, err
+	}
+	return l >= 0 && h <= 0, nil
+}
+
+func stdIn(v, collection interface{}) (bool, error) {
+	if s, ok := collection.(string); ok {
+		sub, ok := v.(string)
+		if !ok {
+			return false, fmt.Errorf("in: cannot search string for %T", v)
+		}
+		return strings.Contains(s, sub), nil
+	}
+
+	cv := reflect.ValueOf(collection)
+	switch cv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < cv.Len(); i++ {
+			if equal(v, cv.Index(i).Interface()) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		for _, k := range cv.MapKeys() {
+			if equal(v, k.Interface()) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Invalid:
+		return false, nil
+	}
+	return false, fmt.Errorf("in: cannot search %T", collection)
+}
+
+func stdOneOf(v interface{}, options ...interface{}) bool {
+	for _, o := range options {
+		if equal(v, o) {
+			return true
+		}
+	}
+	return false
+}
+
+func stdList(vs ...interface{}) []interface{} {
+	return vs
+}
+
+func stdAnyOf(collection interface{}, values ...interface{}) (bool, error) {
+	cs, vs, err := collectionValues("anyOf", collection, values)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range vs {
+		if containsValue(cs, v) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func stdAllOf(collection interface{}, values ...interface{}) (bool, error) {
+	cs, vs, err := collectionValues("allOf", collection, values)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range vs {
+		if !containsValue(cs, v) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func stdIntersects(a, b interface{}) (bool, error) {
+	as, err := toSlice(a)
+	if err != nil {
+		return false, fmt.Errorf("intersects: %w", err)
+	}
+	bs, err := toSlice(b)
+	if err != nil {
+		return false, fmt.Errorf("intersects: %w", err)
+	}
+	for _, v := range as {
+		if containsValue(bs, v) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// collectionValues converts the arguments of `anyOf` and `allOf`, expanding
+// `values` if it is a single list.
+func collectionValues(fn string, collection interface{}, values []interface{}) ([]interface{}, []interface{}, error) {
+	cs, err := toSlice(collection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", fn, err)
+	}
+	if len(values) == 1 {
+		if vs, err := toSlice(values[0]); err == nil {
+			values = vs
+		}
+	}
+	return cs, values, nil
+}
+
+// toSlice converts a slice or array (or nil) to a slice of its elements.
+func toSlice(v interface{}) ([]interface{}, error) {
+	if vs, ok := v.([]interface{}); ok {
+		return vs, nil
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		vs := make([]interface{}, rv.Len())
+		for i := range vs {
+			vs[i] = rv.Index(i).Interface()
+		}
+		return vs, nil
+	case reflect.Invalid:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("%T is not a collection", v)
+}
+
+func containsValue(vs []interface{}, v interface{}) bool {
+	for _, e := range vs {
+		if equal(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func stdMatches(s, pattern string) (bool, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(s), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// number is a numeric value normalized from any of Go's numeric kinds.
+type number struct {
+	kind reflect.Kind // reflect.Int, reflect.Uint or reflect.Float64
+	i    int64
+	u    uint64
+	f    float64
+}
+
+// toNumber normalizes `v` into a number, the second return is false if `v`
+// is not numeric.  A `json.Number` is an integer if it parses as an int64 and
+// is otherwise a float.
+func toNumber(v interface{}) (number, bool) {
+	if jn, ok := v.(json.Number); ok {
+		if i, err := jn.Int64(); err == nil {
+			return number{kind: reflect.Int, i: i}, true
+		}
+		if f, err := jn.Float64(); err == nil {
+			return number{kind: reflect.Float64, f: f}, true
+		}
+		return number{}, false
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number{kind: reflect.Int, i: rv.Int()}, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return number{kind: reflect.Uint, u: rv.Uint()}, true
+	case reflect.Float32, reflect.Float64:
+		return number{kind: reflect.Float64, f: rv.Float()}, true
+	}
+	return number{}, false
+}
+
+func (n number) float() float64 {
+	switch n.kind {
+	case reflect.Int:
+		return float64(n.i)
+	case reflect.Uint:
+		return float64(n.u)
+	}
+	return n.f
+}
+
+// compareNumbers returns -1, 0 or 1, or 2 if either value is NaN.  Integers
+// are compared exactly, anything involving a float is compared as float64.
+func compareNumbers(a, b number) int {
+	switch {
+	case a.kind == reflect.Int && b.kind == reflect.Int:
+		return compareOrdered(a.i, b.i)
+	case a.kind == reflect.Uint && b.kind == reflect.Uint:
+		return compareOrdered(a.u, b.u)
+	case a.kind == reflect.Int && b.kind == reflect.Uint:
+		if a.i < 0 {
+			return -1
+		}
+		return compareOrdered(uint64(a.i), b.u)
+	case a.kind == reflect.Uint && b.kind == reflect.Int:
+		return -compareNumbers(b, a)
+	}
+
+	af, bf := a.float(), b.float()
+	switch {
+	case math.IsNaN(af) || math.IsNaN(bf):
+		return 2 // unordered, never equal, less or greater
+	case af < bf:
+		return -1
+	case af > bf:
+		return 1
+	}
+	return 0
+}
+
+func compareOrdered[T int64 | uint64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compare orders two numbers or two strings.
+func compare(a, b interface{}) (int, error) {
+	an, aok := toNumber(a)
+	bn, bok := toNumber(b)
+	if aok && bok {
+		c := compareNumbers(an, bn)
+		if c == 2 {
+			return 0, errors.New("cannot order NaN")
+		}
+		return c, nil
+	}
+
+	as, aok := a.(string)
+	bs, bok := b.(string)
+	if aok && bok {
+		return compareOrdered(as, bs), nil
+	}
+	return 0, fmt.Errorf("incompatible types for comparison: %T and %T", a, b)
+}
+
+// equal reports whether two values are equal, comparing numbers by value.
+func equal(a, b interface{}) bool {
+	an, aok := toNumber(a)
+	bn, bok := toNumber(b)
+	if aok && bok {
+		return compareNumbers(an, bn) == 0
+	}
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	at := reflect.TypeOf(a)
+	if at != reflect.TypeOf(b) || !at.Comparable() {
+		return false
+	}
+	return a == b
+}
@@ -0,0 +1,165 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestStdFuncs(t *testing.T) {
+	data := map[string]interface{}{
+		"Price":   4.5,
+		"Count":   3,
+		"Big":     uint64(1 << 63),
+		"Name":    "logictree",
+		"Country": "CA",
+		"Tags":    []string{"a", "b"},
+		"Scores":  []interface{}{1.0, 2.0},
+		"Flag":    true,
+		"Num":     json.Number("42"),
+		"Frac":    json.Number("1.5"),
+	}
+
+	for _, tc := range []struct {
+		expr     string
+		expected bool
+	}{
+		{"gt .Price 4", true},
+		{"lt .Price 4", false},
+		{"eq .Count 3.0", true},
+		{"ne .Count 3", false},
+		{"ge .Count 3", true},
+		{"le .Price 4.5", true},
+		{"gt .Big .Count", true},
+		{"lt -1 .Big", true},
+		{`lt "abc" .Name`, true},
+		{"eq .Flag true", true},
+		{`eq .Name "x" "y" "logictree"`, true},
+		{`eq .Count "3"`, false},
+		{"between .Price 4 5", true},
+		{"between .Count 4 5", false},
+		{`in "b" .Tags`, true},
+		{`in "c" .Tags`, false},
+		{"in 2 .Scores", true},
+		{`in "tree" .Name`, true},
+		{`oneOf .Country "US" "CA" "MX"`, true},
+		{`oneOf .Country "US" "MX"`, false},
+		{`contains .Name "ict"`, true},
+		{`hasPrefix .Name "logic"`, true},
+		{`hasSuffix .Name "logic"`, false},
+		{`matches .Name "^l.*e$"`, true},
+		{"eq .Num 42", true},
+		{"gt .Num 41.5", true},
+		{"lt .Frac 2", true},
+		{"between .Frac 1 .Num", true},
+		{`eq .Num "42"`, false},
+	} {
+		ct, err := Compile(NewLeafNode(tc.expr), WithFuncs(StdFuncs()))
+		if err != nil {
+			t.Fatalf("Compile(%s) error: %s\n", tc.expr, err.Error())
+		}
+		v, err := ct.Evaluate(data)
+		if err != nil {
+			t.Errorf("Evaluate(%s) error: %s\n", tc.expr, err.Error())
+		}
+		if v != tc.expected {
+			t.Errorf("Evaluate(%s) expected=%v actual=%v\n", tc.expr, tc.expected, v)
+		}
+	}
+
+	for _, expr := range []string{
+		`gt .Name 4`,
+		`lt .Flag true`,
+		`in 1 .Count`,
+	} {
+		ct, err := Compile(NewLeafNode(expr), WithFuncs(StdFuncs()))
+		if err != nil {
+			t.Fatalf("Compile(%s) error: %s\n", expr, err.Error())
+		}
+		if _, err := ct.Evaluate(data); err == nil {
+			t.Errorf("Evaluate(%s) expected an error\n", expr)
+		}
+	}
+}
+
+func TestCollectionFuncs(t *testing.T) {
+	for _, tc := range []struct {
+		leaf     string
+		expected string
+	}{
+		{`in .Country ["US","CA","MX"]`, `in .Country (list "US" "CA" "MX")`},
+		{`eq .Name "[a, b]"`, `eq .Name "[a, b]"`},
+		{"eq .Name `[\"a\"]` [1, [2, 3]]", "eq .Name `[\"a\"]` (list 1  (list 2  3))"},
+		{`in "\"[" ['[']`, `in "\"[" (list '[')`},
+	} {
+		if s := leafSource(tc.leaf); s != tc.expected {
+			t.Errorf("leafSource(%s) expected=%s actual=%s\n", tc.leaf, tc.expected, s)
+		}
+	}
+
+	data := map[string]interface{}{
+		"Country": "CA",
+		"Tags":    []string{"a", "b", "c"},
+		"Ids":     []interface{}{1.0, 2.0},
+	}
+	for _, tc := range []struct {
+		expr     string
+		expected bool
+	}{
+		{`in .Country ["US", "CA", "MX"]`, true},
+		{`in .Country ["US", "MX"]`, false},
+		{`anyOf .Tags ["x", "b"]`, true},
+		{`anyOf .Tags "x" "y"`, false},
+		{`allOf .Tags ["a", "c"]`, true},
+		{`allOf .Tags "a" "z"`, false},
+		{`intersects .Ids [3, 2]`, true},
+		{`intersects .Tags .Ids`, false},
+		{`anyOf .Missing ["a"]`, false},
+	} {
+		ct, err := Compile(NewLeafNode(tc.expr), WithFuncs(StdFuncs()))
+		if err != nil {
+			t.Fatalf("Compile(%s) error: %s\n", tc.expr, err.Error())
+		}
+		v, err := ct.Evaluate(data)
+		if err != nil {
+			t.Errorf("Evaluate(%s) error: %s\n", tc.expr, err.Error())
+		}
+		if v != tc.expected {
+			t.Errorf("Evaluate(%s) expected=%v actual=%v\n", tc.expr, tc.expected, v)
+		}
+	}
+
+	ct, err := Compile(NewLeafNode(`eq (len [1, 2]) 2`))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if v, err := ct.Evaluate(nil); err != nil || !v {
+		t.Errorf("Evaluate() expected list literals without StdFuncs, got %v (%v)\n", v, err)
+	}
+
+	ct, err = Compile(NewLeafNode(`allOf .Country ["a"]`), WithFuncs(StdFuncs()))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if _, err := ct.Evaluate(data); err == nil {
+		t.Errorf("Evaluate() expected an error for a non-collection\n")
+	}
+}
+
+func TestDefaultComparisons(t *testing.T) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(`{"Milk": 5, "Onions": 2, "Toothpaste": 4}`), &data); err != nil {
+		t.Fatalf("json.Unmarshal() error: %s\n", err.Error())
+	}
+
+	ct, err := Compile(pricesTree())
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if v, err := ct.Evaluate(data); err != nil || !v {
+		t.Errorf("Evaluate() expected=true actual=%v (%v)\n", v, err)
+	}
+}
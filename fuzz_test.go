@@ -0,0 +1,126 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// fuzzData is the data the fuzzed trees are evaluated against.
+var fuzzData = map[string]interface{}{
+	"A":     1,
+	"Name":  "ab",
+	"Tags":  []interface{}{"x", "y"},
+	"Store": map[string]interface{}{"City": "SF"},
+}
+
+// fuzzTree exercises a tree which decoded or parsed: it must validate,
+// render, combine, compile and evaluate, or fail, without panicking.
+func fuzzTree(t *testing.T, n *Node) {
+	n.Validate()
+	_ = n.String()
+	_ = n.Sexpr()
+	n.Combine()
+	if ct, err := Compile(n, WithFuncs(StdFuncs())); err == nil {
+		ct.Evaluate(fuzzData)
+		ct.Explain(fuzzData)
+	}
+}
+
+// FuzzUnmarshal checks that any JSON document decodes or fails without
+// panicking, and that the trees it decodes to survive a round trip.
+func FuzzUnmarshal(f *testing.F) {
+	for _, src := range []string{
+		`{"Op": "leaf", "Leaf": "(true)"}`,
+		`{"Op": "and", "Nodes": [{"Op": "leaf", "Leaf": "(eq .A 1)"}, {"Op": "or", "Nodes": []}]}`,
+		`{"Version": 1, "Tree": {"op": "OR", "nodes": [{"Op": "advanced", "Leaf": "{{ true }}"}]}}`,
+		`{"Op": "switch", "Leaf": ".Name", "Nodes": [{"Op": "case", "Leaf": "\"ab\"", "Nodes": [{"Ref": "x"}]}]}`,
+		`{"Op": "if", "Nodes": [null, {"Op": "leaf"}]}`,
+		deepTree(10),
+	} {
+		f.Add(src)
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		var n Node
+		if err := json.Unmarshal([]byte(src), &n); err == nil {
+			fuzzTree(t, &n)
+		}
+		UnmarshalDocument([]byte(src))
+
+		sn, err := SafeUnmarshal([]byte(src))
+		if err != nil {
+			return
+		}
+		fuzzTree(t, sn)
+		bs, err := json.Marshal(sn)
+		if err != nil {
+			t.Fatalf("Marshal() error: %s\n", err.Error())
+		}
+		actual, err := SafeUnmarshal(bs)
+		if err != nil {
+			t.Fatalf("SafeUnmarshal(%s) error: %s\n", bs, err.Error())
+		}
+		if actualJS, _ := json.Marshal(actual); string(actualJS) != string(bs) {
+			t.Fatalf("SafeUnmarshal(Marshal(%s)) expected=%s actual=%s\n", src, bs, actualJS)
+		}
+	})
+}
+
+// FuzzParseSexpr checks that any text parses or fails without panicking, and
+// that the trees it parses to are written as S-expressions which parse back
+// to them.
+func FuzzParseSexpr(f *testing.F) {
+	for _, src := range []string{
+		"(or (and (ge .Milk 4) (le .Milk 6)) (gt .Toothpaste 5))",
+		"(switch `.Country` (case `\"US\"` (gt .Total 10)) (case .Member))",
+		`(leaf "eq .A \"(\"") ; comment`,
+		"(if (.A) (ref \"x\") (advanced `{{ true }}`))",
+		"(in .Tags [\"x\", \"y\"])",
+	} {
+		f.Add(src)
+	}
+
+	f.Fuzz(func(t *testing.T, src string) {
+		n, err := ParseSexpr(src)
+		if err != nil {
+			return
+		}
+		fuzzTree(t, n)
+		s := n.Sexpr()
+		actual, err := ParseSexpr(s)
+		if err != nil {
+			t.Fatalf("ParseSexpr(%q) error: %s\n", s, err.Error())
+		}
+		if actual.Sexpr() != s {
+			t.Fatalf("ParseSexpr(Sexpr(%q)) expected=%q actual=%q\n", src, s, actual.Sexpr())
+		}
+	})
+}
+
+// FuzzCombine checks that any leaf expression, alone and combined with
+// another, combines, compiles and evaluates or fails without panicking.
+func FuzzCombine(f *testing.F) {
+	for _, leaf := range []string{
+		"eq .A 1",
+		`in .Tags ["x", "y"]`,
+		`and (hasPrefix .Name "a") (not .Missing)`,
+		"between .A 0 (len .Tags)",
+		`{{ template "x" }}`,
+		"$x := 1",
+	} {
+		f.Add(leaf, false)
+	}
+
+	f.Fuzz(func(t *testing.T, leaf string, advanced bool) {
+		l := NewLeafNode(leaf)
+		if advanced {
+			l = NewAdvancedLeafNode(leaf)
+		}
+		fuzzTree(t, l)
+		fuzzTree(t, NewNode(OperatorOr, NewNode(OperatorAnd, l, NewLeafNode("eq .A 1")), l))
+	})
+}
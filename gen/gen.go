@@ -0,0 +1,191 @@
+// Package gen generates Go source evaluating logictree trees as plain
+// functions over a struct type, without templates or reflection, for rules
+// evaluated too often to pay for either.  The leaves of the trees must be
+// those accepted by `(*logictree.Node).ToGo`.
+//
+// The struct type is read with reflection, so it must be compiled into the
+// generator: a small program, run by `go generate`, which loads the trees and
+// writes the file returned by `Generate`:
+//
+//	//go:generate go run ./internal/genrules
+//
+//	src, err := gen.Generate(gen.Config{
+//		Package: "rules",
+//		Type:    reflect.TypeOf(&cart.Cart{}),
+//		Rules:   []gen.Rule{{Func: "IsCheap", Tree: tree}},
+//	})
+//	err = os.WriteFile("rules_gen.go", src, 0644)
+package gen
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Rule is a tree to generate a function for.
+type Rule struct {
+	// Func is the name of the function, which reports whether its argument
+	// satisfies the tree.
+	Func string
+	Tree *logictree.Node
+}
+
+// Config describes the file generated by `Generate`.
+type Config struct {
+	// Package is the name of the package the file belongs to.
+	Package string
+
+	// PkgPath is the import path of that package.  `Type` is imported unless
+	// it is declared in the package itself.
+	PkgPath string
+
+	// Type is the named struct type, or pointer to it, which the generated
+	// functions take.
+	Type reflect.Type
+
+	Rules []Rule
+}
+
+// Generate returns the formatted source of a file with a function for each
+// rule.  A rule with a leaf which cannot be generated fails with an error
+// wrapping `logictree.ErrNotTranslatable`, prefixed with the name of its
+// function, and an invalid config with one wrapping
+// `logictree.ErrInvalidConfig`.
+func Generate(c Config) ([]byte, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	named := c.Type
+	if named.Kind() == reflect.Ptr {
+		named = named.Elem()
+	}
+	typ, alias := named.Name(), ""
+	if named.PkgPath() != c.PkgPath {
+		alias = importName(named.PkgPath())
+		typ = alias + "." + typ
+	}
+	if c.Type.Kind() == reflect.Ptr {
+		typ = "*" + typ
+	}
+
+	funcs := &bytes.Buffer{}
+	usesStrings := false
+	for _, r := range c.Rules {
+		expr, err := r.Tree.ToGo(c.Type, "v")
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Func, err)
+		}
+		if usesPackage(expr, "strings") {
+			usesStrings = true
+		}
+
+		fmt.Fprintf(funcs, "\n// %s reports whether `v` satisfies the tree\n//\n", r.Func)
+		for _, line := range strings.Split(r.Tree.Infix(), "\n") {
+			fmt.Fprintf(funcs, "//\t%s\n", line)
+		}
+		fmt.Fprintf(funcs, "func %s(v %s) bool {\n\treturn %s\n}\n", r.Func, typ, expr)
+	}
+
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "// Code generated by logictree/gen. DO NOT EDIT.\n\npackage %s\n", c.Package)
+	// The standard library is imported apart from the package of the type.
+	imports := []string{}
+	if usesStrings {
+		imports = append(imports, `"strings"`)
+	}
+	if usesStrings && alias != "" {
+		imports = append(imports, "")
+	}
+	if alias != "" {
+		imports = append(imports, fmt.Sprintf("%s %q", alias, named.PkgPath()))
+	}
+	if len(imports) > 0 {
+		fmt.Fprintf(b, "\nimport (\n\t%s\n)\n", strings.Join(imports, "\n\t"))
+	}
+	b.Write(funcs.Bytes())
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid source: %w", err)
+	}
+	return src, nil
+}
+
+func (c Config) validate() error {
+	if !token.IsIdentifier(c.Package) {
+		return fmt.Errorf("%w: package name %q", logictree.ErrInvalidConfig, c.Package)
+	}
+	if c.Type == nil {
+		return fmt.Errorf("%w: no type", logictree.ErrInvalidConfig)
+	}
+	named := c.Type
+	if named.Kind() == reflect.Ptr {
+		named = named.Elem()
+	}
+	if named.Kind() != reflect.Struct || named.Name() == "" {
+		return fmt.Errorf("%w: %s is not a named struct", logictree.ErrInvalidConfig, c.Type)
+	}
+	if named.PkgPath() != c.PkgPath && !token.IsExported(named.Name()) {
+		return fmt.Errorf("%w: %s is not exported from %s", logictree.ErrInvalidConfig, named, named.PkgPath())
+	}
+	seen := map[string]bool{}
+	for _, r := range c.Rules {
+		if !token.IsIdentifier(r.Func) || seen[r.Func] {
+			return fmt.Errorf("%w: function name %q", logictree.ErrInvalidConfig, r.Func)
+		}
+		if r.Tree == nil {
+			return fmt.Errorf("%s: %w", r.Func, logictree.ErrEmptyNode)
+		}
+		seen[r.Func] = true
+	}
+	return nil
+}
+
+// importName returns the name under which the package at `pkgPath` is
+// imported, its last element made an identifier distinct from the names
+// the generated code uses.
+func importName(pkgPath string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, path.Base(pkgPath))
+	if !token.IsIdentifier(name) || name == "v" || name == "strings" {
+		name = "pkg" + name
+	}
+	return name
+}
+
+// usesPackage reports whether the expression `expr` refers to the package
+// `name`.
+func usesPackage(expr, name string) bool {
+	e, err := parser.ParseExpr(expr)
+	if err != nil {
+		return false
+	}
+	found := false
+	ast.Inspect(e, func(n ast.Node) bool {
+		if s, ok := n.(*ast.SelectorExpr); ok {
+			if id, ok := s.X.(*ast.Ident); ok && id.Name == name {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
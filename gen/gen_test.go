@@ -0,0 +1,187 @@
+package gen
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type Store struct {
+	City string
+}
+
+type Order struct {
+	Milk  int
+	Price float64
+	Name  string
+	Store *Store
+}
+
+const orderSource = `
+type Store struct {
+	City string
+}
+
+type Order struct {
+	Milk  int
+	Price float64
+	Name  string
+	Store *Store
+}
+`
+
+func orderRules() []Rule {
+	return []Rule{
+		{Func: "Cheap", Tree: logictree.NewNode(logictree.OperatorOr,
+			logictree.NewNode(logictree.OperatorAnd,
+				logictree.NewLeafNode("between .Milk 4 6"),
+				logictree.NewLeafNode(`in .Store.City ["SF", "LA"]`)),
+			logictree.NewLeafNode("lt .Price 2.5"))},
+		{Func: "Named", Tree: logictree.NewLeafNode(`not (hasPrefix .Name "x")`)},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := Generate(Config{
+		Package: "rules",
+		Type:    reflect.TypeOf(Order{}),
+		Rules:   orderRules(),
+	})
+	if err != nil {
+		t.Fatalf("Generate() error: %s\n", err.Error())
+	}
+	expected := `// Code generated by logictree/gen. DO NOT EDIT.
+
+package rules
+
+import (
+	"strings"
+
+	gen "github.com/sabhiram/logictree/gen"
+)
+
+// Cheap reports whether ` + "`v`" + ` satisfies the tree
+//
+//	(.Milk BETWEEN 4 AND 6 AND .Store.City in ["SF", "LA"]) OR .Price < 2.5
+func Cheap(v gen.Order) bool {
+	return ((int64(v.Milk) >= 4 && int64(v.Milk) <= 6) && (v.Store != nil && (v.Store.City == "SF" || v.Store.City == "LA"))) || v.Price < 2.5
+}
+
+// Named reports whether ` + "`v`" + ` satisfies the tree
+//
+//	NOT hasPrefix(.Name, "x")
+func Named(v gen.Order) bool {
+	return !(strings.HasPrefix(v.Name, "x"))
+}
+`
+	if string(src) != expected {
+		t.Errorf("Generate() expected=%s\nactual=%s\n", expected, src)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tree := logictree.NewLeafNode("gt .Milk 1")
+	for _, tc := range []struct {
+		c        Config
+		expected error
+	}{
+		{Config{Package: "rules", Type: reflect.TypeOf(0)}, logictree.ErrInvalidConfig},
+		{Config{Package: "rules"}, logictree.ErrInvalidConfig},
+		{Config{Package: "two words", Type: reflect.TypeOf(Order{})}, logictree.ErrInvalidConfig},
+		{Config{Package: "rules", Type: reflect.TypeOf(struct{ Milk int }{})}, logictree.ErrInvalidConfig},
+		{Config{Package: "rules", Type: reflect.TypeOf(Order{}), Rules: []Rule{{Func: "A", Tree: tree}, {Func: "A", Tree: tree}}}, logictree.ErrInvalidConfig},
+		{Config{Package: "rules", Type: reflect.TypeOf(Order{}), Rules: []Rule{{Func: "a-b", Tree: tree}}}, logictree.ErrInvalidConfig},
+		{Config{Package: "rules", Type: reflect.TypeOf(Order{}), Rules: []Rule{{Func: "A"}}}, logictree.ErrEmptyNode},
+		{Config{Package: "rules", Type: reflect.TypeOf(Order{}), Rules: []Rule{{Func: "A", Tree: logictree.NewLeafNode("gt .Eggs 1")}}}, logictree.ErrNotTranslatable},
+	} {
+		if _, err := Generate(tc.c); !errors.Is(err, tc.expected) {
+			t.Errorf("Generate(%+v) expected=%v actual=%v\n", tc.c, tc.expected, err)
+		}
+	}
+}
+
+// TestGenerateRun builds the generated functions and checks that they agree
+// with evaluating the trees.
+func TestGenerateRun(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil || testing.Short() {
+		t.Skip("needs the go command")
+	}
+
+	orders := []Order{
+		{Milk: 5, Price: 3, Store: &Store{City: "SF"}},
+		{Milk: 5, Price: 3, Store: &Store{City: "NY"}},
+		{Milk: 5, Price: 3},
+		{Milk: 7, Price: 2, Name: "xy"},
+		{Milk: 4, Price: 2.5, Name: "ab", Store: &Store{City: "LA"}},
+	}
+	rules := orderRules()
+	c := Config{
+		Package: "main",
+		PkgPath: reflect.TypeOf(Order{}).PkgPath(),
+		Type:    reflect.TypeOf(Order{}),
+		Rules:   rules,
+	}
+	src, err := Generate(c)
+	if err != nil {
+		t.Fatalf("Generate() error: %s\n", err.Error())
+	}
+
+	expected := &strings.Builder{}
+	main := &strings.Builder{}
+	fmt.Fprintf(main, "package main\n\nimport \"fmt\"\n%s\nfunc main() {\n\tvar o Order\n", orderSource)
+	for _, o := range orders {
+		fmt.Fprintf(main, "\to = Order{Milk: %d, Price: %v, Name: %q}\n", o.Milk, o.Price, o.Name)
+		if o.Store != nil {
+			fmt.Fprintf(main, "\to.Store = &Store{City: %q}\n", o.Store.City)
+		}
+		for _, r := range rules {
+			ct, err := logictree.Compile(r.Tree, logictree.WithFuncs(logictree.StdFuncs()))
+			if err != nil {
+				t.Fatalf("Compile(%s) error: %s\n", r.Func, err.Error())
+			}
+			v, err := ct.Evaluate(o)
+			if err != nil {
+				// Evaluating fails on the nil store, the generated code is
+				// false instead.
+				v = false
+			}
+			fmt.Fprintf(expected, "%v\n", v)
+			fmt.Fprintf(main, "\tfmt.Println(%s(o))\n", r.Func)
+		}
+	}
+	fmt.Fprintf(main, "}\n")
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":   "module gentest\n\ngo 1.21\n",
+		"main.go":  strings.ReplaceAll(main.String(), "gen.", ""),
+		"rules.go": string(src),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile() error: %s\n", err.Error())
+		}
+	}
+	cmd := exec.Command(gobin, "run", ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOFLAGS=", "GO111MODULE=on")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run error: %s\n%s\n", err.Error(), out)
+	}
+	if string(out) != expected.String() {
+		t.Errorf("go run expected=%s\nactual=%s\n", expected.String(), out)
+	}
+}
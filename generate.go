@@ -0,0 +1,390 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// DefaultGenerateAttempts is the number of documents `GenerateData` tries
+// when `GenerateOptions.MaxAttempts` is zero.
+const DefaultGenerateAttempts = 100
+
+// GenerateOptions configures `GenerateData`.
+type GenerateOptions struct {
+	// Rand is the source of the random choices, one seeded with the time if
+	// nil.  Sources with the same seed generate the same documents.
+	Rand *rand.Rand
+
+	// MaxAttempts bounds the documents tried before giving up.
+	MaxAttempts int
+}
+
+// GenerateData returns a random data document for which the tree evaluates
+// to `want`, for seeding test environments and fuzzing the systems the tree
+// makes decisions for.  The document is a map of the fields the leaves
+// compare, nested for fields such as `.Store.City`.
+//
+// Results are first chosen for the leaves, as `Satisfiable` does, at random
+// and such that the leaves comparing each field can have them together, and
+// a value is then found for each field.  Only the leaves comparing a field
+// with literals, as the comparisons of `StdFuncs` and `contains`,
+// `hasPrefix` and `hasSuffix` do, are given results: a tree whose result
+// depends on other leaves, or whose functions do not behave as those of
+// `StdFuncs`, may not be generated for.  Every document is evaluated before
+// it is returned, and GenerateData fails with an error wrapping
+// `ErrNoSolution` if none is found.
+func (ct *CompiledTree) GenerateData(want bool, o GenerateOptions) (map[string]interface{}, error) {
+	e, vars, err := ct.root.boolean(true)
+	if err != nil {
+		return nil, err
+	}
+	rng := o.Rand
+	if rng == nil {
+		rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	attempts := o.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultGenerateAttempts
+	}
+
+	g := &generator{
+		e:      e,
+		vals:   make([]Truth, len(vars)),
+		cons:   make([]*fieldConstraint, len(vars)),
+		fields: map[string][]int{},
+		rng:    rng,
+	}
+	advanced := map[int]bool{}
+	e.leaves(func(l *boolExpr) {
+		if l.variable >= 0 && l.op == OperatorAdvanced {
+			advanced[l.variable] = true
+		}
+	})
+	var order []int
+	for v, name := range vars {
+		if advanced[v] {
+			continue
+		}
+		if c := newFieldConstraint(name); c != nil {
+			g.cons[v] = c
+			g.fields[c.field] = append(g.fields[c.field], v)
+			order = append(order, v)
+		}
+	}
+
+	for i := 0; i < attempts; i++ {
+		for v := range g.vals {
+			g.vals[v] = Unknown
+		}
+		g.steps = 0
+		rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		ok, err := g.assign(order, 0, want)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: no results of the leaves make the tree %v", ErrNoSolution, want)
+		}
+		doc, ok := g.document()
+		if !ok {
+			continue
+		}
+		if v, err := ct.Evaluate(doc); err == nil && v == want {
+			return doc, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no document making the tree %v in %d attempts", ErrNoSolution, want, attempts)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// generator chooses the results of the leaves of a tree, and the values of
+// the fields they compare, for `GenerateData`.
+type generator struct {
+	e      *boolExpr
+	vals   []Truth
+	cons   []*fieldConstraint // by variable, nil for leaves which are not generated for
+	fields map[string][]int   // the variables comparing each field
+	rng    *rand.Rand
+	steps  int
+}
+
+// assign chooses results for the variables of `order` from `i` on, at
+// random, which make the expression `want` and which the fields they compare
+// can have together, leaving those which do not matter unknown.
+func (g *generator) assign(order []int, i int, want bool) (bool, error) {
+	if g.steps++; g.steps > maxSatisfiableSteps {
+		return false, fmt.Errorf("%w: more than %d steps choosing the results of leaves", ErrLimitExceeded, maxSatisfiableSteps)
+	}
+	switch g.e.eval3(g.vals) {
+	case truth(want):
+		return true, nil
+	case truth(!want):
+		return false, nil
+	}
+	if i == len(order) {
+		return false, nil
+	}
+
+	v := order[i]
+	first := g.rng.Intn(2) == 0
+	for _, r := range []bool{first, !first} {
+		g.vals[v] = truth(r)
+		if _, ok := g.solve(g.cons[v].field); !ok {
+			continue
+		}
+		if ok, err := g.assign(order, i+1, want); ok || err != nil {
+			return ok, err
+		}
+	}
+	g.vals[v] = Unknown
+	return false, nil
+}
+
+// solve returns a random value for `field` for which every leaf comparing it
+// has its chosen result, and for which none fail.
+func (g *generator) solve(field string) (interface{}, bool) {
+	var found []interface{}
+next:
+	for _, c := range g.candidates(field) {
+		for _, v := range g.fields[field] {
+			r, ok := g.cons[v].holds(c)
+			if !ok || g.vals[v] != Unknown && r != (g.vals[v] == True) {
+				continue next
+			}
+		}
+		found = append(found, c)
+	}
+	if len(found) == 0 {
+		return nil, false
+	}
+	return found[g.rng.Intn(len(found))], true
+}
+
+// document returns the data document of a value for every field, or reports
+// that a field is both compared and holds others.
+func (g *generator) document() (map[string]interface{}, bool) {
+	fields := make([]string, 0, len(g.fields))
+	for f := range g.fields {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+
+	doc := map[string]interface{}{}
+	for _, f := range fields {
+		v, ok := g.solve(f)
+		if !ok {
+			return nil, false
+		}
+		path := g.cons[g.fields[f][0]].path
+		m := doc
+		for _, name := range path[:len(path)-1] {
+			next, ok := m[name].(map[string]interface{})
+			if !ok {
+				if _, taken := m[name]; taken {
+					return nil, false
+				}
+				next = map[string]interface{}{}
+				m[name] = next
+			}
+			m = next
+		}
+		if _, taken := m[path[len(path)-1]]; taken {
+			return nil, false
+		}
+		m[path[len(path)-1]] = v
+	}
+	return doc, true
+}
+
+// candidates returns values for `field` among which there is one for every
+// combination of results of the leaves comparing it which they can have
+// together: the literals they compare it with, values between and around
+// them, and strings holding the texts matched.
+func (g *generator) candidates(field string) []interface{} {
+	var nums []float64
+	var strs, parts []string
+	var prefix, suffix string
+	floats, bools := false, false
+	for _, v := range g.fields[field] {
+		c := g.cons[v]
+		if m := c.match; m != nil {
+			strs = append(strs, m.text)
+			if g.vals[v] != True {
+				continue
+			}
+			switch {
+			case m.fn == "hasPrefix" && len(m.text) > len(prefix):
+				prefix = m.text
+			case m.fn == "hasSuffix" && len(m.text) > len(suffix):
+				suffix = m.text
+			case m.fn == "contains":
+				parts = append(parts, m.text)
+			}
+			continue
+		}
+		for _, lit := range c.cmp.values {
+			switch lit := lit.(type) {
+			case int64:
+				nums = append(nums, float64(lit))
+			case float64:
+				nums, floats = append(nums, lit), true
+			case string:
+				strs = append(strs, lit)
+			case bool:
+				bools = true
+			}
+		}
+	}
+
+	cs := []interface{}{}
+	if len(nums) > 0 {
+		cs = append(cs, g.numbers(nums, floats)...)
+	}
+	if len(strs) > 0 {
+		word := g.word()
+		cs = append(cs, "", word, prefix+strings.Join(parts, "")+suffix, prefix+strings.Join(parts, "")+word+suffix)
+		for _, s := range strs {
+			cs = append(cs, s, s+word, word+s)
+			if s != "" {
+				cs = append(cs, s[:len(s)-1])
+			}
+		}
+	}
+	if bools {
+		cs = append(cs, true, false)
+	}
+	return cs
+}
+
+// numbers returns each of `points`, a random number between each two of
+// them and numbers below and above them all.  Numbers are integers unless
+// `floats` is set or no integer lies between two points.
+func (g *generator) numbers(points []float64, floats bool) []interface{} {
+	sort.Float64s(points)
+	num := func(f float64) interface{} {
+		if !floats && f == math.Trunc(f) {
+			return int64(f)
+		}
+		return f
+	}
+
+	cs := []interface{}{num(points[0] - 1 - float64(g.rng.Intn(100)))}
+	for i, p := range points {
+		cs = append(cs, num(p))
+		if i == len(points)-1 || points[i+1] == p {
+			continue
+		}
+		next := points[i+1]
+		lo, hi := math.Floor(p)+1, math.Ceil(next)-1
+		switch {
+		case !floats && lo <= hi:
+			cs = append(cs, num(lo+float64(g.rng.Int63n(int64(hi-lo)+1))))
+		default:
+			cs = append(cs, p+(0.25+0.5*g.rng.Float64())*(next-p))
+		}
+	}
+	return append(cs, num(points[len(points)-1]+1+float64(g.rng.Intn(100))))
+}
+
+// word returns a random lowercase word.
+func (g *generator) word() string {
+	b := make([]byte, 1+g.rng.Intn(8))
+	for i := range b {
+		b[i] = byte('a' + g.rng.Intn(26))
+	}
+	return string(b)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// fieldConstraint is a leaf which compares a field with literals, whose
+// result `GenerateData` chooses.
+type fieldConstraint struct {
+	field string   // the path of the field, such as "Store.City"
+	path  []string // the names of the path
+	cmp   *comparison
+	match *stringMatch
+}
+
+// newFieldConstraint returns the constraint of the normalized leaf `name`, or
+// nil if it does not compare a field with literals.
+func newFieldConstraint(name string) *fieldConstraint {
+	t, err := parseLeaf(name)
+	if err != nil {
+		return nil
+	}
+	p, ok := leafPipe(t)
+	if !ok {
+		return nil
+	}
+	if m, ok := pipeStringMatch(p); ok && m.fn != "matches" {
+		return &fieldConstraint{field: strings.Join(m.field, "."), path: m.field, match: m}
+	}
+	if c, ok := pipeComparison(p); ok {
+		return &fieldConstraint{field: strings.Join(c.field, "."), path: c.field, cmp: c}
+	}
+	return nil
+}
+
+// holds returns the result of the leaf for the field value `v`, or reports
+// that the leaf fails for it, as the functions of `StdFuncs` do.
+func (fc *fieldConstraint) holds(v interface{}) (bool, bool) {
+	if m := fc.match; m != nil {
+		s, ok := v.(string)
+		if !ok {
+			return false, false
+		}
+		switch m.fn {
+		case "hasPrefix":
+			return strings.HasPrefix(s, m.text), true
+		case "hasSuffix":
+			return strings.HasSuffix(s, m.text), true
+		}
+		return strings.Contains(s, m.text), true
+	}
+
+	c := fc.cmp
+	switch c.op {
+	case "eq":
+		for _, lit := range c.values {
+			if equal(v, lit) {
+				return true, true
+			}
+		}
+		return false, true
+	case "ne":
+		return !equal(v, c.values[0]), true
+	case "between":
+		lo, err := compare(v, c.values[0])
+		if err != nil {
+			return false, false
+		}
+		hi, err := compare(v, c.values[1])
+		if err != nil {
+			return false, false
+		}
+		return lo >= 0 && hi <= 0, true
+	}
+	r, err := compare(v, c.values[0])
+	if err != nil {
+		return false, false
+	}
+	switch c.op {
+	case "lt":
+		return r < 0, true
+	case "le":
+		return r <= 0, true
+	case "gt":
+		return r > 0, true
+	}
+	return r >= 0, true
+}
@@ -0,0 +1,79 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestGenerateData(t *testing.T) {
+	for _, tc := range []struct {
+		tree *Node
+	}{
+		{NewLeafNode("ge .Age 18")},
+		{NewNode(OperatorAnd, NewLeafNode("gt .Amount 100"), NewLeafNode("lt .Amount 102"))},
+		{NewNode(OperatorAnd, NewLeafNode("gt .Price 2.5"), NewLeafNode("not (ge .Price 2.75)"))},
+		{NewNode(OperatorOr,
+			NewNode(OperatorAnd, NewLeafNode("between .Milk 4 6"), NewLeafNode(`in .Store.City ["SF", "LA"]`)),
+			NewLeafNode(`eq .Store.Open true`))},
+		{NewNode(OperatorAnd, NewLeafNode(`hasPrefix .Name "ab"`), NewLeafNode(`hasSuffix .Name "yz"`), NewLeafNode(`contains .Name "m"`), NewLeafNode(`ne .Name "abmyz"`))},
+		{NewNode(OperatorIf, NewLeafNode(`eq .Kind "a"`), NewLeafNode("gt .N 10"), NewLeafNode("lt .N -10"))},
+		{NewSwitchNode(".Kind", NewCaseNode(NewLeafNode("gt .N 1"), "a", "b"), NewCaseNode(NewLeafNode("false")))},
+	} {
+		ct, err := Compile(tc.tree, WithFuncs(StdFuncs()))
+		if err != nil {
+			t.Fatalf("Compile(%s) error: %s\n", tc.tree.Sexpr(), err.Error())
+		}
+		rng := rand.New(rand.NewSource(1))
+		for _, want := range []bool{true, false} {
+			for i := 0; i < 20; i++ {
+				doc, err := ct.GenerateData(want, GenerateOptions{Rand: rng})
+				if err != nil {
+					t.Errorf("GenerateData(%s, %v) error: %s\n", tc.tree.Sexpr(), want, err.Error())
+					break
+				}
+				if v, err := ct.Evaluate(doc); err != nil || v != want {
+					t.Errorf("GenerateData(%s, %v) expected=%v actual=%v %v for %v\n", tc.tree.Sexpr(), want, want, v, err, doc)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateDataSeeded(t *testing.T) {
+	ct, err := Compile(NewNode(OperatorAnd, NewLeafNode("ge .Age 18"), NewLeafNode(`hasPrefix .Name "a"`)), WithFuncs(StdFuncs()))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	a, errA := ct.GenerateData(true, GenerateOptions{Rand: rand.New(rand.NewSource(7))})
+	b, errB := ct.GenerateData(true, GenerateOptions{Rand: rand.New(rand.NewSource(7))})
+	if errA != nil || errB != nil || a["Age"] != b["Age"] || a["Name"] != b["Name"] {
+		t.Errorf("GenerateData() expected the same documents for the same seed, actual=%v %v\n", a, b)
+	}
+}
+
+func TestGenerateDataErrors(t *testing.T) {
+	for _, tc := range []struct {
+		tree *Node
+		want bool
+	}{
+		{NewNode(OperatorAnd, NewLeafNode("gt .A 5"), NewLeafNode("lt .A 3")), true},
+		{NewNode(OperatorOr, NewLeafNode("ge .A 5"), NewLeafNode("lt .A 5")), false},
+		{NewLeafNode("true"), false},
+		// Leaves which do not compare fields with literals are not generated.
+		{NewLeafNode("gt .A .B"), true},
+		{NewLeafNode(`matches .Name "^a"`), true},
+	} {
+		ct, err := Compile(tc.tree, WithFuncs(StdFuncs()))
+		if err != nil {
+			t.Fatalf("Compile(%s) error: %s\n", tc.tree.Sexpr(), err.Error())
+		}
+		if doc, err := ct.GenerateData(tc.want, GenerateOptions{Rand: rand.New(rand.NewSource(1))}); !errors.Is(err, ErrNoSolution) {
+			t.Errorf("GenerateData(%s, %v) expected=%v actual=%v %v\n", tc.tree.Sexpr(), tc.want, ErrNoSolution, err, doc)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ToGo converts the tree into a Go boolean expression over the variable `v`
+// of type `t`, a struct or a pointer to a struct, which evaluates the tree
+// without templates or reflection.  The gen package wraps such expressions
+// into generated functions.  Fields become selectors, such as `v.Order.Total`
+// for `.Order.Total`, and may be exported fields of structs, pointers to
+// structs or methods without arguments returning a single value.  `if` nodes
+// become `c && t || !(c) && e`, and switches such expressions testing each of
+// their cases in turn.
+//
+// Leaves must be one of the structured forms accepted by `ToSQL`, other than
+// `matches`, or a field of type bool, possibly negated with `not`.  The
+// functions are assumed to be those from `StdFuncs`, and comparisons are
+// checked against the types of the fields: numbers compare by value as they
+// do there, strings and bools compare only against literals of their type,
+// which values of named string and bool types are never equal to.  Other
+// leaves, and leaves which would always fail, such as ordering a bool, fail
+// with `ErrNotTranslatable`.
+//
+// Where evaluating the tree would fail, on a nil pointer or on ordering a
+// NaN, the leaf of the expression is false instead.  The expression uses the
+// package "strings" if the tree has `contains`, `hasPrefix` or `hasSuffix`
+// leaves.
+func (n *Node) ToGo(t reflect.Type, v string) (string, error) {
+	st := t
+	if st.Kind() == reflect.Ptr {
+		st = st.Elem()
+	}
+	if st.Kind() != reflect.Struct {
+		return "", fmt.Errorf("%w: %s is not a struct", ErrNotTranslatable, t)
+	}
+	if err := n.Validate(); err != nil {
+		return "", err
+	}
+
+	w := &goWriter{t: t, v: v}
+	return w.node(n, "/", false)
+}
+
+// goWriter translates the leaves of a tree, collecting the nil checks each
+// leaf needs.
+type goWriter struct {
+	t      reflect.Type
+	v      string
+	guards []string
+}
+
+// node translates the node, parenthesized if `nested` and it needs to be.
+func (w *goWriter) node(n *Node, path string, nested bool) (string, error) {
+	if n.Op == OperatorAdvanced {
+		return "", nodeError(path, n, fmt.Errorf("%w: advanced leaf", ErrNotTranslatable))
+	}
+	if isCustom(n.Op) {
+		return "", nodeError(path, n, fmt.Errorf("%w: operator %s", ErrNotTranslatable, n.Op))
+	}
+	if n.Op == OperatorLeaf {
+		t, err := parseLeaf(n.Leaf)
+		if err != nil {
+			return "", nodeError(path, n, err)
+		}
+		p, ok := leafPipe(t)
+		if !ok {
+			return "", nodeError(path, n, fmt.Errorf("%w: %s", ErrNotTranslatable, n.Leaf))
+		}
+		w.guards = nil
+		s, ok := w.pipe(p)
+		if !ok {
+			return "", nodeError(path, n, fmt.Errorf("%w: %s", ErrNotTranslatable, n.Leaf))
+		}
+		if len(w.guards) > 0 {
+			return "(" + strings.Join(append(w.guards, s), " && ") + ")", nil
+		}
+		return s, nil
+	}
+	if n.Op == OperatorSwitch {
+		return w.switchNode(n, path, nested)
+	}
+
+	op := " && "
+	if n.Op == OperatorOr {
+		op = " || "
+	}
+	parts := make([]string, len(n.Nodes))
+	for i, c := range n.Nodes {
+		s, err := w.node(c, childPath(path, i), true)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = s
+	}
+	s := strings.Join(parts, op)
+	if n.Op == OperatorIf {
+		s = parts[0] + " && " + parts[1] + " || !(" + parts[0] + ") && " + parts[2]
+	}
+	if nested && len(parts) > 1 {
+		s = "(" + s + ")"
+	}
+	return s, nil
+}
+
+// switchNode translates the switch `n` as the `if` nodes testing its cases in
+// turn, parenthesized if `nested`.
+func (w *goWriter) switchNode(n *Node, path string, nested bool) (string, error) {
+	s := "false"
+	for i, c := range n.Nodes {
+		if c.Leaf == "" {
+			var err error
+			if s, err = w.node(c.Nodes[0], childPath(childPath(path, i), 0), true); err != nil {
+				return "", err
+			}
+		}
+	}
+	for i := len(n.Nodes) - 1; i >= 0; i-- {
+		c, cp := n.Nodes[i], childPath(path, i)
+		if c.Leaf == "" {
+			continue
+		}
+		guard, err := w.node(caseGuard(n, c), cp, true)
+		if err != nil {
+			return "", err
+		}
+		branch, err := w.node(c.Nodes[0], childPath(cp, 0), true)
+		if err != nil {
+			return "", err
+		}
+		s = guard + " && " + branch + " || !(" + guard + ") && " + s
+		if i > 0 || nested {
+			s = "(" + s + ")"
+		}
+	}
+	return s, nil
+}
+
+// pipe returns the Go boolean expression of the pipeline `p` over the fields
+// of the value, or false if it has none: only constants, bool fields,
+// comparisons and string matches, and their negations, have one, over the
+// fields which the type has.
+func (w *goWriter) pipe(p *parse.PipeNode) (string, bool) {
+	if v, ok := pipeConstant(p); ok {
+		return strconv.FormatBool(v), true
+	}
+	if inner, ok := pipeNot(p); ok {
+		s, ok := w.pipe(inner)
+		return "!(" + s + ")", ok
+	}
+	if len(p.Decl) == 0 && len(p.Cmds) == 1 {
+		// A bool field, such as `.Active` or `not .Active`.
+		args := p.Cmds[0].Args
+		not := len(args) == 2 && calls(p.Cmds[0]) == "not"
+		if len(args) == 1 || not {
+			if f, ok := argField(args[len(args)-1]); ok {
+				x, t, ok := w.field(f)
+				if !ok || t != reflect.TypeOf(false) {
+					return "", false
+				}
+				if not {
+					return "!" + x, true
+				}
+				return x, true
+			}
+		}
+	}
+	if m, ok := pipeStringMatch(p); ok {
+		return w.stringMatch(m)
+	}
+	if c, ok := pipeComparison(p); ok {
+		return w.comparison(c)
+	}
+	return "", false
+}
+
+func (w *goWriter) stringMatch(m *stringMatch) (string, bool) {
+	fn := map[string]string{"contains": "Contains", "hasPrefix": "HasPrefix", "hasSuffix": "HasSuffix"}[m.fn]
+	x, t, ok := w.field(m.field)
+	if fn == "" || !ok || t.Kind() != reflect.String {
+		return "", false
+	}
+	if t != reflect.TypeOf("") {
+		x = "string(" + x + ")"
+	}
+	return "strings." + fn + "(" + x + ", " + strconv.Quote(m.text) + ")", true
+}
+
+func (w *goWriter) comparison(c *comparison) (string, bool) {
+	x, t, ok := w.field(c.field)
+	if !ok {
+		return "", false
+	}
+	switch c.op {
+	case "eq":
+		parts := make([]string, len(c.values))
+		for i, v := range c.values {
+			if parts[i], ok = goCompare("eq", x, t, v); !ok {
+				return "", false
+			}
+		}
+		if len(parts) == 1 {
+			return parts[0], true
+		}
+		return "(" + strings.Join(parts, " || ") + ")", true
+	case "between":
+		lo, ok := goCompare("ge", x, t, c.values[0])
+		if !ok {
+			return "", false
+		}
+		hi, ok := goCompare("le", x, t, c.values[1])
+		if !ok {
+			return "", false
+		}
+		return "(" + lo + " && " + hi + ")", true
+	}
+	return goCompare(c.op, x, t, c.values[0])
+}
+
+var goOperators = map[string]string{
+	"eq": "==", "ne": "!=", "lt": "<", "le": "<=", "gt": ">", "ge": ">=",
+}
+
+// goCompare compares the expression `x` of type `t` with the literal `v` as
+// the comparison `op` of `StdFuncs` does.
+func goCompare(op, x string, t reflect.Type, v interface{}) (string, bool) {
+	ordering := op != "eq" && op != "ne"
+	conv := func(to reflect.Type) string {
+		if t == to {
+			return x
+		}
+		return to.String() + "(" + x + ")"
+	}
+	constant := func(b bool) (string, bool) {
+		return strconv.FormatBool(b), true
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		switch v := v.(type) {
+		case float64:
+			return conv(reflect.TypeOf(v)) + " " + goOperators[op] + " " + strconv.FormatFloat(v, 'g', -1, 64), true
+		case int64:
+			switch {
+			case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+				return conv(reflect.TypeOf(float64(0))) + " " + goOperators[op] + " " + strconv.FormatInt(v, 10), true
+			case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64 && v < 0:
+				// Unsigned values are greater than every negative literal.
+				return constant(op == "ne" || op == "gt" || op == "ge")
+			case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+				return conv(reflect.TypeOf(uint64(0))) + " " + goOperators[op] + " " + strconv.FormatInt(v, 10), true
+			}
+			return conv(reflect.TypeOf(v)) + " " + goOperators[op] + " " + strconv.FormatInt(v, 10), true
+		}
+	case reflect.String:
+		if s, ok := v.(string); ok && t == reflect.TypeOf("") {
+			return x + " " + goOperators[op] + " " + strconv.Quote(s), true
+		}
+	case reflect.Bool:
+		if b, ok := v.(bool); ok && t == reflect.TypeOf(false) && !ordering {
+			if b == (op == "eq") {
+				return x, true
+			}
+			return "!" + x, true
+		}
+	}
+	// Values of different types are never equal and cannot be ordered.
+	if ordering {
+		return "", false
+	}
+	return constant(op == "ne")
+}
+
+// field returns the expression selecting the field `path` of the variable
+// and its type, adding the nil checks it needs to the guards of the leaf.
+// Fields are looked up as templates look them up, methods first.
+func (w *goWriter) field(path []string) (string, reflect.Type, bool) {
+	x, t := w.v, w.t
+	for _, name := range path {
+		if t.Kind() == reflect.Ptr {
+			w.guard(x + " != nil")
+		}
+		m, ok := t.MethodByName(name)
+		if !ok && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+			m, ok = t.MethodByName(name)
+		}
+		if ok {
+			if m.Type.NumIn() != 1 || m.Type.NumOut() != 1 {
+				return "", nil, false
+			}
+			x, t = x+"."+name+"()", m.Type.Out(0)
+			continue
+		}
+
+		if t.Kind() != reflect.Struct {
+			return "", nil, false
+		}
+		f, ok := t.FieldByName(name)
+		if !ok || !f.IsExported() || len(f.Index) > 1 && embedsPointer(t, f.Index) {
+			return "", nil, false
+		}
+		x, t = x+"."+name, f.Type
+	}
+	if t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface {
+		return "", nil, false
+	}
+	return x, t, true
+}
+
+func (w *goWriter) guard(g string) {
+	for _, h := range w.guards {
+		if h == g {
+			return
+		}
+	}
+	w.guards = append(w.guards, g)
+}
+
+// embedsPointer reports whether the promoted field at `index` of the struct
+// `t` is reached through an embedded pointer.
+func embedsPointer(t reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		t = t.Field(i).Type
+		if t.Kind() == reflect.Ptr {
+			return true
+		}
+	}
+	return false
+}
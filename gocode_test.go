@@ -0,0 +1,121 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type goStore struct {
+	City string
+}
+
+type goGrade string
+
+type goOrder struct {
+	Milk   int
+	Price  float64
+	Count  uint8
+	Name   string
+	Grade  goGrade
+	Fresh  bool
+	Store  *goStore
+	Tags   []string
+	Any    interface{}
+	Parcel struct{ Weight float32 }
+}
+
+func (o goOrder) Total() float64 {
+	return o.Price * float64(o.Milk)
+}
+
+func (o goOrder) Scale(f float64) float64 {
+	return o.Price * f
+}
+
+func TestToGo(t *testing.T) {
+	n := NewNode(OperatorOr,
+		NewNode(OperatorAnd,
+			NewLeafNode("between .Milk 4 6"),
+			NewLeafNode(`in .Store.City ["SF", "LA"]`)),
+		NewLeafNode("lt 5 .Price"),
+		NewLeafNode(`not (hasPrefix .Name "50%_off")`))
+	s, err := n.ToGo(reflect.TypeOf(&goOrder{}), "o")
+	if err != nil {
+		t.Fatalf("ToGo() error: %s\n", err.Error())
+	}
+	expected := `((o != nil && (int64(o.Milk) >= 4 && int64(o.Milk) <= 6)) && (o != nil && o.Store != nil && (o.Store.City == "SF" || o.Store.City == "LA"))) || (o != nil && o.Price > 5) || (o != nil && !(strings.HasPrefix(o.Name, "50%_off")))`
+	if s != expected {
+		t.Errorf("ToGo() expected=%s\nactual=%s\n", expected, s)
+	}
+}
+
+func TestToGoLeaves(t *testing.T) {
+	for _, tc := range []struct {
+		leaf     string
+		expected string
+	}{
+		{"eq .Milk 4", "int64(v.Milk) == 4"},
+		{"eq .Milk 4.5", "float64(v.Milk) == 4.5"},
+		{"ge .Price 1.5", "v.Price >= 1.5"},
+		{"gt .Count 3", "uint64(v.Count) > 3"},
+		{"gt .Count -3", "true"},
+		{"eq .Count -3", "false"},
+		{"lt .Parcel.Weight 2", "float64(v.Parcel.Weight) < 2"},
+		{"gt .Total 10", "v.Total() > 10"},
+		{`eq .Name "milk"`, `v.Name == "milk"`},
+		{`oneOf .Name "a" "b"`, `(v.Name == "a" || v.Name == "b")`},
+		{`ge .Name "m"`, `v.Name >= "m"`},
+		{"eq .Name 1", "false"},
+		{"ne .Name 1", "true"},
+		{`eq .Grade "A"`, "false"},
+		{"eq .Fresh true", "v.Fresh"},
+		{"ne .Fresh true", "!v.Fresh"},
+		{"eq .Fresh false", "!v.Fresh"},
+		{".Fresh", "v.Fresh"},
+		{"not .Fresh", "!v.Fresh"},
+		{`contains .Name "o"`, `strings.Contains(v.Name, "o")`},
+		{`hasSuffix .Grade "s"`, `strings.HasSuffix(string(v.Grade), "s")`},
+		{"true", "true"},
+		{"not (false)", "!(false)"},
+
+		{`matches .Name "^mi"`, ""},
+		{"gt .Name 1", ""},
+		{"lt .Fresh true", ""},
+		{".Milk", ""},
+		{"eq .Tags 1", "false"},
+		{"lt .Tags 1", ""},
+		{"eq .Any 1", ""},
+		{"eq .Store 1", ""},
+		{"eq .Missing 1", ""},
+		{"gt .Scale 1", ""},
+		{"gt (len .Tags) 1", ""},
+		{`eq .Sel"ect 1`, ""},
+	} {
+		s, err := NewLeafNode(tc.leaf).ToGo(reflect.TypeOf(goOrder{}), "v")
+		if tc.expected == "" {
+			if err == nil {
+				t.Errorf("ToGo(%s) expected an error actual=%s\n", tc.leaf, s)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ToGo(%s) error: %s\n", tc.leaf, err.Error())
+			continue
+		}
+		if s != tc.expected {
+			t.Errorf("ToGo(%s) expected=%s actual=%s\n", tc.leaf, tc.expected, s)
+		}
+	}
+
+	if _, err := NewAdvancedLeafNode("{{ true }}").ToGo(reflect.TypeOf(goOrder{}), "v"); !errors.Is(err, ErrNotTranslatable) {
+		t.Errorf("ToGo(advanced) expected=%v actual=%v\n", ErrNotTranslatable, err)
+	}
+	if _, err := NewLeafNode("true").ToGo(reflect.TypeOf(0), "v"); !errors.Is(err, ErrNotTranslatable) {
+		t.Errorf("ToGo(int) expected=%v actual=%v\n", ErrNotTranslatable, err)
+	}
+}
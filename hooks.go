@@ -0,0 +1,33 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+// EvalHooks are callbacks invoked as each node of a compiled tree is
+// evaluated by `Evaluate` and `EvaluateContext`, so that callers can log,
+// meter or audit decisions.  Any of the callbacks may be nil.  Nodes are
+// identified by their path, "/" for the root and "/0/1" for the second child
+// of its first child.
+//
+// Nodes skipped by short-circuiting, and nodes answered from the cache of a
+// tree compiled `WithIncremental`, are never started.  When compiled with
+// `WithParallelism` the callbacks are invoked concurrently, from the
+// goroutines evaluating each child, and must be safe for concurrent use.
+type EvalHooks struct {
+	// OnNodeStart is called before a node is evaluated.
+	OnNodeStart func(path string, n *Node)
+
+	// OnNodeEnd is called once a node has been evaluated, or has failed.
+	OnNodeEnd func(path string, n *Node, result bool, err error)
+
+	// OnLeafResult is called with the rendered output of a leaf and the
+	// result it was interpreted as, before `OnNodeEnd`.  The output is empty
+	// if the leaf was decided by `WithMissing` without being executed.
+	OnLeafResult func(path string, leaf string, output string, result bool, err error)
+}
+
+// WithHooks sets the callbacks invoked while the tree is evaluated.
+func WithHooks(h EvalHooks) Option {
+	return func(o *compileOptions) {
+		o.hooks = h
+	}
+}
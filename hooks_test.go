@@ -0,0 +1,114 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestHooks(t *testing.T) {
+	events := []string{}
+	ct, err := Compile(pricesTree(), WithHooks(EvalHooks{
+		OnNodeStart: func(path string, n *Node) {
+			events = append(events, "start "+path+" "+string(n.Op))
+		},
+		OnNodeEnd: func(path string, n *Node, result bool, err error) {
+			events = append(events, fmt.Sprintf("end %s %v %v", path, result, err))
+		},
+		OnLeafResult: func(path, leaf, output string, result bool, err error) {
+			events = append(events, fmt.Sprintf("leaf %s %s %q %v", path, leaf, output, result))
+		},
+	}))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	v, err := ct.Evaluate(&prices{5, 0, 8})
+	if err != nil || !v {
+		t.Fatalf("Evaluate() expected=true actual=%v err=%v\n", v, err)
+	}
+
+	expected := []string{
+		"start / or",
+		"start /0 and",
+		"start /0/0 and",
+		"start /0/0/0 leaf",
+		`leaf /0/0/0 (ge .Milk 4) "true" true`,
+		"end /0/0/0 true <nil>",
+		"start /0/0/1 leaf",
+		`leaf /0/0/1 (le .Milk 6) "true" true`,
+		"end /0/0/1 true <nil>",
+		"end /0/0 true <nil>",
+		"start /0/1 and",
+		"start /0/1/0 leaf",
+		`leaf /0/1/0 (ge .Onions 1) "false" false`,
+		"end /0/1/0 false <nil>",
+		"end /0/1 false <nil>",
+		"end /0 false <nil>",
+		"start /1 leaf",
+		`leaf /1 (gt .Toothpaste 5) "true" true`,
+		"end /1 true <nil>",
+		"end / true <nil>",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("hooks expected=%q\nactual=%q\n", expected, events)
+	}
+}
+
+func TestHooksParallel(t *testing.T) {
+	var mu sync.Mutex
+	started, ended, leaves := map[string]bool{}, map[string]bool{}, 0
+	ct, err := Compile(pricesTree(), WithParallelism(4), WithHooks(EvalHooks{
+		OnNodeStart: func(path string, n *Node) {
+			mu.Lock()
+			defer mu.Unlock()
+			started[path] = true
+		},
+		OnNodeEnd: func(path string, n *Node, result bool, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			ended[path] = true
+		},
+		OnLeafResult: func(path, leaf, output string, result bool, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			leaves++
+		},
+	}))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	if _, err := ct.Evaluate(&prices{5, 2, 4}); err != nil {
+		t.Fatalf("Evaluate() error: %s\n", err.Error())
+	}
+	if !reflect.DeepEqual(started, ended) || !started["/"] {
+		t.Errorf("hooks expected every started node to end, started=%v ended=%v\n", started, ended)
+	}
+	if leaves == 0 {
+		t.Errorf("hooks expected OnLeafResult to be called\n")
+	}
+}
+
+func TestHooksMissing(t *testing.T) {
+	outputs := []string{}
+	ct, err := Compile(NewLeafNode("eq .Cheese 1"), WithMissing(MissingIsError), WithHooks(EvalHooks{
+		OnLeafResult: func(path, leaf, output string, result bool, err error) {
+			outputs = append(outputs, fmt.Sprintf("%q %v", output, err != nil))
+		},
+	}))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if _, err := ct.Evaluate(map[string]interface{}{}); err == nil {
+		t.Errorf("Evaluate() expected a missing field error\n")
+	}
+	if expected := []string{`"" true`}; !reflect.DeepEqual(outputs, expected) {
+		t.Errorf("OnLeafResult expected=%q actual=%q\n", expected, outputs)
+	}
+}
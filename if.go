@@ -0,0 +1,50 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NewIfNode returns a node which evaluates to `then` if `cond` is true and to
+// `els` otherwise, such as "if the customer is premium, require a lower
+// threshold, else a higher one".  Only the chosen branch is evaluated.
+//
+// `if` nodes have exactly three children, the condition and the branches in
+// that order, which is significant: they are not sorted by `Canonicalize` or
+// reordered by `CostModel.Reorder`.  In JSON they are encoded as any other
+// node, with the operator "if".
+func NewIfNode(cond, then, els *Node) *Node {
+	return NewNode(OperatorIf, cond, then, els)
+}
+
+// keepsOrder reports whether the order of the children of `op` is
+// significant, as it is of registered operators, `if` nodes and switches.
+func keepsOrder(op Operator) bool {
+	return op == OperatorIf || isSwitchPart(op) || isCustom(op)
+}
+
+// evaluateIf evaluates the condition of an `if` node and then the branch it
+// chooses.
+func (cn *compiledNode) evaluateIf(st *evalState, data interface{}) (bool, error) {
+	v, err := cn.children[0].evaluate(st, data)
+	if err != nil {
+		return false, err
+	}
+	if v {
+		return cn.children[1].evaluate(st, data)
+	}
+	return cn.children[2].evaluate(st, data)
+}
+
+// ifTruth returns the three-valued result of an `if` node given those of its
+// condition and branches: an unknown condition leaves the result unknown
+// unless both branches agree.
+func ifTruth(cond, then, els Truth) Truth {
+	switch cond {
+	case True:
+		return then
+	case False:
+		return els
+	}
+	if then == els {
+		return then
+	}
+	return Unknown
+}
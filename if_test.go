@@ -0,0 +1,161 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// premium requires a lower amount of premium customers than of others.
+var premium = NewIfNode(
+	NewLeafNode(`eq .Tier "premium"`),
+	NewLeafNode("gt .Amount 100"),
+	NewLeafNode("gt .Amount 500"))
+
+func TestIfNode(t *testing.T) {
+	tmpl, err := premium.GetTemplate(nil)
+	if err != nil {
+		t.Fatalf("GetTemplate() error: %s\n", err.Error())
+	}
+	plain, err := Compile(premium)
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	parallel, err := Compile(premium, WithParallelism(4))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	rows := []map[string]interface{}{
+		{"Tier": "premium", "Amount": 200},
+		{"Tier": "premium", "Amount": 50},
+		{"Tier": "basic", "Amount": 200},
+		{"Tier": "basic", "Amount": 600},
+	}
+	expected := []bool{true, false, false, true}
+	cols := Columns{"Tier": {String: []string{"premium", "premium", "basic", "basic"}}, "Amount": {Int64: []int64{200, 50, 200, 600}}}
+	batch, err := plain.EvaluateBatch(cols)
+	if err != nil {
+		t.Fatalf("EvaluateBatch() error: %s\n", err.Error())
+	}
+	for i, data := range rows {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil || buf.String() != strconv.FormatBool(expected[i]) {
+			t.Errorf("Execute(%v) expected=%v actual=%s err=%v\n", data, expected[i], buf.String(), err)
+		}
+		for _, ct := range []*CompiledTree{plain, parallel} {
+			if v, err := ct.Evaluate(data); err != nil || v != expected[i] {
+				t.Errorf("Evaluate(%v) expected=%v actual=%v err=%v\n", data, expected[i], v, err)
+			}
+		}
+		if e, err := plain.Explain(data); err != nil || e.Result != expected[i] || len(e.Flips) == 0 {
+			t.Errorf("Explain(%v) expected=%v actual=%v err=%v\n", data, expected[i], e, err)
+		}
+		if batch.Get(i) != expected[i] {
+			t.Errorf("EvaluateBatch(%d) expected=%v actual=%v\n", i, expected[i], batch.Get(i))
+		}
+	}
+
+	// Only the branch taken is evaluated.
+	ct, err := Compile(NewIfNode(NewLeafNode(".A"), NewLeafNode("true"), NewLeafNode(`index .M "x"`)))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if v, err := ct.Evaluate(map[string]interface{}{"A": true}); err != nil || !v {
+		t.Errorf("Evaluate() expected=true actual=%v err=%v\n", v, err)
+	}
+
+	for _, tc := range []struct {
+		data     map[string]interface{}
+		expected Truth
+	}{
+		{map[string]interface{}{"Amount": 600}, True},
+		{map[string]interface{}{"Amount": 200}, Unknown},
+		{map[string]interface{}{"Tier": "basic"}, Unknown},
+		{map[string]interface{}{"Tier": "basic", "Amount": 200}, False},
+	} {
+		if res, err := plain.EvaluateKleene(tc.data); err != nil || res.Value != tc.expected {
+			t.Errorf("EvaluateKleene(%v) expected=%v actual=%v err=%v\n", tc.data, tc.expected, res.Value, err)
+		}
+	}
+
+	for _, n := range []*Node{
+		NewNode(OperatorIf, NewLeafNode(".A"), NewLeafNode(".B")),
+		NewNode(OperatorIf, NewLeafNode(".A"), NewLeafNode(".B"), NewLeafNode(".C"), NewLeafNode(".D")),
+	} {
+		if err := n.Validate(); !errors.Is(err, ErrInvalidOperator) {
+			t.Errorf("Validate(%v) expected=%v actual=%v\n", n, ErrInvalidOperator, err)
+		}
+		if _, err := n.Combine(); !errors.Is(err, ErrInvalidOperator) {
+			t.Errorf("Combine(%v) expected=%v actual=%v\n", n, ErrInvalidOperator, err)
+		}
+	}
+}
+
+func TestIfNodeTransforms(t *testing.T) {
+	// Encodings round trip.
+	bs, err := json.Marshal(premium)
+	if err != nil {
+		t.Fatalf("Marshal() error: %s\n", err.Error())
+	}
+	var decoded Node
+	if err := json.Unmarshal(bs, &decoded); err != nil || !reflect.DeepEqual(&decoded, premium) {
+		t.Errorf("Unmarshal(%s) expected=%v actual=%v err=%v\n", bs, premium, &decoded, err)
+	}
+	if parsed, err := ParseSexpr(premium.Sexpr()); err != nil || !reflect.DeepEqual(parsed, premium) {
+		t.Errorf("ParseSexpr(%s) expected=%v actual=%v err=%v\n", premium.Sexpr(), premium, parsed, err)
+	}
+	n := NewNode(OperatorAnd, NewLeafNode(".Open"), premium)
+	if s, expected := n.Infix(), `.Open AND (IF .Tier == "premium" THEN .Amount > 100 ELSE .Amount > 500)`; s != expected {
+		t.Errorf("Infix() expected=%s actual=%s\n", expected, s)
+	}
+
+	// Negations keep the condition.
+	var negated Node
+	src := `{"Op": "NOT", "Nodes": [{"Op": "IF", "Nodes": [{"Op": "leaf", "Leaf": "(.A)"}, {"Op": "leaf", "Leaf": "(.B)"}, {"Op": "leaf", "Leaf": "(.C)"}]}]}`
+	if err := json.Unmarshal([]byte(src), &negated); err != nil || negated.Infix() != "IF .A THEN NOT .B ELSE NOT .C" {
+		t.Errorf("Unmarshal(%s) expected=%s actual=%v err=%v\n", src, "IF .A THEN NOT .B ELSE NOT .C", &negated, err)
+	}
+
+	// The order of the children is kept.
+	if c := premium.Canonicalize(); !reflect.DeepEqual(c, premium) {
+		t.Errorf("Canonicalize() expected=%v actual=%v\n", premium, c)
+	}
+	r, err := CostModel{}.Reorder(premium)
+	if err != nil || !reflect.DeepEqual(r, premium) {
+		t.Errorf("Reorder() expected=%v actual=%v err=%v\n", premium, r, err)
+	}
+
+	for _, tc := range []struct {
+		known    map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"Tier": "premium"}, ".Amount > 100"},
+		{map[string]interface{}{"Amount": 200}, `IF .Tier == "premium" THEN true ELSE false`},
+		{map[string]interface{}{"Amount": 600}, "true"},
+	} {
+		r, err := premium.PartialEval(tc.known)
+		if err != nil || r.Infix() != tc.expected {
+			t.Errorf("PartialEval(%v) expected=%s actual=%v err=%v\n", tc.known, tc.expected, r, err)
+		}
+	}
+
+	if ok, _, err := NewNode(OperatorAnd, premium, NewLeafNode(`eq .Tier "premium"`), NewLeafNode("not (gt .Amount 100)")).Satisfiable(); err != nil || ok {
+		t.Errorf("Satisfiable() expected=false actual=%v err=%v\n", ok, err)
+	}
+
+	sql, args, err := premium.ToSQL(DialectPostgres)
+	if expected := `CASE WHEN "Tier" = $1 THEN "Amount" > $2 ELSE "Amount" > $3 END`; err != nil || sql != expected || len(args) != 3 {
+		t.Errorf("ToSQL() expected=%s actual=%s %v err=%v\n", expected, sql, args, err)
+	}
+	if _, err := premium.ToESQuery(); err != nil {
+		t.Errorf("ToESQuery() error: %s\n", err.Error())
+	}
+}
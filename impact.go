@@ -0,0 +1,114 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// RegistryChange is a change to the parts of a registry which its trees
+// share, see `Registry.Impact`.
+type RegistryChange struct {
+	// Trees are the new versions of registered trees by name, such as that
+	// of a subtree referenced by many rules, or nil for those deleted.
+	Trees map[string]*Node
+
+	// Macros are the new expressions of the macros of the policy of the
+	// registry by name, see `Policy`, or empty for those deleted.
+	Macros map[string]string
+}
+
+// RuleImpact is how a `RegistryChange` alters a tree of a registry, see
+// `Registry.Impact`.
+type RuleImpact struct {
+	Name string `json:"Name"`
+
+	// Comparison compares the results of the tree before the change, as A,
+	// with those after it, as B, over the corpus, see `Compare`.  It is nil
+	// if the tree fails to compile before or after the change, and Error is
+	// that error.
+	Comparison *Comparison `json:"Comparison,omitempty"`
+	Error      string      `json:"Error,omitempty"`
+}
+
+// Impact reports how `change` would alter the trees of the registry, before
+// it is made: for every registered tree whose compiled form it changes,
+// directly or through the trees and macros it uses, how often its results
+// over the records of `corpus` change, with the records for which they do.
+// Trees are compiled with `opts`, as for `Compile`, and reported sorted by
+// name, those the change deletes or adds being left out.  Trees whose
+// compiled form changes but whose results do not are reported with an
+// `Agreement` of 1.
+//
+// The registry is not changed.  Changes which cannot be made, such as
+// invalid macros, fail with an error wrapping `ErrInvalidConfig`.
+func (r *Registry) Impact(change RegistryChange, corpus []interface{}, opts ...Option) ([]RuleImpact, error) {
+	after, err := r.withChange(change)
+	if err != nil {
+		return nil, err
+	}
+
+	impacts := []RuleImpact{}
+	for _, name := range r.List() {
+		fp, ok := r.Fingerprint(name)
+		if next, found := after.Fingerprint(name); !ok || !found || next == fp {
+			continue
+		}
+
+		impact := RuleImpact{Name: name}
+		a, err := r.Compile(name, opts...)
+		if err != nil {
+			impact.Error = fmt.Sprintf("before: %v", err)
+			impacts = append(impacts, impact)
+			continue
+		}
+		b, err := after.Compile(name, opts...)
+		if err != nil {
+			impact.Error = fmt.Sprintf("after: %v", err)
+			impacts = append(impacts, impact)
+			continue
+		}
+		impact.Comparison = CompareCompiled(a, b, corpus)
+		impacts = append(impacts, impact)
+	}
+	return impacts, nil
+}
+
+// withChange returns a registry holding the trees and policy of `r` as they
+// would be after `change`, without its namespaces.
+func (r *Registry) withChange(change RegistryChange) (*Registry, error) {
+	after := r.clone()
+	trees, policy := after.trees, after.policy
+	for name, n := range change.Trees {
+		switch {
+		case name == "":
+			return nil, fmt.Errorf("%w: trees need a name", ErrInvalidConfig)
+		case n == nil:
+			delete(trees, name)
+		default:
+			trees[name] = n.copy()
+		}
+	}
+
+	if len(change.Macros) > 0 {
+		p := Policy{}
+		if policy != nil {
+			p = *policy
+		}
+		macros := p.macros()
+		for name, expr := range change.Macros {
+			if expr == "" {
+				delete(macros, name)
+			} else {
+				macros[name] = expr
+			}
+		}
+		p.Macros = macros
+		if err := after.SetPolicy(p); err != nil {
+			return nil, err
+		}
+	}
+	return after, nil
+}
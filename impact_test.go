@@ -0,0 +1,88 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestRegistryImpact(t *testing.T) {
+	r := &Registry{}
+	for name, n := range map[string]*Node{
+		"adult": NewLeafNode("ge .Age 18"),
+		"beer":  NewNode(OperatorAnd, NewRefNode("adult"), NewLeafNode(`eq .Country "US"`)),
+		"vote":  NewRefNode("adult"),
+		"gold":  NewLeafNode("isGold"),
+		"other": NewLeafNode("eq .X 1"),
+	} {
+		if err := r.Register(name, n); err != nil {
+			t.Fatalf("Register(%s) error: %s\n", name, err.Error())
+		}
+	}
+	if err := r.SetPolicy(Policy{Macros: map[string]string{"isGold": "gt .Spent 1000"}}); err != nil {
+		t.Fatalf("SetPolicy() error: %s\n", err.Error())
+	}
+	corpus := []interface{}{
+		map[string]interface{}{"Age": 17, "Country": "US", "Spent": 500, "X": 1},
+		map[string]interface{}{"Age": 19, "Country": "US", "Spent": 1500, "X": 1},
+		map[string]interface{}{"Age": 19, "Country": "CA", "Spent": 3000, "X": 1},
+		map[string]interface{}{"Age": 30, "Country": "US", "Spent": 0, "X": 2},
+	}
+
+	type outcome struct {
+		name    string
+		changed []int
+	}
+	for _, tc := range []struct {
+		change   RegistryChange
+		expected []outcome
+	}{
+		{RegistryChange{Trees: map[string]*Node{"adult": NewLeafNode("ge .Age 21")}}, []outcome{{"adult", []int{1, 2}}, {"beer", []int{1}}, {"vote", []int{1, 2}}}},
+		// Rewriting a tree without changing its results still reports it.
+		{RegistryChange{Trees: map[string]*Node{"adult": NewLeafNode("gt .Age 17")}}, []outcome{{"adult", nil}, {"beer", nil}, {"vote", nil}}},
+		{RegistryChange{Trees: map[string]*Node{"adult": NewLeafNode("ge .Age 18")}}, nil},
+		{RegistryChange{Macros: map[string]string{"isGold": "gt .Spent 2000"}}, []outcome{{"gold", []int{1}}}},
+		// New trees are not reported.
+		{RegistryChange{Trees: map[string]*Node{"new": NewLeafNode("true")}}, nil},
+	} {
+		impacts, err := r.Impact(tc.change, corpus)
+		if err != nil {
+			t.Fatalf("Impact(%v) error: %s\n", tc.change, err.Error())
+		}
+		var actual []outcome
+		for _, im := range impacts {
+			if im.Comparison == nil {
+				t.Fatalf("Impact(%v) expected a comparison of %s, got %s\n", tc.change, im.Name, im.Error)
+			}
+			o := outcome{name: im.Name}
+			for _, d := range im.Comparison.Divergences {
+				o.changed = append(o.changed, d.Index)
+			}
+			actual = append(actual, o)
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("Impact(%v) expected=%v actual=%v\n", tc.change, tc.expected, actual)
+		}
+	}
+
+	// Deleting a referenced tree breaks the trees referencing it.
+	impacts, err := r.Impact(RegistryChange{Trees: map[string]*Node{"adult": nil}}, corpus)
+	if err != nil {
+		t.Fatalf("Impact() error: %s\n", err.Error())
+	}
+	if len(impacts) != 2 || impacts[0].Name != "beer" || impacts[1].Name != "vote" || !strings.HasPrefix(impacts[0].Error, "after: ") {
+		t.Errorf("Impact() expected beer and vote to fail after the change, got %+v\n", impacts)
+	}
+
+	if _, err := r.Impact(RegistryChange{Macros: map[string]string{"loop": "not loop"}}, corpus); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Impact() expected=%v actual=%v\n", ErrInvalidConfig, err)
+	}
+	if n, _ := r.Get("adult"); n.Leaf != "(ge .Age 18)" {
+		t.Errorf("Impact() expected the registry unchanged, got %s\n", n)
+	}
+}
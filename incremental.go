@@ -0,0 +1,107 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// WithIncremental caches the result of every node between evaluations, for
+// trees which are repeatedly evaluated against a single long-lived data
+// object.  Once evaluated, a node keeps returning its cached result until
+// `Invalidate` is called with a field it depends on, so that after a small
+// update only the affected leaves and their ancestors are recomputed.
+//
+// Advanced leaves, leaves which reference no fields, such as
+// `inWindow "09:00" "17:00"`, and leaves which read the data other than
+// through fields, are never cached and neither are their ancestors.  Errors
+// are not cached.  Evaluations of an incremental tree are serialized.
+func WithIncremental() Option {
+	return func(o *compileOptions) {
+		o.incremental = true
+	}
+}
+
+// nodeCache is the cached result of a node of an incremental tree.
+type nodeCache struct {
+	valid bool
+	v     bool
+}
+
+// Invalidate discards the cached results of the leaves referencing any of
+// `fields`, and of their ancestors, after those fields of the data have
+// changed.  Fields are written as in leaves, with or without the leading dot,
+// so "Dairy.Milk" invalidates leaves referencing `.Dairy.Milk` or `.Dairy`
+// and "Dairy" invalidates every leaf referencing a field of `.Dairy`.  With no
+// fields every cached result is discarded.  Invalidate does nothing unless the
+// tree was compiled with `WithIncremental`.
+func (ct *CompiledTree) Invalidate(fields ...string) {
+	if !ct.opts.incremental {
+		return
+	}
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.eval.invalidate(fieldPaths(fields))
+}
+
+// fieldPaths splits fields written as in leaves, with or without the leading
+// dot, into their parts.
+func fieldPaths(fields []string) [][]string {
+	paths := make([][]string, len(fields))
+	for i, f := range fields {
+		paths[i] = strings.Split(strings.TrimPrefix(f, "."), ".")
+	}
+	return paths
+}
+
+// invalidate discards the cached results depending on `paths`, or every one
+// if `paths` is empty, and reports whether any were discarded below and
+// including the node.
+func (cn *compiledNode) invalidate(paths [][]string) bool {
+	dirty := len(paths) == 0
+	for _, f := range cn.fields {
+		for _, p := range paths {
+			dirty = dirty || overlaps(f, p)
+		}
+	}
+	for _, c := range cn.children {
+		if c.invalidate(paths) {
+			dirty = true
+		}
+	}
+	if dirty {
+		cn.cache = nodeCache{}
+	}
+	return dirty
+}
+
+// overlaps reports whether one of the field paths `a` and `b` is a prefix of
+// the other.
+func overlaps(a, b []string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// markVolatile marks the nodes whose results may change without any field
+// changing, and reports whether `cn` is one of them.
+func (cn *compiledNode) markVolatile() bool {
+	if cn.node.isLeaf() {
+		cn.volatile = len(cn.fields) == 0 || cn.opaque
+	}
+	for _, c := range cn.children {
+		if c.markVolatile() {
+			cn.volatile = true
+		}
+	}
+	return cn.volatile
+}
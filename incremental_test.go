@@ -0,0 +1,119 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestIncremental(t *testing.T) {
+	calls := map[string]int{}
+	count := func(name string, v interface{}) interface{} {
+		calls[name]++
+		return v
+	}
+	n := NewNode(OperatorAnd,
+		NewLeafNode(`ge (count "milk" .Dairy.Milk) 4`),
+		NewNode(OperatorOr,
+			NewLeafNode(`gt (count "onions" .Onions) 1`),
+			NewLeafNode(`gt (count "toothpaste" .Toothpaste) 5`)))
+	ct, err := Compile(n, WithIncremental(), WithFuncs(template.FuncMap{"count": count}))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	data := map[string]interface{}{
+		"Dairy":      map[string]interface{}{"Milk": 5},
+		"Onions":     0,
+		"Toothpaste": 8,
+	}
+	for _, tc := range []struct {
+		update     func()
+		invalidate []string
+		expected   bool
+		calls      map[string]int
+	}{
+		{func() {}, nil, true, map[string]int{"milk": 1, "onions": 1, "toothpaste": 1}},
+		{func() {}, nil, true, map[string]int{"milk": 1, "onions": 1, "toothpaste": 1}},
+		{func() { data["Toothpaste"] = 1 }, []string{"Toothpaste"}, false, map[string]int{"milk": 1, "onions": 1, "toothpaste": 2}},
+		{func() { data["Onions"] = 2 }, []string{".Onions"}, true, map[string]int{"milk": 1, "onions": 2, "toothpaste": 2}},
+		{func() { data["Dairy"] = map[string]interface{}{"Milk": 1} }, []string{"Dairy"}, false, map[string]int{"milk": 2, "onions": 2, "toothpaste": 2}},
+		{func() { data["Dairy"].(map[string]interface{})["Milk"] = 6 }, []string{"Dairy.Milk"}, true, map[string]int{"milk": 3, "onions": 2, "toothpaste": 2}},
+		{func() {}, []string{"Cheese"}, true, map[string]int{"milk": 3, "onions": 2, "toothpaste": 2}},
+		{func() {}, []string{}, true, map[string]int{"milk": 4, "onions": 3, "toothpaste": 2}},
+	} {
+		tc.update()
+		if tc.invalidate != nil {
+			ct.Invalidate(tc.invalidate...)
+		}
+		v, err := ct.Evaluate(data)
+		if err != nil {
+			t.Fatalf("Evaluate() error: %s\n", err.Error())
+		}
+		if v != tc.expected {
+			t.Errorf("Evaluate() after Invalidate(%v) expected=%v actual=%v\n", tc.invalidate, tc.expected, v)
+		}
+		for k, c := range tc.calls {
+			if calls[k] != c {
+				t.Errorf("Evaluate() after Invalidate(%v) expected %s calls=%d actual=%d\n", tc.invalidate, k, c, calls[k])
+			}
+		}
+	}
+}
+
+func TestIncrementalVolatile(t *testing.T) {
+	calls := 0
+	ct, err := Compile(NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("tick")),
+		WithIncremental(),
+		WithFuncs(template.FuncMap{"tick": func() bool { calls++; return true }}))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := ct.Evaluate(map[string]interface{}{"A": 1}); err != nil {
+			t.Fatalf("Evaluate() error: %s\n", err.Error())
+		}
+	}
+	if calls != 3 {
+		t.Errorf("Evaluate() expected a leaf without fields to run every time, calls=%d\n", calls)
+	}
+
+	calls = 0
+	ct, err = Compile(NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewAdvancedLeafNode("{{ tick }}{{ eq .B 1 }}")),
+		WithIncremental(),
+		WithFuncs(template.FuncMap{"tick": func() string { calls++; return "" }}))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	for i := 0; i < 3; i++ {
+		ct.Invalidate("C")
+		if _, err := ct.Evaluate(map[string]interface{}{"A": 1, "B": 1}); err != nil {
+			t.Fatalf("Evaluate() error: %s\n", err.Error())
+		}
+	}
+	if calls != 3 {
+		t.Errorf("Evaluate() expected an advanced leaf to run every time, calls=%d\n", calls)
+	}
+}
+
+func TestInvalidateNotIncremental(t *testing.T) {
+	ct, err := Compile(pricesTree())
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	ct.Invalidate("Milk")
+	for _, tc := range []struct {
+		p        prices
+		expected bool
+	}{
+		{prices{5, 2, 4}, true},
+		{prices{5, 0, 4}, false},
+	} {
+		if v, err := ct.Evaluate(&tc.p); err != nil || v != tc.expected {
+			t.Errorf("Evaluate(%#v) expected=%v actual=%v err=%v\n", tc.p, tc.expected, v, err)
+		}
+	}
+}
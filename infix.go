@@ -0,0 +1,220 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// String returns the tree as an infix expression, see `Infix`.
+func (n *Node) String() string {
+	return n.Infix()
+}
+
+// Infix renders the tree as a readable infix expression, such as
+// `(.Milk >= 4 AND .Milk <= 6) OR .Toothpaste > 5`, for showing rules to
+// people who do not read templates.  It is meant for display only and is not
+// parsed back.
+//
+// `and`, `or` and `not`, whether nodes of the tree or functions called by
+// leaves, become AND, OR and NOT, `if` nodes IF ... THEN ... ELSE and
+// switches CASE ... WHEN ... THEN ... ELSE ... END.  The comparisons `eq`, `ne`, `lt`, `le`,
+// `gt` and `ge` become ==, !=, <, <=, > and >=, `eq` with several values,
+// `oneOf` and `in` become `in [...]` and `between` becomes BETWEEN ... AND.
+// Any other function is written as a call, `hasPrefix(.Name, "o")`, as are
+// registered operators, with the payload of a `PayloadOperator` in brackets,
+// `atLeast[{"k":2}](...)`.  Advanced
+// leaves, and leaves which do not parse, are written as they are.
+func (n *Node) Infix() string {
+	s, _ := n.infix()
+	return s
+}
+
+// Precedences of the infix forms, parenthesized when used as the operands of
+// a form of equal or lower precedence.
+const (
+	precAtom = iota // fields, literals and calls
+	precCompare
+	precNot
+	precJunction // AND and OR
+	precIf       // IF ... THEN ... ELSE
+)
+
+func (n *Node) infix() (string, int) {
+	switch n.Op {
+	case OperatorLeaf:
+		return leafInfix(n.Leaf)
+	case OperatorAnd, OperatorOr:
+		switch len(n.Nodes) {
+		case 0:
+			return string(n.Op) + "()", precAtom
+		case 1:
+			return n.Nodes[0].infix()
+		}
+		parts := make([]string, len(n.Nodes))
+		for i, c := range n.Nodes {
+			parts[i] = wrapInfix(c.infix())
+		}
+		return strings.Join(parts, " "+strings.ToUpper(string(n.Op))+" "), precJunction
+	case OperatorIf:
+		if len(n.Nodes) == 3 {
+			cond, prec := n.Nodes[0].infix()
+			if prec >= precIf {
+				cond = "(" + cond + ")"
+			}
+			then, prec := n.Nodes[1].infix()
+			if prec >= precIf {
+				then = "(" + then + ")"
+			}
+			els, _ := n.Nodes[2].infix()
+			return "IF " + cond + " THEN " + then + " ELSE " + els, precIf
+		}
+	case OperatorSwitch:
+		return n.switchInfix(), precAtom
+	case OperatorRef:
+		return "ref(" + strconv.Quote(n.Leaf) + ")", precAtom
+	}
+	if keepsOrder(n.Op) {
+		parts := make([]string, len(n.Nodes))
+		for i, c := range n.Nodes {
+			parts[i], _ = c.infix()
+		}
+		if _, ok := payloadOperator(n.Op); ok && n.Leaf != "" {
+			return string(n.Op) + "[" + n.Leaf + "](" + strings.Join(parts, ", ") + ")", precAtom
+		}
+		return string(n.Op) + "(" + strings.Join(parts, ", ") + ")", precAtom
+	}
+	return n.Leaf, precAtom
+}
+
+// switchInfix renders a switch as CASE ... WHEN ... THEN ... ELSE ... END.
+func (n *Node) switchInfix() string {
+	var sb strings.Builder
+	sb.WriteString("CASE " + n.Leaf)
+	els := ""
+	for _, c := range n.Nodes {
+		s := "false"
+		if len(c.Nodes) == 1 {
+			var prec int
+			if s, prec = c.Nodes[0].infix(); prec >= precIf {
+				s = "(" + s + ")"
+			}
+		}
+		if c.Leaf == "" {
+			els = " ELSE " + s
+			continue
+		}
+		values := strings.Fields(c.Leaf)
+		if vs, err := caseValues(n, c); err == nil {
+			values = make([]string, len(vs))
+			for i, v := range vs {
+				values[i] = formatLiteral(v)
+			}
+		}
+		sb.WriteString(" WHEN " + strings.Join(values, ", ") + " THEN " + s)
+	}
+	sb.WriteString(els + " END")
+	return sb.String()
+}
+
+// wrapInfix parenthesizes the operand `s` of AND or OR if it is itself one.
+func wrapInfix(s string, prec int) string {
+	if prec >= precJunction {
+		return "(" + s + ")"
+	}
+	return s
+}
+
+// leafInfix renders a leaf expression, or returns it with its whitespace
+// collapsed if it does not parse.
+func leafInfix(leaf string) (string, int) {
+	t, err := parseLeaf(leaf)
+	if err == nil {
+		if p, ok := leafPipe(t); ok {
+			if s, prec, ok := pipeInfix(p); ok {
+				return s, prec
+			}
+		}
+	}
+	return strings.Join(strings.Fields(leaf), " "), precAtom
+}
+
+var infixOperators = map[string]string{
+	"eq": "==", "ne": "!=", "lt": "<", "le": "<=", "gt": ">", "ge": ">=",
+}
+
+// pipeInfix renders a pipeline consisting of a single command.
+func pipeInfix(p *parse.PipeNode) (string, int, bool) {
+	if len(p.Decl) != 0 || len(p.Cmds) != 1 {
+		return "", 0, false
+	}
+	args := p.Cmds[0].Args
+	id, ok := args[0].(*parse.IdentifierNode)
+	if !ok || len(args) == 1 {
+		if len(args) != 1 {
+			return "", 0, false
+		}
+		s, prec, ok := argInfix(args[0])
+		return s, prec, ok
+	}
+
+	ops := make([]string, len(args)-1)
+	precs := make([]int, len(args)-1)
+	for i, a := range args[1:] {
+		if ops[i], precs[i], ok = argInfix(a); !ok {
+			return "", 0, false
+		}
+	}
+	// operand returns operand `i`, parenthesized if its precedence is at
+	// least `prec`.
+	operand := func(i, prec int) string {
+		if precs[i] >= prec {
+			return "(" + ops[i] + ")"
+		}
+		return ops[i]
+	}
+
+	switch fn := id.Ident; {
+	case (fn == "and" || fn == "or") && len(ops) > 1:
+		parts := make([]string, len(ops))
+		for i := range ops {
+			parts[i] = operand(i, precJunction)
+		}
+		return strings.Join(parts, " "+strings.ToUpper(fn)+" "), precJunction, true
+	case fn == "not" && len(ops) == 1:
+		return "NOT " + operand(0, precCompare), precNot, true
+	case infixOperators[fn] != "" && len(ops) == 2:
+		return operand(0, precCompare) + " " + infixOperators[fn] + " " + operand(1, precCompare), precCompare, true
+	case (fn == "eq" || fn == "oneOf") && len(ops) > 2:
+		return operand(0, precCompare) + " in [" + strings.Join(ops[1:], ", ") + "]", precCompare, true
+	case fn == "in" && len(ops) == 2:
+		return operand(0, precCompare) + " in " + operand(1, precCompare), precCompare, true
+	case fn == "between" && len(ops) == 3:
+		return operand(0, precCompare) + " BETWEEN " + operand(1, precCompare) + " AND " + operand(2, precCompare), precCompare, true
+	case fn == "list":
+		return "[" + strings.Join(ops, ", ") + "]", precAtom, true
+	}
+	return id.Ident + "(" + strings.Join(ops, ", ") + ")", precAtom, true
+}
+
+// argInfix renders an argument of a command.
+func argInfix(n parse.Node) (string, int, bool) {
+	switch a := n.(type) {
+	case *parse.PipeNode:
+		return pipeInfix(unwrapPipe(a))
+	case *parse.IdentifierNode:
+		if a.Ident == "list" {
+			return "[]", precAtom, true
+		}
+		return a.Ident + "()", precAtom, true
+	case *parse.VariableNode:
+		if len(a.Ident) > 1 && a.Ident[0] == "$" {
+			return "." + strings.Join(a.Ident[1:], "."), precAtom, true
+		}
+	}
+	return n.String(), precAtom, true
+}
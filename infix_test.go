@@ -0,0 +1,46 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestInfix(t *testing.T) {
+	for _, tc := range []struct {
+		n        *Node
+		expected string
+	}{
+		{NewNode(OperatorOr,
+			NewNode(OperatorAnd, NewLeafNode("ge .Milk 4"), NewLeafNode("le .Milk 6")),
+			NewLeafNode("gt .Toothpaste 5")),
+			"(.Milk >= 4 AND .Milk <= 6) OR .Toothpaste > 5"},
+		{pricesTree(), "((.Milk >= 4 AND .Milk <= 6) AND (.Onions >= 1 AND .Onions <= 2)) OR .Toothpaste > 5"},
+		{NewLeafNode("eq .A 1"), ".A == 1"},
+		{NewLeafNode("eq $.A.B 'x' 2"), ".A.B in ['x', 2]"},
+		{NewLeafNode(`in .Country ["US", "CA"]`), `.Country in ["US", "CA"]`},
+		{NewLeafNode(`oneOf .Name "a" "b"`), `.Name in ["a", "b"]`},
+		{NewLeafNode(`in "dairy" .Tags`), `"dairy" in .Tags`},
+		{NewLeafNode("between .X 1 10"), ".X BETWEEN 1 AND 10"},
+		{NewLeafNode(`not (hasPrefix .Name "o")`), `NOT hasPrefix(.Name, "o")`},
+		{NewLeafNode("not (eq .A 1)"), "NOT (.A == 1)"},
+		{NewLeafNode("and (eq .A 1) (or (eq .B 2) (eq .C 3))"), ".A == 1 AND (.B == 2 OR .C == 3)"},
+		{NewNode(OperatorAnd, NewLeafNode("or (eq .B 2) (eq .C 3)"), NewLeafNode("true")), "(.B == 2 OR .C == 3) AND true"},
+		{NewLeafNode(`inWindow "09:00" "17:00"`), `inWindow("09:00", "17:00")`},
+		{NewLeafNode("gt (len .Tags) 2"), "len(.Tags) > 2"},
+		{NewLeafNode("now"), "now()"},
+		{NewNode(OperatorAnd, NewLeafNode("eq .A 1")), ".A == 1"},
+		{NewNode(OperatorAnd, NewAdvancedLeafNode("{{ if .A }}true{{ end }}"), NewLeafNode("gt .B  (")),
+			"{{ if .A }}true{{ end }} AND (gt .B ()"},
+	} {
+		if actual := tc.n.Infix(); actual != tc.expected {
+			t.Errorf("Infix() expected=%s actual=%s\n", tc.expected, actual)
+		}
+		if actual := fmt.Sprint(tc.n); actual != tc.expected {
+			t.Errorf("String() expected=%s actual=%s\n", tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+// The kernels below evaluate a comparison over a column 64 rows at a time,
+// building each word of the result bitmap without branching on the values so
+// that the compiler can emit conditional sets rather than jumps and the inner
+// loop stays free of unpredictable branches.  Each kernel ORs its result into
+// `out`, which must hold at least one bit per value.
+
+type ordered interface {
+	int64 | float64 | string
+}
+
+// compareKernel sets the bit in `out` of every value in `vs` satisfying the
+// comparison `op` against `args`.
+func compareKernel[T ordered](vs []T, op string, args []T, out []uint64) {
+	switch op {
+	case "eq":
+		for _, a := range args {
+			eqKernel(vs, a, out)
+		}
+	case "ne":
+		neKernel(vs, args[0], out)
+	case "lt":
+		ltKernel(vs, args[0], out)
+	case "le":
+		leKernel(vs, args[0], out)
+	case "gt":
+		gtKernel(vs, args[0], out)
+	case "ge":
+		geKernel(vs, args[0], out)
+	case "between":
+		betweenKernel(vs, args[0], args[1], out)
+	}
+}
+
+// b2u converts a boolean into 0 or 1 without branching.
+func b2u(b bool) uint64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// block returns the values of `vs` which map to word `w` of a bitmap.
+func block[T any](vs []T, w int) []T {
+	lo, hi := w*64, w*64+64
+	if hi > len(vs) {
+		hi = len(vs)
+	}
+	return vs[lo:hi]
+}
+
+func words(n int) int {
+	return (n + 63) / 64
+}
+
+func eqKernel[T ordered](vs []T, a T, out []uint64) {
+	for w := 0; w < words(len(vs)); w++ {
+		var word uint64
+		for i, v := range block(vs, w) {
+			word |= b2u(v == a) << i
+		}
+		out[w] |= word
+	}
+}
+
+func neKernel[T ordered](vs []T, a T, out []uint64) {
+	for w := 0; w < words(len(vs)); w++ {
+		var word uint64
+		for i, v := range block(vs, w) {
+			word |= b2u(v != a) << i
+		}
+		out[w] |= word
+	}
+}
+
+func ltKernel[T ordered](vs []T, a T, out []uint64) {
+	for w := 0; w < words(len(vs)); w++ {
+		var word uint64
+		for i, v := range block(vs, w) {
+			word |= b2u(v < a) << i
+		}
+		out[w] |= word
+	}
+}
+
+func leKernel[T ordered](vs []T, a T, out []uint64) {
+	for w := 0; w < words(len(vs)); w++ {
+		var word uint64
+		for i, v := range block(vs, w) {
+			word |= b2u(v <= a) << i
+		}
+		out[w] |= word
+	}
+}
+
+func gtKernel[T ordered](vs []T, a T, out []uint64) {
+	for w := 0; w < words(len(vs)); w++ {
+		var word uint64
+		for i, v := range block(vs, w) {
+			word |= b2u(v > a) << i
+		}
+		out[w] |= word
+	}
+}
+
+func geKernel[T ordered](vs []T, a T, out []uint64) {
+	for w := 0; w < words(len(vs)); w++ {
+		var word uint64
+		for i, v := range block(vs, w) {
+			word |= b2u(v >= a) << i
+		}
+		out[w] |= word
+	}
+}
+
+func betweenKernel[T ordered](vs []T, lo, hi T, out []uint64) {
+	for w := 0; w < words(len(vs)); w++ {
+		var word uint64
+		for i, v := range block(vs, w) {
+			word |= (b2u(lo <= v) & b2u(v <= hi)) << i
+		}
+		out[w] |= word
+	}
+}
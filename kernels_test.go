@@ -0,0 +1,151 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// scalarCompare is the row at a time reference for the kernels.
+func scalarCompare[T ordered](v T, op string, args []T) bool {
+	switch op {
+	case "eq":
+		for _, a := range args {
+			if v == a {
+				return true
+			}
+		}
+		return false
+	case "ne":
+		return v != args[0]
+	case "lt":
+		return v < args[0]
+	case "le":
+		return v <= args[0]
+	case "gt":
+		return v > args[0]
+	case "ge":
+		return v >= args[0]
+	case "between":
+		return args[0] <= v && v <= args[1]
+	}
+	return false
+}
+
+func checkKernel[T ordered](t *testing.T, vs []T, op string, args []T) {
+	b := NewBitmap(len(vs))
+	compareKernel(vs, op, args, b.words)
+	for i, v := range vs {
+		if expected := scalarCompare(v, op, args); b.Get(i) != expected {
+			t.Errorf("compareKernel(%s %v) row %d value %v expected=%v actual=%v\n", op, args, i, v, expected, b.Get(i))
+		}
+	}
+	if b.trim(); b.Count() > len(vs) {
+		t.Errorf("compareKernel(%s %v) set bits past the end\n", op, args)
+	}
+}
+
+func TestCompareKernels(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 63, 64, 65, 200} {
+		is, fs, ss := make([]int64, n), make([]float64, n), make([]string, n)
+		for i := 0; i < n; i++ {
+			is[i] = r.Int63n(20) - 10
+			fs[i] = float64(is[i]) / 2
+			ss[i] = string(rune('a' + r.Intn(10)))
+		}
+		if n > 2 {
+			fs[1] = math.NaN()
+		}
+
+		for _, op := range []string{"eq", "ne", "lt", "le", "gt", "ge", "between"} {
+			checkKernel(t, is, op, []int64{-2, 3})
+			checkKernel(t, fs, op, []float64{-1, 1.5})
+			checkKernel(t, ss, op, []string{"c", "g"})
+		}
+		checkKernel(t, is, "eq", []int64{1, 2, 3, 4})
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+const benchRows = 1 << 16
+
+func benchColumns() Columns {
+	r := rand.New(rand.NewSource(1))
+	is, fs := make([]int64, benchRows), make([]float64, benchRows)
+	for i := range is {
+		is[i] = r.Int63n(1000)
+		fs[i] = r.Float64() * 1000
+	}
+	return Columns{
+		"Price":  {Int64: is},
+		"Weight": {Float64: fs},
+	}
+}
+
+// BenchmarkKernels measures the kernels against a loop which branches on
+// every comparison.
+func BenchmarkKernels(b *testing.B) {
+	cs := benchColumns()
+	is, fs := cs["Price"].Int64, cs["Weight"].Float64
+	out := make([]uint64, words(benchRows))
+
+	for _, op := range []string{"gt", "ge", "lt", "le", "eq"} {
+		b.Run(op+"/int64", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				compareKernel(is, op, []int64{500}, out)
+			}
+		})
+		b.Run(op+"/float64", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				compareKernel(fs, op, []float64{500}, out)
+			}
+		})
+		b.Run(op+"/int64/branching", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for j, v := range is {
+					if scalarCompare(v, op, []int64{500}) {
+						out[j/64] |= 1 << (j % 64)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkEvaluateBatch measures evaluating a tree over a batch against
+// evaluating it a row at a time.
+func BenchmarkEvaluateBatch(b *testing.B) {
+	cs := benchColumns()
+	ct, err := Compile(NewNode(OperatorAnd,
+		NewLeafNode("gt .Price 100"),
+		NewLeafNode("le .Weight 900")))
+	if err != nil {
+		b.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := ct.EvaluateBatch(cs); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("rows", func(b *testing.B) {
+		is, fs := cs["Price"].Int64, cs["Weight"].Float64
+		data := map[string]interface{}{}
+		for i := 0; i < b.N; i++ {
+			for j := range is {
+				data["Price"], data["Weight"] = is[j], fs[j]
+				if _, err := ct.Evaluate(data); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Truth is a three-valued logical result.
+type Truth int8
+
+const (
+	False Truth = iota
+	True
+	Unknown
+)
+
+func (t Truth) String() string {
+	switch t {
+	case False:
+		return "false"
+	case True:
+		return "true"
+	case Unknown:
+		return "unknown"
+	}
+	return fmt.Sprintf("Truth(%d)", int8(t))
+}
+
+// MarshalText encodes the truth as "false", "true" or "unknown".
+func (t Truth) MarshalText() ([]byte, error) {
+	switch t {
+	case False, True, Unknown:
+		return []byte(t.String()), nil
+	}
+	return nil, fmt.Errorf("invalid truth value %d", int8(t))
+}
+
+// UnmarshalText decodes "false", "true" or "unknown".
+func (t *Truth) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "false":
+		*t = False
+	case "true":
+		*t = True
+	case "unknown":
+		*t = Unknown
+	default:
+		return fmt.Errorf("invalid truth value %q", string(bs))
+	}
+	return nil
+}
+
+// TruthResult is the result of a three-valued evaluation.
+type TruthResult struct {
+	Value Truth `json:"Value"`
+
+	// Unknown holds the paths of the leaves which evaluated to unknown, in
+	// evaluation order.  Leaves skipped by short-circuiting are not included,
+	// so when `Value` is decided this may list fewer leaves than are
+	// indeterminate.
+	Unknown []string `json:"Unknown,omitempty"`
+}
+
+// EvaluateKleene evaluates the compiled tree using Kleene's strong
+// three-valued logic.  A leaf is unknown if it references a field missing from
+// the data, as defined by `WithMissing` but regardless of the policy compiled
+// with, or if it renders "unknown", for example by returning `Unknown` from a
+// custom function.  An `and` is false if any child is false, otherwise unknown
+// if any child is unknown; an `or` is true if any child is true, otherwise
+// unknown if any child is unknown.  Children are evaluated sequentially and
+// short-circuit as soon as the result is decided.
+func (ct *CompiledTree) EvaluateKleene(data interface{}) (TruthResult, error) {
+	return ct.EvaluateKleeneContext(context.Background(), data)
+}
+
+// EvaluateKleeneContext is `EvaluateKleene` respecting the cancellation and
+// deadline of `ctx` as `EvaluateContext` does.
+func (ct *CompiledTree) EvaluateKleeneContext(ctx context.Context, data interface{}) (TruthResult, error) {
+	st := &evalState{ctx: ctx, stop: ctx}
+	st.withBudget(ct.opts.budget)
+	defer releaseState(st)
+	res := TruthResult{}
+	v, err := ct.eval.evaluateKleene(st, data, &res)
+	if err != nil {
+		return TruthResult{}, err
+	}
+	res.Value = v
+	return res, nil
+}
+
+func (cn *compiledNode) evaluateKleene(st *evalState, data interface{}, res *TruthResult) (Truth, error) {
+	if err := st.stopped(cn); err != nil {
+		return Unknown, err
+	}
+
+	if cn.node.isLeaf() {
+		v, err := cn.evaluateKleeneLeaf(st, data)
+		if err == nil && v == Unknown {
+			res.Unknown = append(res.Unknown, cn.path)
+		}
+		return v, err
+	}
+
+	if cn.custom != nil {
+		vs := make([]Truth, len(cn.children))
+		for i, c := range cn.children {
+			cv, err := c.evaluateKleene(st, data, res)
+			if err != nil {
+				return Unknown, err
+			}
+			vs[i] = cv
+		}
+		return customTruth(cn.custom, vs), nil
+	}
+
+	if cn.sw != nil {
+		return cn.evaluateKleeneSwitch(st, data, res)
+	}
+
+	if cn.node.Op == OperatorIf {
+		cond, err := cn.children[0].evaluateKleene(st, data, res)
+		if err != nil {
+			return Unknown, err
+		}
+		// Both branches are needed unless the condition is known.
+		then, els := Unknown, Unknown
+		if cond != False {
+			if then, err = cn.children[1].evaluateKleene(st, data, res); err != nil {
+				return Unknown, err
+			}
+		}
+		if cond != True {
+			if els, err = cn.children[2].evaluateKleene(st, data, res); err != nil {
+				return Unknown, err
+			}
+		}
+		return ifTruth(cond, then, els), nil
+	}
+
+	d, v := False, True // the deciding child result and the result otherwise
+	if decisive(cn.node.Op) {
+		d, v = True, False
+	}
+	for _, c := range cn.children {
+		cv, err := c.evaluateKleene(st, data, res)
+		if err != nil {
+			return Unknown, err
+		}
+		if cv == d {
+			return d, nil
+		}
+		if cv == Unknown {
+			v = Unknown
+		}
+	}
+	return v, nil
+}
+
+func (cn *compiledNode) evaluateKleeneLeaf(st *evalState, data interface{}) (Truth, error) {
+	if _, ok := missingField(data, cn.fields); ok {
+		return Unknown, nil
+	}
+
+	out, err := cn.renderLeaf(st, data)
+	if err != nil {
+		return Unknown, err
+	}
+	if strings.TrimSpace(out) == "unknown" {
+		return Unknown, nil
+	}
+
+	v, err := parseResult(out)
+	if err != nil {
+		return Unknown, err
+	}
+	if v {
+		return True, nil
+	}
+	return False, nil
+}
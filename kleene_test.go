@@ -0,0 +1,87 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"testing"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestEvaluateKleene(t *testing.T) {
+	fm := template.FuncMap{
+		"pending": func() Truth { return Unknown },
+	}
+	ct, err := Compile(pricesTree(), WithFuncs(fm))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	for _, tc := range []struct {
+		data     map[string]interface{}
+		expected Truth
+		unknown  []string
+	}{
+		{map[string]interface{}{"Milk": 5, "Onions": 2, "Toothpaste": 4}, True, nil},
+		{map[string]interface{}{"Milk": 5, "Onions": 0, "Toothpaste": 4}, False, nil},
+		{map[string]interface{}{"Milk": 5, "Onions": 2}, True, nil},
+		{map[string]interface{}{"Milk": 5, "Toothpaste": 4}, Unknown, []string{"/0/1/0", "/0/1/1"}},
+		{map[string]interface{}{"Milk": 5}, Unknown, []string{"/0/1/0", "/0/1/1", "/1"}},
+		{map[string]interface{}{"Milk": 9}, Unknown, []string{"/1"}},
+		{map[string]interface{}{"Toothpaste": 8}, True, []string{"/0/0/0", "/0/0/1", "/0/1/0", "/0/1/1"}},
+	} {
+		res, err := ct.EvaluateKleene(tc.data)
+		if err != nil {
+			t.Fatalf("EvaluateKleene() error: %s\n", err.Error())
+		}
+		if res.Value != tc.expected || len(res.Unknown) != len(tc.unknown) {
+			t.Errorf("EvaluateKleene(%v) expected=%v %v actual=%v %v\n", tc.data, tc.expected, tc.unknown, res.Value, res.Unknown)
+			continue
+		}
+		for i, p := range tc.unknown {
+			if res.Unknown[i] != p {
+				t.Errorf("EvaluateKleene(%v) expected unknown=%v actual=%v\n", tc.data, tc.unknown, res.Unknown)
+			}
+		}
+	}
+
+	ct, err = Compile(NewNode(OperatorOr, NewLeafNode("pending"), NewLeafNode("false")), WithFuncs(fm))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if res, err := ct.EvaluateKleene(nil); err != nil || res.Value != Unknown {
+		t.Errorf("EvaluateKleene() expected=unknown actual=%v (%v)\n", res.Value, err)
+	}
+	if _, err := ct.Evaluate(nil); err == nil {
+		t.Errorf("Evaluate() expected unknown to be an error in two-valued evaluation\n")
+	}
+
+	bs, err := json.Marshal(TruthResult{Value: Unknown, Unknown: []string{"/0"}})
+	if err != nil || string(bs) != `{"Value":"unknown","Unknown":["/0"]}` {
+		t.Errorf("json.Marshal(TruthResult) unexpected=%s (%v)\n", bs, err)
+	}
+}
+
+func TestDescribeKleene(t *testing.T) {
+	for _, os := range Describe().Operators {
+		for _, row := range os.KleeneTable {
+			a, b := row.Inputs[0], row.Inputs[1]
+			expected := Unknown
+			switch {
+			case os.Op == OperatorAnd && (a == False || b == False):
+				expected = False
+			case os.Op == OperatorAnd && a == True && b == True:
+				expected = True
+			case os.Op == OperatorOr && (a == True || b == True):
+				expected = True
+			case os.Op == OperatorOr && a == False && b == False:
+				expected = False
+			}
+			if row.Result != expected {
+				t.Errorf("Describe() %s %v expected=%v actual=%v\n", os.Op, row.Inputs, expected, row.Result)
+			}
+		}
+	}
+}
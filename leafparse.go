@@ -0,0 +1,577 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// parseLeaf parses a leaf expression without requiring the functions it
+// calls to be defined.
+func parseLeaf(leaf string) (*parse.Tree, error) {
+	t, _, err := parseTemplate("{{ " + leafSource(leaf) + " }}")
+	return t, err
+}
+
+// parseAdvanced parses the template of an advanced leaf, in isolation from
+// any other leaf, without requiring the functions it calls to be defined.
+func parseAdvanced(src string) (*parse.Tree, error) {
+	t, defined, err := parseTemplate(src)
+	if err != nil {
+		return nil, err
+	}
+	if defined {
+		return nil, fmt.Errorf("advanced leaf may not define templates")
+	}
+	return t, nil
+}
+
+// parseTemplate parses `src`, reporting whether it defines any templates.
+func parseTemplate(src string) (*parse.Tree, bool, error) {
+	t := parse.New("leaf")
+	t.Mode = parse.SkipFuncCheck
+	trees := map[string]*parse.Tree{}
+	if _, err := t.Parse(src, "", "", trees); err != nil {
+		return nil, false, err
+	}
+	delete(trees, t.Name)
+	return t, len(trees) > 0, nil
+}
+
+// leafParseError returns the `*ParseError` of the leaf `n` if it does not
+// parse, with the functions `fm` defined unless they are nil, see
+// `ParseError`, or nil if it parses.  The leaf is parsed on its own, so that
+// its error is located within it: with the offset of the longest prefix of
+// the leaf which does not fail in the same way, moved to the start of the
+// token the error quotes if it ends there.  Syntax errors are located
+// without `fm`, so that the prefixes of function names are not reported as
+// undefined functions.
+func leafParseError(n *Node, fm template.FuncMap) error {
+	src := func(leaf string) string {
+		if n.Op == OperatorAdvanced {
+			return leaf
+		}
+		return "{{ " + leafSource(leaf) + " }}"
+	}
+	parse := func(leaf string) error {
+		_, _, err := parseTemplate(src(leaf))
+		return err
+	}
+	err := parse(n.Leaf)
+	if err == nil && fm != nil {
+		parse = func(leaf string) error {
+			_, err := template.New("leaf").Funcs(fm).Parse(src(leaf))
+			return err
+		}
+		err = parse(n.Leaf)
+	}
+	if err == nil {
+		return nil
+	}
+
+	msg, offset := err.Error(), 0
+	for i := len(n.Leaf) - 1; i >= 0; i-- {
+		if err := parse(n.Leaf[:i]); err == nil || err.Error() != msg {
+			offset = i
+			break
+		}
+	}
+	if q := strings.IndexByte(msg, '"'); q >= 0 {
+		if quoted, err := strconv.QuotedPrefix(msg[q:]); err == nil {
+			tok, _ := strconv.Unquote(quoted)
+			if start := offset + 1 - len(tok); tok != "" && start >= 0 && n.Leaf[start:offset+1] == tok {
+				offset = start
+			}
+		}
+	}
+
+	// Messages are written as "template: leaf:<line>: <message>".
+	if rest := strings.TrimPrefix(msg, "template: "); rest != msg {
+		if i := strings.Index(rest, ": "); i >= 0 {
+			msg = rest[i+2:]
+		}
+	}
+	return &ParseError{Leaf: n.Leaf, Offset: offset, Msg: msg}
+}
+
+// checkExpression returns an error wrapping `ErrNotExpression` unless the
+// parsed leaf `t` is a single expression: one action, without variable
+// declarations, variables other than `$` or pipes, in which any
+// parenthesized arguments are themselves single expressions.  `defined`
+// reports whether the leaf defined templates of its own.
+func checkExpression(t *parse.Tree, defined bool) error {
+	if defined {
+		return fmt.Errorf("%w: defines a template", ErrNotExpression)
+	}
+	if len(t.Root.Nodes) != 1 {
+		return fmt.Errorf("%w: has %d actions", ErrNotExpression, len(t.Root.Nodes))
+	}
+	a, ok := t.Root.Nodes[0].(*parse.ActionNode)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotExpression, t.Root.Nodes[0])
+	}
+	return checkPipe(a.Pipe)
+}
+
+func checkPipe(p *parse.PipeNode) error {
+	if len(p.Decl) > 0 {
+		return fmt.Errorf("%w: declares %s", ErrNotExpression, p.Decl[0])
+	}
+	if len(p.Cmds) > 1 {
+		return fmt.Errorf("%w: pipes into %s", ErrNotExpression, p.Cmds[1])
+	}
+	for _, arg := range p.Cmds[0].Args {
+		switch a := arg.(type) {
+		case *parse.PipeNode:
+			if err := checkPipe(a); err != nil {
+				return err
+			}
+		case *parse.ChainNode:
+			if inner, ok := a.Node.(*parse.PipeNode); ok {
+				if err := checkPipe(inner); err != nil {
+					return err
+				}
+			}
+		case *parse.VariableNode:
+			if a.Ident[0] != "$" {
+				return fmt.Errorf("%w: uses variable %s", ErrNotExpression, a)
+			}
+		}
+	}
+	return nil
+}
+
+// walkCommands calls `fn` for every command in the parse tree rooted at `n`,
+// including those nested inside parenthesized pipelines, chained or not.
+// `piped` is true if
+// the command receives the result of a previous command as its final
+// argument.
+func walkCommands(n parse.Node, fn func(cmd *parse.CommandNode, piped bool)) {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			walkCommands(c, fn)
+		}
+	case *parse.ActionNode:
+		walkCommands(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for i, cmd := range n.Cmds {
+			fn(cmd, i > 0)
+			for _, arg := range cmd.Args {
+				walkCommands(arg, fn)
+			}
+		}
+	case *parse.ChainNode:
+		walkCommands(n.Node, fn)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, fn)
+	}
+}
+
+func walkBranch(n *parse.BranchNode, fn func(*parse.CommandNode, bool)) {
+	walkCommands(n.Pipe, fn)
+	walkCommands(n.List, fn)
+	walkCommands(n.ElseList, fn)
+}
+
+// calls returns the name of the function called by `cmd`, if any.
+func calls(cmd *parse.CommandNode) string {
+	if len(cmd.Args) > 0 {
+		if id, ok := cmd.Args[0].(*parse.IdentifierNode); ok {
+			return id.Ident
+		}
+	}
+	return ""
+}
+
+// literalPatterns returns the string literal patterns passed to `matches` in
+// the parsed leaf `t`.
+func literalPatterns(t *parse.Tree) []string {
+	pats := []string{}
+	walkCommands(t.Root, func(cmd *parse.CommandNode, piped bool) {
+		if piped || calls(cmd) != "matches" || len(cmd.Args) != 3 {
+			return
+		}
+		if s, ok := cmd.Args[2].(*parse.StringNode); ok {
+			pats = append(pats, s.Text)
+		}
+	})
+	return pats
+}
+
+// leafSource returns the template source of a leaf expression, expanding its
+// macros, see `RegisterMacro`, and rewriting its list literals.
+func leafSource(leaf string) string {
+	return listSource(expandMacros(leaf))
+}
+
+// listSource rewrites the list literals of a leaf expression, such as
+// `["US", "CA"]`, into calls to `list "US" "CA"`.  Brackets and commas inside
+// string, raw string and character literals are left alone.
+func listSource(leaf string) string {
+	if !strings.ContainsRune(leaf, '[') {
+		return leaf
+	}
+
+	var b strings.Builder
+	var quote rune
+	depth, escaped := 0, false
+	for _, r := range leaf {
+		switch {
+		case quote != 0:
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\' && quote != '`':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+		case r == '"' || r == '`' || r == '\'':
+			quote = r
+		case r == '[':
+			depth++
+			b.WriteString("(list ")
+			continue
+		case r == ']' && depth > 0:
+			depth--
+			r = ')'
+		case r == ',' && depth > 0:
+			r = ' '
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// normalizeLeaf returns a canonical rendering of a leaf expression, with
+// redundant parentheses and whitespace removed.  If `abstract` is true every
+// literal is replaced by "?" and the literals are returned in order.  Leaves
+// which do not parse are returned with their whitespace collapsed.
+func normalizeLeaf(leaf string, abstract bool) (string, []string) {
+	t, err := parseLeaf(leaf)
+	if err != nil || len(t.Root.Nodes) != 1 {
+		return strings.Join(strings.Fields(leaf), " "), nil
+	}
+	a, ok := t.Root.Nodes[0].(*parse.ActionNode)
+	if !ok {
+		return strings.Join(strings.Fields(leaf), " "), nil
+	}
+
+	p := &leafPrinter{abstract: abstract}
+	p.pipe(unwrapPipe(a.Pipe))
+	return p.b.String(), p.literals
+}
+
+// unwrapPipe strips parentheses which wrap an entire pipeline.
+func unwrapPipe(p *parse.PipeNode) *parse.PipeNode {
+	for len(p.Decl) == 0 && len(p.Cmds) == 1 && len(p.Cmds[0].Args) == 1 {
+		inner, ok := p.Cmds[0].Args[0].(*parse.PipeNode)
+		if !ok {
+			break
+		}
+		p = inner
+	}
+	return p
+}
+
+// leafPrinter renders parsed leaf expressions.
+type leafPrinter struct {
+	b        strings.Builder
+	abstract bool
+	literals []string
+}
+
+func (p *leafPrinter) pipe(n *parse.PipeNode) {
+	for i, v := range n.Decl {
+		if i > 0 {
+			p.b.WriteString(", ")
+		}
+		p.b.WriteString(v.String())
+	}
+	if len(n.Decl) > 0 {
+		p.b.WriteString(" := ")
+	}
+	for i, cmd := range n.Cmds {
+		if i > 0 {
+			p.b.WriteString(" | ")
+		}
+		for j, arg := range cmd.Args {
+			if j > 0 {
+				p.b.WriteByte(' ')
+			}
+			p.arg(arg)
+		}
+	}
+}
+
+func (p *leafPrinter) arg(n parse.Node) {
+	switch n := n.(type) {
+	case *parse.PipeNode:
+		p.b.WriteByte('(')
+		p.pipe(unwrapPipe(n))
+		p.b.WriteByte(')')
+	case *parse.StringNode, *parse.NumberNode, *parse.BoolNode:
+		if p.abstract {
+			p.literals = append(p.literals, n.String())
+			p.b.WriteByte('?')
+			return
+		}
+		p.b.WriteString(n.String())
+	default:
+		p.b.WriteString(n.String())
+	}
+}
+
+// leafFields returns the fields of the data referenced by the parsed leaf
+// `t`, such as ["Dairy", "Milk"] for `.Dairy.Milk`, in order of appearance.
+func leafFields(t *parse.Tree) [][]string {
+	fields := [][]string{}
+	walkCommands(t.Root, func(cmd *parse.CommandNode, _ bool) {
+		for _, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.FieldNode:
+				fields = append(fields, a.Ident)
+			case *parse.VariableNode:
+				if len(a.Ident) > 1 && a.Ident[0] == "$" {
+					fields = append(fields, a.Ident[1:])
+				}
+			}
+		}
+	})
+	return fields
+}
+
+// comparison is a leaf which compares a single field against literal values,
+// such as `ge .Price 10`, which can be evaluated over whole columns at once.
+type comparison struct {
+	op     string        // eq, ne, lt, le, gt, ge or between
+	fn     string        // the function called, which may differ from op
+	field  []string      // the field compared
+	values []interface{} // int64, float64, string or bool literals
+}
+
+// reversed maps each comparison to the equivalent comparison with its
+// operands swapped.
+var reversed = map[string]string{
+	"eq": "eq", "ne": "ne", "lt": "gt", "le": "ge", "gt": "lt", "ge": "le",
+}
+
+// leafComparison recognizes leaves of the forms `op .Field literal`,
+// `op literal .Field`, `eq .Field a b ...`, `oneOf .Field a b ...`,
+// `in .Field [a, b, ...]` and `between .Field lo hi`.  The functions called
+// are not checked, that is left to the caller.
+func leafComparison(t *parse.Tree) (*comparison, bool) {
+	p, ok := leafPipe(t)
+	if !ok {
+		return nil, false
+	}
+	return pipeComparison(p)
+}
+
+// leafPipe returns the pipeline of a leaf consisting of a single action, with
+// any parentheses wrapping it removed.
+func leafPipe(t *parse.Tree) (*parse.PipeNode, bool) {
+	if len(t.Root.Nodes) != 1 {
+		return nil, false
+	}
+	a, ok := t.Root.Nodes[0].(*parse.ActionNode)
+	if !ok {
+		return nil, false
+	}
+	return unwrapPipe(a.Pipe), true
+}
+
+// pipeComparison is `leafComparison` for the pipeline `p`.
+func pipeComparison(p *parse.PipeNode) (*comparison, bool) {
+	if len(p.Decl) > 0 || len(p.Cmds) != 1 || len(p.Cmds[0].Args) < 3 {
+		return nil, false
+	}
+
+	fn := calls(p.Cmds[0])
+	args := p.Cmds[0].Args[1:]
+	op := fn
+	switch fn {
+	case "eq", "oneOf":
+		op = "eq"
+	case "ne", "lt", "le", "gt", "ge":
+		if len(args) != 2 {
+			return nil, false
+		}
+	case "between":
+		if len(args) != 3 {
+			return nil, false
+		}
+	case "in":
+		if len(args) != 2 {
+			return nil, false
+		}
+		l, ok := args[1].(*parse.PipeNode)
+		if !ok || len(l.Decl) > 0 || len(l.Cmds) != 1 || calls(l.Cmds[0]) != "list" {
+			return nil, false
+		}
+		args = append(args[:1:1], l.Cmds[0].Args[1:]...)
+		op = "eq"
+	default:
+		return nil, false
+	}
+
+	field, ok := argField(args[0])
+	if !ok && len(args) == 2 && reversed[fn] != "" {
+		// The literal comes first, as in `lt 10 .Price`.
+		if field, ok = argField(args[1]); ok {
+			args = []parse.Node{args[1], args[0]}
+			op = reversed[op]
+		}
+	}
+	if !ok || len(args) < 2 {
+		return nil, false
+	}
+
+	c := &comparison{op: op, fn: fn, field: field}
+	for _, arg := range args[1:] {
+		v, ok := argLiteral(arg)
+		if !ok {
+			return nil, false
+		}
+		c.values = append(c.values, v)
+	}
+	return c, true
+}
+
+// argField returns the field referenced by `n` if it is a field such as
+// `.Price` or `$.Price`.
+func argField(n parse.Node) ([]string, bool) {
+	switch n := n.(type) {
+	case *parse.FieldNode:
+		return n.Ident, true
+	case *parse.VariableNode:
+		if len(n.Ident) > 1 && n.Ident[0] == "$" {
+			return n.Ident[1:], true
+		}
+	}
+	return nil, false
+}
+
+// argLiteral returns the value `text/template` passes to a function for the
+// literal `n`.  Unsigned integers too large for an int64 are not supported.
+func argLiteral(n parse.Node) (interface{}, bool) {
+	switch n := n.(type) {
+	case *parse.StringNode:
+		return n.Text, true
+	case *parse.BoolNode:
+		return n.True, true
+	case *parse.NumberNode:
+		text := strings.TrimLeft(n.Text, "+-")
+		hexInt := (strings.HasPrefix(text, "0x") || strings.HasPrefix(text, "0X")) && !strings.ContainsAny(text, "pP")
+		switch {
+		case n.IsComplex:
+			return nil, false
+		case n.IsFloat && (!n.IsInt || !hexInt && text[0] != '\'' && strings.ContainsAny(text, ".eEpP")):
+			return n.Float64, true
+		case n.IsInt:
+			return n.Int64, true
+		case n.IsFloat:
+			return n.Float64, true
+		}
+	}
+	return nil, false
+}
+
+// opaqueLeaf reports whether the parsed leaf `t` may read the data other than
+// through the fields returned by `leafFields`, for example by passing `.` or
+// `$` to a function or by changing `.` with a `with` or `range` action.
+func opaqueLeaf(t *parse.Tree) bool {
+	for _, n := range t.Root.Nodes {
+		if _, ok := n.(*parse.ActionNode); !ok {
+			if _, ok := n.(*parse.TextNode); !ok {
+				return true
+			}
+		}
+	}
+
+	opaque := false
+	walkCommands(t.Root, func(cmd *parse.CommandNode, _ bool) {
+		for _, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.DotNode, *parse.ChainNode:
+				opaque = true
+			case *parse.VariableNode:
+				if len(a.Ident) == 1 {
+					opaque = true
+				}
+			}
+		}
+	})
+	return opaque
+}
+
+// stringMatch is a leaf which matches a field against a literal string, such
+// as `hasPrefix .Name "ab"`.
+type stringMatch struct {
+	fn    string // contains, hasPrefix, hasSuffix or matches
+	field []string
+	text  string
+}
+
+// pipeStringMatch recognizes the pipeline `p` if it is a `stringMatch`.
+func pipeStringMatch(p *parse.PipeNode) (*stringMatch, bool) {
+	if len(p.Decl) > 0 || len(p.Cmds) != 1 || len(p.Cmds[0].Args) != 3 {
+		return nil, false
+	}
+	fn := calls(p.Cmds[0])
+	switch fn {
+	case "contains", "hasPrefix", "hasSuffix", "matches":
+	default:
+		return nil, false
+	}
+	field, ok := argField(p.Cmds[0].Args[1])
+	if !ok {
+		return nil, false
+	}
+	s, ok := p.Cmds[0].Args[2].(*parse.StringNode)
+	if !ok {
+		return nil, false
+	}
+	return &stringMatch{fn: fn, field: field, text: s.Text}, true
+}
+
+// pipeNot returns the pipeline negated by `p` if it is `not (...)`.
+func pipeNot(p *parse.PipeNode) (*parse.PipeNode, bool) {
+	if len(p.Decl) > 0 || len(p.Cmds) != 1 || len(p.Cmds[0].Args) != 2 || calls(p.Cmds[0]) != "not" {
+		return nil, false
+	}
+	inner, ok := p.Cmds[0].Args[1].(*parse.PipeNode)
+	if !ok {
+		return nil, false
+	}
+	return unwrapPipe(inner), true
+}
+
+// pipeConstant returns the value of `p` if it is the literal true or false.
+func pipeConstant(p *parse.PipeNode) (bool, bool) {
+	if len(p.Decl) > 0 || len(p.Cmds) != 1 || len(p.Cmds[0].Args) != 1 {
+		return false, false
+	}
+	b, ok := p.Cmds[0].Args[0].(*parse.BoolNode)
+	if !ok {
+		return false, false
+	}
+	return b.True, true
+}
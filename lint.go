@@ -0,0 +1,198 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"text/template/parse"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Checks of `Lint`.
+const (
+	LintParse         = "parse"         // the leaf does not parse
+	LintUnknownFunc   = "unknown-func"  // the leaf calls a function which is not defined
+	LintConstant      = "constant"      // the leaf compares literals, and is always true or false
+	LintDuplicate     = "duplicate"     // the node repeats one of its siblings
+	LintContradiction = "contradiction" // the leaf can never hold with one of its siblings under `and`
+)
+
+// Issue is a suspicious node found by `Lint`.
+type Issue struct {
+	Path  string `json:"Path"`
+	Check string `json:"Check"`
+	Msg   string `json:"Msg"`
+}
+
+// String returns the issue as "/1/0: duplicate of /0 (duplicate)".
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s (%s)", i.Path, i.Msg, i.Check)
+}
+
+// Lint returns the suspicious nodes of the tree rooted at `n`, in the order
+// of their paths, for checking rules before they are merged: leaves which do
+// not parse or which call functions that are not defined, comparisons of
+// literals such as `gt 1 0` which are always true or always false, children
+// of `and` and `or` nodes which repeat one of their siblings, and leaves
+// under an `and` which can never hold along with one of their siblings, such
+// as `gt .X 5` and `lt .X 3`.  The functions defined are those the options
+// `opts` give to `Compile`; comparisons and contradictions are only reported
+// for the functions of `StdFuncs`.  A tree without issues may still fail to
+// compile, for example with operators which are not registered.
+func Lint(n *Node, opts ...Option) []Issue {
+	o := compileOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c := &compiler{opts: &o}
+	c.funcs = c.leafFuncs()
+
+	issues := []Issue{}
+	c.lint(n, "/", &issues)
+	return issues
+}
+
+// lint appends the issues of the subtree `n` at `path` to `issues`.
+func (c *compiler) lint(n *Node, path string, issues *[]Issue) {
+	add := func(check, format string, args ...interface{}) {
+		*issues = append(*issues, Issue{Path: path, Check: check, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	switch n.Op {
+	case OperatorLeaf, OperatorAdvanced:
+		var t *parse.Tree
+		var err error
+		if n.Op == OperatorAdvanced {
+			t, err = parseAdvanced(n.Leaf)
+		} else {
+			t, err = parseLeaf(n.Leaf)
+		}
+		if err != nil {
+			if perr := leafParseError(n, nil); perr != nil {
+				err = perr
+			}
+			add(LintParse, "%s", err.Error())
+			return
+		}
+		unknown := map[string]bool{}
+		for _, name := range leafFuncNames(t) {
+			if _, ok := c.funcs[name]; !ok && isIdentifier(name) && !unknown[name] {
+				unknown[name] = true
+				add(LintUnknownFunc, "function %q is not defined", name)
+			}
+		}
+		if v, ok := c.constantLeaf(t); ok && n.Op == OperatorLeaf {
+			add(LintConstant, "always %v", v)
+		}
+		return
+	}
+
+	siblings := c.lintSiblings(n, path)
+	for i, child := range n.Nodes {
+		*issues = append(*issues, siblings[i]...)
+		c.lint(child, childPath(path, i), issues)
+	}
+}
+
+// lintSiblings returns the issues of each child of the node `n` at `path`
+// with its siblings: the duplicates of `and` and `or` nodes, and the
+// contradictions of `and` nodes.
+func (c *compiler) lintSiblings(n *Node, path string) [][]Issue {
+	issues := make([][]Issue, len(n.Nodes))
+	if n.Op != OperatorAnd && n.Op != OperatorOr {
+		return issues
+	}
+	add := func(i int, check, format string, args ...interface{}) {
+		issues[i] = append(issues[i], Issue{Path: childPath(path, i), Check: check, Msg: fmt.Sprintf(format, args...)})
+	}
+
+	fps := make([]string, len(n.Nodes))
+	seen := map[string]int{}
+	for i, child := range n.Nodes {
+		fps[i] = child.Fingerprint()
+		if j, ok := seen[fps[i]]; ok {
+			add(i, LintDuplicate, "duplicate of %s", childPath(path, j))
+			continue
+		}
+		seen[fps[i]] = i
+	}
+	if n.Op != OperatorAnd {
+		return issues
+	}
+
+	cmps := make([]*comparison, len(n.Nodes))
+	for i, child := range n.Nodes {
+		if child.Op != OperatorLeaf {
+			continue
+		}
+		if t, err := parseLeaf(child.Leaf); err == nil {
+			if cmp, ok := leafComparison(t); ok && c.isStd(cmp.fn) {
+				cmps[i] = cmp
+			}
+		}
+	}
+	for i, a := range cmps {
+		for j := 0; a != nil && j < i; j++ {
+			// Duplicates are reported as such.
+			if b := cmps[j]; b != nil && fps[i] != fps[j] && contradicts(a, b) {
+				add(i, LintContradiction, "never holds with %s", childPath(path, j))
+				break
+			}
+		}
+	}
+	return issues
+}
+
+// constantLeaf returns the result of the parsed leaf `t` if it is a
+// comparison from `StdFuncs` of literals only, such as `gt 1 0`.
+func (c *compiler) constantLeaf(t *parse.Tree) (bool, bool) {
+	p, ok := leafPipe(t)
+	if !ok || len(p.Decl) > 0 || len(p.Cmds) != 1 || len(p.Cmds[0].Args) < 3 {
+		return false, false
+	}
+	fn := calls(p.Cmds[0])
+	if !c.isStd(fn) {
+		return false, false
+	}
+	var values []interface{}
+	for _, arg := range p.Cmds[0].Args[1:] {
+		v, ok := argLiteral(arg)
+		if !ok {
+			return false, false
+		}
+		values = append(values, v)
+	}
+
+	cmp := &comparison{op: fn, fn: fn, values: values[1:]}
+	switch fn {
+	case "eq":
+	case "ne", "lt", "le", "gt", "ge":
+		if len(values) != 2 {
+			return false, false
+		}
+	default:
+		return false, false
+	}
+	v, err := cmp.holds(values[0])
+	return v, err == nil
+}
+
+// contradicts reports whether no value of their field satisfies both of the
+// comparisons `a` and `b`.  Comparisons of different fields, or of values of
+// different types, are never contradictory.
+func contradicts(a, b *comparison) bool {
+	if fmt.Sprint(a.field) != fmt.Sprint(b.field) {
+		return false
+	}
+	rows, ok := boundRows([]*comparison{a, b})
+	if !ok {
+		return false
+	}
+	for _, row := range rows {
+		if row[0] && row[1] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestLint(t *testing.T) {
+	std := WithFuncs(StdFuncs())
+	for _, tc := range []struct {
+		tree     *Node
+		opts     []Option
+		expected []string
+	}{
+		{NewNode(OperatorAnd, NewLeafNode("gt .X 5"), NewLeafNode("lt .Y 3")), nil, nil},
+		{NewLeafNode("gt 1 0"), nil, []string{"/: always true (constant)"}},
+		{NewLeafNode(`eq "a" "b" "c"`), nil, []string{"/: always false (constant)"}},
+		{NewLeafNode(`lt 1 "a"`), nil, nil},
+		{NewLeafNode("gt .X (len .Y)"), nil, nil},
+		{NewLeafNode("gt .X"), nil, nil},
+		{NewLeafNode("isAdult . | not"), nil, []string{`/: function "isAdult" is not defined (unknown-func)`}},
+		{NewLeafNode("isAdult ."), []Option{WithFuncs(template.FuncMap{"isAdult": func(interface{}) bool { return true }})}, nil},
+		{NewLeafNode("oneOf .X 1 (oneOf .Y 2)"), nil, []string{`/: function "oneOf" is not defined (unknown-func)`}},
+		{NewLeafNode("gt .X ("), nil, []string{"/: parse error at offset 8: missing value for parenthesized pipeline (parse)"}},
+		{NewAdvancedLeafNode("{{ if unknown }}true{{ end }}"), nil, []string{`/: function "unknown" is not defined (unknown-func)`}},
+
+		{NewNode(OperatorOr, NewLeafNode("gt .X 5"), NewLeafNode("lt .X 3"), NewLeafNode("gt .X 5")), nil, []string{"/2: duplicate of /0 (duplicate)"}},
+		{NewNode(OperatorAnd, NewLeafNode("(gt .X 5)"), NewLeafNode("gt .X  5")), nil, []string{"/1: duplicate of /0 (duplicate)"}},
+		{NewNode(OperatorAnd, NewLeafNode("gt .X 5"), NewLeafNode("lt .X 3")), nil, []string{"/1: never holds with /0 (contradiction)"}},
+		{NewNode(OperatorAnd, NewLeafNode("gt .X 5"), NewLeafNode("lt .X 6")), nil, nil},
+		{NewNode(OperatorAnd, NewLeafNode("ge .X 5"), NewLeafNode("le .X 5")), nil, nil},
+		{NewNode(OperatorAnd, NewLeafNode("gt .X 5"), NewLeafNode("le .X 5")), nil, []string{"/1: never holds with /0 (contradiction)"}},
+		{NewNode(OperatorAnd, NewLeafNode("lt 5 .X"), NewLeafNode("gt 3 .X")), nil, []string{"/1: never holds with /0 (contradiction)"}},
+		{NewNode(OperatorAnd, NewLeafNode(`eq .C "US"`), NewLeafNode(`eq .C "CA"`)), nil, []string{"/1: never holds with /0 (contradiction)"}},
+		{NewNode(OperatorAnd, NewLeafNode(`eq .C "US" "CA"`), NewLeafNode(`ne .C "CA"`)), nil, nil},
+		{NewNode(OperatorAnd, NewLeafNode(`gt .C "a"`), NewLeafNode(`lt .C "a\x00"`)), nil, []string{"/1: never holds with /0 (contradiction)"}},
+		{NewNode(OperatorAnd, NewLeafNode(`in .C ["US", "CA"]`), NewLeafNode(`eq .C "FR"`)), []Option{std}, []string{"/1: never holds with /0 (contradiction)"}},
+		{NewNode(OperatorAnd, NewLeafNode("between .X 1 3"), NewLeafNode("gt .X 3")), []Option{std}, []string{"/1: never holds with /0 (contradiction)"}},
+		{NewNode(OperatorAnd, NewLeafNode("eq .B true"), NewLeafNode("eq .B false")), nil, []string{"/1: never holds with /0 (contradiction)"}},
+		{NewNode(OperatorAnd, NewLeafNode("gt .X 5"), NewLeafNode(`lt .X "a"`)), nil, nil},
+		{NewNode(OperatorOr, NewLeafNode("gt .X 5"), NewLeafNode("lt .X 3")), nil, nil},
+
+		// Issues are ordered by path, and nested nodes are linted too.
+		{NewNode(OperatorAnd,
+			NewNode(OperatorOr, NewLeafNode("gt 2 1"), NewLeafNode("eq .A 1")),
+			NewLeafNode("eq .A 1"),
+			NewNode(OperatorOr, NewLeafNode("gt 2 1"), NewLeafNode("eq .A 1")),
+			NewLeafNode("eq .A 2")),
+			nil, []string{
+				"/0/0: always true (constant)",
+				"/2: duplicate of /0 (duplicate)",
+				"/2/0: always true (constant)",
+				"/3: never holds with /1 (contradiction)",
+			}},
+	} {
+		var actual []string
+		for _, i := range Lint(tc.tree, tc.opts...) {
+			actual = append(actual, i.String())
+		}
+		if strings.Join(actual, "\n") != strings.Join(tc.expected, "\n") {
+			t.Errorf("Lint(%s) expected=%q actual=%q\n", tc.tree, tc.expected, actual)
+		}
+	}
+}
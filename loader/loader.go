@@ -0,0 +1,309 @@
+// Package loader loads logictree trees from a directory of JSON and YAML
+// files, and reloads them as the files change, so that rules are changed
+// without redeploying the services evaluating them.
+//
+// Every file named `<name>.json`, `<name>.yaml` or `<name>.yml` holds the tree
+// `<name>`, as a `logictree.Document` of any version or a bare tree.  YAML
+// files hold the same documents as JSON files do.  Trees may reference each
+// other by name, see `logictree.NewRefNode`.  A file which fails to decode or
+// a tree which fails to compile is reported, and the version of the tree
+// last loaded successfully is kept until the file is fixed or removed.  Files
+// named `<name>_test.json` and the like hold the fixtures of the tree
+// `<name>`, see package `treetest`, and are skipped.
+package loader
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// DefaultDebounce is the time `Watch` waits for changes to settle before
+// reloading, when `Options.Debounce` is zero.
+const DefaultDebounce = 100 * time.Millisecond
+
+// Options configures a `Loader`.
+type Options struct {
+	// Compile are the options the trees of the directory are compiled with
+	// on every load, such as the functions their leaves call.
+	Compile []logictree.Option
+
+	// Decode limits the trees read from the files.
+	Decode logictree.DecodeOptions
+
+	// Verifier, if set, verifies the signatures of the files, which must
+	// then hold documents signed by `logictree.SignDocument`.  Files which
+	// are not signed, or whose signatures do not verify, fail to load.
+	Verifier logictree.Verifier
+
+	// Debounce is the time `Watch` waits after a change for others before
+	// reloading.
+	Debounce time.Duration
+
+	// OnReload, if set, is called by `Watch` after every reload with the
+	// error it returned, nil if every file loaded.
+	OnReload func(err error)
+}
+
+// Loader holds the trees compiled from the files of a directory.  It is safe
+// for concurrent use, and the trees it returns are swapped atomically as they
+// are reloaded: a tree returned by `Get` is never changed, only replaced.
+type Loader struct {
+	dir  string
+	opts Options
+
+	mu       sync.Mutex // serializes reloads
+	snapshot atomic.Pointer[snapshot]
+}
+
+// snapshot is the state of the loader after a reload.
+type snapshot struct {
+	trees  map[string]*entry
+	errors map[string]error // by file name
+}
+
+// entry is the last good version of a tree.
+type entry struct {
+	root        *logictree.Node
+	ct          *logictree.CompiledTree
+	fingerprint string // of the tree with its references resolved
+}
+
+// Load returns a loader for the trees of `dir`, having loaded them once.  The
+// error, as that of `Reload`, reports the files which failed to load; the
+// loader is returned along with it, holding the trees which did.
+func Load(dir string, o Options) (*Loader, error) {
+	l := &Loader{dir: dir, opts: o}
+	l.snapshot.Store(&snapshot{trees: map[string]*entry{}, errors: map[string]error{}})
+	if err := l.Reload(); err != nil {
+		return l, err
+	}
+	return l, nil
+}
+
+// Get returns the compiled tree `name`, and whether there is one.
+func (l *Loader) Get(name string) (*logictree.CompiledTree, bool) {
+	e, ok := l.snapshot.Load().trees[name]
+	if !ok {
+		return nil, false
+	}
+	return e.ct, true
+}
+
+// Names returns the names of the trees loaded, sorted.
+func (l *Loader) Names() []string {
+	trees := l.snapshot.Load().trees
+	names := make([]string, 0, len(trees))
+	for name := range trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Errors returns the errors of the files which failed to load in the last
+// reload, by file name.
+func (l *Loader) Errors() map[string]error {
+	errs := map[string]error{}
+	for file, err := range l.snapshot.Load().errors {
+		errs[file] = err
+	}
+	return errs
+}
+
+// Reload reads every file of the directory again and swaps in the trees
+// which load.  Trees whose files fail to decode or compile keep their last
+// good version, and trees whose files were removed are dropped.  Trees whose
+// fingerprints, see `logictree.Registry.Fingerprint`, have not changed keep
+// their compiled tree rather than being compiled again.  The error
+// joins those of every file which failed, prefixed with its name, and fails
+// with no changes made if the directory cannot be read.
+func (l *Loader) Reload() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	files, err := l.files()
+	if err != nil {
+		return err
+	}
+	prev := l.snapshot.Load()
+	next := &snapshot{trees: map[string]*entry{}, errors: map[string]error{}}
+
+	// Every tree is decoded before any is compiled, so that trees can
+	// reference those of any file.
+	var reg logictree.Registry
+	names := make([]string, 0, len(files))
+	for name, file := range files {
+		n, err := l.read(file)
+		if err == nil {
+			err = reg.Register(name, n)
+		}
+		if err != nil {
+			next.errors[file] = err
+			if e, ok := prev.trees[name]; ok {
+				next.trees[name] = e
+				reg.Register(name, e.root)
+			}
+			continue
+		}
+		names = append(names, name)
+	}
+	for _, name := range names {
+		fp, _ := reg.Fingerprint(name)
+		if e, ok := prev.trees[name]; ok && e.fingerprint == fp {
+			next.trees[name] = e
+			continue
+		}
+		n, _ := reg.Get(name)
+		ct, err := logictree.Compile(n, append([]logictree.Option{logictree.WithResolver(&reg)}, l.opts.Compile...)...)
+		if err != nil {
+			next.errors[files[name]] = err
+			if e, ok := prev.trees[name]; ok {
+				next.trees[name] = e
+			}
+			continue
+		}
+		next.trees[name] = &entry{root: n, ct: ct, fingerprint: fp}
+	}
+	l.snapshot.Store(next)
+
+	errs := make([]error, 0, len(next.errors))
+	for _, file := range sortedFiles(next.errors) {
+		errs = append(errs, fmt.Errorf("%s: %w", file, next.errors[file]))
+	}
+	return errors.Join(errs...)
+}
+
+// files returns the files of the directory holding trees, by the names of
+// their trees.  A tree held by several files, such as `a.json` and `a.yaml`,
+// is read from the first of them by name.
+func (l *Loader) files() (map[string]string, error) {
+	des, err := os.ReadDir(l.dir)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{}
+	for _, de := range des {
+		if de.IsDir() || !isTreeFile(de.Name()) {
+			continue
+		}
+		name := strings.TrimSuffix(de.Name(), filepath.Ext(de.Name()))
+		if _, ok := files[name]; !ok {
+			files[name] = de.Name()
+		}
+	}
+	return files, nil
+}
+
+// read decodes the tree of `file`.
+func (l *Loader) read(file string) (*logictree.Node, error) {
+	data, err := os.ReadFile(filepath.Join(l.dir, file))
+	if err != nil {
+		return nil, err
+	}
+	if ext := filepath.Ext(file); ext == ".yaml" || ext == ".yml" {
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, err
+		}
+	}
+	if l.opts.Verifier != nil {
+		return l.opts.Decode.VerifyDocument(data, l.opts.Verifier)
+	}
+	return l.opts.Decode.UnmarshalDocument(data)
+}
+
+// yamlToJSON converts the YAML document `data` to JSON.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// isTreeFile reports whether the file `name` holds a tree, rather than
+// fixtures.
+func isTreeFile(name string) bool {
+	switch ext := filepath.Ext(name); ext {
+	case ".json", ".yaml", ".yml":
+		return !strings.HasPrefix(name, ".") && !strings.HasSuffix(strings.TrimSuffix(name, ext), "_test")
+	}
+	return false
+}
+
+// sortedFiles returns the file names of `errs`, sorted.
+func sortedFiles(errs map[string]error) []string {
+	files := make([]string, 0, len(errs))
+	for file := range errs {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Watch reloads the trees whenever the files of the directory change, until
+// `ctx` is done, calling `Options.OnReload` after every reload and with the
+// errors of the watcher.  Changes are debounced, so that a burst of them,
+// such as an editor replacing a file, reloads once.  It returns the error of
+// `ctx`, or that of the watcher if the directory cannot be watched.
+func (l *Loader) Watch(ctx context.Context) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	if err := w.Add(l.dir); err != nil {
+		return err
+	}
+
+	debounce := l.opts.Debounce
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if isTreeFile(filepath.Base(ev.Name)) {
+				timer.Reset(debounce)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			if l.opts.OnReload != nil {
+				l.opts.OnReload(err)
+			}
+		case <-timer.C:
+			err := l.Reload()
+			if l.opts.OnReload != nil {
+				l.opts.OnReload(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package loader
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func writeFile(t *testing.T, dir, name, content string) {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile(%s) error: %s\n", name, err.Error())
+	}
+}
+
+func evaluate(t *testing.T, l *Loader, name string, data interface{}) bool {
+	ct, ok := l.Get(name)
+	if !ok {
+		t.Fatalf("Get(%s) expected a tree\n", name)
+	}
+	v, err := ct.Evaluate(data)
+	if err != nil {
+		t.Fatalf("Evaluate(%s) error: %s\n", name, err.Error())
+	}
+	return v
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "adult.json", `{"Op": "leaf", "Leaf": "(ge .Age 18)"}`)
+	writeFile(t, dir, "large.yaml", "Version: 1\nTree:\n  Op: and\n  Nodes:\n    - Ref: adult\n    - Op: leaf\n      Leaf: (gt .Amount 100)\n")
+	writeFile(t, dir, "notes.txt", "not a tree")
+	writeFile(t, dir, "adult_test.json", `[{"Name": "adult", "Data": {"Age": 20}, "Expected": true}]`)
+
+	l, err := Load(dir, Options{})
+	if err != nil {
+		t.Fatalf("Load() error: %s\n", err.Error())
+	}
+	if names := l.Names(); !reflect.DeepEqual(names, []string{"adult", "large"}) {
+		t.Errorf("Names() expected=[adult large] actual=%v\n", names)
+	}
+	data := map[string]interface{}{"Age": 20, "Amount": 150}
+	if !evaluate(t, l, "large", data) {
+		t.Errorf("Evaluate(large, %v) expected=true\n", data)
+	}
+
+	// Trees which did not change, nor those they reference, are not
+	// compiled again.
+	adult, _ := l.Get("adult")
+	large, _ := l.Get("large")
+	writeFile(t, dir, "large.yaml", "Version: 1\nTree:\n  Op: and\n  Nodes:\n    - Ref: adult\n    - Op: leaf\n      Leaf: (gt .Amount 120)\n")
+	if err := l.Reload(); err != nil {
+		t.Fatalf("Reload() error: %s\n", err.Error())
+	}
+	if ct, _ := l.Get("adult"); ct != adult {
+		t.Errorf("Reload() expected adult to be kept\n")
+	}
+	if ct, _ := l.Get("large"); ct == large {
+		t.Errorf("Reload() expected large to be compiled again\n")
+	}
+
+	// Broken files keep the last good version of their tree.
+	writeFile(t, dir, "large.yaml", "Op: and\nNodes: [")
+	writeFile(t, dir, "adult.json", `{"Op": "leaf", "Leaf": "(ge .Age 21)"}`)
+	writeFile(t, dir, "broken.json", `{"Op": "and", "Nodes": []}`)
+	err = l.Reload()
+	if err == nil || !strings.Contains(err.Error(), "large.yaml") || !strings.Contains(err.Error(), "broken.json") {
+		t.Errorf("Reload() expected errors of large.yaml and broken.json, got %v\n", err)
+	}
+	if errs := l.Errors(); len(errs) != 2 || errs["large.yaml"] == nil || errs["broken.json"] == nil {
+		t.Errorf("Errors() expected large.yaml and broken.json, got %v\n", errs)
+	}
+	if names := l.Names(); !reflect.DeepEqual(names, []string{"adult", "large"}) {
+		t.Errorf("Names() expected=[adult large] actual=%v\n", names)
+	}
+	if evaluate(t, l, "adult", data) || !evaluate(t, l, "large", data) {
+		t.Errorf("Evaluate() expected the new adult and the old large\n")
+	}
+
+	// Removed files drop their trees.
+	if err := os.Remove(filepath.Join(dir, "large.yaml")); err != nil {
+		t.Fatalf("Remove() error: %s\n", err.Error())
+	}
+	if err := l.Reload(); err == nil || len(l.Errors()) != 1 {
+		t.Errorf("Reload() expected the error of broken.json, got %v\n", err)
+	}
+	if _, ok := l.Get("large"); ok {
+		t.Errorf("Get(large) expected no tree\n")
+	}
+
+	if _, err := Load(filepath.Join(dir, "missing"), Options{}); err == nil {
+		t.Errorf("Load(missing) expected an error\n")
+	}
+}
+
+// quorum is true if at least the number of its children which is its
+// payload are.
+type quorum struct{}
+
+func (quorum) Evaluate(results []bool) bool { return quorum{}.EvaluatePayload(1, results) }
+
+func (quorum) DecodePayload(data []byte) (interface{}, error) {
+	var k int
+	err := json.Unmarshal(data, &k)
+	return k, err
+}
+
+func (quorum) EvaluatePayload(payload interface{}, results []bool) bool {
+	n := 0
+	for _, r := range results {
+		if r {
+			n++
+		}
+	}
+	return n >= payload.(int)
+}
+
+func TestLoadPayload(t *testing.T) {
+	logictree.RegisterOperator("quorum", quorum{})
+	dir := t.TempDir()
+	writeFile(t, dir, "vote.yaml", "Op: quorum\nPayload: 2\nNodes:\n  - {Op: leaf, Leaf: .A}\n  - {Op: leaf, Leaf: .B}\n  - {Op: leaf, Leaf: .C}\n")
+	l, err := Load(dir, Options{})
+	if err != nil {
+		t.Fatalf("Load() error: %s\n", err.Error())
+	}
+	for _, tc := range []struct {
+		data     map[string]bool
+		expected bool
+	}{
+		{map[string]bool{"A": true, "B": false, "C": true}, true},
+		{map[string]bool{"A": true, "B": false, "C": false}, false},
+	} {
+		if v := evaluate(t, l, "vote", tc.data); v != tc.expected {
+			t.Errorf("Evaluate(%v) expected=%v actual=%v\n", tc.data, tc.expected, v)
+		}
+	}
+}
+
+func TestLoadSigned(t *testing.T) {
+	dir := t.TempDir()
+	key := []byte("secret")
+	signed, err := logictree.SignDocument(logictree.NewLeafNode("ge .Age 18"), logictree.HMACSigner(key))
+	if err != nil {
+		t.Fatalf("SignDocument() error: %s\n", err.Error())
+	}
+	forged, err := logictree.SignDocument(logictree.NewLeafNode("ge .Age 0"), logictree.HMACSigner([]byte("other")))
+	if err != nil {
+		t.Fatalf("SignDocument() error: %s\n", err.Error())
+	}
+	writeFile(t, dir, "adult.json", string(signed))
+	writeFile(t, dir, "forged.json", string(forged))
+	writeFile(t, dir, "unsigned.json", `{"Op": "leaf", "Leaf": "true"}`)
+
+	l, err := Load(dir, Options{Verifier: logictree.HMACVerifier(key)})
+	if errs := l.Errors(); err == nil || len(errs) != 2 || !errors.Is(errs["forged.json"], logictree.ErrInvalidSignature) || !errors.Is(errs["unsigned.json"], logictree.ErrInvalidSignature) {
+		t.Errorf("Load() expected errors of forged.json and unsigned.json, got %v\n", errs)
+	}
+	if names := l.Names(); !reflect.DeepEqual(names, []string{"adult"}) {
+		t.Errorf("Names() expected=[adult] actual=%v\n", names)
+	}
+	if !evaluate(t, l, "adult", map[string]interface{}{"Age": 20}) {
+		t.Errorf("Evaluate(adult) expected=true\n")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "rule.json", `{"Op": "leaf", "Leaf": "(gt .Amount 100)"}`)
+	reloads := make(chan error, 16)
+	l, err := Load(dir, Options{Debounce: 10 * time.Millisecond, OnReload: func(err error) { reloads <- err }})
+	if err != nil {
+		t.Fatalf("Load() error: %s\n", err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- l.Watch(ctx) }()
+
+	data := map[string]interface{}{"Amount": 50}
+	deadline := time.After(5 * time.Second)
+	for !evaluate(t, l, "rule", data) {
+		// The watcher may start after the first write.
+		writeFile(t, dir, "rule.json", `{"Op": "leaf", "Leaf": "(gt .Amount 10)"}`)
+		select {
+		case err := <-reloads:
+			if err != nil {
+				t.Errorf("Watch() reload error: %s\n", err.Error())
+			}
+		case <-time.After(200 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("Watch() did not reload rule.json\n")
+		}
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Watch() expected=%v actual=%v\n", context.Canceled, err)
+	}
+}
@@ -0,0 +1,76 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// The attributes of the records of `WithLogger`.
+const (
+	LogTree        = "tree"        // the name of the tree, unless empty
+	LogFingerprint = "fingerprint" // of the tree, see `Fingerprint`
+	LogInput       = "input"       // the hex encoded SHA-256 of the JSON of the data
+	LogResult      = "result"      // unless the evaluation failed
+	LogDuration    = "duration"    // of the evaluation
+	LogError       = "error"       // if the evaluation failed
+	LogExplain     = "explain"     // the `Explanation` of the result, at debug level
+)
+
+// WithLogger logs every evaluation of the tree by `EvaluateContext` and
+// `Evaluate` to `l`, for the audit of the decisions made, as a record with
+// the same attributes whatever the service: the name of the tree `name`, its
+// fingerprint, a digest of the data, and the result and duration of the
+// evaluation.  Evaluations are logged at the info level, and those which
+// fail at the error level with their error.  If `l` logs the debug level
+// the explanation of the result, see `Explain`, is logged too, at the cost
+// of explaining every evaluation.
+func WithLogger(l *slog.Logger, name string) Option {
+	return func(o *compileOptions) {
+		o.logging = &logging{logger: l, name: name}
+	}
+}
+
+// logging is the logger of a tree, and the name it is logged under.
+type logging struct {
+	logger *slog.Logger
+	name   string
+}
+
+// logEvaluation logs the evaluation of `data` with the result `v` or the
+// error `err`, which took `d`.
+func (ct *CompiledTree) logEvaluation(ctx context.Context, data interface{}, v bool, err error, d time.Duration) {
+	l := ct.opts.logging
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelError
+	}
+	if !l.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, 7)
+	if l.name != "" {
+		attrs = append(attrs, slog.String(LogTree, l.name))
+	}
+	attrs = append(attrs, slog.String(LogFingerprint, ct.fingerprint))
+	if bs, jerr := json.Marshal(data); jerr == nil {
+		attrs = append(attrs, slog.String(LogInput, hexDigest(bs)))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Duration(LogDuration, d), slog.String(LogError, err.Error()))
+	} else {
+		attrs = append(attrs, slog.Bool(LogResult, v), slog.Duration(LogDuration, d))
+		if l.logger.Enabled(ctx, slog.LevelDebug) {
+			if x, xerr := ct.ExplainContext(ctx, data); xerr == nil {
+				attrs = append(attrs, slog.Any(LogExplain, x))
+			}
+		}
+	}
+	l.logger.LogAttrs(ctx, level, "logictree evaluation", attrs...)
+}
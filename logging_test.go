@@ -0,0 +1,80 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestWithLogger(t *testing.T) {
+	tree := NewNode(OperatorAnd, NewLeafNode("gt .A 1"), NewLeafNode("lt .A 5"))
+	records := func(level slog.Level, name string, data ...interface{}) []map[string]interface{} {
+		var buf bytes.Buffer
+		l := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: level}))
+		ct, err := Compile(tree, WithLogger(l, name), WithMissing(MissingIsError))
+		if err != nil {
+			t.Fatalf("Compile() error: %s\n", err.Error())
+		}
+		for _, d := range data {
+			ct.Evaluate(d)
+		}
+		var rs []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			r := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(line), &r); err != nil {
+				t.Fatalf("Unmarshal(%s) error: %s\n", line, err.Error())
+			}
+			rs = append(rs, r)
+		}
+		return rs
+	}
+
+	rs := records(slog.LevelInfo, "discounts", map[string]interface{}{"A": 2}, map[string]interface{}{"A": 2}, map[string]interface{}{"A": 7}, map[string]interface{}{})
+	if len(rs) != 4 {
+		t.Fatalf("Evaluate() expected 4 records, got %d\n", len(rs))
+	}
+	for i, tc := range []struct {
+		level  string
+		result interface{}
+	}{
+		{"INFO", true},
+		{"INFO", true},
+		{"INFO", false},
+		{"ERROR", nil},
+	} {
+		r := rs[i]
+		if r["level"] != tc.level || r[LogResult] != tc.result || r[LogTree] != "discounts" || r[LogFingerprint] != tree.Fingerprint() || r[LogDuration] == nil {
+			t.Errorf("Evaluate(%d) expected=%s %v actual=%v\n", i, tc.level, tc.result, r)
+		}
+		if _, ok := r[LogExplain]; ok {
+			t.Errorf("Evaluate(%d) expected no explanation at the info level\n", i)
+		}
+	}
+	if rs[0][LogInput] != rs[1][LogInput] || rs[0][LogInput] == rs[2][LogInput] {
+		t.Errorf("Evaluate() expected the digests of the same data to be the same, and only those: %v %v %v\n", rs[0][LogInput], rs[1][LogInput], rs[2][LogInput])
+	}
+	if e, _ := rs[3][LogError].(string); !strings.Contains(e, "missing field") {
+		t.Errorf("Evaluate() expected an error record, got %v\n", rs[3])
+	}
+
+	// The debug level has the explanation of the result.
+	rs = records(slog.LevelDebug, "", map[string]interface{}{"A": 7})
+	x, ok := rs[0][LogExplain].(map[string]interface{})
+	if _, named := rs[0][LogTree]; !ok || x["Result"] != false || named {
+		t.Errorf("Evaluate() expected an explanation and no name, got %v\n", rs[0])
+	}
+
+	// Levels which are not logged are not recorded.
+	if rs := records(slog.LevelError, "", map[string]interface{}{"A": 2}); len(rs) != 0 {
+		t.Errorf("Evaluate() expected no records, got %v\n", rs)
+	}
+}
@@ -7,13 +7,35 @@ package logictree
 import (
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"text/template"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
 
 var (
-	ErrEmptyNode = errors.New("empty node cannot be merged")
+	ErrEmptyNode          = errors.New("empty node cannot be merged")
+	ErrNotBoolean         = errors.New("tree did not evaluate to a boolean")
+	ErrInvalidOperator    = errors.New("invalid operator")
+	ErrInvalidPattern     = errors.New("invalid pattern")
+	ErrMissingField       = errors.New("missing field")
+	ErrNotTranslatable    = errors.New("leaf cannot be translated")
+	ErrNotExpression      = errors.New("leaf is not a single expression")
+	ErrInvalidConfig      = errors.New("invalid config")
+	ErrInvalidPatch       = errors.New("invalid patch")
+	ErrNodeNotFound       = errors.New("node not found")
+	ErrLimitExceeded      = errors.New("limit exceeded")
+	ErrUnsupportedVersion = errors.New("unsupported document version")
+	ErrUnresolvedRef      = errors.New("unresolved reference")
+	ErrCycle              = errors.New("reference cycle")
+	ErrInvalidParam       = errors.New("invalid parameter")
+	ErrNoSolution         = errors.New("no data found")
+	ErrFuncNotAllowed     = errors.New("function not allowed")
+	ErrBudgetExceeded     = errors.New("evaluation budget exceeded")
+	ErrTampered           = errors.New("record tampered with")
+	ErrInvalidSignature   = errors.New("invalid signature")
+	ErrSinkClosed         = errors.New("sink closed")
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -22,23 +44,55 @@ var (
 type Operator string
 
 const (
-	OperatorLeaf = "leaf"
-	OperatorAnd  = "and"
-	OperatorOr   = "or"
+	OperatorLeaf     = "leaf"
+	OperatorAnd      = "and"
+	OperatorOr       = "or"
+	OperatorAdvanced = "advanced" // a leaf holding a complete template, see `NewAdvancedLeafNode`
+	OperatorIf       = "if"       // a condition and the children it chooses between, see `NewIfNode`
+	OperatorSwitch   = "switch"   // cases chosen between by the value of a field, see `NewSwitchNode`
+	OperatorCase     = "case"     // a case of a switch, see `NewCaseNode`
+	OperatorRef      = "ref"      // a reference to a named tree, see `NewRefNode`
 )
 
 func (o Operator) String() string {
 	switch o {
-	case OperatorLeaf, OperatorAnd, OperatorOr:
+	case OperatorLeaf, OperatorAnd, OperatorOr, OperatorAdvanced, OperatorIf, OperatorSwitch, OperatorCase, OperatorRef:
 		return string(o)
 	default:
+		if isCustom(o) {
+			return string(o)
+		}
 		panic("invalid operator type")
 	}
 }
 
+// MarshalText encodes the operator as its name, so that operators can be
+// used with any encoder supporting `encoding.TextMarshaler`, such as YAML,
+// TOML and gob, and as map keys.  Unknown operators are encoded as they are,
+// as they are by JSON, and left for `Validate` to reject.
+func (o Operator) MarshalText() ([]byte, error) {
+	return []byte(o), nil
+}
+
+// UnmarshalText decodes an operator encoded by `MarshalText`, accepting the
+// aliases `&&` and `||`, and the names of the operators in any case, as the
+// operators they stand for.
+func (o *Operator) UnmarshalText(text []byte) error {
+	*o = normalizeOperator(string(text))
+	return nil
+}
+
 // Apply combines the number of `exprs` into a evaluate-able string combining
-// the expressions using the specified operator.
+// the expressions using the specified operator.  Registered operators, see
+// `RegisterOperator`, call their template function with every expression, and
+// `if` chooses between its second and third expressions with its first.
 func (o Operator) Apply(exprs []string) string {
+	if len(exprs) > 0 && isCustom(o) {
+		return o.String() + " (" + strings.Join(exprs, ") (") + ")"
+	}
+	if o == OperatorIf && len(exprs) == 3 {
+		return fmt.Sprintf("or (and (%s) (%s)) (and (not (%s)) (%s))", exprs[0], exprs[1], exprs[0], exprs[2])
+	}
 	switch len(exprs) {
 	case 0:
 		return ""
@@ -79,37 +133,130 @@ func NewLeafNode(expr string) *Node {
 	}
 }
 
-// Combine merges this node with any of its children (evaluated).
+// NewAdvancedLeafNode returns a leaf holding the complete template `src`,
+// including its own delimiters, for the cases a single expression cannot
+// express.  Unlike ordinary leaves it may declare variables, pipe the results
+// of functions into one another and use actions such as `if` and `range`,
+// for example:
+//
+//	{{ $n := len .Tags }}{{ if gt $n 0 }}{{ index .Tags 0 | eq "new" }}{{ else }}false{{ end }}
+//
+// The template is parsed on its own and must render "true" or "false".
+// Advanced leaves can only be evaluated by compiling the tree; since their
+// result depends on more than their fields they are never cached or decided
+// ahead of time, and they cannot be translated into queries.
+func NewAdvancedLeafNode(src string) *Node {
+	return &Node{
+		Op:   OperatorAdvanced,
+		Leaf: src,
+	}
+}
+
+// isLeaf reports whether the node is a leaf of either kind.
+func (n *Node) isLeaf() bool {
+	return n.Op == OperatorLeaf || n.Op == OperatorAdvanced
+}
+
+// Combine merges this node with any of its children (evaluated).  Errors are
+// `*NodeError`s locating the offending node.
 func (n *Node) Combine() (string, error) {
+	return n.combine("/")
+}
+
+// combine is `Combine` of the node at `path`.
+func (n *Node) combine(path string) (string, error) {
 	// If we are a leaf node, we just return our expression.
 	if n.Op == OperatorLeaf {
-		return n.Leaf, nil
+		return expandMacros(n.Leaf), nil
+	}
+	if n.Op == OperatorAdvanced {
+		return "", nodeError(path, n, fmt.Errorf("%w: advanced leaves cannot be combined into one template", ErrNotExpression))
+	}
+	if n.Op == OperatorRef {
+		return "", nodeError(path, n, fmt.Errorf("%w: %q", ErrUnresolvedRef, n.Leaf))
 	}
 
 	if len(n.Nodes) == 0 {
-		return "", ErrEmptyNode
+		return "", nodeError(path, n, ErrEmptyNode)
+	}
+	if n.Op == OperatorIf && len(n.Nodes) != 3 {
+		return "", nodeError(path, n, fmt.Errorf("%w: if needs exactly three children", ErrInvalidOperator))
+	}
+	switch n.Op {
+	case OperatorSwitch:
+		if err := n.validateSwitch(path, false); err != nil {
+			return "", err
+		}
+		return expandSwitch(n).combine(path)
+	case OperatorCase:
+		return "", nodeError(path, n, fmt.Errorf("%w: case nodes must be children of switch nodes", ErrInvalidOperator))
 	}
 
 	exprs := []string{}
-	for _, tm := range n.Nodes {
-		e, err := tm.Combine()
+	for i, tm := range n.Nodes {
+		e, err := tm.combine(childPath(path, i))
 		if err != nil {
 			return "", err
 		}
 		exprs = append(exprs, e)
 	}
 
+	if _, ok := payloadOperator(n.Op); ok && len(exprs) > 0 {
+		// The template functions of payload operators take the payload
+		// first, see `withOperators`.
+		if _, err := bindOperator(n); err != nil {
+			return "", nodeError(path, n, err)
+		}
+		return string(n.Op) + " " + strconv.Quote(n.Leaf) + " (" + strings.Join(exprs, ") (") + ")", nil
+	}
 	return n.Op.Apply(exprs), nil
 }
 
 // GetTemplate squashes the tree down from the root down into a single template
 // expression.  The only argument is the `template.FuncMap` to use for custom
 // functions.
+//
+// Templates are parsed once per distinct tree and set of function names, and
+// cached, so that calling `GetTemplate` again, from any goroutine or after
+// the tree is modified, only combines the tree and clones the template; each
+// call returns a template of its own, with the functions of `fm`.  Leaves
+// which do not parse fail with a `*ParseError` of the leaf, see `Compile`,
+// rather than with an error within the combined template.
 func (n *Node) GetTemplate(fm template.FuncMap) (*template.Template, error) {
 	e, err := n.Combine()
 	if err != nil {
 		return nil, err
 	}
 
-	return template.Must(template.New("tree").Funcs(fm).Parse("{{ " + e + " }}")), nil
+	fm = withOperators(fm)
+	tmpl, err := templates.get(e, fm)
+	if err != nil {
+		// Errors within the combined template are located in its leaves.
+		placeholders := make(template.FuncMap, len(fm))
+		for name := range fm {
+			placeholders[name] = func() bool { return false }
+		}
+		if lerr := n.leafParseErrors("/", placeholders); lerr != nil {
+			return nil, lerr
+		}
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// leafParseErrors returns the errors of the leaves of the tree rooted at `n`,
+// at `path`, which do not parse with the functions `fm`, see
+// `leafParseError`.
+func (n *Node) leafParseErrors(path string, fm template.FuncMap) error {
+	if n.isLeaf() {
+		if err := leafParseError(n, fm); err != nil {
+			return nodeError(path, n, err)
+		}
+		return nil
+	}
+	var errs []error
+	for i, c := range n.Nodes {
+		errs = append(errs, c.leafParseErrors(childPath(path, i), fm))
+	}
+	return joinErrors(errs...)
 }
@@ -3,8 +3,11 @@ package logictree
 ////////////////////////////////////////////////////////////////////////////////
 
 import (
+	"bytes"
+	"encoding/gob"
 	"fmt"
 	"os"
+	"reflect"
 	"testing"
 )
 
@@ -18,14 +21,14 @@ func TestLeafMerge(t *testing.T) {
 		{"1", "(1)"},
 		{"a and b", "(a and b)"},
 	} {
-		l := NewLeaf(tc.expr)
-		e, err := l.Merge()
+		l := NewLeafNode(tc.expr)
+		e, err := l.Combine()
 		if err != nil {
-			t.Errorf("Leaf::Merge() error: %s\n", err.Error())
+			t.Errorf("Node::Combine() error: %s\n", err.Error())
 		}
 
 		if e != tc.expected {
-			t.Errorf("Leaf::Merge() expected=%s actual=%s\n", tc.expected, e)
+			t.Errorf("Node::Combine() expected=%s actual=%s\n", tc.expected, e)
 		}
 	}
 }
@@ -33,32 +36,26 @@ func TestLeafMerge(t *testing.T) {
 ////////////////////////////////////////////////////////////////////////////////
 
 func TestTreeConstruction(t *testing.T) {
-	tree := &Node{
-		op: cOperatorAnd,
-		leaves: []TreeMerger{
-			NewLeaf("gt 1 0"),
-			NewLeaf("gt 2 0"),
-			NewLeaf("gt 3 0"),
-			NewLeaf("gt 4 2"),
-			&Node{
-				op: cOperatorOr,
-				leaves: []TreeMerger{
-					NewLeaf("gt 1 10"),
-					NewLeaf("gt 2 10"),
-					NewLeaf("gt 3 10"),
-					NewLeaf("gt 40 2"),
-				},
-			},
-		},
-	}
+	tree := NewNode(OperatorAnd,
+		NewLeafNode("gt 1 0"),
+		NewLeafNode("gt 2 0"),
+		NewLeafNode("gt 3 0"),
+		NewLeafNode("gt 4 2"),
+		NewNode(OperatorOr,
+			NewLeafNode("gt 1 10"),
+			NewLeafNode("gt 2 10"),
+			NewLeafNode("gt 3 10"),
+			NewLeafNode("gt 40 2"),
+		),
+	)
 
-	s, err := tree.Merge()
+	s, err := tree.Combine()
 	if err != nil {
-		t.Errorf("Merge() failed with error: %s\n", err.Error())
+		t.Errorf("Combine() failed with error: %s\n", err.Error())
 	}
-	fmt.Printf("MERGE: %s\n", s)
+	fmt.Printf("COMBINE: %s\n", s)
 
-	tmpl, err := tree.GetTemplate()
+	tmpl, err := tree.GetTemplate(nil)
 	if err != nil {
 		t.Errorf("GetTemplate() failed with error: %s\n", err.Error())
 	}
@@ -68,3 +65,35 @@ func TestTreeConstruction(t *testing.T) {
 }
 
 ////////////////////////////////////////////////////////////////////////////////
+
+func TestOperatorText(t *testing.T) {
+	for _, op := range []Operator{OperatorLeaf, OperatorAnd, OperatorOr, OperatorAdvanced, "xor"} {
+		bs, err := op.MarshalText()
+		if err != nil || string(bs) != string(op) {
+			t.Errorf("MarshalText(%q) expected=%q actual=%q (%v)\n", string(op), string(op), bs, err)
+		}
+		var actual Operator
+		if err := actual.UnmarshalText(bs); err != nil || actual != op {
+			t.Errorf("UnmarshalText(%q) expected=%q actual=%q (%v)\n", bs, string(op), string(actual), err)
+		}
+	}
+}
+
+func TestGob(t *testing.T) {
+	expected := NewNode(OperatorOr,
+		NewNode(OperatorAnd, NewLeafNode("ge .Milk 4"), NewLeafNode("le .Milk 6")),
+		NewAdvancedLeafNode("{{ true }}"),
+		NewNode(OperatorAnd))
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(expected); err != nil {
+		t.Fatalf("Encode() error: %s\n", err.Error())
+	}
+	var actual *Node
+	if err := gob.NewDecoder(&buf).Decode(&actual); err != nil {
+		t.Fatalf("Decode() error: %s\n", err.Error())
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Decode() expected=%#v actual=%#v\n", expected, actual)
+	}
+}
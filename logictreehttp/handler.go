@@ -0,0 +1,312 @@
+// Package logictreehttp serves the trees of a `logictree.Registry` over HTTP,
+// for the thin services which store rules and evaluate data against them:
+//
+//	GET    /trees                      the names of the trees, as a JSON array
+//	GET    /trees?fingerprints=true    the fingerprints of the trees by name, as a JSON object
+//	GET    /trees/{name}               the tree, as a `logictree.Document`
+//	PUT    /trees/{name}               stores the tree of the body
+//	DELETE /trees/{name}               removes the tree
+//	POST   /trees/{name}/evaluate      evaluates the tree against the body
+//
+// Trees are uploaded as `logictree.Document`s of any version or as bare trees,
+// and must compile, with their references resolved to the other trees of the
+// registry.  Evaluations take a JSON document as their data and respond with
+// an `EvaluateResponse`, which holds the `logictree.Explanation` of the
+// result with `?explain=true`.  Errors respond with an `ErrorResponse` and
+// the status of their cause: 400 for invalid trees or data, 404 for unknown
+// trees, 405 for unknown methods, 413 for bodies beyond `Options.MaxBytes`
+// and 422 for evaluations which fail.
+//
+// Trees are served with the weak ETag of their fingerprint, see
+// `logictree.Registry.Fingerprint`, and requests whose If-None-Match holds it
+// respond with 304, so that consumers polling the handler only fetch and
+// compile the trees which changed.
+package logictreehttp
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// DefaultMaxBytes is the size of the largest body accepted when
+// `Options.MaxBytes` is zero.
+const DefaultMaxBytes = 1 << 20
+
+// Options configures a `Handler`.
+type Options struct {
+	// Compile are the options trees are compiled with, both to check an
+	// upload before it is registered and to evaluate a registered tree.
+	Compile []logictree.Option
+
+	// Decode limits the trees uploaded, `logictree.DefaultDecodeOptions`
+	// if zero.
+	Decode logictree.DecodeOptions
+
+	// MaxBytes is the size of the largest body accepted.
+	MaxBytes int64
+
+	// ReadOnly rejects uploads and deletions with 405.
+	ReadOnly bool
+}
+
+// EvaluateResponse is the response to an evaluation.
+type EvaluateResponse struct {
+	Result      bool                   `json:"Result"`
+	Explanation *logictree.Explanation `json:"Explanation,omitempty"`
+}
+
+// ErrorResponse is the response to a request which failed.
+type ErrorResponse struct {
+	Error string `json:"Error"`
+}
+
+// Handler is an `http.Handler` serving the trees of a registry.  Trees are
+// compiled as they are first evaluated and kept compiled until a tree is
+// uploaded or deleted through the handler; changes made to the registry
+// otherwise must be followed by `Reset`.
+type Handler struct {
+	reg  *logictree.Registry
+	opts Options
+
+	mu       sync.Mutex
+	compiled map[string]*logictree.CompiledTree
+	gen      int // incremented by every `Reset`
+}
+
+// NewHandler returns a handler serving the trees of `reg`, or of a new
+// registry if it is nil.  Trees are compiled with the policy of the registry,
+// see `logictree.Registry.SetPolicy`, so that a handler of a namespace, see
+// `logictree.Registry.Namespace`, serves a tenant of a shared service.
+func NewHandler(reg *logictree.Registry, o Options) *Handler {
+	if reg == nil {
+		reg = &logictree.Registry{}
+	}
+	if o.Decode == (logictree.DecodeOptions{}) {
+		o.Decode = logictree.DefaultDecodeOptions
+	}
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = DefaultMaxBytes
+	}
+	return &Handler{reg: reg, opts: o, compiled: map[string]*logictree.CompiledTree{}}
+}
+
+// Registry returns the registry of the handler.
+func (h *Handler) Registry() *logictree.Registry {
+	return h.reg
+}
+
+// Reset drops the compiled trees, so that they are compiled again from the
+// registry.
+func (h *Handler) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.compiled = map[string]*logictree.CompiledTree{}
+	h.gen++
+}
+
+// ServeHTTP routes the request to its endpoint.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "trees" || len(parts) > 3 || len(parts) == 3 && parts[2] != "evaluate" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no endpoint %s", r.URL.Path))
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, h.opts.MaxBytes)
+
+	switch {
+	case len(parts) == 1 && r.Method == http.MethodGet && r.URL.Query().Get("fingerprints") == "true":
+		writeJSON(w, http.StatusOK, h.reg.Fingerprints())
+	case len(parts) == 1 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, h.reg.List())
+	case len(parts) == 1:
+		writeMethodNotAllowed(w, http.MethodGet)
+	case len(parts) == 3 && r.Method == http.MethodPost:
+		h.evaluate(w, r, parts[1])
+	case len(parts) == 3:
+		writeMethodNotAllowed(w, http.MethodPost)
+	case r.Method == http.MethodGet:
+		h.get(w, r, parts[1])
+	case r.Method == http.MethodPut && !h.opts.ReadOnly:
+		h.put(w, r, parts[1])
+	case r.Method == http.MethodDelete && !h.opts.ReadOnly:
+		h.delete(w, parts[1])
+	case h.opts.ReadOnly:
+		writeMethodNotAllowed(w, http.MethodGet)
+	default:
+		writeMethodNotAllowed(w, http.MethodGet, http.MethodPut, http.MethodDelete)
+	}
+}
+
+// get responds with the tree `name`, unless the request holds its ETag.
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, name string) {
+	fp, _ := h.reg.Fingerprint(name)
+	n, ok := h.reg.Get(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no tree named %q", name))
+		return
+	}
+	etag := `W/"` + fp + `"`
+	w.Header().Set("ETag", etag)
+	if matchesETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeJSON(w, http.StatusOK, logictree.Document{Version: logictree.FormatVersion, Tree: n})
+}
+
+// put stores the tree of the body of `r` as `name`, if it compiles.
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, name string) {
+	data, err := readBody(r)
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	n, err := h.opts.Decode.UnmarshalDocument(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	// The tree is compiled with the others before it replaces its previous
+	// version, so that a broken upload leaves the registry as it was.
+	if _, err := h.reg.CompileAs(name, n, h.opts.Compile...); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := h.reg.Register(name, n); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.Reset()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// delete removes the tree `name`.
+func (h *Handler) delete(w http.ResponseWriter, name string) {
+	if !h.reg.Delete(name) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no tree named %q", name))
+		return
+	}
+	h.Reset()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// evaluate evaluates the tree `name` against the body of `r`.
+func (h *Handler) evaluate(w http.ResponseWriter, r *http.Request, name string) {
+	ct, status, err := h.compile(name)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+	body, err := readBody(r)
+	if err != nil {
+		writeBodyError(w, err)
+		return
+	}
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid data: %w", err))
+		return
+	}
+
+	explain, _ := strconv.ParseBool(r.URL.Query().Get("explain"))
+	var res EvaluateResponse
+	if explain {
+		res.Explanation, err = ct.ExplainContext(r.Context(), data)
+		if err == nil {
+			res.Result = res.Explanation.Result
+		}
+	} else {
+		res.Result, err = ct.EvaluateContext(r.Context(), data)
+	}
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// compile returns the compiled tree `name`, or the status and error of its
+// failure.
+func (h *Handler) compile(name string) (*logictree.CompiledTree, int, error) {
+	h.mu.Lock()
+	ct, ok := h.compiled[name]
+	gen := h.gen
+	h.mu.Unlock()
+	if ok {
+		return ct, 0, nil
+	}
+
+	if _, ok := h.reg.Get(name); !ok {
+		return nil, http.StatusNotFound, fmt.Errorf("no tree named %q", name)
+	}
+	ct, err := h.reg.Compile(name, h.opts.Compile...)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	// A tree compiled while the registry changed may be stale.
+	h.mu.Lock()
+	if h.gen == gen {
+		h.compiled[name] = ct
+	}
+	h.mu.Unlock()
+	return ct, 0, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// matchesETag reports whether the If-None-Match header `header` holds
+// `etag`, compared weakly.
+func matchesETag(header, etag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// readBody reads the body of `r`.
+func readBody(r *http.Request) ([]byte, error) {
+	return io.ReadAll(r.Body)
+}
+
+// writeBodyError responds with the error of reading a body.
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+	writeError(w, http.StatusBadRequest, err)
+}
+
+// writeMethodNotAllowed responds to a method other than `allowed`.
+func writeMethodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method not allowed"))
+}
+
+// writeError responds with `err` and `status`.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
+
+// writeJSON responds with `v` encoded as JSON and `status`.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,176 @@
+package logictreehttp
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func do(t *testing.T, h http.Handler, method, path, body string) (int, string) {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code, strings.TrimSpace(w.Body.String())
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(nil, Options{Compile: []logictree.Option{logictree.WithFuncs(logictree.StdFuncs())}, MaxBytes: 256})
+
+	for _, tc := range []struct {
+		method, path, body string
+		status             int
+		response           string
+	}{
+		{"PUT", "/trees/adult", `{"Op": "leaf", "Leaf": "(ge .Age 18)"}`, http.StatusNoContent, ""},
+		{"PUT", "/trees/large", `{"Version": 1, "Tree": {"Op": "and", "Nodes": [{"Ref": "adult"}, {"Op": "leaf", "Leaf": "(gt .Amount 100)"}]}}`, http.StatusNoContent, ""},
+		{"GET", "/trees", "", http.StatusOK, `["adult","large"]`},
+		{"GET", "/trees/adult", "", http.StatusOK, `{"Version":1,"Tree":{"Op":"leaf","Leaf":"(ge .Age 18)"}}`},
+		{"POST", "/trees/large/evaluate", `{"Age": 20, "Amount": 150}`, http.StatusOK, `{"Result":true}`},
+		{"POST", "/trees/large/evaluate", `{"Age": 16, "Amount": 150}`, http.StatusOK, `{"Result":false}`},
+
+		// Broken uploads leave the tree as it was.
+		{"PUT", "/trees/adult", `{"Op": "and", "Nodes": [{"Ref": "adult"}]}`, http.StatusBadRequest, ""},
+		{"PUT", "/trees/adult", `{"Op": "leaf", "Leaf": "(ge .Age"}`, http.StatusBadRequest, ""},
+		{"PUT", "/trees/adult", `{"Op": "leaf"`, http.StatusBadRequest, ""},
+		{"PUT", "/trees/adult", `{"Op": "leaf", "Leaf": "(` + strings.Repeat("x", 300) + `)"}`, http.StatusRequestEntityTooLarge, ""},
+		{"POST", "/trees/large/evaluate", `{"Age": 20, "Amount": 150}`, http.StatusOK, `{"Result":true}`},
+
+		// Uploads replace the compiled trees referencing them.
+		{"PUT", "/trees/adult", `{"Op": "leaf", "Leaf": "(ge .Age 21)"}`, http.StatusNoContent, ""},
+		{"POST", "/trees/large/evaluate", `{"Age": 20, "Amount": 150}`, http.StatusOK, `{"Result":false}`},
+
+		{"POST", "/trees/large/evaluate", `{"Age": 20`, http.StatusBadRequest, ""},
+		{"POST", "/trees/large/evaluate", `{"Age": "x", "Amount": 150}`, http.StatusUnprocessableEntity, ""},
+		{"POST", "/trees/missing/evaluate", `{}`, http.StatusNotFound, ""},
+		{"GET", "/trees/missing", "", http.StatusNotFound, ""},
+		{"GET", "/other", "", http.StatusNotFound, ""},
+		{"PATCH", "/trees/adult", "", http.StatusMethodNotAllowed, ""},
+		{"GET", "/trees/adult/evaluate", "", http.StatusMethodNotAllowed, ""},
+
+		{"DELETE", "/trees/adult", "", http.StatusNoContent, ""},
+		{"DELETE", "/trees/adult", "", http.StatusNotFound, ""},
+		{"POST", "/trees/large/evaluate", `{"Age": 20, "Amount": 150}`, http.StatusBadRequest, ""},
+	} {
+		status, res := do(t, h, tc.method, tc.path, tc.body)
+		if status != tc.status || tc.response != "" && res != tc.response {
+			t.Errorf("%s %s expected=%d %s actual=%d %s\n", tc.method, tc.path, tc.status, tc.response, status, res)
+		}
+		if status >= 400 {
+			var e ErrorResponse
+			if err := json.Unmarshal([]byte(res), &e); err != nil || e.Error == "" {
+				t.Errorf("%s %s expected an error response, got %s\n", tc.method, tc.path, res)
+			}
+		}
+	}
+}
+
+func TestHandlerExplain(t *testing.T) {
+	var reg logictree.Registry
+	if err := reg.Register("rule", logictree.NewNode(logictree.OperatorAnd, logictree.NewLeafNode(".A"), logictree.NewLeafNode(".B"))); err != nil {
+		t.Fatalf("Register() error: %s\n", err.Error())
+	}
+	h := NewHandler(&reg, Options{ReadOnly: true})
+
+	status, body := do(t, h, "POST", "/trees/rule/evaluate?explain=true", `{"A": true, "B": false}`)
+	var res EvaluateResponse
+	if err := json.Unmarshal([]byte(body), &res); status != http.StatusOK || err != nil || res.Result || res.Explanation == nil || len(res.Explanation.Flips) != 1 {
+		t.Errorf("POST /trees/rule/evaluate?explain=true expected an explanation, got %d %s\n", status, body)
+	}
+	if status, _ := do(t, h, "PUT", "/trees/rule", `{"Op": "leaf", "Leaf": "(true)"}`); status != http.StatusMethodNotAllowed {
+		t.Errorf("PUT /trees/rule expected=%d actual=%d\n", http.StatusMethodNotAllowed, status)
+	}
+
+	// Trees changed in the registry are seen after a reset.
+	if err := reg.Register("rule", logictree.NewLeafNode(".B")); err != nil {
+		t.Fatalf("Register() error: %s\n", err.Error())
+	}
+	h.Reset()
+	if _, body := do(t, h, "POST", "/trees/rule/evaluate", `{"A": true, "B": true}`); body != `{"Result":true}` {
+		t.Errorf("POST /trees/rule/evaluate expected=%s actual=%s\n", `{"Result":true}`, body)
+	}
+}
+
+func TestHandlerETag(t *testing.T) {
+	var reg logictree.Registry
+	if err := reg.Register("adult", logictree.NewLeafNode("ge .Age 18")); err != nil {
+		t.Fatalf("Register() error: %s\n", err.Error())
+	}
+	h := NewHandler(&reg, Options{})
+	get := func(etag string) (int, string) {
+		r := httptest.NewRequest("GET", "/trees/adult", nil)
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code, w.Header().Get("ETag")
+	}
+
+	fp, _ := reg.Fingerprint("adult")
+	status, etag := get("")
+	if status != http.StatusOK || etag != `W/"`+fp+`"` {
+		t.Errorf("GET /trees/adult expected=200 %s actual=%d %s\n", fp, status, etag)
+	}
+	for _, tc := range []struct {
+		header string
+		status int
+	}{
+		{etag, http.StatusNotModified},
+		{`"` + fp + `"`, http.StatusNotModified},
+		{`"other", ` + etag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`"other"`, http.StatusOK},
+	} {
+		if status, _ := get(tc.header); status != tc.status {
+			t.Errorf("GET /trees/adult If-None-Match: %s expected=%d actual=%d\n", tc.header, tc.status, status)
+		}
+	}
+
+	if _, body := do(t, h, "GET", "/trees?fingerprints=true", ""); body != `{"adult":"`+fp+`"}` {
+		t.Errorf("GET /trees?fingerprints=true expected=%s actual=%s\n", fp, body)
+	}
+
+	// Trees which change change ETag.
+	if status, _ := do(t, h, "PUT", "/trees/adult", `{"Op": "leaf", "Leaf": "(ge .Age 21)"}`); status != http.StatusNoContent {
+		t.Fatalf("PUT /trees/adult expected=%d actual=%d\n", http.StatusNoContent, status)
+	}
+	if status, next := get(etag); status != http.StatusOK || next == etag {
+		t.Errorf("GET /trees/adult expected=200 and a new ETag, got %d %s\n", status, next)
+	}
+}
+
+func TestHandlerNamespace(t *testing.T) {
+	var reg logictree.Registry
+	acme := reg.Namespace("acme")
+	if err := acme.SetPolicy(logictree.Policy{Macros: map[string]string{"isAdult": "ge .Age 18"}, AllowedFuncs: []string{"ge", "gt"}}); err != nil {
+		t.Fatalf("SetPolicy() error: %s\n", err.Error())
+	}
+	if err := reg.Register("base", logictree.NewLeafNode("true")); err != nil {
+		t.Fatalf("Register() error: %s\n", err.Error())
+	}
+	h := NewHandler(acme, Options{})
+
+	for _, tc := range []struct {
+		method, path, body string
+		status             int
+		response           string
+	}{
+		{"PUT", "/trees/adult", `{"Op": "leaf", "Leaf": "(isAdult)"}`, http.StatusNoContent, ""},
+		{"POST", "/trees/adult/evaluate", `{"Age": 20}`, http.StatusOK, `{"Result":true}`},
+		{"PUT", "/trees/other", `{"Op": "leaf", "Leaf": "(lt .Age 18)"}`, http.StatusBadRequest, ""},
+		{"PUT", "/trees/other", `{"Ref": "base"}`, http.StatusBadRequest, ""},
+		{"GET", "/trees", "", http.StatusOK, `["adult"]`},
+	} {
+		if status, res := do(t, h, tc.method, tc.path, tc.body); status != tc.status || tc.response != "" && res != tc.response {
+			t.Errorf("%s %s expected=%d %s actual=%d %s\n", tc.method, tc.path, tc.status, tc.response, status, res)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+// Package logictreeotel traces the evaluations of logictree trees with
+// OpenTelemetry, so that rule evaluation shows in distributed traces:
+//
+//	tracer := logictreeotel.NewTracer(otel.Tracer("rules"))
+//	ct, err := logictree.Compile(tree, logictree.WithTracer(tracer, 1))
+//	ok, err := ct.EvaluateContext(ctx, data)
+//
+// Each evaluation is a `logictree.SpanEvaluate` span, child of the span of
+// the context it is given, with the attributes of `logictree.WithTracer`.
+// Evaluations which fail record their error and have the error status.
+package logictreeotel
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NewTracer returns the `logictree.Tracer` starting the spans of evaluations
+// with `t`.
+func NewTracer(t trace.Tracer) logictree.Tracer {
+	return tracer{t}
+}
+
+type tracer struct {
+	t trace.Tracer
+}
+
+func (t tracer) Start(ctx context.Context, name string) (context.Context, logictree.Span) {
+	ctx, s := t.t.Start(ctx, name)
+	return ctx, span{s}
+}
+
+type span struct {
+	s trace.Span
+}
+
+// SetAttribute sets the attribute of a string, bool or int value as such,
+// and one of any other value as its text.
+func (s span) SetAttribute(key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		s.s.SetAttributes(attribute.String(key, v))
+	case bool:
+		s.s.SetAttributes(attribute.Bool(key, v))
+	case int:
+		s.s.SetAttributes(attribute.Int(key, v))
+	default:
+		s.s.SetAttributes(attribute.String(key, fmt.Sprint(v)))
+	}
+}
+
+func (s span) End(err error) {
+	if err != nil {
+		s.s.RecordError(err)
+		s.s.SetStatus(codes.Error, err.Error())
+	}
+	s.s.End()
+}
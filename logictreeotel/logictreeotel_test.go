@@ -0,0 +1,65 @@
+package logictreeotel
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestTracer(t *testing.T) {
+	rec := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec))
+	tracer := NewTracer(tp.Tracer("test"))
+
+	tree := logictree.NewNode(logictree.OperatorAnd, logictree.NewLeafNode("eq .A 1"), logictree.NewLeafNode("eq .B 1"))
+	ct, err := logictree.Compile(tree, logictree.WithTracer(tracer, 1), logictree.WithMissing(logictree.MissingIsError))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	if v, err := ct.EvaluateContext(ctx, map[string]interface{}{"A": 1, "B": 1}); err != nil || !v {
+		t.Fatalf("EvaluateContext() expected=true actual=%v %v\n", v, err)
+	}
+	parent.End()
+
+	spans := rec.Ended()
+	if len(spans) != 4 {
+		t.Fatalf("EvaluateContext() expected 4 spans, got %d\n", len(spans))
+	}
+	eval := spans[2]
+	if eval.Name() != logictree.SpanEvaluate || eval.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("EvaluateContext() expected a %s span child of the request\n", logictree.SpanEvaluate)
+	}
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range eval.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	if attrs[logictree.AttrFingerprint].AsString() != tree.Fingerprint() || !attrs[logictree.AttrResult].AsBool() || attrs[logictree.AttrLeaves].AsInt64() != 2 {
+		t.Errorf("EvaluateContext() expected the fingerprint, result and leaves, got %v\n", eval.Attributes())
+	}
+	for _, s := range spans[:2] {
+		if s.Name() != logictree.SpanNode || s.Parent().SpanID() != eval.SpanContext().SpanID() {
+			t.Errorf("EvaluateContext() expected %s spans children of the evaluation, got %s\n", logictree.SpanNode, s.Name())
+		}
+	}
+
+	// Evaluations which fail have the error status.
+	if _, err := ct.Evaluate(map[string]interface{}{}); err == nil {
+		t.Fatalf("Evaluate() expected an error\n")
+	}
+	spans = rec.Ended()
+	if last := spans[len(spans)-1]; last.Status().Code != codes.Error || len(last.Events()) != 1 {
+		t.Errorf("Evaluate() expected an error status and event, got %v %v\n", last.Status(), last.Events())
+	}
+}
@@ -0,0 +1,121 @@
+// Package logictreepb is the protocol buffer representation of logictree
+// trees, defined by logictree.proto, for sending rule definitions between
+// services over gRPC rather than as JSON strings.
+//
+// Regenerate logictree.pb.go after editing logictree.proto with:
+//
+//	protoc --go_out=. --go_opt=paths=source_relative logictree.proto
+package logictreepb
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+var (
+	toProto = map[logictree.Operator]Operator{
+		logictree.OperatorLeaf:     Operator_OPERATOR_LEAF,
+		logictree.OperatorAnd:      Operator_OPERATOR_AND,
+		logictree.OperatorOr:       Operator_OPERATOR_OR,
+		logictree.OperatorAdvanced: Operator_OPERATOR_ADVANCED,
+	}
+	fromProto = map[Operator]logictree.Operator{}
+)
+
+func init() {
+	for k, v := range toProto {
+		fromProto[v] = k
+	}
+}
+
+// ToProto converts the tree rooted at `n` to its protocol buffer form.
+// Registered operators are carried by name, as OPERATOR_REGISTERED nodes.
+// Trees with other operators which have no protocol buffer equivalent fail
+// with an error wrapping `logictree.ErrInvalidOperator`, prefixed with the
+// path of the offending node.
+func ToProto(n *logictree.Node) (*Node, error) {
+	return toNode(n, "/")
+}
+
+func toNode(n *logictree.Node, path string) (*Node, error) {
+	var p *Node
+	if op, ok := toProto[n.Op]; ok {
+		p = &Node{Op: op, Leaf: n.Leaf}
+	} else if logictree.IsRegisteredOperator(n.Op) {
+		p = &Node{Op: Operator_OPERATOR_REGISTERED, Leaf: n.Leaf, Name: string(n.Op)}
+	} else {
+		return nil, fmt.Errorf("%s: %w: %q", path, logictree.ErrInvalidOperator, string(n.Op))
+	}
+	for i, c := range n.Nodes {
+		pc, err := toNode(c, childPath(path, i))
+		if err != nil {
+			return nil, err
+		}
+		p.Nodes = append(p.Nodes, pc)
+	}
+	return p, nil
+}
+
+// FromProto converts the protocol buffer form of a tree back into a tree.
+// Unspecified or unknown operators, and OPERATOR_REGISTERED nodes naming
+// operators which are not registered in this program, fail as for `ToProto`.
+func FromProto(p *Node) (*logictree.Node, error) {
+	return fromNode(p, "/")
+}
+
+func fromNode(p *Node, path string) (*logictree.Node, error) {
+	op, ok := fromProto[p.GetOp()]
+	if p.GetOp() == Operator_OPERATOR_REGISTERED {
+		op = logictree.Operator(p.GetName())
+		if !logictree.IsRegisteredOperator(op) {
+			return nil, fmt.Errorf("%s: %w: %q is not registered", path, logictree.ErrInvalidOperator, p.GetName())
+		}
+	} else if !ok {
+		return nil, fmt.Errorf("%s: %w: %s", path, logictree.ErrInvalidOperator, p.GetOp())
+	}
+	n := &logictree.Node{Op: op, Leaf: p.GetLeaf()}
+	for i, pc := range p.GetNodes() {
+		c, err := fromNode(pc, childPath(path, i))
+		if err != nil {
+			return nil, err
+		}
+		n.Nodes = append(n.Nodes, c)
+	}
+	return n, nil
+}
+
+// ToProtoDocument converts the tree rooted at `n` to a document of the
+// current `logictree.FormatVersion`.
+func ToProtoDocument(n *logictree.Node) (*Document, error) {
+	p, err := ToProto(n)
+	if err != nil {
+		return nil, err
+	}
+	return &Document{Version: logictree.FormatVersion, Tree: p}, nil
+}
+
+// FromProtoDocument converts a document back into its tree, failing with
+// `logictree.ErrUnsupportedVersion` for documents of a newer version.
+func FromProtoDocument(d *Document) (*logictree.Node, error) {
+	if v := d.GetVersion(); v < 0 || v > logictree.FormatVersion {
+		return nil, fmt.Errorf("%w: %d, at most %d is supported", logictree.ErrUnsupportedVersion, v, logictree.FormatVersion)
+	}
+	if d.GetTree() == nil {
+		return nil, fmt.Errorf("invalid document: missing tree")
+	}
+	return FromProto(d.GetTree())
+}
+
+// childPath returns the path of the `i`th child of the node at `path`, as in
+// the errors of package logictree.
+func childPath(path string, i int) string {
+	if path == "/" {
+		return fmt.Sprintf("/%d", i)
+	}
+	return fmt.Sprintf("%s/%d", path, i)
+}
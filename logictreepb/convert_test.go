@@ -0,0 +1,159 @@
+package logictreepb
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestRoundTrip(t *testing.T) {
+	for _, n := range []*logictree.Node{
+		logictree.NewLeafNode("true"),
+		logictree.NewAdvancedLeafNode("{{ $n := len .Tags }}{{ gt $n 0 }}"),
+		logictree.NewNode(logictree.OperatorAnd),
+		logictree.NewNode(logictree.OperatorOr,
+			logictree.NewNode(logictree.OperatorAnd,
+				logictree.NewLeafNode("ge .Milk 4"),
+				logictree.NewLeafNode(`in .Country ["US", "CA"]`)),
+			logictree.NewAdvancedLeafNode("{{ true }}"),
+			logictree.NewLeafNode("gt .Toothpaste 5")),
+	} {
+		p, err := ToProto(n)
+		if err != nil {
+			t.Fatalf("ToProto(%s) error: %s\n", n, err.Error())
+		}
+		bs, err := proto.Marshal(p)
+		if err != nil {
+			t.Fatalf("Marshal() error: %s\n", err.Error())
+		}
+		var decoded Node
+		if err := proto.Unmarshal(bs, &decoded); err != nil {
+			t.Fatalf("Unmarshal() error: %s\n", err.Error())
+		}
+		actual, err := FromProto(&decoded)
+		if err != nil {
+			t.Fatalf("FromProto() error: %s\n", err.Error())
+		}
+		if !reflect.DeepEqual(actual, n) {
+			t.Errorf("FromProto(ToProto()) expected=%#v actual=%#v\n", n, actual)
+		}
+	}
+}
+
+func TestDocument(t *testing.T) {
+	n := logictree.NewNode(logictree.OperatorOr, logictree.NewLeafNode("eq .A 1"), logictree.NewLeafNode("eq .B 2"))
+	d, err := ToProtoDocument(n)
+	if err != nil || d.GetVersion() != logictree.FormatVersion {
+		t.Fatalf("ToProtoDocument() expected version %d, got %v (%v)\n", logictree.FormatVersion, d, err)
+	}
+	if actual, err := FromProtoDocument(d); err != nil || !reflect.DeepEqual(actual, n) {
+		t.Errorf("FromProtoDocument() expected=%s actual=%s (%v)\n", n, actual, err)
+	}
+
+	d.Version = logictree.FormatVersion + 1
+	if _, err := FromProtoDocument(d); !errors.Is(err, logictree.ErrUnsupportedVersion) {
+		t.Errorf("FromProtoDocument() expected=%v actual=%v\n", logictree.ErrUnsupportedVersion, err)
+	}
+	if _, err := FromProtoDocument(&Document{Version: 1}); err == nil {
+		t.Errorf("FromProtoDocument() expected an error for a missing tree\n")
+	}
+}
+
+func TestInvalidOperator(t *testing.T) {
+	n := logictree.NewNode(logictree.OperatorAnd, logictree.NewLeafNode("true"), logictree.NewNode("xor"))
+	if _, err := ToProto(n); !errors.Is(err, logictree.ErrInvalidOperator) || !strings.HasPrefix(err.Error(), "/1: ") {
+		t.Errorf("ToProto() expected an invalid operator at /1, got: %v\n", err)
+	}
+
+	p := &Node{Op: Operator_OPERATOR_OR, Nodes: []*Node{{Op: Operator_OPERATOR_LEAF, Leaf: "(true)"}, {}}}
+	if _, err := FromProto(p); !errors.Is(err, logictree.ErrInvalidOperator) || !strings.HasPrefix(err.Error(), "/1: ") {
+		t.Errorf("FromProto() expected an invalid operator at /1, got: %v\n", err)
+	}
+}
+
+func init() {
+	logictree.RegisterOperator("majority", logictree.OperatorFunc(func(results []bool) bool {
+		n := 0
+		for _, r := range results {
+			if r {
+				n++
+			}
+		}
+		return 2*n > len(results)
+	}))
+	logictree.RegisterOperator("quorum", quorum{})
+}
+
+// quorum is true if at least the number of its children which is its
+// payload are.
+type quorum struct{}
+
+func (quorum) Evaluate(results []bool) bool { return quorum{}.EvaluatePayload(1, results) }
+
+func (quorum) DecodePayload(data []byte) (interface{}, error) {
+	var k int
+	err := json.Unmarshal(data, &k)
+	return k, err
+}
+
+func (quorum) EvaluatePayload(payload interface{}, results []bool) bool {
+	n := 0
+	for _, r := range results {
+		if r {
+			n++
+		}
+	}
+	return n >= payload.(int)
+}
+
+func TestRegisteredOperator(t *testing.T) {
+	q, err := logictree.NewPayloadNode("quorum", 2, logictree.NewLeafNode(".D"), logictree.NewLeafNode(".E"))
+	if err != nil {
+		t.Fatalf("NewPayloadNode() error: %s\n", err.Error())
+	}
+	n := logictree.NewNode(logictree.OperatorAnd,
+		logictree.NewLeafNode("gt .Amount 5"),
+		logictree.NewNode("majority", logictree.NewLeafNode(".A"), logictree.NewLeafNode(".B"), logictree.NewLeafNode(".C")),
+		q)
+	p, err := ToProto(n)
+	if err != nil {
+		t.Fatalf("ToProto() error: %s\n", err.Error())
+	}
+	if c := p.GetNodes()[1]; c.GetOp() != Operator_OPERATOR_REGISTERED || c.GetName() != "majority" {
+		t.Errorf("ToProto() expected a registered majority node, got %v\n", c)
+	}
+	if c := p.GetNodes()[2]; c.GetName() != "quorum" || c.GetLeaf() != "2" {
+		t.Errorf("ToProto() expected a quorum node with its payload, got %v\n", c)
+	}
+	bs, err := proto.Marshal(p)
+	if err != nil {
+		t.Fatalf("Marshal() error: %s\n", err.Error())
+	}
+	var decoded Node
+	if err := proto.Unmarshal(bs, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error: %s\n", err.Error())
+	}
+	actual, err := FromProto(&decoded)
+	if err != nil || !reflect.DeepEqual(actual, n) {
+		t.Errorf("FromProto(ToProto()) expected=%s actual=%s err=%v\n", n, actual, err)
+	}
+
+	// Operators not registered in this program, and built-in operators, are
+	// not decoded as registered ones.
+	for _, name := range []string{"minority", "and", ""} {
+		p := &Node{Op: Operator_OPERATOR_OR, Nodes: []*Node{{Op: Operator_OPERATOR_LEAF, Leaf: "(true)"}, {Op: Operator_OPERATOR_REGISTERED, Name: name}}}
+		if _, err := FromProto(p); !errors.Is(err, logictree.ErrInvalidOperator) || !strings.HasPrefix(err.Error(), "/1: ") {
+			t.Errorf("FromProto(%q) expected an invalid operator at /1, got: %v\n", name, err)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Protocol buffer representation of logictree trees, for sending rule
+// definitions between services over gRPC.  Convert with `ToProto` and
+// `FromProto` of the Go package.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: logictree.proto
+
+package logictreepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Operator mirrors `logictree.Operator`.
+type Operator int32
+
+const (
+	Operator_OPERATOR_UNSPECIFIED Operator = 0
+	Operator_OPERATOR_LEAF        Operator = 1
+	Operator_OPERATOR_AND         Operator = 2
+	Operator_OPERATOR_OR          Operator = 3
+	Operator_OPERATOR_ADVANCED    Operator = 4
+	// An operator registered by `logictree.RegisterOperator`, named by the
+	// `name` of its node.
+	Operator_OPERATOR_REGISTERED Operator = 5
+)
+
+// Enum value maps for Operator.
+var (
+	Operator_name = map[int32]string{
+		0: "OPERATOR_UNSPECIFIED",
+		1: "OPERATOR_LEAF",
+		2: "OPERATOR_AND",
+		3: "OPERATOR_OR",
+		4: "OPERATOR_ADVANCED",
+		5: "OPERATOR_REGISTERED",
+	}
+	Operator_value = map[string]int32{
+		"OPERATOR_UNSPECIFIED": 0,
+		"OPERATOR_LEAF":        1,
+		"OPERATOR_AND":         2,
+		"OPERATOR_OR":          3,
+		"OPERATOR_ADVANCED":    4,
+		"OPERATOR_REGISTERED":  5,
+	}
+)
+
+func (x Operator) Enum() *Operator {
+	p := new(Operator)
+	*p = x
+	return p
+}
+
+func (x Operator) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Operator) Descriptor() protoreflect.EnumDescriptor {
+	return file_logictree_proto_enumTypes[0].Descriptor()
+}
+
+func (Operator) Type() protoreflect.EnumType {
+	return &file_logictree_proto_enumTypes[0]
+}
+
+func (x Operator) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Operator.Descriptor instead.
+func (Operator) EnumDescriptor() ([]byte, []int) {
+	return file_logictree_proto_rawDescGZIP(), []int{0}
+}
+
+// Node mirrors `logictree.Node`.
+type Node struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Op    Operator               `protobuf:"varint,1,opt,name=op,proto3,enum=logictree.v1.Operator" json:"op,omitempty"`
+	Nodes []*Node                `protobuf:"bytes,2,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	Leaf  string                 `protobuf:"bytes,3,opt,name=leaf,proto3" json:"leaf,omitempty"`
+	// The name of the operator of an OPERATOR_REGISTERED node.
+	Name          string `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Node) Reset() {
+	*x = Node{}
+	mi := &file_logictree_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Node) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Node) ProtoMessage() {}
+
+func (x *Node) ProtoReflect() protoreflect.Message {
+	mi := &file_logictree_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Node.ProtoReflect.Descriptor instead.
+func (*Node) Descriptor() ([]byte, []int) {
+	return file_logictree_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Node) GetOp() Operator {
+	if x != nil {
+		return x.Op
+	}
+	return Operator_OPERATOR_UNSPECIFIED
+}
+
+func (x *Node) GetNodes() []*Node {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+func (x *Node) GetLeaf() string {
+	if x != nil {
+		return x.Leaf
+	}
+	return ""
+}
+
+func (x *Node) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// Document mirrors `logictree.Document`, a tree with the version of the
+// format it was written in.
+type Document struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Version       int32                  `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Tree          *Node                  `protobuf:"bytes,2,opt,name=tree,proto3" json:"tree,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Document) Reset() {
+	*x = Document{}
+	mi := &file_logictree_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Document) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Document) ProtoMessage() {}
+
+func (x *Document) ProtoReflect() protoreflect.Message {
+	mi := &file_logictree_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Document.ProtoReflect.Descriptor instead.
+func (*Document) Descriptor() ([]byte, []int) {
+	return file_logictree_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Document) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *Document) GetTree() *Node {
+	if x != nil {
+		return x.Tree
+	}
+	return nil
+}
+
+var File_logictree_proto protoreflect.FileDescriptor
+
+const file_logictree_proto_rawDesc = "" +
+	"\n" +
+	"\x0flogictree.proto\x12\flogictree.v1\"\x80\x01\n" +
+	"\x04Node\x12&\n" +
+	"\x02op\x18\x01 \x01(\x0e2\x16.logictree.v1.OperatorR\x02op\x12(\n" +
+	"\x05nodes\x18\x02 \x03(\v2\x12.logictree.v1.NodeR\x05nodes\x12\x12\n" +
+	"\x04leaf\x18\x03 \x01(\tR\x04leaf\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\"L\n" +
+	"\bDocument\x12\x18\n" +
+	"\aversion\x18\x01 \x01(\x05R\aversion\x12&\n" +
+	"\x04tree\x18\x02 \x01(\v2\x12.logictree.v1.NodeR\x04tree*\x8a\x01\n" +
+	"\bOperator\x12\x18\n" +
+	"\x14OPERATOR_UNSPECIFIED\x10\x00\x12\x11\n" +
+	"\rOPERATOR_LEAF\x10\x01\x12\x10\n" +
+	"\fOPERATOR_AND\x10\x02\x12\x0f\n" +
+	"\vOPERATOR_OR\x10\x03\x12\x15\n" +
+	"\x11OPERATOR_ADVANCED\x10\x04\x12\x17\n" +
+	"\x13OPERATOR_REGISTERED\x10\x05B+Z)github.com/sabhiram/logictree/logictreepbb\x06proto3"
+
+var (
+	file_logictree_proto_rawDescOnce sync.Once
+	file_logictree_proto_rawDescData []byte
+)
+
+func file_logictree_proto_rawDescGZIP() []byte {
+	file_logictree_proto_rawDescOnce.Do(func() {
+		file_logictree_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_logictree_proto_rawDesc), len(file_logictree_proto_rawDesc)))
+	})
+	return file_logictree_proto_rawDescData
+}
+
+var file_logictree_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_logictree_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_logictree_proto_goTypes = []any{
+	(Operator)(0),    // 0: logictree.v1.Operator
+	(*Node)(nil),     // 1: logictree.v1.Node
+	(*Document)(nil), // 2: logictree.v1.Document
+}
+var file_logictree_proto_depIdxs = []int32{
+	0, // 0: logictree.v1.Node.op:type_name -> logictree.v1.Operator
+	1, // 1: logictree.v1.Node.nodes:type_name -> logictree.v1.Node
+	1, // 2: logictree.v1.Document.tree:type_name -> logictree.v1.Node
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_logictree_proto_init() }
+func file_logictree_proto_init() {
+	if File_logictree_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_logictree_proto_rawDesc), len(file_logictree_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_logictree_proto_goTypes,
+		DependencyIndexes: file_logictree_proto_depIdxs,
+		EnumInfos:         file_logictree_proto_enumTypes,
+		MessageInfos:      file_logictree_proto_msgTypes,
+	}.Build()
+	File_logictree_proto = out.File
+	file_logictree_proto_goTypes = nil
+	file_logictree_proto_depIdxs = nil
+}
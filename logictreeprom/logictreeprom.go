@@ -0,0 +1,99 @@
+// Package logictreeprom exports the metrics of logictree trees to
+// Prometheus, see `logictree.WithMetrics`:
+//
+//	m, err := logictreeprom.New(prometheus.DefaultRegisterer, "rules")
+//	ct, err := logictree.Compile(tree, logictree.WithMetrics(m.Tree("discounts")))
+//
+// The metrics are labeled by the name of the tree, empty for the `Metrics`
+// itself:
+//
+//	<namespace>_evaluations_total{tree, result}     evaluations, by result: "true", "false" or "error"
+//	<namespace>_evaluation_duration_seconds{tree}   the latency of evaluations
+//	<namespace>_compile_errors_total{tree}          trees which failed to compile
+package logictreeprom
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Metrics are the Prometheus collectors of the metrics of trees.  It is the
+// `logictree.Metrics` of trees without a name.
+type Metrics struct {
+	evaluations   *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	compileErrors *prometheus.CounterVec
+}
+
+// New returns the metrics named within `namespace`, registered with `reg`.
+// It fails if metrics of the same names are already registered.
+func New(reg prometheus.Registerer, namespace string) (*Metrics, error) {
+	m := &Metrics{
+		evaluations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "evaluations_total",
+			Help:      "Evaluations of logictree trees, by result.",
+		}, []string{"tree", "result"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "evaluation_duration_seconds",
+			Help:      "Latency of evaluations of logictree trees.",
+			Buckets:   prometheus.ExponentialBuckets(1e-6, 4, 12),
+		}, []string{"tree"}),
+		compileErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "compile_errors_total",
+			Help:      "logictree trees which failed to compile.",
+		}, []string{"tree"}),
+	}
+	for _, c := range []prometheus.Collector{m.evaluations, m.duration, m.compileErrors} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Tree returns the `logictree.Metrics` of the tree `name`.
+func (m *Metrics) Tree(name string) logictree.Metrics {
+	return treeMetrics{m: m, name: name}
+}
+
+func (m *Metrics) ObserveEvaluation(v bool, err error, d time.Duration) {
+	m.observeEvaluation("", v, err, d)
+}
+
+func (m *Metrics) ObserveCompileError(err error) {
+	m.compileErrors.WithLabelValues("").Inc()
+}
+
+func (m *Metrics) observeEvaluation(tree string, v bool, err error, d time.Duration) {
+	result := strconv.FormatBool(v)
+	if err != nil {
+		result = "error"
+	}
+	m.evaluations.WithLabelValues(tree, result).Inc()
+	m.duration.WithLabelValues(tree).Observe(d.Seconds())
+}
+
+// treeMetrics are the metrics of a single named tree.
+type treeMetrics struct {
+	m    *Metrics
+	name string
+}
+
+func (t treeMetrics) ObserveEvaluation(v bool, err error, d time.Duration) {
+	t.m.observeEvaluation(t.name, v, err, d)
+}
+
+func (t treeMetrics) ObserveCompileError(err error) {
+	t.m.compileErrors.WithLabelValues(t.name).Inc()
+}
@@ -0,0 +1,58 @@
+package logictreeprom
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m, err := New(reg, "rules")
+	if err != nil {
+		t.Fatalf("New() error: %s\n", err.Error())
+	}
+	if _, err := New(reg, "rules"); err == nil {
+		t.Errorf("New() expected an error registering the metrics twice\n")
+	}
+
+	ct, err := logictree.Compile(logictree.NewLeafNode("gt .A 1"), logictree.WithMetrics(m.Tree("a")), logictree.WithMissing(logictree.MissingIsError))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	for _, d := range []interface{}{
+		map[string]interface{}{"A": 2},
+		map[string]interface{}{"A": 2},
+		map[string]interface{}{"A": 0},
+		map[string]interface{}{},
+	} {
+		ct.Evaluate(d)
+	}
+	logictree.Compile(logictree.NewNode(logictree.OperatorAnd), logictree.WithMetrics(m.Tree("b")))
+	logictree.Compile(logictree.NewNode(logictree.OperatorAnd), logictree.WithMetrics(m))
+
+	for _, tc := range []struct {
+		c        prometheus.Collector
+		expected float64
+	}{
+		{m.evaluations.WithLabelValues("a", "true"), 2},
+		{m.evaluations.WithLabelValues("a", "false"), 1},
+		{m.evaluations.WithLabelValues("a", "error"), 1},
+		{m.compileErrors.WithLabelValues("b"), 1},
+		{m.compileErrors.WithLabelValues(""), 1},
+	} {
+		if actual := testutil.ToFloat64(tc.c); actual != tc.expected {
+			t.Errorf("ToFloat64() expected=%v actual=%v\n", tc.expected, actual)
+		}
+	}
+	if n := testutil.CollectAndCount(m.duration, "rules_evaluation_duration_seconds"); n != 1 {
+		t.Errorf("CollectAndCount() expected=1 actual=%d\n", n)
+	}
+}
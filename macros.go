@@ -0,0 +1,160 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+var (
+	macrosMu sync.RWMutex
+	macros   = map[string]string{}
+)
+
+// RegisterMacro defines the macro `name`, such as `isAdult`, standing for the
+// leaf expression `expr`, such as `ge .Age 18`, so that commonly repeated
+// predicates are written once and leaves stay short:
+//
+//	logictree.NewLeafNode("and isAdult (gt .Amount 100)")
+//
+// Macros are expanded, wherever they appear as a word of an ordinary leaf
+// outside string literals, into their parenthesized expression, before the
+// leaf is combined, compiled, validated or translated.  Expressions may use
+// other macros, which are expanded in turn, including those registered
+// later, but no macro may expand into itself.  Macros replace any function of
+// the same name given by `WithFuncs`, and are not expanded in advanced
+// leaves.
+//
+// Macros are registered for the life of the program, typically from an
+// `init` function.  RegisterMacro panics if `name` is already a macro or an
+// operator, if it is not a valid template identifier, or the name of a
+// keyword or built-in function of templates, if `expr` is not a single
+// expression, see `ErrNotExpression`, or if it expands into `name`, directly
+// or through other macros.
+func RegisterMacro(name, expr string) {
+	macrosMu.Lock()
+	defer macrosMu.Unlock()
+
+	switch {
+	case !isIdentifier(name):
+		panic("logictree: RegisterMacro macro name is not an identifier: " + name)
+	case isMacro(name):
+		panic("logictree: RegisterMacro called twice for macro " + name)
+	case isCustom(Operator(name)) || isBuiltin(Operator(name)):
+		panic("logictree: RegisterMacro called for the operator " + name)
+	}
+	expr = "(" + strings.TrimSpace(expr) + ")"
+	t, defined, err := parseTemplate("{{ " + listSource(expr) + " }}")
+	if err == nil {
+		err = checkExpression(t, defined)
+	}
+	if err != nil {
+		panic("logictree: RegisterMacro macro " + name + " is not a single expression: " + err.Error())
+	}
+	if cycle := macroCycle(name, expr, []string{name}); cycle != nil {
+		panic("logictree: RegisterMacro macro " + name + " expands into itself: " + strings.Join(cycle, " -> "))
+	}
+	macros[name] = expr
+}
+
+// isMacro reports whether `name` is a registered macro.  `macrosMu` must be
+// held.
+func isMacro(name string) bool {
+	_, ok := macros[name]
+	return ok
+}
+
+// macroCycle returns the macros through which `expr`, expanded from the
+// macros `stack`, expands into `name`, or nil if it does not, given the
+// macros registered so far.  `macrosMu` must be held.
+func macroCycle(name, expr string, stack []string) []string {
+	var cycle []string
+	mapWords(expr, func(word string) string {
+		if cycle != nil {
+			return word
+		}
+		if word == name {
+			cycle = append(append([]string{}, stack...), name)
+		} else if e, ok := macros[word]; ok {
+			cycle = macroCycle(name, e, append(stack, word))
+		}
+		return word
+	})
+	return cycle
+}
+
+// expandMacros returns the leaf expression `leaf` with its macros expanded.
+func expandMacros(leaf string) string {
+	macrosMu.RLock()
+	defer macrosMu.RUnlock()
+	if len(macros) == 0 {
+		return leaf
+	}
+	return expandWords(leaf)
+}
+
+// expandWords expands the macros of `s`, recursively.  `macrosMu` must be
+// held; registered macros never expand into themselves.
+func expandWords(s string) string {
+	return mapWords(s, func(word string) string {
+		if e, ok := macros[word]; ok {
+			return expandWords(e)
+		}
+		return word
+	})
+}
+
+// mapWords replaces the words of the leaf expression `s` which could name
+// functions by what `fn` returns for them.  Fields, variables, numbers and the
+// contents of string, raw string and character literals are left alone.
+func mapWords(s string, fn func(string) string) string {
+	var b strings.Builder
+	var quote rune
+	escaped := false
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case quote != 0:
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\' && quote != '`':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+		case r == '"' || r == '`' || r == '\'':
+			quote = r
+		case isWordRune(r):
+			j := i
+			for j < len(s) {
+				r, size := utf8.DecodeRuneInString(s[j:])
+				if !isWordRune(r) {
+					break
+				}
+				j += size
+			}
+			word := s[i:j]
+			prev, _ := utf8.DecodeLastRuneInString(s[:i])
+			if prev != '.' && prev != '$' && !unicode.IsDigit(r) {
+				word = fn(word)
+			}
+			b.WriteString(word)
+			i = j
+			continue
+		}
+		b.WriteString(s[i : i+size])
+		i += size
+	}
+	return b.String()
+}
+
+// isWordRune reports whether `r` may appear in an identifier or a number.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
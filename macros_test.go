@@ -0,0 +1,97 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func registerTestMacro(t *testing.T, name, expr string) {
+	RegisterMacro(name, expr)
+	t.Cleanup(func() {
+		macrosMu.Lock()
+		defer macrosMu.Unlock()
+		delete(macros, name)
+	})
+}
+
+func TestRegisterMacro(t *testing.T) {
+	// Macros may use those registered later.
+	registerTestMacro(t, "isEligible", "and isAdult .Verified")
+	registerTestMacro(t, "isAdult", "ge .Age 18")
+
+	n := NewNode(OperatorAnd, NewLeafNode("isEligible"), NewLeafNode(`or (gt .Amount 100) (eq .Name "isAdult")`))
+	tmpl, err := n.GetTemplate(nil)
+	if err != nil {
+		t.Fatalf("GetTemplate() error: %s\n", err.Error())
+	}
+	ct, err := Compile(n)
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	for _, tc := range []struct {
+		data     map[string]interface{}
+		expected bool
+	}{
+		{map[string]interface{}{"Age": 20, "Verified": true, "Amount": 200, "Name": "x"}, true},
+		{map[string]interface{}{"Age": 16, "Verified": true, "Amount": 200, "Name": "x"}, false},
+		{map[string]interface{}{"Age": 20, "Verified": true, "Amount": 50, "Name": "isAdult"}, true},
+		{map[string]interface{}{"Age": 20, "Verified": true, "Amount": 50, "Name": "x"}, false},
+	} {
+		if v, err := ct.Evaluate(tc.data); err != nil || v != tc.expected {
+			t.Errorf("Evaluate(%v) expected=%v actual=%v err=%v\n", tc.data, tc.expected, v, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, tc.data); err != nil || (buf.String() == "true") != tc.expected {
+			t.Errorf("Execute(%v) expected=%v actual=%s err=%v\n", tc.data, tc.expected, buf.String(), err)
+		}
+	}
+	if err := NewLeafNode("isAdult").Validate(); err != nil {
+		t.Errorf("Validate() error: %s\n", err.Error())
+	}
+	if s, expected := NewLeafNode("isEligible").Infix(), ".Age >= 18 AND .Verified"; s != expected {
+		t.Errorf("Infix() expected=%s actual=%s\n", expected, s)
+	}
+
+	// Fields, variables and numbers are not macros.
+	for _, tc := range []struct {
+		leaf     string
+		expected string
+	}{
+		{"eq .isAdult $.isAdult", "eq .isAdult $.isAdult"},
+		{"not isAdult", "not (ge .Age 18)"},
+		{"eq 1e5 .X `isAdult`", "eq 1e5 .X `isAdult`"},
+	} {
+		if s := expandMacros(tc.leaf); s != tc.expected {
+			t.Errorf("expandMacros(%s) expected=%s actual=%s\n", tc.leaf, tc.expected, s)
+		}
+	}
+
+	for _, tc := range []struct {
+		name string
+		expr string
+	}{
+		{"isAdult", "ge .Age 21"},
+		{"and", "true"},
+		{"switch", "true"},
+		{"is-adult", "true"},
+		{"multiple", "ge .Age 18}}{{ true"},
+		{"selfish", "not selfish"},
+		{"isMinor", "not isOld"},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterMacro(%s) expected a panic\n", tc.name)
+				}
+			}()
+			if tc.name == "isMinor" {
+				registerTestMacro(t, "isOld", "and isMinor (ge .Age 65)")
+			}
+			registerTestMacro(t, tc.name, tc.expr)
+		}()
+	}
+}
@@ -0,0 +1,33 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Metrics records the evaluations and compile errors of trees, see
+// `WithMetrics`, for services which export them as counters and histograms.
+// Package `logictreeprom` implements it with Prometheus.  Its methods may be
+// called from any number of goroutines at once.
+type Metrics interface {
+	// ObserveEvaluation records an evaluation which took `d`, with the
+	// result `v` unless it failed with `err`.
+	ObserveEvaluation(v bool, err error, d time.Duration)
+
+	// ObserveCompileError records a tree which failed to compile with
+	// `err`.
+	ObserveCompileError(err error)
+}
+
+// WithMetrics records every evaluation of the tree by `EvaluateContext` and
+// `Evaluate` with `m`, including those answered by its cache, and its error
+// if the tree fails to compile, so that call sites need not be wrapped to
+// count them.
+func WithMetrics(m Metrics) Option {
+	return func(o *compileOptions) {
+		o.metrics = m
+	}
+}
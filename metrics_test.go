@@ -0,0 +1,63 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// counts is a `Metrics` counting what it records.
+type counts struct {
+	mu                    sync.Mutex
+	trues, falses, failed int
+	compileErrors         int
+	d                     time.Duration
+}
+
+func (c *counts) ObserveEvaluation(v bool, err error, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case err != nil:
+		c.failed++
+	case v:
+		c.trues++
+	default:
+		c.falses++
+	}
+	c.d += d
+}
+
+func (c *counts) ObserveCompileError(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.compileErrors++
+}
+
+func TestWithMetrics(t *testing.T) {
+	m := &counts{}
+	ct, err := Compile(NewLeafNode("gt .A 1"), WithMetrics(m), WithMissing(MissingIsError), WithCache(8))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	for _, d := range []interface{}{
+		map[string]interface{}{"A": 2},
+		map[string]interface{}{"A": 2},
+		map[string]interface{}{"A": 0},
+		map[string]interface{}{},
+	} {
+		ct.Evaluate(d)
+	}
+	if m.trues != 2 || m.falses != 1 || m.failed != 1 || m.compileErrors != 0 || m.d <= 0 {
+		t.Errorf("Evaluate() expected=2 1 1 0 actual=%d %d %d %d (%v)\n", m.trues, m.falses, m.failed, m.compileErrors, m.d)
+	}
+
+	if _, err := Compile(NewNode(OperatorAnd), WithMetrics(m)); !errors.Is(err, ErrEmptyNode) || m.compileErrors != 1 {
+		t.Errorf("Compile() expected=%v and 1 compile error actual=%v and %d\n", ErrEmptyNode, err, m.compileErrors)
+	}
+}
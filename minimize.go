@@ -0,0 +1,300 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// MinimizeOptions limits the trees minimized by `Minimize`, whose cost grows
+// exponentially with the number of distinct leaves.
+type MinimizeOptions struct {
+	// MaxLeaves is the maximum number of distinct leaves, at most 16.
+	MaxLeaves int `json:"maxLeaves,omitempty" yaml:"maxLeaves,omitempty"`
+}
+
+// DefaultMinimizeOptions are the limits used by `Node.Minimize`.
+var DefaultMinimizeOptions = MinimizeOptions{
+	MaxLeaves: 10,
+}
+
+// maxMinimizeLeaves bounds `MinimizeOptions.MaxLeaves`, a table of 2^16 rows.
+const maxMinimizeLeaves = 16
+
+// maxCoverSteps bounds the search for the smallest cover of `Minimize`,
+// beyond which the smallest found so far is used.
+const maxCoverSteps = 1 << 12
+
+// Minimize returns a minimal equivalent of the tree within
+// `DefaultMinimizeOptions`, see `MinimizeOptions.Minimize`.
+func (n *Node) Minimize() (*Node, error) {
+	return DefaultMinimizeOptions.Minimize(n)
+}
+
+// Minimize returns the smallest sum of products equivalent to the tree
+// rooted at `n`, found by the Quine-McCluskey method: the `or` of as few
+// `and` nodes of as few leaves as there can be, a product of a single leaf
+// being the leaf itself.  Leaves are the boolean inputs of `Satisfiable`, so
+// a leaf `not X` negates `X`, negated leaves are written as `not X` and the
+// leaves of the result are normalized.  Products and their leaves are ordered
+// by leaf, so minimizing the result returns it unchanged.  A tree which is
+// always true or always false becomes the leaf `true` or `false`.
+//
+// Minimizing decision tables and other generated trees removes the cases
+// which other cases subsume, making them both faster to evaluate and easier
+// to read.  Like `Canonicalize`, minimizing assumes leaves are free of side
+// effects: the minimal tree evaluates fewer of them, in a different order.
+// Trees of more than `MaxLeaves` distinct leaves fail with an error wrapping
+// `ErrLimitExceeded`.  The tree is not modified.
+func (o MinimizeOptions) Minimize(n *Node) (*Node, error) {
+	e, vars, err := n.boolean(true)
+	if err != nil {
+		return nil, err
+	}
+	limit := o.MaxLeaves
+	if limit <= 0 || limit > maxMinimizeLeaves {
+		limit = maxMinimizeLeaves
+	}
+	if len(vars) > limit {
+		return nil, fmt.Errorf("%w: %d distinct leaves, at most %d are minimized", ErrLimitExceeded, len(vars), limit)
+	}
+
+	advanced := make([]bool, len(vars))
+	e.leaves(func(l *boolExpr) {
+		if l.op == OperatorAdvanced {
+			advanced[l.variable] = true
+		}
+	})
+
+	// Variables are ordered by name, rather than as they occur, for the
+	// result to depend only on the function of the tree.
+	order := make([]int, len(vars))
+	for v := range order {
+		order[v] = v
+	}
+	sort.Slice(order, func(i, j int) bool { return vars[order[i]] < vars[order[j]] })
+
+	minterms := []uint32{}
+	vals := make([]bool, len(vars))
+	for m := uint32(0); m < 1<<len(vars); m++ {
+		for b, v := range order {
+			vals[v] = m&(1<<b) != 0
+		}
+		if e.eval(vals) {
+			minterms = append(minterms, m)
+		}
+	}
+	switch len(minterms) {
+	case 0:
+		return NewLeafNode("false"), nil
+	case 1 << len(vars):
+		return NewLeafNode("true"), nil
+	}
+
+	cover := minimalCover(primeImplicants(minterms, len(vars)), minterms)
+	sort.Slice(cover, func(i, j int) bool { return cover[i].less(cover[j]) })
+
+	sum := NewNode(OperatorOr)
+	for _, p := range cover {
+		product := NewNode(OperatorAnd)
+		for b, v := range order {
+			name := vars[v]
+			if p.dashes&(1<<b) != 0 {
+				continue
+			}
+			switch {
+			case p.bits&(1<<b) == 0 && advanced[v]:
+				// Advanced leaves are never negated in the tree, so they
+				// are never negated in its prime implicants.
+				return nil, fmt.Errorf("cannot negate advanced leaf %s", name)
+			case p.bits&(1<<b) == 0:
+				product.Nodes = append(product.Nodes, NewLeafNode("not "+name))
+			case advanced[v]:
+				product.Nodes = append(product.Nodes, NewAdvancedLeafNode(name))
+			default:
+				product.Nodes = append(product.Nodes, &Node{Op: OperatorLeaf, Leaf: name})
+			}
+		}
+		if len(product.Nodes) == 1 {
+			product = product.Nodes[0]
+		}
+		sum.Nodes = append(sum.Nodes, product)
+	}
+	if len(sum.Nodes) == 1 {
+		return sum.Nodes[0], nil
+	}
+	return sum, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// implicant is a product of variables, those whose bits are set in `dashes`
+// not appearing in it and the others true if their bits are set in `bits`.
+// The bits of variables beyond those of the function are always dashes.
+type implicant struct {
+	bits, dashes uint32
+}
+
+func (p implicant) covers(m uint32) bool {
+	return m&^p.dashes == p.bits
+}
+
+func (p implicant) literals() int {
+	return 32 - bits.OnesCount32(p.dashes)
+}
+
+// less orders products by their variables, lowest first, then by their
+// values, true first.
+func (p implicant) less(q implicant) bool {
+	pv, qv := ^p.dashes, ^q.dashes
+	if pv != qv {
+		return bits.Reverse32(pv) > bits.Reverse32(qv)
+	}
+	return bits.Reverse32(p.bits) > bits.Reverse32(q.bits)
+}
+
+// primeImplicants returns the prime implicants of the function of `vars`
+// variables true for `minterms`, by combining implicants differing in a
+// single variable until none combine, in the order of `implicant.less`.
+func primeImplicants(minterms []uint32, vars int) []implicant {
+	unused := ^uint32(0) << vars
+	current := map[implicant]bool{}
+	for _, m := range minterms {
+		current[implicant{bits: m, dashes: unused}] = true
+	}
+
+	primes := []implicant{}
+	for len(current) > 0 {
+		next := map[implicant]bool{}
+		for p := range current {
+			combined := false
+			for v := uint32(1); v&unused == 0; v <<= 1 {
+				if p.dashes&v != 0 {
+					continue
+				}
+				// The implicant differing from p in the variable v alone.
+				q := implicant{bits: p.bits ^ v, dashes: p.dashes}
+				if current[q] {
+					combined = true
+					next[implicant{bits: p.bits &^ v, dashes: p.dashes | v}] = true
+				}
+			}
+			if !combined {
+				primes = append(primes, p)
+			}
+		}
+		current = next
+	}
+	sort.Slice(primes, func(i, j int) bool { return primes[i].less(primes[j]) })
+	return primes
+}
+
+// minimalCover returns the fewest `primes`, of the fewest literals, which
+// together cover `minterms`.
+func minimalCover(primes []implicant, minterms []uint32) []implicant {
+	c := &coverSearch{primes: primes, minterms: minterms}
+	c.best = c.greedy()
+	c.search(nil, make([]int, len(minterms)))
+	return c.best
+}
+
+// coverSearch is a branch and bound search for the smallest cover.
+type coverSearch struct {
+	primes   []implicant
+	minterms []uint32
+	best     []implicant
+	steps    int
+}
+
+// greedy returns a cover choosing, until every minterm is covered, the prime
+// covering the most uncovered minterms.
+func (c *coverSearch) greedy() []implicant {
+	covered := make([]bool, len(c.minterms))
+	cover := []implicant{}
+	for {
+		best, most := -1, 0
+		for i, p := range c.primes {
+			count := 0
+			for j, m := range c.minterms {
+				if !covered[j] && p.covers(m) {
+					count++
+				}
+			}
+			if count > most {
+				best, most = i, count
+			}
+		}
+		if best < 0 {
+			return cover
+		}
+		cover = append(cover, c.primes[best])
+		for j, m := range c.minterms {
+			covered[j] = covered[j] || c.primes[best].covers(m)
+		}
+	}
+}
+
+// search extends `cover`, which covers the minterms whose counts in
+// `covered` are non-zero, branching on each prime covering the first
+// uncovered minterm.
+func (c *coverSearch) search(cover []implicant, covered []int) {
+	if c.steps++; c.steps > maxCoverSteps || !c.better(cover, len(cover) < len(c.best)) {
+		return
+	}
+	first := -1
+	for j, n := range covered {
+		if n == 0 {
+			first = j
+			break
+		}
+	}
+	if first < 0 {
+		c.best = append([]implicant(nil), cover...)
+		return
+	}
+	if len(cover)+1 > len(c.best) {
+		return
+	}
+
+	for _, p := range c.primes {
+		if !p.covers(c.minterms[first]) {
+			continue
+		}
+		for j, m := range c.minterms {
+			if p.covers(m) {
+				covered[j]++
+			}
+		}
+		c.search(append(cover, p), covered)
+		for j, m := range c.minterms {
+			if p.covers(m) {
+				covered[j]--
+			}
+		}
+	}
+}
+
+// better reports whether `cover` could still improve on the best cover:
+// whether it has fewer primes, or as many and fewer literals.  `fewer`
+// reports whether it has fewer primes.
+func (c *coverSearch) better(cover []implicant, fewer bool) bool {
+	if fewer {
+		return true
+	}
+	if len(cover) > len(c.best) {
+		return false
+	}
+	return literalCount(cover) < literalCount(c.best)
+}
+
+func literalCount(cover []implicant) int {
+	n := 0
+	for _, p := range cover {
+		n += p.literals()
+	}
+	return n
+}
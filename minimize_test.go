@@ -0,0 +1,119 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// equivalent reports whether trees `a` and `b` have the same result for every
+// combination of the results of their leaves.
+func equivalent(a, b *Node) bool {
+	ea, vars, _ := a.boolean(true)
+	eb, bvars, _ := b.boolean(true)
+	index := map[string]int{}
+	for i, v := range vars {
+		index[v] = i
+	}
+	for m := 0; m < 1<<len(vars); m++ {
+		va := make([]bool, len(vars))
+		for i := range va {
+			va[i] = m&(1<<i) != 0
+		}
+		vb := make([]bool, len(bvars))
+		for i, v := range bvars {
+			vb[i] = va[index[v]]
+		}
+		if ea.eval(va) != eb.eval(vb) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMinimize(t *testing.T) {
+	a, b, c := NewLeafNode("eq .A 1"), NewLeafNode("eq .B 1"), NewLeafNode("eq .C 1")
+	notB := NewLeafNode("not (eq .B 1)")
+	for _, tc := range []struct {
+		n        *Node
+		expected string
+	}{
+		{NewNode(OperatorOr, NewNode(OperatorAnd, a, b), NewNode(OperatorAnd, a, notB)), ".A == 1"},
+		{NewNode(OperatorOr, NewNode(OperatorAnd, a, b), NewNode(OperatorAnd, a, b, c), NewNode(OperatorAnd, c, a)), "(.A == 1 AND .B == 1) OR (.A == 1 AND .C == 1)"},
+		{NewNode(OperatorAnd, a, NewNode(OperatorOr, b, c)), "(.A == 1 AND .B == 1) OR (.A == 1 AND .C == 1)"},
+		{NewNode(OperatorOr, NewNode(OperatorAnd, NewLeafNode("not  (eq .A 1)"), b), NewNode(OperatorAnd, a, b), c), ".B == 1 OR .C == 1"},
+		{NewNode(OperatorOr, NewNode(OperatorAnd, a, notB), NewNode(OperatorAnd, NewLeafNode("not (eq .A 1)"), b)), "(.A == 1 AND NOT (.B == 1)) OR (NOT (.A == 1) AND .B == 1)"},
+		{NewNode(OperatorOr, b, notB), "true"},
+		{NewNode(OperatorAnd, b, notB, a), "false"},
+		{NewNode(OperatorAnd, b, NewLeafNode("false")), "false"},
+		{NewNode(OperatorOr, NewNode(OperatorAnd, NewAdvancedLeafNode("{{ .X }}"), a), NewAdvancedLeafNode("{{ .X }}")), "{{ .X }}"},
+		{pricesTree(), "(.Milk >= 4 AND .Onions >= 1 AND .Milk <= 6 AND .Onions <= 2) OR .Toothpaste > 5"},
+	} {
+		m, err := tc.n.Minimize()
+		if err != nil {
+			t.Errorf("Minimize(%s) error: %s\n", tc.n, err.Error())
+			continue
+		}
+		if actual := m.Infix(); actual != tc.expected {
+			t.Errorf("Minimize(%s) expected=%s actual=%s\n", tc.n, tc.expected, actual)
+		}
+		if err := m.Validate(); err != nil {
+			t.Errorf("Minimize(%s) invalid tree: %s\n", tc.n, err.Error())
+		}
+	}
+}
+
+func TestMinimizeEquivalent(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	var random func(depth int) *Node
+	random = func(depth int) *Node {
+		if depth == 0 || rnd.Intn(3) == 0 {
+			leaf := fmt.Sprintf("eq .F%d 1", rnd.Intn(5))
+			if rnd.Intn(3) == 0 {
+				leaf = "not (" + leaf + ")"
+			}
+			return NewLeafNode(leaf)
+		}
+		n := NewNode([]Operator{OperatorAnd, OperatorOr}[rnd.Intn(2)])
+		for i := 0; i < 2+rnd.Intn(3); i++ {
+			n.Nodes = append(n.Nodes, random(depth-1))
+		}
+		return n
+	}
+
+	for i := 0; i < 200; i++ {
+		n := random(4)
+		m, err := n.Minimize()
+		if err != nil {
+			t.Fatalf("Minimize(%s) error: %s\n", n, err.Error())
+		}
+		if !equivalent(n, m) {
+			t.Fatalf("Minimize(%s) not equivalent: %s\n", n, m)
+		}
+		if again, _ := m.Minimize(); again.Infix() != m.Infix() {
+			t.Errorf("Minimize(%s) expected a fixed point=%s actual=%s\n", n, m, again)
+		}
+	}
+}
+
+func TestMinimizeLimits(t *testing.T) {
+	wide := NewNode(OperatorOr)
+	for i := 0; i < 11; i++ {
+		wide.Nodes = append(wide.Nodes, NewLeafNode(fmt.Sprintf("eq .A %d", i)))
+	}
+	if _, err := wide.Minimize(); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Minimize() expected=%v actual=%v\n", ErrLimitExceeded, err)
+	}
+	m, err := MinimizeOptions{MaxLeaves: 11}.Minimize(wide)
+	if err != nil || len(m.Nodes) != 11 {
+		t.Errorf("Minimize() expected the or of 11 leaves, got %v (%v)\n", m, err)
+	}
+	if _, err := NewNode(OperatorAnd).Minimize(); !errors.Is(err, ErrEmptyNode) {
+		t.Errorf("Minimize() expected=%v actual=%v\n", ErrEmptyNode, err)
+	}
+}
@@ -0,0 +1,129 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// MissingPolicy governs how a leaf which references a field that does not
+// exist in the data is evaluated.
+type MissingPolicy int
+
+const (
+	// MissingDefault leaves missing fields to text/template: missing map keys
+	// render as their zero value ("<no value>" when printed) and missing
+	// struct fields are an error.
+	MissingDefault MissingPolicy = iota
+
+	// MissingIsFalse evaluates any leaf referencing a missing field to false
+	// without executing it.
+	MissingIsFalse
+
+	// MissingIsError fails the evaluation with `ErrMissingField`.
+	MissingIsError
+)
+
+func (p MissingPolicy) String() string {
+	switch p {
+	case MissingDefault:
+		return "default"
+	case MissingIsFalse:
+		return "false"
+	case MissingIsError:
+		return "error"
+	}
+	return fmt.Sprintf("MissingPolicy(%d)", int(p))
+}
+
+// MarshalText encodes the policy as its name, "default", "false" or "error",
+// so that it reads naturally in JSON and YAML config files.
+func (p MissingPolicy) MarshalText() ([]byte, error) {
+	switch p {
+	case MissingDefault, MissingIsFalse, MissingIsError:
+		return []byte(p.String()), nil
+	}
+	return nil, fmt.Errorf("%w: unknown missing policy %d", ErrInvalidConfig, int(p))
+}
+
+// UnmarshalText decodes a policy encoded by `MarshalText`.  The empty string
+// is the default policy.
+func (p *MissingPolicy) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "default":
+		*p = MissingDefault
+	case "false":
+		*p = MissingIsFalse
+	case "error":
+		*p = MissingIsError
+	default:
+		return fmt.Errorf("%w: unknown missing policy %q", ErrInvalidConfig, text)
+	}
+	return nil
+}
+
+// WithMissing sets the policy for leaves which reference fields that do not
+// exist in the data.  A field is missing if a map has no entry for it, a
+// struct has no exported field of that name, or a value along its path is a
+// nil pointer or interface.  A field which exists with a nil value is not
+// missing.
+func WithMissing(p MissingPolicy) Option {
+	return func(o *compileOptions) {
+		o.missing = p
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// missingField returns the first of `fields` which does not exist in `data`,
+// formatted as it appears in a leaf.
+func missingField(data interface{}, fields [][]string) (string, bool) {
+	for _, f := range fields {
+		if !hasField(data, f) {
+			return "." + strings.Join(f, "."), true
+		}
+	}
+	return "", false
+}
+
+// hasField reports whether the field `path` can be resolved in `data`.  A
+// method found along the path is assumed to resolve the remainder, since it
+// cannot be called without side effects.
+func hasField(data interface{}, path []string) bool {
+	v := reflect.ValueOf(data)
+	for _, name := range path {
+		if v.IsValid() && v.MethodByName(name).IsValid() {
+			return true
+		}
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return false
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return false
+			}
+			v = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return false
+			}
+		case reflect.Struct:
+			sf, ok := v.Type().FieldByName(name)
+			if !ok || !sf.IsExported() {
+				return false
+			}
+			v = v.FieldByIndex(sf.Index)
+		default:
+			return false
+		}
+	}
+	return true
+}
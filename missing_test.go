@@ -0,0 +1,125 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type dairy struct {
+	Milk   int
+	cheese int
+}
+
+type basket struct {
+	Dairy *dairy
+	Extra map[string]interface{}
+}
+
+func (b basket) Total() int { return 1 }
+
+func TestHasField(t *testing.T) {
+	b := &basket{Dairy: &dairy{Milk: 5}, Extra: map[string]interface{}{"Eggs": nil}}
+	for _, tc := range []struct {
+		data     interface{}
+		path     []string
+		expected bool
+	}{
+		{b, []string{"Dairy", "Milk"}, true},
+		{b, []string{"Dairy", "cheese"}, false},
+		{b, []string{"Dairy", "Butter"}, false},
+		{b, []string{"Extra", "Eggs"}, true},
+		{b, []string{"Extra", "Ham"}, false},
+		{b, []string{"Total"}, true},
+		{&basket{}, []string{"Dairy", "Milk"}, false},
+		{map[string]interface{}{"A": map[string]interface{}{"B": 1}}, []string{"A", "B"}, true},
+		{map[string]interface{}{"A": 1}, []string{"A", "B"}, false},
+		{nil, []string{"A"}, false},
+	} {
+		if v := hasField(tc.data, tc.path); v != tc.expected {
+			t.Errorf("hasField(%#v, %v) expected=%v actual=%v\n", tc.data, tc.path, tc.expected, v)
+		}
+	}
+}
+
+func TestWithMissing(t *testing.T) {
+	tree := NewNode(OperatorOr,
+		NewLeafNode("gt .Price 4"),
+		NewLeafNode(`eq .Name "milk"`))
+	data := map[string]interface{}{"Name": "milk"}
+
+	ct, err := Compile(tree)
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if _, err := ct.Evaluate(data); err == nil {
+		t.Errorf("Evaluate() expected the default policy to fail comparing <no value>\n")
+	}
+
+	ct, err = Compile(tree, WithMissing(MissingIsFalse))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if v, err := ct.Evaluate(data); err != nil || !v {
+		t.Errorf("Evaluate() expected=true actual=%v (%v)\n", v, err)
+	}
+	if v, err := ct.Evaluate(&basket{}); err != nil || v {
+		t.Errorf("Evaluate() expected=false actual=%v (%v)\n", v, err)
+	}
+
+	ct, err = Compile(tree, WithMissing(MissingIsError))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	_, err = ct.Evaluate(data)
+	if !errors.Is(err, ErrMissingField) {
+		t.Fatalf("Evaluate() expected a missing field error, got: %v\n", err)
+	}
+	if err.Error() != "/0: missing field: .Price" {
+		t.Errorf("Evaluate() unexpected error: %s\n", err.Error())
+	}
+}
+
+func TestFields(t *testing.T) {
+	ct, err := Compile(NewNode(OperatorAnd,
+		NewLeafNode("gt .Dairy.Milk 4"),
+		NewLeafNode("lt .Dairy.Milk $.Limit"),
+		NewLeafNode("eq (len .Extra) 0")))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	expected := []string{"Dairy.Milk", "Limit", "Extra"}
+	fields := ct.Fields()
+	if len(fields) != len(expected) {
+		t.Fatalf("Fields() expected=%v actual=%v\n", expected, fields)
+	}
+	for i, f := range fields {
+		if strings.Join(f, ".") != expected[i] {
+			t.Errorf("Fields()[%d] expected=%s actual=%v\n", i, expected[i], f)
+		}
+	}
+}
+
+func TestOpaque(t *testing.T) {
+	for _, tc := range []struct {
+		n        *Node
+		expected bool
+	}{
+		{NewNode(OperatorAnd, NewLeafNode("gt .Dairy.Milk 4"), NewLeafNode("eq (len .Extra) 0")), false},
+		{NewNode(OperatorAnd, NewLeafNode("gt .Dairy.Milk 4"), NewLeafNode(`eq (index . "Extra") 0`)), true},
+		{NewNode(OperatorOr, NewLeafNode("lt .A 1"), NewAdvancedLeafNode("{{ eq .A 1 }}")), true},
+	} {
+		ct, err := Compile(tc.n)
+		if err != nil {
+			t.Fatalf("Compile(%s) error: %s\n", tc.n, err.Error())
+		}
+		if actual := ct.Opaque(); actual != tc.expected {
+			t.Errorf("Opaque(%s) expected=%v actual=%v\n", tc.n, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Policy is the policy of the trees of a namespace of a registry, see
+// `Registry.Namespace`: the functions and macros its leaves may use, and the
+// options they are compiled with.
+type Policy struct {
+	// Funcs are the functions of the leaves of the namespace, besides those
+	// given to `Registry.Compile`.
+	Funcs template.FuncMap
+
+	// AllowedFuncs, if not nil, are the only functions the leaves of the
+	// namespace may call, see `WithAllowedFuncs`, whatever the options given
+	// to `Registry.Compile`.
+	AllowedFuncs []string
+
+	// Macros are the macros of the namespace by name, as `RegisterMacro`
+	// defines for every tree, which only its trees may use.  They are
+	// expanded before those registered, and shadow them.
+	Macros map[string]string
+
+	// Options are the other options of the trees of the namespace, applied
+	// after those given to `Registry.Compile`.
+	Options []Option
+}
+
+// Namespace returns the registry of the namespace `name`, such as that of a
+// tenant of a shared rules service, created empty if there is none.  The
+// trees of a namespace are isolated from those of the registry and of its
+// other namespaces: their references only resolve to the trees of their own
+// namespace, and they may only use its macros, besides those registered for
+// every tree, see `Policy`.  Namespaces are not encoded by `MarshalJSON`,
+// each is a registry of its own.
+func (r *Registry) Namespace(name string) *Registry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ns, ok := r.namespaces[name]
+	if !ok {
+		if r.namespaces == nil {
+			r.namespaces = map[string]*Registry{}
+		}
+		ns = &Registry{}
+		r.namespaces[name] = ns
+	}
+	return ns
+}
+
+// Namespaces returns the names of the namespaces of the registry, sorted.
+func (r *Registry) Namespaces() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.namespaces))
+	for name := range r.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DeleteNamespace removes the namespace `name` and its trees, reporting
+// whether there was one.
+func (r *Registry) DeleteNamespace(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.namespaces[name]
+	delete(r.namespaces, name)
+	return ok
+}
+
+// SetPolicy sets the policy of the trees of the registry, typically that of
+// a namespace, replacing any other.  Macros which are not valid, as for
+// `RegisterMacro`, fail with an error wrapping `ErrInvalidConfig`, leaving
+// the policy as it was.
+func (r *Registry) SetPolicy(p Policy) error {
+	names := make([]string, 0, len(p.Macros))
+	for name := range p.Macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	macros := make(map[string]string, len(p.Macros))
+	for _, name := range names {
+		expr := "(" + strings.TrimSpace(p.Macros[name]) + ")"
+		if !isIdentifier(name) || isCustom(Operator(name)) || isBuiltin(Operator(name)) {
+			return fmt.Errorf("%w: macro %q is not a valid name", ErrInvalidConfig, name)
+		}
+		t, defined, err := parseTemplate("{{ " + listSource(expr) + " }}")
+		if err == nil {
+			err = checkExpression(t, defined)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: macro %q is not a single expression: %v", ErrInvalidConfig, name, err)
+		}
+		macros[name] = expr
+	}
+	for _, name := range names {
+		if cycle := nsMacroCycle(macros, name, macros[name], []string{name}); cycle != nil {
+			return fmt.Errorf("%w: macro %q expands into itself: %s", ErrInvalidConfig, name, strings.Join(cycle, " -> "))
+		}
+	}
+	p.Macros = macros
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policy = &p
+	r.changed()
+	return nil
+}
+
+// macros returns the macros of the policy as they were given to
+// `SetPolicy`, which holds them parenthesized.
+func (p *Policy) macros() map[string]string {
+	macros := make(map[string]string, len(p.Macros))
+	for name, expr := range p.Macros {
+		macros[name] = expr[1 : len(expr)-1]
+	}
+	return macros
+}
+
+// getPolicy returns the policy of the registry, nil if it has none.
+func (r *Registry) getPolicy() *Policy {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.policy
+}
+
+// prepare returns the tree `n` of a registry as it is compiled: with its
+// references resolved by `res` and the macros of the policy `p`, if any,
+// expanded.
+func prepare(n *Node, p *Policy, res Resolver) (*Node, error) {
+	n, err := n.Resolve(res)
+	if err != nil {
+		return nil, err
+	}
+	if p != nil && len(p.Macros) > 0 {
+		n.RewriteLeaves(func(expr string) (string, error) {
+			return nsExpandWords(p.Macros, expr), nil
+		})
+	}
+	return n, nil
+}
+
+// options returns the options of the policy, applied after those given to
+// `Registry.Compile`.
+func (p *Policy) options() []Option {
+	var opts []Option
+	if p.Funcs != nil {
+		opts = append(opts, WithFuncs(p.Funcs))
+	}
+	opts = append(opts, p.Options...)
+	if p.AllowedFuncs != nil {
+		// The allowlist of the policy replaces those of the other options.
+		opts = append(opts, func(o *compileOptions) { o.allowed = nil }, WithAllowedFuncs(p.AllowedFuncs...))
+	}
+	return opts
+}
+
+// nsMacroCycle is `macroCycle` for the macros of a namespace.
+func nsMacroCycle(macros map[string]string, name, expr string, stack []string) []string {
+	var cycle []string
+	mapWords(expr, func(word string) string {
+		if cycle != nil {
+			return word
+		}
+		if word == name {
+			cycle = append(append([]string{}, stack...), name)
+		} else if e, ok := macros[word]; ok {
+			cycle = nsMacroCycle(macros, name, e, append(stack, word))
+		}
+		return word
+	})
+	return cycle
+}
+
+// nsExpandWords is `expandWords` for the macros of a namespace, which never
+// expand into themselves.
+func nsExpandWords(macros map[string]string, s string) string {
+	return mapWords(s, func(word string) string {
+		if e, ok := macros[word]; ok {
+			return nsExpandWords(macros, e)
+		}
+		return word
+	})
+}
@@ -0,0 +1,112 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestNamespace(t *testing.T) {
+	var r Registry
+	acme, globex := r.Namespace("acme"), r.Namespace("globex")
+	if r.Namespace("acme") != acme {
+		t.Errorf("Namespace(acme) expected the same namespace\n")
+	}
+	if names := r.Namespaces(); !reflect.DeepEqual(names, []string{"acme", "globex"}) {
+		t.Errorf("Namespaces() expected=[acme globex] actual=%v\n", names)
+	}
+
+	if err := acme.SetPolicy(Policy{
+		Funcs:  template.FuncMap{"tier": func() string { return "gold" }},
+		Macros: map[string]string{"isAdult": "ge .Age 18", "isGold": `eq tier "gold"`},
+	}); err != nil {
+		t.Fatalf("SetPolicy(acme) error: %s\n", err.Error())
+	}
+	if err := globex.SetPolicy(Policy{AllowedFuncs: []string{"ge"}}); err != nil {
+		t.Fatalf("SetPolicy(globex) error: %s\n", err.Error())
+	}
+	for _, tc := range []struct {
+		r    *Registry
+		name string
+		n    *Node
+	}{
+		{acme, "base", NewLeafNode("and isAdult isGold")},
+		{acme, "rule", NewNode(OperatorAnd, NewRefNode("base"), NewLeafNode("gt .Amount 100"))},
+		{globex, "adult", NewLeafNode("ge .Age 21")},
+		{globex, "borrowed", NewRefNode("base")},
+		{globex, "macro", NewLeafNode("isAdult")},
+		{&r, "base", NewLeafNode("true")},
+	} {
+		if err := tc.r.Register(tc.name, tc.n); err != nil {
+			t.Fatalf("Register(%s) error: %s\n", tc.name, err.Error())
+		}
+	}
+
+	ct, err := acme.Compile("rule")
+	if err != nil {
+		t.Fatalf("Compile(rule) error: %s\n", err.Error())
+	}
+	if v, err := ct.Evaluate(map[string]interface{}{"Age": 20, "Amount": 150}); err != nil || !v {
+		t.Errorf("Evaluate(rule) expected=true actual=%v %v\n", v, err)
+	}
+
+	// The trees and macros of a namespace are its own.
+	if _, err := globex.Compile("borrowed"); !errors.Is(err, ErrUnresolvedRef) {
+		t.Errorf("Compile(borrowed) expected=%v actual=%v\n", ErrUnresolvedRef, err)
+	}
+	if _, err := globex.Compile("macro"); err == nil {
+		t.Errorf("Compile(macro) expected an error\n")
+	}
+	if _, err := globex.Compile("adult"); err != nil {
+		t.Errorf("Compile(adult) error: %s\n", err.Error())
+	}
+	if _, err := r.Compile("rule"); !errors.Is(err, ErrUnresolvedRef) {
+		t.Errorf("Compile(rule) expected=%v actual=%v\n", ErrUnresolvedRef, err)
+	}
+
+	// The allowlist of a policy cannot be widened.
+	if err := globex.Register("adult", NewLeafNode("lt .Age 21")); err != nil {
+		t.Fatalf("Register(adult) error: %s\n", err.Error())
+	}
+	if _, err := globex.Compile("adult", WithAllowedFuncs("lt")); !errors.Is(err, ErrFuncNotAllowed) {
+		t.Errorf("Compile(adult) expected=%v actual=%v\n", ErrFuncNotAllowed, err)
+	}
+
+	// Fingerprints change with the macros of the policy.
+	fp, _ := acme.Fingerprint("rule")
+	if err := acme.SetPolicy(Policy{
+		Funcs:  template.FuncMap{"tier": func() string { return "gold" }},
+		Macros: map[string]string{"isAdult": "ge .Age 21", "isGold": `eq tier "gold"`},
+	}); err != nil {
+		t.Fatalf("SetPolicy(acme) error: %s\n", err.Error())
+	}
+	if next, _ := acme.Fingerprint("rule"); next == fp {
+		t.Errorf("Fingerprint(rule) expected a new fingerprint, got %s\n", next)
+	}
+
+	for _, macros := range []map[string]string{
+		{"bad name": "true"},
+		{"and": "true"},
+		{"open": `eq .A "x`},
+		{"a": "not b", "b": "not a"},
+	} {
+		if err := acme.SetPolicy(Policy{Macros: macros}); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("SetPolicy(%v) expected=%v actual=%v\n", macros, ErrInvalidConfig, err)
+		}
+	}
+	if ct, err := acme.Compile("rule"); err != nil || !reflect.DeepEqual(ct.Fields(), [][]string{{"Age"}, {"Amount"}}) {
+		t.Errorf("SetPolicy() expected the policy to be kept, got %v\n", err)
+	}
+
+	if !r.DeleteNamespace("acme") || r.DeleteNamespace("acme") {
+		t.Errorf("DeleteNamespace(acme) expected=true then false\n")
+	}
+	if names := r.Namespaces(); !reflect.DeepEqual(names, []string{"globex"}) {
+		t.Errorf("Namespaces() expected=[globex] actual=%v\n", names)
+	}
+}
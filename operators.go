@@ -0,0 +1,303 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// OperatorImpl is a custom operator combining the results of the children of
+// a node, see `RegisterOperator`.
+type OperatorImpl interface {
+	// Evaluate returns the result of a node given the results of all of its
+	// children, in order.
+	Evaluate(results []bool) bool
+}
+
+// OperatorFunc adapts a function to an `OperatorImpl`.
+type OperatorFunc func(results []bool) bool
+
+// Evaluate calls `f`.
+func (f OperatorFunc) Evaluate(results []bool) bool {
+	return f(results)
+}
+
+// PayloadOperator is an `OperatorImpl` whose nodes are configured by a
+// payload of their own, such as the k of a k-of-n operator or the weights of
+// a weighted vote, rather than by leaves smuggling it in.  The payload of a
+// node is held as JSON in its `Leaf`, see `NewPayloadNode`, and encoded in
+// JSON as its "Payload", a value of any type rather than a string, so that it
+// is written naturally in JSON documents and in the YAML files of package
+// `loader`.  Other encodings hold the JSON of the payload as the leaf.
+type PayloadOperator interface {
+	OperatorImpl
+
+	// DecodePayload decodes the JSON payload `data` of a node, the JSON null
+	// for nodes without one, failing if it is not valid for the operator.
+	DecodePayload(data []byte) (interface{}, error)
+
+	// EvaluatePayload returns the result of a node of the decoded payload
+	// `payload` given the results of all of its children, in order.  It is
+	// called for the nodes of the operator rather than `Evaluate`.
+	EvaluatePayload(payload interface{}, results []bool) bool
+}
+
+var (
+	operatorsMu sync.RWMutex
+	operators   = map[Operator]OperatorImpl{}
+)
+
+// RegisterOperator defines the operator `name`, such as `majority`, which
+// combines the results of the children of a node with `op`.  Nodes of
+// registered operators are valid wherever `and` and `or` nodes are, and
+// need at least one child.  `Apply` combines their children as a call of the
+// template function `name`, which `GetTemplate` defines, and trees using them
+// are evaluated, compiled, validated, encoded and decoded like any others.
+//
+// Unlike `and` and `or`, the children of registered operators are always all
+// evaluated, in order, even by trees compiled `WithParallelism`, and the
+// order of the children is significant: they are not sorted by
+// `Canonicalize` or reordered by `CostModel.Reorder`.  They are encoded by
+// name in JSON, CBOR, S-expressions and the protocol buffers of
+// `logictreepb`, and decode wherever they are registered, but translations to
+// queries or Go code fail with `ErrNotTranslatable`.
+//
+// Operators are registered for the life of the program, typically from an
+// `init` function.  RegisterOperator panics if `name` is already an
+// operator, if it is not a valid template identifier, or the name of a keyword
+// or built-in function of templates, or if `op` is nil.
+func RegisterOperator(name string, op OperatorImpl) {
+	operatorsMu.Lock()
+	defer operatorsMu.Unlock()
+
+	switch o := Operator(name); {
+	case op == nil:
+		panic("logictree: RegisterOperator operator is nil")
+	case isBuiltin(o):
+		panic("logictree: RegisterOperator called for the built-in operator " + name)
+	case operators[o] != nil:
+		panic("logictree: RegisterOperator called twice for operator " + name)
+	case !isIdentifier(name):
+		panic("logictree: RegisterOperator operator name is not an identifier: " + name)
+	}
+	operators[Operator(name)] = op
+}
+
+// IsRegisteredOperator reports whether `op` is an operator registered by
+// `RegisterOperator`, for encodings of trees outside of the package to carry
+// registered operators by name.
+func IsRegisteredOperator(op Operator) bool {
+	return isCustom(op)
+}
+
+// customOperator returns the implementation of the registered operator `op`.
+func customOperator(op Operator) (OperatorImpl, bool) {
+	operatorsMu.RLock()
+	defer operatorsMu.RUnlock()
+	impl, ok := operators[op]
+	return impl, ok
+}
+
+// NewPayloadNode returns a node of the registered `PayloadOperator` `op`
+// combining the children `cs`, configured by the JSON encoding of `payload`.
+// Payloads which do not encode, or which the operator does not decode, fail
+// with an error wrapping `ErrInvalidOperator`, as do operators which are not
+// registered with payloads.
+func NewPayloadNode(op Operator, payload interface{}, cs ...*Node) (*Node, error) {
+	bs, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: payload of %s: %v", ErrInvalidOperator, op, err)
+	}
+	n := &Node{Op: op, Nodes: cs, Leaf: string(bs)}
+	if _, ok := payloadOperator(op); !ok {
+		return nil, fmt.Errorf("%w: %s is not registered with a payload", ErrInvalidOperator, op)
+	}
+	if _, err := bindOperator(n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// payloadOperator returns the registered operator `op` if it has payloads.
+func payloadOperator(op Operator) (PayloadOperator, bool) {
+	impl, ok := customOperator(op)
+	if !ok {
+		return nil, false
+	}
+	p, ok := impl.(PayloadOperator)
+	return p, ok
+}
+
+// bindOperator returns the implementation of the registered operator of the
+// node `n`, combining the results of its children with its payload for a
+// `PayloadOperator`.  Payloads the operator does not decode fail with an
+// error wrapping `ErrInvalidOperator`.
+func bindOperator(n *Node) (OperatorImpl, error) {
+	impl, ok := customOperator(n.Op)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidOperator, string(n.Op))
+	}
+	p, ok := impl.(PayloadOperator)
+	if !ok {
+		return impl, nil
+	}
+	v, err := decodePayload(p, n.Leaf)
+	if err != nil {
+		return nil, err
+	}
+	return OperatorFunc(func(results []bool) bool {
+		return p.EvaluatePayload(v, results)
+	}), nil
+}
+
+// decodePayload decodes the payload `leaf` of a node of `p`.
+func decodePayload(p PayloadOperator, leaf string) (interface{}, error) {
+	data := []byte(leaf)
+	if strings.TrimSpace(leaf) == "" {
+		data = []byte("null")
+	}
+	v, err := p.DecodePayload(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid payload: %v", ErrInvalidOperator, err)
+	}
+	return v, nil
+}
+
+// isBuiltin reports whether `op` is one of the operators of the package.
+func isBuiltin(op Operator) bool {
+	switch op {
+	case OperatorLeaf, OperatorAnd, OperatorOr, OperatorAdvanced, OperatorIf, OperatorSwitch, OperatorCase, OperatorRef:
+		return true
+	}
+	return false
+}
+
+// isCustom reports whether `op` is a registered operator.
+func isCustom(op Operator) bool {
+	_, ok := customOperator(op)
+	return ok
+}
+
+// customOperators returns the names of the registered operators, sorted.
+func customOperators() []Operator {
+	operatorsMu.RLock()
+	defer operatorsMu.RUnlock()
+	ops := make([]Operator, 0, len(operators))
+	for op := range operators {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i] < ops[j] })
+	return ops
+}
+
+// isIdentifier reports whether `name` can name a template function without
+// shadowing a keyword or a built-in function of templates.
+func isIdentifier(name string) bool {
+	switch name {
+	case "", "block", "break", "continue", "define", "else", "end", "if", "range", "nil", "template", "with",
+		"true", "false", "and", "or", "not", "len", "index", "slice", "print", "printf", "println",
+		"html", "js", "urlquery", "call", "eq", "ne", "lt", "le", "gt", "ge":
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// withOperators returns `fm` with a template function for every registered
+// operator it does not define itself, as the templates of `Apply` call them.
+func withOperators(fm template.FuncMap) template.FuncMap {
+	ops := customOperators()
+	if len(ops) == 0 {
+		return fm
+	}
+
+	out := make(template.FuncMap, len(fm)+len(ops))
+	for name, f := range fm {
+		out[name] = f
+	}
+	for _, op := range ops {
+		if _, ok := out[string(op)]; ok {
+			continue
+		}
+		impl, _ := customOperator(op)
+		if p, ok := impl.(PayloadOperator); ok {
+			// The payload is given first, see `Node.combine`.
+			out[string(op)] = func(leaf string, args ...interface{}) (bool, error) {
+				v, err := decodePayload(p, leaf)
+				if err != nil {
+					return false, err
+				}
+				results, err := templateResults(args)
+				if err != nil {
+					return false, err
+				}
+				return p.EvaluatePayload(v, results), nil
+			}
+			continue
+		}
+		out[string(op)] = func(args ...interface{}) (bool, error) {
+			results, err := templateResults(args)
+			if err != nil {
+				return false, err
+			}
+			return impl.Evaluate(results), nil
+		}
+	}
+	return out
+}
+
+// templateResults returns the truth of the arguments `args` of the template
+// function of a registered operator.
+func templateResults(args []interface{}) ([]bool, error) {
+	results := make([]bool, len(args))
+	for i, a := range args {
+		v, ok := template.IsTrue(a)
+		if !ok {
+			return nil, fmt.Errorf("%w: %v", ErrNotBoolean, a)
+		}
+		results[i] = v
+	}
+	return results, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// evaluateCustom evaluates every child of a node of a registered operator,
+// in order, and combines their results.
+func (cn *compiledNode) evaluateCustom(st *evalState, data interface{}) (bool, error) {
+	results := make([]bool, len(cn.children))
+	for i, c := range cn.children {
+		v, err := c.evaluate(st, data)
+		if err != nil {
+			return false, err
+		}
+		results[i] = v
+	}
+	return cn.custom.Evaluate(results), nil
+}
+
+// customTruth combines the three-valued results of the children of a node of
+// the registered operator `impl`: unknown if any of them is, as either value
+// could change the result.
+func customTruth(impl OperatorImpl, vs []Truth) Truth {
+	results := make([]bool, len(vs))
+	for i, v := range vs {
+		if v == Unknown {
+			return Unknown
+		}
+		results[i] = v == True
+	}
+	return truth(impl.Evaluate(results))
+}
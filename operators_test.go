@@ -0,0 +1,397 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// registerTestOperator registers an operator for the duration of the test.
+func registerTestOperator(t *testing.T, name string, op OperatorImpl) {
+	RegisterOperator(name, op)
+	t.Cleanup(func() {
+		operatorsMu.Lock()
+		defer operatorsMu.Unlock()
+		delete(operators, Operator(name))
+	})
+}
+
+var (
+	// majority is true if more than half of its children are.
+	majority = OperatorFunc(func(results []bool) bool {
+		n := 0
+		for _, r := range results {
+			if r {
+				n++
+			}
+		}
+		return 2*n > len(results)
+	})
+	// implies is true if its first child is false or all of the others are
+	// true.
+	implies = OperatorFunc(func(results []bool) bool {
+		for _, r := range results[1:] {
+			if !r {
+				return !results[0]
+			}
+		}
+		return true
+	})
+)
+
+func TestRegisterOperator(t *testing.T) {
+	registerTestOperator(t, "majority", majority)
+	for _, tc := range []struct {
+		name string
+		op   OperatorImpl
+	}{
+		{"majority", majority},
+		{"and", majority},
+		{"leaf", majority},
+		{"not", majority},
+		{"if", majority},
+		{"", majority},
+		{"2of3", majority},
+		{"at-least", majority},
+		{"minority", nil},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RegisterOperator(%q) expected a panic\n", tc.name)
+				}
+			}()
+			RegisterOperator(tc.name, tc.op)
+		}()
+	}
+}
+
+func TestCustomOperator(t *testing.T) {
+	registerTestOperator(t, "majority", majority)
+	registerTestOperator(t, "implies", implies)
+
+	n := NewNode(OperatorOr,
+		NewNode("majority", NewLeafNode("gt .A 1"), NewLeafNode("gt .B 1"), NewLeafNode("gt .C 1")),
+		NewNode("implies", NewLeafNode("eq .A 0"), NewLeafNode("eq .B 0")))
+	if err := n.Validate(); err != nil {
+		t.Fatalf("Validate() error: %s\n", err.Error())
+	}
+	if err := NewNode("majority").Validate(); !errors.Is(err, ErrEmptyNode) {
+		t.Errorf("Validate(empty) expected=%v actual=%v\n", ErrEmptyNode, err)
+	}
+	if err := NewNode("minority", NewLeafNode("true")).Validate(); !errors.Is(err, ErrInvalidOperator) {
+		t.Errorf("Validate(unregistered) expected=%v actual=%v\n", ErrInvalidOperator, err)
+	}
+
+	tmpl, err := n.GetTemplate(nil)
+	if err != nil {
+		t.Fatalf("GetTemplate() error: %s\n", err.Error())
+	}
+	plain, err := Compile(n)
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	parallel, err := Compile(n, WithParallelism(4))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	rows := []map[string]interface{}{
+		{"A": 2, "B": 2, "C": 0}, // a majority
+		{"A": 2, "B": 0, "C": 0}, // no majority, but A is not 0
+		{"A": 0, "B": 0, "C": 0}, // A is 0, so B is
+		{"A": 0, "B": 1, "C": 2}, // A is 0 but B is not
+		{"A": 0, "B": 5, "C": 5}, // a majority, although B is not 0
+	}
+	expected := []bool{true, true, true, false, true}
+	cols := Columns{"A": {Int64: []int64{2, 2, 0, 0, 0}}, "B": {Int64: []int64{2, 0, 0, 1, 5}}, "C": {Int64: []int64{0, 0, 0, 2, 5}}}
+	batch, err := plain.EvaluateBatch(cols)
+	if err != nil {
+		t.Fatalf("EvaluateBatch() error: %s\n", err.Error())
+	}
+	for i, data := range rows {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil || buf.String() != strconv.FormatBool(expected[i]) {
+			t.Errorf("Execute(%v) expected=%v actual=%s err=%v\n", data, expected[i], buf.String(), err)
+		}
+		for _, ct := range []*CompiledTree{plain, parallel} {
+			if v, err := ct.Evaluate(data); err != nil || v != expected[i] {
+				t.Errorf("Evaluate(%v) expected=%v actual=%v err=%v\n", data, expected[i], v, err)
+			}
+		}
+		if res, err := plain.EvaluateKleene(data); err != nil || res.Value != truth(expected[i]) {
+			t.Errorf("EvaluateKleene(%v) expected=%v actual=%v err=%v\n", data, expected[i], res.Value, err)
+		}
+		if e, err := plain.Explain(data); err != nil || e.Result != expected[i] {
+			t.Errorf("Explain(%v) expected=%v actual=%v err=%v\n", data, expected[i], e, err)
+		}
+		if batch.Get(i) != expected[i] {
+			t.Errorf("EvaluateBatch(%d) expected=%v actual=%v\n", i, expected[i], batch.Get(i))
+		}
+	}
+
+	// Unknown children leave the result unknown.
+	if res, err := plain.EvaluateKleene(map[string]interface{}{"A": 2}); err != nil || res.Value != Unknown {
+		t.Errorf("EvaluateKleene() expected=%v actual=%v err=%v\n", Unknown, res.Value, err)
+	}
+}
+
+func TestCustomOperatorTransforms(t *testing.T) {
+	registerTestOperator(t, "implies", implies)
+	n := NewNode("implies", NewLeafNode("eq .B 0"), NewLeafNode("eq .A 0"))
+
+	// Encodings round trip, with the operator at the root and nested.
+	for _, tree := range []*Node{n, NewNode(OperatorOr, NewLeafNode(".C"), n)} {
+		bs, err := json.Marshal(tree)
+		if err != nil {
+			t.Fatalf("Marshal() error: %s\n", err.Error())
+		}
+		var decoded Node
+		if err := json.Unmarshal(bs, &decoded); err != nil || !reflect.DeepEqual(&decoded, tree) {
+			t.Errorf("Unmarshal() expected=%v actual=%v err=%v\n", tree, &decoded, err)
+		}
+		bs, err = tree.MarshalCBOR()
+		if err != nil {
+			t.Fatalf("MarshalCBOR() error: %s\n", err.Error())
+		}
+		decoded = Node{}
+		if err := decoded.UnmarshalCBOR(bs); err != nil || !reflect.DeepEqual(&decoded, tree) {
+			t.Errorf("UnmarshalCBOR() expected=%v actual=%v err=%v\n", tree, &decoded, err)
+		}
+		if parsed, err := ParseSexpr(tree.Sexpr()); err != nil || !reflect.DeepEqual(parsed, tree) {
+			t.Errorf("ParseSexpr(%s) expected=%v actual=%v err=%v\n", tree.Sexpr(), tree, parsed, err)
+		}
+	}
+	if !IsRegisteredOperator("implies") || IsRegisteredOperator("minority") || IsRegisteredOperator(OperatorAnd) {
+		t.Errorf("IsRegisteredOperator() expected only implies to be registered\n")
+	}
+	if s := n.Infix(); s != "implies(.B == 0, .A == 0)" {
+		t.Errorf("Infix() expected=%s actual=%s\n", "implies(.B == 0, .A == 0)", s)
+	}
+
+	// The order of the children is kept.
+	if c := n.Canonicalize(); c.Nodes[0].Leaf != "(eq .B 0)" {
+		t.Errorf("Canonicalize() expected the order kept, got %s\n", c.Infix())
+	}
+
+	for _, tc := range []struct {
+		known    map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"B": 1}, "implies(false, .A == 0)"},
+		{map[string]interface{}{"A": 0, "B": 1}, "true"},
+		{map[string]interface{}{"A": 1, "B": 0}, "false"},
+	} {
+		r, err := n.PartialEval(tc.known)
+		if err != nil || r.Infix() != tc.expected {
+			t.Errorf("PartialEval(%v) expected=%s actual=%v err=%v\n", tc.known, tc.expected, r, err)
+		}
+	}
+
+	if ok, _, err := NewNode(OperatorAnd, n, NewLeafNode("not (eq .B 0)"), NewLeafNode("not (eq .A 0)")).Satisfiable(); err != nil || !ok {
+		t.Errorf("Satisfiable() expected=true actual=%v err=%v\n", ok, err)
+	}
+	if ok, _, err := NewNode(OperatorAnd, n, NewLeafNode("eq .B 0"), NewLeafNode("not (eq .A 0)")).Satisfiable(); err != nil || ok {
+		t.Errorf("Satisfiable() expected=false actual=%v err=%v\n", ok, err)
+	}
+
+	// Translations fail at the path of the operator.
+	nested := NewNode(OperatorAnd, NewLeafNode("eq .C 1"), n)
+	if _, _, err := nested.ToSQL(DialectPostgres); !errors.Is(err, ErrNotTranslatable) || !strings.HasPrefix(err.Error(), "/1") {
+		t.Errorf("ToSQL() expected=%v at /1 actual=%v\n", ErrNotTranslatable, err)
+	}
+	if _, err := nested.ToESQuery(); !errors.Is(err, ErrNotTranslatable) || !strings.HasPrefix(err.Error(), "/1") {
+		t.Errorf("ToESQuery() expected=%v at /1 actual=%v\n", ErrNotTranslatable, err)
+	}
+	order := NewNode(OperatorAnd, NewLeafNode("eq .Milk 1"), NewNode("implies", NewLeafNode(".Fresh"), NewLeafNode("gt .Milk 0")))
+	if _, err := order.ToGo(reflect.TypeOf(goOrder{}), "v"); !errors.Is(err, ErrNotTranslatable) || !strings.HasPrefix(err.Error(), "/1") {
+		t.Errorf("ToGo() expected=%v at /1 actual=%v\n", ErrNotTranslatable, err)
+	}
+}
+
+// atLeast is true if at least k of its children are, k being its payload.
+type atLeast struct{}
+
+func (atLeast) Evaluate(results []bool) bool {
+	return atLeast{}.EvaluatePayload(1, results)
+}
+
+func (atLeast) DecodePayload(data []byte) (interface{}, error) {
+	var p struct {
+		K int `json:"k"`
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	if p.K < 1 {
+		return nil, errors.New("k must be positive")
+	}
+	return p.K, nil
+}
+
+func (atLeast) EvaluatePayload(payload interface{}, results []bool) bool {
+	n := 0
+	for _, r := range results {
+		if r {
+			n++
+		}
+	}
+	return n >= payload.(int)
+}
+
+func TestPayloadOperator(t *testing.T) {
+	registerTestOperator(t, "atLeast", atLeast{})
+	registerTestOperator(t, "implies", implies)
+	leaves := func() []*Node {
+		return []*Node{NewLeafNode("gt .A 1"), NewLeafNode("gt .B 1"), NewLeafNode("gt .C 1")}
+	}
+	two, err := NewPayloadNode("atLeast", map[string]int{"k": 2}, leaves()...)
+	if err != nil {
+		t.Fatalf("NewPayloadNode() error: %s\n", err.Error())
+	}
+	three, err := NewPayloadNode("atLeast", map[string]int{"k": 3}, leaves()...)
+	if err != nil {
+		t.Fatalf("NewPayloadNode() error: %s\n", err.Error())
+	}
+	if two.Leaf != `{"k":2}` {
+		t.Errorf("NewPayloadNode() expected=%s actual=%s\n", `{"k":2}`, two.Leaf)
+	}
+
+	// Payloads the operator does not decode are invalid operators.
+	if _, err := NewPayloadNode("atLeast", map[string]int{"k": 0}, leaves()...); !errors.Is(err, ErrInvalidOperator) {
+		t.Errorf("NewPayloadNode(k=0) expected=%v actual=%v\n", ErrInvalidOperator, err)
+	}
+	if _, err := NewPayloadNode("implies", nil, leaves()...); !errors.Is(err, ErrInvalidOperator) {
+		t.Errorf("NewPayloadNode(implies) expected=%v actual=%v\n", ErrInvalidOperator, err)
+	}
+	for _, bad := range []*Node{
+		{Op: "atLeast", Leaf: "two", Nodes: leaves()},
+		NewNode(OperatorOr, NewLeafNode(".D"), NewNode("atLeast", leaves()...)),
+	} {
+		if err := bad.Validate(); !errors.Is(err, ErrInvalidOperator) {
+			t.Errorf("Validate(%s) expected=%v actual=%v\n", bad, ErrInvalidOperator, err)
+		}
+		if _, err := Compile(bad); !errors.Is(err, ErrInvalidOperator) {
+			t.Errorf("Compile(%s) expected=%v actual=%v\n", bad, ErrInvalidOperator, err)
+		}
+	}
+
+	rows := []map[string]interface{}{
+		{"A": 2, "B": 2, "C": 2},
+		{"A": 2, "B": 0, "C": 2},
+		{"A": 0, "B": 0, "C": 2},
+	}
+	for _, tc := range []struct {
+		n        *Node
+		expected []bool
+	}{
+		{two, []bool{true, true, false}},
+		{three, []bool{true, false, false}},
+	} {
+		tmpl, err := tc.n.GetTemplate(nil)
+		if err != nil {
+			t.Fatalf("GetTemplate() error: %s\n", err.Error())
+		}
+		plain, err := Compile(tc.n)
+		if err != nil {
+			t.Fatalf("Compile() error: %s\n", err.Error())
+		}
+		parallel, err := Compile(tc.n, WithParallelism(4))
+		if err != nil {
+			t.Fatalf("Compile() error: %s\n", err.Error())
+		}
+		for i, data := range rows {
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, data); err != nil || buf.String() != strconv.FormatBool(tc.expected[i]) {
+				t.Errorf("Execute(%s, %v) expected=%v actual=%s err=%v\n", tc.n, data, tc.expected[i], buf.String(), err)
+			}
+			for _, ct := range []*CompiledTree{plain, parallel} {
+				if v, err := ct.Evaluate(data); err != nil || v != tc.expected[i] {
+					t.Errorf("Evaluate(%s, %v) expected=%v actual=%v err=%v\n", tc.n, data, tc.expected[i], v, err)
+				}
+			}
+			if e, err := plain.Explain(data); err != nil || e.Result != tc.expected[i] {
+				t.Errorf("Explain(%s, %v) expected=%v actual=%v err=%v\n", tc.n, data, tc.expected[i], e, err)
+			}
+		}
+	}
+
+	// Encodings carry the payload, JSON as a value of its own.
+	tree := NewNode(OperatorOr, NewLeafNode(".D"), two)
+	bs, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatalf("Marshal() error: %s\n", err.Error())
+	}
+	if !strings.Contains(string(bs), `"Payload":{"k":2}`) {
+		t.Errorf("Marshal() expected the payload as a value, got %s\n", bs)
+	}
+	var decoded Node
+	if err := json.Unmarshal(bs, &decoded); err != nil || !reflect.DeepEqual(&decoded, tree) {
+		t.Errorf("Unmarshal() expected=%v actual=%v err=%v\n", tree, &decoded, err)
+	}
+	if strict, err := (DecodeOptions{Strict: true}).Unmarshal(bs); err != nil || !reflect.DeepEqual(strict, tree) {
+		t.Errorf("Unmarshal(strict) expected=%v actual=%v err=%v\n", tree, strict, err)
+	}
+	bs, err = tree.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR() error: %s\n", err.Error())
+	}
+	decoded = Node{}
+	if err := decoded.UnmarshalCBOR(bs); err != nil || !reflect.DeepEqual(&decoded, tree) {
+		t.Errorf("UnmarshalCBOR() expected=%v actual=%v err=%v\n", tree, &decoded, err)
+	}
+	if parsed, err := ParseSexpr(tree.Sexpr()); err != nil || !reflect.DeepEqual(parsed, tree) {
+		t.Errorf("ParseSexpr(%s) expected=%v actual=%v err=%v\n", tree.Sexpr(), tree, parsed, err)
+	}
+	if s := two.Infix(); s != `atLeast[{"k":2}](.A > 1, .B > 1, .C > 1)` {
+		t.Errorf("Infix() expected=%s actual=%s\n", `atLeast[{"k":2}](.A > 1, .B > 1, .C > 1)`, s)
+	}
+
+	// Payloads are written as JSON values, and only for payload operators.
+	doc := `{"Op": "atLeast", "Payload": {"k": 2}, "Nodes": [{"Op": "leaf", "Leaf": "(gt .A 1)"}, {"Op": "leaf", "Leaf": "(gt .B 1)"}, {"Op": "leaf", "Leaf": "(gt .C 1)"}]}`
+	if n, err := SafeUnmarshal([]byte(doc)); err != nil || !reflect.DeepEqual(n, two) {
+		t.Errorf("SafeUnmarshal() expected=%v actual=%v err=%v\n", two, n, err)
+	}
+	for _, bad := range []string{
+		`{"Op": "and", "Payload": {"k": 2}, "Nodes": [{"Op": "leaf", "Leaf": "true"}]}`,
+		`{"Op": "atLeast", "Payload": {"k": 2}, "Leaf": "{\"k\":2}", "Nodes": [{"Op": "leaf", "Leaf": "true"}]}`,
+	} {
+		if err := json.Unmarshal([]byte(bad), &Node{}); !errors.Is(err, ErrInvalidOperator) {
+			t.Errorf("Unmarshal(%s) expected=%v actual=%v\n", bad, ErrInvalidOperator, err)
+		}
+		if _, err := SafeUnmarshal([]byte(bad)); !errors.Is(err, ErrInvalidOperator) {
+			t.Errorf("SafeUnmarshal(%s) expected=%v actual=%v\n", bad, ErrInvalidOperator, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		known    map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"A": 2}, `atLeast[{"k":2}](true, .B > 1, .C > 1)`},
+		{map[string]interface{}{"A": 2, "B": 0, "C": 2}, "true"},
+		{map[string]interface{}{"A": 2, "B": 0, "C": 0}, "false"},
+	} {
+		r, err := two.PartialEval(tc.known)
+		if err != nil || r.Infix() != tc.expected {
+			t.Errorf("PartialEval(%v) expected=%s actual=%v err=%v\n", tc.known, tc.expected, r, err)
+		}
+	}
+	if ok, _, err := NewNode(OperatorAnd, two, NewLeafNode("not (gt .A 1)"), NewLeafNode("not (gt .B 1)")).Satisfiable(); err != nil || ok {
+		t.Errorf("Satisfiable() expected=false actual=%v err=%v\n", ok, err)
+	}
+
+	for _, os := range Describe().Operators {
+		if os.Op == "atLeast" && (!os.Payload || os.TruthTable != nil) {
+			t.Errorf("Describe() expected no truth tables of payload operators, got %+v\n", os)
+		}
+	}
+}
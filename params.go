@@ -0,0 +1,201 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// TreeTemplate is a tree whose leaves hold placeholders for parameters, such
+// as thresholds, written `$name`:
+//
+//	logictree.NewLeafNode("ge .Price $minPrice")
+//
+// Concrete trees are instantiated from it by binding every parameter to a
+// value, so that rules differing only in their constants are written once.
+// Placeholders are only replaced in ordinary leaves, not in advanced leaves,
+// whose templates may declare variables of their own.  A template is safe for
+// concurrent use.
+type TreeTemplate struct {
+	root   *Node
+	params []string
+}
+
+// NewTreeTemplate returns the template of the tree rooted at `n`, which must
+// be well formed, see `Validate`, once its placeholders are bound.  `n` is
+// copied, so later changes to it do not change the template.
+func NewTreeTemplate(n *Node) (*TreeTemplate, error) {
+	seen := map[string]bool{}
+	probe, err := bindParams(n, "/", func(name string) (string, error) {
+		seen[name] = true
+		return "0", nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := probe.Validate(); err != nil {
+		return nil, err
+	}
+
+	t := &TreeTemplate{root: n.copy(), params: make([]string, 0, len(seen))}
+	for name := range seen {
+		t.params = append(t.params, name)
+	}
+	sort.Strings(t.params)
+	return t, nil
+}
+
+// Params returns the names of the parameters of the template, sorted.
+func (t *TreeTemplate) Params() []string {
+	return append([]string{}, t.params...)
+}
+
+// Root returns a copy of the tree of the template, with its placeholders.
+func (t *TreeTemplate) Root() *Node {
+	return t.root.copy()
+}
+
+// Instantiate returns the tree of the template with the placeholders of the
+// parameters replaced by the values `params` binds them to, written as
+// template literals: strings, booleans, numbers and slices of them, the
+// latter as list literals such as `["US", "CA"]`.  Parameters left unbound,
+// bindings of unknown parameters and values of other types fail with an
+// error wrapping `ErrInvalidParam`, prefixed with the path of the offending
+// leaf where there is one.
+func (t *TreeTemplate) Instantiate(params map[string]interface{}) (*Node, error) {
+	for name := range params {
+		i := sort.SearchStrings(t.params, name)
+		if i == len(t.params) || t.params[i] != name {
+			return nil, fmt.Errorf("%w: unknown parameter %q", ErrInvalidParam, name)
+		}
+	}
+	return bindParams(t.root, "/", func(name string) (string, error) {
+		v, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("%w: $%s is not bound", ErrInvalidParam, name)
+		}
+		return paramLiteral(name, v)
+	})
+}
+
+// bindParams returns a copy of the tree rooted at `n`, at `path`, in which the
+// placeholders of its leaves are replaced by what `bind` returns for them.
+func bindParams(n *Node, path string, bind func(name string) (string, error)) (*Node, error) {
+	c := &Node{Op: n.Op, Leaf: n.Leaf}
+	if n.Op == OperatorLeaf {
+		leaf, err := replaceParams(n.Leaf, bind)
+		if err != nil {
+			return nil, nodeError(path, n, err)
+		}
+		c.Leaf = leaf
+	}
+	if n.Nodes != nil {
+		c.Nodes = make([]*Node, len(n.Nodes))
+		for i, child := range n.Nodes {
+			var err error
+			if c.Nodes[i], err = bindParams(child, childPath(path, i), bind); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c, nil
+}
+
+// replaceParams replaces the placeholders `$name` of the leaf expression
+// `leaf` by what `bind` returns for them.  Placeholders inside string, raw
+// string and character literals, as `leafSource` skips brackets, and `$`
+// alone, the data, are left alone.
+func replaceParams(leaf string, bind func(name string) (string, error)) (string, error) {
+	if !strings.ContainsRune(leaf, '$') {
+		return leaf, nil
+	}
+
+	var b strings.Builder
+	var quote byte
+	escaped := false
+	for i := 0; i < len(leaf); i++ {
+		c := leaf[i]
+		switch {
+		case quote != 0:
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\' && quote != '`':
+				escaped = true
+			case c == quote:
+				quote = 0
+			}
+		case c == '"' || c == '`' || c == '\'':
+			quote = c
+		case c == '$':
+			j := i + 1
+			for j < len(leaf) && isIdentByte(leaf[j], j == i+1) {
+				j++
+			}
+			if j == i+1 {
+				break
+			}
+			lit, err := bind(leaf[i+1 : j])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(lit)
+			i = j - 1
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String(), nil
+}
+
+// isIdentByte reports whether `c` may appear in the name of a parameter,
+// digits only after the `first` byte.
+func isIdentByte(c byte, first bool) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || !first && '0' <= c && c <= '9'
+}
+
+// paramLiteral writes the value `v` of the parameter `name` as a template
+// literal.
+func paramLiteral(name string, v interface{}) (string, error) {
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return formatLiteral(rv.String()), nil
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		if math.IsNaN(rv.Float()) || math.IsInf(rv.Float(), 0) {
+			return "", fmt.Errorf("%w: $%s is not a finite number", ErrInvalidParam, name)
+		}
+		if rv.Kind() == reflect.Float32 {
+			return formatLiteral(float32(rv.Float())), nil
+		}
+		return formatLiteral(rv.Float()), nil
+	case reflect.Slice, reflect.Array:
+		lits := make([]string, rv.Len())
+		for i := range lits {
+			e := rv.Index(i).Interface()
+			if k := reflect.ValueOf(e).Kind(); k == reflect.Slice || k == reflect.Array {
+				return "", fmt.Errorf("%w: $%s holds nested lists", ErrInvalidParam, name)
+			}
+			lit, err := paramLiteral(name, e)
+			if err != nil {
+				return "", err
+			}
+			lits[i] = lit
+		}
+		return "[" + strings.Join(lits, ", ") + "]", nil
+	}
+	return "", fmt.Errorf("%w: $%s cannot be bound to a %T", ErrInvalidParam, name, v)
+}
@@ -0,0 +1,84 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestTreeTemplate(t *testing.T) {
+	type level int
+	n := NewNode(OperatorAnd,
+		NewLeafNode("ge .Price $minPrice"),
+		NewLeafNode(`in .Country $countries`),
+		NewNode(OperatorOr, NewLeafNode(`eq .Tier $tier "$tier"`), NewLeafNode("ge $.Level $level")),
+		NewAdvancedLeafNode(`{{ $x := .Price }}{{ gt $x 0 }}`))
+	tmpl, err := NewTreeTemplate(n)
+	if err != nil {
+		t.Fatalf("NewTreeTemplate() error: %s\n", err.Error())
+	}
+	if params, expected := tmpl.Params(), []string{"countries", "level", "minPrice", "tier"}; !reflect.DeepEqual(params, expected) {
+		t.Errorf("Params() expected=%v actual=%v\n", expected, params)
+	}
+
+	for _, tc := range []struct {
+		params   map[string]interface{}
+		expected []string
+	}{
+		{
+			map[string]interface{}{"minPrice": 4, "countries": []string{"US", "CA"}, "tier": "gold", "level": level(2)},
+			[]string{"(ge .Price 4)", `(in .Country ["US", "CA"])`, `(eq .Tier "gold" "$tier")`, "(ge $.Level 2)"},
+		},
+		{
+			map[string]interface{}{"minPrice": 2.5, "countries": []interface{}{}, "tier": true, "level": uint8(1)},
+			[]string{"(ge .Price 2.5)", "(in .Country [])", `(eq .Tier true "$tier")`, "(ge $.Level 1)"},
+		},
+	} {
+		r, err := tmpl.Instantiate(tc.params)
+		if err != nil {
+			t.Errorf("Instantiate(%v) error: %s\n", tc.params, err.Error())
+			continue
+		}
+		leaves := []string{r.Nodes[0].Leaf, r.Nodes[1].Leaf, r.Nodes[2].Nodes[0].Leaf, r.Nodes[2].Nodes[1].Leaf}
+		if !reflect.DeepEqual(leaves, tc.expected) || r.Nodes[3].Leaf != n.Nodes[3].Leaf {
+			t.Errorf("Instantiate(%v) expected=%v actual=%v\n", tc.params, tc.expected, r)
+		}
+		if _, err := Compile(r, WithFuncs(StdFuncs())); err != nil {
+			t.Errorf("Compile(%v) error: %s\n", r, err.Error())
+		}
+	}
+
+	// The template is unchanged by instantiations and by its tree.
+	n.Nodes[0].Leaf = "(false)"
+	r, err := tmpl.Instantiate(map[string]interface{}{"minPrice": 4, "countries": []string{"US"}, "tier": "gold", "level": 1})
+	if err != nil {
+		t.Fatalf("Instantiate() error: %s\n", err.Error())
+	}
+	ct, err := Compile(r, WithFuncs(StdFuncs()))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	data := map[string]interface{}{"Price": 5, "Country": "US", "Tier": "gold", "Level": 0}
+	if v, err := ct.Evaluate(data); err != nil || !v {
+		t.Errorf("Evaluate(%v) expected=true actual=%v err=%v\n", data, v, err)
+	}
+
+	for _, params := range []map[string]interface{}{
+		{"minPrice": 4, "countries": []string{"US"}, "tier": "gold"},
+		{"minPrice": 4, "countries": []string{"US"}, "tier": "gold", "level": 1, "maxPrice": 10},
+		{"minPrice": map[string]int{}, "countries": []string{"US"}, "tier": "gold", "level": 1},
+		{"minPrice": 4, "countries": [][]string{{"US"}}, "tier": "gold", "level": 1},
+	} {
+		if _, err := tmpl.Instantiate(params); !errors.Is(err, ErrInvalidParam) {
+			t.Errorf("Instantiate(%v) expected=%v actual=%v\n", params, ErrInvalidParam, err)
+		}
+	}
+
+	if _, err := NewTreeTemplate(NewNode(OperatorAnd, NewLeafNode("ge .Price ($min"))); err == nil {
+		t.Errorf("NewTreeTemplate() expected an error\n")
+	}
+}
@@ -0,0 +1,138 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"strconv"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// PartialEval evaluates the leaves of the tree which can be decided from the
+// `known` fields alone and returns the residual tree over the leaves which
+// cannot, so that cheap fields can be used to pre-filter before expensive ones
+// are fetched.  `opts` are the options the tree will later be compiled with,
+// such as `WithFuncs`.
+//
+// A leaf is decided if every field it references exists in `known`, in the
+// sense of `WithMissing`.  Advanced leaves, leaves which reference no fields,
+// and leaves which read the data in ways other than through fields, are
+// always kept.  Functions are
+// assumed to depend only on their arguments, so a leaf such as
+// `within .Created "24h"` is decided as of the call.  Decided children are removed from
+// their parent, an `and` with a false child and an `or` with a true child are
+// replaced by that constant, and a node left with one child is replaced by
+// it.  Nodes of registered operators keep every child, decided or not, unless
+// all of them are decided, and an `if` node whose condition is decided is
+// replaced by the residual of the branch it chooses.  A fully decided tree is
+// returned as the leaf `(true)` or `(false)`.
+//
+// The tree is not modified, the residual tree shares no nodes with it.
+func (n *Node) PartialEval(known map[string]interface{}, opts ...Option) (*Node, error) {
+	ct, err := Compile(n, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &evalState{ctx: context.Background(), stop: context.Background()}
+	r, _, err := ct.eval.partial(st, known)
+	return r, err
+}
+
+// partial returns the residual of the node given `known`, and whether it is
+// a constant.
+func (cn *compiledNode) partial(st *evalState, known map[string]interface{}) (*Node, bool, error) {
+	if cn.node.isLeaf() {
+		if len(cn.fields) == 0 || cn.opaque {
+			return &Node{Op: cn.node.Op, Leaf: cn.node.Leaf}, false, nil
+		}
+		if _, ok := missingField(known, cn.fields); ok {
+			return &Node{Op: cn.node.Op, Leaf: cn.node.Leaf}, false, nil
+		}
+		v, err := cn.evaluateLeaf(st, known)
+		if err != nil {
+			return nil, false, err
+		}
+		return constantNode(v), true, nil
+	}
+
+	if cn.custom != nil {
+		// Unless every child is decided, all of them are kept in order.
+		children, results, decided := make([]*Node, len(cn.children)), make([]bool, len(cn.children)), true
+		for i, c := range cn.children {
+			r, constant, err := c.partial(st, known)
+			if err != nil {
+				return nil, false, err
+			}
+			children[i], results[i], decided = r, constant && constantValue(r), decided && constant
+		}
+		if decided {
+			return constantNode(cn.custom.Evaluate(results)), true, nil
+		}
+		return &Node{Op: cn.node.Op, Leaf: cn.node.Leaf, Nodes: children}, false, nil
+	}
+
+	if cn.sw != nil {
+		return cn.partialSwitch(st, known)
+	}
+
+	if cn.node.Op == OperatorIf {
+		cond, constant, err := cn.children[0].partial(st, known)
+		if err != nil {
+			return nil, false, err
+		}
+		if constant {
+			if constantValue(cond) {
+				return cn.children[1].partial(st, known)
+			}
+			return cn.children[2].partial(st, known)
+		}
+		then, thenConstant, err := cn.children[1].partial(st, known)
+		if err != nil {
+			return nil, false, err
+		}
+		els, elsConstant, err := cn.children[2].partial(st, known)
+		if err != nil {
+			return nil, false, err
+		}
+		if thenConstant && elsConstant && constantValue(then) == constantValue(els) {
+			return then, true, nil
+		}
+		return NewIfNode(cond, then, els), false, nil
+	}
+
+	d := decisive(cn.node.Op)
+	rest := []*Node{}
+	for _, c := range cn.children {
+		r, constant, err := c.partial(st, known)
+		if err != nil {
+			return nil, false, err
+		}
+		if !constant {
+			rest = append(rest, r)
+			continue
+		}
+		if constantValue(r) == d {
+			return constantNode(d), true, nil
+		}
+	}
+
+	switch len(rest) {
+	case 0:
+		return constantNode(!d), true, nil
+	case 1:
+		return rest[0], false, nil
+	}
+	return NewNode(cn.node.Op, rest...), false, nil
+}
+
+// constantNode returns the leaf which always evaluates to `v`.
+func constantNode(v bool) *Node {
+	return NewLeafNode(strconv.FormatBool(v))
+}
+
+// constantValue returns the value of a leaf returned by `constantNode`.
+func constantValue(n *Node) bool {
+	return n.Leaf == constantNode(true).Leaf
+}
@@ -0,0 +1,92 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestPartialEval(t *testing.T) {
+	n := NewNode(OperatorOr,
+		NewNode(OperatorAnd,
+			NewLeafNode("ge .Milk 4"),
+			NewLeafNode("le .Milk 6"),
+			NewLeafNode(`eq .Store.City "SF"`)),
+		NewNode(OperatorAnd,
+			NewLeafNode("ge .Onions 1"),
+			NewLeafNode("le .Onions 2")),
+		NewLeafNode("gt .Toothpaste 5"))
+
+	for _, tc := range []struct {
+		known    map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{}, mustCombine(t, n)},
+		{map[string]interface{}{"Milk": 5}, "or ((eq .Store.City \"SF\")) (or (and ((ge .Onions 1)) ((le .Onions 2))) ((gt .Toothpaste 5)))"},
+		{map[string]interface{}{"Milk": 9, "Onions": 0}, "(gt .Toothpaste 5)"},
+		{map[string]interface{}{"Toothpaste": 8}, "(true)"},
+		{map[string]interface{}{"Milk": 9, "Onions": 0, "Toothpaste": 1}, "(false)"},
+		{map[string]interface{}{"Milk": 5, "Store": map[string]interface{}{"City": "SF"}}, "(true)"},
+	} {
+		r, err := n.PartialEval(tc.known)
+		if err != nil {
+			t.Errorf("PartialEval(%v) error: %s\n", tc.known, err.Error())
+			continue
+		}
+		if actual := mustCombine(t, r); actual != tc.expected {
+			t.Errorf("PartialEval(%v) expected=%q actual=%q\n", tc.known, tc.expected, actual)
+		}
+	}
+
+	if actual := mustCombine(t, n); actual == "(true)" {
+		t.Errorf("PartialEval() modified the tree\n")
+	}
+}
+
+func TestPartialEvalKeeps(t *testing.T) {
+	known := map[string]interface{}{"A": 1}
+	for _, leaf := range []*Node{
+		NewLeafNode("eq 1 1"),
+		NewLeafNode(`eq (index . "A") 1`),
+		NewAdvancedLeafNode("{{ with .A }}{{ eq . 1 }}{{ end }}"),
+		NewAdvancedLeafNode("{{ eq .A 1 }}"),
+	} {
+		n := NewNode(OperatorAnd, leaf, NewLeafNode("eq .B 2"))
+		r, err := n.PartialEval(known)
+		if err != nil {
+			t.Errorf("PartialEval(%q) error: %s\n", leaf.Leaf, err.Error())
+			continue
+		}
+		if len(r.Nodes) != 2 || r.Nodes[0].Op != leaf.Op {
+			t.Errorf("PartialEval(%q) expected the leaf to be kept, actual=%#v\n", leaf.Leaf, r)
+		}
+	}
+}
+
+func TestPartialEvalErrors(t *testing.T) {
+	n := NewNode(OperatorAnd, NewLeafNode(`between .A 1 2`), NewLeafNode("eq .B 2"))
+	if _, err := n.PartialEval(map[string]interface{}{"A": 1}); err == nil {
+		t.Errorf("PartialEval() expected an error without StdFuncs\n")
+	}
+	r, err := n.PartialEval(map[string]interface{}{"A": 1}, WithFuncs(StdFuncs()))
+	if err != nil {
+		t.Fatalf("PartialEval() error: %s\n", err.Error())
+	}
+	if actual := mustCombine(t, r); actual != "(eq .B 2)" {
+		t.Errorf("PartialEval() expected=%q actual=%q\n", "(eq .B 2)", actual)
+	}
+
+	if _, err := NewNode(OperatorAnd, NewLeafNode("lt .A 1")).PartialEval(map[string]interface{}{"A": "x"}); err == nil {
+		t.Errorf("PartialEval() expected an error comparing a string and an int\n")
+	}
+}
+
+func mustCombine(t *testing.T, n *Node) string {
+	s, err := n.Combine()
+	if err != nil {
+		t.Fatalf("Combine() error: %s\n", err.Error())
+	}
+	return s
+}
@@ -0,0 +1,126 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Operations of a `PatchOp`.
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+)
+
+// PatchOp is a single edit of a tree, modelled on JSON Patch (RFC 6902) with
+// nodes addressed by their paths, "/" for the root and "/0/1" for the second
+// child of its first child.
+//
+// `add` inserts `Value` as the child at `Path`, shifting that child and those
+// after it along, and a path ending in "/-" appends to the children of the
+// node before it.  `remove` deletes the node at `Path` and `replace` swaps
+// it, or the whole tree for "/", for `Value`.
+type PatchOp struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value *Node  `json:"value,omitempty"`
+}
+
+// ApplyPatch applies `ops` in order to a copy of the tree and returns the
+// patched copy, which must still pass `Validate`.  The paths of each
+// operation address the tree as left by the operations before it.  Errors
+// wrap `ErrInvalidPatch` and the tree is not modified.
+func (n *Node) ApplyPatch(ops []PatchOp) (*Node, error) {
+	root := n.copy()
+	for i, op := range ops {
+		var err error
+		if root, err = applyPatchOp(root, op); err != nil {
+			return nil, fmt.Errorf("%w: op %d (%s %s): %v", ErrInvalidPatch, i, op.Op, op.Path, err)
+		}
+	}
+	if err := root.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: patched tree: %w", ErrInvalidPatch, err)
+	}
+	return root, nil
+}
+
+// applyPatchOp applies `op` to `root`, returning the patched root.
+func applyPatchOp(root *Node, op PatchOp) (*Node, error) {
+	switch op.Op {
+	case PatchAdd, PatchReplace:
+		if op.Value == nil {
+			return nil, fmt.Errorf("missing value")
+		}
+	case PatchRemove:
+	default:
+		return nil, fmt.Errorf("unknown op %q", op.Op)
+	}
+
+	path, appending := op.Path, op.Op == PatchAdd && strings.HasSuffix(op.Path, "/-")
+	if appending {
+		if path = strings.TrimSuffix(path, "-"); path != "/" {
+			path = strings.TrimSuffix(path, "/")
+		}
+	}
+	idx, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(idx) == 0 && !appending {
+		if op.Op != PatchReplace {
+			return nil, fmt.Errorf("cannot %s the root", op.Op)
+		}
+		return op.Value.copy(), nil
+	}
+
+	// Resolve the parent of the node addressed.
+	parentIdx := idx
+	if !appending {
+		parentIdx = idx[:len(idx)-1]
+	}
+	parent, err := root.descend(parentIdx)
+	if err != nil {
+		return nil, err
+	}
+	if parent.isLeaf() {
+		return nil, fmt.Errorf("%s is a leaf", pathOf(parentIdx))
+	}
+
+	i := len(parent.Nodes)
+	if !appending {
+		i = idx[len(idx)-1]
+	}
+	last := len(parent.Nodes) - 1
+	if op.Op == PatchAdd {
+		last++
+	}
+	if i > last {
+		return nil, fmt.Errorf("%s has no child %d", pathOf(parentIdx), i)
+	}
+
+	switch op.Op {
+	case PatchAdd:
+		parent.Nodes = append(parent.Nodes[:i], append([]*Node{op.Value.copy()}, parent.Nodes[i:]...)...)
+	case PatchRemove:
+		parent.Nodes = append(parent.Nodes[:i], parent.Nodes[i+1:]...)
+	case PatchReplace:
+		parent.Nodes[i] = op.Value.copy()
+	}
+	return root, nil
+}
+
+// copy returns a deep copy of the tree.
+func (n *Node) copy() *Node {
+	c := &Node{Op: n.Op, Leaf: n.Leaf}
+	if n.Nodes != nil {
+		c.Nodes = make([]*Node, len(n.Nodes))
+		for i, child := range n.Nodes {
+			c.Nodes[i] = child.copy()
+		}
+	}
+	return c
+}
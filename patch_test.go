@@ -0,0 +1,81 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestApplyPatch(t *testing.T) {
+	base := func() *Node {
+		return NewNode(OperatorAnd,
+			NewLeafNode("eq .A 1"),
+			NewNode(OperatorOr, NewLeafNode("eq .B 2"), NewLeafNode("eq .C 3")))
+	}
+
+	for _, tc := range []struct {
+		ops      []PatchOp
+		expected string
+	}{
+		{nil, ".A == 1 AND (.B == 2 OR .C == 3)"},
+		{[]PatchOp{{Op: PatchAdd, Path: "/0", Value: NewLeafNode("eq .X 0")}}, ".X == 0 AND .A == 1 AND (.B == 2 OR .C == 3)"},
+		{[]PatchOp{{Op: PatchAdd, Path: "/-", Value: NewLeafNode("eq .X 0")}}, ".A == 1 AND (.B == 2 OR .C == 3) AND .X == 0"},
+		{[]PatchOp{{Op: PatchAdd, Path: "/1/2", Value: NewLeafNode("eq .D 4")}}, ".A == 1 AND (.B == 2 OR .C == 3 OR .D == 4)"},
+		{[]PatchOp{{Op: PatchAdd, Path: "/1/-", Value: NewLeafNode("eq .D 4")}}, ".A == 1 AND (.B == 2 OR .C == 3 OR .D == 4)"},
+		{[]PatchOp{{Op: PatchRemove, Path: "/1/0"}}, ".A == 1 AND .C == 3"},
+		{[]PatchOp{{Op: PatchReplace, Path: "/1/1", Value: NewLeafNode("eq .C 9")}}, ".A == 1 AND (.B == 2 OR .C == 9)"},
+		{[]PatchOp{{Op: PatchReplace, Path: "/", Value: NewLeafNode("true")}}, "true"},
+		{[]PatchOp{
+			{Op: PatchRemove, Path: "/0"},
+			{Op: PatchReplace, Path: "/0/0", Value: NewLeafNode("eq .B 5")},
+		}, ".B == 5 OR .C == 3"},
+	} {
+		n := base()
+		p, err := n.ApplyPatch(tc.ops)
+		if err != nil {
+			t.Errorf("ApplyPatch(%+v) error: %s\n", tc.ops, err.Error())
+			continue
+		}
+		if actual := p.Infix(); actual != tc.expected {
+			t.Errorf("ApplyPatch(%+v) expected=%s actual=%s\n", tc.ops, tc.expected, actual)
+		}
+		if !equalNodes(n, base()) {
+			t.Errorf("ApplyPatch(%+v) modified the tree: %s\n", tc.ops, n)
+		}
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	n := NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("eq .B 2"))
+	for _, ops := range [][]PatchOp{
+		{{Op: "move", Path: "/0"}},
+		{{Op: PatchAdd, Path: "/0"}},
+		{{Op: PatchRemove, Path: "/"}},
+		{{Op: PatchRemove, Path: "/2"}},
+		{{Op: PatchAdd, Path: "/3", Value: NewLeafNode("true")}},
+		{{Op: PatchAdd, Path: "/0/0", Value: NewLeafNode("true")}},
+		{{Op: PatchRemove, Path: "0"}},
+		{{Op: PatchRemove, Path: "/01"}},
+		{{Op: PatchRemove, Path: "/0/"}},
+		{{Op: PatchRemove, Path: "/0"}, {Op: PatchRemove, Path: "/0"}},
+		{{Op: PatchReplace, Path: "/1", Value: NewNode(OperatorOr)}},
+		{{Op: PatchReplace, Path: "/1", Value: NewLeafNode("true }}{{ .Secret")}},
+	} {
+		if _, err := n.ApplyPatch(ops); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("ApplyPatch(%+v) expected=%v actual=%v\n", ops, ErrInvalidPatch, err)
+		}
+	}
+
+	var ops []PatchOp
+	if err := json.Unmarshal([]byte(`[{"op": "replace", "path": "/1", "value": {"Op": "leaf", "Leaf": "(eq .B 3)"}}]`), &ops); err != nil {
+		t.Fatalf("Unmarshal() error: %s\n", err.Error())
+	}
+	p, err := n.ApplyPatch(ops)
+	if err != nil || p.Nodes[1].Leaf != "(eq .B 3)" {
+		t.Errorf("ApplyPatch() expected the decoded replacement, got %v (%v)\n", p, err)
+	}
+}
@@ -0,0 +1,78 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// At returns the node at `path` within the tree, addressed as in errors and
+// `EvalHooks`: "/" for the root and "/0/1" for the second child of its first
+// child.  An error wrapping `ErrNodeNotFound` is returned if the tree has no
+// node at that path.
+func (n *Node) At(path string) (*Node, error) {
+	idx, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return n.descend(idx)
+}
+
+// PathOf returns the path of `target` within the tree, which must be the
+// node itself rather than an equal copy, and whether it was found.
+func (n *Node) PathOf(target *Node) (string, bool) {
+	if n == target {
+		return "/", true
+	}
+	for i, c := range n.Nodes {
+		if p, ok := c.PathOf(target); ok {
+			return strings.TrimSuffix(childPath("/", i)+p, "/"), true
+		}
+	}
+	return "", false
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// parsePath splits a node path such as "/0/1" into its child indices.
+func parsePath(path string) ([]int, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("invalid path %q", path)
+	}
+	idx := []int{}
+	if path == "/" {
+		return idx, nil
+	}
+	for _, p := range strings.Split(path[1:], "/") {
+		i, err := strconv.Atoi(p)
+		if err != nil || i < 0 || strconv.Itoa(i) != p {
+			return nil, fmt.Errorf("invalid path %q", path)
+		}
+		idx = append(idx, i)
+	}
+	return idx, nil
+}
+
+// pathOf formats child indices as a node path.
+func pathOf(idx []int) string {
+	path := "/"
+	for _, i := range idx {
+		path = childPath(path, i)
+	}
+	return path
+}
+
+// descend returns the node reached by following the child indices `idx`.
+func (n *Node) descend(idx []int) (*Node, error) {
+	for d, i := range idx {
+		if i >= len(n.Nodes) {
+			return nil, fmt.Errorf("%w: %s has no child %d", ErrNodeNotFound, pathOf(idx[:d]), i)
+		}
+		n = n.Nodes[i]
+	}
+	return n, nil
+}
@@ -0,0 +1,53 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestAt(t *testing.T) {
+	n := pricesTree()
+	for _, tc := range []struct {
+		path     string
+		expected *Node
+		err      error
+	}{
+		{"/", n, nil},
+		{"/1", n.Nodes[1], nil},
+		{"/0/1/0", n.Nodes[0].Nodes[1].Nodes[0], nil},
+		{"/2", nil, ErrNodeNotFound},
+		{"/1/0", nil, ErrNodeNotFound},
+		{"/0/-1", nil, nil},
+		{"0/1", nil, nil},
+	} {
+		actual, err := n.At(tc.path)
+		switch {
+		case tc.expected != nil && (err != nil || actual != tc.expected):
+			t.Errorf("At(%q) expected=%s actual=%s (%v)\n", tc.path, tc.expected, actual, err)
+		case tc.expected == nil && err == nil:
+			t.Errorf("At(%q) expected an error\n", tc.path)
+		case tc.err != nil && !errors.Is(err, tc.err):
+			t.Errorf("At(%q) expected=%v actual=%v\n", tc.path, tc.err, err)
+		}
+	}
+}
+
+func TestPathOf(t *testing.T) {
+	n := pricesTree()
+	for _, path := range []string{"/", "/0", "/1", "/0/1", "/0/1/1"} {
+		target, err := n.At(path)
+		if err != nil {
+			t.Fatalf("At(%q) error: %s\n", path, err.Error())
+		}
+		if actual, ok := n.PathOf(target); !ok || actual != path {
+			t.Errorf("PathOf() expected=%s actual=%s\n", path, actual)
+		}
+	}
+	if _, ok := n.PathOf(NewLeafNode("gt .Toothpaste 5")); ok {
+		t.Errorf("PathOf() expected a copy not to be found\n")
+	}
+}
@@ -0,0 +1,192 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// PrintOption configures how a tree is drawn by `PrettyPrint`, `PrintDOT` and
+// `PrintMermaid`.
+type PrintOption func(*printOptions)
+
+type printOptions struct {
+	ascii   bool
+	results map[string]bool
+}
+
+// PrintASCII draws the branches of the tree with ASCII rather than Unicode
+// box drawing characters, for terminals and logs which cannot show them.  It
+// has no effect on graphs.
+func PrintASCII() PrintOption {
+	return func(o *printOptions) {
+		o.ascii = true
+	}
+}
+
+// PrintResults annotates each node with its result, keyed by the path of the
+// node as given to `EvalHooks`, such as those recorded by `OnNodeEnd` during
+// the last evaluation.  Nodes without a result, such as those skipped by
+// short-circuiting, are not annotated.
+func PrintResults(results map[string]bool) PrintOption {
+	return func(o *printOptions) {
+		o.results = results
+	}
+}
+
+// branches are the strings drawn before each node, for the last child of its
+// parent and for any other child, and before the descendants of each.
+type branches struct {
+	child, last, under, lastUnder string
+}
+
+var (
+	unicodeBranches = branches{"├── ", "└── ", "│   ", "    "}
+	asciiBranches   = branches{"|-- ", "`-- ", "|   ", "    "}
+)
+
+// PrettyPrint draws the tree rooted at `n` to `w`, one node per line, with
+// the operators at its branches and the leaf expressions at its ends:
+//
+//	or
+//	├── and
+//	│   ├── (ge .Milk 4)
+//	│   └── (le .Milk 6)
+//	└── (gt .Toothpaste 5)
+func (n *Node) PrettyPrint(w io.Writer, opts ...PrintOption) error {
+	o := printOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	b := unicodeBranches
+	if o.ascii {
+		b = asciiBranches
+	}
+
+	bw := bufio.NewWriter(w)
+	n.prettyPrint(bw, &o, b, "/", "", "")
+	return bw.Flush()
+}
+
+// prettyPrint draws the node at `path`, with `first` before its first line
+// and `rest` before any others and before its descendants.
+func (n *Node) prettyPrint(w *bufio.Writer, o *printOptions, b branches, path, first, rest string) {
+	for i, line := range strings.Split(n.label(o, path), "\n") {
+		if i == 0 {
+			w.WriteString(first)
+		} else {
+			w.WriteString(rest)
+		}
+		w.WriteString(line)
+		w.WriteByte('\n')
+	}
+
+	if n.isLeaf() {
+		return
+	}
+	for i, c := range n.Nodes {
+		if i == len(n.Nodes)-1 {
+			c.prettyPrint(w, o, b, childPath(path, i), rest+b.last, rest+b.lastUnder)
+		} else {
+			c.prettyPrint(w, o, b, childPath(path, i), rest+b.child, rest+b.under)
+		}
+	}
+}
+
+// label returns the text drawn for the node at `path`: its operator, or its
+// expression for leaves, and its result.
+func (n *Node) label(o *printOptions, path string) string {
+	label := string(n.Op)
+	switch {
+	case n.isLeaf():
+		label = n.Leaf
+	case n.Op == OperatorCase && n.Leaf == "":
+		label = "default"
+	case isSwitchPart(n.Op) || n.Op == OperatorRef:
+		label += " " + n.Leaf
+	}
+	if v, ok := o.results[path]; ok {
+		label += " => " + strconv.FormatBool(v)
+	}
+	return label
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// PrintDOT draws the tree rooted at `n` to `w` as a Graphviz DOT graph, with
+// a box for each operator and an ellipse for each leaf, for rendering with
+// `dot -Tsvg`.  Nodes are named after their paths, `n` for the root and
+// `n_1_0` for the first child of its second child.
+func (n *Node) PrintDOT(w io.Writer, opts ...PrintOption) error {
+	o := printOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("digraph tree {\n")
+	n.walkGraph("/", func(c *Node, path, parent string) {
+		shape := "box"
+		if c.isLeaf() {
+			shape = "ellipse"
+		}
+		label := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(c.label(&o, path))
+		bw.WriteString("\t" + graphID(path) + " [label=\"" + label + "\", shape=" + shape + "];\n")
+		if parent != "" {
+			bw.WriteString("\t" + graphID(parent) + " -> " + graphID(path) + ";\n")
+		}
+	})
+	bw.WriteString("}\n")
+	return bw.Flush()
+}
+
+// PrintMermaid draws the tree rooted at `n` to `w` as a Mermaid flowchart,
+// for embedding in Markdown, with nodes named as by `PrintDOT`.  Operators
+// are drawn as rectangles and leaves with rounded corners.
+func (n *Node) PrintMermaid(w io.Writer, opts ...PrintOption) error {
+	o := printOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	bw := bufio.NewWriter(w)
+	bw.WriteString("flowchart TD\n")
+	n.walkGraph("/", func(c *Node, path, parent string) {
+		start, end := "[", "]"
+		if c.isLeaf() {
+			start, end = "(", ")"
+		}
+		label := strings.NewReplacer(`"`, "#quot;", "\n", "<br>").Replace(c.label(&o, path))
+		bw.WriteString("\t" + graphID(path) + start + "\"" + label + "\"" + end + "\n")
+		if parent != "" {
+			bw.WriteString("\t" + graphID(parent) + " --> " + graphID(path) + "\n")
+		}
+	})
+	return bw.Flush()
+}
+
+// walkGraph calls `fn` with every node under and including `n`, at `path`,
+// parents first, and the path of its parent, empty for `n`.
+func (n *Node) walkGraph(path string, fn func(c *Node, path, parent string)) {
+	var walk func(c *Node, path, parent string)
+	walk = func(c *Node, path, parent string) {
+		fn(c, path, parent)
+		if c.isLeaf() {
+			return
+		}
+		for i, child := range c.Nodes {
+			walk(child, childPath(path, i), path)
+		}
+	}
+	walk(n, path, "")
+}
+
+// graphID returns the name of the node at `path` in graphs.
+func graphID(path string) string {
+	return "n" + strings.ReplaceAll(strings.TrimSuffix(path, "/"), "/", "_")
+}
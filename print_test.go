@@ -0,0 +1,126 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestPrettyPrint(t *testing.T) {
+	for _, tc := range []struct {
+		n        *Node
+		opts     []PrintOption
+		expected string
+	}{
+		{pricesTree(), nil, `or
+├── and
+│   ├── and
+│   │   ├── (ge .Milk 4)
+│   │   └── (le .Milk 6)
+│   └── and
+│       ├── (ge .Onions 1)
+│       └── (le .Onions 2)
+└── (gt .Toothpaste 5)
+`},
+		{pricesTree(), []PrintOption{PrintASCII()}, "or\n" +
+			"|-- and\n" +
+			"|   |-- and\n" +
+			"|   |   |-- (ge .Milk 4)\n" +
+			"|   |   `-- (le .Milk 6)\n" +
+			"|   `-- and\n" +
+			"|       |-- (ge .Onions 1)\n" +
+			"|       `-- (le .Onions 2)\n" +
+			"`-- (gt .Toothpaste 5)\n"},
+		{NewLeafNode("true"), nil, "(true)\n"},
+		{NewNode(OperatorAnd, NewAdvancedLeafNode("{{ if .A }}\ntrue{{ else }}false{{ end }}"), NewLeafNode("true")), nil, `and
+├── {{ if .A }}
+│   true{{ else }}false{{ end }}
+└── (true)
+`},
+	} {
+		var sb strings.Builder
+		if err := tc.n.PrettyPrint(&sb, tc.opts...); err != nil {
+			t.Fatalf("PrettyPrint() error: %s\n", err.Error())
+		}
+		if sb.String() != tc.expected {
+			t.Errorf("PrettyPrint() expected=\n%s\nactual=\n%s\n", tc.expected, sb.String())
+		}
+	}
+}
+
+func TestPrettyPrintResults(t *testing.T) {
+	results := map[string]bool{}
+	ct, err := Compile(pricesTree(), WithHooks(EvalHooks{
+		OnNodeEnd: func(path string, n *Node, result bool, err error) {
+			results[path] = result
+		},
+	}))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if _, err := ct.Evaluate(&prices{3, 0, 8}); err != nil {
+		t.Fatalf("Evaluate() error: %s\n", err.Error())
+	}
+
+	var sb strings.Builder
+	if err := pricesTree().PrettyPrint(&sb, PrintResults(results)); err != nil {
+		t.Fatalf("PrettyPrint() error: %s\n", err.Error())
+	}
+	expected := `or => true
+├── and => false
+│   ├── and => false
+│   │   ├── (ge .Milk 4) => false
+│   │   └── (le .Milk 6)
+│   └── and
+│       ├── (ge .Onions 1)
+│       └── (le .Onions 2)
+└── (gt .Toothpaste 5) => true
+`
+	if sb.String() != expected {
+		t.Errorf("PrettyPrint() expected=\n%s\nactual=\n%s\n", expected, sb.String())
+	}
+}
+
+func TestPrintGraphs(t *testing.T) {
+	n := NewNode(OperatorAnd, NewLeafNode(`eq .Name "x"`), NewNode(OperatorOr, NewLeafNode(".A"), NewLeafNode(".B")))
+	results := map[string]bool{"/": false, "/0": false}
+	for _, tc := range []struct {
+		print    func(*strings.Builder) error
+		expected string
+	}{
+		{func(sb *strings.Builder) error { return n.PrintDOT(sb, PrintResults(results)) }, `digraph tree {
+	n [label="and => false", shape=box];
+	n_0 [label="(eq .Name \"x\") => false", shape=ellipse];
+	n -> n_0;
+	n_1 [label="or", shape=box];
+	n -> n_1;
+	n_1_0 [label="(.A)", shape=ellipse];
+	n_1 -> n_1_0;
+	n_1_1 [label="(.B)", shape=ellipse];
+	n_1 -> n_1_1;
+}
+`},
+		{func(sb *strings.Builder) error { return n.PrintMermaid(sb) }, `flowchart TD
+	n["and"]
+	n_0("(eq .Name #quot;x#quot;)")
+	n --> n_0
+	n_1["or"]
+	n --> n_1
+	n_1_0("(.A)")
+	n_1 --> n_1_0
+	n_1_1("(.B)")
+	n_1 --> n_1_1
+`},
+	} {
+		var sb strings.Builder
+		if err := tc.print(&sb); err != nil {
+			t.Fatalf("print() error: %s\n", err.Error())
+		}
+		if sb.String() != tc.expected {
+			t.Errorf("print() expected=\n%s\nactual=\n%s\n", tc.expected, sb.String())
+		}
+	}
+}
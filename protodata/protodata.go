@@ -0,0 +1,231 @@
+// Package protodata evaluates logictree trees directly against protobuf
+// messages.
+//
+// Only the fields a compiled tree references are read from the message, using
+// protoreflect, and converted into the maps and values that leaves expect.
+// Trees whose leaves read the data other than through fields, as reported by
+// `(*logictree.CompiledTree).Opaque`, are given the whole message, with its
+// populated fields keyed by their proto names.
+// Fields may be referenced by their proto name (`.user_id`), JSON name
+// (`.userId`) or Go name (`.UserId`).  Well-known types are converted to their
+// natural Go values: `google.protobuf.Timestamp` to `time.Time` (in UTC),
+// `google.protobuf.Duration` to `time.Duration` and the wrapper types to the
+// value they wrap.  Enums are converted to the name of their value, repeated
+// fields to slices and map fields to maps keyed by the formatted map key.
+//
+// Fields which track presence and are not set are left out of the data, so
+// that `logictree.WithMissing` applies to them.
+package protodata
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Evaluate executes `ct` against the fields of `msg`.
+func Evaluate(ct *logictree.CompiledTree, msg proto.Message) (bool, error) {
+	return ct.Evaluate(treeData(ct, msg))
+}
+
+// EvaluateContext is like `Evaluate` but respects `ctx` as described by
+// `(*logictree.CompiledTree).EvaluateContext`.
+func EvaluateContext(ctx context.Context, ct *logictree.CompiledTree, msg proto.Message) (bool, error) {
+	return ct.EvaluateContext(ctx, treeData(ct, msg))
+}
+
+// treeData returns the data `ct` is evaluated against for `msg`: the fields
+// it references, along with the whole message if the tree is opaque.
+func treeData(ct *logictree.CompiledTree, msg proto.Message) map[string]interface{} {
+	if !ct.Opaque() || msg == nil {
+		return Data(msg, ct.Fields())
+	}
+	out, ok := message(msg.ProtoReflect()).(map[string]interface{})
+	if !ok {
+		// Well-known types have no fields of their own.
+		return Data(msg, ct.Fields())
+	}
+	m := msg.ProtoReflect()
+	for _, f := range ct.Fields() {
+		if len(f) > 0 {
+			set(out, m, f)
+		}
+	}
+	return out
+}
+
+// Data returns the values of `fields` in `msg`, as returned by
+// `(*logictree.CompiledTree).Fields`, nested in maps keyed by the names used
+// to reference them.
+func Data(msg proto.Message, fields [][]string) map[string]interface{} {
+	out := map[string]interface{}{}
+	if msg == nil {
+		return out
+	}
+	m := msg.ProtoReflect()
+	for _, f := range fields {
+		if len(f) > 0 {
+			set(out, m, f)
+		}
+	}
+	return out
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// set copies the field at `path` in `m` into `out`.
+func set(out map[string]interface{}, m protoreflect.Message, path []string) {
+	fd := findField(m.Descriptor(), path[0])
+	if fd == nil || (fd.HasPresence() && !m.Has(fd)) {
+		return
+	}
+	v := m.Get(fd)
+
+	switch {
+	case len(path) == 1 || fd.IsList():
+		out[path[0]] = value(fd, v)
+	case fd.IsMap():
+		if fd.MapKey().Kind() != protoreflect.StringKind {
+			out[path[0]] = value(fd, v)
+			return
+		}
+		mv := v.Map().Get(protoreflect.ValueOfString(path[1]).MapKey())
+		if !mv.IsValid() {
+			return
+		}
+		sub, ok := out[path[0]].(map[string]interface{})
+		if !ok {
+			sub = map[string]interface{}{}
+			out[path[0]] = sub
+		}
+		sub[path[1]] = singular(fd.MapValue(), mv)
+	case fd.Message() != nil && !wellKnown(fd.Message().FullName()):
+		sub, ok := out[path[0]].(map[string]interface{})
+		if !ok {
+			sub = map[string]interface{}{}
+			out[path[0]] = sub
+		}
+		set(sub, v.Message(), path[1:])
+	default:
+		out[path[0]] = value(fd, v)
+	}
+}
+
+// findField looks up a field by its proto, JSON or Go name.
+func findField(md protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := md.Fields()
+	if fd := fields.ByName(protoreflect.Name(name)); fd != nil {
+		return fd
+	}
+	if fd := fields.ByJSONName(name); fd != nil {
+		return fd
+	}
+	for i := 0; i < fields.Len(); i++ {
+		if fd := fields.Get(i); goName(string(fd.Name())) == name {
+			return fd
+		}
+	}
+	return nil
+}
+
+// goName converts a proto field name to the name of its generated Go field.
+func goName(s string) string {
+	parts := strings.Split(s, "_")
+	for i, p := range parts {
+		if p != "" {
+			parts[i] = strings.ToUpper(p[:1]) + p[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// value converts a field value, which may be a list or map.
+func value(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch {
+	case fd.IsList():
+		l := v.List()
+		out := make([]interface{}, l.Len())
+		for i := range out {
+			out[i] = singular(fd, l.Get(i))
+		}
+		return out
+	case fd.IsMap():
+		out := map[string]interface{}{}
+		v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+			out[k.String()] = singular(fd.MapValue(), mv)
+			return true
+		})
+		return out
+	}
+	return singular(fd, v)
+}
+
+// singular converts a single, non-list and non-map, value.
+func singular(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			return string(ev.Name())
+		}
+		return int32(v.Enum())
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return message(v.Message())
+	}
+	return v.Interface()
+}
+
+// message converts a message, every populated field of which is included.
+func message(m protoreflect.Message) interface{} {
+	md := m.Descriptor()
+	switch name := md.FullName(); {
+	case name == "google.protobuf.Timestamp":
+		s, n := wktInts(m)
+		return time.Unix(s, n).UTC()
+	case name == "google.protobuf.Duration":
+		s, n := wktInts(m)
+		return time.Duration(s)*time.Second + time.Duration(n)
+	case wrapper(name):
+		fd := md.Fields().ByName("value")
+		return singular(fd, m.Get(fd))
+	}
+
+	out := map[string]interface{}{}
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		out[string(fd.Name())] = value(fd, v)
+		return true
+	})
+	return out
+}
+
+// wktInts returns the seconds and nanos fields of a Timestamp or Duration.
+func wktInts(m protoreflect.Message) (int64, int64) {
+	fields := m.Descriptor().Fields()
+	return m.Get(fields.ByName("seconds")).Int(), m.Get(fields.ByName("nanos")).Int()
+}
+
+func wellKnown(name protoreflect.FullName) bool {
+	return name == "google.protobuf.Timestamp" || name == "google.protobuf.Duration" || wrapper(name)
+}
+
+func wrapper(name protoreflect.FullName) bool {
+	switch name {
+	case "google.protobuf.DoubleValue", "google.protobuf.FloatValue",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value",
+		"google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.BoolValue", "google.protobuf.StringValue",
+		"google.protobuf.BytesValue":
+		return true
+	}
+	return false
+}
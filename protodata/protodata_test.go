@@ -0,0 +1,167 @@
+package protodata
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func field(name string, num int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+	f := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String(name),
+		Number: proto.Int32(num),
+		Type:   typ.Enum(),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+func repeated(f *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+	f.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	return f
+}
+
+// eventDescriptor builds a message descriptor for:
+//
+//	enum Kind { KIND_UNKNOWN = 0; KIND_ALERT = 1; }
+//	message Inner { double score = 1; }
+//	message Event {
+//	  string user_id = 1;
+//	  int64 count = 2;
+//	  google.protobuf.Timestamp created = 3;
+//	  repeated string tags = 4;
+//	  map<string, string> labels = 5;
+//	  Kind kind = 6;
+//	  Inner inner = 7;
+//	}
+func eventDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	const (
+		str = descriptorpb.FieldDescriptorProto_TYPE_STRING
+		msg = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE
+	)
+	fdp := &descriptorpb.FileDescriptorProto{
+		Name:       proto.String("event.proto"),
+		Package:    proto.String("test"),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/timestamp.proto"},
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Kind"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("KIND_UNKNOWN"), Number: proto.Int32(0)},
+				{Name: proto.String("KIND_ALERT"), Number: proto.Int32(1)},
+			},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:  proto.String("Inner"),
+			Field: []*descriptorpb.FieldDescriptorProto{field("score", 1, descriptorpb.FieldDescriptorProto_TYPE_DOUBLE, "")},
+		}, {
+			Name: proto.String("Event"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				field("user_id", 1, str, ""),
+				field("count", 2, descriptorpb.FieldDescriptorProto_TYPE_INT64, ""),
+				field("created", 3, msg, ".google.protobuf.Timestamp"),
+				repeated(field("tags", 4, str, "")),
+				repeated(field("labels", 5, msg, ".test.Event.LabelsEntry")),
+				field("kind", 6, descriptorpb.FieldDescriptorProto_TYPE_ENUM, ".test.Kind"),
+				field("inner", 7, msg, ".test.Inner"),
+			},
+			NestedType: []*descriptorpb.DescriptorProto{{
+				Name:    proto.String("LabelsEntry"),
+				Field:   []*descriptorpb.FieldDescriptorProto{field("key", 1, str, ""), field("value", 2, str, "")},
+				Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+			}},
+		}},
+	}
+
+	fd, err := protodesc.NewFile(fdp, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("protodesc.NewFile() error: %s\n", err.Error())
+	}
+	return fd.Messages().ByName("Event")
+}
+
+func TestEvaluate(t *testing.T) {
+	md := eventDescriptor(t)
+	fields := md.Fields()
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	ev := dynamicpb.NewMessage(md)
+	ev.Set(fields.ByName("user_id"), protoreflect.ValueOfString("u-1"))
+	ev.Set(fields.ByName("count"), protoreflect.ValueOfInt64(7))
+	ev.Set(fields.ByName("created"), protoreflect.ValueOfMessage(timestamppb.New(created).ProtoReflect()))
+	ev.Set(fields.ByName("kind"), protoreflect.ValueOfEnum(1))
+	tags := ev.Mutable(fields.ByName("tags")).List()
+	tags.Append(protoreflect.ValueOfString("a"))
+	tags.Append(protoreflect.ValueOfString("b"))
+	labels := ev.Mutable(fields.ByName("labels")).Map()
+	labels.Set(protoreflect.ValueOfString("env").MapKey(), protoreflect.ValueOfString("prod"))
+
+	for _, tc := range []struct {
+		expr     string
+		expected bool
+	}{
+		{`eq .user_id "u-1"`, true},
+		{`eq .userId "u-1"`, true},
+		{`eq .UserId "u-1"`, true},
+		{"gt .count 5", true},
+		{`eq .kind "KIND_ALERT"`, true},
+		{`in "b" .tags`, true},
+		{`eq .labels.env "prod"`, true},
+		{`after .created "2024-01-01T00:00:00Z"`, true},
+		{`eq (len .labels) 1`, true},
+		// Opaque leaves are given every populated field.
+		{`eq (index . "user_id") "u-1"`, true},
+		{`eq (len .) 6`, true},
+		{`and (eq .UserId "u-1") (eq (index . "count") 7)`, true},
+	} {
+		ct, err := logictree.Compile(logictree.NewLeafNode(tc.expr), logictree.WithFuncs(logictree.StdFuncs()))
+		if err != nil {
+			t.Fatalf("Compile(%s) error: %s\n", tc.expr, err.Error())
+		}
+		v, err := Evaluate(ct, ev)
+		if err != nil {
+			t.Errorf("Evaluate(%s) error: %s\n", tc.expr, err.Error())
+		}
+		if v != tc.expected {
+			t.Errorf("Evaluate(%s) expected=%v actual=%v\n", tc.expr, tc.expected, v)
+		}
+	}
+
+	ct, err := logictree.Compile(logictree.NewLeafNode("gt .inner.score 0.5"), logictree.WithMissing(logictree.MissingIsFalse))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if v, err := Evaluate(ct, ev); err != nil || v {
+		t.Errorf("Evaluate() expected an unset message to be missing, got %v (%v)\n", v, err)
+	}
+
+	inner := ev.Mutable(fields.ByName("inner")).Message()
+	inner.Set(inner.Descriptor().Fields().ByName("score"), protoreflect.ValueOfFloat64(0.9))
+	if v, err := Evaluate(ct, ev); err != nil || !v {
+		t.Errorf("Evaluate() expected=true actual=%v (%v)\n", v, err)
+	}
+
+	d := Data(ev, [][]string{{"created"}, {"labels", "missing"}})
+	if _, ok := d["created"].(time.Time); !ok {
+		t.Errorf("Data() expected created to be a time.Time, got %T\n", d["created"])
+	}
+	if _, ok := d["labels"]; ok {
+		t.Errorf("Data() expected a missing map key to be left out\n")
+	}
+}
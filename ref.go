@@ -0,0 +1,141 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NewRefNode returns a node standing for the tree named `id`, such as
+// "base-eligibility", so that clauses common to many rules are written once
+// and referenced rather than copied into every rule.
+//
+// References are replaced by the trees they name by `Resolve`, or when the
+// tree is compiled with `WithResolver`; other operations fail on unresolved
+// references with an error wrapping `ErrUnresolvedRef`.  In JSON a reference
+// is encoded with the operator "ref" and the name of the tree as its "Leaf",
+// and `{"Ref": "base-eligibility"}` is accepted as a shorthand when decoding.
+func NewRefNode(id string) *Node {
+	return &Node{Op: OperatorRef, Leaf: id}
+}
+
+// Resolver looks up the trees named by references.
+type Resolver interface {
+	// Lookup returns the tree named `id`, and whether there is one.
+	Lookup(id string) (*Node, bool)
+}
+
+// Trees is a `Resolver` holding the trees by name.
+type Trees map[string]*Node
+
+// Lookup returns the tree named `id`.
+func (t Trees) Lookup(id string) (*Node, bool) {
+	n, ok := t[id]
+	return n, ok
+}
+
+// WithResolver resolves the references of the tree with `r`, as `Resolve`
+// does, before it is compiled.
+func WithResolver(r Resolver) Option {
+	return func(o *compileOptions) {
+		o.resolver = r
+	}
+}
+
+// Resolve returns a copy of the tree in which every reference is replaced by
+// a copy of the tree it names, itself resolved, so that the result holds no
+// references.  A tree may be referenced any number of times, but not by
+// itself, directly or through others: such cycles fail with an error wrapping
+// `ErrCycle` naming the references which form it, and references to
+// unknown trees with one wrapping `ErrUnresolvedRef`.  Errors are prefixed
+// with the path of the offending reference.
+func (n *Node) Resolve(r Resolver) (*Node, error) {
+	res := &resolver{r: r, done: map[string]*Node{}}
+	return res.resolve(n, "/")
+}
+
+// resolver holds the state of a single `Resolve`.
+type resolver struct {
+	r     Resolver
+	done  map[string]*Node // resolved trees by name
+	stack []string         // names of the trees being resolved
+}
+
+// resolve resolves the tree `n` at `path`.
+func (res *resolver) resolve(n *Node, path string) (*Node, error) {
+	if n.Op == OperatorRef {
+		t, err := res.tree(n.Leaf, path)
+		if err != nil {
+			return nil, err
+		}
+		return t.copy(), nil
+	}
+	c := &Node{Op: n.Op, Leaf: n.Leaf}
+	if n.Nodes != nil {
+		c.Nodes = make([]*Node, len(n.Nodes))
+		for i, child := range n.Nodes {
+			var err error
+			if c.Nodes[i], err = res.resolve(child, childPath(path, i)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c, nil
+}
+
+// tree returns the resolved tree named `id`, referenced at `path`.
+func (res *resolver) tree(id, path string) (*Node, error) {
+	if t, ok := res.done[id]; ok {
+		return t, nil
+	}
+	for i, s := range res.stack {
+		if s == id {
+			cycle := append(append([]string{}, res.stack[i:]...), id)
+			return nil, nodeError(path, NewRefNode(id), fmt.Errorf("%w: %s", ErrCycle, strings.Join(cycle, " -> ")))
+		}
+	}
+	var n *Node
+	var ok bool
+	if res.r != nil {
+		n, ok = res.r.Lookup(id)
+	}
+	if !ok || n == nil {
+		return nil, nodeError(path, NewRefNode(id), fmt.Errorf("%w: no tree named %q", ErrUnresolvedRef, id))
+	}
+
+	res.stack = append(res.stack, id)
+	t, err := res.resolve(n, "/")
+	res.stack = res.stack[:len(res.stack)-1]
+	if err != nil {
+		return nil, fmt.Errorf("%s: in %q: %w", path, id, err)
+	}
+	res.done[id] = t
+	return t, nil
+}
+
+// expandRef replaces the decoded node `n` by the reference to `id`, given
+// with the shorthand `{"Ref": id}`, which holds no other fields than an "Op"
+// and "Leaf" agreeing with it.
+func expandRef(n *Node, id string) error {
+	if (n.Op != "" && n.Op != OperatorRef) || (n.Leaf != "" && n.Leaf != id) || len(n.Nodes) > 0 {
+		return fmt.Errorf("%w: references hold nothing but the name of a tree", ErrInvalidOperator)
+	}
+	n.Op, n.Leaf = OperatorRef, id
+	return nil
+}
+
+// hasRefs reports whether the tree rooted at `n` holds references.
+func (n *Node) hasRefs() bool {
+	if n.Op == OperatorRef {
+		return true
+	}
+	for _, c := range n.Nodes {
+		if c.hasRefs() {
+			return true
+		}
+	}
+	return false
+}
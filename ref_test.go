@@ -0,0 +1,127 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+var eligibility = Trees{
+	"adult":  NewLeafNode("ge .Age 18"),
+	"base":   NewNode(OperatorAnd, NewRefNode("adult"), NewLeafNode(".Verified")),
+	"loop-a": NewNode(OperatorOr, NewLeafNode(".A"), NewRefNode("loop-b")),
+	"loop-b": NewNode(OperatorAnd, NewLeafNode(".B"), NewRefNode("loop-a")),
+}
+
+func TestResolve(t *testing.T) {
+	n := NewNode(OperatorOr,
+		NewNode(OperatorAnd, NewRefNode("base"), NewLeafNode("gt .Amount 100")),
+		NewNode(OperatorAnd, NewRefNode("adult"), NewLeafNode(".Premium")))
+	expected := NewNode(OperatorOr,
+		NewNode(OperatorAnd,
+			NewNode(OperatorAnd, NewLeafNode("ge .Age 18"), NewLeafNode(".Verified")),
+			NewLeafNode("gt .Amount 100")),
+		NewNode(OperatorAnd, NewLeafNode("ge .Age 18"), NewLeafNode(".Premium")))
+
+	r, err := n.Resolve(eligibility)
+	if err != nil || !reflect.DeepEqual(r, expected) {
+		t.Errorf("Resolve() expected=%v actual=%v err=%v\n", expected, r, err)
+	}
+	// Resolved trees share nothing with the referenced ones.
+	r.Nodes[0].Nodes[0].Nodes[1].Leaf = "(.Changed)"
+	if eligibility["base"].Nodes[1].Leaf != "(.Verified)" {
+		t.Errorf("Resolve() modified the referenced tree: %v\n", eligibility["base"])
+	}
+
+	ct, err := Compile(n, WithResolver(eligibility))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	for _, tc := range []struct {
+		data     map[string]interface{}
+		expected bool
+	}{
+		{map[string]interface{}{"Age": 30, "Verified": true, "Amount": 200, "Premium": false}, true},
+		{map[string]interface{}{"Age": 30, "Verified": false, "Amount": 200, "Premium": false}, false},
+		{map[string]interface{}{"Age": 16, "Verified": true, "Amount": 200, "Premium": true}, false},
+	} {
+		if v, err := ct.Evaluate(tc.data); err != nil || v != tc.expected {
+			t.Errorf("Evaluate(%v) expected=%v actual=%v err=%v\n", tc.data, tc.expected, v, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		n        *Node
+		expected error
+		msg      string
+	}{
+		{NewNode(OperatorAnd, NewLeafNode(".X"), NewRefNode("loop-a")), ErrCycle, "loop-a -> loop-b -> loop-a"},
+		{NewRefNode("self"), ErrCycle, "self -> self"},
+		{NewNode(OperatorAnd, NewRefNode("missing")), ErrUnresolvedRef, `/0: unresolved reference: no tree named "missing"`},
+	} {
+		trees := Trees{"self": NewNode(OperatorAnd, NewRefNode("self"))}
+		for k, v := range eligibility {
+			trees[k] = v
+		}
+		_, err := tc.n.Resolve(trees)
+		if !errors.Is(err, tc.expected) || !strings.Contains(err.Error(), tc.msg) {
+			t.Errorf("Resolve(%v) expected=%v %s actual=%v\n", tc.n, tc.expected, tc.msg, err)
+		}
+		if _, err := Compile(tc.n, WithResolver(trees)); !errors.Is(err, tc.expected) {
+			t.Errorf("Compile(%v) expected=%v actual=%v\n", tc.n, tc.expected, err)
+		}
+	}
+
+	// Unresolved references are rejected.
+	if _, err := Compile(n); !errors.Is(err, ErrUnresolvedRef) {
+		t.Errorf("Compile() expected=%v actual=%v\n", ErrUnresolvedRef, err)
+	}
+	if _, err := n.Combine(); !errors.Is(err, ErrUnresolvedRef) {
+		t.Errorf("Combine() expected=%v actual=%v\n", ErrUnresolvedRef, err)
+	}
+}
+
+func TestRefEncodings(t *testing.T) {
+	n := NewNode(OperatorAnd, NewRefNode("base"), NewLeafNode(".Open"))
+	for _, src := range []string{
+		`{"Op": "and", "Nodes": [{"Ref": "base"}, {"Op": "leaf", "Leaf": "(.Open)"}]}`,
+		`{"Op": "and", "Nodes": [{"Op": "ref", "Leaf": "base"}, {"Op": "leaf", "Leaf": "(.Open)"}]}`,
+		string(mustMarshal(t, n)),
+	} {
+		var decoded Node
+		if err := json.Unmarshal([]byte(src), &decoded); err != nil || !reflect.DeepEqual(&decoded, n) {
+			t.Errorf("Unmarshal(%s) expected=%v actual=%v err=%v\n", src, n, &decoded, err)
+		}
+		if decoded, err := (DecodeOptions{Strict: true}).Unmarshal([]byte(src)); err != nil || !reflect.DeepEqual(decoded, n) {
+			t.Errorf("DecodeOptions.Unmarshal(%s) expected=%v actual=%v err=%v\n", src, n, decoded, err)
+		}
+	}
+	for _, src := range []string{
+		`{"Ref": "base", "Op": "and"}`,
+		`{"Ref": "base", "Nodes": [{"Op": "leaf", "Leaf": "(.Open)"}]}`,
+	} {
+		var decoded Node
+		if err := json.Unmarshal([]byte(src), &decoded); !errors.Is(err, ErrInvalidOperator) {
+			t.Errorf("Unmarshal(%s) expected=%v actual=%v\n", src, ErrInvalidOperator, err)
+		}
+		if _, err := SafeUnmarshal([]byte(src)); !errors.Is(err, ErrInvalidOperator) {
+			t.Errorf("SafeUnmarshal(%s) expected=%v actual=%v\n", src, ErrInvalidOperator, err)
+		}
+	}
+
+	if parsed, err := ParseSexpr(n.Sexpr()); err != nil || !reflect.DeepEqual(parsed, n) {
+		t.Errorf("ParseSexpr(%s) expected=%v actual=%v err=%v\n", n.Sexpr(), n, parsed, err)
+	}
+	if s, expected := n.Infix(), `ref("base") AND .Open`; s != expected {
+		t.Errorf("Infix() expected=%s actual=%s\n", expected, s)
+	}
+	if err := n.Validate(); !errors.Is(err, ErrUnresolvedRef) {
+		t.Errorf("Validate() expected=%v actual=%v\n", ErrUnresolvedRef, err)
+	}
+}
@@ -0,0 +1,261 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Registry stores trees by name, such as the rules of a service, for use by
+// any number of goroutines.  It resolves the references of the trees it
+// compiles, see `NewRefNode`, to the trees it holds.  The zero Registry is
+// empty and ready to use.
+//
+// Trees are copied as they are registered and as they are returned, so that
+// changes to them do not change the registered trees.  They are stored as
+// they are given, and only validated when they are compiled.
+type Registry struct {
+	mu    sync.RWMutex
+	trees map[string]*Node
+
+	fingerprints map[string]string // of the resolved trees, see `Fingerprint`
+	gen          int               // incremented by every change of the trees
+
+	namespaces map[string]*Registry
+	policy     *Policy // nil unless set by `SetPolicy`
+}
+
+// Register stores the tree rooted at `n` as `name`, replacing any tree of
+// that name.  Empty names and nil trees fail with an error wrapping
+// `ErrInvalidConfig`.
+func (r *Registry) Register(name string, n *Node) error {
+	if name == "" || n == nil {
+		return fmt.Errorf("%w: trees need a name and a root", ErrInvalidConfig)
+	}
+	n = n.copy()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.trees == nil {
+		r.trees = map[string]*Node{}
+	}
+	r.trees[name] = n
+	r.changed()
+	return nil
+}
+
+// Get returns the tree registered as `name`, and whether there is one.
+func (r *Registry) Get(name string) (*Node, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	n, ok := r.trees[name]
+	if !ok {
+		return nil, false
+	}
+	return n.copy(), true
+}
+
+// Lookup returns the tree registered as `id`, as `Get` does, so that a
+// registry can resolve references.
+func (r *Registry) Lookup(id string) (*Node, bool) {
+	return r.Get(id)
+}
+
+// List returns the names of the registered trees, sorted.
+func (r *Registry) List() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.trees))
+	for name := range r.trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Delete removes the tree registered as `name`, reporting whether there was
+// one.
+func (r *Registry) Delete(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.trees[name]
+	delete(r.trees, name)
+	if ok {
+		r.changed()
+	}
+	return ok
+}
+
+// Compile compiles the tree registered as `name` as `Compile` does, with its
+// references resolved to the registered trees, and the policy of the
+// registry, if any, see `SetPolicy`.  Unknown names fail with an error
+// wrapping `ErrUnresolvedRef`.
+func (r *Registry) Compile(name string, opts ...Option) (*CompiledTree, error) {
+	n, ok := r.Get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: no tree named %q", ErrUnresolvedRef, name)
+	}
+	return r.compile(n, r, opts)
+}
+
+// CompileAs compiles the tree rooted at `n` as `Compile` would if it were
+// registered as `name`, replacing any tree of that name, so that a new
+// version of a tree is checked before it is registered.
+func (r *Registry) CompileAs(name string, n *Node, opts ...Option) (*CompiledTree, error) {
+	return r.compile(n, &overlay{reg: r, name: name, n: n}, opts)
+}
+
+// compile compiles the tree `n` of the registry, resolving its references
+// with `res`.
+func (r *Registry) compile(n *Node, res Resolver, opts []Option) (*CompiledTree, error) {
+	opts = append([]Option{WithResolver(res)}, opts...)
+	if p := r.getPolicy(); p != nil {
+		var err error
+		if n, err = prepare(n, p, res); err != nil {
+			return nil, err
+		}
+		opts = append(opts, p.options()...)
+	}
+	return Compile(n, opts...)
+}
+
+// overlay resolves references to the registry with the tree `name` replaced
+// by `n`.
+type overlay struct {
+	reg  *Registry
+	name string
+	n    *Node
+}
+
+func (o *overlay) Lookup(id string) (*Node, bool) {
+	if id == o.name {
+		return o.n, true
+	}
+	return o.reg.Lookup(id)
+}
+
+// MarshalJSON encodes the registered trees as an object of their
+// `Document`s by name, of the current `FormatVersion`.
+func (r *Registry) MarshalJSON() ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	docs := make(map[string]Document, len(r.trees))
+	for name, n := range r.trees {
+		docs[name] = Document{Version: FormatVersion, Tree: n}
+	}
+	return json.Marshal(docs)
+}
+
+// UnmarshalJSON registers the trees of an object encoded by `MarshalJSON`, as
+// `Import` does without decoding limits.
+func (r *Registry) UnmarshalJSON(data []byte) error {
+	return r.Import(data, DecodeOptions{})
+}
+
+// Import registers the trees of the JSON object `data`, those of any version
+// of `Document` or bare trees by name, decoded within the limits of `o`, see
+// `DecodeOptions.UnmarshalDocument`.  The trees replace those of the same
+// names, and are only registered if all of them decode: errors are prefixed
+// with the name of the offending tree, and leave the registry unchanged.
+func (r *Registry) Import(data []byte, o DecodeOptions) error {
+	var docs map[string]json.RawMessage
+	if err := json.Unmarshal(data, &docs); err != nil {
+		return fmt.Errorf("invalid registry: %w", err)
+	}
+	trees := make(map[string]*Node, len(docs))
+	for name, doc := range docs {
+		n, err := o.UnmarshalDocument(doc)
+		if err != nil {
+			return fmt.Errorf("%q: %w", name, err)
+		}
+		if name == "" || n == nil {
+			return fmt.Errorf("%q: %w: trees need a name and a root", name, ErrInvalidConfig)
+		}
+		trees[name] = n
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.trees == nil {
+		r.trees = make(map[string]*Node, len(trees))
+	}
+	for name, n := range trees {
+		r.trees[name] = n
+	}
+	r.changed()
+	return nil
+}
+
+// Fingerprint returns the fingerprint of the tree registered as `name`, see
+// `Node.Fingerprint`, and whether there is one, so that the consumers of the
+// registry poll it cheaply and only fetch and compile the trees which
+// changed.  It is the fingerprint of the tree as it is compiled, with its
+// references resolved and the macros of the policy expanded, so that it
+// changes with the trees and macros it uses, or of the tree as it is if its
+// references do not resolve.  Fingerprints are computed once per change of the
+// registry.
+func (r *Registry) Fingerprint(name string) (string, bool) {
+	r.mu.RLock()
+	fp, ok := r.fingerprints[name]
+	gen := r.gen
+	r.mu.RUnlock()
+	if ok {
+		return fp, true
+	}
+
+	n, ok := r.Get(name)
+	if !ok {
+		return "", false
+	}
+	if prepared, err := prepare(n, r.getPolicy(), r); err == nil {
+		n = prepared
+	}
+	fp = n.Fingerprint()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.gen == gen {
+		if r.fingerprints == nil {
+			r.fingerprints = map[string]string{}
+		}
+		r.fingerprints[name] = fp
+	}
+	return fp, true
+}
+
+// Fingerprints returns the fingerprints of the registered trees by name, see
+// `Fingerprint`.
+func (r *Registry) Fingerprints() map[string]string {
+	fps := map[string]string{}
+	for _, name := range r.List() {
+		if fp, ok := r.Fingerprint(name); ok {
+			fps[name] = fp
+		}
+	}
+	return fps
+}
+
+// changed drops the fingerprints of the trees after a change of the
+// registry, which any of them may reference.  The registry is locked.
+func (r *Registry) changed() {
+	r.fingerprints = nil
+	r.gen++
+}
+
+// clone returns a registry holding the trees and policy of `r` as they are,
+// without its namespaces.  Registered trees are never changed in place, so
+// they are shared rather than copied.
+func (r *Registry) clone() *Registry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	trees := make(map[string]*Node, len(r.trees))
+	for name, n := range r.trees {
+		trees[name] = n
+	}
+	return &Registry{trees: trees, policy: r.policy}
+}
@@ -0,0 +1,175 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestRegistry(t *testing.T) {
+	var r Registry
+	base := NewNode(OperatorAnd, NewLeafNode("ge .Age 18"), NewLeafNode(".Verified"))
+	rule := NewNode(OperatorAnd, NewRefNode("base"), NewLeafNode("gt .Amount 100"))
+	for name, n := range map[string]*Node{"base": base, "rule": rule} {
+		if err := r.Register(name, n); err != nil {
+			t.Fatalf("Register(%s) error: %s\n", name, err.Error())
+		}
+	}
+	for _, tc := range []struct {
+		name string
+		n    *Node
+	}{
+		{"", base},
+		{"nil", nil},
+	} {
+		if err := r.Register(tc.name, tc.n); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("Register(%q) expected=%v actual=%v\n", tc.name, ErrInvalidConfig, err)
+		}
+	}
+
+	// Registered trees are copies.
+	base.Nodes[1].Leaf = "(false)"
+	n, ok := r.Get("base")
+	if !ok || n.Nodes[1].Leaf != "(.Verified)" {
+		t.Errorf("Get(base) expected=.Verified actual=%v,%v\n", n, ok)
+	}
+	n.Nodes[0].Leaf = "(false)"
+	if n, _ := r.Get("base"); n.Nodes[0].Leaf != "(ge .Age 18)" {
+		t.Errorf("Get(base) expected=ge .Age 18 actual=%v\n", n)
+	}
+	if names := r.List(); !reflect.DeepEqual(names, []string{"base", "rule"}) {
+		t.Errorf("List() expected=[base rule] actual=%v\n", names)
+	}
+
+	ct, err := r.Compile("rule")
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	data := map[string]interface{}{"Age": 20, "Verified": true, "Amount": 200}
+	if v, err := ct.Evaluate(data); err != nil || !v {
+		t.Errorf("Evaluate(%v) expected=true actual=%v err=%v\n", data, v, err)
+	}
+	if _, err := r.Compile("missing"); !errors.Is(err, ErrUnresolvedRef) {
+		t.Errorf("Compile(missing) expected=%v actual=%v\n", ErrUnresolvedRef, err)
+	}
+
+	// Registries round trip through JSON.
+	bs, err := json.Marshal(&r)
+	if err != nil {
+		t.Fatalf("Marshal() error: %s\n", err.Error())
+	}
+	var decoded Registry
+	if err := json.Unmarshal(bs, &decoded); err != nil || !reflect.DeepEqual(decoded.List(), r.List()) {
+		t.Errorf("Unmarshal(%s) expected=%v actual=%v err=%v\n", bs, r.List(), decoded.List(), err)
+	}
+	if n, _ := decoded.Get("rule"); !reflect.DeepEqual(n, rule) {
+		t.Errorf("Get(rule) expected=%v actual=%v\n", rule, n)
+	}
+
+	if !r.Delete("base") || r.Delete("base") {
+		t.Errorf("Delete(base) expected=true, then false\n")
+	}
+	if _, err := r.Compile("rule"); !errors.Is(err, ErrUnresolvedRef) {
+		t.Errorf("Compile(rule) expected=%v actual=%v\n", ErrUnresolvedRef, err)
+	}
+}
+
+func TestRegistryImport(t *testing.T) {
+	var r Registry
+	src := `{"a": {"Op": "leaf", "Leaf": "(.A)"}, "b": {"Version": 1, "Tree": {"Op": "or", "Nodes": [{"Ref": "a"}]}}}`
+	if err := r.Import([]byte(src), DefaultDecodeOptions); err != nil {
+		t.Fatalf("Import() error: %s\n", err.Error())
+	}
+	if names := r.List(); !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("List() expected=[a b] actual=%v\n", names)
+	}
+
+	for _, tc := range []struct {
+		src      string
+		o        DecodeOptions
+		expected error
+	}{
+		{`{"c": {"Op": "leaf", "Leaf": "(.C)"}, "a": {"Version": 99, "Tree": {}}}`, DecodeOptions{}, ErrUnsupportedVersion},
+		{`{"c": {"Op": "and", "Nodes": [{"Op": "and", "Nodes": [{"Op": "leaf", "Leaf": "(.C)"}]}]}}`, DecodeOptions{MaxDepth: 2}, ErrLimitExceeded},
+		{`[]`, DecodeOptions{}, nil},
+	} {
+		err := r.Import([]byte(tc.src), tc.o)
+		if err == nil || tc.expected != nil && !errors.Is(err, tc.expected) {
+			t.Errorf("Import(%s) expected=%v actual=%v\n", tc.src, tc.expected, err)
+		}
+		if names := r.List(); !reflect.DeepEqual(names, []string{"a", "b"}) {
+			t.Errorf("Import(%s) changed the registry: %v\n", tc.src, names)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := "t" + strconv.Itoa(i)
+			if err := r.Register(name, NewLeafNode(".A")); err != nil {
+				t.Errorf("Register(%s) error: %s\n", name, err.Error())
+			}
+			if _, err := r.Compile("b"); err != nil {
+				t.Errorf("Compile(b) error: %s\n", err.Error())
+			}
+			r.Delete(name)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestRegistryFingerprint(t *testing.T) {
+	var r Registry
+	base := NewLeafNode("ge .Age 18")
+	rule := NewNode(OperatorAnd, NewRefNode("base"), NewLeafNode("gt .Amount 100"))
+	orphan := NewNode(OperatorOr, NewRefNode("missing"), NewLeafNode("true"))
+	for name, n := range map[string]*Node{"base": base, "rule": rule, "orphan": orphan} {
+		if err := r.Register(name, n); err != nil {
+			t.Fatalf("Register(%s) error: %s\n", name, err.Error())
+		}
+	}
+
+	resolved, _ := rule.Resolve(&r)
+	expected := map[string]string{
+		"base":   base.Fingerprint(),
+		"rule":   resolved.Fingerprint(),
+		"orphan": orphan.Fingerprint(),
+	}
+	if fps := r.Fingerprints(); !reflect.DeepEqual(fps, expected) {
+		t.Errorf("Fingerprints() expected=%v actual=%v\n", expected, fps)
+	}
+	if _, ok := r.Fingerprint("missing"); ok {
+		t.Errorf("Fingerprint(missing) expected no fingerprint\n")
+	}
+
+	// Trees change fingerprint with the trees they reference, and keep it
+	// otherwise.
+	if err := r.Register("base", NewLeafNode("ge .Age 21")); err != nil {
+		t.Fatalf("Register(base) error: %s\n", err.Error())
+	}
+	if fp, _ := r.Fingerprint("rule"); fp == expected["rule"] {
+		t.Errorf("Fingerprint(rule) expected a new fingerprint, got %s\n", fp)
+	}
+	if fp, _ := r.Fingerprint("orphan"); fp != expected["orphan"] {
+		t.Errorf("Fingerprint(orphan) expected=%s actual=%s\n", expected["orphan"], fp)
+	}
+	if err := r.Register("base", base); err != nil {
+		t.Fatalf("Register(base) error: %s\n", err.Error())
+	}
+	if fp, _ := r.Fingerprint("rule"); fp != expected["rule"] {
+		t.Errorf("Fingerprint(rule) expected=%s actual=%s\n", expected["rule"], fp)
+	}
+	r.Delete("base")
+	if fp, _ := r.Fingerprint("rule"); fp != rule.Fingerprint() {
+		t.Errorf("Fingerprint(rule) expected=%s actual=%s\n", rule.Fingerprint(), fp)
+	}
+}
@@ -0,0 +1,49 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Replace swaps every subtree for which `match` returns true with a copy of
+// `with`, and returns the number of subtrees replaced.  Nodes are matched
+// from the root down; the descendants of a replaced subtree, and the copies
+// of `with`, are not matched.  The tree is modified in place, so a matching
+// root is overwritten with the copy.
+func (n *Node) Replace(match func(*Node) bool, with *Node) int {
+	if match(n) {
+		*n = *with.copy()
+		return 1
+	}
+	count := 0
+	for _, c := range n.Nodes {
+		count += c.Replace(match, with)
+	}
+	return count
+}
+
+// ReferencesField returns a matcher for `Replace` selecting the ordinary
+// leaves which reference `field`, written as in leaves with or without the
+// leading dot, or any field nested within it.  For example ".Price" matches
+// leaves referencing `.Price` or `.Price.Net` but not `.PriceList`.
+func ReferencesField(field string) func(*Node) bool {
+	path := strings.Split(strings.TrimPrefix(field, "."), ".")
+	return func(n *Node) bool {
+		if n.Op != OperatorLeaf {
+			return false
+		}
+		t, err := parseLeaf(n.Leaf)
+		if err != nil {
+			return false
+		}
+		for _, f := range leafFields(t) {
+			if len(f) >= len(path) && overlaps(f, path) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,37 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestReplace(t *testing.T) {
+	n := NewNode(OperatorOr,
+		NewNode(OperatorAnd,
+			NewLeafNode("gt .LegacyPrice 5"),
+			NewLeafNode("lt .LegacyPrice.Net 10")),
+		NewLeafNode("eq .LegacyPriceList 1"),
+		NewLeafNode("eq $.LegacyPrice 2"))
+
+	with := NewLeafNode("gt .Price.Amount 5")
+	if count := n.Replace(ReferencesField(".LegacyPrice"), with); count != 3 {
+		t.Errorf("Replace() expected=3 actual=%d\n", count)
+	}
+	expected := "(.Price.Amount > 5 AND .Price.Amount > 5) OR .LegacyPriceList == 1 OR .Price.Amount > 5"
+	if actual := n.Infix(); actual != expected {
+		t.Errorf("Replace() expected=%s actual=%s\n", expected, actual)
+	}
+	if n.Nodes[0].Nodes[0] == with || n.Nodes[0].Nodes[0] == n.Nodes[0].Nodes[1] {
+		t.Errorf("Replace() expected each replacement to be a copy\n")
+	}
+
+	if count := n.Replace(func(n *Node) bool { return n.Op == OperatorOr }, NewLeafNode("true")); count != 1 || n.Leaf != "(true)" || n.Nodes != nil {
+		t.Errorf("Replace() expected the root to be replaced, got %d %s\n", count, n)
+	}
+	if count := n.Replace(func(*Node) bool { return false }, with); count != 0 {
+		t.Errorf("Replace() expected=0 actual=%d\n", count)
+	}
+}
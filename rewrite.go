@@ -0,0 +1,176 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// RewriteLeaves replaces the expression of every ordinary leaf with the
+// result of `fn`, for migrating rules.  If `fn` fails for any leaf its error
+// is returned, prefixed with the path of the leaf, and the tree is left
+// unchanged.  Rewritten leaves are not validated.
+func (n *Node) RewriteLeaves(fn func(expr string) (string, error)) error {
+	leaves := []*Node{}
+	exprs := []string{}
+	var err error
+	n.walkLeaves("/", func(path string, l *Node) {
+		if err != nil {
+			return
+		}
+		var e string
+		if e, err = fn(l.Leaf); err != nil {
+			err = nodeError(path, l, err)
+			return
+		}
+		leaves = append(leaves, l)
+		exprs = append(exprs, e)
+	})
+	if err != nil {
+		return err
+	}
+	for i, l := range leaves {
+		l.Leaf = exprs[i]
+	}
+	return nil
+}
+
+// RenameField renames every reference to the field `old` in the leaves of the
+// tree, of either kind, and in the fields of its switches, to `new`, and
+// returns the number of references renamed.  Fields are written as in leaves, with or without the leading dot,
+// so that renaming ".Milk" to ".Dairy.Milk" rewrites `.Milk` and `$.Milk`, and
+// `.Milk.Skim` into `.Dairy.Milk.Skim`, but leaves `.MilkShake`, string
+// literals, variables such as `$m.Milk` and fields of function results alone.
+func (n *Node) RenameField(old, new string) (int, error) {
+	op, err := fieldName(old)
+	if err != nil {
+		return 0, err
+	}
+	np, err := fieldName(new)
+	if err != nil {
+		return 0, err
+	}
+
+	var rename func(n *Node) int
+	rename = func(n *Node) int {
+		if n.isLeaf() {
+			var count int
+			n.Leaf, count = renameFields(n.Leaf, op, np, n.Op == OperatorAdvanced)
+			return count
+		}
+		count := 0
+		if n.Op == OperatorSwitch {
+			n.Leaf, count = renameFields(n.Leaf, op, np, false)
+		}
+		for _, c := range n.Nodes {
+			count += rename(c)
+		}
+		return count
+	}
+	return rename(n), nil
+}
+
+// fieldName splits a field written as in leaves into its parts, checking
+// that each is an identifier.
+func fieldName(field string) ([]string, error) {
+	parts := strings.Split(strings.TrimPrefix(field, "."), ".")
+	for _, p := range parts {
+		if p == "" || identEnd(p, 0) != len(p) || unicode.IsDigit(rune(p[0])) {
+			return nil, fmt.Errorf("invalid field name %q", field)
+		}
+	}
+	return parts, nil
+}
+
+// renameFields rewrites the references to the field `old`, or fields nested in
+// it, within the expression `src` (or, if `template` is set, within the
+// actions of the template `src`) to `new`, returning the result and the
+// number of references renamed.  String, raw string and character literals
+// are skipped.
+func renameFields(src string, old, new []string, template bool) (string, int) {
+	var b strings.Builder
+	count := 0
+	inAction := !template
+	var quote rune
+	escaped := false
+	prev := ' ' // the previous rune outside of literals
+
+	for i := 0; i < len(src); {
+		r, w := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case !inAction:
+			if strings.HasPrefix(src[i:], "{{") {
+				inAction, prev, w = true, ' ', 2
+			}
+		case quote != 0:
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\' && quote != '`':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+		case template && strings.HasPrefix(src[i:], "}}"):
+			inAction, w = false, 2
+		case r == '"' || r == '`' || r == '\'':
+			quote = r
+		case (r == '.' || (r == '$' && strings.HasPrefix(src[i+1:], "."))) && !isIdentRune(prev) && prev != ')' && prev != '.':
+			// A field of the data, `.A.B` or `$.A.B`.
+			start := i
+			if r == '$' {
+				start++
+			}
+			parts := []string{}
+			end := start
+			for end < len(src) && src[end] == '.' {
+				e := identEnd(src, end+1)
+				if e == end+1 {
+					break
+				}
+				parts = append(parts, src[end+1:e])
+				end = e
+			}
+			if len(parts) == 0 {
+				break
+			}
+			b.WriteString(src[i:start])
+			if len(parts) >= len(old) && overlaps(parts, old) {
+				b.WriteString("." + strings.Join(append(append([]string{}, new...), parts[len(old):]...), "."))
+				count++
+			} else {
+				b.WriteString(src[start:end])
+			}
+			i, prev = end, 'a'
+			continue
+		}
+		b.WriteString(src[i : i+w])
+		if inAction && quote == 0 {
+			prev = r
+		}
+		i += w
+	}
+	return b.String(), count
+}
+
+// identEnd returns the index just past the identifier starting at index `i`
+// of `s`, or `i` if there is none.
+func identEnd(s string, i int) int {
+	for i < len(s) {
+		r, w := utf8.DecodeRuneInString(s[i:])
+		if !isIdentRune(r) {
+			break
+		}
+		i += w
+	}
+	return i
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
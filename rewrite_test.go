@@ -0,0 +1,85 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestRewriteLeaves(t *testing.T) {
+	n := pricesTree()
+	err := n.RewriteLeaves(func(expr string) (string, error) {
+		return strings.Replace(expr, "ge ", "gt ", 1), nil
+	})
+	if err != nil {
+		t.Fatalf("RewriteLeaves() error: %s\n", err.Error())
+	}
+	expected := "((.Milk > 4 AND .Milk <= 6) AND (.Onions > 1 AND .Onions <= 2)) OR .Toothpaste > 5"
+	if actual := n.Infix(); actual != expected {
+		t.Errorf("RewriteLeaves() expected=%s actual=%s\n", expected, actual)
+	}
+
+	fail := errors.New("fail")
+	err = n.RewriteLeaves(func(expr string) (string, error) {
+		if strings.Contains(expr, "Onions") {
+			return "", fail
+		}
+		return "(true)", nil
+	})
+	if !errors.Is(err, fail) || !strings.HasPrefix(err.Error(), "/0/1/0: ") {
+		t.Errorf("RewriteLeaves() expected a failure at /0/1/0, got: %v\n", err)
+	}
+	if actual := n.Infix(); actual != expected {
+		t.Errorf("RewriteLeaves() expected the tree to be unchanged, got %s\n", actual)
+	}
+}
+
+func TestRenameField(t *testing.T) {
+	for _, tc := range []struct {
+		leaf     string
+		expected string
+		count    int
+	}{
+		{"ge .Milk 4", "(ge .Dairy.Milk 4)", 1},
+		{"and (ge $.Milk 4) (le .Milk.Skim 6)", "(and (ge $.Dairy.Milk 4) (le .Dairy.Milk.Skim 6))", 2},
+		{"eq .MilkShake .Milk", "(eq .MilkShake .Dairy.Milk)", 1},
+		{`eq .Name ".Milk"`, `(eq .Name ".Milk")`, 0},
+		{"eq .Name `.Milk` '.'", "(eq .Name `.Milk` '.')", 0},
+		{`eq "a\".Milk" .Milk`, `(eq "a\".Milk" .Dairy.Milk)`, 1},
+		{"eq (index .Items 0).Milk $x.Milk", "(eq (index .Items 0).Milk $x.Milk)", 0},
+		{`in .Milk [1.5, .5]`, `(in .Dairy.Milk [1.5, .5])`, 1},
+		{"eq .Other.Milk 1", "(eq .Other.Milk 1)", 0},
+	} {
+		n := NewLeafNode(tc.leaf)
+		count, err := n.RenameField(".Milk", "Dairy.Milk")
+		if err != nil {
+			t.Fatalf("RenameField() error: %s\n", err.Error())
+		}
+		if n.Leaf != tc.expected || count != tc.count {
+			t.Errorf("RenameField(%q) expected=%s (%d) actual=%s (%d)\n", tc.leaf, tc.expected, tc.count, n.Leaf, count)
+		}
+	}
+
+	n := NewNode(OperatorAnd,
+		NewLeafNode("ge .Milk 4"),
+		NewAdvancedLeafNode("Milk {{ $m := .Milk }}.Milk{{ gt $m 1 }}"))
+	if count, err := n.RenameField("Milk", "Dairy.Milk"); err != nil || count != 2 {
+		t.Errorf("RenameField() expected=2 actual=%d (%v)\n", count, err)
+	}
+	if expected := "Milk {{ $m := .Dairy.Milk }}.Milk{{ gt $m 1 }}"; n.Nodes[1].Leaf != expected {
+		t.Errorf("RenameField() expected=%s actual=%s\n", expected, n.Nodes[1].Leaf)
+	}
+	if err := n.Validate(); err != nil {
+		t.Errorf("RenameField() produced an invalid tree: %s\n", err.Error())
+	}
+
+	for _, names := range [][2]string{{"", "A"}, {"A", ".1B"}, {"A..B", "C"}, {"A", "B-C"}} {
+		if _, err := n.RenameField(names[0], names[1]); err == nil {
+			t.Errorf("RenameField(%q, %q) expected an error\n", names[0], names[1])
+		}
+	}
+}
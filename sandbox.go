@@ -0,0 +1,78 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"text/template/parse"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// sandboxFuncs are the functions leaves may always call when compiled
+// `WithAllowedFuncs`: the logical builtins, and `list`, which list literals
+// are rewritten into.
+var sandboxFuncs = []string{"and", "or", "not", "list"}
+
+// WithAllowedFuncs compiles the tree in a sandbox, in which leaves may only
+// call the functions `names`, along with `and`, `or`, `not` and the `list` of
+// list literals, for trees uploaded by users who are not trusted with every
+// function given by `WithFuncs`.  Trees whose leaves call, or merely name,
+// any other function, including template builtins such as `call` and
+// `printf` and the comparisons `eq` or `lt`, fail to compile with an error
+// wrapping `ErrFuncNotAllowed`.  Macros are expanded first, so the functions
+// they call must be allowed too.  The names of several options are merged.
+//
+// Leaves compiled by a backend, see `WithBackend`, are left to the backend.
+func WithAllowedFuncs(names ...string) Option {
+	return func(o *compileOptions) {
+		if o.allowed == nil {
+			o.allowed = map[string]bool{}
+			for _, name := range sandboxFuncs {
+				o.allowed[name] = true
+			}
+		}
+		for _, name := range names {
+			o.allowed[name] = true
+		}
+	}
+}
+
+// checkAllowed returns an error wrapping `ErrFuncNotAllowed` for the first
+// function called by the leaf `n` which the sandbox does not allow.
+func (c *compiler) checkAllowed(n *Node) error {
+	var t *parse.Tree
+	var err error
+	if n.Op == OperatorAdvanced {
+		t, err = parseAdvanced(n.Leaf)
+	} else {
+		t, err = parseLeaf(n.Leaf)
+	}
+	if err != nil {
+		return err
+	}
+	for _, name := range leafFuncNames(t) {
+		if !c.opts.allowed[name] {
+			return fmt.Errorf("%w: %q", ErrFuncNotAllowed, name)
+		}
+	}
+	return nil
+}
+
+// leafFuncNames returns the names of the functions the parsed leaf `t` calls,
+// in order: those of its commands, and those given as arguments, which
+// templates call without arguments.
+func leafFuncNames(t *parse.Tree) []string {
+	var names []string
+	walkCommands(t.Root, func(cmd *parse.CommandNode, _ bool) {
+		for _, arg := range cmd.Args {
+			if c, ok := arg.(*parse.ChainNode); ok {
+				arg = c.Node
+			}
+			if id, ok := arg.(*parse.IdentifierNode); ok {
+				names = append(names, id.Ident)
+			}
+		}
+	})
+	return names
+}
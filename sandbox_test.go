@@ -0,0 +1,44 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"testing"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestWithAllowedFuncs(t *testing.T) {
+	secret := template.FuncMap{"secret": func() bool { return true }}
+	for _, tc := range []struct {
+		n    *Node
+		opts []Option
+		path string // of the leaf not allowed, if any
+	}{
+		{NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("not (gt .B 2)")), []Option{WithAllowedFuncs("eq", "gt")}, ""},
+		{NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("gt .B 2")), []Option{WithAllowedFuncs("eq")}, "/1"},
+		{NewNode(OperatorOr, NewLeafNode("eq .A 1"), NewLeafNode("secret")), []Option{WithFuncs(secret), WithAllowedFuncs("eq")}, "/1"},
+		{NewLeafNode("secret"), []Option{WithFuncs(secret), WithAllowedFuncs("eq"), WithAllowedFuncs("secret")}, ""},
+		{NewLeafNode("eq .A secret"), []Option{WithFuncs(secret), WithAllowedFuncs("eq")}, "/"},
+		{NewLeafNode("eq (call .F).X 1"), []Option{WithAllowedFuncs("eq")}, "/"},
+		{NewLeafNode(`eq (printf "%d" .A) "1"`), []Option{WithAllowedFuncs("eq")}, "/"},
+		{NewLeafNode(`in .A ["x", "y"]`), []Option{WithFuncs(StdFuncs()), WithAllowedFuncs("in")}, ""},
+		{NewAdvancedLeafNode("{{ if .A }}{{ secret }}{{ else }}false{{ end }}"), []Option{WithFuncs(secret), WithAllowedFuncs()}, "/"},
+		{NewSwitchNode(".A", NewCaseNode(NewLeafNode("true"), "1"), NewCaseNode(NewLeafNode("gt .B 2"))), []Option{WithAllowedFuncs()}, "/1/0"},
+		{NewLeafNode("secret"), []Option{WithFuncs(secret)}, ""},
+		{NewLeafNode("secret"), append(Config{AllowedFuncs: []string{"eq"}}.Options(), WithFuncs(secret)), "/"},
+	} {
+		_, err := Compile(tc.n, tc.opts...)
+		var ne *NodeError
+		switch {
+		case tc.path == "" && err != nil:
+			t.Errorf("Compile(%s) unexpected error: %s\n", tc.n.Leaf, err.Error())
+		case tc.path != "" && (!errors.Is(err, ErrFuncNotAllowed) || !errors.As(err, &ne)):
+			t.Errorf("Compile(%s) expected=%v actual=%v\n", tc.n.Leaf, ErrFuncNotAllowed, err)
+		case tc.path != "" && ne.Path != tc.path:
+			t.Errorf("Compile(%s) expected=%s actual=%s\n", tc.n.Leaf, tc.path, ne.Path)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// SatCountOptions configures the estimates of `SatCount`.
+type SatCountOptions struct {
+	// Probabilities are the probabilities of leaves being true, keyed by
+	// leaf as written or as named by `TruthTable`.  Other leaves are true
+	// with a probability of one half.
+	Probabilities map[string]float64 `json:"probabilities,omitempty" yaml:"probabilities,omitempty"`
+
+	// Samples is the number of combinations of the results of the leaves
+	// drawn when the estimate is not exact, 10000 if it is not positive.
+	Samples int `json:"samples,omitempty" yaml:"samples,omitempty"`
+
+	// Seed seeds the drawing of the samples, so that estimates are
+	// reproducible.
+	Seed int64 `json:"seed,omitempty" yaml:"seed,omitempty"`
+}
+
+// DefaultSatCountOptions are the options used by `Node.SatCount`.
+var DefaultSatCountOptions = SatCountOptions{
+	Samples: 10000,
+}
+
+// SatEstimate is the probability of a tree being true, see `SatCount`.
+type SatEstimate struct {
+	// Fraction is the probability of the tree being true, the fraction of
+	// the combinations of the results of its leaves which make it true if
+	// they are all equally likely.
+	Fraction float64 `json:"Fraction"`
+
+	// Exact reports whether the fraction was computed rather than sampled.
+	Exact bool `json:"Exact"`
+
+	// Samples is the number of combinations the fraction was sampled from,
+	// and StdErr its standard error, both zero if it is exact.
+	Samples int     `json:"Samples"`
+	StdErr  float64 `json:"StdErr"`
+}
+
+// SatCount estimates the firing probability of the tree within
+// `DefaultSatCountOptions`, see `SatCountOptions.SatCount`.
+func (n *Node) SatCount() (*SatEstimate, error) {
+	return DefaultSatCountOptions.SatCount(n)
+}
+
+// SatCount estimates the probability of the tree rooted at `n` being true,
+// each of its distinct leaves being true independently of the others with
+// the probability of `Probabilities`, for estimating how often a new rule
+// would fire before deploying it.  Leaves are the inputs of `ToBDD`: the
+// fraction is exact unless the decision diagram of the tree exceeds its
+// limits, and is sampled from `Samples` combinations otherwise.
+//
+// As for `Satisfiable`, leaves other than `not` are independent of one
+// another, so that comparisons which never hold together, such as
+// `(gt .A 5)` and `(lt .A 3)`, should first be pruned by `PruneBounds`.
+// Probabilities outside of [0, 1], or of leaves which are not in the tree,
+// are errors.  The tree is not modified.
+func (o SatCountOptions) SatCount(n *Node) (*SatEstimate, error) {
+	e, vars, err := n.boolean(true)
+	if err != nil {
+		return nil, err
+	}
+	probs, err := o.probabilities(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := n.ToBDD()
+	switch {
+	case err == nil:
+		return &SatEstimate{Fraction: b.probability(probs), Exact: true}, nil
+	case !errors.Is(err, ErrLimitExceeded):
+		return nil, err
+	}
+
+	samples := o.Samples
+	if samples <= 0 {
+		samples = DefaultSatCountOptions.Samples
+	}
+	r := rand.New(rand.NewSource(o.Seed))
+	vals := make([]bool, len(vars))
+	hits := 0
+	for i := 0; i < samples; i++ {
+		for v, p := range probs {
+			vals[v] = r.Float64() < p
+		}
+		if e.eval(vals) {
+			hits++
+		}
+	}
+	f := float64(hits) / float64(samples)
+	return &SatEstimate{Fraction: f, Samples: samples, StdErr: math.Sqrt(f * (1 - f) / float64(samples))}, nil
+}
+
+// probabilities returns the probability of each of the variables `vars`
+// being true.
+func (o SatCountOptions) probabilities(vars []string) ([]float64, error) {
+	index := map[string]int{}
+	probs := make([]float64, len(vars))
+	for i, name := range vars {
+		index[name] = i
+		probs[i] = 0.5
+	}
+	for leaf, p := range o.Probabilities {
+		i, ok := index[leaf]
+		if !ok {
+			s, _ := normalizeLeaf(leaf, false)
+			i, ok = index["("+s+")"]
+		}
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("no leaf %s in the tree", leaf)
+		case !(p >= 0 && p <= 1):
+			return nil, fmt.Errorf("probability of %s is %v, not within [0, 1]", leaf, p)
+		}
+		probs[i] = p
+	}
+	return probs, nil
+}
+
+// probability returns the probability of the function of the diagram being
+// true, each of its leaves being true with the probability of `probs`.
+func (b *BDD) probability(probs []float64) float64 {
+	memo := map[int]float64{bddFalse: 0, bddTrue: 1}
+	var p func(u int) float64
+	p = func(u int) float64 {
+		if v, ok := memo[u]; ok {
+			return v
+		}
+		n := b.m.nodes[u]
+		memo[u] = probs[n.v]*p(n.hi) + (1-probs[n.v])*p(n.lo)
+		return memo[u]
+	}
+	return p(b.root)
+}
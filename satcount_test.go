@@ -0,0 +1,76 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestSatCount(t *testing.T) {
+	for _, tc := range []struct {
+		tree     *Node
+		probs    map[string]float64
+		expected float64
+	}{
+		{NewLeafNode(".A"), nil, 0.5},
+		{NewNode(OperatorAnd, NewLeafNode(".A"), NewLeafNode(".B")), nil, 0.25},
+		{NewNode(OperatorOr, NewLeafNode(".A"), NewLeafNode(".B"), NewLeafNode(".C")), nil, 0.875},
+		{NewNode(OperatorAnd, NewLeafNode(".A"), NewLeafNode("not .A")), nil, 0},
+		{NewNode(OperatorOr, NewLeafNode(".A"), NewLeafNode("true")), nil, 1},
+		{NewNode(OperatorAnd, NewLeafNode("gt .Amount 1000"), NewLeafNode(`eq .Country "US"`)), map[string]float64{"gt .Amount 1000": 0.01, `(eq .Country "US")`: 0.4}, 0.004},
+		{NewNode(OperatorAnd, NewLeafNode("not .A"), NewLeafNode(".B")), map[string]float64{".A": 0.9, ".B": 0.5}, 0.05},
+		{NewIfNode(NewLeafNode(".A"), NewLeafNode(".B"), NewLeafNode(".C")), map[string]float64{".A": 0.2, ".B": 1, ".C": 0}, 0.2},
+	} {
+		est, err := SatCountOptions{Probabilities: tc.probs}.SatCount(tc.tree)
+		if err != nil {
+			t.Fatalf("SatCount(%s) error: %s\n", tc.tree, err.Error())
+		}
+		if math.Abs(est.Fraction-tc.expected) > 1e-12 || !est.Exact || est.Samples != 0 {
+			t.Errorf("SatCount(%s) expected=%v actual=%+v\n", tc.tree, tc.expected, est)
+		}
+	}
+
+	for _, probs := range []map[string]float64{
+		{".B": 0.5},
+		{".A": 1.5},
+		{".A": math.NaN()},
+	} {
+		if _, err := (SatCountOptions{Probabilities: probs}).SatCount(NewLeafNode(".A")); err == nil {
+			t.Errorf("SatCount(%v) expected an error\n", probs)
+		}
+	}
+}
+
+func TestSatCountSampled(t *testing.T) {
+	registerTestOperator(t, "majority", majority)
+
+	// Registered operators of too many children have no decision diagram.
+	tree := NewNode("majority")
+	for i := 0; i <= maxBDDOperatorChildren; i++ {
+		tree.Nodes = append(tree.Nodes, NewLeafNode(fmt.Sprintf(".L%d", i)))
+	}
+	est, err := tree.SatCount()
+	if err != nil {
+		t.Fatalf("SatCount() error: %s\n", err.Error())
+	}
+	if est.Exact || est.Samples != DefaultSatCountOptions.Samples || math.Abs(est.Fraction-0.5) > 4*est.StdErr || est.StdErr == 0 {
+		t.Errorf("SatCount() expected a sample of 0.5 actual=%+v\n", est)
+	}
+	again, err := tree.SatCount()
+	if err != nil || *again != *est {
+		t.Errorf("SatCount() expected=%+v actual=%+v %v\n", est, again, err)
+	}
+
+	// Leaves which are always true make every sample true.
+	o := SatCountOptions{Samples: 100, Probabilities: map[string]float64{}}
+	for i := 0; i <= maxBDDOperatorChildren; i++ {
+		o.Probabilities[fmt.Sprintf(".L%d", i)] = 1
+	}
+	if est, err := o.SatCount(tree); err != nil || est.Fraction != 1 || est.Samples != 100 {
+		t.Errorf("SatCount() expected=1 actual=%+v %v\n", est, err)
+	}
+}
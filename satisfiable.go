@@ -0,0 +1,75 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// maxSatisfiableSteps bounds the partial assignments `Satisfiable` tries.
+const maxSatisfiableSteps = 1 << 20
+
+// Satisfiable reports whether some combination of the results of the leaves
+// of the tree makes it true, returning one such combination keyed by leaf as
+// in `TruthTable`, so that rules which can never fire can be flagged and
+// rule authors shown a scenario in which theirs does.  Leaves whose results
+// do not matter to the combination found are false.
+//
+// The leaves `true` and `false` are constants, and a leaf `not X` is the
+// negation of the leaf `X`, so `(and (eq .A 1) (not (eq .A 1)))` is not
+// satisfiable.  Other leaves are independent of one another, so leaves which
+// cannot be true together, such as `(gt .A 5)` and `(lt .A 3)`, are not
+// recognized as such.  Searches which try more than a million partial
+// combinations fail with an error wrapping `ErrLimitExceeded`.
+func (n *Node) Satisfiable() (bool, map[string]bool, error) {
+	e, vars, err := n.boolean(true)
+	if err != nil {
+		return false, nil, err
+	}
+
+	vals := make([]Truth, len(vars))
+	for i := range vals {
+		vals[i] = Unknown
+	}
+	steps := 0
+	ok, err := e.satisfy(vals, 0, &steps)
+	if err != nil || !ok {
+		return false, nil, err
+	}
+
+	assignment := map[string]bool{}
+	e.leaves(func(l *boolExpr) {
+		v := l.constant
+		if l.variable >= 0 {
+			v = (vals[l.variable] == True) != l.negated
+		}
+		assignment[l.name] = v
+	})
+	return true, assignment, nil
+}
+
+// satisfy searches for values of the variables from `i` on, given those
+// before it in `vals`, which make the expression true, trying false before
+// true and leaving the variables whose values do not matter unknown.
+func (e *boolExpr) satisfy(vals []Truth, i int, steps *int) (bool, error) {
+	if *steps++; *steps > maxSatisfiableSteps {
+		return false, fmt.Errorf("%w: more than %d steps deciding satisfiability", ErrLimitExceeded, maxSatisfiableSteps)
+	}
+	switch e.eval3(vals) {
+	case True:
+		return true, nil
+	case False:
+		return false, nil
+	}
+
+	for _, v := range []Truth{False, True} {
+		vals[i] = v
+		if ok, err := e.satisfy(vals, i+1, steps); ok || err != nil {
+			return ok, err
+		}
+	}
+	vals[i] = Unknown
+	return false, nil
+}
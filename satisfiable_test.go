@@ -0,0 +1,76 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestSatisfiable(t *testing.T) {
+	for _, tc := range []struct {
+		n        *Node
+		sat      bool
+		expected map[string]bool
+	}{
+		{pricesTree(), true, map[string]bool{
+			"(ge .Milk 4)": false, "(le .Milk 6)": false, "(ge .Onions 1)": false, "(le .Onions 2)": false, "(gt .Toothpaste 5)": true,
+		}},
+		{NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("not (eq  .A 1)")), false, nil},
+		{NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode("not (not (eq .A 1))")), true, map[string]bool{
+			"(eq .A 1)": true, "(not (not (eq .A 1)))": true,
+		}},
+		{NewNode(OperatorOr, NewLeafNode("false"), NewNode(OperatorAnd, NewLeafNode("not true"), NewLeafNode(".B"))), false, nil},
+		{NewNode(OperatorOr, NewLeafNode("false"), NewLeafNode("not .Deleted")), true, map[string]bool{
+			"(false)": false, "(not .Deleted)": true,
+		}},
+		{NewNode(OperatorAnd, NewAdvancedLeafNode("{{ .A }}"), NewLeafNode("true")), true, map[string]bool{
+			"{{ .A }}": true, "(true)": true,
+		}},
+	} {
+		sat, assignment, err := tc.n.Satisfiable()
+		if err != nil {
+			t.Errorf("Satisfiable(%s) error: %s\n", tc.n, err.Error())
+			continue
+		}
+		if sat != tc.sat || !reflect.DeepEqual(assignment, tc.expected) {
+			t.Errorf("Satisfiable(%s) expected=%v %v actual=%v %v\n", tc.n, tc.sat, tc.expected, sat, assignment)
+		}
+	}
+}
+
+func TestSatisfiableLarge(t *testing.T) {
+	// The and of 40 ors, 2^80 combinations in all.
+	n := NewNode(OperatorAnd)
+	for i := 0; i < 40; i++ {
+		n.Nodes = append(n.Nodes, NewNode(OperatorOr, NewLeafNode(fmt.Sprintf("eq .A %d", i)), NewLeafNode(fmt.Sprintf("eq .B %d", i))))
+	}
+	sat, assignment, err := n.Satisfiable()
+	if err != nil || !sat || len(assignment) != 80 {
+		t.Fatalf("Satisfiable() expected a satisfying assignment, got %v %d (%v)\n", sat, len(assignment), err)
+	}
+	e, _, _ := n.boolean(true)
+	vals := make([]bool, 80)
+	for i := range vals {
+		vals[i] = assignment[fmt.Sprintf("(eq .%s %d)", "AB"[i%2:i%2+1], i/2)]
+	}
+	if !e.eval(vals) {
+		t.Errorf("Satisfiable() assignment does not satisfy the tree: %v\n", assignment)
+	}
+
+	for _, tc := range []struct {
+		n   *Node
+		err error
+	}{
+		{NewNode(OperatorAnd, NewNode(OperatorOr)), ErrEmptyNode},
+		{NewNode("xor"), ErrInvalidOperator},
+	} {
+		if _, _, err := tc.n.Satisfiable(); !errors.Is(err, tc.err) {
+			t.Errorf("Satisfiable(%s) expected=%v actual=%v\n", tc.n, tc.err, err)
+		}
+	}
+}
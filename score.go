@@ -0,0 +1,327 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Aggregate combines the scores of the children of a node, see `Score`.
+type Aggregate string
+
+const (
+	AggregateSum  = Aggregate("sum")  // the weighted sum of the scores
+	AggregateMean = Aggregate("mean") // the weighted average of the scores
+	AggregateMin  = Aggregate("min")  // the least score
+	AggregateMax  = Aggregate("max")  // the greatest score
+
+	// The probabilistic aggregates of scores between 0 and 1, see
+	// `FuzzyProduct`, which ignore weights.
+	AggregateProduct = Aggregate("product") // the product of the scores
+	AggregateProbSum = Aggregate("probsum") // one less the product of one less each score
+)
+
+// ScoreOptions configures how a tree is scored by `Score`.
+type ScoreOptions struct {
+	// And and Or aggregate the scores of the children of `and` and `or`
+	// nodes, `min` and `max` if empty, which score a tree of boolean leaves as
+	// it evaluates.
+	And Aggregate `json:"And,omitempty"`
+	Or  Aggregate `json:"Or,omitempty"`
+
+	// Nodes overrides the aggregate of the nodes at the paths given, and sets
+	// that of nodes of registered operators, which cannot be scored
+	// otherwise.
+	Nodes map[string]Aggregate `json:"Nodes,omitempty"`
+
+	// Weights weighs the scores of the nodes at the paths given in the sum or
+	// average of their parent, 1 if not given.
+	Weights map[string]float64 `json:"Weights,omitempty"`
+
+	// Threshold is the score from which `ScoreResult.Pass` is set.
+	Threshold float64 `json:"Threshold,omitempty"`
+
+	unit bool // leaves must score between 0 and 1, see `EvaluateFuzzy`
+}
+
+// ScoreResult is the score of a tree.
+type ScoreResult struct {
+	Value float64 `json:"Value"`
+	Pass  bool    `json:"Pass"` // `Value` is at least the threshold
+}
+
+// Score evaluates the compiled tree as a score rather than a boolean, for
+// graded results such as risk: each leaf scores the number it renders, or 1
+// for "true" and 0 for "false", and each node aggregates the scores of all of
+// its children as configured by `o`, without short-circuiting.  `if` nodes
+// score as the branch their condition chooses.  Leaves
+// referencing missing fields are handled as `WithMissing` says, scoring 0
+// where they would be false.
+//
+// Unknown aggregates, NaN, infinite or negative weights and weights or
+// aggregates of paths which are not nodes of the tree fail with an error
+// wrapping `ErrInvalidConfig`, and leaves rendering anything other than a
+// finite number or a boolean with one wrapping `ErrNotBoolean`.
+func (ct *CompiledTree) Score(data interface{}, o ScoreOptions) (ScoreResult, error) {
+	return ct.ScoreContext(context.Background(), data, o)
+}
+
+// ScoreContext is `Score` respecting the cancellation and deadline of `ctx`
+// as `EvaluateContext` does.
+func (ct *CompiledTree) ScoreContext(ctx context.Context, data interface{}, o ScoreOptions) (ScoreResult, error) {
+	if err := o.validate(ct); err != nil {
+		return ScoreResult{}, err
+	}
+	st := &evalState{ctx: ctx, stop: ctx, missing: ct.opts.missing}
+	st.withBudget(ct.opts.budget)
+	defer releaseState(st)
+	v, err := ct.eval.score(st, data, &o)
+	if err != nil {
+		return ScoreResult{}, err
+	}
+	return ScoreResult{Value: v, Pass: v >= o.Threshold}, nil
+}
+
+// validate checks the options against the tree `ct`.
+func (o *ScoreOptions) validate(ct *CompiledTree) error {
+	paths := map[string]bool{}
+	ct.eval.walk(func(cn *compiledNode) {
+		paths[cn.path] = true
+	})
+
+	for _, a := range []Aggregate{o.And, o.Or} {
+		if a != "" && !a.valid() {
+			return fmt.Errorf("%w: unknown aggregate %q", ErrInvalidConfig, string(a))
+		}
+	}
+	for _, p := range sortedKeys(o.Nodes) {
+		if !paths[p] {
+			return fmt.Errorf("%w: aggregate of %s: %v", ErrInvalidConfig, p, ErrNodeNotFound)
+		}
+		if !o.Nodes[p].valid() {
+			return fmt.Errorf("%w: unknown aggregate %q of %s", ErrInvalidConfig, string(o.Nodes[p]), p)
+		}
+	}
+	for _, p := range sortedKeys(o.Weights) {
+		if !paths[p] {
+			return fmt.Errorf("%w: weight of %s: %v", ErrInvalidConfig, p, ErrNodeNotFound)
+		}
+		if w := o.Weights[p]; math.IsNaN(w) || math.IsInf(w, 0) || w < 0 {
+			return fmt.Errorf("%w: weight %v of %s", ErrInvalidConfig, w, p)
+		}
+	}
+	return nil
+}
+
+func (a Aggregate) valid() bool {
+	switch a {
+	case AggregateSum, AggregateMean, AggregateMin, AggregateMax, AggregateProduct, AggregateProbSum:
+		return true
+	}
+	return false
+}
+
+// aggregate returns the aggregate of the node `cn`.
+func (o *ScoreOptions) aggregate(cn *compiledNode) (Aggregate, error) {
+	if a, ok := o.Nodes[cn.path]; ok {
+		return a, nil
+	}
+	switch cn.node.Op {
+	case OperatorAnd:
+		if o.And != "" {
+			return o.And, nil
+		}
+		return AggregateMin, nil
+	case OperatorOr:
+		if o.Or != "" {
+			return o.Or, nil
+		}
+		return AggregateMax, nil
+	}
+	return "", nodeError(cn.path, cn.node, fmt.Errorf("%w: %s nodes need an aggregate to be scored", ErrInvalidConfig, cn.node.Op))
+}
+
+// weight returns the weight of the node `cn`.
+func (o *ScoreOptions) weight(cn *compiledNode) float64 {
+	if w, ok := o.Weights[cn.path]; ok {
+		return w
+	}
+	return 1
+}
+
+func (cn *compiledNode) score(st *evalState, data interface{}, o *ScoreOptions) (float64, error) {
+	if err := st.stopped(cn); err != nil {
+		return 0, err
+	}
+	if cn.node.isLeaf() {
+		v, err := cn.scoreLeaf(st, data)
+		if err == nil && o.unit && (v < 0 || v > 1) {
+			return 0, nodeError(cn.path, cn.node, fmt.Errorf("%w: %v is not between 0 and 1", ErrNotBoolean, v))
+		}
+		return v, err
+	}
+
+	if cn.node.Op == OperatorIf {
+		return cn.scoreIf(st, data, o)
+	}
+	if cn.sw != nil {
+		c, err := cn.selectCase(st, data)
+		if err != nil || c == nil {
+			return 0, err
+		}
+		return c.children[0].score(st, data, o)
+	}
+
+	a, err := o.aggregate(cn)
+	if err != nil {
+		return 0, err
+	}
+	var sum, weights float64
+	min, max := math.Inf(1), math.Inf(-1)
+	product, complements := 1.0, 1.0
+	for _, c := range cn.children {
+		v, err := c.score(st, data, o)
+		if err != nil {
+			return 0, err
+		}
+		w := o.weight(c)
+		sum, weights = sum+w*v, weights+w
+		min, max = math.Min(min, v), math.Max(max, v)
+		product, complements = product*v, complements*(1-v)
+	}
+
+	switch a {
+	case AggregateSum:
+		return sum, nil
+	case AggregateMean:
+		if weights == 0 {
+			return 0, nil
+		}
+		return sum / weights, nil
+	case AggregateMin:
+		return min, nil
+	case AggregateProduct:
+		return product, nil
+	case AggregateProbSum:
+		return 1 - complements, nil
+	}
+	return max, nil
+}
+
+// scoreIf scores an `if` node as the branch its condition chooses, evaluated
+// as `Evaluate` would, or in fuzzy logic as the average of the branches
+// weighted by the degree of the condition and its complement.
+func (cn *compiledNode) scoreIf(st *evalState, data interface{}, o *ScoreOptions) (float64, error) {
+	if !o.unit {
+		v, err := cn.children[0].evaluate(st, data)
+		if err != nil {
+			return 0, err
+		}
+		if v {
+			return cn.children[1].score(st, data, o)
+		}
+		return cn.children[2].score(st, data, o)
+	}
+
+	var vs [3]float64
+	for i, c := range cn.children {
+		v, err := c.score(st, data, o)
+		if err != nil {
+			return 0, err
+		}
+		vs[i] = v
+	}
+	return vs[0]*vs[1] + (1-vs[0])*vs[2], nil
+}
+
+func (cn *compiledNode) scoreLeaf(st *evalState, data interface{}) (float64, error) {
+	if st.missing != MissingDefault {
+		if f, ok := missingField(data, cn.fields); ok {
+			if st.missing == MissingIsFalse {
+				return 0, nil
+			}
+			return 0, nodeError(cn.path, cn.node, fmt.Errorf("%w: %s", ErrMissingField, f))
+		}
+	}
+
+	out, err := cn.renderLeaf(st, data)
+	if err != nil {
+		return 0, err
+	}
+	return parseScore(cn, out)
+}
+
+// parseScore parses the output of the leaf `cn` as a score.
+func parseScore(cn *compiledNode, out string) (float64, error) {
+	s := strings.TrimSpace(out)
+	switch s {
+	case "true":
+		return 1, nil
+	case "false":
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, nodeError(cn.path, cn.node, fmt.Errorf("%w: %q is not a score", ErrNotBoolean, out))
+	}
+	return v, nil
+}
+
+// sortedKeys returns the keys of `m` in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// FuzzyLogic chooses how `EvaluateFuzzy` combines degrees of truth.
+type FuzzyLogic string
+
+const (
+	FuzzyMinMax  = FuzzyLogic("minmax")  // `and` is the minimum, `or` the maximum
+	FuzzyProduct = FuzzyLogic("product") // `and` is the product, `or` the probabilistic sum
+)
+
+// EvaluateFuzzy evaluates the compiled tree in fuzzy logic, returning the
+// degree between 0 and 1 to which it is true rather than a boolean, for
+// instance as a confidence.  Leaves render their degree, or "true" for 1 and
+// "false" for 0; `logic` combines the degrees of the children of `and` and
+// `or` nodes, `FuzzyMinMax` if empty, and `if` nodes weigh their branches by
+// the degree of their condition.  Every child is evaluated, as by
+// `Score`, and a tree of boolean leaves evaluates to 1 if it is true and 0 if
+// it is false with either logic.
+//
+// Leaves rendering a degree outside of [0, 1], or anything else, fail with an
+// error wrapping `ErrNotBoolean`, and nodes of registered operators with one
+// wrapping `ErrInvalidConfig`.
+func (ct *CompiledTree) EvaluateFuzzy(data interface{}, logic FuzzyLogic) (float64, error) {
+	return ct.EvaluateFuzzyContext(context.Background(), data, logic)
+}
+
+// EvaluateFuzzyContext is `EvaluateFuzzy` respecting the cancellation and
+// deadline of `ctx` as `EvaluateContext` does.
+func (ct *CompiledTree) EvaluateFuzzyContext(ctx context.Context, data interface{}, logic FuzzyLogic) (float64, error) {
+	o := ScoreOptions{unit: true}
+	switch logic {
+	case "", FuzzyMinMax:
+		o.And, o.Or = AggregateMin, AggregateMax
+	case FuzzyProduct:
+		o.And, o.Or = AggregateProduct, AggregateProbSum
+	default:
+		return 0, fmt.Errorf("%w: unknown fuzzy logic %q", ErrInvalidConfig, string(logic))
+	}
+	res, err := ct.ScoreContext(ctx, data, o)
+	return res.Value, err
+}
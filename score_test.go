@@ -0,0 +1,158 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestScore(t *testing.T) {
+	fm := template.FuncMap{"ratio": func(a, b float64) float64 { return a / b }}
+	n := NewNode(OperatorOr,
+		NewNode(OperatorAnd, NewLeafNode("gt .Amount 100"), NewLeafNode("ratio .Amount 1000")),
+		NewLeafNode("0.25"))
+	ct, err := Compile(n, WithFuncs(fm))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	data := map[string]interface{}{"Amount": 500.0}
+	for _, tc := range []struct {
+		name     string
+		o        ScoreOptions
+		expected float64
+		pass     bool
+	}{
+		// min(1, 0.5), then max(0.5, 0.25).
+		{"default", ScoreOptions{}, 0.5, true},
+		{"sum", ScoreOptions{And: AggregateSum, Or: AggregateSum}, 1.75, true},
+		{"mean", ScoreOptions{And: AggregateMean, Or: AggregateMean, Threshold: 0.9}, 0.5, false},
+		{"weighted", ScoreOptions{
+			Or:      AggregateMean,
+			Weights: map[string]float64{"/0": 3, "/1": 1},
+		}, 0.4375, true},
+		{"nodes", ScoreOptions{Nodes: map[string]Aggregate{"/": AggregateMin, "/0": AggregateMax}}, 0.25, true},
+		{"zero weights", ScoreOptions{Or: AggregateMean, Weights: map[string]float64{"/0": 0, "/1": 0}}, 0, true},
+	} {
+		res, err := ct.Score(data, tc.o)
+		if err != nil {
+			t.Errorf("Score(%s) error: %s\n", tc.name, err.Error())
+			continue
+		}
+		if math.Abs(res.Value-tc.expected) > 1e-9 || res.Pass != tc.pass {
+			t.Errorf("Score(%s) expected=%v,%v actual=%v,%v\n", tc.name, tc.expected, tc.pass, res.Value, res.Pass)
+		}
+	}
+}
+
+func TestScoreErrors(t *testing.T) {
+	registerTestOperator(t, "majority", majority)
+	n := NewNode(OperatorAnd, NewLeafNode(".A"), NewNode("majority", NewLeafNode(".B")))
+	ct, err := Compile(n)
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	data := map[string]interface{}{"A": 2, "B": "high"}
+
+	for _, tc := range []struct {
+		name     string
+		o        ScoreOptions
+		expected error
+	}{
+		{"aggregate", ScoreOptions{And: "median"}, ErrInvalidConfig},
+		{"node aggregate", ScoreOptions{Nodes: map[string]Aggregate{"/1": "median"}}, ErrInvalidConfig},
+		{"path", ScoreOptions{Nodes: map[string]Aggregate{"/2": AggregateSum}}, ErrInvalidConfig},
+		{"weight", ScoreOptions{Weights: map[string]float64{"/0": -1}}, ErrInvalidConfig},
+		{"operator", ScoreOptions{}, ErrInvalidConfig},
+		{"leaf", ScoreOptions{Nodes: map[string]Aggregate{"/1": AggregateSum}}, ErrNotBoolean},
+	} {
+		if _, err := ct.Score(data, tc.o); !errors.Is(err, tc.expected) {
+			t.Errorf("Score(%s) expected=%v actual=%v\n", tc.name, tc.expected, err)
+		}
+	}
+
+	// Missing fields score as false would evaluate.
+	ct, err = Compile(NewLeafNode("eq .A 1"), WithMissing(MissingIsFalse))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if res, err := ct.Score(map[string]interface{}{}, ScoreOptions{Threshold: 0.5}); err != nil || res.Value != 0 || res.Pass {
+		t.Errorf("Score(missing) expected=0 actual=%v err=%v\n", res, err)
+	}
+}
+
+func TestEvaluateFuzzy(t *testing.T) {
+	fm := template.FuncMap{"near": func(v, target, width float64) float64 {
+		return math.Max(0, 1-math.Abs(v-target)/width)
+	}}
+	n := NewNode(OperatorOr,
+		NewNode(OperatorAnd, NewLeafNode("near .Price 10 5"), NewLeafNode("near .Rating 5 2")),
+		NewLeafNode(`eq .Brand "acme"`))
+	ct, err := Compile(n, WithFuncs(fm))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	for _, tc := range []struct {
+		data    map[string]interface{}
+		minmax  float64
+		product float64
+	}{
+		// and(0.8, 0.5) or 0.
+		{map[string]interface{}{"Price": 11.0, "Rating": 4.0, "Brand": "other"}, 0.5, 0.4},
+		// and(0.8, 0.5) or 1.
+		{map[string]interface{}{"Price": 11.0, "Rating": 4.0, "Brand": "acme"}, 1, 1},
+		{map[string]interface{}{"Price": 30.0, "Rating": 5.0, "Brand": "other"}, 0, 0},
+	} {
+		for _, logic := range []FuzzyLogic{FuzzyMinMax, FuzzyProduct} {
+			expected := tc.minmax
+			if logic == FuzzyProduct {
+				expected = tc.product
+			}
+			v, err := ct.EvaluateFuzzy(tc.data, logic)
+			if err != nil || math.Abs(v-expected) > 1e-9 {
+				t.Errorf("EvaluateFuzzy(%v, %s) expected=%v actual=%v err=%v\n", tc.data, logic, expected, v, err)
+			}
+		}
+	}
+
+	if _, err := ct.EvaluateFuzzy(map[string]interface{}{}, "gödel"); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("EvaluateFuzzy(gödel) expected=%v actual=%v\n", ErrInvalidConfig, err)
+	}
+	ct, err = Compile(NewLeafNode("1.5"))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if _, err := ct.EvaluateFuzzy(nil, FuzzyMinMax); !errors.Is(err, ErrNotBoolean) {
+		t.Errorf("EvaluateFuzzy(1.5) expected=%v actual=%v\n", ErrNotBoolean, err)
+	}
+}
+
+func TestScoreIf(t *testing.T) {
+	n := NewIfNode(NewLeafNode(".Premium"), NewLeafNode("0.9"), NewLeafNode(".Degree"))
+	ct, err := Compile(n)
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	for _, tc := range []struct {
+		data     map[string]interface{}
+		expected float64
+	}{
+		{map[string]interface{}{"Premium": true, "Degree": 0.5}, 0.9},
+		{map[string]interface{}{"Premium": false, "Degree": 0.5}, 0.5},
+	} {
+		if res, err := ct.Score(tc.data, ScoreOptions{}); err != nil || math.Abs(res.Value-tc.expected) > 1e-9 {
+			t.Errorf("Score(%v) expected=%v actual=%v err=%v\n", tc.data, tc.expected, res.Value, err)
+		}
+	}
+
+	// A condition of degree 0.5 weighs both branches equally.
+	if v, err := ct.EvaluateFuzzy(map[string]interface{}{"Premium": 0.5, "Degree": 0.1}, FuzzyMinMax); err != nil || math.Abs(v-0.5) > 1e-9 {
+		t.Errorf("EvaluateFuzzy() expected=0.5 actual=%v err=%v\n", v, err)
+	}
+}
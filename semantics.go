@@ -0,0 +1,228 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"reflect"
+	"sort"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Semantics is a machine-readable description of how trees are evaluated.
+// It is generated by `Describe` from the same options given to `Compile`,
+// and truth tables are produced by evaluating trees with the engine itself,
+// so that it can be used to verify other implementations and by auditors.
+type Semantics struct {
+	Operators []OperatorSemantics `json:"Operators"`
+	Leaves    LeafSemantics       `json:"Leaves"`
+	Errors    ErrorSemantics      `json:"Errors"`
+	Coercion  []CoercionRule      `json:"Coercion"`
+}
+
+// OperatorSemantics describes a single operator.
+type OperatorSemantics struct {
+	Op           Operator   `json:"Op"`
+	MinChildren  int        `json:"MinChildren"`
+	ShortCircuit bool       `json:"ShortCircuit"`
+	Order        string     `json:"Order"`
+	TruthTable   []TruthRow `json:"TruthTable"`
+
+	// KleeneTable is the truth table used by `EvaluateKleene`.
+	KleeneTable []KleeneRow `json:"KleeneTable"`
+
+	// Payload is whether the nodes of the operator are configured by a
+	// payload, see `PayloadOperator`, on which their results depend, so that
+	// the operator has no truth tables.
+	Payload bool `json:"Payload,omitempty"`
+}
+
+// TruthRow is the result of an operator applied to the child results in
+// `Inputs`.
+type TruthRow struct {
+	Inputs []bool `json:"Inputs"`
+	Result bool   `json:"Result"`
+}
+
+// KleeneRow is the three-valued result of an operator applied to the child
+// results in `Inputs`.
+type KleeneRow struct {
+	Inputs []Truth `json:"Inputs"`
+	Result Truth   `json:"Result"`
+}
+
+// LeafSemantics describes how the rendered output of a leaf is interpreted.
+type LeafSemantics struct {
+	True      string   `json:"True"`
+	False     string   `json:"False"`
+	TrimSpace bool     `json:"TrimSpace"`
+	Otherwise string   `json:"Otherwise"`
+	Values    string   `json:"Values"`
+	Unknown   string   `json:"Unknown"`
+	Missing   string   `json:"Missing"`
+	Functions []string `json:"Functions"`
+}
+
+// ErrorSemantics describes how errors propagate through a tree.
+type ErrorSemantics struct {
+	EmptyNode       string `json:"EmptyNode"`
+	InvalidOperator string `json:"InvalidOperator"`
+	NotExpression   string `json:"NotExpression"`
+	Propagation     string `json:"Propagation"`
+	Reported        string `json:"Reported"`
+}
+
+// CoercionRule describes how the arguments of a comparison function are
+// coerced.
+type CoercionRule struct {
+	Func string `json:"Func"`
+	Rule string `json:"Rule"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Semantic rules shared by the descriptions.
+const (
+	coercionNumeric = "numbers compare by value across int, uint and float; strings compare lexicographically; values of different non-numeric types are never equal"
+	coercionCustom  = "custom function supplied by the caller"
+)
+
+// Describe returns the `Semantics` of trees compiled with `opts`.
+func Describe(opts ...Option) *Semantics {
+	o := compileOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	s := &Semantics{
+		Leaves: LeafSemantics{
+			True:      "true",
+			False:     "false",
+			TrimSpace: true,
+			Otherwise: ErrNotBoolean.Error(),
+			Values:    "every leaf is true, false or an error",
+			Unknown:   "two-valued by Evaluate; with EvaluateKleene a leaf referencing a missing field or rendering \"unknown\" is unknown and operators follow Kleene's strong three-valued logic",
+			Missing:   describeMissing(o.missing),
+		},
+		Errors: ErrorSemantics{
+			EmptyNode:       "compile error: " + ErrEmptyNode.Error(),
+			InvalidOperator: "compile error: " + ErrInvalidOperator.Error(),
+			NotExpression:   "compile error: " + ErrNotExpression.Error() + ", unless the node is an advanced leaf",
+			Propagation:     "an error from any evaluated child aborts the whole evaluation",
+			Reported:        "the error of the first failing child in left to right order",
+		},
+	}
+	if o.parallelism > 1 {
+		s.Errors.Reported = "the error of the first failing child to finish, which is not deterministic"
+	}
+
+	for _, op := range append([]Operator{OperatorAnd, OperatorOr}, customOperators()...) {
+		s.Operators = append(s.Operators, describeOperator(op, &o))
+	}
+
+	for name := range o.funcs {
+		s.Leaves.Functions = append(s.Leaves.Functions, name)
+	}
+	sort.Strings(s.Leaves.Functions)
+
+	std := StdFuncs()
+	for _, name := range []string{"eq", "ne", "lt", "le", "gt", "ge"} {
+		rule := coercionNumeric
+		if f, ok := o.funcs[name]; ok && reflect.ValueOf(f).Pointer() != reflect.ValueOf(std[name]).Pointer() {
+			rule = coercionCustom
+		}
+		s.Coercion = append(s.Coercion, CoercionRule{Func: name, Rule: rule})
+	}
+	return s
+}
+
+// describeOperator builds the truth table of `op` by compiling and evaluating
+// a two child tree for every combination of child results.  The tables
+// describe the operator alone, so only the parallelism of `o` is kept: the
+// functions, hooks, backend and missing policy of the caller, which could make
+// the literal leaves of the tables fail, are left out.
+func describeOperator(op Operator, o *compileOptions) OperatorSemantics {
+	os := OperatorSemantics{
+		Op:           op,
+		MinChildren:  1,
+		ShortCircuit: true,
+		Order:        "left to right",
+	}
+	if isCustom(op) {
+		os.ShortCircuit = false
+	} else if o.parallelism > 1 {
+		os.Order = "concurrent"
+	}
+	if _, ok := payloadOperator(op); ok {
+		os.Payload = true
+		return os
+	}
+	table := withOptions(compileOptions{parallelism: o.parallelism})
+
+	for _, a := range []bool{false, true} {
+		for _, b := range []bool{false, true} {
+			// Literal leaves with the default options cannot fail.
+			ct, err := Compile(NewNode(op, boolLeaf(a), boolLeaf(b)), table)
+			if err != nil {
+				panic(err)
+			}
+			v, err := ct.Evaluate(nil)
+			if err != nil {
+				panic(err)
+			}
+			os.TruthTable = append(os.TruthTable, TruthRow{
+				Inputs: []bool{a, b},
+				Result: v,
+			})
+		}
+	}
+
+	for _, a := range []Truth{False, True, Unknown} {
+		for _, b := range []Truth{False, True, Unknown} {
+			ct, err := Compile(NewNode(op, truthLeaf(a), truthLeaf(b)), table)
+			if err != nil {
+				panic(err)
+			}
+			res, err := ct.EvaluateKleene(nil)
+			if err != nil {
+				panic(err)
+			}
+			os.KleeneTable = append(os.KleeneTable, KleeneRow{
+				Inputs: []Truth{a, b},
+				Result: res.Value,
+			})
+		}
+	}
+	return os
+}
+
+func truthLeaf(v Truth) *Node {
+	if v == Unknown {
+		return NewLeafNode(`print "unknown"`)
+	}
+	return boolLeaf(v == True)
+}
+
+func describeMissing(p MissingPolicy) string {
+	switch p {
+	case MissingIsFalse:
+		return "a leaf referencing a missing field is false"
+	case MissingIsError:
+		return "a leaf referencing a missing field is an error: " + ErrMissingField.Error()
+	}
+	return "text/template default: missing map keys are zero values, missing struct fields are errors"
+}
+
+func boolLeaf(v bool) *Node {
+	if v {
+		return NewLeafNode("true")
+	}
+	return NewLeafNode("false")
+}
+
+// withOptions replaces all compile options with `o`.
+func withOptions(o compileOptions) Option {
+	return func(co *compileOptions) {
+		*co = o
+	}
+}
@@ -0,0 +1,83 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestDescribe(t *testing.T) {
+	s := Describe()
+	for _, os := range s.Operators {
+		for _, row := range os.TruthTable {
+			expected := row.Inputs[0] && row.Inputs[1]
+			if os.Op == OperatorOr {
+				expected = row.Inputs[0] || row.Inputs[1]
+			}
+			if row.Result != expected {
+				t.Errorf("Describe() %s%v expected=%v actual=%v\n", os.Op, row.Inputs, expected, row.Result)
+			}
+		}
+	}
+	if len(s.Operators) != 2 || len(s.Operators[0].TruthTable) != 4 {
+		t.Errorf("Describe() expected two operators with four rows, got %#v\n", s.Operators)
+	}
+
+	fm := StdFuncs()
+	fm["lt"] = func(a, b int) bool { return a < b }
+	s = Describe(WithFuncs(fm), WithParallelism(4))
+	for _, tc := range []struct {
+		fn   string
+		rule string
+	}{
+		{"gt", coercionNumeric},
+		{"lt", coercionCustom},
+	} {
+		for _, c := range s.Coercion {
+			if c.Func == tc.fn && c.Rule != tc.rule {
+				t.Errorf("Describe() coercion for %s expected=%q actual=%q\n", tc.fn, tc.rule, c.Rule)
+			}
+		}
+	}
+	if s.Operators[0].Order != "concurrent" {
+		t.Errorf("Describe() expected concurrent order with parallelism\n")
+	}
+
+	if Describe(WithFuncs(template.FuncMap{})).Coercion[0].Rule != coercionNumeric {
+		t.Errorf("Describe() expected numeric coercion by default\n")
+	}
+
+	if _, err := json.Marshal(s); err != nil {
+		t.Errorf("json.Marshal(Semantics) error: %s\n", err.Error())
+	}
+}
+
+// rejectBackend compiles no leaves.
+type rejectBackend struct{}
+
+func (rejectBackend) CompileLeaf(string) (interface{}, error) { return nil, errors.New("rejected") }
+func (rejectBackend) Evaluate(interface{}, interface{}) (bool, error) {
+	return false, errors.New("rejected")
+}
+
+func TestDescribeOptions(t *testing.T) {
+	// The truth tables are the same whatever else the options would do.
+	failing := EvalHooks{OnNodeStart: func(string, *Node) { panic("hook") }}
+	for _, opts := range [][]Option{
+		{WithBackend(rejectBackend{})},
+		{WithHooks(failing)},
+		{WithMissing(MissingIsError), WithIncremental()},
+		{WithFuncs(template.FuncMap{"print": func(...interface{}) (string, error) { return "", errors.New("print") }})},
+	} {
+		s := Describe(opts...)
+		if !reflect.DeepEqual(s.Operators, Describe().Operators) {
+			t.Errorf("Describe() expected the default truth tables, got %+v\n", s.Operators)
+		}
+	}
+}
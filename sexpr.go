@@ -0,0 +1,313 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ParseSexpr parses a tree written as an S-expression, the format written by
+// `Sexpr`:
+//
+//	(or
+//	  (and (ge .Milk 4) (le .Milk 6))
+//	  (gt .Toothpaste 5))
+//
+// Lists headed by an operator, such as `and` or `or`, are nodes of the tree
+// and any other list is a leaf, whose expression is the list as written with
+// its whitespace collapsed.  Switches and their cases give their field and
+// values as a string after their operator, as in
+// (switch `.Country` (case `"US"` (gt .Total 10)) (case .Member)), and so do
+// the nodes of a `PayloadOperator` their payload, as in
+// (atLeast `{"k":2}` .A .B .C).  A bare word, such as `true` or `.InStock`,
+// is a leaf of its own.
+// `(leaf "...")` and `(advanced "...")` hold the expression of a leaf or the
+// template of an advanced leaf as a quoted or raw Go string, for those which
+// cannot be written as lists.  A `;` starts a comment running to the end of
+// the line.
+//
+// The tree is not validated, see `Validate`.
+func ParseSexpr(src string) (*Node, error) {
+	p := &sexprParser{src: src}
+	n, err := p.node()
+	if err != nil {
+		return nil, err
+	}
+	if p.space(); p.pos < len(p.src) {
+		return nil, p.errorf("unexpected data after the root node")
+	}
+	return n, nil
+}
+
+// Sexpr returns the tree as an S-expression, which `ParseSexpr` parses back
+// into the same tree.  Nodes are written one per line, indented by depth, so
+// that changes to a rule show up as changes to the lines of its leaves.
+func (n *Node) Sexpr() string {
+	var sb strings.Builder
+	n.sexpr(&sb, "")
+	return sb.String()
+}
+
+func (n *Node) sexpr(sb *strings.Builder, indent string) {
+	switch n.Op {
+	case OperatorLeaf:
+		if isSexprLeaf(n.Leaf) {
+			sb.WriteString(n.Leaf)
+		} else {
+			sb.WriteString("(leaf " + quoteSexpr(n.Leaf) + ")")
+		}
+		return
+	case OperatorAdvanced:
+		sb.WriteString("(advanced " + quoteSexpr(n.Leaf) + ")")
+		return
+	}
+
+	sb.WriteString("(" + string(n.Op))
+	if hasSexprString(n.Op) && n.Leaf != "" {
+		sb.WriteString(" " + quoteSexpr(n.Leaf))
+	}
+	for _, c := range n.Nodes {
+		sb.WriteString("\n" + indent + "  ")
+		c.sexpr(sb, indent+"  ")
+	}
+	sb.WriteString(")")
+}
+
+// isSexprLeaf reports whether the leaf expression `leaf` parses back from an
+// S-expression as written.
+func isSexprLeaf(leaf string) bool {
+	n, err := ParseSexpr(leaf)
+	return err == nil && n.Op == OperatorLeaf && n.Leaf == leaf
+}
+
+// quoteSexpr quotes `s` as a raw string where it can be, since templates are
+// full of double quotes, and as a quoted string otherwise.
+func quoteSexpr(s string) string {
+	if strconv.CanBackquote(s) {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// sexprParser reads a tree from `src`, from `pos` on.
+type sexprParser struct {
+	src string
+	pos int
+}
+
+func (p *sexprParser) errorf(format string, args ...interface{}) error {
+	line := 1 + strings.Count(p.src[:p.pos], "\n")
+	col := 1 + p.pos - (strings.LastIndexByte(p.src[:p.pos], '\n') + 1)
+	return fmt.Errorf("invalid s-expression at line %d, column %d: %s", line, col, fmt.Sprintf(format, args...))
+}
+
+// space skips whitespace and comments, reporting whether there were any.
+func (p *sexprParser) space() bool {
+	start := p.pos
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; {
+		case c == ';':
+			if i := strings.IndexByte(p.src[p.pos:], '\n'); i >= 0 {
+				p.pos += i
+			} else {
+				p.pos = len(p.src)
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			p.pos++
+		default:
+			return p.pos > start
+		}
+	}
+	return p.pos > start
+}
+
+// token reads a word or a quoted string, character constant or raw string,
+// as written.
+func (p *sexprParser) token() (string, error) {
+	start := p.pos
+	switch q := p.src[p.pos]; q {
+	case '"', '\'', '`':
+		for p.pos++; p.pos < len(p.src); p.pos++ {
+			switch c := p.src[p.pos]; {
+			case c == '\\' && q != '`':
+				p.pos++
+			case c == '\n' && q != '`':
+				return "", p.errorf("unterminated string")
+			case c == q:
+				p.pos++
+				return p.src[start:p.pos], nil
+			}
+		}
+		p.pos = start
+		return "", p.errorf("unterminated string")
+	}
+	for p.pos < len(p.src) && !strings.ContainsRune(" \t\r\n();\"'`", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	return p.src[start:p.pos], nil
+}
+
+func (p *sexprParser) node() (*Node, error) {
+	p.space()
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unexpected end of input")
+	}
+	start := p.pos
+
+	switch p.src[p.pos] {
+	case ')':
+		return nil, p.errorf("unexpected )")
+	case '(':
+	default:
+		word, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		if isSexprKeyword(word) {
+			p.pos = start
+			return nil, p.errorf("%s outside of a list", word)
+		}
+		return NewLeafNode(word), nil
+	}
+
+	p.pos++
+	p.space()
+	head := ""
+	if p.pos < len(p.src) && p.src[p.pos] != '(' && p.src[p.pos] != ')' {
+		var err error
+		if head, err = p.token(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch op := sexprOperator(head); {
+	case op == OperatorAnd || op == OperatorOr || op == OperatorIf || isSwitchPart(op) || op == OperatorRef || op == operatorNot || isCustom(op):
+		n := NewNode(op)
+		if p.space(); hasSexprString(op) && p.pos < len(p.src) && strings.ContainsRune("\"`", rune(p.src[p.pos])) {
+			// The field of a switch, the values of a case, the name of the
+			// tree referenced or the payload of a registered operator.
+			at := p.pos
+			q, err := p.token()
+			if err != nil {
+				return nil, err
+			}
+			if n.Leaf, err = strconv.Unquote(q); err != nil {
+				p.pos = at
+				return nil, p.errorf("invalid string: %v", err)
+			}
+		}
+		for {
+			p.space()
+			if p.pos < len(p.src) && p.src[p.pos] == ')' {
+				if err := normalizeNode(n); err != nil {
+					p.pos = start
+					return nil, p.errorf("%v", err)
+				}
+				p.pos++
+				return n, nil
+			}
+			c, err := p.node()
+			if err != nil {
+				return nil, err
+			}
+			n.Nodes = append(n.Nodes, c)
+		}
+	case op == OperatorLeaf || op == OperatorAdvanced:
+		p.space()
+		at := p.pos
+		if p.pos >= len(p.src) || !strings.ContainsRune("\"`", rune(p.src[p.pos])) {
+			return nil, p.errorf("expected a string")
+		}
+		q, err := p.token()
+		if err != nil {
+			return nil, err
+		}
+		s, err := strconv.Unquote(q)
+		if err != nil {
+			p.pos = at
+			return nil, p.errorf("invalid string: %v", err)
+		}
+		if p.space(); p.pos >= len(p.src) || p.src[p.pos] != ')' {
+			return nil, p.errorf("expected )")
+		}
+		p.pos++
+		return &Node{Op: op, Leaf: s}, nil
+	}
+
+	p.pos = start
+	leaf, err := p.list()
+	if err != nil {
+		return nil, err
+	}
+	return &Node{Op: OperatorLeaf, Leaf: leaf}, nil
+}
+
+// list reads the list of a leaf expression, collapsing its whitespace into
+// single spaces and dropping it inside parentheses.
+func (p *sexprParser) list() (string, error) {
+	var sb strings.Builder
+	depth := 0
+	for {
+		spaced := p.space()
+		if p.pos >= len(p.src) {
+			return "", p.errorf("unexpected end of input, expected )")
+		}
+		c := p.src[p.pos]
+		if spaced && c != ')' && !strings.HasSuffix(sb.String(), "(") {
+			sb.WriteByte(' ')
+		}
+
+		switch c {
+		case '(':
+			depth++
+			p.pos++
+			sb.WriteByte(c)
+		case ')':
+			depth--
+			p.pos++
+			sb.WriteByte(c)
+			if depth == 0 {
+				return sb.String(), nil
+			}
+		default:
+			t, err := p.token()
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(t)
+		}
+	}
+}
+
+// hasSexprString reports whether the lists of operator `op` give the leaf of
+// their node as a string after the operator.
+func hasSexprString(op Operator) bool {
+	_, payload := payloadOperator(op)
+	return isSwitchPart(op) || op == OperatorRef || payload
+}
+
+// isSexprKeyword reports whether `word` heads the lists of nodes, rather
+// than being a leaf.
+func isSexprKeyword(word string) bool {
+	switch op := sexprOperator(word); op {
+	case OperatorAnd, OperatorOr, OperatorIf, OperatorSwitch, OperatorCase, OperatorRef, OperatorLeaf, OperatorAdvanced, operatorNot:
+		return true
+	default:
+		return isCustom(op)
+	}
+}
+
+// sexprOperator returns the operator heading a list with `word`.  Only `!`
+// negates its child, since lists headed by `not` are leaves calling it.
+func sexprOperator(word string) Operator {
+	if op := normalizeOperator(word); op != operatorNot || word == string(operatorNot) {
+		return op
+	}
+	return Operator(word)
+}
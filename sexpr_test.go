@@ -0,0 +1,106 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"reflect"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestParseSexpr(t *testing.T) {
+	for _, tc := range []struct {
+		src      string
+		expected *Node
+	}{
+		{"(or (and (ge .Milk 4) (le .Milk 6)) (gt .Toothpaste 5))", NewNode(OperatorOr,
+			NewNode(OperatorAnd, NewLeafNode("ge .Milk 4"), NewLeafNode("le .Milk 6")),
+			NewLeafNode("gt .Toothpaste 5"))},
+		{"  true ; always\n", NewLeafNode("true")},
+		{"(and\n  .InStock ; listed\n  (  eq  .Name\t\"a  (b\"  )\n  (gt (len .Tags) 0))", NewNode(OperatorAnd,
+			NewLeafNode(".InStock"),
+			NewLeafNode(`eq .Name "a  (b"`),
+			NewLeafNode("gt (len .Tags) 0"))},
+		{"(or)", NewNode(OperatorOr)},
+		{"((.Milk).Fat)", &Node{Op: OperatorLeaf, Leaf: "((.Milk).Fat)"}},
+		{`(eq .C ')')`, NewLeafNode(`eq .C ')'`)},
+		{`(leaf "(and .A .B)")`, NewLeafNode("and .A .B")},
+		{"(advanced `{{ index .Tags 0 | eq \"new\" }}`)", NewAdvancedLeafNode(`{{ index .Tags 0 | eq "new" }}`)},
+		{`(advanced "{{ if .A }}\ntrue{{ else }}false{{ end }}")`, NewAdvancedLeafNode("{{ if .A }}\ntrue{{ else }}false{{ end }}")},
+	} {
+		actual, err := ParseSexpr(tc.src)
+		if err != nil {
+			t.Errorf("ParseSexpr(%q) error: %s\n", tc.src, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("ParseSexpr(%q) expected=%s actual=%s\n", tc.src, tc.expected.Sexpr(), actual.Sexpr())
+		}
+	}
+}
+
+func TestParseSexprErrors(t *testing.T) {
+	for _, tc := range []struct {
+		src, err string
+	}{
+		{"", "invalid s-expression at line 1, column 1: unexpected end of input"},
+		{"(and (eq .A 1)", "invalid s-expression at line 1, column 15: unexpected end of input"},
+		{"(and\n  (eq .A 1", "invalid s-expression at line 2, column 11: unexpected end of input, expected )"},
+		{"(or true) false", "invalid s-expression at line 1, column 11: unexpected data after the root node"},
+		{")", "invalid s-expression at line 1, column 1: unexpected )"},
+		{"(and or)", "invalid s-expression at line 1, column 6: or outside of a list"},
+		{`(eq .A "x)`, "invalid s-expression at line 1, column 8: unterminated string"},
+		{"(leaf .A)", "invalid s-expression at line 1, column 7: expected a string"},
+		{`(leaf "a" "b")`, "invalid s-expression at line 1, column 11: expected )"},
+		{`(advanced "\q")`, `invalid s-expression at line 1, column 11: invalid string: invalid syntax`},
+	} {
+		_, err := ParseSexpr(tc.src)
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("ParseSexpr(%q) expected error=%q actual=%v\n", tc.src, tc.err, err)
+		}
+	}
+}
+
+func TestSexpr(t *testing.T) {
+	expected := `(or
+  (and
+    (and
+      (ge .Milk 4)
+      (le .Milk 6))
+    (and
+      (ge .Onions 1)
+      (le .Onions 2)))
+  (gt .Toothpaste 5))`
+	if actual := pricesTree().Sexpr(); actual != expected {
+		t.Errorf("Sexpr() expected=%s actual=%s\n", expected, actual)
+	}
+
+	for _, n := range []*Node{
+		pricesTree(),
+		NewNode(OperatorAnd),
+		NewLeafNode("and .A .B"),
+		NewLeafNode(`eq .Name "a  b"`),
+		&Node{Op: OperatorLeaf, Leaf: "(eq  .A 1)"},
+		&Node{Op: OperatorLeaf, Leaf: "true"},
+		&Node{Op: OperatorLeaf, Leaf: "(eq .A 1) ; x"},
+		NewNode(OperatorOr, NewAdvancedLeafNode("{{ if .A }}\n`true`{{ end }}"), NewAdvancedLeafNode(`{{ eq .A "x" }}`)),
+	} {
+		src := n.Sexpr()
+		actual, err := ParseSexpr(src)
+		if err != nil {
+			t.Errorf("ParseSexpr(%q) error: %s\n", src, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(actual, n) {
+			t.Errorf("ParseSexpr(Sexpr()) expected=%#v actual=%#v\n", n, actual)
+		}
+	}
+
+	if actual, expected := NewLeafNode("and .A .B").Sexpr(), "(leaf `(and .A .B)`)"; actual != expected {
+		t.Errorf("Sexpr() expected=%s actual=%s\n", expected, actual)
+	}
+	if actual, expected := NewAdvancedLeafNode(`{{ eq .A "x" }}`).Sexpr(), "(advanced `{{ eq .A \"x\" }}`)"; actual != expected {
+		t.Errorf("Sexpr() expected=%s actual=%s\n", expected, actual)
+	}
+}
@@ -0,0 +1,113 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// WithSharedEvaluation evaluates subtrees which occur more than once in the
+// tree only once per evaluation, for generated trees which repeat the same
+// comparisons and clauses many times over.  Leaves are the same if they are
+// equal but for their whitespace and redundant parentheses, and `and` and
+// `or` nodes if they have the same children in any order, as for
+// `Canonicalize`.
+//
+// Sharing assumes leaves are free of side effects, as the repeated subtrees
+// are answered from the result of the first to be evaluated.  Hooks are not
+// called for shared results.  Errors are never shared, they abort the
+// evaluation as always.
+func WithSharedEvaluation() Option {
+	return func(o *compileOptions) {
+		o.shared = true
+	}
+}
+
+// sharedResults holds the results of the shared nodes of a single
+// evaluation, indexed by `compiledNode.share`.
+type sharedResults struct {
+	mu    sync.Mutex
+	valid []bool
+	v     []bool
+}
+
+func newSharedResults(n int) *sharedResults {
+	return &sharedResults{valid: make([]bool, n), v: make([]bool, n)}
+}
+
+// get returns the result of the shared node `i`, if it has been evaluated.
+func (sr *sharedResults) get(i int) (bool, bool) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	return sr.v[i], sr.valid[i]
+}
+
+func (sr *sharedResults) set(i int, v bool) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.valid[i], sr.v[i] = true, v
+}
+
+// markShared numbers the nodes under and including `cn` whose subtrees occur
+// more than once, setting `share` of every other node to -1, and returns the
+// number of distinct shared subtrees.
+func (cn *compiledNode) markShared() int {
+	keys := map[*compiledNode]string{}
+	count := map[string]int{}
+	cn.shareKey(keys, count)
+
+	// Subtrees are numbered in the order they first occur.
+	index := map[string]int{}
+	cn.walk(func(c *compiledNode) {
+		c.share = -1
+		k := keys[c]
+		if count[k] < 2 {
+			return
+		}
+		i, ok := index[k]
+		if !ok {
+			i = len(index)
+			index[k] = i
+		}
+		c.share = i
+	})
+	return len(index)
+}
+
+// shareKey returns the canonical form of the subtree of `cn`, recording that
+// of every node under and including it in `keys` and counting their
+// occurrences in `count`.
+func (cn *compiledNode) shareKey(keys map[*compiledNode]string, count map[string]int) string {
+	var k string
+	if cn.node.isLeaf() {
+		k = string(cn.node.Op) + strconv.Quote(leafVariable(cn.node))
+	} else {
+		children := make([]string, len(cn.children))
+		for i, c := range cn.children {
+			children[i] = c.shareKey(keys, count)
+		}
+		if !keepsOrder(cn.node.Op) {
+			sort.Strings(children)
+		}
+		k = string(cn.node.Op) + "(" + strings.Join(children, ",") + ")"
+		if isSwitchPart(cn.node.Op) {
+			k = strconv.Quote(cn.node.Leaf) + k
+		}
+	}
+	keys[cn] = k
+	count[k]++
+	return k
+}
+
+// walk calls `fn` with every node under and including `cn`, parents first.
+func (cn *compiledNode) walk(fn func(*compiledNode)) {
+	fn(cn)
+	for _, c := range cn.children {
+		c.walk(fn)
+	}
+}
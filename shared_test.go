@@ -0,0 +1,78 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestSharedEvaluation(t *testing.T) {
+	calls := map[string]int{}
+	fm := template.FuncMap{"count": func(name string, v interface{}) interface{} {
+		calls[name]++
+		return v
+	}}
+	milk := func() *Node {
+		return NewNode(OperatorAnd, NewLeafNode(`ge (count "milk" .Milk) 4`), NewLeafNode(`le  (count "milk6" .Milk) 6`))
+	}
+	// The `and` of milk is repeated with its children in another order, and
+	// the onions leaf is repeated with other spacing.
+	n := NewNode(OperatorOr,
+		NewNode(OperatorAnd, milk(), NewLeafNode(`eq (count "onions" .Onions) 1`)),
+		NewNode(OperatorAnd, NewNode(OperatorAnd, milk().Nodes[1], milk().Nodes[0]), NewLeafNode(`eq  (count "onions" .Onions)  1`), NewLeafNode("eq .Eggs 1")),
+		NewLeafNode(`eq (count "onions" .Onions) 2`))
+
+	for _, tc := range []struct {
+		opts     []Option
+		expected map[string]int
+	}{
+		{nil, map[string]int{"milk": 2, "milk6": 2, "onions": 3}},
+		{[]Option{WithSharedEvaluation()}, map[string]int{"milk": 1, "milk6": 1, "onions": 2}},
+		{[]Option{WithSharedEvaluation(), WithParallelism(1)}, map[string]int{"milk": 1, "milk6": 1, "onions": 2}},
+	} {
+		ct, err := Compile(n, append([]Option{WithFuncs(fm)}, tc.opts...)...)
+		if err != nil {
+			t.Fatalf("Compile() error: %s\n", err.Error())
+		}
+		for k := range calls {
+			delete(calls, k)
+		}
+		v, err := ct.Evaluate(map[string]interface{}{"Milk": 5, "Onions": 0, "Eggs": 1})
+		if err != nil || v {
+			t.Errorf("Evaluate() expected=false actual=%v err=%v\n", v, err)
+		}
+		for k, c := range tc.expected {
+			if calls[k] != c {
+				t.Errorf("Evaluate(%d options) expected %s calls=%d actual=%d\n", len(tc.opts), k, c, calls[k])
+			}
+		}
+	}
+}
+
+func TestSharedEvaluationParallel(t *testing.T) {
+	leaf := func() *Node { return NewLeafNode("gt .A 1") }
+	n := NewNode(OperatorAnd,
+		NewNode(OperatorOr, leaf(), NewLeafNode("eq .B 1")),
+		NewNode(OperatorOr, NewLeafNode("eq .B 1"), leaf()),
+		leaf())
+	ct, err := Compile(n, WithSharedEvaluation(), WithParallelism(4))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	for _, tc := range []struct {
+		data     map[string]interface{}
+		expected bool
+	}{
+		{map[string]interface{}{"A": 2, "B": 0}, true},
+		{map[string]interface{}{"A": 0, "B": 1}, false},
+	} {
+		for i := 0; i < 20; i++ {
+			if v, err := ct.Evaluate(tc.data); err != nil || v != tc.expected {
+				t.Fatalf("Evaluate(%v) expected=%v actual=%v err=%v\n", tc.data, tc.expected, v, err)
+			}
+		}
+	}
+}
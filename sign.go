@@ -0,0 +1,147 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// SignedDocument is a `Document` signed by `SignDocument`, for trees which are
+// authored centrally and distributed to the services evaluating them, which
+// must reject rule files tampered with on the way.  The document is held as
+// the bytes signed, encoded in base64 in JSON, so that it is verified exactly
+// as it was signed, whatever the tools the file goes through.
+type SignedDocument struct {
+	Algorithm string `json:"Algorithm"`
+	Document  []byte `json:"Document"`
+	Signature []byte `json:"Signature"`
+}
+
+// Signer signs documents, see `SignDocument`.
+type Signer interface {
+	// Algorithm is the name of the algorithm of the signatures, recorded in
+	// the signed documents.
+	Algorithm() string
+
+	// Sign returns the signature of `msg`.
+	Sign(msg []byte) ([]byte, error)
+}
+
+// Verifier verifies the signatures of documents, see `VerifyDocument`.
+type Verifier interface {
+	// Algorithm is the name of the algorithm of the signatures verified.
+	Algorithm() string
+
+	// Verify reports whether `sig` is the signature of `msg`.
+	Verify(msg, sig []byte) bool
+}
+
+// The algorithms of the signers and verifiers of this package.
+const (
+	AlgorithmEd25519    = "ed25519"
+	AlgorithmHMACSHA256 = "hmac-sha256"
+)
+
+// Ed25519Signer returns a signer of documents with the private key `key`, for
+// documents verified with the public key alone.
+func Ed25519Signer(key ed25519.PrivateKey) Signer {
+	return ed25519Signer(key)
+}
+
+// Ed25519Verifier returns a verifier of the documents signed with the private
+// key of the public key `key`.
+func Ed25519Verifier(key ed25519.PublicKey) Verifier {
+	return ed25519Verifier(key)
+}
+
+// HMACSigner returns a signer of documents with HMAC-SHA256 and the secret
+// `key`, which the verifiers must share.
+func HMACSigner(key []byte) Signer {
+	return hmacKey(key)
+}
+
+// HMACVerifier returns a verifier of the documents signed with HMAC-SHA256
+// and the secret `key`.
+func HMACVerifier(key []byte) Verifier {
+	return hmacKey(key)
+}
+
+type ed25519Signer ed25519.PrivateKey
+
+func (k ed25519Signer) Algorithm() string { return AlgorithmEd25519 }
+
+func (k ed25519Signer) Sign(msg []byte) ([]byte, error) {
+	if len(k) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid ed25519 private key of %d bytes", len(k))
+	}
+	return ed25519.Sign(ed25519.PrivateKey(k), msg), nil
+}
+
+type ed25519Verifier ed25519.PublicKey
+
+func (k ed25519Verifier) Algorithm() string { return AlgorithmEd25519 }
+
+func (k ed25519Verifier) Verify(msg, sig []byte) bool {
+	return len(k) == ed25519.PublicKeySize && ed25519.Verify(ed25519.PublicKey(k), msg, sig)
+}
+
+type hmacKey []byte
+
+func (k hmacKey) Algorithm() string { return AlgorithmHMACSHA256 }
+
+func (k hmacKey) Sign(msg []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, k)
+	mac.Write(msg)
+	return mac.Sum(nil), nil
+}
+
+func (k hmacKey) Verify(msg, sig []byte) bool {
+	expected, _ := k.Sign(msg)
+	return hmac.Equal(expected, sig)
+}
+
+// SignDocument encodes the tree rooted at `n` as a `Document`, as
+// `MarshalDocument` does, signed by `s`.
+func SignDocument(n *Node, s Signer) ([]byte, error) {
+	doc, err := MarshalDocument(n)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := s.Sign(doc)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(SignedDocument{Algorithm: s.Algorithm(), Document: doc, Signature: sig})
+}
+
+// VerifyDocument decodes a tree from a document signed by `SignDocument`,
+// having verified its signature with `v`.  Documents which were not signed,
+// signed with another algorithm or key, or tampered with since, are rejected
+// with an error wrapping `ErrInvalidSignature` before their tree is decoded.
+// No decoding limits apply, see `DecodeOptions`.
+func VerifyDocument(data []byte, v Verifier) (*Node, error) {
+	return DecodeOptions{}.VerifyDocument(data, v)
+}
+
+// VerifyDocument is `VerifyDocument` within the limits of `o`.
+func (o DecodeOptions) VerifyDocument(data []byte, v Verifier) (*Node, error) {
+	var sd SignedDocument
+	if err := json.Unmarshal(data, &sd); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	switch {
+	case len(sd.Signature) == 0:
+		return nil, fmt.Errorf("%w: document is not signed", ErrInvalidSignature)
+	case sd.Algorithm != v.Algorithm():
+		return nil, fmt.Errorf("%w: document is signed with %q, expected %q", ErrInvalidSignature, sd.Algorithm, v.Algorithm())
+	case !v.Verify(sd.Document, sd.Signature):
+		return nil, fmt.Errorf("%w: signature does not match the document", ErrInvalidSignature)
+	}
+	return o.UnmarshalDocument(sd.Document)
+}
@@ -0,0 +1,76 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestSignDocument(t *testing.T) {
+	tree := NewNode(OperatorAnd, NewLeafNode("ge .Age 18"), NewLeafNode("gt .Amount 100"))
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %s\n", err.Error())
+	}
+	otherPub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("GenerateKey() error: %s\n", err.Error())
+	}
+
+	for _, tc := range []struct {
+		name   string
+		signer Signer
+		good   Verifier
+		bad    []Verifier
+	}{
+		{AlgorithmEd25519, Ed25519Signer(priv), Ed25519Verifier(pub), []Verifier{Ed25519Verifier(otherPub), HMACVerifier([]byte("secret"))}},
+		{AlgorithmHMACSHA256, HMACSigner([]byte("secret")), HMACVerifier([]byte("secret")), []Verifier{HMACVerifier([]byte("other")), Ed25519Verifier(pub)}},
+	} {
+		data, err := SignDocument(tree, tc.signer)
+		if err != nil {
+			t.Fatalf("SignDocument(%s) error: %s\n", tc.name, err.Error())
+		}
+		n, err := VerifyDocument(data, tc.good)
+		if err != nil {
+			t.Fatalf("VerifyDocument(%s) error: %s\n", tc.name, err.Error())
+		}
+		if n.Fingerprint() != tree.Fingerprint() {
+			t.Errorf("VerifyDocument(%s) expected=%v actual=%v\n", tc.name, tree, n)
+		}
+		for _, v := range tc.bad {
+			if _, err := VerifyDocument(data, v); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("VerifyDocument(%s, %s) expected=%v actual=%v\n", tc.name, v.Algorithm(), ErrInvalidSignature, err)
+			}
+		}
+
+		// Documents tampered with are rejected.
+		var sd SignedDocument
+		if err := json.Unmarshal(data, &sd); err != nil {
+			t.Fatalf("Unmarshal(%s) error: %s\n", tc.name, err.Error())
+		}
+		sd.Document, _ = MarshalDocument(NewNode(OperatorOr, tree.Nodes...))
+		tampered, _ := json.Marshal(sd)
+		if _, err := VerifyDocument(tampered, tc.good); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("VerifyDocument(%s) expected=%v actual=%v\n", tc.name, ErrInvalidSignature, err)
+		}
+	}
+
+	// Documents which are not signed are rejected.
+	doc, _ := MarshalDocument(tree)
+	for _, data := range []string{string(doc), `{"Op": "leaf", "Leaf": "true"}`, `not json`} {
+		if _, err := VerifyDocument([]byte(data), HMACVerifier([]byte("secret"))); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("VerifyDocument(%s) expected=%v actual=%v\n", data, ErrInvalidSignature, err)
+		}
+	}
+
+	// The tree signed is decoded within the limits given.
+	data, _ := SignDocument(tree, HMACSigner([]byte("secret")))
+	if _, err := (DecodeOptions{MaxNodes: 2}).VerifyDocument(data, HMACVerifier([]byte("secret"))); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("VerifyDocument() expected=%v actual=%v\n", ErrLimitExceeded, err)
+	}
+}
@@ -0,0 +1,247 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// OverflowPolicy is what a bounded sink does with an event arriving while its
+// buffer is full, see `SinkOptions`.
+type OverflowPolicy int
+
+const (
+	// DropOldest drops the oldest event of the buffer to make room for the
+	// new one, so that the backend catches up with the latest events.
+	DropOldest OverflowPolicy = iota
+
+	// Block waits for room for the new event, for at most `BlockTimeout`,
+	// dropping it if there is none by then.  It is the only policy which
+	// delays the evaluations writing to the sink.
+	Block
+
+	// Sample keeps one in every `SampleEvery` new events while the buffer is
+	// at least half full, and drops every new event while it is full, so that
+	// a backend falling behind is written a thinned out stream of events
+	// rather than one with gaps.
+	Sample
+)
+
+// SinkOptions configures the buffer of a bounded sink, see
+// `NewBoundedAuditSink` and `NewBoundedMetrics`.
+type SinkOptions struct {
+	// Buffer is the number of events held for the backend, 1024 if zero.
+	Buffer int
+
+	// Overflow is what is done with events arriving while the buffer is
+	// full.
+	Overflow OverflowPolicy
+
+	// BlockTimeout is how long `Block` waits for room, zero waiting for as
+	// long as it takes.
+	BlockTimeout time.Duration
+
+	// SampleEvery is the one in how many events `Sample` keeps, 10 if zero.
+	SampleEvery int
+
+	// OnError, if set, is called with the errors of the backend, from the
+	// goroutine of the sink.
+	OnError func(error)
+}
+
+// sinkQueue hands events to a backend from a goroutine of its own, through a
+// buffer bounded by its options.
+type sinkQueue struct {
+	opts    SinkOptions
+	events  chan interface{}
+	deliver func(e interface{}) error
+	done    chan struct{} // closed once every event is delivered
+
+	mu     sync.RWMutex // held by writers for reading and by close for writing
+	closed bool
+
+	dropped atomic.Uint64
+	sampled atomic.Uint64 // the events `Sample` chose between
+}
+
+func newSinkQueue(o SinkOptions, deliver func(e interface{}) error) *sinkQueue {
+	if o.Buffer <= 0 {
+		o.Buffer = 1024
+	}
+	if o.SampleEvery <= 0 {
+		o.SampleEvery = 10
+	}
+	q := &sinkQueue{
+		opts:    o,
+		events:  make(chan interface{}, o.Buffer),
+		deliver: deliver,
+		done:    make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// run delivers events until the queue is closed and drained.
+func (q *sinkQueue) run() {
+	defer close(q.done)
+	for e := range q.events {
+		if err := q.deliver(e); err != nil && q.opts.OnError != nil {
+			q.opts.OnError(err)
+		}
+	}
+}
+
+// put buffers the event `e`, or drops it as the overflow policy says.
+// Events put after the queue is closed are dropped with `ErrSinkClosed`.
+func (q *sinkQueue) put(e interface{}) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		q.dropped.Add(1)
+		return ErrSinkClosed
+	}
+
+	if q.opts.Overflow == Sample && len(q.events) >= cap(q.events)/2 && q.sampled.Add(1)%uint64(q.opts.SampleEvery) != 0 {
+		q.dropped.Add(1)
+		return nil
+	}
+	select {
+	case q.events <- e:
+		return nil
+	default:
+	}
+
+	switch q.opts.Overflow {
+	case Block:
+		var timeout <-chan time.Time
+		if q.opts.BlockTimeout > 0 {
+			t := time.NewTimer(q.opts.BlockTimeout)
+			defer t.Stop()
+			timeout = t.C
+		}
+		select {
+		case q.events <- e:
+			return nil
+		case <-timeout:
+		}
+	case DropOldest:
+		for {
+			select {
+			case q.events <- e:
+				return nil
+			default:
+			}
+			select {
+			case <-q.events:
+				q.dropped.Add(1)
+			default:
+			}
+		}
+	}
+	q.dropped.Add(1)
+	return nil
+}
+
+// close stops the queue taking events, waiting for those buffered to be
+// delivered.
+func (q *sinkQueue) close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.events)
+	}
+	q.mu.Unlock()
+	<-q.done
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// BoundedAuditSink is an `AuditSink` writing records to another from a
+// goroutine of its own, through a buffer bounded by its `SinkOptions`, so
+// that a slow audit backend delays evaluations only as its overflow policy
+// allows.  It is safe for concurrent use, and writes records in the order
+// it is written them.
+type BoundedAuditSink struct {
+	q *sinkQueue
+}
+
+// NewBoundedAuditSink returns a bounded sink writing records to `s`, which
+// must be closed by `Close` once done with.
+func NewBoundedAuditSink(s AuditSink, o SinkOptions) *BoundedAuditSink {
+	return &BoundedAuditSink{q: newSinkQueue(o, func(e interface{}) error {
+		return s.WriteRecord(e.(*DecisionRecord))
+	})}
+}
+
+// WriteRecord buffers `r` to be written, or drops it as the overflow policy
+// says.  Records written after `Close` are dropped with `ErrSinkClosed`.
+func (b *BoundedAuditSink) WriteRecord(r *DecisionRecord) error {
+	return b.q.put(r)
+}
+
+// Dropped returns the number of records dropped, rather than written.
+func (b *BoundedAuditSink) Dropped() uint64 {
+	return b.q.dropped.Load()
+}
+
+// Close stops the sink, waiting for the records buffered to be written.
+func (b *BoundedAuditSink) Close() error {
+	b.q.close()
+	return nil
+}
+
+// BoundedMetrics is a `Metrics` recording observations with another from a
+// goroutine of its own, through a buffer bounded by its `SinkOptions`, as
+// `BoundedAuditSink` does records.
+type BoundedMetrics struct {
+	q *sinkQueue
+}
+
+// metricsEvent is an observation of a `BoundedMetrics`.
+type metricsEvent struct {
+	compile bool
+	v       bool
+	err     error
+	d       time.Duration
+}
+
+// NewBoundedMetrics returns bounded metrics recording observations with `m`,
+// which must be closed by `Close` once done with.
+func NewBoundedMetrics(m Metrics, o SinkOptions) *BoundedMetrics {
+	return &BoundedMetrics{q: newSinkQueue(o, func(e interface{}) error {
+		me := e.(metricsEvent)
+		if me.compile {
+			m.ObserveCompileError(me.err)
+		} else {
+			m.ObserveEvaluation(me.v, me.err, me.d)
+		}
+		return nil
+	})}
+}
+
+// ObserveEvaluation buffers the observation of an evaluation.
+func (b *BoundedMetrics) ObserveEvaluation(v bool, err error, d time.Duration) {
+	b.q.put(metricsEvent{v: v, err: err, d: d})
+}
+
+// ObserveCompileError buffers the observation of a compile error.
+func (b *BoundedMetrics) ObserveCompileError(err error) {
+	b.q.put(metricsEvent{compile: true, err: err})
+}
+
+// Dropped returns the number of observations dropped, rather than recorded,
+// including those made after `Close`.
+func (b *BoundedMetrics) Dropped() uint64 {
+	return b.q.dropped.Load()
+}
+
+// Close stops the metrics, waiting for the observations buffered to be
+// recorded.
+func (b *BoundedMetrics) Close() error {
+	b.q.close()
+	return nil
+}
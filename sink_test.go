@@ -0,0 +1,151 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// gatedSink is an `AuditSink` collecting records, which holds each of them
+// until its gate is closed.
+type gatedSink struct {
+	started chan struct{} // closed once the first record is being written
+	once    sync.Once
+	gate    chan struct{}
+
+	mu      sync.Mutex
+	records []*DecisionRecord
+}
+
+func newGatedSink() *gatedSink {
+	return &gatedSink{started: make(chan struct{}), gate: make(chan struct{})}
+}
+
+func (s *gatedSink) WriteRecord(r *DecisionRecord) error {
+	s.once.Do(func() { close(s.started) })
+	<-s.gate
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, r)
+	return nil
+}
+
+func (s *gatedSink) versions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	vs := []string{}
+	for _, r := range s.records {
+		vs = append(vs, r.Version)
+	}
+	return vs
+}
+
+func TestBoundedAuditSink(t *testing.T) {
+	for _, tc := range []struct {
+		opts     SinkOptions
+		expected []string
+	}{
+		{SinkOptions{Buffer: 2}, []string{"1", "8", "9"}},
+		{SinkOptions{Buffer: 2, Overflow: Block, BlockTimeout: time.Millisecond}, []string{"1", "2", "3"}},
+		{SinkOptions{Buffer: 4, Overflow: Sample, SampleEvery: 2}, []string{"1", "2", "3", "5", "7"}},
+	} {
+		backend := newGatedSink()
+		s := NewBoundedAuditSink(backend, tc.opts)
+		for i := 1; i <= 9; i++ {
+			if err := s.WriteRecord(&DecisionRecord{Version: strconv.Itoa(i)}); err != nil {
+				t.Fatalf("WriteRecord() error: %s\n", err.Error())
+			}
+			if i == 1 {
+				// The backend holds the first record while the others
+				// overflow the buffer.
+				<-backend.started
+			}
+		}
+		close(backend.gate)
+		s.Close()
+
+		if vs := backend.versions(); !reflect.DeepEqual(vs, tc.expected) {
+			t.Errorf("WriteRecord(%+v) expected=%v actual=%v\n", tc.opts, tc.expected, vs)
+		}
+		if d := s.Dropped(); d != uint64(9-len(tc.expected)) {
+			t.Errorf("Dropped(%+v) expected=%d actual=%d\n", tc.opts, 9-len(tc.expected), d)
+		}
+		if err := s.WriteRecord(&DecisionRecord{}); !errors.Is(err, ErrSinkClosed) {
+			t.Errorf("WriteRecord() expected=%v actual=%v\n", ErrSinkClosed, err)
+		}
+	}
+}
+
+// failingSink is an `AuditSink` which fails.
+type failingSink struct{}
+
+func (failingSink) WriteRecord(*DecisionRecord) error { return errors.New("backend down") }
+
+func TestAuditorSink(t *testing.T) {
+	ct, err := Compile(NewLeafNode("eq .A 1"))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	backend := newGatedSink()
+	close(backend.gate)
+	s := NewBoundedAuditSink(backend, SinkOptions{})
+	a := ct.NewAuditor(AuditOptions{Sink: s})
+	for i := 0; i < 3; i++ {
+		if _, err := a.Evaluate(context.Background(), map[string]interface{}{"A": i}); err != nil {
+			t.Fatalf("Evaluate() error: %s\n", err.Error())
+		}
+	}
+	s.Close()
+	if len(backend.records) != 3 || VerifyRecords(backend.records) != nil {
+		t.Errorf("Evaluate() expected a chain of 3 records written, got %d: %v\n", len(backend.records), VerifyRecords(backend.records))
+	}
+
+	// Sinks which fail fail the evaluation, which is still recorded.
+	var errs []error
+	failing := NewBoundedAuditSink(failingSink{}, SinkOptions{OnError: func(err error) { errs = append(errs, err) }})
+	a = ct.NewAuditor(AuditOptions{Sink: failing})
+	if r, err := a.Evaluate(context.Background(), map[string]interface{}{"A": 1}); err != nil || r == nil {
+		t.Errorf("Evaluate() expected the bounded sink not to fail, got %v\n", err)
+	}
+	failing.Close()
+	if len(errs) != 1 {
+		t.Errorf("OnError() expected=1 actual=%d\n", len(errs))
+	}
+	a = ct.NewAuditor(AuditOptions{Sink: failingSink{}})
+	if r, err := a.Evaluate(context.Background(), map[string]interface{}{"A": 1}); err == nil || !strings.Contains(err.Error(), "backend down") || r == nil || !r.Result {
+		t.Errorf("Evaluate() expected the record and the error of the sink, got %v %v\n", r, err)
+	}
+}
+
+func TestBoundedMetrics(t *testing.T) {
+	m := &counts{}
+	b := NewBoundedMetrics(m, SinkOptions{})
+	ct, err := Compile(NewLeafNode("gt .A 1"), WithMetrics(b), WithMissing(MissingIsError))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	for _, d := range []interface{}{
+		map[string]interface{}{"A": 2},
+		map[string]interface{}{"A": 0},
+		map[string]interface{}{},
+	} {
+		ct.Evaluate(d)
+	}
+	Compile(NewNode(OperatorAnd), WithMetrics(b))
+	b.Close()
+	if m.trues != 1 || m.falses != 1 || m.failed != 1 || m.compileErrors != 1 || b.Dropped() != 0 {
+		t.Errorf("Close() expected=1 1 1 1 0 actual=%d %d %d %d %d\n", m.trues, m.falses, m.failed, m.compileErrors, b.Dropped())
+	}
+	if b.ObserveEvaluation(true, nil, 0); b.Dropped() != 1 {
+		t.Errorf("ObserveEvaluation() expected observations after Close dropped, got %d\n", b.Dropped())
+	}
+}
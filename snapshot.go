@@ -0,0 +1,160 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"sort"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Snapshot is the state of a registry at a point in time, see
+// `Registry.Snapshot`.  Its JSON encoding is deterministic, so that the
+// snapshots of two environments holding the same rules are byte for byte
+// the same.
+type Snapshot struct {
+	// Version is the `FormatVersion` the trees are written in.
+	Version int `json:"Version"`
+
+	// Trees are the registered trees by name, and Fingerprints their
+	// fingerprints as compiled, see `Registry.Fingerprint`, which identify
+	// the version of every tree.
+	Trees        map[string]*Node  `json:"Trees"`
+	Fingerprints map[string]string `json:"Fingerprints"`
+
+	// Macros are the macros of the policy of the registry, and Funcs the
+	// names of its functions, sorted, which are code and cannot be restored.
+	Macros map[string]string `json:"Macros,omitempty"`
+	Funcs  []string          `json:"Funcs,omitempty"`
+
+	// Namespaces are the snapshots of the namespaces of the registry by
+	// name.
+	Namespaces map[string]*Snapshot `json:"Namespaces,omitempty"`
+}
+
+// Snapshot returns the state of the registry and of its namespaces: their
+// trees, with the fingerprints identifying their versions, and the macros
+// and functions of their policies, for backups, disaster recovery and
+// comparing environments.  The trees and policy of each registry are read at
+// once, so that they are consistent with one another, while trees registered
+// in other namespaces meanwhile may or may not be included.  The snapshot
+// holds copies of the trees, and is restored by `Restore`.
+func (r *Registry) Snapshot() *Snapshot {
+	state := r.clone()
+	s := &Snapshot{
+		Version:      FormatVersion,
+		Trees:        make(map[string]*Node, len(state.trees)),
+		Fingerprints: state.Fingerprints(),
+	}
+	for name, n := range state.trees {
+		s.Trees[name] = n.copy()
+	}
+	if p := state.policy; p != nil {
+		if len(p.Macros) > 0 {
+			s.Macros = p.macros()
+		}
+		for name := range p.Funcs {
+			s.Funcs = append(s.Funcs, name)
+		}
+		sort.Strings(s.Funcs)
+	}
+	for _, name := range r.Namespaces() {
+		if s.Namespaces == nil {
+			s.Namespaces = map[string]*Snapshot{}
+		}
+		s.Namespaces[name] = r.Namespace(name).Snapshot()
+	}
+	return s
+}
+
+// Restore replaces the trees of the registry and of its namespaces by those
+// of the snapshot `s`, and the macros of their policies by its macros,
+// keeping their functions and other options, which the program sets.
+// Namespaces which are not in the snapshot are deleted.  Snapshots of a
+// newer `FormatVersion` fail with an error wrapping `ErrUnsupportedVersion`,
+// and invalid trees or macros with one wrapping `ErrInvalidConfig`, leaving
+// the registry as it was.
+func (r *Registry) Restore(s *Snapshot) error {
+	states, err := r.restored(s, "")
+	if err != nil {
+		return err
+	}
+	r.apply(states)
+	return nil
+}
+
+// restoredState is the state of a registry restored from a snapshot.
+type restoredState struct {
+	trees      map[string]*Node
+	policy     *Policy
+	namespaces map[string]*restoredState
+}
+
+// restored returns the state of the registry restored from `s`, the
+// snapshot of its namespace `ns`, named in errors.
+func (r *Registry) restored(s *Snapshot, ns string) (*restoredState, error) {
+	if s == nil {
+		return nil, fmt.Errorf("%w: %smissing snapshot", ErrInvalidConfig, ns)
+	}
+	if s.Version < 0 || s.Version > FormatVersion {
+		return nil, fmt.Errorf("%w: %s%d, at most %d is supported", ErrUnsupportedVersion, ns, s.Version, FormatVersion)
+	}
+
+	st := &restoredState{trees: make(map[string]*Node, len(s.Trees))}
+	for name, n := range s.Trees {
+		if name == "" || n == nil {
+			return nil, fmt.Errorf("%w: %s%q: trees need a name and a root", ErrInvalidConfig, ns, name)
+		}
+		st.trees[name] = n.copy()
+	}
+
+	if old := r.getPolicy(); old != nil || len(s.Macros) > 0 {
+		p := Policy{}
+		if old != nil {
+			p = *old
+		}
+		p.Macros = s.Macros
+		check := &Registry{}
+		if err := check.SetPolicy(p); err != nil {
+			return nil, fmt.Errorf("%s%w", ns, err)
+		}
+		st.policy = check.policy
+	}
+
+	for name, sub := range s.Namespaces {
+		r.mu.RLock()
+		nsr := r.namespaces[name]
+		r.mu.RUnlock()
+		if nsr == nil {
+			nsr = &Registry{}
+		}
+		nst, err := nsr.restored(sub, ns+name+": ")
+		if err != nil {
+			return nil, err
+		}
+		if st.namespaces == nil {
+			st.namespaces = map[string]*restoredState{}
+		}
+		st.namespaces[name] = nst
+	}
+	return st, nil
+}
+
+// apply sets the state of the registry and of its namespaces to `st`.
+func (r *Registry) apply(st *restoredState) {
+	for _, name := range r.Namespaces() {
+		if _, ok := st.namespaces[name]; !ok {
+			r.DeleteNamespace(name)
+		}
+	}
+	for name, nst := range st.namespaces {
+		r.Namespace(name).apply(nst)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trees = st.trees
+	r.policy = st.policy
+	r.changed()
+}
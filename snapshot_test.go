@@ -0,0 +1,108 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// snapshotRegistry returns a registry of a few trees, with a namespace,
+// registered in the order of `names`.
+func snapshotRegistry(t *testing.T, names ...string) *Registry {
+	trees := map[string]*Node{
+		"adult": NewLeafNode("isAdult"),
+		"beer":  NewNode(OperatorAnd, NewRefNode("adult"), NewLeafNode(`eq .Country "US"`)),
+		"gold":  NewLeafNode("eq tier \"gold\""),
+	}
+	r := &Registry{}
+	for _, name := range names {
+		if err := r.Register(name, trees[name]); err != nil {
+			t.Fatalf("Register(%s) error: %s\n", name, err.Error())
+		}
+	}
+	if err := r.SetPolicy(Policy{
+		Funcs:  template.FuncMap{"tier": func() string { return "gold" }},
+		Macros: map[string]string{"isAdult": "ge .Age 18"},
+	}); err != nil {
+		t.Fatalf("SetPolicy() error: %s\n", err.Error())
+	}
+	if err := r.Namespace("acme").Register("rule", NewLeafNode("gt .Amount 5")); err != nil {
+		t.Fatalf("Register(acme/rule) error: %s\n", err.Error())
+	}
+	return r
+}
+
+func TestRegistrySnapshot(t *testing.T) {
+	a := snapshotRegistry(t, "adult", "beer", "gold")
+	b := snapshotRegistry(t, "gold", "beer", "adult")
+	sa, err := json.Marshal(a.Snapshot())
+	if err != nil {
+		t.Fatalf("Marshal() error: %s\n", err.Error())
+	}
+	sb, err := json.Marshal(b.Snapshot())
+	if err != nil {
+		t.Fatalf("Marshal() error: %s\n", err.Error())
+	}
+	if string(sa) != string(sb) {
+		t.Errorf("Snapshot() expected the same encoding of the same registries, got\n%s\n%s\n", sa, sb)
+	}
+
+	s := a.Snapshot()
+	fp, _ := a.Fingerprint("beer")
+	if s.Fingerprints["beer"] != fp || !reflect.DeepEqual(s.Funcs, []string{"tier"}) || s.Macros["isAdult"] != "ge .Age 18" || len(s.Namespaces["acme"].Trees) != 1 {
+		t.Errorf("Snapshot() expected the trees, fingerprints and policy of the registry, got %s\n", sa)
+	}
+	s.Trees["adult"].Leaf = "(false)"
+	if n, _ := a.Get("adult"); n.Leaf != "(isAdult)" {
+		t.Errorf("Snapshot() expected copies of the trees, got %s\n", n)
+	}
+
+	// A registry restored from the encoded snapshot is the same.
+	var decoded Snapshot
+	if err := json.Unmarshal(sa, &decoded); err != nil {
+		t.Fatalf("Unmarshal() error: %s\n", err.Error())
+	}
+	restored := &Registry{}
+	if err := restored.SetPolicy(Policy{Funcs: template.FuncMap{"tier": func() string { return "gold" }}}); err != nil {
+		t.Fatalf("SetPolicy() error: %s\n", err.Error())
+	}
+	restored.Namespace("stale")
+	if err := restored.Register("stale", NewLeafNode("true")); err != nil {
+		t.Fatalf("Register() error: %s\n", err.Error())
+	}
+	if err := restored.Restore(&decoded); err != nil {
+		t.Fatalf("Restore() error: %s\n", err.Error())
+	}
+	if again, _ := json.Marshal(restored.Snapshot()); string(again) != string(sa) {
+		t.Errorf("Restore() expected=%s actual=%s\n", sa, again)
+	}
+	if ct, err := restored.Compile("gold"); err != nil {
+		t.Errorf("Compile(gold) error: %v\n", err)
+	} else if v, err := ct.Evaluate(nil); err != nil || !v {
+		t.Errorf("Evaluate(gold) expected=true actual=%v err=%v\n", v, err)
+	}
+
+	// Snapshots which cannot be restored leave the registry as it was.
+	for _, tc := range []struct {
+		s        *Snapshot
+		expected error
+	}{
+		{&Snapshot{Version: FormatVersion + 1}, ErrUnsupportedVersion},
+		{&Snapshot{Version: FormatVersion, Macros: map[string]string{"a": "not a"}}, ErrInvalidConfig},
+		{&Snapshot{Version: FormatVersion, Trees: map[string]*Node{"x": nil}}, ErrInvalidConfig},
+		{&Snapshot{Version: FormatVersion, Namespaces: map[string]*Snapshot{"acme": {Version: FormatVersion + 1}}}, ErrUnsupportedVersion},
+	} {
+		if err := restored.Restore(tc.s); !errors.Is(err, tc.expected) {
+			t.Errorf("Restore(%+v) expected=%v actual=%v\n", tc.s, tc.expected, err)
+		}
+	}
+	if again, _ := json.Marshal(restored.Snapshot()); string(again) != string(sa) {
+		t.Errorf("Restore() expected the registry unchanged=%s actual=%s\n", sa, again)
+	}
+}
@@ -0,0 +1,233 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Dialect is the flavor of SQL generated by `ToSQL`.
+type Dialect string
+
+const (
+	DialectPostgres Dialect = "postgres" // $1 placeholders, "quoted" identifiers
+	DialectMySQL    Dialect = "mysql"    // ? placeholders, `quoted` identifiers
+	DialectSQLite   Dialect = "sqlite"   // ? placeholders, "quoted" identifiers
+)
+
+// ToSQL converts the tree into a parameterized SQL WHERE clause, without the
+// WHERE keyword, and the arguments for its placeholders.  Fields become
+// column names, with nested fields such as `.Orders.Total` qualified as
+// "Orders"."Total", and every literal becomes a placeholder.
+//
+// Leaves must be one of the structured forms: a comparison of a field against
+// literals as accepted by `EvaluateBatch`, `contains`, `hasPrefix` or
+// `hasSuffix` of a field and a string literal (translated to LIKE),
+// `matches` (translated to `~` for Postgres and REGEXP otherwise, whose
+// regular expression syntax may differ from Go's), `not (...)` of any of
+// those, or the literals `true` and `false`.  Any other leaf fails with
+// `ErrNotTranslatable`.  `if` nodes become CASE WHEN ... THEN ... ELSE ...
+// END, and switches a CASE with a WHEN for each of their cases.
+//
+// The functions are assumed to be those from `StdFuncs`.  Note that SQL
+// comparisons involving NULL are never true, and that LIKE is case
+// insensitive for MySQL and SQLite by default.
+func (n *Node) ToSQL(dialect Dialect) (string, []interface{}, error) {
+	switch dialect {
+	case DialectPostgres, DialectMySQL, DialectSQLite:
+	default:
+		return "", nil, fmt.Errorf("unknown SQL dialect %q", string(dialect))
+	}
+	if err := n.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	w := &sqlWriter{dialect: dialect, args: []interface{}{}}
+	s, err := w.node(n, "/")
+	if err != nil {
+		return "", nil, err
+	}
+	return s, w.args, nil
+}
+
+// sqlWriter accumulates the arguments of a clause as it is generated.
+type sqlWriter struct {
+	dialect Dialect
+	args    []interface{}
+}
+
+func (w *sqlWriter) node(n *Node, path string) (string, error) {
+	if n.Op == OperatorAdvanced {
+		return "", nodeError(path, n, fmt.Errorf("%w: advanced leaf", ErrNotTranslatable))
+	}
+	if isCustom(n.Op) {
+		return "", nodeError(path, n, fmt.Errorf("%w: operator %s", ErrNotTranslatable, n.Op))
+	}
+	if n.Op == OperatorLeaf {
+		t, err := parseLeaf(n.Leaf)
+		if err != nil {
+			return "", nodeError(path, n, err)
+		}
+		p, ok := leafPipe(t)
+		if !ok {
+			return "", nodeError(path, n, fmt.Errorf("%w: %s", ErrNotTranslatable, n.Leaf))
+		}
+		s, ok := w.pipe(p)
+		if !ok {
+			return "", nodeError(path, n, fmt.Errorf("%w: %s", ErrNotTranslatable, n.Leaf))
+		}
+		return s, nil
+	}
+	if n.Op == OperatorSwitch {
+		return w.switchNode(n, path)
+	}
+
+	op := " AND "
+	if n.Op == OperatorOr {
+		op = " OR "
+	}
+	parts := make([]string, len(n.Nodes))
+	for i, c := range n.Nodes {
+		s, err := w.node(c, childPath(path, i))
+		if err != nil {
+			return "", err
+		}
+		if !c.isLeaf() && len(c.Nodes) > 1 {
+			s = "(" + s + ")"
+		}
+		parts[i] = s
+	}
+	if n.Op == OperatorIf {
+		return "CASE WHEN " + parts[0] + " THEN " + parts[1] + " ELSE " + parts[2] + " END", nil
+	}
+	return strings.Join(parts, op), nil
+}
+
+// switchNode translates the switch `n` into a CASE testing the guard of each
+// of its cases in turn.
+func (w *sqlWriter) switchNode(n *Node, path string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("CASE")
+	def := -1
+	for i, c := range n.Nodes {
+		cp := childPath(path, i)
+		if c.Leaf == "" {
+			def = i
+			continue
+		}
+		guard, err := w.node(caseGuard(n, c), cp)
+		if err != nil {
+			return "", err
+		}
+		s, err := w.node(c.Nodes[0], childPath(cp, 0))
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(" WHEN " + guard + " THEN " + s)
+	}
+	// The default is translated last, for the arguments to be in order.
+	els := "1=0"
+	if def >= 0 {
+		var err error
+		if els, err = w.node(n.Nodes[def].Nodes[0], childPath(childPath(path, def), 0)); err != nil {
+			return "", err
+		}
+	}
+	sb.WriteString(" ELSE " + els + " END")
+	return sb.String(), nil
+}
+
+// pipe translates a leaf pipeline, returning false if it is not structured.
+func (w *sqlWriter) pipe(p *parse.PipeNode) (string, bool) {
+	if v, ok := pipeConstant(p); ok {
+		if v {
+			return "1=1", true
+		}
+		return "1=0", true
+	}
+	if inner, ok := pipeNot(p); ok {
+		s, ok := w.pipe(inner)
+		return "NOT (" + s + ")", ok
+	}
+	if m, ok := pipeStringMatch(p); ok {
+		return w.stringMatch(m), true
+	}
+	if c, ok := pipeComparison(p); ok {
+		return w.comparison(c), true
+	}
+	return "", false
+}
+
+func (w *sqlWriter) comparison(c *comparison) string {
+	col := w.ident(c.field)
+	switch c.op {
+	case "eq":
+		if len(c.values) == 1 {
+			return col + " = " + w.param(c.values[0])
+		}
+		ps := make([]string, len(c.values))
+		for i, v := range c.values {
+			ps[i] = w.param(v)
+		}
+		return col + " IN (" + strings.Join(ps, ", ") + ")"
+	case "between":
+		lo := w.param(c.values[0])
+		return col + " BETWEEN " + lo + " AND " + w.param(c.values[1])
+	}
+	return col + " " + sqlOperators[c.op] + " " + w.param(c.values[0])
+}
+
+var sqlOperators = map[string]string{
+	"ne": "<>", "lt": "<", "le": "<=", "gt": ">", "ge": ">=",
+}
+
+func (w *sqlWriter) stringMatch(m *stringMatch) string {
+	col := w.ident(m.field)
+	if m.fn == "matches" {
+		if w.dialect == DialectPostgres {
+			return col + " ~ " + w.param(m.text)
+		}
+		return col + " REGEXP " + w.param(m.text)
+	}
+
+	pattern := likeEscaper.Replace(m.text)
+	switch m.fn {
+	case "contains":
+		pattern = "%" + pattern + "%"
+	case "hasPrefix":
+		pattern = pattern + "%"
+	case "hasSuffix":
+		pattern = "%" + pattern
+	}
+	return col + " LIKE " + w.param(pattern) + " ESCAPE '!'"
+}
+
+// likeEscaper escapes the LIKE wildcards in a literal string.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// param adds `v` to the arguments and returns its placeholder.
+func (w *sqlWriter) param(v interface{}) string {
+	w.args = append(w.args, v)
+	if w.dialect == DialectPostgres {
+		return "$" + strconv.Itoa(len(w.args))
+	}
+	return "?"
+}
+
+// ident quotes the column, qualified by any enclosing fields, for `field`.
+func (w *sqlWriter) ident(field []string) string {
+	q := `"`
+	if w.dialect == DialectMySQL {
+		q = "`"
+	}
+	parts := make([]string, len(field))
+	for i, f := range field {
+		parts[i] = q + strings.ReplaceAll(f, q, q+q) + q
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,91 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestToSQL(t *testing.T) {
+	n := NewNode(OperatorOr,
+		NewNode(OperatorAnd,
+			NewLeafNode("between .Milk 4 6"),
+			NewLeafNode(`in .Store.City ["SF", "LA"]`)),
+		NewLeafNode("lt 5 .Toothpaste"),
+		NewLeafNode(`not (hasPrefix .Name "50%_off")`))
+
+	for _, tc := range []struct {
+		dialect  Dialect
+		expected string
+	}{
+		{DialectPostgres, `("Milk" BETWEEN $1 AND $2 AND "Store"."City" IN ($3, $4)) OR "Toothpaste" > $5 OR NOT ("Name" LIKE $6 ESCAPE '!')`},
+		{DialectMySQL, "(`Milk` BETWEEN ? AND ? AND `Store`.`City` IN (?, ?)) OR `Toothpaste` > ? OR NOT (`Name` LIKE ? ESCAPE '!')"},
+		{DialectSQLite, `("Milk" BETWEEN ? AND ? AND "Store"."City" IN (?, ?)) OR "Toothpaste" > ? OR NOT ("Name" LIKE ? ESCAPE '!')`},
+	} {
+		s, args, err := n.ToSQL(tc.dialect)
+		if err != nil {
+			t.Errorf("ToSQL(%s) error: %s\n", tc.dialect, err.Error())
+			continue
+		}
+		if s != tc.expected {
+			t.Errorf("ToSQL(%s) expected=%s\nactual=%s\n", tc.dialect, tc.expected, s)
+		}
+		expected := []interface{}{int64(4), int64(6), "SF", "LA", int64(5), "50!%!_off%"}
+		if !reflect.DeepEqual(args, expected) {
+			t.Errorf("ToSQL(%s) expected args=%v actual=%v\n", tc.dialect, expected, args)
+		}
+	}
+}
+
+func TestToSQLLeaves(t *testing.T) {
+	for _, tc := range []struct {
+		leaf     string
+		expected string
+		args     []interface{}
+	}{
+		{`eq .Name "milk"`, `"Name" = $1`, []interface{}{"milk"}},
+		{"ne .Fresh true", `"Fresh" <> $1`, []interface{}{true}},
+		{"ge .Price 1.5", `"Price" >= $1`, []interface{}{1.5}},
+		{`oneOf .Aisle "a" "b"`, `"Aisle" IN ($1, $2)`, []interface{}{"a", "b"}},
+		{`contains .Name "o"`, `"Name" LIKE $1 ESCAPE '!'`, []interface{}{"%o%"}},
+		{`hasSuffix .Name "s"`, `"Name" LIKE $1 ESCAPE '!'`, []interface{}{"%s"}},
+		{`matches .Name "^mi"`, `"Name" ~ $1`, []interface{}{"^mi"}},
+		{`eq .Sel"ect 1`, ``, nil},
+		{"true", "1=1", []interface{}{}},
+	} {
+		s, args, err := NewLeafNode(tc.leaf).ToSQL(DialectPostgres)
+		if tc.expected == "" {
+			if err == nil {
+				t.Errorf("ToSQL(%q) expected an error, actual=%s\n", tc.leaf, s)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ToSQL(%q) error: %s\n", tc.leaf, err.Error())
+			continue
+		}
+		if s != tc.expected || !reflect.DeepEqual(args, tc.args) {
+			t.Errorf("ToSQL(%q) expected=%s %v actual=%s %v\n", tc.leaf, tc.expected, tc.args, s, args)
+		}
+	}
+}
+
+func TestToSQLErrors(t *testing.T) {
+	for _, leaf := range []string{
+		"ge .Price .Cost",
+		`in "a" .Tags`,
+		"gt (len .Tags) 2",
+	} {
+		_, _, err := NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewLeafNode(leaf)).ToSQL(DialectMySQL)
+		if !errors.Is(err, ErrNotTranslatable) {
+			t.Errorf("ToSQL(%q) expected=%v actual=%v\n", leaf, ErrNotTranslatable, err)
+		}
+	}
+	if _, _, err := NewLeafNode("eq .A 1").ToSQL("oracle"); err == nil {
+		t.Errorf("ToSQL() expected an error for an unknown dialect\n")
+	}
+}
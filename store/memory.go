@@ -0,0 +1,93 @@
+package store
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Memory is a `Store` holding the versions of trees in memory.
+type Memory struct {
+	mu    sync.RWMutex
+	trees map[string][]memoryVersion
+}
+
+// memoryVersion is a version of a tree, held as its document so that the
+// trees returned are copies, as they are for other stores.
+type memoryVersion struct {
+	Version
+	doc []byte
+}
+
+// NewMemory returns an empty store holding its trees in memory.
+func NewMemory() *Memory {
+	return &Memory{trees: map[string][]memoryVersion{}}
+}
+
+// Get implements `Store`.
+func (m *Memory) Get(ctx context.Context, name string, version int) (*logictree.Node, Version, error) {
+	m.mu.RLock()
+	vs := m.trees[name]
+	m.mu.RUnlock()
+	if version == Latest {
+		version = len(vs)
+	}
+	if version < 1 || version > len(vs) {
+		return nil, Version{}, notFound(name, version)
+	}
+	v := vs[version-1]
+	n, err := logictree.UnmarshalDocument(v.doc)
+	if err != nil {
+		return nil, Version{}, err
+	}
+	return n, v.Version, nil
+}
+
+// Put implements `Store`.
+func (m *Memory) Put(ctx context.Context, name string, n *logictree.Node, meta Meta) (Version, error) {
+	doc, err := logictree.MarshalDocument(n)
+	if err != nil {
+		return Version{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v := Version{Name: name, Number: len(m.trees[name]) + 1, Created: now(), Meta: meta, Fingerprint: n.Fingerprint()}
+	m.trees[name] = append(m.trees[name], memoryVersion{Version: v, doc: doc})
+	return v, nil
+}
+
+// Head implements `Store`.
+func (m *Memory) Head(ctx context.Context, name string) (Version, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	vs := m.trees[name]
+	if len(vs) == 0 {
+		return Version{}, notFound(name, Latest)
+	}
+	return vs[len(vs)-1].Version, nil
+}
+
+// ListVersions implements `Store`.
+func (m *Memory) ListVersions(ctx context.Context, name string) ([]Version, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	vs := m.trees[name]
+	if len(vs) == 0 {
+		return nil, notFound(name, Latest)
+	}
+	out := make([]Version, len(vs))
+	for i, v := range vs {
+		out[i] = v.Version
+	}
+	return out, nil
+}
+
+// Rollback implements `Store`.
+func (m *Memory) Rollback(ctx context.Context, name string, version int, meta Meta) (Version, error) {
+	return rollback(ctx, m, name, version, meta)
+}
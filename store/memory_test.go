@@ -0,0 +1,13 @@
+package store
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestMemory(t *testing.T) {
+	testStore(t, NewMemory())
+}
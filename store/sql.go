@@ -0,0 +1,179 @@
+package store
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// SQL is a `Store` holding the versions of trees in a table of a SQL
+// database, one row per version, with the columns:
+//
+//	name         VARCHAR(255)  the name of the tree
+//	version      INTEGER       the number of the version, from 1
+//	author       VARCHAR(255)
+//	message      TEXT
+//	created      BIGINT        the time the version was made, in Unix microseconds
+//	fingerprint  VARCHAR(64)   the fingerprint of the tree
+//	tree         TEXT          the tree, as a `logictree.Document`
+//
+// and the primary key (name, version), as created by `CreateTable`.  A `Put`
+// of a tree while another of the same tree is in progress may fail with the
+// violation of the key, and be retried.
+type SQL struct {
+	db      *sql.DB
+	dialect logictree.Dialect
+	table   string // quoted
+}
+
+// NewSQL returns a store holding its trees in the table `table` of `db`,
+// whose flavor of SQL is `dialect`.
+func NewSQL(db *sql.DB, dialect logictree.Dialect, table string) *SQL {
+	q := `"`
+	if dialect == logictree.DialectMySQL {
+		q = "`"
+	}
+	return &SQL{db: db, dialect: dialect, table: q + strings.ReplaceAll(table, q, q+q) + q}
+}
+
+// CreateTable creates the table of the store, unless it exists.
+func (s *SQL) CreateTable(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+s.table+` (
+	name VARCHAR(255) NOT NULL,
+	version INTEGER NOT NULL,
+	author VARCHAR(255) NOT NULL,
+	message TEXT NOT NULL,
+	created BIGINT NOT NULL,
+	fingerprint VARCHAR(64) NOT NULL,
+	tree TEXT NOT NULL,
+	PRIMARY KEY (name, version)
+)`)
+	return err
+}
+
+// query returns `q` with its `?` placeholders in the style of the dialect.
+func (s *SQL) query(q string) string {
+	if s.dialect != logictree.DialectPostgres {
+		return q
+	}
+	var sb strings.Builder
+	n := 0
+	for _, r := range q {
+		if r == '?' {
+			n++
+			sb.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// Get implements `Store`.
+func (s *SQL) Get(ctx context.Context, name string, version int) (*logictree.Node, Version, error) {
+	var row *sql.Row
+	if version == Latest {
+		row = s.db.QueryRowContext(ctx, s.query(`SELECT version, author, message, created, fingerprint, tree FROM `+s.table+` WHERE name = ? ORDER BY version DESC LIMIT 1`), name)
+	} else {
+		row = s.db.QueryRowContext(ctx, s.query(`SELECT version, author, message, created, fingerprint, tree FROM `+s.table+` WHERE name = ? AND version = ?`), name, version)
+	}
+	v := Version{Name: name}
+	var created int64
+	var doc string
+	if err := row.Scan(&v.Number, &v.Author, &v.Message, &created, &v.Fingerprint, &doc); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, Version{}, notFound(name, version)
+		}
+		return nil, Version{}, err
+	}
+	v.Created = time.UnixMicro(created).UTC()
+	n, err := logictree.UnmarshalDocument([]byte(doc))
+	if err != nil {
+		return nil, Version{}, fmt.Errorf("%q version %d: %w", name, v.Number, err)
+	}
+	return n, v, nil
+}
+
+// Put implements `Store`.
+func (s *SQL) Put(ctx context.Context, name string, n *logictree.Node, meta Meta) (Version, error) {
+	doc, err := logictree.MarshalDocument(n)
+	if err != nil {
+		return Version{}, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Version{}, err
+	}
+	defer tx.Rollback()
+
+	v := Version{Name: name, Created: now(), Meta: meta, Fingerprint: n.Fingerprint()}
+	if err := tx.QueryRowContext(ctx, s.query(`SELECT COALESCE(MAX(version), 0) FROM `+s.table+` WHERE name = ?`), name).Scan(&v.Number); err != nil {
+		return Version{}, err
+	}
+	v.Number++
+	if _, err := tx.ExecContext(ctx, s.query(`INSERT INTO `+s.table+` (name, version, author, message, created, fingerprint, tree) VALUES (?, ?, ?, ?, ?, ?, ?)`),
+		name, v.Number, meta.Author, meta.Message, v.Created.UnixMicro(), v.Fingerprint, string(doc)); err != nil {
+		return Version{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Version{}, err
+	}
+	return v, nil
+}
+
+// Head implements `Store`.
+func (s *SQL) Head(ctx context.Context, name string) (Version, error) {
+	v := Version{Name: name}
+	var created int64
+	err := s.db.QueryRowContext(ctx, s.query(`SELECT version, author, message, created, fingerprint FROM `+s.table+` WHERE name = ? ORDER BY version DESC LIMIT 1`), name).
+		Scan(&v.Number, &v.Author, &v.Message, &created, &v.Fingerprint)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Version{}, notFound(name, Latest)
+		}
+		return Version{}, err
+	}
+	v.Created = time.UnixMicro(created).UTC()
+	return v, nil
+}
+
+// ListVersions implements `Store`.
+func (s *SQL) ListVersions(ctx context.Context, name string) ([]Version, error) {
+	rows, err := s.db.QueryContext(ctx, s.query(`SELECT version, author, message, created, fingerprint FROM `+s.table+` WHERE name = ? ORDER BY version`), name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var vs []Version
+	for rows.Next() {
+		v := Version{Name: name}
+		var created int64
+		if err := rows.Scan(&v.Number, &v.Author, &v.Message, &created, &v.Fingerprint); err != nil {
+			return nil, err
+		}
+		v.Created = time.UnixMicro(created).UTC()
+		vs = append(vs, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(vs) == 0 {
+		return nil, notFound(name, Latest)
+	}
+	return vs, nil
+}
+
+// Rollback implements `Store`.
+func (s *SQL) Rollback(ctx context.Context, name string, version int, meta Meta) (Version, error) {
+	return rollback(ctx, s, name, version, meta)
+}
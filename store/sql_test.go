@@ -0,0 +1,50 @@
+package store
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/sabhiram/logictree"
+
+	_ "modernc.org/sqlite"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestSQL(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("Open() error: %s\n", err.Error())
+	}
+	defer db.Close()
+	// Every connection to :memory: has a database of its own.
+	db.SetMaxOpenConns(1)
+
+	s := NewSQL(db, logictree.DialectSQLite, "rule versions")
+	for i := 0; i < 2; i++ {
+		if err := s.CreateTable(context.Background()); err != nil {
+			t.Fatalf("CreateTable() error: %s\n", err.Error())
+		}
+	}
+	testStore(t, s)
+}
+
+func TestSQLQuery(t *testing.T) {
+	for _, tc := range []struct {
+		dialect  logictree.Dialect
+		table    string
+		expected string
+	}{
+		{logictree.DialectPostgres, `rules`, `SELECT * FROM "rules" WHERE name = $1 AND version = $2`},
+		{logictree.DialectSQLite, `my "rules"`, `SELECT * FROM "my ""rules""" WHERE name = ? AND version = ?`},
+		{logictree.DialectMySQL, "rules", "SELECT * FROM `rules` WHERE name = ? AND version = ?"},
+	} {
+		s := NewSQL(nil, tc.dialect, tc.table)
+		if actual := s.query(`SELECT * FROM ` + s.table + ` WHERE name = ? AND version = ?`); actual != tc.expected {
+			t.Errorf("query(%s) expected=%s actual=%s\n", tc.dialect, tc.expected, actual)
+		}
+	}
+}
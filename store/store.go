@@ -0,0 +1,103 @@
+// Package store persists logictree trees by name, keeping every version of
+// them, so that rules are edited, audited and rolled back without every
+// service writing its own persistence layer.
+//
+// Every `Put` of a tree adds a version of it, numbered from 1 and always
+// increasing, recording its author, a message and the time it was made.
+// Versions are never changed or removed: `Rollback` adds a version holding
+// the tree of an older one.  `NewMemory` keeps the versions in memory, for
+// tests and single processes, and `NewSQL` in a table of a SQL database.
+package store
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// ErrNotFound is the error of a tree, or a version of it, which is not in the
+// store.
+var ErrNotFound = errors.New("tree not found")
+
+// Latest is the version of `Store.Get` returning the latest version of a
+// tree.
+const Latest = 0
+
+// Meta describes a change of a tree.
+type Meta struct {
+	Author  string `json:"Author"`
+	Message string `json:"Message,omitempty"`
+}
+
+// Version is a version of a tree.
+type Version struct {
+	Name    string    `json:"Name"`
+	Number  int       `json:"Number"`
+	Created time.Time `json:"Created"`
+	Meta
+
+	// Fingerprint is that of the tree, see `logictree.Node.Fingerprint`.
+	Fingerprint string `json:"Fingerprint"`
+}
+
+// Store holds the versions of trees by name.  Implementations are safe for
+// concurrent use.
+type Store interface {
+	// Get returns the version `version` of the tree `name`, or its latest
+	// version for `Latest`, failing with an error wrapping `ErrNotFound` if
+	// there is none.
+	Get(ctx context.Context, name string, version int) (*logictree.Node, Version, error)
+
+	// Put adds the tree `n` as the next version of the tree `name`, the
+	// first if there is none, and returns that version.
+	Put(ctx context.Context, name string, n *logictree.Node, meta Meta) (Version, error)
+
+	// Head returns the latest version of the tree `name` without the tree,
+	// so that consumers poll its fingerprint cheaply and only fetch and
+	// compile trees which changed, failing with an error wrapping
+	// `ErrNotFound` if there is none.
+	Head(ctx context.Context, name string) (Version, error)
+
+	// ListVersions returns the versions of the tree `name`, oldest first,
+	// failing with an error wrapping `ErrNotFound` if there are none.
+	ListVersions(ctx context.Context, name string) ([]Version, error)
+
+	// Rollback adds the tree of the version `version` of the tree `name` as
+	// its next version, and returns that version.  Its message is "rollback
+	// to version <version>" unless `meta` has one.
+	Rollback(ctx context.Context, name string, version int, meta Meta) (Version, error)
+}
+
+// now returns the time a version is made, in UTC to the microsecond, as
+// stored by `SQL`.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// rollback is `Store.Rollback` for `s`.
+func rollback(ctx context.Context, s Store, name string, version int, meta Meta) (Version, error) {
+	n, _, err := s.Get(ctx, name, version)
+	if err != nil {
+		return Version{}, err
+	}
+	if meta.Message == "" {
+		meta.Message = fmt.Sprintf("rollback to version %d", version)
+	}
+	return s.Put(ctx, name, n, meta)
+}
+
+// notFound returns the error of the version `version` of the tree `name`,
+// which is not in the store.
+func notFound(name string, version int) error {
+	if version == Latest {
+		return fmt.Errorf("%w: %q", ErrNotFound, name)
+	}
+	return fmt.Errorf("%w: %q version %d", ErrNotFound, name, version)
+}
@@ -0,0 +1,113 @@
+package store
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// testStore tests the behavior every `Store` shares, against the empty
+// store `s`.
+func testStore(t *testing.T, s Store) {
+	ctx := context.Background()
+	trees := []*logictree.Node{
+		logictree.NewLeafNode("ge .Age 18"),
+		logictree.NewNode(logictree.OperatorAnd, logictree.NewLeafNode("ge .Age 18"), logictree.NewLeafNode("gt .Amount 100")),
+		logictree.NewLeafNode("ge .Age 21"),
+	}
+	for i, n := range trees {
+		v, err := s.Put(ctx, "adult", n, Meta{Author: "ana", Message: "edit"})
+		if err != nil {
+			t.Fatalf("Put(%d) error: %s\n", i, err.Error())
+		}
+		if v.Name != "adult" || v.Number != i+1 || v.Author != "ana" || v.Created.IsZero() || v.Fingerprint != n.Fingerprint() {
+			t.Errorf("Put(%d) expected version %d, got %+v\n", i, i+1, v)
+		}
+	}
+	if _, err := s.Put(ctx, "other", trees[0], Meta{Author: "bo"}); err != nil {
+		t.Fatalf("Put(other) error: %s\n", err.Error())
+	}
+
+	for _, tc := range []struct {
+		version  int
+		expected *logictree.Node
+		number   int
+	}{
+		{Latest, trees[2], 3},
+		{1, trees[0], 1},
+		{2, trees[1], 2},
+	} {
+		n, v, err := s.Get(ctx, "adult", tc.version)
+		if err != nil {
+			t.Fatalf("Get(%d) error: %s\n", tc.version, err.Error())
+		}
+		if !reflect.DeepEqual(n, tc.expected) || v.Number != tc.number {
+			t.Errorf("Get(%d) expected=%v version %d actual=%v version %d\n", tc.version, tc.expected, tc.number, n, v.Number)
+		}
+	}
+
+	// Trees returned are copies of those stored.
+	n, _, _ := s.Get(ctx, "adult", 1)
+	n.Leaf = "(false)"
+	if n, _, _ = s.Get(ctx, "adult", 1); n.Leaf != "(ge .Age 18)" {
+		t.Errorf("Get(1) expected a copy of the tree, got %v\n", n)
+	}
+
+	v, err := s.Rollback(ctx, "adult", 1, Meta{Author: "bo"})
+	if err != nil {
+		t.Fatalf("Rollback() error: %s\n", err.Error())
+	}
+	if v.Number != 4 || v.Message != "rollback to version 1" {
+		t.Errorf("Rollback() expected version 4, got %+v\n", v)
+	}
+	if n, _, _ := s.Get(ctx, "adult", Latest); !reflect.DeepEqual(n, trees[0]) {
+		t.Errorf("Get() expected=%v actual=%v\n", trees[0], n)
+	}
+
+	head, err := s.Head(ctx, "adult")
+	if err != nil {
+		t.Fatalf("Head() error: %s\n", err.Error())
+	}
+	if head != v {
+		t.Errorf("Head() expected=%+v actual=%+v\n", v, head)
+	}
+
+	vs, err := s.ListVersions(ctx, "adult")
+	if err != nil {
+		t.Fatalf("ListVersions() error: %s\n", err.Error())
+	}
+	numbers, authors := []int{}, []string{}
+	for i, v := range vs {
+		numbers = append(numbers, v.Number)
+		authors = append(authors, v.Author)
+		if expected := append(trees, trees[0])[i].Fingerprint(); v.Fingerprint != expected {
+			t.Errorf("ListVersions() expected=%s actual=%s\n", expected, v.Fingerprint)
+		}
+	}
+	if !reflect.DeepEqual(numbers, []int{1, 2, 3, 4}) || !reflect.DeepEqual(authors, []string{"ana", "ana", "ana", "bo"}) {
+		t.Errorf("ListVersions() expected=[1 2 3 4] by [ana ana ana bo] actual=%v by %v\n", numbers, authors)
+	}
+
+	if _, _, err := s.Get(ctx, "missing", Latest); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(missing) expected=%v actual=%v\n", ErrNotFound, err)
+	}
+	if _, _, err := s.Get(ctx, "adult", 9); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(9) expected=%v actual=%v\n", ErrNotFound, err)
+	}
+	if _, err := s.Head(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Head(missing) expected=%v actual=%v\n", ErrNotFound, err)
+	}
+	if _, err := s.ListVersions(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ListVersions(missing) expected=%v actual=%v\n", ErrNotFound, err)
+	}
+	if _, err := s.Rollback(ctx, "adult", 9, Meta{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Rollback(9) expected=%v actual=%v\n", ErrNotFound, err)
+	}
+}
@@ -0,0 +1,89 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// StreamResult is the per-record output of `StreamResults`.
+type StreamResult struct {
+	Line   int             `json:"Line"`
+	Record json.RawMessage `json:"Record"`
+	Result bool            `json:"Result"`
+	Error  string          `json:"Error,omitempty"`
+}
+
+// Stream reads newline delimited JSON records from `r`, evaluates the tree
+// against each one and copies the records which evaluate to true to `w`.  Blank
+// lines are skipped.  The first record which cannot be decoded or evaluated
+// stops the stream and its line number is reported in the error.
+func (ct *CompiledTree) Stream(r io.Reader, w io.Writer) error {
+	return ct.stream(r, func(line int, rec []byte, ok bool, err error) error {
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if !ok {
+			return nil
+		}
+		if _, err := w.Write(rec); err != nil {
+			return err
+		}
+		_, err = w.Write([]byte{'\n'})
+		return err
+	})
+}
+
+// StreamResults is like `Stream` but writes a `StreamResult` line to `w` for
+// every record read.  Records that fail to decode or evaluate are reported in
+// the `Error` field rather than stopping the stream.
+func (ct *CompiledTree) StreamResults(r io.Reader, w io.Writer) error {
+	enc := json.NewEncoder(w)
+	return ct.stream(r, func(line int, rec []byte, ok bool, err error) error {
+		res := StreamResult{
+			Line:   line,
+			Result: ok,
+		}
+		if json.Valid(rec) {
+			res.Record = rec
+		} else {
+			res.Record, _ = json.Marshal(string(rec))
+		}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		return enc.Encode(&res)
+	})
+}
+
+// stream invokes `emit` with the result of evaluating each record in `r`.
+func (ct *CompiledTree) stream(r io.Reader, emit func(int, []byte, bool, error) error) error {
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		bs, rerr := br.ReadBytes('\n')
+		if rerr != nil && rerr != io.EOF {
+			return rerr
+		}
+
+		if rec := bytes.TrimSpace(bs); len(rec) > 0 {
+			var data interface{}
+			ok, err := false, json.Unmarshal(rec, &data)
+			if err == nil {
+				ok, err = ct.Evaluate(data)
+			}
+			if err := emit(line, rec, ok, err); err != nil {
+				return err
+			}
+		}
+
+		if rerr == io.EOF {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestStream(t *testing.T) {
+	ct, err := Compile(NewNode(OperatorAnd,
+		NewLeafNode(`eq .Level "error"`),
+		NewLeafNode("gt .Code 499.0")))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	in := strings.Join([]string{
+		`{"Level": "error", "Code": 500}`,
+		`{"Level": "info", "Code": 500}`,
+		``,
+		`{"Level": "error", "Code": 404}`,
+		`{"Level": "error", "Code": 503}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := ct.Stream(strings.NewReader(in), &out); err != nil {
+		t.Fatalf("Stream() error: %s\n", err.Error())
+	}
+
+	expected := `{"Level": "error", "Code": 500}` + "\n" + `{"Level": "error", "Code": 503}` + "\n"
+	if out.String() != expected {
+		t.Errorf("Stream() expected=%q actual=%q\n", expected, out.String())
+	}
+
+	if err := ct.Stream(strings.NewReader("{\"Level\": \"error\", \"Code\": 1}\nnot json\n"), &out); err == nil {
+		t.Errorf("Stream() expected an error for an invalid record\n")
+	} else if !strings.HasPrefix(err.Error(), "line 2:") {
+		t.Errorf("Stream() expected error on line 2, got: %s\n", err.Error())
+	}
+}
+
+func TestStreamResults(t *testing.T) {
+	ct, err := Compile(NewLeafNode(`eq .Level "error"`))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	var out bytes.Buffer
+	in := "{\"Level\": \"error\"}\nnot json\n{\"Level\": \"info\"}"
+	if err := ct.StreamResults(strings.NewReader(in), &out); err != nil {
+		t.Fatalf("StreamResults() error: %s\n", err.Error())
+	}
+
+	dec := json.NewDecoder(&out)
+	for _, tc := range []struct {
+		line     int
+		result   bool
+		hasError bool
+	}{
+		{1, true, false},
+		{2, false, true},
+		{3, false, false},
+	} {
+		var res StreamResult
+		if err := dec.Decode(&res); err != nil {
+			t.Fatalf("StreamResults() produced invalid output: %s\n", err.Error())
+		}
+		if res.Line != tc.line || res.Result != tc.result || (res.Error != "") != tc.hasError {
+			t.Errorf("StreamResults() expected=%v actual=%#v\n", tc, res)
+		}
+	}
+}
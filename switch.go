@@ -0,0 +1,374 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// NewSwitchNode returns a node which evaluates to the child of the first of
+// its `cases` listing the value of `field`, such as `.Country`, or to that of
+// its default case if none does, for rules with a variant per value of a
+// field:
+//
+//	logictree.NewSwitchNode(".Country",
+//		logictree.NewCaseNode(us, "US"),
+//		logictree.NewCaseNode(eu, "DE", "FR"),
+//		logictree.NewCaseNode(other))
+//
+// The field is read once and its case looked up rather than compared against
+// every value in turn when it is found as simply as `Evaluate` compares
+// fields, see `WithFuncs`.  Values compare as `eq` does, numbers by value.  A
+// switch without a default case, whose field matches no case, is false.
+//
+// In JSON a switch is encoded as any other node, with the operator "switch"
+// and the field as its "Leaf", and its cases with the operator "case", their
+// values written as template literals in their "Leaf", such as `"DE" "FR"`,
+// and their child as their only node.
+func NewSwitchNode(field string, cases ...*Node) *Node {
+	return &Node{Op: OperatorSwitch, Leaf: field, Nodes: cases}
+}
+
+// NewCaseNode returns the case of a switch choosing `child` for the
+// `values`, which are strings, booleans or numbers, or the default case if
+// no values are given.
+func NewCaseNode(child *Node, values ...interface{}) *Node {
+	lits := make([]string, len(values))
+	for i, v := range values {
+		lits[i] = formatLiteral(v)
+	}
+	return &Node{Op: OperatorCase, Leaf: strings.Join(lits, " "), Nodes: []*Node{child}}
+}
+
+// formatLiteral writes `v` as a template literal.
+func formatLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return strconv.Quote(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+// isSwitchPart reports whether `op` is that of a switch or of its cases,
+// which, unlike other nodes, have both children and a "Leaf".
+func isSwitchPart(op Operator) bool {
+	return op == OperatorSwitch || op == OperatorCase
+}
+
+// caseGuard returns the leaf which is true if the field of the switch `sw`
+// takes one of the values of its case `c`, which is not the default.
+func caseGuard(sw, c *Node) *Node {
+	return NewLeafNode("eq " + sw.Leaf + " " + c.Leaf)
+}
+
+// validateSwitch checks the switch `n` at `path`: its field is a field, each
+// of its children is a case of literal values with a single child, and at
+// most one of them is the default, reporting every problem as `validate`
+// does.
+func (n *Node) validateSwitch(path string, leaves bool) error {
+	if len(n.Nodes) == 0 {
+		return nodeError(path, n, ErrEmptyNode)
+	}
+	var errs []error
+	_, err := parseField(n.Leaf)
+	if err != nil {
+		errs = append(errs, nodeError(path, n, err))
+	}
+	field := err == nil
+	def := false
+	for i, c := range n.Nodes {
+		cp := childPath(path, i)
+		if c.Op != OperatorCase {
+			errs = append(errs, nodeError(cp, c, fmt.Errorf("%w: switch children must be case nodes, not %q", ErrInvalidOperator, string(c.Op))))
+			continue
+		}
+		if len(c.Nodes) != 1 {
+			errs = append(errs, nodeError(cp, c, fmt.Errorf("%w: case needs exactly one child", ErrInvalidOperator)))
+			continue
+		}
+		if _, err := caseValues(n, c); field && err != nil {
+			errs = append(errs, nodeError(cp, c, err))
+		}
+		if c.Leaf == "" {
+			if def {
+				errs = append(errs, nodeError(cp, c, fmt.Errorf("%w: switch has several default cases", ErrInvalidOperator)))
+			}
+			def = true
+		}
+		errs = append(errs, c.Nodes[0].validate(childPath(cp, 0), leaves))
+	}
+	return joinErrors(errs...)
+}
+
+// parseField returns the parts of the field `field`, such as `.Store.City`.
+func parseField(field string) ([]string, error) {
+	t, err := parseLeaf(field)
+	if err == nil {
+		if p, ok := leafPipe(t); ok && len(p.Decl) == 0 && len(p.Cmds) == 1 && len(p.Cmds[0].Args) == 1 {
+			if f, ok := argField(p.Cmds[0].Args[0]); ok {
+				return f, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: switch on %q, which is not a field", ErrInvalidOperator, field)
+}
+
+// caseValues returns the values of the case `c` of the switch `sw`, none for
+// the default case.
+func caseValues(sw, c *Node) ([]interface{}, error) {
+	if strings.TrimSpace(c.Leaf) == "" {
+		return nil, nil
+	}
+	t, err := parseLeaf(caseGuard(sw, c).Leaf)
+	if err == nil {
+		if p, ok := leafPipe(t); ok {
+			if cmp, ok := pipeComparison(p); ok && cmp.fn == "eq" && len(cmp.values) == len(p.Cmds[0].Args)-2 {
+				return cmp.values, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: case values %q are not literals", ErrInvalidOperator, c.Leaf)
+}
+
+// expandSwitch returns the switch `n` as the `if` nodes choosing between its
+// cases in turn, for the translations which have no switch of their own.
+func expandSwitch(n *Node) *Node {
+	e := constantNode(false)
+	for _, c := range n.Nodes {
+		if c.Leaf == "" && len(c.Nodes) == 1 {
+			e = c.Nodes[0]
+		}
+	}
+	for i := len(n.Nodes) - 1; i >= 0; i-- {
+		if c := n.Nodes[i]; c.Leaf != "" && len(c.Nodes) == 1 {
+			e = NewIfNode(caseGuard(n, c), c.Nodes[0], e)
+		}
+	}
+	return e
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// switchIndex finds the case of the value of the field of a compiled switch.
+type switchIndex struct {
+	strings map[string]*compiledNode // the first case of each string value
+	values  [][]interface{}          // the values of each case, by index of child
+	def     *compiledNode            // the default case, nil if none
+}
+
+// compileSwitch compiles the switch `cn`, whose cases are compiled with the
+// leaf of their guard, see `caseGuard`.
+func (c *compiler) compileSwitch(cn *compiledNode) error {
+	n := cn.node
+	sw := &switchIndex{strings: map[string]*compiledNode{}, values: make([][]interface{}, len(n.Nodes))}
+	for i, child := range n.Nodes {
+		cc := &compiledNode{node: child, path: childPath(cn.path, i)}
+		values, _ := caseValues(n, child)
+		if values == nil {
+			sw.def = cc
+		} else if err := c.compileTemplate(cc, caseGuard(n, child)); err != nil {
+			return err
+		}
+		branch, err := c.compileNode(child.Nodes[0], childPath(cc.path, 0))
+		if err != nil {
+			return err
+		}
+		cc.children = []*compiledNode{branch}
+		cn.children = append(cn.children, cc)
+
+		sw.values[i] = values
+		for _, v := range values {
+			if s, ok := v.(string); ok && sw.strings[s] == nil {
+				sw.strings[s] = cc
+			}
+		}
+	}
+
+	field, _ := parseField(n.Leaf)
+	cn.fields = [][]string{field}
+	if c.isStd("eq") {
+		cn.cmp = &comparison{op: "eq", fn: "eq", field: field}
+		cn.index = make([]*fieldIndex, len(field))
+		for i := range cn.index {
+			cn.index[i] = &fieldIndex{}
+		}
+	}
+	cn.sw = sw
+	return nil
+}
+
+// selectCase returns the case of the switch `cn` chosen by `data`, nil if
+// none is.
+func (cn *compiledNode) selectCase(st *evalState, data interface{}) (*compiledNode, error) {
+	if cn.cmp != nil {
+		if o, ok := cn.lookupField(data); ok {
+			if o.str {
+				if c := cn.sw.strings[o.s]; c != nil {
+					return c, nil
+				}
+				return cn.sw.def, nil
+			}
+			for i, values := range cn.sw.values {
+				for _, v := range values {
+					if o.equal(v) {
+						return cn.children[i], nil
+					}
+				}
+			}
+			return cn.sw.def, nil
+		}
+	}
+
+	// The guards fail or handle missing fields as any other leaf.
+	for i, c := range cn.children {
+		if cn.sw.values[i] == nil {
+			continue
+		}
+		_, v, err := c.runLeaf(st, data)
+		if err != nil {
+			return nil, err
+		}
+		if v {
+			return c, nil
+		}
+	}
+	return cn.sw.def, nil
+}
+
+// evaluateSwitch evaluates the child of the case chosen by `data`.
+func (cn *compiledNode) evaluateSwitch(st *evalState, data interface{}) (bool, error) {
+	c, err := cn.selectCase(st, data)
+	if err != nil || c == nil {
+		return false, err
+	}
+	return c.children[0].evaluate(st, data)
+}
+
+// evaluateKleeneSwitch is `evaluateKleene` of the switch `cn`: if its field
+// is missing, the result is unknown unless every case agrees.
+func (cn *compiledNode) evaluateKleeneSwitch(st *evalState, data interface{}, res *TruthResult) (Truth, error) {
+	if _, ok := missingField(data, cn.fields); !ok {
+		c, err := cn.selectCase(st, data)
+		if err != nil || c == nil {
+			return False, err
+		}
+		return c.children[0].evaluateKleene(st, data, res)
+	}
+
+	res.Unknown = append(res.Unknown, cn.path)
+	v := Unknown
+	for i, c := range cn.children {
+		cv, err := c.children[0].evaluateKleene(st, data, res)
+		if err != nil {
+			return Unknown, err
+		}
+		if i > 0 && cv != v {
+			return Unknown, nil
+		}
+		v = cv
+	}
+	if cn.sw.def == nil && v != False {
+		return Unknown, nil
+	}
+	return v, nil
+}
+
+// explainSwitch is `explain` of the switch `cn`, recording the results of
+// the guards of its cases by their paths.
+func (cn *compiledNode) explainSwitch(st *evalState, data interface{}, results map[string]bool, skipped bool, en *ExplainedNode) (*ExplainedNode, error) {
+	chosen, err := cn.selectCase(st, data)
+	if err != nil && !skipped {
+		return nil, err
+	}
+	for i, c := range cn.children {
+		if cn.sw.values[i] != nil && err == nil {
+			if _, v, gerr := c.runLeaf(st, data); gerr == nil {
+				results[c.path] = v
+			}
+		}
+		ce, cerr := c.children[0].explain(st, data, results, skipped || c != chosen)
+		if cerr != nil {
+			return nil, cerr
+		}
+		en.Nodes = append(en.Nodes, &ExplainedNode{
+			Path:   c.path,
+			Op:     c.node.Op,
+			Leaf:   c.node.Leaf,
+			Result: ce.Result,
+			Nodes:  []*ExplainedNode{ce},
+		})
+		if c == chosen {
+			en.Result = ce.Result
+		}
+	}
+	if err != nil {
+		en.Error = err.Error()
+	}
+	return en, nil
+}
+
+// evaluateBatchSwitch is `evaluateBatch` of the switch `cn`: each case is
+// evaluated over the rows left by those before it which its guard chooses.
+func (cn *compiledNode) evaluateBatchSwitch(bs *batchState, active *Bitmap) (*Bitmap, error) {
+	acc, rest := NewBitmap(bs.n), active.clone()
+	for i, c := range cn.children {
+		if cn.sw.values[i] == nil || rest.empty() {
+			continue
+		}
+		rows, err := c.evaluateBatchLeaf(bs, rest)
+		if err != nil {
+			return nil, err
+		}
+		rest.andNot(rows)
+		if rows.empty() {
+			continue
+		}
+		r, err := c.children[0].evaluateBatch(bs, rows)
+		if err != nil {
+			return nil, err
+		}
+		acc.or(r)
+	}
+	if cn.sw.def != nil && !rest.empty() {
+		r, err := cn.sw.def.children[0].evaluateBatch(bs, rest)
+		if err != nil {
+			return nil, err
+		}
+		acc.or(r)
+	}
+	return acc, nil
+}
+
+// partialSwitch is `partial` of the switch `cn`: the residual of the case
+// chosen if its field is known, and otherwise the switch of the residuals of
+// its cases.
+func (cn *compiledNode) partialSwitch(st *evalState, known map[string]interface{}) (*Node, bool, error) {
+	if _, ok := missingField(known, cn.fields); !ok {
+		c, err := cn.selectCase(st, known)
+		if err != nil {
+			return nil, false, err
+		}
+		if c == nil {
+			return constantNode(false), true, nil
+		}
+		return c.children[0].partial(st, known)
+	}
+
+	cases := make([]*Node, len(cn.children))
+	for i, c := range cn.children {
+		r, _, err := c.children[0].partial(st, known)
+		if err != nil {
+			return nil, false, err
+		}
+		cases[i] = &Node{Op: OperatorCase, Leaf: c.node.Leaf, Nodes: []*Node{r}}
+	}
+	return NewSwitchNode(cn.node.Leaf, cases...), false, nil
+}
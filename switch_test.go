@@ -0,0 +1,216 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// byCountry has a variant of a rule per country.
+var byCountry = NewSwitchNode(".Country",
+	NewCaseNode(NewLeafNode("gt .Total 10"), "US", "CA"),
+	NewCaseNode(NewNode(OperatorAnd, NewLeafNode("eq .Member true"), NewLeafNode("gt .Total 20")), "DE"),
+	NewCaseNode(NewLeafNode("gt .Total 100")))
+
+func TestSwitchNode(t *testing.T) {
+	tmpl, err := byCountry.GetTemplate(nil)
+	if err != nil {
+		t.Fatalf("GetTemplate() error: %s\n", err.Error())
+	}
+	plain, err := Compile(byCountry)
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	incremental, err := Compile(byCountry, WithIncremental())
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	type order struct {
+		Country string
+		Total   int
+		Member  bool
+	}
+	rows := []order{
+		{"US", 20, false},
+		{"CA", 5, false},
+		{"DE", 50, true},
+		{"DE", 50, false},
+		{"FR", 50, true},
+		{"FR", 150, false},
+	}
+	expected := []bool{true, false, true, false, false, true}
+	cols := Columns{
+		"Country": {String: []string{"US", "CA", "DE", "DE", "FR", "FR"}},
+		"Total":   {Int64: []int64{20, 5, 50, 50, 50, 150}},
+		"Member":  {Bool: []bool{false, false, true, false, true, false}},
+	}
+	batch, err := plain.EvaluateBatch(cols)
+	if err != nil {
+		t.Fatalf("EvaluateBatch() error: %s\n", err.Error())
+	}
+	for i, o := range rows {
+		data := map[string]interface{}{"Country": o.Country, "Total": o.Total, "Member": o.Member}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil || buf.String() != strconv.FormatBool(expected[i]) {
+			t.Errorf("Execute(%v) expected=%v actual=%s err=%v\n", data, expected[i], buf.String(), err)
+		}
+		for _, d := range []interface{}{data, o, &o} {
+			if v, err := plain.Evaluate(d); err != nil || v != expected[i] {
+				t.Errorf("Evaluate(%v) expected=%v actual=%v err=%v\n", d, expected[i], v, err)
+			}
+		}
+		if e, err := plain.Explain(data); err != nil || e.Result != expected[i] {
+			t.Errorf("Explain(%v) expected=%v actual=%v err=%v\n", data, expected[i], e, err)
+		}
+		if batch.Get(i) != expected[i] {
+			t.Errorf("EvaluateBatch(%d) expected=%v actual=%v\n", i, expected[i], batch.Get(i))
+		}
+	}
+
+	// Changing the field of the switch invalidates it.
+	data := map[string]interface{}{"Country": "US", "Total": 20}
+	for _, country := range []string{"US", "FR", "CA"} {
+		data["Country"] = country
+		incremental.Invalidate("Country")
+		if v, err := incremental.Evaluate(data); err != nil || v != (country != "FR") {
+			t.Errorf("Evaluate(incremental, %v) expected=%v actual=%v err=%v\n", data, country != "FR", v, err)
+		}
+	}
+
+	// Numbers compare by value, and a switch without a default is false.
+	ct, err := Compile(NewSwitchNode(".Level", NewCaseNode(NewLeafNode("true"), 1, 2.5)))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	for _, tc := range []struct {
+		level    interface{}
+		expected bool
+	}{
+		{1.0, true},
+		{uint8(1), true},
+		{2.5, true},
+		{3, false},
+		{"1", false},
+	} {
+		if v, err := ct.Evaluate(map[string]interface{}{"Level": tc.level}); err != nil || v != tc.expected {
+			t.Errorf("Evaluate(%v) expected=%v actual=%v err=%v\n", tc.level, tc.expected, v, err)
+		}
+	}
+	if v, err := ct.Evaluate(map[string]interface{}{}); err != nil || v {
+		t.Errorf("Evaluate(missing) expected=false actual=%v err=%v\n", v, err)
+	}
+
+	for _, tc := range []struct {
+		data     map[string]interface{}
+		expected Truth
+	}{
+		{map[string]interface{}{"Country": "US", "Total": 20}, True},
+		{map[string]interface{}{"Total": 500, "Member": true}, True},
+		{map[string]interface{}{"Total": 50, "Member": true}, Unknown},
+		{map[string]interface{}{"Total": 5}, False},
+	} {
+		if res, err := plain.EvaluateKleene(tc.data); err != nil || res.Value != tc.expected {
+			t.Errorf("EvaluateKleene(%v) expected=%v actual=%v err=%v\n", tc.data, tc.expected, res.Value, err)
+		}
+	}
+
+	for _, n := range []*Node{
+		NewSwitchNode(".Country"),
+		NewSwitchNode("len .Country", NewCaseNode(NewLeafNode("true"), 2)),
+		NewSwitchNode(".Country", NewLeafNode("true")),
+		NewSwitchNode(".Country", &Node{Op: OperatorCase, Leaf: `"US"`}),
+		NewSwitchNode(".Country", &Node{Op: OperatorCase, Leaf: `.Other`, Nodes: []*Node{NewLeafNode("true")}}),
+		NewSwitchNode(".Country", NewCaseNode(NewLeafNode("true")), NewCaseNode(NewLeafNode("false"))),
+		NewCaseNode(NewLeafNode("true"), "US"),
+	} {
+		if err := n.Validate(); err == nil {
+			t.Errorf("Validate(%v) expected an error\n", n)
+		}
+	}
+	if err := NewSwitchNode(".Country").Validate(); !errors.Is(err, ErrEmptyNode) {
+		t.Errorf("Validate(empty) expected=%v actual=%v\n", ErrEmptyNode, err)
+	}
+}
+
+func TestSwitchNodeTransforms(t *testing.T) {
+	// Encodings round trip.
+	bs, err := json.Marshal(byCountry)
+	if err != nil {
+		t.Fatalf("Marshal() error: %s\n", err.Error())
+	}
+	for _, decode := range []func([]byte) (*Node, error){
+		func(bs []byte) (*Node, error) {
+			var n Node
+			return &n, json.Unmarshal(bs, &n)
+		},
+		func(bs []byte) (*Node, error) {
+			return DecodeOptions{Strict: true}.Unmarshal(bs)
+		},
+	} {
+		if n, err := decode(bs); err != nil || !reflect.DeepEqual(n, byCountry) {
+			t.Errorf("decode(%s) expected=%v actual=%v err=%v\n", bs, byCountry, n, err)
+		}
+	}
+	if parsed, err := ParseSexpr(byCountry.Sexpr()); err != nil || !reflect.DeepEqual(parsed, byCountry) {
+		t.Errorf("ParseSexpr(%s) expected=%v actual=%v err=%v\n", byCountry.Sexpr(), byCountry, parsed, err)
+	}
+	if s, expected := byCountry.Infix(), `CASE .Country WHEN "US", "CA" THEN .Total > 10 WHEN "DE" THEN .Member == true AND .Total > 20 ELSE .Total > 100 END`; s != expected {
+		t.Errorf("Infix() expected=%s actual=%s\n", expected, s)
+	}
+
+	for _, tc := range []struct {
+		known    map[string]interface{}
+		expected string
+	}{
+		{map[string]interface{}{"Country": "DE"}, ".Member == true AND .Total > 20"},
+		{map[string]interface{}{"Country": "FR", "Total": 5}, "false"},
+		{map[string]interface{}{"Total": 50}, `CASE .Country WHEN "US", "CA" THEN true WHEN "DE" THEN .Member == true ELSE false END`},
+	} {
+		r, err := byCountry.PartialEval(tc.known)
+		if err != nil || r.Infix() != tc.expected {
+			t.Errorf("PartialEval(%v) expected=%s actual=%v err=%v\n", tc.known, tc.expected, r, err)
+		}
+	}
+
+	// A negated switch is true where no case matches.
+	n := NewSwitchNode(".Country", NewCaseNode(NewLeafNode(".A"), "US"))
+	var negated Node
+	src := `{"Op": "!", "Nodes": [` + string(mustMarshal(t, n)) + `]}`
+	if err := json.Unmarshal([]byte(src), &negated); err != nil || negated.Infix() != `CASE .Country WHEN "US" THEN NOT .A ELSE true END` {
+		t.Errorf("Unmarshal(%s) expected the negated switch, got %v err=%v\n", src, &negated, err)
+	}
+
+	renamed := NewSwitchNode(".Country", NewCaseNode(NewLeafNode(`eq .Country "US"`), "US"))
+	if count, err := renamed.RenameField(".Country", ".Address.Country"); err != nil || count != 2 || renamed.Leaf != ".Address.Country" {
+		t.Errorf("RenameField() expected=2 actual=%d %v err=%v\n", count, renamed, err)
+	}
+
+	// Guards are the leaves testing the values of their case.
+	if ok, _, err := NewNode(OperatorAnd, byCountry, NewLeafNode(`eq .Country "US" "CA"`), NewLeafNode("not (gt .Total 10)")).Satisfiable(); err != nil || ok {
+		t.Errorf("Satisfiable() expected=false actual=%v err=%v\n", ok, err)
+	}
+
+	sql, args, err := byCountry.ToSQL(DialectSQLite)
+	if expected := `CASE WHEN "Country" IN (?, ?) THEN "Total" > ? WHEN "Country" = ? THEN "Member" = ? AND "Total" > ? ELSE "Total" > ? END`; err != nil || sql != expected || len(args) != 7 {
+		t.Errorf("ToSQL() expected=%s actual=%s %v err=%v\n", expected, sql, args, err)
+	}
+	if _, err := byCountry.ToESQuery(); err != nil {
+		t.Errorf("ToESQuery() error: %s\n", err.Error())
+	}
+}
+
+func mustMarshal(t *testing.T, n *Node) []byte {
+	bs, err := json.Marshal(n)
+	if err != nil {
+		t.Fatalf("Marshal() error: %s\n", err.Error())
+	}
+	return bs
+}
@@ -0,0 +1,155 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// DecisionTable converts decision tables kept as CSV, such as those exported
+// from spreadsheets, into trees.  The first row of the table names its
+// columns, each a condition, and every other row is a case: the tree is the
+// `or` of its cases and each case the `and` of the leaves of its cells.
+// Empty cells, and those holding only "-", are conditions the case does not
+// care about, so a case of such cells alone always matches.
+//
+//	Milk,Toothpaste
+//	4,-
+//	-,5
+//
+// is `.Milk == 4 OR .Toothpaste == 5` with the default templates.
+type DecisionTable struct {
+	// Columns maps the name of a column to the template of the leaf
+	// expression of its cells, given the `.Column` name and the `.Value`
+	// of the cell, such as "ge .Milk {{ .Value }}".  The `literal` function
+	// writes a value as a number or boolean if it is one and as a quoted
+	// string otherwise.
+	//
+	// Columns without a template compare their field, named by the column,
+	// with the cell: `eq .{{ .Column }} {{ literal .Value }}`.
+	Columns map[string]string `json:"columns,omitempty" yaml:"columns,omitempty"`
+}
+
+// defaultColumn is the leaf template of a column without one.
+const defaultColumn = "eq .{{ .Column }} {{ literal .Value }}"
+
+// ParseDecisionTable converts the CSV decision table read from `r` with the
+// default templates of `DecisionTable`.
+func ParseDecisionTable(r io.Reader) (*Node, error) {
+	return DecisionTable{}.Parse(r)
+}
+
+// Parse converts the CSV decision table read from `r` into a tree, and
+// validates it.  Errors name the offending row, counting the header as row
+// 1, and column.
+func (t DecisionTable) Parse(r io.Reader) (*Node, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid decision table: no header")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid decision table: %w", err)
+	}
+
+	columns, err := t.columns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	root := NewNode(OperatorOr)
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid decision table: %w", err)
+		}
+
+		c := NewNode(OperatorAnd)
+		for i, cell := range record {
+			if cell = strings.TrimSpace(cell); cell == "" || cell == "-" {
+				continue
+			}
+			var sb strings.Builder
+			data := struct{ Column, Value string }{header[i], cell}
+			if err := columns[i].Execute(&sb, data); err != nil {
+				return nil, fmt.Errorf("invalid decision table at row %d, column %q: %w", row, header[i], err)
+			}
+			leaf := NewLeafNode(sb.String())
+			if err := leaf.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid decision table at row %d, column %q: %w", row, header[i], err)
+			}
+			c.Nodes = append(c.Nodes, leaf)
+		}
+		if len(c.Nodes) == 0 {
+			c = NewLeafNode("true")
+		}
+		root.Nodes = append(root.Nodes, c)
+	}
+	if len(root.Nodes) == 0 {
+		return nil, fmt.Errorf("invalid decision table: no cases")
+	}
+	return root, nil
+}
+
+// columns parses the leaf template of each column named by `header`.
+func (t DecisionTable) columns(header []string) ([]*template.Template, error) {
+	for name := range t.Columns {
+		found := false
+		for _, h := range header {
+			found = found || strings.TrimSpace(h) == name
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid decision table: no column %q", name)
+		}
+	}
+
+	seen := map[string]bool{}
+	columns := make([]*template.Template, len(header))
+	for i := range header {
+		name := strings.TrimSpace(header[i])
+		header[i] = name
+		if seen[name] {
+			return nil, fmt.Errorf("invalid decision table: repeated column %q", name)
+		}
+		seen[name] = true
+
+		src, ok := t.Columns[name]
+		if !ok {
+			if _, err := fieldName(name); err != nil {
+				return nil, fmt.Errorf("invalid decision table: column %q needs a template: %w", name, err)
+			}
+			src = defaultColumn
+		}
+		tmpl, err := template.New(name).Funcs(template.FuncMap{"literal": literal}).Parse(src)
+		if err != nil {
+			return nil, fmt.Errorf("invalid decision table: column %q: %w", name, err)
+		}
+		columns[i] = tmpl.Option("missingkey=error")
+	}
+	return columns, nil
+}
+
+// literal writes the cell `v` as a template constant: as it is if it is a
+// number or boolean, and quoted otherwise.
+func literal(v string) string {
+	if v == "true" || v == "false" {
+		return v
+	}
+	// Infinities and NaN parse as floats but are not constants.
+	if _, err := strconv.ParseFloat(v, 64); err == nil && !strings.ContainsAny(v, "iInN") {
+		return v
+	}
+	return strconv.Quote(v)
+}
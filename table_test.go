@@ -0,0 +1,63 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"strings"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestParseDecisionTable(t *testing.T) {
+	for _, tc := range []struct {
+		table    DecisionTable
+		src      string
+		expected string
+	}{
+		{DecisionTable{}, "Milk,Toothpaste\n4,-\n,5\n", ".Milk == 4 OR .Toothpaste == 5"},
+		{DecisionTable{}, "Name, Item.InStock, Price\nmilk, true, 1.5\n\"o, k\",,\n", `(.Name == "milk" AND .Item.InStock == true AND .Price == 1.5) OR .Name == "o, k"`},
+		{DecisionTable{}, "Milk\n-\n", "true"},
+		{DecisionTable{}, "Tag\nInf\n", `.Tag == "Inf"`},
+		{DecisionTable{Columns: map[string]string{
+			"Min milk": "ge .Milk {{ .Value }}",
+			"Max milk": "le .Milk {{ .Value }}",
+			"Tag":      "hasPrefix .Tag {{ literal .Value }}",
+		}}, "Min milk,Max milk,Tag\n4,6,-\n,,new\n", `(.Milk >= 4 AND .Milk <= 6) OR hasPrefix(.Tag, "new")`},
+	} {
+		n, err := tc.table.Parse(strings.NewReader(tc.src))
+		if err != nil {
+			t.Errorf("Parse(%q) error: %s\n", tc.src, err.Error())
+			continue
+		}
+		if actual := n.Infix(); actual != tc.expected {
+			t.Errorf("Parse(%q) expected=%s actual=%s\n", tc.src, tc.expected, actual)
+		}
+		if err := n.Validate(); err != nil {
+			t.Errorf("Parse(%q) invalid tree: %s\n", tc.src, err.Error())
+		}
+	}
+}
+
+func TestParseDecisionTableErrors(t *testing.T) {
+	for _, tc := range []struct {
+		table DecisionTable
+		src   string
+		err   string
+	}{
+		{DecisionTable{}, "", "invalid decision table: no header"},
+		{DecisionTable{}, "Milk\n", "invalid decision table: no cases"},
+		{DecisionTable{}, "Milk,Milk\n1,2\n", `invalid decision table: repeated column "Milk"`},
+		{DecisionTable{}, "Min milk\n4\n", `invalid decision table: column "Min milk" needs a template`},
+		{DecisionTable{}, "Milk,Eggs\n4\n", "invalid decision table: record on line 2: wrong number of fields"},
+		{DecisionTable{Columns: map[string]string{"Eggs": "eq .Eggs {{ .Value }}"}}, "Milk\n4\n", `invalid decision table: no column "Eggs"`},
+		{DecisionTable{Columns: map[string]string{"Milk": "eq .Milk {{ .Value"}}, "Milk\n4\n", `invalid decision table: column "Milk"`},
+		{DecisionTable{Columns: map[string]string{"Milk": "eq .Milk {{ .Cell }}"}}, "Milk\n4\n", `invalid decision table at row 2, column "Milk"`},
+		{DecisionTable{Columns: map[string]string{"Milk": "eq .Milk {{ .Value }}"}}, "Milk\n1\n4 }}{{ .Secret\n", `invalid decision table at row 3, column "Milk"`},
+	} {
+		_, err := tc.table.Parse(strings.NewReader(tc.src))
+		if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+			t.Errorf("Parse(%q) expected error=%q actual=%v\n", tc.src, tc.err, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"container/list"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// templateCacheSize is the number of distinct trees whose templates are kept
+// by `GetTemplate`.
+const templateCacheSize = 1024
+
+// templates caches the parsed templates of `GetTemplate`, keyed by the
+// combined expression of a tree and the names of the functions it was parsed
+// with; the functions themselves are set on a clone of the template for each
+// call, since functions cannot be compared.  A tree which is modified has a
+// different expression, so the cache never needs to be invalidated.
+var templates = &templateCache{entries: map[string]*list.Element{}, order: list.New()}
+
+type templateCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // of *templateEntry, most recently used first
+}
+
+// templateEntry is parsed once, outside the lock of the cache.
+type templateEntry struct {
+	key  string
+	once sync.Once
+	tmpl *template.Template
+	err  error
+}
+
+// get returns a template executing `{{ e }}` with the functions `fm`.
+func (c *templateCache) get(e string, fm template.FuncMap) (*template.Template, error) {
+	names := make([]string, 0, len(fm))
+	for name := range fm {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	key := e + "\x00" + strings.Join(names, "\x00")
+
+	c.mu.Lock()
+	el, ok := c.entries[key]
+	if ok {
+		c.order.MoveToFront(el)
+	} else {
+		el = c.order.PushFront(&templateEntry{key: key})
+		c.entries[key] = el
+		if c.order.Len() > templateCacheSize {
+			last := c.order.Back()
+			c.order.Remove(last)
+			delete(c.entries, last.Value.(*templateEntry).key)
+		}
+	}
+	te := el.Value.(*templateEntry)
+	c.mu.Unlock()
+
+	te.once.Do(func() {
+		// Parsing only checks that the functions exist.
+		placeholders := make(template.FuncMap, len(names))
+		for _, name := range names {
+			placeholders[name] = func() bool { return false }
+		}
+		te.tmpl, te.err = template.New("tree").Funcs(placeholders).Parse("{{ " + e + " }}")
+	})
+	if te.err != nil {
+		return nil, te.err
+	}
+
+	tmpl, err := te.tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.Funcs(fm), nil
+}
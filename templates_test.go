@@ -0,0 +1,100 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestGetTemplateCached(t *testing.T) {
+	run := func(n *Node, fm template.FuncMap) string {
+		tmpl, err := n.GetTemplate(fm)
+		if err != nil {
+			t.Fatalf("GetTemplate() error: %s\n", err.Error())
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, map[string]interface{}{"A": 3}); err != nil {
+			t.Fatalf("Execute() error: %s\n", err.Error())
+		}
+		return buf.String()
+	}
+	limit := func(l int) template.FuncMap {
+		return template.FuncMap{"limit": func() int { return l }}
+	}
+
+	n := NewNode(OperatorAnd, NewLeafNode("gt .A limit"), NewLeafNode("lt .A 10"))
+	for i, tc := range []struct {
+		fm       template.FuncMap
+		expected string
+	}{
+		{limit(1), "true"},
+		// The functions of each call are used, even with the same names.
+		{limit(5), "false"},
+		{limit(1), "true"},
+	} {
+		if actual := run(n, tc.fm); actual != tc.expected {
+			t.Errorf("GetTemplate(%d) expected=%s actual=%s\n", i, tc.expected, actual)
+		}
+	}
+
+	// Modifying the tree changes its template.
+	n.Nodes[1].Leaf = "(lt .A 2)"
+	if actual := run(n, limit(1)); actual != "false" {
+		t.Errorf("GetTemplate(modified) expected=false actual=%s\n", actual)
+	}
+
+	// Templates are the caller's own.
+	a, _ := n.GetTemplate(limit(1))
+	template.Must(a.New("extra").Parse("extra"))
+	b, _ := n.GetTemplate(limit(1))
+	if b.Lookup("extra") != nil {
+		t.Errorf("GetTemplate() expected a template of its own\n")
+	}
+}
+
+func TestGetTemplateParseError(t *testing.T) {
+	n := NewLeafNode("undefined .A")
+	for i := 0; i < 2; i++ {
+		var pe *ParseError
+		if _, err := n.GetTemplate(nil); !errors.As(err, &pe) || pe.Offset != 1 {
+			t.Errorf("GetTemplate(%d) expected a ParseError at offset 1, got %v\n", i, err)
+		}
+	}
+}
+
+func TestGetTemplateConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			n := NewLeafNode(fmt.Sprintf("eq .A %d", i%4))
+			tmpl, err := n.GetTemplate(nil)
+			if err != nil {
+				errs <- err
+				return
+			}
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, map[string]interface{}{"A": 1}); err != nil {
+				errs <- err
+				return
+			}
+			if expected := fmt.Sprint(i%4 == 1); buf.String() != expected {
+				errs <- fmt.Errorf("%d: expected=%s actual=%s", i, expected, buf.String())
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("GetTemplate() error: %s\n", err.Error())
+	}
+}
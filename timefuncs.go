@@ -0,0 +1,120 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// now is the clock used by the time functions, replaced in tests.
+var now = time.Now
+
+func timeBefore(a, b interface{}) (bool, error) {
+	at, bt, err := toTimes(a, b)
+	return err == nil && at.Before(bt), err
+}
+
+func timeAfter(a, b interface{}) (bool, error) {
+	at, bt, err := toTimes(a, b)
+	return err == nil && at.After(bt), err
+}
+
+// timeWithin reports whether `t` is no older than `d` and not in the future.
+func timeWithin(t, d interface{}) (bool, error) {
+	tt, err := toTime(t)
+	if err != nil {
+		return false, err
+	}
+	dd, err := toDuration(d)
+	if err != nil {
+		return false, err
+	}
+	n := now()
+	return !tt.Before(n.Add(-dd)) && !tt.After(n), nil
+}
+
+// timeOlderThan reports whether `t` is more than `d` in the past.
+func timeOlderThan(t, d interface{}) (bool, error) {
+	tt, err := toTime(t)
+	if err != nil {
+		return false, err
+	}
+	dd, err := toDuration(d)
+	if err != nil {
+		return false, err
+	}
+	return tt.Before(now().Add(-dd)), nil
+}
+
+// timeInWindow reports whether the time of day of `at` (or now) is within the
+// "15:04" formatted window [start, end).  Windows where start is after end
+// wrap around midnight.
+func timeInWindow(start, end string, at ...interface{}) (bool, error) {
+	if len(at) > 1 {
+		return false, fmt.Errorf("inWindow: expected at most 3 arguments, got %d", len(at)+2)
+	}
+	t := now()
+	if len(at) == 1 {
+		var err error
+		if t, err = toTime(at[0]); err != nil {
+			return false, err
+		}
+	}
+
+	s, err := time.Parse("15:04", start)
+	if err != nil {
+		return false, fmt.Errorf("inWindow: %w", err)
+	}
+	e, err := time.Parse("15:04", end)
+	if err != nil {
+		return false, fmt.Errorf("inWindow: %w", err)
+	}
+
+	minutes := func(t time.Time) int { return t.Hour()*60 + t.Minute() }
+	m, sm, em := minutes(t), minutes(s), minutes(e)
+	if sm <= em {
+		return m >= sm && m < em, nil
+	}
+	return m >= sm || m < em, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// toTime converts a `time.Time` or an RFC3339 formatted string to a time.
+func toTime(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case *time.Time:
+		if t != nil {
+			return *t, nil
+		}
+	case string:
+		return time.Parse(time.RFC3339, t)
+	}
+	return time.Time{}, fmt.Errorf("cannot use %T as a time", v)
+}
+
+func toTimes(a, b interface{}) (time.Time, time.Time, error) {
+	at, err := toTime(a)
+	if err != nil {
+		return at, at, err
+	}
+	bt, err := toTime(b)
+	return at, bt, err
+}
+
+// toDuration converts a `time.Duration` or a `time.ParseDuration` formatted
+// string to a duration.
+func toDuration(v interface{}) (time.Duration, error) {
+	switch d := v.(type) {
+	case time.Duration:
+		return d, nil
+	case string:
+		return time.ParseDuration(d)
+	}
+	return 0, fmt.Errorf("cannot use %T as a duration", v)
+}
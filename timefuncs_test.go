@@ -0,0 +1,71 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"testing"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestTimeFuncs(t *testing.T) {
+	fixed := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	now = func() time.Time { return fixed }
+	defer func() { now = time.Now }()
+
+	data := map[string]interface{}{
+		"Created": "2024-03-01T02:00:00Z",
+		"Old":     fixed.Add(-72 * time.Hour),
+		"Late":    "2024-03-01T23:15:00Z",
+		"Future":  "2024-03-02T00:00:00Z",
+		"Window":  2 * time.Hour,
+	}
+
+	for _, tc := range []struct {
+		expr     string
+		expected bool
+	}{
+		{"before .Created .Future", true},
+		{"after .Created .Future", false},
+		{"before .Old .Created", true},
+		{`within .Created "24h"`, true},
+		{`within .Created "1h"`, false},
+		{`within .Future "24h"`, false},
+		{"within .Created .Window", false},
+		{`olderThan .Old "48h"`, true},
+		{`olderThan .Created "48h"`, false},
+		{`inWindow "09:00" "17:00"`, true},
+		{`inWindow "13:00" "17:00"`, false},
+		{`inWindow "09:00" "17:00" .Late`, false},
+		{`inWindow "22:00" "06:00" .Late`, true},
+		{`inWindow "22:00" "06:00"`, false},
+	} {
+		ct, err := Compile(NewLeafNode(tc.expr), WithFuncs(StdFuncs()))
+		if err != nil {
+			t.Fatalf("Compile(%s) error: %s\n", tc.expr, err.Error())
+		}
+		v, err := ct.Evaluate(data)
+		if err != nil {
+			t.Errorf("Evaluate(%s) error: %s\n", tc.expr, err.Error())
+		}
+		if v != tc.expected {
+			t.Errorf("Evaluate(%s) expected=%v actual=%v\n", tc.expr, tc.expected, v)
+		}
+	}
+
+	for _, expr := range []string{
+		`before .Created "yesterday"`,
+		`within .Created "a day"`,
+		`within 5 "24h"`,
+		`inWindow "9am" "17:00"`,
+	} {
+		ct, err := Compile(NewLeafNode(expr), WithFuncs(StdFuncs()))
+		if err != nil {
+			t.Fatalf("Compile(%s) error: %s\n", expr, err.Error())
+		}
+		if _, err := ct.Evaluate(data); err == nil {
+			t.Errorf("Evaluate(%s) expected an error\n", expr)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Tracer starts the spans of the evaluations of a tree, see `WithTracer`, so
+// that they show in the traces of the service evaluating it.  Package
+// `logictreeotel` implements it with OpenTelemetry, keeping this package
+// free of the dependency.
+type Tracer interface {
+	// Start starts the span `name` as a child of the span of `ctx`, if any,
+	// returning the context holding it.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a `Tracer`.
+type Span interface {
+	// SetAttribute sets the attribute `key` of the span to `value`, a
+	// string, a bool or an int.
+	SetAttribute(key string, value interface{})
+
+	// End ends the span, which failed with `err` unless it is nil.
+	End(err error)
+}
+
+// The names of the spans and attributes of a traced evaluation.
+const (
+	SpanEvaluate = "logictree.Evaluate"
+	SpanNode     = "logictree.node"
+
+	AttrFingerprint = "logictree.fingerprint" // of the tree, see `Fingerprint`
+	AttrResult      = "logictree.result"      // of the tree or node, unless it failed
+	AttrLeaves      = "logictree.leaves"      // the number of leaves executed
+	AttrPath        = "logictree.path"        // of the node
+	AttrOp          = "logictree.op"          // of the node
+)
+
+// WithTracer traces every evaluation of the tree by `EvaluateContext` and
+// `Evaluate` with `t`: in a `SpanEvaluate` span, child of the span of the
+// context given to `EvaluateContext`, with the fingerprint of the tree, its
+// result and the number of leaves executed, and with spans of their own for
+// the subtrees down to `depth`, the children of the root being at depth 1.
+// Results which are cached, see `WithCache` and `WithIncremental`, and
+// shared, see `WithSharedEvaluation`, have no span of their own.
+func WithTracer(t Tracer, depth int) Option {
+	return func(o *compileOptions) {
+		o.tracing = &tracing{tracer: t, depth: depth}
+	}
+}
+
+// tracing is the tracer of a tree, and the depth of the subtrees it traces.
+type tracing struct {
+	tracer Tracer
+	depth  int
+}
+
+// traces reports whether the node at `path` has a span of its own.
+func (t *tracing) traces(path string) bool {
+	return path != "/" && strings.Count(path, "/") <= t.depth
+}
+
+// evaluateTraced is `EvaluateContext` within a span of the tracer of the
+// tree, if it is traced.
+func (ct *CompiledTree) evaluateTraced(ctx context.Context, data interface{}) (bool, error) {
+	if ct.opts.tracing == nil {
+		v, _, err := ct.evaluateContext(ctx, data)
+		return v, err
+	}
+	ctx, span := ct.opts.tracing.tracer.Start(ctx, SpanEvaluate)
+	span.SetAttribute(AttrFingerprint, ct.fingerprint)
+	v, leaves, err := ct.evaluateContext(ctx, data)
+	span.SetAttribute(AttrLeaves, leaves)
+	if err == nil {
+		span.SetAttribute(AttrResult, v)
+	}
+	span.End(err)
+	return v, err
+}
+
+// evaluateTraced evaluates the node, in a span of its own if the tree traces
+// it, see `WithTracer`.
+func (cn *compiledNode) evaluateTraced(st *evalState, data interface{}) (bool, error) {
+	if st.tracing == nil || !st.tracing.traces(cn.path) {
+		return cn.evaluateHooked(st, data)
+	}
+
+	ctx, span := st.tracing.tracer.Start(st.ctx, SpanNode)
+	span.SetAttribute(AttrPath, cn.path)
+	span.SetAttribute(AttrOp, string(cn.node.Op))
+	sub := *st
+	sub.ctx = ctx
+	v, err := cn.evaluateHooked(&sub, data)
+	if err == nil {
+		span.SetAttribute(AttrResult, v)
+	}
+	span.End(err)
+	return v, err
+}
@@ -0,0 +1,119 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// recorder is a `Tracer` recording its spans as text, such as
+// "logictree.node(/1) logictree.op=or logictree.path=/1".
+type recorder struct {
+	mu    sync.Mutex
+	spans []string
+}
+
+type recorderKey struct{}
+
+type recordedSpan struct {
+	r     *recorder
+	name  string
+	attrs map[string]interface{}
+}
+
+func (r *recorder) Start(ctx context.Context, name string) (context.Context, Span) {
+	if parent, ok := ctx.Value(recorderKey{}).(string); ok {
+		name = parent + ">" + name
+	}
+	return context.WithValue(ctx, recorderKey{}, name), &recordedSpan{r: r, name: name, attrs: map[string]interface{}{}}
+}
+
+func (s *recordedSpan) SetAttribute(key string, value interface{}) {
+	s.attrs[key] = value
+}
+
+func (s *recordedSpan) End(err error) {
+	keys := []string{AttrPath, AttrOp, AttrResult, AttrLeaves}
+	parts := []string{s.name}
+	for _, k := range keys {
+		if v, ok := s.attrs[k]; ok {
+			parts = append(parts, fmt.Sprintf("%s=%v", strings.TrimPrefix(k, "logictree."), v))
+		}
+	}
+	if err != nil {
+		parts = append(parts, "error")
+	}
+	s.r.mu.Lock()
+	s.r.spans = append(s.r.spans, strings.Join(parts, " "))
+	s.r.mu.Unlock()
+}
+
+func TestWithTracer(t *testing.T) {
+	tree := NewNode(OperatorAnd, NewLeafNode("eq .A 1"), NewNode(OperatorOr, NewLeafNode("eq .B 1"), NewLeafNode("eq .B 2")))
+	data := map[string]interface{}{"A": 1, "B": 2}
+	for _, tc := range []struct {
+		depth    int
+		data     interface{}
+		expected []string
+	}{
+		{0, data, []string{"logictree.Evaluate result=true leaves=3"}},
+		{1, data, []string{
+			"logictree.Evaluate>logictree.node path=/0 op=leaf result=true",
+			"logictree.Evaluate>logictree.node path=/1 op=or result=true",
+			"logictree.Evaluate result=true leaves=3",
+		}},
+		{2, map[string]interface{}{"A": 1, "B": 1}, []string{
+			"logictree.Evaluate>logictree.node path=/0 op=leaf result=true",
+			"logictree.Evaluate>logictree.node>logictree.node path=/1/0 op=leaf result=true",
+			"logictree.Evaluate>logictree.node path=/1 op=or result=true",
+			"logictree.Evaluate result=true leaves=2",
+		}},
+		{1, map[string]interface{}{"A": 2}, []string{
+			"logictree.Evaluate>logictree.node path=/0 op=leaf result=false",
+			"logictree.Evaluate result=false leaves=1",
+		}},
+	} {
+		r := &recorder{}
+		ct, err := Compile(tree, WithTracer(r, tc.depth))
+		if err != nil {
+			t.Fatalf("Compile() error: %s\n", err.Error())
+		}
+		if _, err := ct.Evaluate(tc.data); err != nil {
+			t.Fatalf("Evaluate() error: %s\n", err.Error())
+		}
+		if strings.Join(r.spans, "\n") != strings.Join(tc.expected, "\n") {
+			t.Errorf("Evaluate(%d) expected=%q actual=%q\n", tc.depth, tc.expected, r.spans)
+		}
+	}
+
+	// Spans hold the fingerprint of the tree, and end with the errors of
+	// evaluations which fail.
+	var span *recordedSpan
+	tracer := tracerFunc(func(ctx context.Context, name string) (context.Context, Span) {
+		span = &recordedSpan{r: &recorder{}, name: name, attrs: map[string]interface{}{}}
+		return ctx, span
+	})
+	ct, err := Compile(tree, WithTracer(tracer, 0), WithMissing(MissingIsError))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if _, err := ct.Evaluate(map[string]interface{}{}); !errors.Is(err, ErrMissingField) {
+		t.Errorf("Evaluate() expected=%v actual=%v\n", ErrMissingField, err)
+	}
+	if span.attrs[AttrFingerprint] != tree.Fingerprint() || strings.Join(span.r.spans, "") != "logictree.Evaluate leaves=0 error" {
+		t.Errorf("Evaluate() expected a failed span of %s, got %v %q\n", tree.Fingerprint(), span.attrs, span.r.spans)
+	}
+}
+
+type tracerFunc func(ctx context.Context, name string) (context.Context, Span)
+
+func (f tracerFunc) Start(ctx context.Context, name string) (context.Context, Span) {
+	return f(ctx, name)
+}
@@ -0,0 +1,198 @@
+// Package treetest checks logictree trees against fixtures declaring the
+// results expected for named records, so that rule files and the behavior
+// expected of them are reviewed together and checked by `go test` in CI.
+//
+// The fixtures of the tree file `<name>.json`, `<name>.yaml` or `<name>.yml`
+// are held by the file `<name>_test.json`, `<name>_test.yaml` or
+// `<name>_test.yml` next to it, as an array of `Fixture`s:
+//
+//	[
+//	  {"Name": "adult", "Data": {"Age": 20}, "Expected": true},
+//	  {"Name": "minor", "Data": {"Age": 16}, "Expected": false},
+//	  {"Name": "no age", "Data": {}, "Error": "Age"}
+//	]
+//
+// `RunDir` loads the trees of a directory as package `loader` does and runs
+// the fixtures of each as subtests, and failures are reported with the
+// explanation of the result the tree had:
+//
+//	func TestRules(t *testing.T) {
+//		treetest.RunDir(t, "rules", logictree.WithFuncs(logictree.StdFuncs()))
+//	}
+package treetest
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sabhiram/logictree"
+	"github.com/sabhiram/logictree/loader"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Fixture is a named record and the result a tree must have for it, or, if
+// `Error` is set, a part of the message of the error it must fail with.
+type Fixture struct {
+	Name        string      `json:"Name"`
+	Description string      `json:"Description,omitempty"`
+	Data        interface{} `json:"Data"`
+	Expected    bool        `json:"Expected,omitempty"`
+	Error       string      `json:"Error,omitempty"`
+}
+
+// Check evaluates `ct` against the data of `f`, and returns nil if it has the
+// outcome `f` expects, or an error describing the outcome it had.  A result
+// other than the one expected is described by the explanation of the result,
+// see `logictree.Explanation`: the leaves it came down to and the result of
+// every node.
+func Check(ct *logictree.CompiledTree, f Fixture) error {
+	v, err := ct.Evaluate(f.Data)
+	switch {
+	case f.Error != "" && err == nil:
+		return fmt.Errorf("expected an error containing %q, got result=%v", f.Error, v)
+	case f.Error != "" && !strings.Contains(err.Error(), f.Error):
+		return fmt.Errorf("expected an error containing %q, got %q", f.Error, err.Error())
+	case f.Error != "":
+		return nil
+	case err != nil:
+		return fmt.Errorf("unexpected error: %s", err.Error())
+	case v == f.Expected:
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "expected=%v actual=%v", f.Expected, v)
+	x, err := ct.Explain(f.Data)
+	if err != nil {
+		return errors.New(b.String())
+	}
+	for _, flip := range x.Flips {
+		fmt.Fprintf(&b, "\n%s was %v at %s", flip.Leaf, flip.Result, strings.Join(flip.Paths, ", "))
+	}
+	results := map[string]bool{}
+	explainedResults(x.Root, results)
+	b.WriteString("\n")
+	ct.Root().PrettyPrint(&b, logictree.PrintASCII(), logictree.PrintResults(results))
+	return errors.New(strings.TrimSuffix(b.String(), "\n"))
+}
+
+// explainedResults records the results of the nodes under `en` by path,
+// leaving out those of leaves which failed.
+func explainedResults(en *logictree.ExplainedNode, results map[string]bool) {
+	if en.Error == "" || len(en.Nodes) > 0 {
+		results[en.Path] = en.Result
+	}
+	for _, c := range en.Nodes {
+		explainedResults(c, results)
+	}
+}
+
+// Run checks `ct` against every fixture with `Check`, each as a subtest of
+// `t` named after the fixture.
+func Run(t *testing.T, ct *logictree.CompiledTree, fixtures []Fixture) {
+	t.Helper()
+	for _, f := range fixtures {
+		f := f
+		t.Run(f.Name, func(t *testing.T) {
+			if err := Check(ct, f); err != nil {
+				t.Errorf("%s\n", err.Error())
+			}
+		})
+	}
+}
+
+// ReadFixtures reads the fixtures of the file `path`, YAML for the
+// extensions `.yaml` and `.yml` and JSON otherwise.  Fields which are not
+// those of a `Fixture`, such as misspelled ones, fail.
+func ReadFixtures(path string) ([]Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		var v interface{}
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if data, err = json.Marshal(v); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	fixtures := []Fixture{}
+	if err := dec.Decode(&fixtures); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for i, f := range fixtures {
+		if f.Name == "" {
+			return nil, fmt.Errorf("%s: fixture %d has no name", path, i)
+		}
+	}
+	return fixtures, nil
+}
+
+// RunDir loads the trees of `dir` compiled with `opts`, see package
+// `loader`, and runs the fixtures of each, as a subtest of `t` named after
+// the tree.  It fails if any tree fails to load, and reports fixtures whose
+// tree does not exist.
+func RunDir(t *testing.T, dir string, opts ...logictree.Option) {
+	t.Helper()
+	l, err := loader.Load(dir, loader.Options{Compile: opts})
+	if err != nil {
+		t.Fatalf("treetest: loading %s: %s\n", dir, err.Error())
+	}
+	files, err := fixtureFiles(dir)
+	if err != nil {
+		t.Fatalf("treetest: reading %s: %s\n", dir, err.Error())
+	}
+
+	for _, file := range files {
+		name := strings.TrimSuffix(strings.TrimSuffix(file, filepath.Ext(file)), "_test")
+		fixtures, err := ReadFixtures(filepath.Join(dir, file))
+		if err != nil {
+			t.Errorf("treetest: %s\n", err.Error())
+			continue
+		}
+		ct, ok := l.Get(name)
+		if !ok {
+			t.Errorf("treetest: %s: no tree named %q\n", file, name)
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			Run(t, ct, fixtures)
+		})
+	}
+}
+
+// fixtureFiles returns the names of the fixture files of `dir`, sorted.
+func fixtureFiles(dir string) ([]string, error) {
+	des, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, de := range des {
+		ext := filepath.Ext(de.Name())
+		if de.IsDir() || strings.HasPrefix(de.Name(), ".") || !strings.HasSuffix(strings.TrimSuffix(de.Name(), ext), "_test") {
+			continue
+		}
+		switch ext {
+		case ".json", ".yaml", ".yml":
+			files = append(files, de.Name())
+		}
+	}
+	return files, nil
+}
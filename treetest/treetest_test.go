@@ -0,0 +1,84 @@
+package treetest
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sabhiram/logictree"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func writeFile(t *testing.T, dir, name, content string) {
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("WriteFile(%s) error: %s\n", name, err.Error())
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ct, err := logictree.Compile(logictree.NewNode(logictree.OperatorAnd,
+		logictree.NewLeafNode("ge .Age 18"),
+		logictree.NewLeafNode("gt .Amount 100"),
+	), logictree.WithMissing(logictree.MissingIsError))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	for _, tc := range []struct {
+		fixture  Fixture
+		expected string // a part of the failure, or empty if it passes
+	}{
+		{Fixture{Name: "large", Data: map[string]interface{}{"Age": 20, "Amount": 150}, Expected: true}, ""},
+		{Fixture{Name: "small", Data: map[string]interface{}{"Age": 20, "Amount": 50}}, ""},
+		{Fixture{Name: "no age", Data: map[string]interface{}{"Amount": 50}, Error: "Age"}, ""},
+		{
+			Fixture{Name: "wrong", Data: map[string]interface{}{"Age": 20, "Amount": 50}, Expected: true},
+			"expected=true actual=false\n(gt .Amount 100) was false at /1\nand => false\n|-- (ge .Age 18) => true\n`-- (gt .Amount 100) => false",
+		},
+		{Fixture{Name: "no error", Data: map[string]interface{}{"Age": 20, "Amount": 50}, Error: "Age"}, `expected an error containing "Age", got result=false`},
+		{Fixture{Name: "other error", Data: map[string]interface{}{"Amount": 50}, Error: "Amount"}, `expected an error containing "Amount"`},
+		{Fixture{Name: "error", Data: map[string]interface{}{"Amount": 50}}, "unexpected error"},
+	} {
+		err := Check(ct, tc.fixture)
+		switch {
+		case tc.expected == "" && err != nil:
+			t.Errorf("Check(%s) unexpected error: %s\n", tc.fixture.Name, err.Error())
+		case tc.expected != "" && (err == nil || !strings.Contains(err.Error(), tc.expected)):
+			t.Errorf("Check(%s) expected=%q actual=%v\n", tc.fixture.Name, tc.expected, err)
+		}
+	}
+}
+
+func TestReadFixtures(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "a_test.json", `[{"Name": "adult", "Data": {"Age": 20}, "Expected": true}]`)
+	writeFile(t, dir, "b_test.yaml", "- Name: adult\n  Data: {Age: 20}\n  Expected: true\n")
+	writeFile(t, dir, "typo_test.json", `[{"Name": "adult", "Data": {"Age": 20}, "Expect": true}]`)
+	writeFile(t, dir, "unnamed_test.json", `[{"Data": {"Age": 20}}]`)
+
+	for _, file := range []string{"a_test.json", "b_test.yaml"} {
+		fs, err := ReadFixtures(filepath.Join(dir, file))
+		if err != nil || len(fs) != 1 || fs[0].Name != "adult" || !fs[0].Expected || fs[0].Data.(map[string]interface{})["Age"] != 20.0 {
+			t.Errorf("ReadFixtures(%s) expected the adult fixture, actual=%+v err=%v\n", file, fs, err)
+		}
+	}
+	for _, file := range []string{"typo_test.json", "unnamed_test.json", "missing_test.json"} {
+		if _, err := ReadFixtures(filepath.Join(dir, file)); err == nil {
+			t.Errorf("ReadFixtures(%s) expected an error\n", file)
+		}
+	}
+}
+
+func TestRunDir(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "adult.json", `{"Op": "leaf", "Leaf": "(ge .Age 18)"}`)
+	writeFile(t, dir, "large.yaml", "Op: and\nNodes:\n  - Ref: adult\n  - Op: leaf\n    Leaf: (gt .Amount 100)\n")
+	writeFile(t, dir, "adult_test.json", `[{"Name": "adult", "Data": {"Age": 20}, "Expected": true}, {"Name": "minor", "Data": {"Age": 16}}]`)
+	writeFile(t, dir, "large_test.yml", "- Name: large\n  Data: {Age: 20, Amount: 150}\n  Expected: true\n- Name: minor\n  Data: {Age: 16, Amount: 150}\n")
+
+	RunDir(t, dir)
+}
@@ -0,0 +1,91 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// MaxTruthTableLeaves is the largest number of distinct leaves `TruthTable`
+// enumerates, a table of 2^16 rows.
+const MaxTruthTableLeaves = 16
+
+// TruthTable lists the result of a tree for every combination of the results
+// of its leaves.
+type TruthTable struct {
+	// Leaves are the distinct leaves of the tree, its inputs, in the order
+	// they first occur.
+	Leaves []string `json:"Leaves"`
+
+	// Rows hold every combination of the results of the leaves, counting
+	// up from all false with the first leaf as the most significant.
+	Rows []TruthTableRow `json:"Rows"`
+}
+
+// TruthTableRow is a single combination of the results of the leaves of a
+// `TruthTable` and the result of the tree.
+type TruthTableRow struct {
+	Inputs []bool `json:"Inputs"`
+	Result bool   `json:"Result"`
+}
+
+// TruthTable treats each distinct leaf of the tree as a boolean input and
+// enumerates the result of the tree for every combination of inputs, for
+// documenting rules and for snapshot tests of changes to them.  Leaves which
+// are equal but for their whitespace and redundant parentheses are the same
+// input, named by the normalized leaf; advanced leaves are named as they are
+// written.
+//
+// Leaves which cannot be true or false together, such as `(gt .A 5)` and
+// `(lt .A 3)`, are still enumerated as independent inputs.  Trees of more than
+// `MaxTruthTableLeaves` distinct leaves fail with an error wrapping
+// `ErrLimitExceeded`.
+func (n *Node) TruthTable() (*TruthTable, error) {
+	e, vars, err := n.boolean(false)
+	if err != nil {
+		return nil, err
+	}
+	if len(vars) > MaxTruthTableLeaves {
+		return nil, fmt.Errorf("%w: %d distinct leaves, at most %d are enumerated", ErrLimitExceeded, len(vars), MaxTruthTableLeaves)
+	}
+
+	tt := &TruthTable{Leaves: vars, Rows: make([]TruthTableRow, 1<<len(vars))}
+	for i := range tt.Rows {
+		inputs := make([]bool, len(vars))
+		for v := range inputs {
+			inputs[v] = i&(1<<(len(vars)-1-v)) != 0
+		}
+		tt.Rows[i] = TruthTableRow{Inputs: inputs, Result: e.eval(inputs)}
+	}
+	return tt, nil
+}
+
+// String renders the table as aligned columns, a leaf per column and a row
+// per line, with the result of the tree last:
+//
+//	(ge .Milk 4)  (le .Milk 6)  result
+//	false         false         false
+//	false         true          false
+//	true          false         false
+//	true          true          true
+func (tt *TruthTable) String() string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	for _, l := range tt.Leaves {
+		fmt.Fprintf(w, "%s\t", strings.Join(strings.Fields(l), " "))
+	}
+	fmt.Fprintln(w, "result")
+	for _, r := range tt.Rows {
+		for _, v := range r.Inputs {
+			fmt.Fprintf(w, "%s\t", strconv.FormatBool(v))
+		}
+		fmt.Fprintln(w, strconv.FormatBool(r.Result))
+	}
+	w.Flush()
+	return sb.String()
+}
@@ -0,0 +1,71 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestTruthTable(t *testing.T) {
+	n := NewNode(OperatorOr,
+		NewNode(OperatorAnd, NewLeafNode("ge .Milk 4"), NewLeafNode("le .Milk 6")),
+		NewNode(OperatorAnd, NewLeafNode("ge  .Milk 4"), NewLeafNode("((gt .Toothpaste 5))")))
+	tt, err := n.TruthTable()
+	if err != nil {
+		t.Fatalf("TruthTable() error: %s\n", err.Error())
+	}
+
+	if expected := []string{"(ge .Milk 4)", "(le .Milk 6)", "(gt .Toothpaste 5)"}; !reflect.DeepEqual(tt.Leaves, expected) {
+		t.Errorf("TruthTable() expected leaves=%q actual=%q\n", expected, tt.Leaves)
+	}
+	expected := `(ge .Milk 4)  (le .Milk 6)  (gt .Toothpaste 5)  result
+false         false         false               false
+false         false         true                false
+false         true          false               false
+false         true          true                false
+true          false         false               false
+true          false         true                true
+true          true          false               true
+true          true          true                true
+`
+	if actual := tt.String(); actual != expected {
+		t.Errorf("TruthTable() expected=\n%s\nactual=\n%s\n", expected, actual)
+	}
+
+	tt, err = NewNode(OperatorAnd, NewLeafNode("true"), NewAdvancedLeafNode("{{ .A }}")).TruthTable()
+	if err != nil {
+		t.Fatalf("TruthTable() error: %s\n", err.Error())
+	}
+	if len(tt.Rows) != 4 || tt.Leaves[1] != "{{ .A }}" || !tt.Rows[3].Result || tt.Rows[2].Result {
+		t.Errorf("TruthTable() unexpected table for an advanced leaf: %+v\n", tt)
+	}
+}
+
+func TestTruthTableErrors(t *testing.T) {
+	wide := NewNode(OperatorOr)
+	for i := 0; i <= MaxTruthTableLeaves; i++ {
+		wide.Nodes = append(wide.Nodes, NewLeafNode(fmt.Sprintf("eq .A %d", i)))
+	}
+	for _, tc := range []struct {
+		n   *Node
+		err error
+	}{
+		{wide, ErrLimitExceeded},
+		{NewNode(OperatorAnd, NewLeafNode("true"), NewNode(OperatorOr)), ErrEmptyNode},
+		{NewNode("xor", NewLeafNode("true")), ErrInvalidOperator},
+	} {
+		if _, err := tc.n.TruthTable(); !errors.Is(err, tc.err) {
+			t.Errorf("TruthTable(%s) expected=%v actual=%v\n", tc.n, tc.err, err)
+		}
+	}
+
+	wide.Nodes = wide.Nodes[1:]
+	if _, err := wide.TruthTable(); err != nil {
+		t.Errorf("TruthTable() expected %d leaves to be enumerated, got: %s\n", MaxTruthTableLeaves, err.Error())
+	}
+}
@@ -0,0 +1,106 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// TypedEvaluator is a tree compiled for data of type `T`, see `CompileTyped`.
+type TypedEvaluator[T any] struct {
+	ct *CompiledTree
+}
+
+// CompileTyped compiles the tree as `Compile` does and checks that every
+// field its leaves reference exists in `T`, so that a misspelled
+// `.Tothpaste` fails when the tree is loaded rather than whenever it is
+// evaluated.  A field exists if it is an exported field or a method of a
+// struct, or any key of a map with string keys; fields of interface values
+// cannot be checked and are assumed to exist, as are those of leaves reading
+// the data other than through fields, see `Opaque`.  A field which does not
+// exist fails with an error wrapping `ErrMissingField`, prefixed with the
+// path of its leaf.
+func CompileTyped[T any](n *Node, opts ...Option) (*TypedEvaluator[T], error) {
+	ct, err := Compile(n, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	ct.eval.walk(func(cn *compiledNode) {
+		for _, f := range cn.fields {
+			if err == nil {
+				if ferr := checkField(t, f); ferr != nil {
+					err = nodeError(cn.path, cn.node, ferr)
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &TypedEvaluator[T]{ct: ct}, nil
+}
+
+// Evaluate returns the result of the tree for `v`, see `CompiledTree.Evaluate`.
+func (te *TypedEvaluator[T]) Evaluate(v T) (bool, error) {
+	return te.ct.Evaluate(v)
+}
+
+// EvaluateContext is like `Evaluate` but stops once `ctx` is done, see
+// `CompiledTree.EvaluateContext`.
+func (te *TypedEvaluator[T]) EvaluateContext(ctx context.Context, v T) (bool, error) {
+	return te.ct.EvaluateContext(ctx, v)
+}
+
+// Tree returns the compiled tree, for its other methods.
+func (te *TypedEvaluator[T]) Tree() *CompiledTree {
+	return te.ct
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// checkField checks that the field `path` can be read from values of type
+// `t`, as templates read it.
+func checkField(t reflect.Type, path []string) error {
+	for i, name := range path {
+		// Templates call the methods of values and of those they point to.
+		m, ok := t.MethodByName(name)
+		for !ok && t.Kind() == reflect.Ptr {
+			t = t.Elem()
+			m, ok = t.MethodByName(name)
+		}
+		if ok {
+			if m.Type.NumOut() == 0 {
+				return fmt.Errorf("%w: method %s of %s returns nothing", ErrMissingField, name, t)
+			}
+			t = m.Type.Out(0)
+			continue
+		}
+
+		switch t.Kind() {
+		case reflect.Interface:
+			// The fields of other values depend on the value.
+			return nil
+		case reflect.Map:
+			if t.Key().Kind() != reflect.String {
+				return fmt.Errorf("%w: .%s is a map without string keys", ErrMissingField, strings.Join(path[:i], "."))
+			}
+			t = t.Elem()
+		case reflect.Struct:
+			f, ok := t.FieldByName(name)
+			if !ok || !f.IsExported() {
+				return fmt.Errorf("%w: %s has no field .%s", ErrMissingField, t, strings.Join(path[:i+1], "."))
+			}
+			t = f.Type
+		default:
+			return fmt.Errorf("%w: .%s is a %s without field %s", ErrMissingField, strings.Join(path[:i], "."), t, name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+type typedDairy struct {
+	Milk int
+}
+
+type typedCart struct {
+	Dairy      *typedDairy
+	Onions     int
+	Toothpaste int
+	Extras     map[string]int
+	Any        interface{}
+	secret     int
+}
+
+func (c typedCart) Total() int {
+	return c.Dairy.Milk + c.Onions + c.Toothpaste + c.secret
+}
+
+func (c typedCart) Pantry() typedDairy {
+	return *c.Dairy
+}
+
+func TestCompileTyped(t *testing.T) {
+	for _, tc := range []struct {
+		leaf string
+		path string // of the leaf whose field is missing, if any
+	}{
+		{"ge .Dairy.Milk 4", ""},
+		{"gt .Tothpaste 5", "/1"},
+		{"gt .Dairy.Eggs 5", "/1"},
+		{"gt .Extras.Bread 1", ""},
+		{"gt .Any.Whatever 1", ""},
+		{"gt .Total 1", ""},
+		{"gt .Pantry.Milk 1", ""},
+		{"gt .Pantry.Cream 1", "/1"},
+		{"gt .Onions.Red 1", "/1"},
+		{"gt .secret 1", "/1"},
+		{"gt (index . \"Tothpaste\") 1", ""},
+	} {
+		n := NewNode(OperatorAnd, NewLeafNode("gt .Onions 1"), NewLeafNode(tc.leaf))
+		te, err := CompileTyped[typedCart](n)
+		if tc.path != "" {
+			if !errors.Is(err, ErrMissingField) || err.Error()[:len(tc.path)+1] != tc.path+":" {
+				t.Errorf("CompileTyped(%s) expected a missing field at %s actual=%v\n", tc.leaf, tc.path, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("CompileTyped(%s) error: %s\n", tc.leaf, err.Error())
+			continue
+		}
+		if te.Tree() == nil {
+			t.Errorf("Tree() expected a tree\n")
+		}
+	}
+
+	te, err := CompileTyped[*typedCart](NewNode(OperatorAnd, NewLeafNode("ge .Dairy.Milk 4"), NewLeafNode("ge .Onions 1")))
+	if err != nil {
+		t.Fatalf("CompileTyped() error: %s\n", err.Error())
+	}
+	for _, tc := range []struct {
+		cart     *typedCart
+		expected bool
+	}{
+		{&typedCart{Dairy: &typedDairy{Milk: 5}, Onions: 2}, true},
+		{&typedCart{Dairy: &typedDairy{Milk: 5}}, false},
+	} {
+		if v, err := te.Evaluate(tc.cart); err != nil || v != tc.expected {
+			t.Errorf("Evaluate(%+v) expected=%v actual=%v err=%v\n", tc.cart, tc.expected, v, err)
+		}
+	}
+}
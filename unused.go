@@ -0,0 +1,137 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"sort"
+	"text/template/parse"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Unused is the part of a registry which none of its rules use, see
+// `Registry.Unused`, each sorted by name.
+type Unused struct {
+	// Trees are the registered trees which are neither rules nor
+	// referenced by them, directly or through other trees.
+	Trees []string `json:"Trees"`
+
+	// Macros and Funcs are the macros and functions of the policy of the
+	// registry, see `Policy`, which the leaves of the rules and of the trees
+	// they reference do not call, directly or through other macros.
+	Macros []string `json:"Macros"`
+	Funcs  []string `json:"Funcs"`
+}
+
+// Unused reports the trees, macros and functions of the registry which the
+// trees named `rules`, its active rules, do not use, so that libraries of
+// shared subtrees and macros accumulated over the years can be trimmed of
+// their dead entries.  Only the registry itself is scanned, not its
+// namespaces, nor the macros registered for every tree by `RegisterMacro`.
+// Unknown rules fail with an error wrapping `ErrUnresolvedRef`, and the
+// references of the rules to trees which are not registered are ignored.
+// Nothing is removed.
+func (r *Registry) Unused(rules ...string) (*Unused, error) {
+	used := map[string]bool{}
+	stack := []string{}
+	for _, name := range rules {
+		if _, ok := r.Get(name); !ok {
+			return nil, fmt.Errorf("%w: no tree named %q", ErrUnresolvedRef, name)
+		}
+		stack = append(stack, name)
+	}
+
+	macros := map[string]string{}
+	if p := r.getPolicy(); p != nil {
+		macros = p.Macros
+	}
+	words, calls := map[string]bool{}, map[string]bool{}
+	for len(stack) > 0 {
+		name := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if used[name] {
+			continue
+		}
+		n, ok := r.Get(name)
+		if !ok {
+			continue
+		}
+		used[name] = true
+		n.walkUsage(func(ref string) { stack = append(stack, ref) }, func(l *Node) {
+			src := l.Leaf
+			if l.Op == OperatorLeaf {
+				macroWords(macros, src, words)
+				src = nsExpandWords(macros, src)
+			}
+			leafCalls(l.Op, src, calls)
+		})
+	}
+
+	u := &Unused{Trees: []string{}, Macros: []string{}, Funcs: []string{}}
+	for _, name := range r.List() {
+		if !used[name] {
+			u.Trees = append(u.Trees, name)
+		}
+	}
+	for name := range macros {
+		if !words[name] {
+			u.Macros = append(u.Macros, name)
+		}
+	}
+	if p := r.getPolicy(); p != nil {
+		for name := range p.Funcs {
+			if !calls[name] {
+				u.Funcs = append(u.Funcs, name)
+			}
+		}
+	}
+	sort.Strings(u.Macros)
+	sort.Strings(u.Funcs)
+	return u, nil
+}
+
+// walkUsage calls `ref` with the name of every reference of the tree rooted
+// at `n`, and `leaf` with every ordinary and advanced leaf.
+func (n *Node) walkUsage(ref func(string), leaf func(*Node)) {
+	switch n.Op {
+	case OperatorRef:
+		ref(n.Leaf)
+	case OperatorLeaf, OperatorAdvanced:
+		leaf(n)
+	}
+	for _, c := range n.Nodes {
+		c.walkUsage(ref, leaf)
+	}
+}
+
+// macroWords records in `words` the macros of `macros` which the leaf
+// expression `expr` uses, directly or through other macros.
+func macroWords(macros map[string]string, expr string, words map[string]bool) {
+	mapWords(expr, func(word string) string {
+		if e, ok := macros[word]; ok && !words[word] {
+			words[word] = true
+			macroWords(macros, e, words)
+		}
+		return word
+	})
+}
+
+// leafCalls records in `calls` the functions which the leaf of operator
+// `op` and source `src` calls, see `leafFuncNames`.  Leaves which do not
+// parse call nothing.
+func leafCalls(op Operator, src string, calls map[string]bool) {
+	var t *parse.Tree
+	var err error
+	if op == OperatorAdvanced {
+		t, err = parseAdvanced(src)
+	} else {
+		t, err = parseLeaf(src)
+	}
+	if err != nil {
+		return
+	}
+	for _, name := range leafFuncNames(t) {
+		calls[name] = true
+	}
+}
@@ -0,0 +1,59 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestRegistryUnused(t *testing.T) {
+	r := &Registry{}
+	for name, n := range map[string]*Node{
+		"adult":   NewLeafNode("isAdult"),
+		"local":   NewNode(OperatorOr, NewLeafNode(`eq .City "SF"`), NewRefNode("nearby")),
+		"nearby":  NewAdvancedLeafNode("{{ near .City }}"),
+		"beer":    NewNode(OperatorAnd, NewRefNode("adult"), NewRefNode("local"), NewRefNode("missing")),
+		"old":     NewLeafNode("isSenior"),
+		"retired": NewNode(OperatorAnd, NewRefNode("old"), NewLeafNode("legacy .Plan")),
+	} {
+		if err := r.Register(name, n); err != nil {
+			t.Fatalf("Register(%s) error: %s\n", name, err.Error())
+		}
+	}
+	noop := func(interface{}) bool { return true }
+	if err := r.SetPolicy(Policy{
+		Funcs:  template.FuncMap{"near": noop, "legacy": noop, "minAge": func() int { return 18 }},
+		Macros: map[string]string{"isAdult": "ge .Age adultAge", "adultAge": "minAge", "isSenior": "ge .Age 65", "unusedMacro": "true"},
+	}); err != nil {
+		t.Fatalf("SetPolicy() error: %s\n", err.Error())
+	}
+
+	for _, tc := range []struct {
+		rules    []string
+		expected *Unused
+	}{
+		{[]string{"beer"}, &Unused{Trees: []string{"old", "retired"}, Macros: []string{"isSenior", "unusedMacro"}, Funcs: []string{"legacy"}}},
+		{[]string{"beer", "retired"}, &Unused{Trees: []string{}, Macros: []string{"unusedMacro"}, Funcs: []string{}}},
+		{nil, &Unused{Trees: r.List(), Macros: []string{"adultAge", "isAdult", "isSenior", "unusedMacro"}, Funcs: []string{"legacy", "minAge", "near"}}},
+	} {
+		u, err := r.Unused(tc.rules...)
+		if err != nil {
+			t.Fatalf("Unused(%v) error: %s\n", tc.rules, err.Error())
+		}
+		if !reflect.DeepEqual(u, tc.expected) {
+			t.Errorf("Unused(%v) expected=%+v actual=%+v\n", tc.rules, tc.expected, u)
+		}
+	}
+
+	if _, err := r.Unused("nope"); !errors.Is(err, ErrUnresolvedRef) {
+		t.Errorf("Unused() expected=%v actual=%v\n", ErrUnresolvedRef, err)
+	}
+	if len(r.List()) != 6 {
+		t.Errorf("Unused() expected the registry unchanged, got %v\n", r.List())
+	}
+}
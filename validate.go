@@ -0,0 +1,86 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"fmt"
+	"regexp"
+	"text/template/parse"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// Validate checks that the tree rooted at `n` is well formed without
+// compiling it: every operator is known, no `and` / `or` node is empty, every
+// `if` node has three children, every switch is on a field and has cases of
+// literal values with one child each and at most one default, every leaf is a
+// valid single expression (see `ErrNotExpression`), every advanced leaf is a
+// valid template and every literal pattern given to `matches` is a valid
+// regular expression.  Leaves are parsed one by one, and those which do not
+// parse fail with a `*ParseError` locating the problem within the leaf.
+// Functions called by leaves need not be defined, but references must have
+// been resolved, see `Resolve`.  Every problem of the
+// tree is reported at once rather than only the first: errors are
+// `*NodeError`s, prefixed with the path of the offending node, and several are
+// joined as by `errors.Join`, one per line, see `NodeErrors`.
+func (n *Node) Validate() error {
+	return n.validate("/", true)
+}
+
+// validate checks the tree rooted at `n`, checking ordinary leaves only if
+// `leaves` is set, since leaves compiled by a backend need not be templates.
+// Every problem of the tree is reported, see `joinErrors`.
+func (n *Node) validate(path string, leaves bool) error {
+	var errs []error
+	switch n.Op {
+	case OperatorLeaf, OperatorAdvanced:
+		if n.Op == OperatorLeaf && !leaves {
+			break
+		}
+		var t *parse.Tree
+		var err error
+		if n.Op == OperatorLeaf {
+			var defined bool
+			if t, defined, err = parseTemplate("{{ " + leafSource(n.Leaf) + " }}"); err == nil {
+				err = checkExpression(t, defined)
+			}
+		} else {
+			t, err = parseAdvanced(n.Leaf)
+		}
+		if err != nil {
+			if perr := leafParseError(n, nil); perr != nil {
+				err = perr
+			}
+			return nodeError(path, n, err)
+		}
+		for _, p := range literalPatterns(t) {
+			if _, err := regexp.Compile(p); err != nil {
+				errs = append(errs, nodeError(path, n, fmt.Errorf("%w %q: %v", ErrInvalidPattern, p, err)))
+			}
+		}
+	case OperatorAnd, OperatorOr, OperatorIf:
+		if len(n.Nodes) == 0 {
+			return nodeError(path, n, ErrEmptyNode)
+		}
+		if n.Op == OperatorIf && len(n.Nodes) != 3 {
+			errs = append(errs, nodeError(path, n, fmt.Errorf("%w: if needs exactly three children", ErrInvalidOperator)))
+		}
+		for i, c := range n.Nodes {
+			errs = append(errs, c.validate(childPath(path, i), leaves))
+		}
+	case OperatorSwitch:
+		return n.validateSwitch(path, leaves)
+	case OperatorRef:
+		return nodeError(path, n, fmt.Errorf("%w: %q", ErrUnresolvedRef, n.Leaf))
+	default:
+		if _, err := bindOperator(n); err != nil {
+			errs = append(errs, nodeError(path, n, err))
+		} else if len(n.Nodes) == 0 {
+			return nodeError(path, n, ErrEmptyNode)
+		}
+		for i, c := range n.Nodes {
+			errs = append(errs, c.validate(childPath(path, i), leaves))
+		}
+	}
+	return joinErrors(errs...)
+}
@@ -0,0 +1,160 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		n    *Node
+		err  error
+		path string
+	}{
+		{pricesTree(), nil, ""},
+		{NewLeafNode("undefinedFunc .X"), nil, ""},
+		{NewNode(OperatorAnd, NewLeafNode("true"), NewNode(OperatorOr)), ErrEmptyNode, "/1:"},
+		{NewNode(OperatorOr, NewNode("xor")), ErrInvalidOperator, "/0:"},
+		{NewNode(OperatorOr, NewLeafNode("true"), NewLeafNode(`matches .Name "(["`)), ErrInvalidPattern, "/1:"},
+		{NewLeafNode("gt .X ("), nil, "/:"},
+		{&Node{Op: OperatorLeaf, Leaf: "}} {{"}, nil, "/:"},
+		{&Node{Op: OperatorLeaf, Leaf: "true }}{{ .Secret }}{{ true"}, ErrNotExpression, "/:"},
+		{&Node{Op: OperatorLeaf, Leaf: `true }}{{ define "x" }}{{ .Secret }}{{ end }}{{ true`}, ErrNotExpression, "/:"},
+		{NewNode(OperatorOr, NewLeafNode("true"), NewLeafNode("$x := .A")), ErrNotExpression, "/1:"},
+		{NewLeafNode("eq .A 1 | not"), ErrNotExpression, "/:"},
+		{NewLeafNode("not (eq .A 1 | not)"), ErrNotExpression, "/:"},
+		{NewLeafNode("eq $.A 1"), nil, ""},
+		{NewAdvancedLeafNode("{{ $n := len .Tags }}{{ gt $n 0 | not }}"), nil, ""},
+		{NewAdvancedLeafNode("{{ if .A }}"), nil, "/:"},
+		{NewAdvancedLeafNode(`{{ define "x" }}{{ end }}true`), nil, "/:"},
+	} {
+		err := tc.n.Validate()
+		switch {
+		case tc.path == "" && err != nil:
+			t.Errorf("Validate() unexpected error: %s\n", err.Error())
+		case tc.path != "" && err == nil:
+			t.Errorf("Validate() expected an error at %s\n", tc.path)
+		case err != nil && !strings.HasPrefix(err.Error(), tc.path):
+			t.Errorf("Validate() expected error at %s, got: %s\n", tc.path, err.Error())
+		case tc.err != nil && !errors.Is(err, tc.err):
+			t.Errorf("Validate() expected=%v actual=%v\n", tc.err, err)
+		}
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	for _, tc := range []struct {
+		n     *Node
+		paths []string
+	}{
+		{pricesTree(), nil},
+		{NewNode(OperatorAnd, NewLeafNode("true"), NewNode(OperatorOr)), []string{"/1"}},
+		{NewNode(OperatorAnd, NewNode(OperatorOr), NewLeafNode("gt .X ("), NewNode("xor", NewNode(OperatorAnd))), []string{"/0", "/1", "/2", "/2/0"}},
+		{NewNode(OperatorIf, NewLeafNode("gt .X (")), []string{"/", "/0"}},
+		{NewLeafNode(`or (matches .A "(") (matches .B "[")`), []string{"/", "/"}},
+		{NewSwitchNode(".A", &Node{Op: OperatorLeaf}, NewCaseNode(NewNode(OperatorOr), "1"), NewCaseNode(NewLeafNode("true")), NewCaseNode(NewLeafNode("gt .X (")), NewCaseNode(NewLeafNode("true"), "1")),
+			[]string{"/0", "/1/0", "/3", "/3/0"}},
+		{NewSwitchNode("gt .A 1", NewCaseNode(NewNode(OperatorAnd), "1")), []string{"/", "/0/0"}},
+	} {
+		err := tc.n.Validate()
+		paths := []string{}
+		for _, ne := range NodeErrors(err) {
+			paths = append(paths, ne.Path)
+		}
+		if strings.Join(paths, " ") != strings.Join(tc.paths, " ") {
+			t.Errorf("Validate() expected=%v actual=%v\n", tc.paths, paths)
+		}
+		if err != nil && strings.Count(err.Error(), "\n") != len(tc.paths)-1 {
+			t.Errorf("Validate() expected one line per error, got: %q\n", err.Error())
+		}
+	}
+
+	// A single problem is its own error, and joined ones are found by errors.Is.
+	if _, ok := NewNode(OperatorOr).Validate().(*NodeError); !ok {
+		t.Errorf("Validate() expected a single *NodeError\n")
+	}
+	err := NewNode(OperatorOr, NewNode(OperatorAnd), NewNode("xor", NewLeafNode("true"))).Validate()
+	if !errors.Is(err, ErrEmptyNode) || !errors.Is(err, ErrInvalidOperator) {
+		t.Errorf("Validate() expected to wrap both errors, got: %v\n", err)
+	}
+	if _, err := Compile(NewNode(OperatorOr, NewNode(OperatorAnd), NewNode(OperatorAnd))); len(NodeErrors(err)) != 2 {
+		t.Errorf("Compile() expected two errors, got: %v\n", err)
+	}
+}
+
+func TestPrecompiledMatches(t *testing.T) {
+	if _, err := Compile(NewLeafNode(`matches .Name "(["`)); !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("Compile() expected an invalid pattern error, got: %v\n", err)
+	}
+
+	for _, fm := range []template.FuncMap{nil, StdFuncs()} {
+		ct, err := Compile(NewNode(OperatorAnd,
+			NewLeafNode(`matches .Name "^log"`),
+			NewLeafNode(`matches .Name .Pattern`)), WithFuncs(fm))
+		if err != nil {
+			t.Fatalf("Compile() error: %s\n", err.Error())
+		}
+		if len(ct.eval.children) != 2 {
+			t.Fatalf("Compile() expected two children\n")
+		}
+
+		data := map[string]string{"Name": "logictree", "Pattern": "tree$"}
+		if v, err := ct.Evaluate(data); err != nil || !v {
+			t.Errorf("Evaluate() expected=true actual=%v (%v)\n", v, err)
+		}
+
+		data["Pattern"] = "("
+		if _, err := ct.Evaluate(data); err == nil {
+			t.Errorf("Evaluate() expected an error for an invalid dynamic pattern\n")
+		}
+	}
+
+	custom := template.FuncMap{"matches": func(s, p string) bool { return s == p }}
+	ct, err := Compile(NewLeafNode(`matches .Name "logictree"`), WithFuncs(custom))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	if v, err := ct.Evaluate(map[string]string{"Name": "logictree"}); err != nil || !v {
+		t.Errorf("Evaluate() expected the custom matches to be used, got %v (%v)\n", v, err)
+	}
+}
+
+func TestAdvancedLeaf(t *testing.T) {
+	ct, err := Compile(NewNode(OperatorAnd,
+		NewLeafNode("ge .Milk 4"),
+		NewAdvancedLeafNode(`{{ $n := len .Tags }}{{ if gt $n 0 }}{{ index .Tags 0 | eq "new" }}{{ else }}false{{ end }}`)))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	for _, tc := range []struct {
+		data     map[string]interface{}
+		expected bool
+	}{
+		{map[string]interface{}{"Milk": 5, "Tags": []string{"new", "sale"}}, true},
+		{map[string]interface{}{"Milk": 5, "Tags": []string{"sale"}}, false},
+		{map[string]interface{}{"Milk": 5, "Tags": []string{}}, false},
+		{map[string]interface{}{"Milk": 1, "Tags": []string{"new"}}, false},
+	} {
+		v, err := ct.Evaluate(tc.data)
+		if err != nil {
+			t.Errorf("Evaluate(%v) error: %s\n", tc.data, err.Error())
+		}
+		if v != tc.expected {
+			t.Errorf("Evaluate(%v) expected=%v actual=%v\n", tc.data, tc.expected, v)
+		}
+	}
+
+	if _, err := NewAdvancedLeafNode("{{ true }}").Combine(); !errors.Is(err, ErrNotExpression) {
+		t.Errorf("Combine() expected=%v actual=%v\n", ErrNotExpression, err)
+	}
+	if _, _, err := NewAdvancedLeafNode("{{ true }}").ToSQL(DialectPostgres); !errors.Is(err, ErrNotTranslatable) {
+		t.Errorf("ToSQL() expected=%v actual=%v\n", ErrNotTranslatable, err)
+	}
+}
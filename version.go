@@ -0,0 +1,131 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// FormatVersion is the version of the serialized form written by
+// `MarshalDocument`.  Version 0 is a bare tree, as written by `json.Marshal`.
+const FormatVersion = 1
+
+// Document is the versioned serialized form of a tree.
+type Document struct {
+	Version int   `json:"Version"`
+	Tree    *Node `json:"Tree"`
+}
+
+// MarshalDocument encodes the tree rooted at `n` as a `Document` of the
+// current `FormatVersion`, for persisting trees which are later loaded with
+// `UnmarshalDocument` after the format has evolved.
+func MarshalDocument(n *Node) ([]byte, error) {
+	return json.Marshal(Document{Version: FormatVersion, Tree: n})
+}
+
+// UnmarshalDocument decodes a tree from a document of any version up to the
+// current `FormatVersion`, migrating older documents, including bare trees,
+// as it is read.  No decoding limits apply, see `DecodeOptions`.
+func UnmarshalDocument(data []byte) (*Node, error) {
+	return DecodeOptions{}.UnmarshalDocument(data)
+}
+
+// UnmarshalDocument is `UnmarshalDocument` within the limits of `o`.  Strict
+// decoding also rejects fields of the document other than "Version" and
+// "Tree".
+func (o DecodeOptions) UnmarshalDocument(data []byte) (*Node, error) {
+	doc, err := migrateDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	if o.Strict {
+		keys := make([]string, 0, len(doc))
+		for k := range doc {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if k != "Version" && k != "Tree" {
+				errs = append(errs, fmt.Errorf("invalid document: unknown field %q", k))
+			}
+		}
+	}
+	tree, ok := doc["Tree"]
+	if !ok {
+		return nil, joinErrors(append(errs, fmt.Errorf("invalid document: missing Tree"))...)
+	}
+	n, err := o.Unmarshal(tree)
+	if err := joinErrors(append(errs, err)...); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// MigrateDocument upgrades a document of any version up to the current
+// `FormatVersion`, including a bare tree, to the current version, so that
+// persisted trees can be rewritten in place.  Only the migrations between the
+// formats of this package are applied.
+func MigrateDocument(data []byte) ([]byte, error) {
+	doc, err := migrateDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// migrations upgrade the fields of a document from the version of their index
+// to the next one.  Trees within the documents are left encoded, so that
+// they are only decoded once, within any limits, by `Unmarshal`.
+//
+// Migrations are internal to the package and cannot be registered by
+// callers: a change to the format adds its migration here along with an
+// increment of `FormatVersion`, so that there is exactly one migration from
+// each earlier version.
+var migrations = []func(doc map[string]json.RawMessage) error{
+	// 0: the fields of a bare tree become the tree of a document.
+	func(doc map[string]json.RawMessage) error {
+		tree, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		for k := range doc {
+			delete(doc, k)
+		}
+		doc["Tree"] = tree
+		return nil
+	},
+}
+
+// migrateDocument returns the fields of `data` migrated to the current
+// version.  Documents without a version are bare trees.
+func migrateDocument(data []byte) (map[string]json.RawMessage, error) {
+	doc := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+
+	version := 0
+	if v, ok := doc["Version"]; ok {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return nil, fmt.Errorf("invalid document: Version: %w", err)
+		}
+	}
+	if version < 0 || version > FormatVersion {
+		return nil, fmt.Errorf("%w: %d, at most %d is supported", ErrUnsupportedVersion, version, FormatVersion)
+	}
+
+	for ; version < FormatVersion; version++ {
+		if err := migrations[version](doc); err != nil {
+			return nil, fmt.Errorf("migrating from version %d: %w", version, err)
+		}
+	}
+	doc["Version"] = json.RawMessage(fmt.Sprint(FormatVersion))
+	return doc, nil
+}
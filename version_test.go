@@ -0,0 +1,81 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func TestDocument(t *testing.T) {
+	bs, err := MarshalDocument(pricesTree())
+	if err != nil {
+		t.Fatalf("MarshalDocument() error: %s\n", err.Error())
+	}
+	var doc Document
+	if err := json.Unmarshal(bs, &doc); err != nil || doc.Version != FormatVersion {
+		t.Errorf("MarshalDocument() expected version %d, got %s (%v)\n", FormatVersion, bs, err)
+	}
+
+	bare, err := json.Marshal(pricesTree())
+	if err != nil {
+		t.Fatalf("Marshal() error: %s\n", err.Error())
+	}
+	for _, src := range [][]byte{bs, bare} {
+		n, err := UnmarshalDocument(src)
+		if err != nil {
+			t.Errorf("UnmarshalDocument(%s) error: %s\n", src, err.Error())
+			continue
+		}
+		if !reflect.DeepEqual(n, pricesTree()) {
+			t.Errorf("UnmarshalDocument(%s) expected=%s actual=%s\n", src, pricesTree(), n)
+		}
+	}
+
+	migrated, err := MigrateDocument(bare)
+	if err != nil {
+		t.Fatalf("MigrateDocument() error: %s\n", err.Error())
+	}
+	doc = Document{}
+	if err := json.Unmarshal(migrated, &doc); err != nil || doc.Version != FormatVersion || !reflect.DeepEqual(doc.Tree, pricesTree()) {
+		t.Errorf("MigrateDocument() expected a current document, got %s (%v)\n", migrated, err)
+	}
+}
+
+func TestDocumentErrors(t *testing.T) {
+	for _, tc := range []struct {
+		opts DecodeOptions
+		src  string
+		err  error
+	}{
+		{DecodeOptions{}, `{"Version": 2, "Tree": {"Op": "leaf", "Leaf": "(true)"}}`, ErrUnsupportedVersion},
+		{DecodeOptions{}, `{"Version": -1, "Tree": {"Op": "leaf", "Leaf": "(true)"}}`, ErrUnsupportedVersion},
+		{DecodeOptions{}, `{"Version": "1"}`, nil},
+		{DecodeOptions{}, `{"Version": 1}`, nil},
+		{DecodeOptions{}, `[]`, nil},
+		{DecodeOptions{MaxDepth: 1}, `{"Version": 1, "Tree": {"Op": "and", "Nodes": [{"Op": "leaf", "Leaf": "(true)"}]}}`, ErrLimitExceeded},
+		{DecodeOptions{MaxDepth: 1}, `{"Op": "and", "Nodes": [{"Op": "leaf", "Leaf": "(true)"}]}`, ErrLimitExceeded},
+		{DecodeOptions{Strict: true}, `{"Version": 1, "Tree": {"Op": "leaf", "Leaf": "(true)"}, "Author": "x"}`, nil},
+	} {
+		_, err := tc.opts.UnmarshalDocument([]byte(tc.src))
+		if err == nil || (tc.err != nil && !errors.Is(err, tc.err)) {
+			t.Errorf("UnmarshalDocument(%s) expected error=%v actual=%v\n", tc.src, tc.err, err)
+		}
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	// Every earlier version has exactly one migration to the next.
+	if len(migrations) != FormatVersion {
+		t.Errorf("migrations expected=%d actual=%d\n", FormatVersion, len(migrations))
+	}
+	for v, m := range migrations {
+		if m == nil {
+			t.Errorf("migrations[%d] expected a migration\n", v)
+		}
+	}
+}
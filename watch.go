@@ -0,0 +1,319 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+// DataHandle is a mutable data context which notifies the trees watching it,
+// see `Watch`, of the fields which change.  It is safe for concurrent use.
+type DataHandle struct {
+	mu   sync.RWMutex
+	data map[string]interface{}
+	subs map[*watcher]struct{}
+}
+
+// NewDataHandle returns a handle holding `data`, which must not be modified
+// other than through the handle.
+func NewDataHandle(data map[string]interface{}) *DataHandle {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return &DataHandle{data: data, subs: map[*watcher]struct{}{}}
+}
+
+// Set sets the field, written as in leaves with or without the leading dot,
+// to `v`, creating nested maps as needed.
+func (h *DataHandle) Set(field string, v interface{}) {
+	h.Update(func(data map[string]interface{}) {
+		setField(data, strings.Split(strings.TrimPrefix(field, "."), "."), v)
+	}, field)
+}
+
+// Update calls `fn` to modify the data, then notifies watchers that `fields`
+// changed.  With no fields every field is assumed to have changed.
+func (h *DataHandle) Update(fn func(data map[string]interface{}), fields ...string) {
+	h.mu.Lock()
+	fn(h.data)
+	subs := make([]*watcher, 0, len(h.subs))
+	for w := range h.subs {
+		subs = append(subs, w)
+	}
+	h.mu.Unlock()
+
+	for _, w := range subs {
+		w.notify(fields)
+	}
+}
+
+// view calls `fn` with the data, which must not be modified or retained.
+func (h *DataHandle) view(fn func(data map[string]interface{})) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	fn(h.data)
+}
+
+func (h *DataHandle) subscribe(w *watcher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subs[w] = struct{}{}
+}
+
+func (h *DataHandle) unsubscribe(w *watcher) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs, w)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+
+// VerdictChange reports that the result of a watched tree changed.
+type VerdictChange struct {
+	// Result is the new result.
+	Result bool
+
+	// Fields are the fields updated since the previous evaluation, nil if an
+	// update changed every field.
+	Fields []string
+
+	// Err is set, and Result is not, if the tree failed to evaluate.  The
+	// next change is reported relative to the last successful result.
+	Err error
+}
+
+// Watch evaluates the tree against `h` and then again after every update,
+// sending a change on the returned channel whenever the result flips between
+// true and false.  Only the leaves referencing updated fields, and their
+// ancestors, are re-evaluated, as described by `WithIncremental`, using a
+// cache private to this watch.  Updates made while a change is waiting to be
+// received are coalesced into a single evaluation.
+//
+// The tree is first evaluated before Watch returns, that initial result is
+// not sent; it is the opposite of the first change's result.  The channel is
+// closed once `ctx` is done.
+func (ct *CompiledTree) Watch(ctx context.Context, h *DataHandle) <-chan VerdictChange {
+	w := &watcher{
+		ct:   ct,
+		root: ct.eval.clone(),
+		wake: make(chan struct{}, 1),
+	}
+	out := make(chan VerdictChange)
+
+	h.subscribe(w)
+	last, err := w.evaluate(ctx, h)
+	known := err == nil
+
+	go func() {
+		defer close(out)
+		defer h.unsubscribe(w)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.wake:
+			}
+
+			fields, all := w.take()
+			if all {
+				fields = nil
+				w.root.invalidate(nil)
+			} else {
+				w.root.invalidate(fieldPaths(fields))
+			}
+
+			v, err := w.evaluate(ctx, h)
+			var change *VerdictChange
+			switch {
+			case err != nil && ctx.Err() != nil:
+				return
+			case err != nil:
+				change = &VerdictChange{Fields: fields, Err: err}
+			case !known || v != last:
+				if known {
+					change = &VerdictChange{Result: v, Fields: fields}
+				}
+				last, known = v, true
+			}
+			if change == nil {
+				continue
+			}
+
+			select {
+			case out <- *change:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// WatchOptions configure `Evaluator.Watch`.
+type WatchOptions struct {
+	// Debounce is how long a new result must hold before its change is
+	// sent.  A result which flips back within it, as a metric hovering
+	// around its threshold does, sends no change.  Zero sends every change
+	// as it happens.
+	Debounce time.Duration `json:"debounce,omitempty" yaml:"debounce,omitempty"`
+}
+
+// Watch applies each delta received from `deltas`, as `Apply` does, sending a
+// change on the returned channel whenever the result flips between true and
+// false.  The `Fields` of a change are those set since the previous change
+// was sent, and a delta which fails to evaluate sends a change with its `Err`.
+//
+// The snapshot is first evaluated before Watch returns, that initial result
+// is not sent.  The channel is closed once `ctx` is done, or once `deltas` is
+// closed and any change it left pending has been sent.  The evaluator must
+// not be given other deltas while it is watched.
+func (e *Evaluator) Watch(ctx context.Context, deltas <-chan map[string]interface{}, opts WatchOptions) <-chan VerdictChange {
+	out := make(chan VerdictChange)
+	reported, err := e.Evaluate(ctx)
+	known := err == nil
+
+	go func() {
+		defer close(out)
+
+		send := func(c VerdictChange) bool {
+			select {
+			case out <- c:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var fields []string
+		var timer *time.Timer
+		var fire <-chan time.Time // set while a change waits out the debounce
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+		}()
+
+		for deltas != nil || fire != nil {
+			select {
+			case <-ctx.Done():
+				return
+			case delta, ok := <-deltas:
+				if !ok {
+					deltas = nil
+					continue
+				}
+				fields = addFields(fields, delta)
+
+				v, _, err := e.Apply(ctx, delta)
+				switch {
+				case err != nil && ctx.Err() != nil:
+					return
+				case err != nil:
+					if !send(VerdictChange{Fields: fields, Err: err}) {
+						return
+					}
+					fields = nil
+				case !known:
+					reported, known, fields = v, true, nil
+				case v == reported:
+					if fire != nil {
+						timer.Stop()
+						fire = nil
+					}
+				case opts.Debounce <= 0:
+					if !send(VerdictChange{Result: v, Fields: fields}) {
+						return
+					}
+					reported, fields = v, nil
+				case fire == nil:
+					timer = time.NewTimer(opts.Debounce)
+					fire = timer.C
+				}
+			case <-fire:
+				fire = nil
+				reported = !reported
+				if !send(VerdictChange{Result: reported, Fields: fields}) {
+					return
+				}
+				fields = nil
+			}
+		}
+	}()
+	return out
+}
+
+// addFields adds the fields of `delta` missing from the sorted `fields`.
+func addFields(fields []string, delta map[string]interface{}) []string {
+	for f := range delta {
+		if i := sort.SearchStrings(fields, f); i == len(fields) || fields[i] != f {
+			fields = append(fields, "")
+			copy(fields[i+1:], fields[i:])
+			fields[i] = f
+		}
+	}
+	return fields
+}
+
+// watcher is the state of a single `Watch`.
+type watcher struct {
+	ct   *CompiledTree
+	root *compiledNode // a copy of the tree holding this watch's cache
+	wake chan struct{}
+
+	mu      sync.Mutex
+	pending []string // fields updated since the last evaluation
+	all     bool     // every field was updated
+}
+
+// notify records that `fields` were updated and wakes the watcher.
+func (w *watcher) notify(fields []string) {
+	w.mu.Lock()
+	if len(fields) == 0 {
+		w.all = true
+	}
+	w.pending = append(w.pending, fields...)
+	w.mu.Unlock()
+
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+// take returns and clears the fields updated since it was last called.
+func (w *watcher) take() ([]string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	fields, all := w.pending, w.all
+	w.pending, w.all = nil, false
+	return fields, all
+}
+
+func (w *watcher) evaluate(ctx context.Context, h *DataHandle) (v bool, err error) {
+	st := w.ct.newState(ctx)
+	defer releaseState(st)
+	st.cached = true
+	h.view(func(data map[string]interface{}) {
+		v, err = w.root.evaluate(st, data)
+	})
+	return v, err
+}
+
+// clone returns a copy of the node and its descendants with an empty cache.
+// Templates are shared, since they may be executed concurrently.
+func (cn *compiledNode) clone() *compiledNode {
+	c := *cn
+	c.cache = nodeCache{}
+	c.children = make([]*compiledNode, len(cn.children))
+	for i, child := range cn.children {
+		c.children[i] = child.clone()
+	}
+	return &c
+}
@@ -0,0 +1,165 @@
+package logictree
+
+////////////////////////////////////////////////////////////////////////////////
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+
+func receive(t *testing.T, ch <-chan VerdictChange) VerdictChange {
+	t.Helper()
+	select {
+	case c := <-ch:
+		return c
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Watch() expected a change\n")
+	}
+	return VerdictChange{}
+}
+
+func TestWatch(t *testing.T) {
+	ct, err := Compile(pricesTree())
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+
+	h := NewDataHandle(map[string]interface{}{"Milk": 5, "Onions": 0, "Toothpaste": 4})
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := ct.Watch(ctx, h)
+
+	h.Set("Onions", 2)
+	if c := receive(t, ch); !c.Result || c.Err != nil || !reflect.DeepEqual(c.Fields, []string{"Onions"}) {
+		t.Errorf("Watch() expected a change to true for Onions, actual=%+v\n", c)
+	}
+
+	// Neither of these flip the verdict, so the next change is the third.
+	h.Set("Milk", 6)
+	h.Set("Toothpaste", 9)
+	h.Set("Onions", 0)
+	h.Set(".Toothpaste", 1)
+	if c := receive(t, ch); c.Result || c.Err != nil {
+		t.Errorf("Watch() expected a change to false, actual=%+v\n", c)
+	}
+
+	h.Update(func(data map[string]interface{}) {
+		data["Toothpaste"] = "lots"
+	})
+	if c := receive(t, ch); c.Err == nil || c.Fields != nil {
+		t.Errorf("Watch() expected an error for every field, actual=%+v\n", c)
+	}
+
+	h.Update(func(data map[string]interface{}) {
+		data["Toothpaste"] = 7
+	}, "Toothpaste")
+	if c := receive(t, ch); !c.Result || c.Err != nil {
+		t.Errorf("Watch() expected a change to true, actual=%+v\n", c)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		if ok {
+			t.Errorf("Watch() expected the channel to be closed\n")
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Watch() expected the channel to be closed\n")
+	}
+}
+
+func TestWatchIndependent(t *testing.T) {
+	ct, err := Compile(NewLeafNode("gt .A 1"))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := NewDataHandle(map[string]interface{}{"A": 0})
+	b := NewDataHandle(map[string]interface{}{"A": 5})
+	cha, chb := ct.Watch(ctx, a), ct.Watch(ctx, b)
+
+	b.Set("A", 0)
+	a.Set("A", 2)
+	if c := receive(t, cha); !c.Result {
+		t.Errorf("Watch(a) expected a change to true, actual=%+v\n", c)
+	}
+	if c := receive(t, chb); c.Result {
+		t.Errorf("Watch(b) expected a change to false, actual=%+v\n", c)
+	}
+}
+
+func TestEvaluatorWatch(t *testing.T) {
+	ct, err := Compile(NewLeafNode("gt .CPU 90"))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	e := ct.NewEvaluator(map[string]interface{}{"CPU": 50})
+	deltas := make(chan map[string]interface{})
+	ch := e.Watch(ctx, deltas, WatchOptions{})
+
+	deltas <- map[string]interface{}{"CPU": 60, "Host": "a"}
+	deltas <- map[string]interface{}{"CPU": 95}
+	if c := receive(t, ch); !c.Result || c.Err != nil || !reflect.DeepEqual(c.Fields, []string{"CPU", "Host"}) {
+		t.Errorf("Watch() expected a change to true for CPU and Host, actual=%+v\n", c)
+	}
+	deltas <- map[string]interface{}{"CPU": "hot"}
+	if c := receive(t, ch); c.Err == nil || !reflect.DeepEqual(c.Fields, []string{"CPU"}) {
+		t.Errorf("Watch() expected an error for CPU, actual=%+v\n", c)
+	}
+	deltas <- map[string]interface{}{"CPU": 10}
+	if c := receive(t, ch); c.Result || c.Err != nil {
+		t.Errorf("Watch() expected a change to false, actual=%+v\n", c)
+	}
+
+	close(deltas)
+	if _, ok := <-ch; ok {
+		t.Errorf("Watch() expected the channel to be closed\n")
+	}
+}
+
+func TestEvaluatorWatchDebounce(t *testing.T) {
+	ct, err := Compile(NewLeafNode("gt .CPU 90"))
+	if err != nil {
+		t.Fatalf("Compile() error: %s\n", err.Error())
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	e := ct.NewEvaluator(map[string]interface{}{"CPU": 50})
+	deltas := make(chan map[string]interface{})
+	ch := e.Watch(ctx, deltas, WatchOptions{Debounce: 200 * time.Millisecond})
+
+	// Flipping back within the debounce sends no change.
+	for i := 0; i < 5; i++ {
+		deltas <- map[string]interface{}{"CPU": 95}
+		deltas <- map[string]interface{}{"CPU": 50}
+	}
+	start := time.Now()
+	deltas <- map[string]interface{}{"CPU": 99}
+	deltas <- map[string]interface{}{"CPU": 98, "Host": "a"}
+	c := receive(t, ch)
+	if !c.Result || c.Err != nil || !reflect.DeepEqual(c.Fields, []string{"CPU", "Host"}) {
+		t.Errorf("Watch() expected a change to true for CPU and Host, actual=%+v\n", c)
+	}
+	if d := time.Since(start); d < 200*time.Millisecond {
+		t.Errorf("Watch() expected the change after the debounce, actual=%s\n", d)
+	}
+
+	// A change pending when the deltas are closed is still sent.
+	deltas <- map[string]interface{}{"CPU": 0}
+	close(deltas)
+	if c := receive(t, ch); c.Result || c.Err != nil {
+		t.Errorf("Watch() expected a change to false, actual=%+v\n", c)
+	}
+	if _, ok := <-ch; ok {
+		t.Errorf("Watch() expected the channel to be closed\n")
+	}
+}